@@ -0,0 +1,18 @@
+// Command ulidcheck reports case-sensitive comparisons of ULID strings with
+// external input and loose parsing of external input with ulid.Parse.
+//
+// It can be run directly or with go vet:
+//
+//	go install go.rtnl.ai/ulid/analysis/cmd/ulidcheck@latest
+//	ulidcheck ./...
+//	go vet -vettool=$(which ulidcheck) ./...
+package main
+
+import (
+	"go.rtnl.ai/ulid/analysis/ulidcheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(ulidcheck.Analyzer)
+}
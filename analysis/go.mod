@@ -0,0 +1,15 @@
+module go.rtnl.ai/ulid/analysis
+
+go 1.23.3
+
+require (
+	go.rtnl.ai/ulid v0.0.0-00010101000000-000000000000
+	golang.org/x/tools v0.31.0
+)
+
+require (
+	golang.org/x/mod v0.24.0 // indirect
+	golang.org/x/sync v0.12.0 // indirect
+)
+
+replace go.rtnl.ai/ulid => ../
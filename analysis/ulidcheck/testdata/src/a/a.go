@@ -0,0 +1,103 @@
+package a
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.rtnl.ai/ulid"
+)
+
+var name string
+
+func init() {
+	flag.StringVar(&name, "name", "", "")
+}
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	id := ulid.Make()
+
+	if id.String() == r.FormValue("id") { // want `case-sensitive comparison of ulid.ULID.String\(\) with external input`
+	}
+
+	if r.URL.Query().Get("id") != id.String() { // want `case-sensitive comparison`
+	}
+
+	if id.String() == r.Header.Get("X-Request-ID") { // want `case-sensitive comparison`
+	}
+
+	param := r.PathValue("id")
+	if id.String() == param { // want `case-sensitive comparison`
+	}
+
+	alias := param
+	if (id.String()) == (alias) { // want `case-sensitive comparison`
+	}
+
+	ulid.Parse(r.FormValue("id")) // want `ulid.Parse of external input accepts invalid characters: use ulid.ParseStrict`
+	ulid.MustParse(param)         // want `ulid.MustParse of external input accepts invalid characters: use ulid.MustParseStrict`
+	ulid.ParseStrict(r.FormValue("id"))
+
+	// Canonicalized, transformed, or internal strings are not reported.
+	canonical, _ := ulid.Canonicalize(param)
+	if id.String() == canonical {
+	}
+
+	if id.String() == strings.ToUpper(param) {
+	}
+
+	if ulid.EqualsString(id, param) {
+	}
+
+	if id.String() == ulid.Make().String() {
+	}
+
+	internal := "01HTNMW2JAW89YSBG7NFPHABA4"
+	if id.String() == internal {
+	}
+	ulid.Parse(internal)
+	ulid.Parse(id.String())
+}
+
+func command() {
+	id := ulid.Make()
+	p := flag.String("id", "", "")
+	flag.Parse()
+
+	if id.String() == *p { // want `case-sensitive comparison`
+	}
+
+	if id.String() == flag.Arg(0) { // want `case-sensitive comparison`
+	}
+
+	if id.String() == os.Args[1] { // want `case-sensitive comparison`
+	}
+
+	if id.String() == os.Getenv("ID") { // want `case-sensitive comparison`
+	}
+
+	if id.String() == name { // want `case-sensitive comparison`
+	}
+
+	ulid.Parse(*p)         // want `ulid.Parse of external input`
+	ulid.Parse(os.Args[1]) // want `ulid.Parse of external input`
+
+	// A pointer to a flag is not itself a string.
+	if p == nil {
+	}
+}
+
+func conversions(b []byte, arr [16]byte, ids []ulid.ULID) {
+	id := ulid.ULID(arr) // want `raw conversion of \[16\]byte to ulid.ULID: use ulid.FromArray`
+	_ = ulid.ULID(b)     // want `raw conversion of \[\]byte to ulid.ULID panics for short slices: use ulid.FromSlice`
+	_ = [16]byte(id)     // want `raw conversion of ulid.ULID to \[16\]byte: use the Array method`
+	_ = [16]byte(ids[0]) // want `raw conversion of ulid.ULID to \[16\]byte: use the Array method`
+
+	// Conversions to other types defined as arrays of 16 bytes are not reported.
+	type uuid [16]byte
+	_ = uuid(id)
+	_ = ulid.ULID(uuid(arr))
+	_ = ulid.FromArray(arr)
+	_ = id.Array()
+}
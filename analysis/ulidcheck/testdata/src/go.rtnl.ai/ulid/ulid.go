@@ -0,0 +1,18 @@
+// Package ulid is a stub of go.rtnl.ai/ulid for the analyzer tests.
+package ulid
+
+type ULID [16]byte
+
+func (id ULID) String() string  { return "" }
+func (id ULID) Array() [16]byte { return [16]byte(id) }
+
+func Make() ULID                            { return ULID{} }
+func Parse(ulid any) (ULID, error)          { return ULID{}, nil }
+func ParseStrict(ulid any) (ULID, error)    { return ULID{}, nil }
+func MustParse(ulid any) ULID               { return ULID{} }
+func MustParseStrict(ulid any) ULID         { return ULID{} }
+func Const(s string) ULID                   { return ULID{} }
+func Canonicalize(s string) (string, error) { return s, nil }
+func EqualsString(id ULID, s string) bool   { return false }
+func FromArray(b [16]byte) ULID             { return ULID(b) }
+func FromSlice(b []byte) (ULID, error)      { return ULID(b), nil }
@@ -0,0 +1,11 @@
+// Package ulidtest is a stub of go.rtnl.ai/ulid/ulidtest for the analyzer tests.
+package ulidtest
+
+import (
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func MustParse(tb testing.TB, v any) ulid.ULID       { return ulid.ULID{} }
+func MustParseStrict(tb testing.TB, v any) ulid.ULID { return ulid.ULID{} }
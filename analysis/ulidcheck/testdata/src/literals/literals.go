@@ -0,0 +1,44 @@
+package literals
+
+import (
+	"os"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/ulidtest"
+)
+
+const prefix = "01HTNMW2JA"
+
+var (
+	valid    = ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	strict   = ulid.MustParseStrict(`01HTNMW2JAW89YSBG7NFPHABA4`)
+	constant = ulid.Const("01HTNMW2JAW89YSBG7NFPHABA4")
+	max      = ulid.Const("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	joined   = ulid.Const(prefix + "W89YSBG7NFPHABA4")
+
+	short    = ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA")         // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPHABA": 25 characters, want 26`
+	long     = ulid.MustParseStrict("01HTNMW2JAW89YSBG7NFPHABA44") // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPHABA44": 27 characters, want 26`
+	letterI  = ulid.Const("01HTNMW2JAW89YSBG7NFPHIBA4")            // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPHIBA4": character 23 'I' is not in the base32 alphabet`
+	letterU  = ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABAu")        // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPHABAu": character 26 'u' is not in the base32 alphabet`
+	overflow = ulid.Const("81HTNMW2JAW89YSBG7NFPHABA4")            // want `invalid ULID literal "81HTNMW2JAW89YSBG7NFPHABA4": character 1 '8' overflows 128 bits, the first character must be at most 7`
+	lower    = ulid.Const("01htnmw2jaw89ysbg7nfphaba4")            // want `lowercase ULID literal "01htnmw2jaw89ysbg7nfphaba4": use the canonical uppercase form`
+	mixed    = ulid.MustParse(prefix + "w89ysbg7nfphaba4")         // want `lowercase ULID literal "01HTNMW2JAw89ysbg7nfphaba4": use the canonical uppercase form`
+
+	// The invalid character of a concatenation is reported in the literal that
+	// contains it.
+	split = ulid.Const(prefix +
+		"W89YSBG7NFPH-BA4") // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPH-BA4": character 23 '-' is not in the base32 alphabet`
+
+	// Strings that are not constant are only reported for ulid.Const.
+	env     = ulid.MustParseStrict(os.Getenv("ID"))
+	dynamic = ulid.Const(os.Getenv("ID")) // want `ulid.Const of a string that is not constant: use ulid.ParseStrict`
+	bytes   = ulid.MustParse([]byte("not a ULID"))
+)
+
+func TestFixtures(t *testing.T) {
+	ulidtest.MustParse(t, "01HTNMW2JAW89YSBG7NFPHABA4")
+	ulidtest.MustParseStrict(t, "01HTNMW2JAW89YSBG7NFPHABA")  // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPHABA": 25 characters, want 26`
+	ulidtest.MustParse(t, "01HTNMW2JAW89YSBG7NFPHABAL")       // want `invalid ULID literal "01HTNMW2JAW89YSBG7NFPHABAL": character 26 'L' is not in the base32 alphabet`
+	ulidtest.MustParseStrict(t, "01htnmw2jaw89ysbg7nfphaba4") // want `lowercase ULID literal "01htnmw2jaw89ysbg7nfphaba4": use the canonical uppercase form`
+}
@@ -0,0 +1,11 @@
+package lowercase
+
+import "go.rtnl.ai/ulid"
+
+// With -lowercase, only the lowercase literals that are otherwise invalid are
+// reported.
+var (
+	lower   = ulid.Const("01htnmw2jaw89ysbg7nfphaba4")
+	mixed   = ulid.MustParse("01HTNMW2JAw89ysbg7nfphaba4")
+	invalid = ulid.MustParseStrict("01htnmw2jaw89ysbg7nfphabau") // want `invalid ULID literal "01htnmw2jaw89ysbg7nfphabau": character 26 'u' is not in the base32 alphabet`
+)
@@ -0,0 +1,495 @@
+// Package ulidcheck implements a static analyzer that reports case-sensitive
+// comparisons of ULID strings with external input, loose parsing of external
+// input with ulid.Parse, and raw conversions between ULIDs and bytes.
+//
+// Because the base32 encoding of ULIDs is case insensitive, the same ULID may
+// be stored or submitted in lowercase while String always returns uppercase, so
+// comparing the strings directly silently fails. The analyzer is deliberately
+// conservative to keep false positives low: input is only considered external
+// if it comes directly from an HTTP request, a command line flag or argument, or
+// an environment variable, or from a local variable defined from such a source.
+// Input that has been transformed in any way, e.g. by ulid.Canonicalize or
+// strings.ToUpper, is not reported.
+//
+// Raw conversions such as ulid.ULID(b) and [16]byte(id) are reported so that
+// byte-level conversions go through the audited helpers of the ulid package; the
+// conversion of a slice panics if it is too short. Only conversions to and from
+// the unnamed types [16]byte and []byte are reported, not conversions to other
+// types defined as arrays of 16 bytes.
+//
+// The string literals and constants passed to ulid.MustParse, ulid.MustParseStrict,
+// ulid.Const, and the MustParse functions of the ulidtest package are validated
+// as ULIDs, including literals concatenated from constants, so that a typo in a
+// fixture is reported at the exact character rather than panicking at run time.
+// Lowercase literals are reported unless the -lowercase flag is set.
+package ulidcheck
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"go.rtnl.ai/ulid"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	ulidPath     = "go.rtnl.ai/ulid"
+	ulidtestPath = "go.rtnl.ai/ulid/ulidtest"
+)
+
+const doc = `report case-sensitive comparisons and loose parsing of external ULID strings and raw byte conversions
+
+The ulidcheck analyzer reports:
+
+  - string equality (== or !=) between ulid.ULID.String() and a string from
+    external input, which fails if the input is not uppercase; compare with
+    ulid.EqualsString or ulid.Canonicalize the input first.
+  - calls to ulid.Parse or ulid.MustParse with a string from external input,
+    which silently accept invalid characters; use ulid.ParseStrict instead.
+  - conversions of a [16]byte or []byte to ulid.ULID and of a ulid.ULID to a
+    [16]byte; use ulid.FromArray, ulid.FromSlice, or the Array method instead.
+  - string literals and constants passed to ulid.MustParse, ulid.MustParseStrict,
+    ulid.Const, ulidtest.MustParse, or ulidtest.MustParseStrict that are not
+    valid ULIDs: of the wrong length, with a character outside of the Crockford
+    base32 alphabet, or that overflow 128 bits because the first character is
+    greater than 7. Lowercase literals are reported unless -lowercase is set,
+    and calls of ulid.Const with a string that is not constant are reported.
+
+External input is a string that comes directly from an HTTP request (form and
+query values, path values, and headers), a command line flag or argument, or an
+environment variable, or from a local variable defined from such a source.`
+
+// Analyzer reports case-sensitive comparisons and loose parsing of ULID strings
+// from external input and raw conversions between ULIDs and bytes.
+var Analyzer = &analysis.Analyzer{
+	Name:     "ulidcheck",
+	Doc:      doc,
+	URL:      "https://pkg.go.dev/go.rtnl.ai/ulid/analysis/ulidcheck",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// allowLowercase is the -lowercase flag of the analyzer.
+var allowLowercase bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&allowLowercase, "lowercase", false, "allow lowercase ULID literals")
+}
+
+// literals are the functions whose arguments are validated as ULID literals, keyed
+// by package path and function name, with the index of the argument.
+var literals = map[string]map[string]int{
+	ulidPath: {
+		"MustParse":       0,
+		"MustParseStrict": 0,
+		"Const":           0,
+	},
+	ulidtestPath: {
+		"MustParse":       1,
+		"MustParseStrict": 1,
+	},
+}
+
+// sources are the functions and methods whose string results are external input,
+// keyed by package path and then by the receiver type name (empty for functions)
+// and the function name.
+var sources = map[string]map[[2]string]bool{
+	"net/http": {
+		{"Request", "FormValue"}:     true,
+		{"Request", "PostFormValue"}: true,
+		{"Request", "PathValue"}:     true,
+		{"Header", "Get"}:            true,
+	},
+	"net/url": {
+		{"Values", "Get"}: true,
+	},
+	"flag": {
+		{"", "Arg"}:        true,
+		{"FlagSet", "Arg"}: true,
+	},
+	"os": {
+		{"", "Getenv"}: true,
+	},
+}
+
+// flagVars are the functions that bind a string variable to a command line flag.
+var flagVars = map[[2]string]bool{
+	{"", "StringVar"}:        true,
+	{"FlagSet", "StringVar"}: true,
+}
+
+type checker struct {
+	pass     *analysis.Pass
+	external map[types.Object]bool
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	c := &checker{pass: pass, external: make(map[types.Object]bool)}
+
+	// First find the local variables that are defined from external input so that
+	// their uses can be reported regardless of the order of declarations.
+	inspect.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE && len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					c.define(lhs, n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				for i, name := range n.Names {
+					c.define(name, n.Values[i])
+				}
+			}
+		case *ast.CallExpr:
+			// flag.StringVar(&v, ...) makes v external input.
+			if pkg, fn := c.callee(n); pkg == "flag" && flagVars[fn] && len(n.Args) > 0 {
+				if addr, ok := n.Args[0].(*ast.UnaryExpr); ok && addr.Op == token.AND {
+					if obj := c.object(addr.X); obj != nil {
+						c.external[obj] = true
+					}
+				}
+			}
+		}
+	})
+
+	inspect.Preorder([]ast.Node{(*ast.BinaryExpr)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			c.checkComparison(n)
+		case *ast.CallExpr:
+			c.checkParse(n)
+			c.checkConversion(n)
+			c.checkLiteral(n)
+		}
+	})
+	return nil, nil
+}
+
+func (c *checker) checkComparison(n *ast.BinaryExpr) {
+	if n.Op != token.EQL && n.Op != token.NEQ {
+		return
+	}
+
+	for _, pair := range [][2]ast.Expr{{n.X, n.Y}, {n.Y, n.X}} {
+		if c.isULIDString(pair[0]) && c.isExternal(pair[1]) {
+			c.pass.Reportf(n.Pos(), "case-sensitive comparison of ulid.ULID.String() with external input: use ulid.EqualsString or ulid.Canonicalize the input")
+			return
+		}
+	}
+}
+
+func (c *checker) checkParse(n *ast.CallExpr) {
+	pkg, fn := c.callee(n)
+	if pkg != ulidPath || fn[0] != "" || (fn[1] != "Parse" && fn[1] != "MustParse") || len(n.Args) != 1 {
+		return
+	}
+
+	if !c.isExternal(n.Args[0]) {
+		return
+	}
+
+	strict := fn[1] + "Strict"
+	fun := n.Fun
+	if sel, ok := fun.(*ast.SelectorExpr); ok {
+		fun = sel.Sel
+	}
+
+	c.pass.Report(analysis.Diagnostic{
+		Pos:     n.Pos(),
+		End:     n.End(),
+		Message: "ulid." + fn[1] + " of external input accepts invalid characters: use ulid." + strict,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Use ulid." + strict,
+			TextEdits: []analysis.TextEdit{{
+				Pos:     fun.End(),
+				End:     fun.End(),
+				NewText: []byte("Strict"),
+			}},
+		}},
+	})
+}
+
+// checkConversion reports conversions of a [16]byte or []byte to a ulid.ULID and
+// of a ulid.ULID to a [16]byte outside of the ulid package.
+func (c *checker) checkConversion(n *ast.CallExpr) {
+	if len(n.Args) != 1 || c.pass.Pkg.Path() == ulidPath {
+		return
+	}
+
+	tv, ok := c.pass.TypesInfo.Types[n.Fun]
+	if !ok || !tv.IsType() {
+		return
+	}
+
+	arg := n.Args[0]
+	to, from := tv.Type, c.pass.TypesInfo.TypeOf(arg)
+	if from == nil {
+		return
+	}
+
+	switch {
+	case isULID(to) && isByteArray(from):
+		d := analysis.Diagnostic{
+			Pos:     n.Pos(),
+			End:     n.End(),
+			Message: "raw conversion of [16]byte to ulid.ULID: use ulid.FromArray",
+		}
+
+		// Replace the type name of ulid.ULID(b), or ULID(b) with a dot import.
+		name, ok := ast.Unparen(n.Fun).(*ast.Ident)
+		if sel, isSel := ast.Unparen(n.Fun).(*ast.SelectorExpr); isSel {
+			name, ok = sel.Sel, true
+		}
+
+		if ok {
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Use ulid.FromArray",
+				TextEdits: []analysis.TextEdit{{Pos: name.Pos(), End: name.End(), NewText: []byte("FromArray")}},
+			}}
+		}
+		c.pass.Report(d)
+	case isULID(to) && isByteSlice(from):
+		c.pass.Reportf(n.Pos(), "raw conversion of []byte to ulid.ULID panics for short slices: use ulid.FromSlice")
+	case isULID(from) && isByteArray(to):
+		d := analysis.Diagnostic{
+			Pos:     n.Pos(),
+			End:     n.End(),
+			Message: "raw conversion of ulid.ULID to [16]byte: use the Array method",
+		}
+
+		// Only simple operands can be used as the receiver without parentheses.
+		switch ast.Unparen(arg).(type) {
+		case *ast.Ident, *ast.SelectorExpr, *ast.CallExpr, *ast.IndexExpr:
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Use the Array method",
+				TextEdits: []analysis.TextEdit{{Pos: n.Pos(), End: n.End(), NewText: []byte(types.ExprString(ast.Unparen(arg)) + ".Array()")}},
+			}}
+		}
+		c.pass.Report(d)
+	}
+}
+
+// checkLiteral validates the constant strings passed to the functions that parse
+// ULID literals and reports the position of the first invalid character.
+func (c *checker) checkLiteral(n *ast.CallExpr) {
+	pkg, fn := c.callee(n)
+	i, ok := literals[pkg][fn[1]]
+	if !ok || fn[0] != "" || len(n.Args) <= i {
+		return
+	}
+
+	arg := n.Args[i]
+	tv := c.pass.TypesInfo.Types[arg]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		if pkg == ulidPath && fn[1] == "Const" {
+			c.pass.Reportf(arg.Pos(), "ulid.Const of a string that is not constant: use ulid.ParseStrict")
+		}
+		return
+	}
+
+	s := constant.StringVal(tv.Value)
+	if len(s) != ulid.EncodedSize {
+		c.pass.Reportf(arg.Pos(), "invalid ULID literal %q: %d characters, want %d", s, len(s), ulid.EncodedSize)
+		return
+	}
+
+	lower := false
+	for j := 0; j < len(s); j++ {
+		ch := s[j]
+		if ch >= 'a' && ch <= 'z' {
+			lower = true
+			ch -= 'a' - 'A'
+		}
+
+		if strings.IndexByte(ulid.Encoding, ch) < 0 {
+			c.pass.Reportf(c.charPos(arg, j), "invalid ULID literal %q: character %d %q is not in the base32 alphabet", s, j+1, s[j])
+			return
+		}
+	}
+
+	if s[0] > '7' {
+		c.pass.Reportf(c.charPos(arg, 0), "invalid ULID literal %q: character 1 %q overflows 128 bits, the first character must be at most 7", s, s[0])
+		return
+	}
+
+	if !lower || allowLowercase {
+		return
+	}
+
+	d := analysis.Diagnostic{
+		Pos:     arg.Pos(),
+		End:     arg.End(),
+		Message: "lowercase ULID literal " + strconv.Quote(s) + ": use the canonical uppercase form",
+	}
+
+	if lit, ok := ast.Unparen(arg).(*ast.BasicLit); ok {
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Use the uppercase ULID",
+			TextEdits: []analysis.TextEdit{{Pos: lit.Pos(), End: lit.End(), NewText: []byte(strconv.Quote(strings.ToUpper(s)))}},
+		}}
+	}
+	c.pass.Report(d)
+}
+
+// charPos returns the position of the character at index i of the constant string
+// expression, descending into concatenations, or the position of the expression if
+// the character is not in a literal without escapes.
+func (c *checker) charPos(e ast.Expr, i int) token.Pos {
+	switch x := ast.Unparen(e).(type) {
+	case *ast.BasicLit:
+		// The characters of a literal are at their offsets after the opening quote
+		// only if the literal is raw or has no escapes.
+		if s, err := strconv.Unquote(x.Value); err == nil && (x.Value[0] == '`' || len(s) == len(x.Value)-2) {
+			return x.Pos() + token.Pos(1+i)
+		}
+	case *ast.BinaryExpr:
+		if tv := c.pass.TypesInfo.Types[x.X]; x.Op == token.ADD && tv.Value != nil && tv.Value.Kind() == constant.String {
+			if n := len(constant.StringVal(tv.Value)); i >= n {
+				return c.charPos(x.Y, i-n)
+			}
+			return c.charPos(x.X, i)
+		}
+	}
+	return e.Pos()
+}
+
+// isULIDString returns true if the expression is a call to ulid.ULID.String.
+func (c *checker) isULIDString(e ast.Expr) bool {
+	call, ok := ast.Unparen(e).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	pkg, fn := c.callee(call)
+	return pkg == ulidPath && fn == [2]string{"ULID", "String"}
+}
+
+// isExternal returns true if the string expression comes directly from external
+// input or from a local variable defined from external input.
+func (c *checker) isExternal(e ast.Expr) bool {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.CallExpr:
+		pkg, fn := c.callee(e)
+		return sources[pkg][fn]
+	case *ast.IndexExpr:
+		// os.Args[i]
+		if sel, ok := ast.Unparen(e.X).(*ast.SelectorExpr); ok {
+			if v, ok := c.pass.TypesInfo.Uses[sel.Sel].(*types.Var); ok && v.Pkg() != nil {
+				return v.Pkg().Path() == "os" && v.Name() == "Args"
+			}
+		}
+	case *ast.StarExpr:
+		// *flag.String(...) or *p where p := flag.String(...)
+		if call, ok := ast.Unparen(e.X).(*ast.CallExpr); ok {
+			pkg, fn := c.callee(call)
+			return pkg == "flag" && fn[1] == "String"
+		}
+		if obj := c.object(e.X); obj != nil {
+			return c.external[obj] && isPointer(obj.Type())
+		}
+	case *ast.Ident:
+		if obj := c.object(e); obj != nil {
+			return c.external[obj] && !isPointer(obj.Type())
+		}
+	}
+	return false
+}
+
+// define records that the variable is external input if its value is.
+func (c *checker) define(lhs, rhs ast.Expr) {
+	obj := c.object(lhs)
+	if obj == nil {
+		return
+	}
+
+	if c.isExternal(rhs) {
+		c.external[obj] = true
+		return
+	}
+
+	// p := flag.String(...) is a pointer to external input.
+	if call, ok := ast.Unparen(rhs).(*ast.CallExpr); ok {
+		if pkg, fn := c.callee(call); pkg == "flag" && fn[1] == "String" {
+			c.external[obj] = true
+		}
+	}
+}
+
+// object returns the variable referred to by the identifier, if any.
+func (c *checker) object(e ast.Expr) types.Object {
+	id, ok := ast.Unparen(e).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	if v, ok := c.pass.TypesInfo.ObjectOf(id).(*types.Var); ok && !v.IsField() {
+		return v
+	}
+	return nil
+}
+
+// callee returns the package path, receiver type name, and name of the function
+// or method called, or an empty package path if it is not a static call.
+func (c *checker) callee(call *ast.CallExpr) (pkg string, fn [2]string) {
+	f, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+	if !ok || f.Pkg() == nil {
+		return "", fn
+	}
+
+	fn[1] = f.Name()
+	if recv := f.Type().(*types.Signature).Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+
+		if named, ok := t.(*types.Named); ok {
+			fn[0] = named.Obj().Name()
+		}
+	}
+	return f.Pkg().Path(), fn
+}
+
+func isPointer(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Pointer)
+	return ok
+}
+
+// isULID returns true if the type is ulid.ULID.
+func isULID(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == ulidPath && obj.Name() == "ULID"
+}
+
+// isByteArray returns true if the type is the unnamed type [16]byte.
+func isByteArray(t types.Type) bool {
+	arr, ok := t.(*types.Array)
+	return ok && arr.Len() == 16 && isByte(arr.Elem())
+}
+
+// isByteSlice returns true if the type is the unnamed type []byte.
+func isByteSlice(t types.Type) bool {
+	slice, ok := t.(*types.Slice)
+	return ok && isByte(slice.Elem())
+}
+
+func isByte(t types.Type) bool {
+	basic, ok := t.(*types.Basic)
+	return ok && basic.Kind() == types.Byte
+}
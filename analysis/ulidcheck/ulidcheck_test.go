@@ -0,0 +1,52 @@
+package ulidcheck_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid/analysis/ulidcheck"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	results := analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), ulidcheck.Analyzer, "a", "literals")
+
+	// The invalid characters of literals are reported at their exact positions,
+	// e.g. "character 23 '-'" at the '-' of the literal.
+	checked := 0
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			_, char, ok := strings.Cut(d.Message, ": character ")
+			if !ok {
+				continue
+			}
+			want := char[strings.IndexByte(char, '\'')+1]
+
+			pos := result.Pass.Fset.Position(d.Pos)
+			src, err := os.ReadFile(pos.Filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if line := strings.Split(string(src), "\n")[pos.Line-1]; line[pos.Column-1] != want {
+				t.Errorf("%s: %s: reported at %q, want %q", pos, d.Message, line[pos.Column-1], want)
+			}
+			checked++
+		}
+	}
+
+	if checked == 0 {
+		t.Error("expected diagnostics of invalid characters")
+	}
+}
+
+// NOTE: this test is not parallel since it sets the flags of the analyzer.
+func TestAnalyzerLowercase(t *testing.T) {
+	if err := ulidcheck.Analyzer.Flags.Set("lowercase", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer ulidcheck.Analyzer.Flags.Set("lowercase", "false")
+
+	analysistest.Run(t, analysistest.TestData(), ulidcheck.Analyzer, "lowercase")
+}
@@ -0,0 +1,46 @@
+package ulid
+
+// The conversions between ULIDs and bytes in this file are the audited place for
+// byte-level conversions: the ulidcheck analyzer reports raw conversions such as
+// ULID(b) and [16]byte(id) outside of this package and suggests these helpers.
+
+// FromArray returns the ULID with the 16 bytes of b. Every array is a valid
+// ULID, so FromArray cannot fail.
+func FromArray(b [16]byte) ULID {
+	return ULID(b)
+}
+
+// Array returns the 16 bytes of the ULID as an array, which unlike Bytes does
+// not allocate or share memory with the ULID.
+func (id ULID) Array() [16]byte {
+	return [16]byte(id)
+}
+
+// FromSlice returns the ULID with the bytes of b, copying them. ErrDataSize is
+// returned if b is not exactly 16 bytes long, rather than panicking like the
+// conversion ULID(b) for shorter slices or silently ignoring the extra bytes of
+// longer ones.
+func FromSlice(b []byte) (id ULID, err error) {
+	if len(b) != len(id) {
+		return id, ErrDataSize
+	}
+	return ULID(b), nil
+}
+
+// MustFromSlice is a convenience function equivalent to FromSlice that panics on
+// failure instead of returning an error, e.g. for literals in tests. The panic
+// value is a *PanicError that wraps the error returned by FromSlice.
+func MustFromSlice(b []byte) ULID {
+	id, err := FromSlice(b)
+	if err != nil {
+		panic(newPanicError("MustFromSlice", b, err))
+	}
+	return id
+}
+
+// IsAllOnes returns true if all 16 bytes of the ULID are 0xFF, the largest ULID,
+// which is also what erased flash storage and some sentinel values decode to. Use
+// IsZero for ULIDs that were decoded from zeroed or unwritten storage.
+func (id ULID) IsAllOnes() bool {
+	return id == ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+}
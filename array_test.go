@@ -0,0 +1,95 @@
+package ulid_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestArray(t *testing.T) {
+	t.Parallel()
+
+	roundTrip := func(b [16]byte) bool {
+		id := ulid.FromArray(b)
+		if id.Array() != b || !bytes.Equal(id.Bytes(), b[:]) {
+			return false
+		}
+
+		fromSlice, err := ulid.FromSlice(b[:])
+		return err == nil && fromSlice == id && ulid.MustFromSlice(b[:]) == id
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The array is a copy that does not share memory with the ULID.
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	arr := id.Array()
+	arr[0] = 0xFF
+	if id == ulid.FromArray(arr) {
+		t.Error("expected the array to be a copy of the ULID")
+	}
+
+	// FromSlice copies the slice.
+	b := id.Bytes()
+	copied, _ := ulid.FromSlice(b)
+	b[0] = 0xFF
+	if copied != id {
+		t.Error("expected FromSlice to copy the slice")
+	}
+}
+
+func TestFromSliceErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 1, 15, 17, 26} {
+		if _, err := ulid.FromSlice(make([]byte, n)); err != ulid.ErrDataSize {
+			t.Errorf("%d bytes: got error %v, want %v", n, err, ulid.ErrDataSize)
+		}
+
+		testPanics(t, ulid.ErrDataSize, func() { ulid.MustFromSlice(make([]byte, n)) })
+	}
+
+	if _, err := ulid.FromSlice(nil); err != ulid.ErrDataSize {
+		t.Errorf("nil: got error %v, want %v", err, ulid.ErrDataSize)
+	}
+}
+
+func TestIsZeroAllOnes(t *testing.T) {
+	t.Parallel()
+
+	ones := ulid.MustFromSlice(bytes.Repeat([]byte{0xFF}, 16))
+	for _, tc := range []struct {
+		id         ulid.ULID
+		zero, ones bool
+	}{
+		{ulid.Zero, true, false},
+		{ones, false, true},
+		{ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"), false, true},
+		{ulid.MustParse("00000000000000000000000001"), false, false},
+		{ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZY"), false, false},
+		{ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), false, false},
+	} {
+		if got := tc.id.IsZero(); got != tc.zero {
+			t.Errorf("%s: got IsZero %t, want %t", tc.id, got, tc.zero)
+		}
+
+		if got := tc.id.IsAllOnes(); got != tc.ones {
+			t.Errorf("%s: got IsAllOnes %t, want %t", tc.id, got, tc.ones)
+		}
+	}
+}
+
+func TestArrayAllocs(t *testing.T) {
+	id := ulid.Make()
+	b := id.Bytes()
+	if n := testing.AllocsPerRun(10, func() {
+		_ = ulid.FromArray(id.Array())
+		_, _ = ulid.FromSlice(b)
+	}); n != 0 {
+		t.Errorf("expected no allocations, got %f", n)
+	}
+}
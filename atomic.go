@@ -0,0 +1,125 @@
+package ulid
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// AtomicULID is a ULID that can be loaded and updated atomically by multiple
+// goroutines without a mutex or the allocations of atomic.Value, e.g. to track the
+// latest ULID seen by a service as a high-water mark with StoreIfGreater. The zero
+// value holds the zero ULID and is ready to use; an AtomicULID must not be copied
+// after first use.
+//
+// A ULID is 128 bits, which is more than a single atomic word, so the ULID is
+// stored in two atomic.Uint64 words guarded by a sequence lock: writers make the
+// sequence odd while they update the words and even again when they are done,
+// and readers retry if the sequence was odd or changed while they read the words.
+// Every operation is linearizable: a Load never returns a torn value with the
+// words of two different stores, and the compare and the store of
+// CompareAndSwap and StoreIfGreater happen as one atomic step. Writers are
+// serialized with each other but do not wait for readers; readers never block
+// writers and only spin while a write is in progress. StoreIfGreater does not
+// write when the current value is already greater or equal, so high-water marks
+// that rarely advance mostly load.
+type AtomicULID struct {
+	seq atomic.Uint64
+	hi  atomic.Uint64
+	lo  atomic.Uint64
+}
+
+// Load atomically loads the ULID.
+func (a *AtomicULID) Load() ULID {
+	for {
+		seq := a.seq.Load()
+		if seq&1 == 0 {
+			hi, lo := a.hi.Load(), a.lo.Load()
+			if a.seq.Load() == seq {
+				return fromHalves(hi, lo)
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+// Store atomically stores the ULID.
+func (a *AtomicULID) Store(id ULID) {
+	seq := a.lock()
+	a.store(id)
+	a.seq.Store(seq + 1)
+}
+
+// Swap atomically stores the new ULID and returns the previous ULID.
+func (a *AtomicULID) Swap(id ULID) (old ULID) {
+	seq := a.lock()
+	old = fromHalves(a.hi.Load(), a.lo.Load())
+	a.store(id)
+	a.seq.Store(seq + 1)
+	return old
+}
+
+// CompareAndSwap atomically stores the new ULID if the current ULID equals old and
+// reports whether it did. Like the compare-and-swap of an atomic.Uint64, it only
+// compares values: if the ULID changed from old to another ULID and back to old
+// since it was loaded, the swap succeeds.
+func (a *AtomicULID) CompareAndSwap(old, new ULID) (swapped bool) {
+	hi, lo := halves(old)
+	if a.hi.Load() != hi || a.lo.Load() != lo {
+		// Each word is loaded atomically, so a mismatch of either word shows that
+		// the ULID was not old at that moment, even during a concurrent write.
+		return false
+	}
+
+	seq := a.lock()
+	if swapped = a.hi.Load() == hi && a.lo.Load() == lo; swapped {
+		a.store(new)
+	}
+	a.seq.Store(seq + 1)
+	return swapped
+}
+
+// StoreIfGreater atomically stores the ULID if it is greater than the current ULID
+// and reports whether it did, which advances a high-water mark: after concurrent
+// calls the AtomicULID holds the greatest of their ULIDs and the ULID it held
+// before. A ULID equal to the current ULID is not stored.
+func (a *AtomicULID) StoreIfGreater(id ULID) (stored bool) {
+	hi, lo := halves(id)
+	if !greater(hi, lo, a.Load()) {
+		// The ULID was not greater than the value that was loaded, so the call
+		// takes effect at the Load without writing; a concurrent Store or Swap
+		// that lowers the value afterwards is ordered after this call.
+		return false
+	}
+
+	seq := a.lock()
+	if stored = greater(hi, lo, fromHalves(a.hi.Load(), a.lo.Load())); stored {
+		a.hi.Store(hi)
+		a.lo.Store(lo)
+	}
+	a.seq.Store(seq + 1)
+	return stored
+}
+
+// lock waits until no write is in progress and makes the sequence odd, returning
+// the odd sequence; the write is finished by storing the next even sequence.
+func (a *AtomicULID) lock() uint64 {
+	for {
+		if seq := a.seq.Load(); seq&1 == 0 && a.seq.CompareAndSwap(seq, seq+1) {
+			return seq + 1
+		}
+		runtime.Gosched()
+	}
+}
+
+func (a *AtomicULID) store(id ULID) {
+	hi, lo := halves(id)
+	a.hi.Store(hi)
+	a.lo.Store(lo)
+}
+
+// greater reports whether the ULID of the halves is greater than id; the halves
+// compare in the order of the ULIDs.
+func greater(hi, lo uint64, id ULID) bool {
+	idHi, idLo := halves(id)
+	return hi > idHi || (hi == idHi && lo > idLo)
+}
@@ -0,0 +1,260 @@
+package ulid_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestAtomicULID(t *testing.T) {
+	t.Parallel()
+
+	a, b := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), ulid.MustParse("01JKEHNQPA0END3NHMFNPBB9WE")
+	var v ulid.AtomicULID
+	if got := v.Load(); got != ulid.Zero {
+		t.Fatalf("expected the zero value to hold the zero ULID, got %s", got)
+	}
+
+	v.Store(a)
+	if got := v.Load(); got != a {
+		t.Fatalf("got %s, want %s", got, a)
+	}
+
+	if old := v.Swap(b); old != a || v.Load() != b {
+		t.Fatalf("got %s and %s after a swap", old, v.Load())
+	}
+
+	// The swap only succeeds with the current ULID.
+	if v.CompareAndSwap(a, ulid.Zero) || v.Load() != b {
+		t.Fatalf("expected the swap with a stale ULID to fail, got %s", v.Load())
+	}
+
+	if !v.CompareAndSwap(b, a) || v.Load() != a {
+		t.Fatalf("expected the swap to succeed, got %s", v.Load())
+	}
+
+	// ABA: a ULID that changed and changed back swaps, since only values compare.
+	old := v.Load()
+	v.Store(b)
+	v.Store(a)
+	if !v.CompareAndSwap(old, b) || v.Load() != b {
+		t.Fatalf("expected the swap after A-B-A to succeed, got %s", v.Load())
+	}
+
+	// Swapping a ULID for itself succeeds and changes nothing.
+	if !v.CompareAndSwap(b, b) || v.Load() != b {
+		t.Fatalf("expected the swap of b for b to succeed, got %s", v.Load())
+	}
+}
+
+func TestAtomicULIDStoreIfGreater(t *testing.T) {
+	t.Parallel()
+
+	maxULID := ulid.ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+	// ULIDs that differ only in the low or the high half.
+	lowMax := ulid.ULID{7: 0, 8: 0xFF, 9: 0xFF, 10: 0xFF, 11: 0xFF, 12: 0xFF, 13: 0xFF, 14: 0xFF, 15: 0xFF}
+	highOne := ulid.ULID{7: 1}
+	testCases := []struct {
+		current, id ulid.ULID
+		stored      bool
+	}{
+		{ulid.Zero, ulid.Zero, false},
+		{ulid.Zero, ulid.ULID{15: 1}, true},
+		{ulid.ULID{15: 1}, ulid.Zero, false},
+		{lowMax, highOne, true},
+		{highOne, lowMax, false},
+		{highOne, highOne, false},
+		{ulid.ULID{0: 0x80}, ulid.ULID{0: 0x7F, 15: 0xFF}, false},
+		{ulid.ULID{0: 0x7F, 15: 0xFF}, ulid.ULID{0: 0x80}, true},
+		{maxULID, maxULID, false},
+		{ulid.Zero, maxULID, true},
+	}
+
+	for _, tc := range testCases {
+		var v ulid.AtomicULID
+		v.Store(tc.current)
+		want := tc.current
+		if tc.stored {
+			want = tc.id
+		}
+
+		// The comparison is the same as ULID.Compare.
+		if tc.stored != (tc.id.Compare(tc.current) > 0) {
+			t.Fatalf("bad test case %s > %s", tc.id, tc.current)
+		}
+
+		if stored := v.StoreIfGreater(tc.id); stored != tc.stored || v.Load() != want {
+			t.Errorf("%s > %s: got %t and %s", tc.id, tc.current, stored, v.Load())
+		}
+	}
+}
+
+func TestAtomicULIDConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// Goroutines advance the high-water mark with random ULIDs; the final ULID is
+	// the maximum of all of them and every stored ULID was greater than the last.
+	const goroutines, n = 16, 2000
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	batches := make([][]ulid.ULID, goroutines)
+	var max ulid.ULID
+	for i := range batches {
+		batches[i] = randomBatch(rng, n)
+		max, _ = ulid.MaxOf(append(batches[i], max)...)
+	}
+
+	var (
+		v      ulid.AtomicULID
+		wg     sync.WaitGroup
+		stored = make([][]ulid.ULID, goroutines)
+	)
+
+	for i := range batches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var prev ulid.ULID
+			for _, id := range batches[i] {
+				if v.StoreIfGreater(id) {
+					stored[i] = append(stored[i], id)
+				}
+
+				// Loads never go backwards.
+				cur := v.Load()
+				if cur.Compare(prev) < 0 {
+					t.Errorf("loaded %s after %s", cur, prev)
+					return
+				}
+				prev = cur
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := v.Load(); got != max {
+		t.Fatalf("got %s, want the maximum %s", got, max)
+	}
+
+	// Exactly one goroutine stored the maximum, and each goroutine stored
+	// increasing ULIDs.
+	var count int
+	for _, ids := range stored {
+		for i, id := range ids {
+			if id == max {
+				count++
+			}
+
+			if i > 0 && id.Compare(ids[i-1]) <= 0 {
+				t.Fatalf("stored %s after %s", id, ids[i-1])
+			}
+		}
+	}
+
+	if count != 1 {
+		t.Errorf("expected the maximum to be stored once, got %d", count)
+	}
+}
+
+func TestAtomicULIDTorn(t *testing.T) {
+	t.Parallel()
+
+	// Writers alternate between ULIDs whose halves both differ, so a torn load
+	// would return a ULID that was never stored.
+	a := ulid.ULID{0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	b := ulid.ULID{0, 0, 0, 0, 0, 0, 0, 1}
+
+	var (
+		v    ulid.AtomicULID
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	v.Store(a)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				switch cur := v.Load(); {
+				case i%2 == 0:
+					v.CompareAndSwap(cur, [2]ulid.ULID{a, b}[j%2])
+				default:
+					v.Swap([2]ulid.ULID{b, a}[j%2])
+				}
+			}
+		}(i)
+	}
+
+	for i := 0; i < 100000; i++ {
+		if got := v.Load(); got != a && got != b {
+			close(done)
+			wg.Wait()
+			t.Fatalf("loaded a torn ULID %s", got)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestAtomicULIDAllocs(t *testing.T) {
+	var v ulid.AtomicULID
+	id := ulid.Make()
+	allocs := testing.AllocsPerRun(100, func() {
+		v.Store(id)
+		v.StoreIfGreater(ulid.Max(id, v.Load()))
+		v.CompareAndSwap(id, v.Load())
+		v.Swap(id)
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkAtomicULID(b *testing.B) {
+	b.Run("Load", func(b *testing.B) {
+		var v ulid.AtomicULID
+		v.Store(ulid.Make())
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = v.Load()
+			}
+		})
+	})
+
+	b.Run("StoreIfGreater", func(b *testing.B) {
+		var v ulid.AtomicULID
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				v.StoreIfGreater(ulid.Make())
+			}
+		})
+	})
+
+	b.Run("Mutex", func(b *testing.B) {
+		var (
+			mu sync.Mutex
+			v  ulid.ULID
+		)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				id := ulid.Make()
+				mu.Lock()
+				if id.Compare(v) > 0 {
+					v = id
+				}
+				mu.Unlock()
+			}
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package ulid
+
+import (
+	"slices"
+	"time"
+)
+
+// DefaultAuditBucket is the default size of the time buckets that an OrderAudit
+// counts the inversions in.
+const DefaultAuditBucket = time.Hour
+
+// DefaultAuditSamples is the default number of inversions that an OrderAudit keeps
+// as samples in its report.
+const DefaultAuditSamples = 100
+
+// OrderAudit detects time travel in a table of ULIDs by comparing the order that
+// the IDs were inserted in, e.g. from an autoincrement column, to the order of
+// their timestamps. Pairs of sequence numbers and IDs are added in insertion order
+// and an ID whose timestamp is earlier than the latest timestamp added before it
+// by more than the tolerance is an inversion: it was issued after an ID with a
+// later timestamp, e.g. because the clock of its generator was stepped back.
+//
+// The audit streams: it only keeps the running maximum timestamp, a count for
+// each time bucket that contains an inversion, and a bounded sample of the
+// inversions, so a table with few incidents is audited in constant memory. To
+// find every inversion rather than a sample, use the inversions returned by Add.
+//
+// An OrderAudit is not safe for concurrent use.
+type OrderAudit struct {
+	tolerance uint64
+	bucket    uint64
+	samples   int
+	started   bool
+	prevSeq   int64
+	maxSeq    int64
+	maxID     ULID
+	buckets   map[uint64]uint64
+	report    OrderReport
+}
+
+// OrderReport is the result of an OrderAudit.
+type OrderReport struct {
+	Pairs         uint64           `json:"pairs"`             // Number of pairs added
+	Unordered     uint64           `json:"unordered"`         // Number of pairs whose sequence was not greater than the sequence before it
+	Inversions    uint64           `json:"inversions"`        // Number of pairs whose timestamp regressed by more than the tolerance
+	MaxRegression time.Duration    `json:"max_regression_ns"` // The largest regression of an inversion
+	Tolerance     time.Duration    `json:"tolerance_ns"`      // The regression that is allowed before a pair is an inversion
+	Bucket        time.Duration    `json:"bucket_ns"`         // The size of the time buckets
+	Buckets       []OrderBucket    `json:"buckets"`           // The buckets of the timestamps of the inversions, in time order
+	Samples       []OrderInversion `json:"samples"`           // The first inversions, in insertion order
+}
+
+// OrderBucket counts the inversions whose timestamps are in a time bucket.
+type OrderBucket struct {
+	Start time.Time `json:"start"` // The start of the bucket (UTC)
+	Count uint64    `json:"count"` // Number of inversions in the bucket
+}
+
+// OrderInversion is a pair whose timestamp regressed: the ID was inserted after
+// the ID with the latest timestamp before it but has an earlier timestamp.
+type OrderInversion struct {
+	Seq        int64         `json:"seq"`           // The sequence of the inverted ID
+	ID         ULID          `json:"id"`            // The inverted ID
+	MaxSeq     int64         `json:"max_seq"`       // The sequence of the ID with the latest timestamp before it
+	MaxID      ULID          `json:"max_id"`        // The ID with the latest timestamp before it
+	Regression time.Duration `json:"regression_ns"` // How far the timestamp of the ID is before the latest timestamp
+}
+
+// OrderAuditOption configures an OrderAudit.
+type OrderAuditOption func(*OrderAudit)
+
+// AuditTolerance sets how far a timestamp may be before the latest timestamp added
+// before it without being an inversion, e.g. to ignore the reordering of
+// concurrent inserts (default 0, where any earlier timestamp is an inversion).
+func AuditTolerance(tolerance time.Duration) OrderAuditOption {
+	return func(a *OrderAudit) {
+		a.tolerance = durationMillis(tolerance)
+	}
+}
+
+// AuditBucket sets the size of the time buckets that inversions are counted in
+// (default DefaultAuditBucket).
+func AuditBucket(size time.Duration) OrderAuditOption {
+	return func(a *OrderAudit) {
+		a.bucket = durationMillis(size)
+	}
+}
+
+// AuditSamples sets the number of inversions that are kept as samples in the
+// report (default DefaultAuditSamples); a negative number keeps none.
+func AuditSamples(n int) OrderAuditOption {
+	return func(a *OrderAudit) {
+		a.samples = max(n, 0)
+	}
+}
+
+// NewOrderAudit creates an audit of the order of ULIDs configured by the options.
+func NewOrderAudit(opts ...OrderAuditOption) *OrderAudit {
+	a := &OrderAudit{
+		bucket:  durationMillis(DefaultAuditBucket),
+		samples: DefaultAuditSamples,
+		buckets: make(map[uint64]uint64),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.bucket == 0 {
+		a.bucket = durationMillis(DefaultAuditBucket)
+	}
+	return a
+}
+
+// Add adds the ID that was inserted with the sequence, which must be called in
+// insertion order, and returns the inversion and true if the timestamp of the ID
+// regressed by more than the tolerance. Pairs whose sequence is not greater than
+// the sequence before them are counted as unordered, which shows that the pairs
+// were not added in insertion order, but are audited like any other pair.
+func (a *OrderAudit) Add(seq int64, id ULID) (inversion OrderInversion, inverted bool) {
+	a.report.Pairs++
+	if !a.started {
+		a.started, a.prevSeq, a.maxSeq, a.maxID = true, seq, seq, id
+		return inversion, false
+	}
+
+	if seq <= a.prevSeq {
+		a.report.Unordered++
+	}
+	a.prevSeq = seq
+
+	ms, maxMS := id.Time(), a.maxID.Time()
+	if ms >= maxMS {
+		if ms > maxMS {
+			a.maxSeq, a.maxID = seq, id
+		}
+		return inversion, false
+	}
+
+	regression := maxMS - ms
+	if regression <= a.tolerance {
+		return inversion, false
+	}
+
+	inversion = OrderInversion{
+		Seq:        seq,
+		ID:         id,
+		MaxSeq:     a.maxSeq,
+		MaxID:      a.maxID,
+		Regression: time.Duration(regression) * time.Millisecond,
+	}
+
+	a.report.Inversions++
+	a.report.MaxRegression = max(a.report.MaxRegression, inversion.Regression)
+	a.buckets[ms/a.bucket]++
+	if len(a.report.Samples) < a.samples {
+		a.report.Samples = append(a.report.Samples, inversion)
+	}
+	return inversion, true
+}
+
+// Report returns the report of the pairs that have been added so far; the audit
+// can continue to add pairs after a report.
+func (a *OrderAudit) Report() *OrderReport {
+	report := a.report
+	report.Tolerance = time.Duration(a.tolerance) * time.Millisecond
+	report.Bucket = time.Duration(a.bucket) * time.Millisecond
+	report.Samples = append([]OrderInversion{}, a.report.Samples...)
+	report.Buckets = make([]OrderBucket, 0, len(a.buckets))
+	for key, count := range a.buckets {
+		report.Buckets = append(report.Buckets, OrderBucket{Start: Time(key * a.bucket).UTC(), Count: count})
+	}
+
+	slices.SortFunc(report.Buckets, func(x, y OrderBucket) int {
+		return x.Start.Compare(y.Start)
+	})
+	return &report
+}
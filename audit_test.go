@@ -0,0 +1,167 @@
+package ulid_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestOrderAudit(t *testing.T) {
+	t.Parallel()
+
+	// Timestamps in milliseconds from an epoch, in insertion order: after seq 4
+	// the clock steps back by 5s, after seq 8 by 50ms (within the tolerance), and
+	// after seq 10 by 2h.
+	epoch := uint64(time.Date(2025, 2, 6, 21, 0, 0, 0, time.UTC).UnixMilli())
+	offsets := []uint64{0, 10, 20, 5000, 0, 1000, 4899, 5100, 5050, 7200000, 0, 1, 7200001}
+	ids := make([]ulid.ULID, len(offsets))
+	for i, ms := range offsets {
+		ids[i] = ulid.MustNew(epoch+ms, rand.Reader)
+	}
+
+	audit := ulid.NewOrderAudit(ulid.AuditTolerance(100*time.Millisecond), ulid.AuditSamples(3))
+	var inverted []int64
+	for i, id := range ids {
+		if inv, ok := audit.Add(int64(i+1), id); ok {
+			inverted = append(inverted, inv.Seq)
+		}
+	}
+
+	// seq 5 (5s), 6 (4s), and 7 (101ms) regress behind seq 4, and seq 11 and 12
+	// regress about 2h behind seq 10.
+	want := []int64{5, 6, 7, 11, 12}
+	if len(inverted) != len(want) {
+		t.Fatalf("got inversions at %v, want %v", inverted, want)
+	}
+
+	for i := range want {
+		if inverted[i] != want[i] {
+			t.Fatalf("got inversions at %v, want %v", inverted, want)
+		}
+	}
+
+	report := audit.Report()
+	if report.Pairs != uint64(len(ids)) || report.Inversions != 5 || report.Unordered != 0 {
+		t.Errorf("got %d pairs, %d inversions, and %d unordered", report.Pairs, report.Inversions, report.Unordered)
+	}
+
+	if report.MaxRegression != 2*time.Hour || report.Tolerance != 100*time.Millisecond || report.Bucket != ulid.DefaultAuditBucket {
+		t.Errorf("got max regression %s, tolerance %s, and bucket %s", report.MaxRegression, report.Tolerance, report.Bucket)
+	}
+
+	// The inversions are in the 21:00 bucket.
+	if len(report.Buckets) != 1 || report.Buckets[0].Count != 5 || !report.Buckets[0].Start.Equal(ulid.Time(epoch)) {
+		t.Errorf("got buckets %+v", report.Buckets)
+	}
+
+	// The first inversions are sampled with the ID with the latest timestamp
+	// before them.
+	if len(report.Samples) != 3 {
+		t.Fatalf("got %d samples, want 3", len(report.Samples))
+	}
+
+	first := report.Samples[0]
+	if first.Seq != 5 || first.ID != ids[4] || first.MaxSeq != 4 || first.MaxID != ids[3] || first.Regression != 5*time.Second {
+		t.Errorf("got sample %+v", first)
+	}
+
+	if last := report.Samples[2]; last.Seq != 7 || last.MaxSeq != 4 || last.Regression != 101*time.Millisecond {
+		t.Errorf("got sample %+v", last)
+	}
+}
+
+func TestOrderAuditBuckets(t *testing.T) {
+	t.Parallel()
+
+	// Each minute the clock steps back by its minute in seconds, so the
+	// regressions grow and each minute has one inversion.
+	start := uint64(time.Date(2025, 2, 6, 0, 0, 0, 0, time.UTC).UnixMilli())
+	audit := ulid.NewOrderAudit(ulid.AuditBucket(time.Minute), ulid.AuditSamples(-1))
+	var seq int64
+	for m := uint64(1); m <= 10; m++ {
+		ms := start + m*60000
+		for _, ts := range []uint64{ms + 30000, ms + 30000 - m*1000} {
+			seq++
+			audit.Add(seq, ulid.MustNew(ts, rand.Reader))
+		}
+	}
+
+	// Unordered pairs are counted but still audited.
+	audit.Add(seq, ulid.MustNew(start, rand.Reader))
+
+	report := audit.Report()
+	if report.Inversions != 11 || report.Unordered != 1 || len(report.Samples) != 0 {
+		t.Fatalf("got %d inversions, %d unordered, and %d samples", report.Inversions, report.Unordered, len(report.Samples))
+	}
+
+	if report.MaxRegression != 10*time.Minute+30*time.Second {
+		t.Errorf("got max regression %s", report.MaxRegression)
+	}
+
+	if len(report.Buckets) != 11 || report.Buckets[0].Start != ulid.Time(start).UTC() {
+		t.Fatalf("got %d buckets starting at %s", len(report.Buckets), report.Buckets[0].Start)
+	}
+
+	for i, b := range report.Buckets[1:] {
+		if want := ulid.Time(start + uint64(i+1)*60000).UTC(); b.Start != want || b.Count != 1 {
+			t.Errorf("bucket %d: got %d at %s, want 1 at %s", i+1, b.Count, b.Start, want)
+		}
+	}
+}
+
+func TestOrderAuditNone(t *testing.T) {
+	t.Parallel()
+
+	// Monotonic IDs, including IDs in the same millisecond, have no inversions.
+	audit := ulid.NewOrderAudit()
+	entropy := ulid.Monotonic(rand.Reader, 0)
+	ms := ulid.Now()
+	for i := 0; i < 1000; i++ {
+		audit.Add(int64(i), ulid.MustNew(ms+uint64(i/10), entropy))
+	}
+
+	report := audit.Report()
+	if report.Pairs != 1000 || report.Inversions != 0 || report.MaxRegression != 0 || len(report.Buckets) != 0 {
+		t.Errorf("got %+v", report)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"inversions":0,"max_regression_ns":0,"tolerance_ns":0,"bucket_ns":3600000000000,"buckets":[],"samples":[]`) {
+		t.Errorf("got %s", data)
+	}
+}
+
+func TestOrderReportJSON(t *testing.T) {
+	t.Parallel()
+
+	a, b := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), ulid.MustParse("01JKEHMRSH3HXYCYYZ1HZR2JBS")
+	audit := ulid.NewOrderAudit()
+	audit.Add(1, a)
+	audit.Add(2, b)
+
+	data, err := json.Marshal(audit.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report ulid.OrderReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Samples) != 1 || report.Samples[0].ID != b || report.Samples[0].MaxID != a || report.Samples[0].Regression != time.Duration(a.Time()-b.Time())*time.Millisecond {
+		t.Errorf("got %s", data)
+	}
+
+	if !strings.Contains(string(data), `"samples":[{"seq":2,"id":"01JKEHMRSH3HXYCYYZ1HZR2JBS","max_seq":1,"max_id":"01JKEHNQPA0END3NHMFKB2Y6SE","regression_ns":`) {
+		t.Errorf("got %s", data)
+	}
+}
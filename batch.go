@@ -0,0 +1,306 @@
+package ulid
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// Batch generates ULIDs that all share the same timestamp, e.g. so that the rows
+// of a batch insert have partition locality, with strictly increasing entropy
+// within the batch. A Batch is not safe for concurrent use, but concurrent
+// batches created from the same PoolEntropy do not share state.
+type Batch struct {
+	ms      uint64
+	err     error
+	entropy MonotonicReader
+	pool    *PoolEntropy
+	pooled  io.Reader
+	last    ULID
+	count   uint64
+	closed  bool
+}
+
+// NewBatchAt creates a batch whose ULIDs all have the timestamp t. The entropy
+// source determines how entropy is generated for the batch:
+//
+//   - If entropy is nil, DefaultEntropy is used.
+//   - If entropy is a *PoolEntropy (such as DefaultEntropy and SecureEntropy), a
+//     reader is checked out of the pool for the lifetime of the batch and is
+//     returned to the pool when the batch is closed. The pooled readers must be
+//     MonotonicReaders for the batch to be strictly ordered.
+//   - If entropy is a MonotonicReader it is used directly and must not be used
+//     concurrently by another batch.
+//   - Otherwise the reader is wrapped with Monotonic.
+//
+// If t cannot be represented in a ULID the error is returned by Next.
+func NewBatchAt(t time.Time, entropy io.Reader) *Batch {
+	b := &Batch{}
+	b.ms, b.err = TimestampChecked(t)
+
+	if entropy == nil {
+		entropy = DefaultEntropy()
+	}
+
+	if pool, ok := entropy.(*PoolEntropy); ok {
+		b.pool = pool
+		b.pooled = pool.Get()
+		entropy = b.pooled
+	}
+
+	switch e := entropy.(type) {
+	case MonotonicReader:
+		b.entropy = e
+	default:
+		b.entropy = Monotonic(e, 0)
+	}
+	return b
+}
+
+// Next returns the next ULID in the batch, which has the batch timestamp and sorts
+// strictly after every previous ULID in the batch. ErrMonotonicOverflow is
+// returned when the entropy space of the batch timestamp is exhausted and
+// ErrBatchClosed is returned after the batch has been closed.
+func (b *Batch) Next() (id ULID, err error) {
+	if b.closed {
+		return Zero, ErrBatchClosed
+	}
+
+	if b.err != nil {
+		return Zero, b.err
+	}
+
+	if id, err = New(b.ms, b.entropy); err != nil {
+		return Zero, err
+	}
+
+	// Guard against readers that do not continue a previous sequence at the
+	// same timestamp (e.g. a pooled reader used at a different time).
+	if b.count > 0 && id.Compare(b.last) <= 0 {
+		return Zero, ErrMonotonicOverflow
+	}
+
+	b.last = id
+	b.count++
+	return id, nil
+}
+
+// Time returns the timestamp shared by all ULIDs in the batch in Unix milliseconds.
+func (b *Batch) Time() uint64 {
+	return b.ms
+}
+
+// Count returns the number of ULIDs generated by the batch so far.
+func (b *Batch) Count() uint64 {
+	return b.count
+}
+
+// Remaining returns an upper bound on the number of ULIDs that can still be
+// generated by the batch before the entropy space overflows, saturating at
+// math.MaxUint64. The actual number depends on the increments used by the
+// monotonic entropy source; with the default increment it is far smaller.
+func (b *Batch) Remaining() uint64 {
+	if b.closed || b.err != nil {
+		return 0
+	}
+
+	if b.count == 0 {
+		return math.MaxUint64
+	}
+
+	var last uint80
+	last.SetBytes(b.last[6:])
+	if last.Hi != math.MaxUint16 {
+		return math.MaxUint64
+	}
+	return math.MaxUint64 - last.Lo
+}
+
+// Close releases the entropy reader checked out from a pool, if any. Calling
+// Next after Close returns ErrBatchClosed; Close may be called multiple times.
+func (b *Batch) Close() error {
+	if b.closed {
+		return nil
+	}
+
+	b.closed = true
+	if b.pool != nil {
+		b.pool.Put(b.pooled)
+		b.pool, b.pooled = nil, nil
+	}
+
+	b.entropy = nil
+	return nil
+}
+
+// EncodeBatchText appends the text encoding of each ULID to dst, separated by the
+// sep byte (no separator is appended after the last ULID), and returns the
+// extended buffer. The buffer is grown once for the whole batch and each ULID is
+// encoded directly into it, avoiding the per-ID bounds checks and buffer checks
+// of calling MarshalTextTo in a loop. ErrInvalidSeparator is returned if sep is a
+// character of the base32 encoding, which could not be told apart from the ULIDs.
+func EncodeBatchText(ids []ULID, dst []byte, sep byte) ([]byte, error) {
+	if dec[sep] != 0xFF {
+		return dst, ErrInvalidSeparator
+	}
+
+	if len(ids) == 0 {
+		return dst, nil
+	}
+
+	off := len(dst)
+	size := len(ids)*(EncodedSize+1) - 1
+	dst = slices.Grow(dst, size)[:off+size]
+
+	for i := range ids {
+		encodeRegisters((*[EncodedSize]byte)(dst[off:off+EncodedSize]), &ids[i])
+		off += EncodedSize
+
+		if off < len(dst) {
+			dst[off] = sep
+			off++
+		}
+	}
+	return dst, nil
+}
+
+// DecodeBatchText strictly parses data containing text encoded ULIDs separated by
+// the sep byte, the inverse of EncodeBatchText. A single trailing separator is
+// allowed, so both empty data and data containing only the separator decode to an
+// empty batch; any other empty record is an error. If a ULID cannot be parsed a
+// *BatchError is returned that reports the index of the record and its byte
+// offset in data, along with the ULIDs that were successfully decoded before the
+// error. Like EncodeBatchText, ErrInvalidSeparator is returned if sep is a
+// character of the base32 encoding.
+func DecodeBatchText(data []byte, sep byte) (ids []ULID, err error) {
+	if dec[sep] != 0xFF {
+		return nil, ErrInvalidSeparator
+	}
+
+	if len(data) > 0 && data[len(data)-1] == sep {
+		data = data[:len(data)-1]
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	ids = make([]ULID, 0, (len(data)+1)/(EncodedSize+1))
+	for off := 0; off <= len(data); {
+		end := off + EncodedSize
+		if end > len(data) || (end < len(data) && data[end] != sep) {
+			return ids, &BatchError{Index: len(ids), Offset: int64(off), Err: ErrDataSize}
+		}
+
+		var id ULID
+		if err = parse(data[off:end], true, &id); err != nil {
+			return ids, &BatchError{Index: len(ids), Offset: int64(off), Err: err}
+		}
+
+		ids = append(ids, id)
+		off = end + 1
+	}
+	return ids, nil
+}
+
+// TimesUnixMilli appends the Unix milliseconds timestamp of each ULID to dst and
+// returns the extended slice, e.g. to reuse a buffer with dst[:0]. The slice is
+// grown once for the whole batch, so it does not allocate if dst has capacity.
+func TimesUnixMilli(ids []ULID, dst []uint64) []uint64 {
+	off := len(dst)
+	dst = slices.Grow(dst, len(ids))[:off+len(ids)]
+	for i := range ids {
+		dst[off+i] = ids[i].Time()
+	}
+	return dst
+}
+
+// AgesSince appends the age of each ULID at now in seconds to dst and returns the
+// extended slice, e.g. to observe the ages of events in a histogram. The ages are
+// computed from the millisecond timestamps directly rather than by constructing a
+// time.Time for each ULID, and are identical to now.Sub(Time(id.Time())).Seconds()
+// where that duration does not overflow, including the sub-millisecond part of
+// now. The ages of ULIDs with timestamps after now are negative rather than
+// clamped to zero, so that events from hosts with clocks ahead of now (or with
+// timestamps advanced by BumpOnOverflow) can be detected; clamp the ages before
+// observing them if necessary. Like TimesUnixMilli it does not allocate if dst
+// has capacity.
+func AgesSince(ids []ULID, now time.Time, dst []float64) []float64 {
+	s, ns := now.Unix(), int64(now.Nanosecond())
+	off := len(dst)
+	dst = slices.Grow(dst, len(ids))[:off+len(ids)]
+	for i := range ids {
+		ms := ids[i].Time()
+		sec := s - int64(ms/1000)
+		nsec := ns - int64(ms%1000)*int64(time.Millisecond)
+
+		// Give both parts the same sign as time.Duration.Seconds does, so that the
+		// floating point result is identical.
+		switch {
+		case sec > 0 && nsec < 0:
+			sec, nsec = sec-1, nsec+int64(time.Second)
+		case sec < 0 && nsec > 0:
+			sec, nsec = sec+1, nsec-int64(time.Second)
+		}
+		dst[off+i] = float64(sec) + float64(nsec)/1e9
+	}
+	return dst
+}
+
+// encodeRegisters encodes the ULID by loading the 48 bit timestamp and the two
+// 40 bit halves of the entropy into uint64 registers and extracting 5 bits per
+// character, rather than combining bits from adjacent bytes for every character.
+func encodeRegisters(dst *[EncodedSize]byte, id *ULID) {
+	t := uint64(id[0])<<40 | uint64(id[1])<<32 | uint64(id[2])<<24 |
+		uint64(id[3])<<16 | uint64(id[4])<<8 | uint64(id[5])
+	dst[0] = Encoding[(t>>45)&31]
+	dst[1] = Encoding[(t>>40)&31]
+	dst[2] = Encoding[(t>>35)&31]
+	dst[3] = Encoding[(t>>30)&31]
+	dst[4] = Encoding[(t>>25)&31]
+	dst[5] = Encoding[(t>>20)&31]
+	dst[6] = Encoding[(t>>15)&31]
+	dst[7] = Encoding[(t>>10)&31]
+	dst[8] = Encoding[(t>>5)&31]
+	dst[9] = Encoding[t&31]
+
+	e := uint64(id[6])<<32 | uint64(id[7])<<24 | uint64(id[8])<<16 |
+		uint64(id[9])<<8 | uint64(id[10])
+	dst[10] = Encoding[(e>>35)&31]
+	dst[11] = Encoding[(e>>30)&31]
+	dst[12] = Encoding[(e>>25)&31]
+	dst[13] = Encoding[(e>>20)&31]
+	dst[14] = Encoding[(e>>15)&31]
+	dst[15] = Encoding[(e>>10)&31]
+	dst[16] = Encoding[(e>>5)&31]
+	dst[17] = Encoding[e&31]
+
+	e = uint64(id[11])<<32 | uint64(id[12])<<24 | uint64(id[13])<<16 |
+		uint64(id[14])<<8 | uint64(id[15])
+	dst[18] = Encoding[(e>>35)&31]
+	dst[19] = Encoding[(e>>30)&31]
+	dst[20] = Encoding[(e>>25)&31]
+	dst[21] = Encoding[(e>>20)&31]
+	dst[22] = Encoding[(e>>15)&31]
+	dst[23] = Encoding[(e>>10)&31]
+	dst[24] = Encoding[(e>>5)&31]
+	dst[25] = Encoding[e&31]
+}
+
+// BatchError reports the position of a record that could not be processed in a
+// batch operation. The underlying error can be checked with errors.Is.
+type BatchError struct {
+	Index  int   // The index of the record in the batch
+	Offset int64 // The byte offset of the start of the record in the input
+	Err    error // The underlying error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%s (record %d at offset %d)", e.Err, e.Index, e.Offset)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
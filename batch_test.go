@@ -0,0 +1,431 @@
+package ulid_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestEncodeBatchText(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, n := range []int{0, 1, 2, 17, 1000} {
+		ids := randomBatch(rng, n)
+		for _, prefix := range [][]byte{nil, []byte("prefix:")} {
+			want := naiveEncodeBatchText(ids, bytes.Clone(prefix), '\n')
+			got, err := ulid.EncodeBatchText(ids, bytes.Clone(prefix), '\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Fatalf("batch of %d: encoded output does not match naive loop", n)
+			}
+		}
+	}
+}
+
+func TestDecodeBatchText(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, n := range []int{1, 2, 17, 1000} {
+		ids := randomBatch(rng, n)
+		data, _ := ulid.EncodeBatchText(ids, nil, ',')
+
+		for _, input := range [][]byte{data, append(bytes.Clone(data), ',')} {
+			got, err := ulid.DecodeBatchText(input, ',')
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(ids) {
+				t.Fatalf("got %d ulids, want %d", len(got), len(ids))
+			}
+
+			for i := range ids {
+				if got[i] != ids[i] {
+					t.Fatalf("ulid %d: got %s, want %s", i, got[i], ids[i])
+				}
+			}
+		}
+	}
+
+	// A lone trailing separator is the same empty batch as no data at all.
+	for _, data := range []string{"", ","} {
+		if ids, err := ulid.DecodeBatchText([]byte(data), ','); err != nil || len(ids) != 0 {
+			t.Errorf("%q: expected empty batch, got %v (%v)", data, ids, err)
+		}
+	}
+}
+
+func TestDecodeBatchTextErrors(t *testing.T) {
+	t.Parallel()
+
+	a, b := "01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFNPBB9WE"
+	for _, tc := range []struct {
+		name   string
+		input  string
+		index  int
+		offset int64
+		err    error
+	}{
+		{"Short", a + "," + b[:20], 1, 27, ulid.ErrDataSize},
+		{"Long", a + "," + b + "X," + a, 1, 27, ulid.ErrDataSize},
+		{"Empty", a + ",," + b, 1, 27, ulid.ErrDataSize},
+		{"DoubleTrailing", a + ",,", 1, 27, ulid.ErrDataSize},
+		{"OnlySeparators", ",,", 0, 0, ulid.ErrDataSize},
+		{"Leading", "," + a, 0, 0, ulid.ErrDataSize},
+		{"InvalidCharacters", a + "," + b + "," + a[:10] + "UUUUUUUUUUUUUUUU", 2, 54, ulid.ErrInvalidCharacters},
+		{"Overflow", "8" + a[1:], 0, 0, ulid.ErrOverflow},
+		{"WrongSeparator", a + ";" + b, 0, 0, ulid.ErrDataSize},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ids, err := ulid.DecodeBatchText([]byte(tc.input), ',')
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got err %v, want %v", err, tc.err)
+			}
+
+			var berr *ulid.BatchError
+			if !errors.As(err, &berr) {
+				t.Fatalf("expected a *BatchError, got %T", err)
+			}
+
+			if berr.Index != tc.index || berr.Offset != tc.offset {
+				t.Errorf("got record %d at offset %d, want record %d at offset %d", berr.Index, berr.Offset, tc.index, tc.offset)
+			}
+
+			if len(ids) != tc.index {
+				t.Errorf("expected %d ulids decoded before the error, got %d", tc.index, len(ids))
+			}
+		})
+	}
+}
+
+func TestBatchTextSeparator(t *testing.T) {
+	t.Parallel()
+
+	// A separator that is a base32 character, in either case, is rejected.
+	ids := randomBatch(rand.New(rand.NewSource(1364)), 2)
+	for _, sep := range []byte{'0', '9', 'A', 'z', 'h'} {
+		if _, err := ulid.EncodeBatchText(ids, nil, sep); !errors.Is(err, ulid.ErrInvalidSeparator) {
+			t.Errorf("%q: got %v, want %v", sep, err, ulid.ErrInvalidSeparator)
+		}
+
+		if _, err := ulid.DecodeBatchText([]byte(ids[0].String()), sep); !errors.Is(err, ulid.ErrInvalidSeparator) {
+			t.Errorf("%q: got %v, want %v", sep, err, ulid.ErrInvalidSeparator)
+		}
+	}
+
+	// Characters outside of the encoding, including I, L, O, and U, are allowed.
+	for _, sep := range []byte{0, ' ', '\t', '|', 'I', 'u', 0xFF} {
+		data, err := ulid.EncodeBatchText(ids, nil, sep)
+		if err != nil {
+			t.Fatalf("%q: %v", sep, err)
+		}
+
+		if got, err := ulid.DecodeBatchText(append(data, sep), sep); err != nil || !slices.Equal(got, ids) {
+			t.Errorf("%q: got %v (%v), want %v", sep, got, err, ids)
+		}
+	}
+}
+
+func TestTimesUnixMilli(t *testing.T) {
+	t.Parallel()
+
+	ids := randomBatch(rand.New(rand.NewSource(time.Now().UnixNano())), 1000)
+	for _, prefix := range [][]uint64{nil, {42}} {
+		got := ulid.TimesUnixMilli(ids, slices.Clone(prefix))
+		if len(got) != len(prefix)+len(ids) || !slices.Equal(got[:len(prefix)], prefix) {
+			t.Fatalf("expected %d timestamps after the prefix, got %d", len(ids), len(got)-len(prefix))
+		}
+
+		for i, id := range ids {
+			if ms := got[len(prefix)+i]; ms != id.Time() {
+				t.Fatalf("ulid %d: got %d, want %d", i, ms, id.Time())
+			}
+		}
+	}
+}
+
+func TestAgesSince(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	now := time.Date(2025, 2, 6, 21, 11, 53, 290_654_321, time.UTC)
+	nowMs := ulid.Timestamp(now)
+
+	// Ages around now in both directions, within the range of time.Duration.
+	ids := []ulid.ULID{
+		ulid.MustNew(nowMs, nil), ulid.MustNew(nowMs-1, nil), ulid.MustNew(nowMs+1, nil),
+		ulid.MustNew(nowMs-1000, nil), ulid.MustNew(nowMs+1000, nil), ulid.Zero,
+	}
+	for i := 0; i < 1000; i++ {
+		offset := rng.Int63n(2*365*24*3600*1000) - 365*24*3600*1000
+		ids = append(ids, ulid.MustNew(uint64(int64(nowMs)+offset), rng))
+	}
+
+	for _, at := range []time.Time{now, now.Truncate(time.Millisecond), now.Add(999_999 * time.Nanosecond)} {
+		ages := ulid.AgesSince(ids, at, nil)
+		if len(ages) != len(ids) {
+			t.Fatalf("got %d ages, want %d", len(ages), len(ids))
+		}
+
+		for i, id := range ids {
+			if want := at.Sub(ulid.Time(id.Time())).Seconds(); ages[i] != want {
+				t.Fatalf("%s at %s: got age %v, want %v", id, at, ages[i], want)
+			}
+		}
+	}
+
+	// The ages of future ULIDs are negative and the sub-millisecond part of now is
+	// not rounded away.
+	ages := ulid.AgesSince(ids[:3], now, []float64{42})
+	if want := []float64{42, 0.000654321, 0.001654321, -0.000345679}; !slices.Equal(ages, want) {
+		t.Errorf("got ages %v, want %v", ages, want)
+	}
+
+	// Ages beyond the range of time.Duration are still computed.
+	if ages := ulid.AgesSince([]ulid.ULID{ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")}, ulid.Time(0), nil); ages[0] != -float64(ulid.MaxTime())/1000 {
+		t.Errorf("got age %v for the largest timestamp", ages[0])
+	}
+}
+
+func TestAgesSinceAllocs(t *testing.T) {
+	ids := randomBatch(rand.New(rand.NewSource(1)), 1024)
+	ages, times := make([]float64, 0, len(ids)), make([]uint64, 0, len(ids))
+	now := time.Now()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ages = ulid.AgesSince(ids, now, ages[:0])
+		times = ulid.TimesUnixMilli(ids, times[:0])
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations with a destination of sufficient capacity, got %v", allocs)
+	}
+}
+
+func BenchmarkEncodeBatchText(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 1024)
+	buf := make([]byte, 0, len(ids)*(ulid.EncodedSize+1))
+
+	b.Run("Batch", func(b *testing.B) {
+		b.SetBytes(int64(len(ids) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			_, _ = ulid.EncodeBatchText(ids, buf[:0], '\n')
+		}
+	})
+
+	b.Run("Naive", func(b *testing.B) {
+		b.SetBytes(int64(len(ids) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			_ = naiveEncodeBatchText(ids, buf[:0], '\n')
+		}
+	})
+}
+
+func BenchmarkDecodeBatchText(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	data, _ := ulid.EncodeBatchText(randomBatch(rng, 1024), nil, '\n')
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ulid.DecodeBatchText(data, '\n')
+	}
+}
+
+func BenchmarkAgesSince(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 1024)
+	ages := make([]float64, 0, len(ids))
+	now := time.Now()
+
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ages = ulid.AgesSince(ids, now, ages[:0])
+		}
+	})
+
+	b.Run("Naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ages = ages[:0]
+			for _, id := range ids {
+				ages = append(ages, now.Sub(ulid.Time(id.Time())).Seconds())
+			}
+		}
+	})
+}
+
+// naiveEncodeBatchText is the per-ID MarshalTextTo loop that EncodeBatchText
+// replaces; the output must be identical.
+func naiveEncodeBatchText(ids []ulid.ULID, dst []byte, sep byte) []byte {
+	for i, id := range ids {
+		if i > 0 {
+			dst = append(dst, sep)
+		}
+
+		off := len(dst)
+		dst = append(dst, make([]byte, ulid.EncodedSize)...)
+		_ = id.MarshalTextTo(dst[off:])
+	}
+	return dst
+}
+
+func randomBatch(rng *rand.Rand, n int) []ulid.ULID {
+	ids := make([]ulid.ULID, n)
+	for i := range ids {
+		ids[i] = ulid.MustNew(uint64(rng.Int63n(int64(ulid.MaxTime()))), rng)
+	}
+	return ids
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2025, 2, 7, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		entropy io.Reader
+	}{
+		{"Default", nil},
+		{"Secure", ulid.SecureEntropy()},
+		{"Monotonic", ulid.Monotonic(rand.New(rand.NewSource(1)), 0)},
+		{"Reader", rand.New(rand.NewSource(1))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			batch := ulid.NewBatchAt(ts, tc.entropy)
+			defer batch.Close()
+
+			if batch.Time() != ulid.Timestamp(ts) {
+				t.Fatalf("got batch time %d, want %d", batch.Time(), ulid.Timestamp(ts))
+			}
+
+			var prev ulid.ULID
+			for i := 0; i < 1000; i++ {
+				id, err := batch.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if id.Time() != batch.Time() {
+					t.Fatalf("ulid %d: got time %d, want %d", i, id.Time(), batch.Time())
+				}
+
+				if i > 0 && prev.Compare(id) >= 0 {
+					t.Fatalf("ulid %d: expected %s < %s", i, prev, id)
+				}
+				prev = id
+			}
+
+			if batch.Count() != 1000 {
+				t.Errorf("got count %d, want 1000", batch.Count())
+			}
+
+			if batch.Remaining() == 0 {
+				t.Error("expected remaining entropy space")
+			}
+
+			if err := batch.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := batch.Next(); err != ulid.ErrBatchClosed {
+				t.Errorf("got err %v, want %v", err, ulid.ErrBatchClosed)
+			}
+
+			if batch.Remaining() != 0 {
+				t.Error("expected no remaining ulids after close")
+			}
+
+			if err := batch.Close(); err != nil {
+				t.Errorf("expected close to be idempotent, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBatchOverflow(t *testing.T) {
+	t.Parallel()
+
+	var start [10]byte
+	for i := range start {
+		start[i] = 0xFF
+	}
+	start[9] = 0xFD
+
+	batch := ulid.NewBatchAt(time.Unix(1, 0), ulid.SequentialEntropy(start))
+	if batch.Remaining() != math.MaxUint64 {
+		t.Errorf("got remaining %d for new batch, want max", batch.Remaining())
+	}
+
+	for i := uint64(3); i > 0; i-- {
+		if _, err := batch.Next(); err != nil {
+			t.Fatal(err)
+		}
+
+		if batch.Remaining() != i-1 {
+			t.Errorf("got remaining %d, want %d", batch.Remaining(), i-1)
+		}
+	}
+
+	if _, err := batch.Next(); err != ulid.ErrMonotonicOverflow {
+		t.Errorf("got err %v, want %v", err, ulid.ErrMonotonicOverflow)
+	}
+
+	batch = ulid.NewBatchAt(time.Time{}, nil)
+	if _, err := batch.Next(); err != ulid.ErrSmallTime {
+		t.Errorf("got err %v, want %v", err, ulid.ErrSmallTime)
+	}
+}
+
+func TestConcurrentBatches(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Now()
+	results := make([][]ulid.ULID, 8)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			batch := ulid.NewBatchAt(ts, ulid.DefaultEntropy())
+			defer batch.Close()
+
+			for j := 0; j < 512; j++ {
+				id, err := batch.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[i] = append(results[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[ulid.ULID]struct{})
+	for _, ids := range results {
+		for j, id := range ids {
+			if j > 0 && ids[j-1].Compare(id) >= 0 {
+				t.Fatalf("batch is not strictly ordered: %s >= %s", ids[j-1], id)
+			}
+
+			if _, ok := seen[id]; ok {
+				t.Fatalf("duplicate ulid %s across batches", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
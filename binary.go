@@ -0,0 +1,165 @@
+package ulid
+
+import (
+	"bufio"
+	"io"
+	"iter"
+	"unsafe"
+)
+
+// Compile time guarantees that a ULID is exactly 16 bytes with no padding so that
+// its memory layout is its big-endian binary encoding.
+var (
+	_ [unsafe.Sizeof(ULID{}) - 16]struct{}
+	_ [16 - unsafe.Sizeof(ULID{})]struct{}
+	_ [1 - unsafe.Alignof(ULID{})]struct{}
+)
+
+var (
+	_ io.ReaderFrom = (*ULID)(nil)
+	_ io.WriterTo   = ULID{}
+)
+
+// ReadFrom implements the io.ReaderFrom interface by reading exactly 16 bytes of
+// binary encoded ULID from r, e.g. when reading a stream of packed binary ULIDs.
+// Unlike most implementations of io.ReaderFrom, ReadFrom does not read until EOF:
+// it returns io.EOF if no bytes could be read so that the end of a stream can be
+// detected, or io.ErrUnexpectedEOF if fewer than 16 bytes were read. The ULID is
+// only modified if all 16 bytes were read.
+func (id *ULID) ReadFrom(r io.Reader) (n int64, err error) {
+	var buf ULID
+	nn, err := io.ReadFull(r, buf[:])
+	if err != nil {
+		return int64(nn), err
+	}
+
+	*id = buf
+	return int64(nn), nil
+}
+
+// WriteTo implements the io.WriterTo interface by writing the 16 byte binary
+// encoding of the ULID to w.
+func (id ULID) WriteTo(w io.Writer) (n int64, err error) {
+	nn, err := w.Write(id[:])
+	if err == nil && nn != len(id) {
+		err = io.ErrShortWrite
+	}
+	return int64(nn), err
+}
+
+// SliceFromBytes copies the packed binary encoded ULIDs in b into a new slice of
+// ULIDs, equivalent to calling UnmarshalBinary for each 16 byte record.
+// ErrDataSize is returned if the length of b is not a multiple of 16.
+func SliceFromBytes(b []byte) ([]ULID, error) {
+	if len(b)%len(ULID{}) != 0 {
+		return nil, ErrDataSize
+	}
+
+	ids := make([]ULID, len(b)/len(ULID{}))
+	for i := range ids {
+		copy(ids[i][:], b[i*len(ULID{}):])
+	}
+	return ids, nil
+}
+
+// UnsafeSliceFromBytes reinterprets the packed binary encoded ULIDs in b as a
+// slice of ULIDs without copying, e.g. to access a memory mapped file of ULIDs.
+// Because a ULID's memory layout is its binary encoding this is always
+// correct, however the returned slice shares memory with b: modifying either
+// modifies both and the slice must not be used after b is unmapped or freed. If
+// b is read-only memory (such as a read-only mmap), writing to the returned
+// ULIDs will crash the program. ErrDataSize is returned if the length of b is
+// not a multiple of 16.
+func UnsafeSliceFromBytes(b []byte) ([]ULID, error) {
+	if len(b)%len(ULID{}) != 0 {
+		return nil, ErrDataSize
+	}
+
+	if len(b) == 0 {
+		return []ULID{}, nil
+	}
+	return unsafe.Slice((*ULID)(unsafe.Pointer(unsafe.SliceData(b))), len(b)/len(ULID{})), nil
+}
+
+//===========================================================================
+// Binary Streams
+//===========================================================================
+
+// Reader reads a stream of packed 16 byte binary ULIDs, e.g. a sorted segment
+// file written by a Writer or by ConvertTextToBinary.
+type Reader struct {
+	r   *bufio.Reader
+	err error
+}
+
+// NewReader returns a buffered reader of the binary ULIDs in r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next ULID in the stream. It returns io.EOF at the end of the
+// stream and io.ErrUnexpectedEOF if the stream ends with a partial ULID.
+func (r *Reader) Read() (id ULID, err error) {
+	_, err = id.ReadFrom(r.r)
+	return id, err
+}
+
+// All returns an iterator over the remaining ULIDs in the stream, e.g. to pass to
+// Merge. Iteration stops at the end of the stream or at the first error, which
+// is then returned by Err.
+func (r *Reader) All() iter.Seq[ULID] {
+	return func(yield func(ULID) bool) {
+		for {
+			id, err := r.Read()
+			if err != nil {
+				if err != io.EOF {
+					r.err = err
+				}
+				return
+			}
+
+			if !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the first error other than io.EOF encountered by All.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Writer writes a stream of packed 16 byte binary ULIDs. Writes are buffered, so
+// Flush must be called when done.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a buffered writer of binary ULIDs to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes the binary encoding of the ULID to the stream.
+func (w *Writer) Write(id ULID) error {
+	_, err := w.w.Write(id[:])
+	return err
+}
+
+// WriteAll writes every ULID in seq to the stream and flushes it, returning the
+// number of ULIDs written, e.g. to write the output of Merge.
+func (w *Writer) WriteAll(seq iter.Seq[ULID]) (n int64, err error) {
+	for id := range seq {
+		if err = w.Write(id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, w.Flush()
+}
+
+// Flush writes any buffered ULIDs to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
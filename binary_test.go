@@ -0,0 +1,201 @@
+package ulid_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestReadFromWriteTo(t *testing.T) {
+	t.Parallel()
+
+	prop := func(ids []ulid.ULID) bool {
+		var buf bytes.Buffer
+		for _, id := range ids {
+			if n, err := id.WriteTo(&buf); err != nil || n != 16 {
+				return false
+			}
+		}
+
+		if buf.Len() != 16*len(ids) {
+			return false
+		}
+
+		for _, id := range ids {
+			var got ulid.ULID
+			if n, err := got.ReadFrom(&buf); err != nil || n != 16 || got != id {
+				return false
+			}
+		}
+
+		var end ulid.ULID
+		n, err := end.ReadFrom(&buf)
+		return n == 0 && err == io.EOF && end.IsZero()
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Short", func(t *testing.T) {
+		t.Parallel()
+
+		id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+		got := id
+		n, err := got.ReadFrom(bytes.NewReader(make([]byte, 15)))
+		if n != 15 || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("got n=%d err=%v on short read", n, err)
+		}
+
+		if got != id {
+			t.Errorf("expected ulid to be unmodified by a short read")
+		}
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := ulid.Make().WriteTo(errWriter{}); err == nil {
+			t.Errorf("expected write error")
+		}
+	})
+}
+
+func TestSliceFromBytes(t *testing.T) {
+	t.Parallel()
+
+	prop := func(ids []ulid.ULID) bool {
+		b := make([]byte, 0, 16*len(ids))
+		for _, id := range ids {
+			b = append(b, id[:]...)
+		}
+
+		copied, err := ulid.SliceFromBytes(b)
+		if err != nil || len(copied) != len(ids) {
+			return false
+		}
+
+		unsafe, err := ulid.UnsafeSliceFromBytes(b)
+		if err != nil || len(unsafe) != len(ids) {
+			return false
+		}
+
+		for i := range ids {
+			var want ulid.ULID
+			if err := want.UnmarshalBinary(b[i*16 : (i+1)*16]); err != nil {
+				return false
+			}
+
+			if want != ids[i] || copied[i] != want || unsafe[i] != want {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Aliasing", func(t *testing.T) {
+		t.Parallel()
+
+		b := make([]byte, 32)
+		copied, _ := ulid.SliceFromBytes(b)
+		unsafe, _ := ulid.UnsafeSliceFromBytes(b)
+
+		b[16] = 0xFF
+		if copied[1][0] != 0 {
+			t.Errorf("expected SliceFromBytes to copy the data")
+		}
+
+		if unsafe[1][0] != 0xFF {
+			t.Errorf("expected UnsafeSliceFromBytes to share memory with the data")
+		}
+	})
+
+	t.Run("Misaligned", func(t *testing.T) {
+		t.Parallel()
+
+		for _, n := range []int{1, 15, 17, 31, 33} {
+			if _, err := ulid.SliceFromBytes(make([]byte, n)); !errors.Is(err, ulid.ErrDataSize) {
+				t.Errorf("expected ErrDataSize for %d bytes, got %v", n, err)
+			}
+
+			if _, err := ulid.UnsafeSliceFromBytes(make([]byte, n)); !errors.Is(err, ulid.ErrDataSize) {
+				t.Errorf("expected ErrDataSize for %d bytes, got %v", n, err)
+			}
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		for _, b := range [][]byte{nil, {}} {
+			if ids, err := ulid.SliceFromBytes(b); err != nil || len(ids) != 0 {
+				t.Errorf("unexpected result %v %v", ids, err)
+			}
+
+			if ids, err := ulid.UnsafeSliceFromBytes(b); err != nil || len(ids) != 0 {
+				t.Errorf("unexpected result %v %v", ids, err)
+			}
+		}
+	})
+}
+
+func TestReaderWriter(t *testing.T) {
+	t.Parallel()
+
+	prop := func(ids []ulid.ULID) bool {
+		var buf bytes.Buffer
+		n, err := ulid.NewWriter(&buf).WriteAll(slices.Values(ids))
+		if err != nil || n != int64(len(ids)) || buf.Len() != 16*len(ids) {
+			return false
+		}
+
+		r := ulid.NewReader(&buf)
+		return slices.Equal(slices.Collect(r.All()), ids) && r.Err() == nil
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Partial", func(t *testing.T) {
+		t.Parallel()
+
+		id := ulid.Make()
+		r := ulid.NewReader(bytes.NewReader(append(id.Bytes(), 0x01, 0x02)))
+		if got := slices.Collect(r.All()); !slices.Equal(got, []ulid.ULID{id}) {
+			t.Errorf("expected the complete ulid to be read, got %v", got)
+		}
+
+		if err := r.Err(); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+		}
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		w := ulid.NewWriter(&buf)
+		if err := w.Write(ulid.Make()); err != nil || buf.Len() != 0 {
+			t.Fatalf("expected the write to be buffered")
+		}
+
+		if err := w.Flush(); err != nil || buf.Len() != 16 {
+			t.Errorf("expected the write to be flushed")
+		}
+
+		w = ulid.NewWriter(errWriter{})
+		if _, err := w.WriteAll(slices.Values([]ulid.ULID{ulid.Make()})); err == nil {
+			t.Errorf("expected a write error")
+		}
+	})
+}
@@ -0,0 +1,239 @@
+// Package bloom implements a Bloom filter for membership tests over large sets
+// of ULIDs, e.g. to ship a compact filter between services instead of the set.
+//
+// The filter does not hash the ULIDs. Instead it uses Kirsch-Mitzenmacher double
+// hashing, where the k bit positions of a ULID are g_i = h1 + i*h2 mod m for i in
+// [0, k), with the two base hashes taken directly from the two 64 bit halves of
+// the ULID: h1 is the low half, which is 64 bits of entropy, and h2 is the high
+// half, which holds the 48 bit timestamp and 16 bits of entropy and is therefore
+// mixed with a multiply-xorshift finalizer and forced to be odd. Add and Contains
+// are allocation free and only compute k additions and reductions. Because the
+// bit positions depend on the entropy, ULIDs must have random (or monotonic
+// random) entropy; ULIDs with zero entropy in the same millisecond all map to
+// the same bits.
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+
+	"go.rtnl.ai/ulid"
+)
+
+var (
+	// Returned when merging filters with different parameters.
+	ErrIncompatible = errors.New("bloom: filters have different parameters")
+
+	// Returned when unmarshaling data that is not an encoded filter.
+	ErrInvalidFilter = errors.New("bloom: invalid filter encoding")
+
+	// Returned when unmarshaling a filter encoded with an unsupported version.
+	ErrVersion = errors.New("bloom: unsupported filter encoding version")
+)
+
+// Version is the version of the binary encoding of filters.
+const Version = 1
+
+// The binary encoding is a header of the magic bytes, the version byte, three
+// reserved zero bytes, the number of hashes as a big endian uint32, and the number
+// of bits as a big endian uint64, followed by the bits as big endian uint64 words.
+const (
+	magic      = "ULBF"
+	headerSize = 4 + 1 + 3 + 4 + 8
+)
+
+// Filter is a Bloom filter of ULIDs. A Filter is not safe for concurrent use.
+//
+// The zero value is an empty filter that is sized like NewFilter(DefaultItems,
+// DefaultFPRate) by its first Add or by the parameters of the first filter merged
+// into it. An empty zero value filter can be merged into any filter.
+type Filter struct {
+	bits   uint64
+	hashes uint64
+	words  []uint64
+}
+
+// The parameters of a zero value filter.
+const (
+	DefaultItems  = 10000
+	DefaultFPRate = 0.01
+)
+
+// NewFilter returns a filter sized for the expected number of items at the false
+// positive rate, which must be between 0 and 1 exclusive (otherwise DefaultFPRate
+// is used).
+// If more items than expected are added, the false positive rate is higher.
+func NewFilter(expectedItems uint64, fpRate float64) *Filter {
+	n := float64(max(expectedItems, 1))
+	if fpRate <= 0 || fpRate >= 1 || math.IsNaN(fpRate) {
+		fpRate = DefaultFPRate
+	}
+
+	m := math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / n * math.Ln2)
+	return newFilter(max(uint64(m), 64), max(uint64(k), 1))
+}
+
+func newFilter(m, k uint64) *Filter {
+	return &Filter{bits: m, hashes: k, words: make([]uint64, wordCount(m))}
+}
+
+// wordCount returns the number of 64 bit words of a filter of m bits, computed
+// without (m+63)/64 so that it does not overflow for m near 2^64.
+func wordCount(m uint64) uint64 {
+	n := m / 64
+	if m%64 != 0 {
+		n++
+	}
+	return n
+}
+
+// Add adds the ULID to the filter.
+func (f *Filter) Add(id ulid.ULID) {
+	if f.bits == 0 {
+		*f = *NewFilter(DefaultItems, DefaultFPRate)
+	}
+
+	h1, h2 := baseHashes(id)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.bits
+		f.words[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains returns true if the ULID may have been added to the filter and false
+// if it definitely has not.
+func (f *Filter) Contains(id ulid.ULID) bool {
+	if f.bits == 0 {
+		return false
+	}
+
+	h1, h2 := baseHashes(id)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.bits
+		if f.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// baseHashes returns the two base hashes of the ULID for double hashing.
+func baseHashes(id ulid.ULID) (h1, h2 uint64) {
+	h1 = binary.BigEndian.Uint64(id[8:])
+	h2 = binary.BigEndian.Uint64(id[:8])
+
+	// The murmur3 finalizer spreads the timestamp and high entropy bits.
+	h2 ^= h2 >> 33
+	h2 *= 0xff51afd7ed558ccd
+	h2 ^= h2 >> 33
+	h2 *= 0xc4ceb9fe1a85ec53
+	h2 ^= h2 >> 33
+	return h1, h2 | 1
+}
+
+// Bits returns the number of bits of the filter.
+func (f *Filter) Bits() uint64 { return f.bits }
+
+// Hashes returns the number of bits set for each ULID.
+func (f *Filter) Hashes() uint64 { return f.hashes }
+
+// ApproxItems estimates the number of distinct ULIDs added to the filter from the
+// number of bits that are set, as -(m/k) ln(1 - X/m) for X set bits of m.
+func (f *Filter) ApproxItems() uint64 {
+	if f.bits == 0 {
+		return 0
+	}
+
+	set := float64(f.setBits())
+	if set >= float64(f.bits) {
+		return math.MaxUint64
+	}
+
+	m, k := float64(f.bits), float64(f.hashes)
+	return uint64(math.Round(-m / k * math.Log1p(-set/m)))
+}
+
+// EffectiveFPRate returns the false positive rate of the filter given the bits that
+// are currently set, (X/m)^k for X set bits of m, which increases as items are
+// added and exceeds the rate the filter was created with once it is overfull.
+func (f *Filter) EffectiveFPRate() float64 {
+	if f.bits == 0 {
+		return 0
+	}
+	return math.Pow(float64(f.setBits())/float64(f.bits), float64(f.hashes))
+}
+
+func (f *Filter) setBits() (n uint64) {
+	for _, w := range f.words {
+		n += uint64(bits.OnesCount64(w))
+	}
+	return n
+}
+
+// Merge adds all of the ULIDs of the other filter to this filter, i.e. the union
+// of the filters. ErrIncompatible is returned if the filters do not have the same
+// number of bits and hashes, unless one of them is an empty zero value filter.
+func (f *Filter) Merge(other *Filter) error {
+	switch {
+	case other.bits == 0:
+		return nil
+	case f.bits == 0:
+		*f = *newFilter(other.bits, other.hashes)
+	}
+
+	if f.bits != other.bits || f.hashes != other.hashes {
+		return ErrIncompatible
+	}
+
+	for i, w := range other.words {
+		f.words[i] |= w
+	}
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The encoding
+// has a versioned header so that filters can be persisted and shipped. An empty
+// zero value filter is encoded with zero bits and hashes.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, headerSize, headerSize+8*len(f.words))
+	copy(data, magic)
+	data[4] = Version
+	binary.BigEndian.PutUint32(data[8:], uint32(f.hashes))
+	binary.BigEndian.PutUint64(data[12:], f.bits)
+
+	for _, w := range f.words {
+		data = binary.BigEndian.AppendUint64(data, w)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface, replacing
+// the filter with the decoded filter. ErrVersion is returned for an unsupported
+// version and ErrInvalidFilter for any other invalid data.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return ErrInvalidFilter
+	}
+
+	if data[4] != Version {
+		return ErrVersion
+	}
+
+	k := uint64(binary.BigEndian.Uint32(data[8:]))
+	m := binary.BigEndian.Uint64(data[12:])
+	words := data[headerSize:]
+	if data[5]|data[6]|data[7] != 0 || (k == 0) != (m == 0) || len(words)%8 != 0 || uint64(len(words)/8) != wordCount(m) {
+		return ErrInvalidFilter
+	}
+
+	decoded := newFilter(m, k)
+	for i := range decoded.words {
+		decoded.words[i] = binary.BigEndian.Uint64(words[8*i:])
+	}
+
+	*f = *decoded
+	return nil
+}
@@ -0,0 +1,351 @@
+package bloom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/bloom"
+)
+
+func TestNewFilter(t *testing.T) {
+	t.Parallel()
+
+	f := bloom.NewFilter(1e6, 0.01)
+	if bits := f.Bits(); bits != 9585059 {
+		t.Errorf("got %d bits, want 9585059", bits)
+	}
+
+	if k := f.Hashes(); k != 7 {
+		t.Errorf("got %d hashes, want 7", k)
+	}
+
+	// Invalid parameters use the defaults.
+	for _, p := range []float64{0, -1, 1, 2, math.NaN()} {
+		if f := bloom.NewFilter(1e6, p); f.Bits() != 9585059 {
+			t.Errorf("expected the default false positive rate for %v", p)
+		}
+	}
+
+	if f := bloom.NewFilter(0, 0.01); f.Bits() != 64 || f.Hashes() < 1 {
+		t.Errorf("expected a minimum filter size, got %d bits and %d hashes", f.Bits(), f.Hashes())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		n    int
+		p    float64
+		gen  func() ulid.ULID
+	}{
+		{"Random", 100000, 0.01, randomULIDs(1)},
+		{"Monotonic", 100000, 0.01, ulid.Make},
+		{"LowRate", 50000, 0.001, randomULIDs(2)},
+		{"HighRate", 50000, 0.1, ulid.Make},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := bloom.NewFilter(uint64(tc.n), tc.p)
+			added := make(map[ulid.ULID]struct{}, tc.n)
+			for len(added) < tc.n {
+				id := tc.gen()
+				added[id] = struct{}{}
+				f.Add(id)
+			}
+
+			// There are no false negatives.
+			for id := range added {
+				if !f.Contains(id) {
+					t.Fatalf("expected %s to be in the filter", id)
+				}
+			}
+
+			// The false positive rate is within tolerance of the configured rate.
+			queries, fp := 0, 0
+			query := randomULIDs(99)
+			for queries < 200000 {
+				id := query()
+				if _, ok := added[id]; ok {
+					continue
+				}
+
+				queries++
+				if f.Contains(id) {
+					fp++
+				}
+			}
+
+			rate := float64(fp) / float64(queries)
+			if rate > tc.p*1.5 || rate < tc.p/2 {
+				t.Errorf("got false positive rate %.5f, want about %.5f", rate, tc.p)
+			}
+
+			if eff := f.EffectiveFPRate(); eff > tc.p*1.25 || eff < tc.p*0.75 {
+				t.Errorf("got effective false positive rate %.5f, want about %.5f", eff, tc.p)
+			}
+
+			if approx := f.ApproxItems(); math.Abs(float64(approx)-float64(tc.n)) > 0.02*float64(tc.n) {
+				t.Errorf("got approximately %d items, want about %d", approx, tc.n)
+			}
+		})
+	}
+}
+
+func TestFilterEmpty(t *testing.T) {
+	t.Parallel()
+
+	f := bloom.NewFilter(1000, 0.01)
+	if f.Contains(ulid.Make()) {
+		t.Error("expected an empty filter to contain nothing")
+	}
+
+	if f.ApproxItems() != 0 || f.EffectiveFPRate() != 0 {
+		t.Errorf("expected no items, got %d items and rate %f", f.ApproxItems(), f.EffectiveFPRate())
+	}
+
+	// A full filter contains everything.
+	full := bloom.NewFilter(1, 0.5)
+	for i := 0; i < 10000; i++ {
+		full.Add(ulid.Make())
+	}
+
+	if full.ApproxItems() != math.MaxUint64 || full.EffectiveFPRate() != 1 {
+		t.Errorf("expected a saturated filter, got %d items and rate %f", full.ApproxItems(), full.EffectiveFPRate())
+	}
+}
+
+func TestFilterZero(t *testing.T) {
+	t.Parallel()
+
+	var f bloom.Filter
+	id := ulid.Make()
+	if f.Contains(id) || f.ApproxItems() != 0 || f.EffectiveFPRate() != 0 {
+		t.Error("expected the zero value to be an empty filter")
+	}
+
+	// An empty zero value filter round trips and merges with any filter.
+	var decoded bloom.Filter
+	if data, err := f.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	} else if err := decoded.UnmarshalBinary(data); err != nil || decoded.Bits() != 0 {
+		t.Fatalf("got %d bits (%v), want an empty filter", decoded.Bits(), err)
+	}
+
+	other := bloom.NewFilter(100, 0.01)
+	other.Add(id)
+	if err := other.Merge(&decoded); err != nil || !other.Contains(id) {
+		t.Errorf("expected merging an empty filter to be a no-op, got %v", err)
+	}
+
+	var merged bloom.Filter
+	if err := merged.Merge(other); err != nil || !merged.Contains(id) || merged.Bits() != other.Bits() {
+		t.Errorf("expected the zero value to take the parameters of the merged filter, got %v", err)
+	}
+
+	// The first Add sizes the filter with the defaults.
+	f.Add(id)
+	if want := bloom.NewFilter(bloom.DefaultItems, bloom.DefaultFPRate); f.Bits() != want.Bits() || f.Hashes() != want.Hashes() {
+		t.Errorf("got %d bits and %d hashes, want %d and %d", f.Bits(), f.Hashes(), want.Bits(), want.Hashes())
+	}
+
+	if !f.Contains(id) || f.ApproxItems() != 1 {
+		t.Errorf("expected the filter to contain %s", id)
+	}
+}
+
+func TestFilterMerge(t *testing.T) {
+	t.Parallel()
+
+	a, b := bloom.NewFilter(1000, 0.01), bloom.NewFilter(1000, 0.01)
+	gen := randomULIDs(3)
+
+	var ids []ulid.ULID
+	for i := 0; i < 1000; i++ {
+		id := gen()
+		ids = append(ids, id)
+		if i%2 == 0 {
+			a.Add(id)
+		} else {
+			b.Add(id)
+		}
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		if !a.Contains(id) {
+			t.Fatalf("expected the merged filter to contain %s", id)
+		}
+	}
+
+	for _, other := range []*bloom.Filter{bloom.NewFilter(2000, 0.01), bloom.NewFilter(1000, 0.001)} {
+		if err := a.Merge(other); err != bloom.ErrIncompatible {
+			t.Errorf("expected ErrIncompatible, got %v", err)
+		}
+	}
+}
+
+func TestFilterBinary(t *testing.T) {
+	t.Parallel()
+
+	f := bloom.NewFilter(10000, 0.01)
+	gen := randomULIDs(4)
+	ids := make([]ulid.ULID, 10000)
+	for i := range ids {
+		ids[i] = gen()
+		f.Add(ids[i])
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("ULBF\x01\x00\x00\x00")) {
+		t.Errorf("unexpected header %x", data[:8])
+	}
+
+	var decoded bloom.Filter
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Bits() != f.Bits() || decoded.Hashes() != f.Hashes() || decoded.ApproxItems() != f.ApproxItems() {
+		t.Fatalf("expected the decoded filter to have the same parameters")
+	}
+
+	for _, id := range ids {
+		if !decoded.Contains(id) {
+			t.Fatalf("expected the decoded filter to contain %s", id)
+		}
+	}
+
+	if again, _ := decoded.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Error("expected the encoding to round trip")
+	}
+
+	// The decoded filter can be merged with the original.
+	if err := decoded.Merge(f); err != nil {
+		t.Errorf("expected identical filters to merge, got %v", err)
+	}
+}
+
+func TestFilterUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	data, _ := bloom.NewFilter(100, 0.01).MarshalBinary()
+	corrupt := func(i int, b byte) []byte {
+		c := bytes.Clone(data)
+		c[i] = b
+		return c
+	}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"Empty", nil, bloom.ErrInvalidFilter},
+		{"Header", data[:10], bloom.ErrInvalidFilter},
+		{"Magic", corrupt(0, 'X'), bloom.ErrInvalidFilter},
+		{"Version", corrupt(4, 2), bloom.ErrVersion},
+		{"Reserved", corrupt(6, 1), bloom.ErrInvalidFilter},
+		{"ZeroHashes", append(append(bytes.Clone(data[:8]), 0, 0, 0, 0), data[12:]...), bloom.ErrInvalidFilter},
+		{"Bits", corrupt(19, data[19]+64), bloom.ErrInvalidFilter},
+		{"Short", data[:len(data)-8], bloom.ErrInvalidFilter},
+		{"Long", append(bytes.Clone(data), 0), bloom.ErrInvalidFilter},
+		{"MaxBits", withBits(data[:headerSize], math.MaxUint64), bloom.ErrInvalidFilter},
+		{"OverflowBits", withBits(data[:headerSize+8], math.MaxUint64-62), bloom.ErrInvalidFilter},
+	} {
+		f := bloom.NewFilter(10, 0.5)
+		if err := f.UnmarshalBinary(tc.data); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+
+		if f.Bits() != bloom.NewFilter(10, 0.5).Bits() {
+			t.Errorf("%s: expected the filter to be unmodified on error", tc.name)
+		}
+	}
+}
+
+// headerSize is the size of the header of the binary encoding of a filter.
+const headerSize = 20
+
+// withBits returns a copy of the encoded filter with the number of bits replaced.
+func withBits(data []byte, m uint64) []byte {
+	c := bytes.Clone(data)
+	binary.BigEndian.PutUint64(c[12:], m)
+	return c
+}
+
+// FuzzFilterUnmarshal checks that any data that is decoded is a usable filter
+// that encodes back to the same data.
+func FuzzFilterUnmarshal(f *testing.F) {
+	data, _ := bloom.NewFilter(100, 0.01).MarshalBinary()
+	f.Add(data)
+	f.Add(withBits(data[:headerSize], math.MaxUint64))
+	f.Add(withBits(data[:headerSize+8], 65))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Filters with billions of hashes are valid but too slow to fuzz.
+		var filter bloom.Filter
+		if err := filter.UnmarshalBinary(data); err != nil || filter.Hashes() > 1<<16 {
+			return
+		}
+
+		id := ulid.Make()
+		filter.Add(id)
+		if !filter.Contains(id) {
+			t.Fatal("expected the decoded filter to contain the added ulid")
+		}
+
+		if encoded, _ := filter.MarshalBinary(); len(encoded) != len(data) {
+			t.Fatalf("got %d bytes encoded, decoded %d bytes", len(encoded), len(data))
+		}
+	})
+}
+
+func TestFilterAllocs(t *testing.T) {
+	f := bloom.NewFilter(1000, 0.01)
+	id := ulid.Make()
+	if allocs := testing.AllocsPerRun(100, func() { f.Add(id); _ = f.Contains(id) }); allocs != 0 {
+		t.Errorf("expected no allocations, got %.1f", allocs)
+	}
+}
+
+func randomULIDs(seed int64) func() ulid.ULID {
+	rng := rand.New(rand.NewSource(seed))
+	return func() ulid.ULID {
+		return ulid.MustNew(ulid.Now(), rng)
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	f := bloom.NewFilter(1e6, 0.01)
+	id := ulid.Make()
+
+	b.Run("Add", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			id[15] = byte(i)
+			f.Add(id)
+		}
+	})
+
+	b.Run("Contains", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			id[15] = byte(i)
+			_ = f.Contains(id)
+		}
+	})
+}
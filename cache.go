@@ -0,0 +1,163 @@
+package ulid
+
+import (
+	"encoding/json"
+	"hash/maphash"
+	"math/bits"
+	"sync/atomic"
+)
+
+// DefaultDecodingCacheSize is the default maximum number of entries of a
+// DecodingCache.
+const DefaultDecodingCacheSize = 4096
+
+// DecodingCache remembers the ULIDs parsed from their encoded text, so that the
+// repeated occurrences of the same small set of ULIDs in a large payload, e.g. a
+// foreign key in row-oriented JSON, are looked up rather than parsed again, and
+// so that their canonical strings are interned: every occurrence of a ULID shares
+// one string rather than allocating its own for re-encoding.
+//
+// The cache is keyed by the raw text, so the uppercase and lowercase encodings of
+// a ULID are separate entries, and it only holds the text that parsed without an
+// error; the results are identical to decoding without a cache. The cache is a
+// bounded table of entries indexed by the hash of the text: a new text replaces
+// the entry with the same index, so the frequently repeated ULIDs are likely to
+// stay cached while the rare ones come and go. Lookups do not lock, so a
+// DecodingCache is safe for concurrent use by multiple decoders without
+// contention, and a nil *DecodingCache decodes without caching.
+type DecodingCache struct {
+	seed    maphash.Seed
+	entries []atomic.Pointer[cachedULID]
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// cachedULID is an entry of a DecodingCache, which is not modified once it has
+// been stored in the table.
+type cachedULID struct {
+	text [EncodedSize]byte
+	id   ULID
+	s    string // The interned canonical string, if it has been requested
+}
+
+// DecodingCacheStats are the counters of a DecodingCache.
+type DecodingCacheStats struct {
+	Hits    uint64 // The number of texts that were found in the cache
+	Misses  uint64 // The number of texts that were parsed, including invalid texts
+	Entries int    // The number of entries in the cache
+}
+
+// NewDecodingCache returns a cache with at most size entries, rounded up to a
+// power of two; values less than 1 use DefaultDecodingCacheSize. Each entry uses
+// about 80 bytes plus the canonical string if it is interned, and the table of
+// entries uses 8 bytes per entry.
+func NewDecodingCache(size int) *DecodingCache {
+	if size < 1 {
+		size = DefaultDecodingCacheSize
+	}
+
+	return &DecodingCache{
+		seed:    maphash.MakeSeed(),
+		entries: make([]atomic.Pointer[cachedULID], 1<<bits.Len(uint(size-1))),
+	}
+}
+
+// Parse parses the encoded ULID as with UnmarshalText, looking it up in the cache
+// first.
+func (c *DecodingCache) Parse(text []byte) (ULID, error) {
+	id, _, err := c.lookup(text, false)
+	return id, err
+}
+
+// ParseString parses the encoded ULID like Parse and also returns its canonical
+// string, which is interned: the same string is returned for every occurrence of
+// the text while it is cached, so only the first occurrence allocates.
+func (c *DecodingCache) ParseString(text []byte) (ULID, string, error) {
+	return c.lookup(text, true)
+}
+
+// Unmarshal decodes the ULID from a JSON string into id like encoding/json does
+// with UnmarshalText, looking it up in the cache, and leaves id unchanged for a
+// JSON null. Its signature is that of the unmarshal functions of custom JSON
+// unmarshalers, e.g. to decode the ULID fields of a struct with the cache from
+// their UnmarshalJSON methods. If an error is returned, id is not modified.
+func (c *DecodingCache) Unmarshal(data []byte, id *ULID) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	text, err := jsonText(data)
+	if err != nil {
+		return err
+	}
+
+	u, err := c.Parse(text)
+	if err != nil {
+		return err
+	}
+
+	*id = u
+	return nil
+}
+
+// DecodeJSONArray decodes a JSON array of ULID strings from the decoder like the
+// DecodeJSONArray function, looking up each element in the cache.
+func (c *DecodingCache) DecodeJSONArray(dec *json.Decoder, dst []ULID) ([]ULID, error) {
+	return decodeJSONArray(dec, dst, c.Parse)
+}
+
+// Stats returns the hits and misses of the cache since it was created and its
+// current number of entries. The stats of a nil cache are always zero.
+func (c *DecodingCache) Stats() DecodingCacheStats {
+	if c == nil {
+		return DecodingCacheStats{}
+	}
+
+	stats := DecodingCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	for i := range c.entries {
+		if c.entries[i].Load() != nil {
+			stats.Entries++
+		}
+	}
+	return stats
+}
+
+func (c *DecodingCache) lookup(text []byte, intern bool) (id ULID, s string, err error) {
+	if c == nil || len(text) != EncodedSize {
+		if id, err = parseText(text); err == nil && intern {
+			s = id.String()
+		}
+		return id, s, err
+	}
+
+	slot := &c.entries[maphash.Bytes(c.seed, text)&uint64(len(c.entries)-1)]
+	entry := slot.Load()
+	if entry != nil && string(entry.text[:]) == string(text) {
+		c.hits.Add(1)
+		if entry.s != "" || !intern {
+			return entry.id, entry.s, nil
+		}
+
+		// The entry was cached by Parse; replace it with its interned string. If
+		// another decoder interned it first, its string is returned instead.
+		interned := &cachedULID{text: entry.text, id: entry.id, s: entry.id.String()}
+		if !slot.CompareAndSwap(entry, interned) {
+			if current := slot.Load(); current != nil && current.text == entry.text && current.s != "" {
+				interned = current
+			}
+		}
+		return interned.id, interned.s, nil
+	}
+
+	c.misses.Add(1)
+	if id, err = parseText(text); err != nil {
+		return id, s, err
+	}
+
+	entry = &cachedULID{text: [EncodedSize]byte(text), id: id}
+	if intern {
+		entry.s = id.String()
+	}
+	slot.Store(entry)
+	return entry.id, entry.s, nil
+}
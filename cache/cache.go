@@ -0,0 +1,386 @@
+// Package cache implements a generic LRU cache keyed by ULID.
+//
+// The cache is split into shards, each with its own lock, so that it is safe and
+// scales for concurrent use; a ULID is assigned to a shard by mixing its two 64
+// bit halves. Each shard stores its entries in a slice of nodes that form an
+// intrusive doubly linked list by index, so that apart from the map of ULIDs to
+// indices, neither Get nor Put allocate and values are not boxed in interfaces.
+// Because eviction is least recently used within a shard, a sharded cache is
+// an approximation of a single LRU; use Shards(1) for exact LRU eviction.
+//
+// Optionally, entries expire after a TTL measured from the timestamp embedded in
+// the ULID or from the time the entry was put into the cache. Each shard keeps
+// its entries in a second list ordered by expiry, which is inserted into from the
+// newest end; for time-ordered ULIDs (or with insertion times) each insertion is
+// O(1), and expired entries are removed in bulk from the oldest end in O(1) per
+// entry, both when the cache is full and with Expire.
+package cache
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// DefaultShards is the maximum number of shards used unless specified with the
+// Shards option. Small caches use fewer shards so that each shard holds at least
+// minShardCapacity entries.
+const DefaultShards = 16
+
+const minShardCapacity = 64
+
+// Cache is an LRU cache of values of type V keyed by ULID. A Cache is safe for
+// concurrent use and must be created with New.
+type Cache[V any] struct {
+	shards []shard[V]
+	mask   uint64
+	ttl    time.Duration
+	insert bool
+	now    func() time.Time
+}
+
+// Option configures a cache returned by New.
+type Option func(*config)
+
+type config struct {
+	shards int
+	ttl    time.Duration
+	insert bool
+	now    func() time.Time
+}
+
+// Shards sets the number of shards of the cache, rounded up to a power of two and
+// at most the capacity of the cache. Values less than 1 use the default.
+func Shards(n int) Option {
+	return func(c *config) {
+		c.shards = n
+	}
+}
+
+// TTL expires entries once the duration has passed since the timestamp embedded
+// in their ULID, e.g. so that a ULID created an hour ago with a TTL of an hour is
+// never returned by Get. Durations less than or equal to zero disable expiry.
+func TTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// InsertionTTL expires entries once the duration has passed since they were last
+// put into the cache rather than since the time of their ULID. Durations less
+// than or equal to zero disable expiry.
+func InsertionTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+		c.insert = true
+	}
+}
+
+// Clock sets the function used to get the current time for expiry, which
+// defaults to time.Now.
+func Clock(now func() time.Time) Option {
+	return func(c *config) {
+		if now != nil {
+			c.now = now
+		}
+	}
+}
+
+// New returns an empty cache that holds at most capacity entries; a capacity less
+// than 1 is treated as 1. The capacity is divided evenly between the shards and
+// all of the memory of the cache is allocated up front, so a shard may evict its
+// entries before the cache as a whole holds capacity entries.
+func New[V any](capacity int, opts ...Option) *Cache[V] {
+	capacity = max(capacity, 1)
+	conf := config{now: time.Now}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	n := conf.shards
+	if n < 1 {
+		for n = DefaultShards; n > 1 && capacity/n < minShardCapacity; n /= 2 {
+		}
+	}
+
+	// Round up to a power of two, then down to the capacity.
+	shards := 1
+	for shards < n {
+		shards <<= 1
+	}
+	for shards > capacity {
+		shards >>= 1
+	}
+
+	c := &Cache[V]{
+		shards: make([]shard[V], shards),
+		mask:   uint64(shards - 1),
+		ttl:    max(conf.ttl, 0),
+		insert: conf.insert,
+		now:    conf.now,
+	}
+
+	for i := range c.shards {
+		size := capacity / shards
+		if i < capacity%shards {
+			size++
+		}
+		c.shards[i].init(size, c.ttl > 0)
+	}
+	return c
+}
+
+// Get returns the value of the ULID and marks it as the most recently used entry,
+// or false if the ULID is not in the cache or has expired.
+func (c *Cache[V]) Get(id ulid.ULID) (v V, ok bool) {
+	s := c.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.index[id]
+	if !ok {
+		return v, false
+	}
+
+	if c.ttl > 0 && s.nodes[i].deadline <= c.now().UnixNano() {
+		s.remove(i)
+		return v, false
+	}
+
+	s.moveToFront(lru, i)
+	return s.nodes[i].value, true
+}
+
+// Put adds or replaces the value of the ULID as the most recently used entry. If
+// the cache is full, expired entries are removed or otherwise the least recently
+// used entry of the shard of the ULID is evicted.
+func (c *Cache[V]) Put(id ulid.ULID, v V) {
+	var now int64
+	if c.ttl > 0 {
+		now = c.now().UnixNano()
+	}
+
+	s := c.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i, ok := s.index[id]; ok {
+		s.nodes[i].value = v
+		s.moveToFront(lru, i)
+		if c.insert {
+			s.unlink(exp, i)
+			s.nodes[i].deadline = deadline(now, c.ttl)
+			s.insertByDeadline(i)
+		}
+		return
+	}
+
+	if s.len == s.capacity && c.ttl > 0 {
+		s.expire(now)
+	}
+
+	if s.len == s.capacity {
+		s.remove(s.nodes[head].links[lru].prev)
+	}
+
+	i := s.free
+	s.free = s.nodes[i].links[lru].next
+	s.len++
+	s.index[id] = i
+
+	s.nodes[i].key, s.nodes[i].value = id, v
+	s.pushFront(lru, i)
+	if c.ttl > 0 {
+		if c.insert {
+			s.nodes[i].deadline = deadline(now, c.ttl)
+		} else {
+			s.nodes[i].deadline = deadline(timestampNano(id), c.ttl)
+		}
+		s.insertByDeadline(i)
+	}
+}
+
+// timestampNano returns the timestamp of the ULID in Unix nanoseconds, saturating
+// at math.MaxInt64 for timestamps after the year 2262 that cannot be represented.
+func timestampNano(id ulid.ULID) int64 {
+	if ms := id.Time(); ms <= math.MaxInt64/uint64(time.Millisecond) {
+		return int64(ms) * int64(time.Millisecond)
+	}
+	return math.MaxInt64
+}
+
+// deadline returns the time in Unix nanoseconds when an entry from the time expires
+// after the ttl, saturating at math.MaxInt64 so that the entries of ULIDs with
+// timestamps near the end of the representable times do not expire immediately.
+func deadline(from int64, ttl time.Duration) int64 {
+	if from > math.MaxInt64-int64(ttl) {
+		return math.MaxInt64
+	}
+	return from + int64(ttl)
+}
+
+// Delete removes the ULID from the cache, returning false if it was not present.
+func (c *Cache[V]) Delete(id ulid.ULID) bool {
+	s := c.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.index[id]
+	if ok {
+		s.remove(i)
+	}
+	return ok
+}
+
+// Len returns the number of entries in the cache, including expired entries that
+// have not yet been removed.
+func (c *Cache[V]) Len() (n int) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		n += int(s.len)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Expire removes all of the expired entries from the cache, returning the number
+// of entries removed. Without a TTL it does nothing.
+func (c *Cache[V]) Expire() (n int) {
+	if c.ttl <= 0 {
+		return 0
+	}
+
+	now := c.now().UnixNano()
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		n += s.expire(now)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (c *Cache[V]) shard(id ulid.ULID) *shard[V] {
+	// The high half holds the timestamp, which is the same for many ULIDs, and the
+	// low half may be zero, so both are mixed with a multiply-xorshift.
+	h := binary.BigEndian.Uint64(id[:8]) ^ binary.BigEndian.Uint64(id[8:])
+	h *= 0x9E3779B97F4A7C15
+	h ^= h >> 32
+	return &c.shards[h&c.mask]
+}
+
+//===========================================================================
+// Shards
+//===========================================================================
+
+// The two lists of a shard: by recency of use and by deadline.
+const (
+	lru = 0
+	exp = 1
+)
+
+// head is the index of the sentinel node of both lists of a shard; the front of a
+// list is the next of the sentinel (the most recently used or latest deadline)
+// and the back is its prev.
+const head = 0
+
+type link struct {
+	prev, next int32
+}
+
+type node[V any] struct {
+	key      ulid.ULID
+	value    V
+	deadline int64
+	links    [2]link
+}
+
+type shard[V any] struct {
+	mu       sync.Mutex
+	index    map[ulid.ULID]int32
+	nodes    []node[V]
+	free     int32 // the first unused node, linked by the next of the lru links
+	len      int32
+	capacity int32
+	timed    bool // whether the nodes are in the expiry list
+}
+
+func (s *shard[V]) init(capacity int, timed bool) {
+	s.capacity = int32(capacity)
+	s.timed = timed
+	s.index = make(map[ulid.ULID]int32, capacity)
+	s.nodes = make([]node[V], capacity+1)
+
+	s.free = 1
+	for i := range s.nodes[1:] {
+		s.nodes[i+1].links[lru].next = int32(i + 2)
+	}
+}
+
+// remove unlinks the node from both lists and returns it to the free list.
+func (s *shard[V]) remove(i int32) {
+	n := &s.nodes[i]
+	delete(s.index, n.key)
+	s.unlink(lru, i)
+	if s.timed {
+		s.unlink(exp, i)
+	}
+
+	var zero V
+	n.value = zero
+	n.links = [2]link{{next: s.free}, {}}
+	s.free = i
+	s.len--
+}
+
+// expire removes the entries from the back of the expiry list whose deadline has
+// passed.
+func (s *shard[V]) expire(now int64) (n int) {
+	for {
+		i := s.nodes[head].links[exp].prev
+		if i == head || s.nodes[i].deadline > now {
+			return n
+		}
+		s.remove(i)
+		n++
+	}
+}
+
+// insertByDeadline inserts the node into the expiry list after the first node
+// from the front with a deadline that is not later than that of the node.
+func (s *shard[V]) insertByDeadline(i int32) {
+	at := s.nodes[head].links[exp].next
+	for at != head && s.nodes[at].deadline > s.nodes[i].deadline {
+		at = s.nodes[at].links[exp].next
+	}
+	s.insertBefore(exp, at, i)
+}
+
+func (s *shard[V]) moveToFront(l int, i int32) {
+	if s.nodes[head].links[l].next != i {
+		s.unlink(l, i)
+		s.pushFront(l, i)
+	}
+}
+
+func (s *shard[V]) pushFront(l int, i int32) {
+	s.insertBefore(l, s.nodes[head].links[l].next, i)
+}
+
+func (s *shard[V]) insertBefore(l int, at, i int32) {
+	prev := s.nodes[at].links[l].prev
+	s.nodes[i].links[l] = link{prev: prev, next: at}
+	s.nodes[prev].links[l].next = i
+	s.nodes[at].links[l].prev = i
+}
+
+func (s *shard[V]) unlink(l int, i int32) {
+	n := &s.nodes[i].links[l]
+	s.nodes[n.prev].links[l].next = n.next
+	s.nodes[n.next].links[l].prev = n.prev
+	*n = link{}
+}
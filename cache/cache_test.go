@@ -0,0 +1,397 @@
+package cache_test
+
+import (
+	"container/list"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/cache"
+)
+
+// ids returns n ULIDs, one per millisecond from the time.
+func ids(n int, from time.Time) []ulid.ULID {
+	out := make([]ulid.ULID, n)
+	for i := range out {
+		out[i] = ulid.MustNew(ulid.Timestamp(from)+uint64(i), ulid.DefaultEntropy())
+	}
+	return out
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	// Each shard holds its share of the capacity, so leave room for the imbalance.
+	keys := ids(1000, time.Now())
+	c := cache.New[int](2 * len(keys))
+	for i, id := range keys {
+		c.Put(id, i)
+	}
+
+	if n := c.Len(); n != len(keys) {
+		t.Fatalf("expected %d entries, got %d", len(keys), n)
+	}
+
+	for i, id := range keys {
+		if v, ok := c.Get(id); !ok || v != i {
+			t.Fatalf("got %d, %t for %s, want %d", v, ok, id, i)
+		}
+	}
+
+	// Replacing a value does not add an entry.
+	c.Put(keys[0], -1)
+	if v, ok := c.Get(keys[0]); !ok || v != -1 || c.Len() != len(keys) {
+		t.Errorf("expected the value to be replaced, got %d, %t with %d entries", v, ok, c.Len())
+	}
+
+	if !c.Delete(keys[0]) || c.Delete(keys[0]) {
+		t.Error("expected the ULID to be deleted once")
+	}
+
+	if _, ok := c.Get(keys[0]); ok || c.Len() != len(keys)-1 {
+		t.Error("expected the deleted ULID not to be in the cache")
+	}
+
+	if _, ok := c.Get(ulid.Make()); ok {
+		t.Error("expected a missing ULID not to be in the cache")
+	}
+
+	// The cache never holds more than its capacity, however it is sharded.
+	for _, shards := range []int{0, 1, 3, 64, 2000} {
+		c := cache.New[int](100, cache.Shards(shards))
+		for i, id := range keys {
+			c.Put(id, i)
+		}
+
+		if n := c.Len(); n != 100 {
+			t.Errorf("%d shards: expected 100 entries, got %d", shards, n)
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	t.Parallel()
+
+	keys := ids(5, time.Now())
+	c := cache.New[int](3, cache.Shards(1))
+	c.Put(keys[0], 0)
+	c.Put(keys[1], 1)
+	c.Put(keys[2], 2)
+
+	// Using the oldest entry makes the second entry the least recently used.
+	c.Get(keys[0])
+	c.Put(keys[3], 3)
+	if _, ok := c.Get(keys[1]); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+
+	// Replacing a value also uses the entry.
+	c.Put(keys[2], 2)
+	c.Put(keys[4], 4)
+	for i, want := range []bool{false, false, true, true, true} {
+		if _, ok := c.Get(keys[i]); ok != want {
+			t.Errorf("entry %d: got %t, want %t", i, ok, want)
+		}
+	}
+
+	// Deleted entries are reused before evicting.
+	c.Delete(keys[3])
+	c.Put(keys[0], 0)
+	for i, want := range []bool{true, false, true, false, true} {
+		if _, ok := c.Get(keys[i]); ok != want {
+			t.Errorf("entry %d after deleting: got %t, want %t", i, ok, want)
+		}
+	}
+}
+
+func TestCapacityOne(t *testing.T) {
+	t.Parallel()
+
+	keys := ids(3, time.Now())
+	for _, capacity := range []int{1, 0, -1} {
+		c := cache.New[string](capacity)
+		if _, ok := c.Get(keys[0]); ok || c.Len() != 0 {
+			t.Fatalf("capacity %d: expected an empty cache", capacity)
+		}
+
+		c.Put(keys[0], "a")
+		c.Put(keys[0], "b")
+		if v, ok := c.Get(keys[0]); !ok || v != "b" || c.Len() != 1 {
+			t.Errorf("capacity %d: got %q, %t, want b", capacity, v, ok)
+		}
+
+		c.Put(keys[1], "c")
+		if _, ok := c.Get(keys[0]); ok {
+			t.Errorf("capacity %d: expected the entry to be evicted", capacity)
+		}
+
+		if v, ok := c.Get(keys[1]); !ok || v != "c" || c.Len() != 1 {
+			t.Errorf("capacity %d: got %q, %t, want c", capacity, v, ok)
+		}
+
+		c.Delete(keys[1])
+		c.Put(keys[2], "d")
+		if v, ok := c.Get(keys[2]); !ok || v != "d" || c.Len() != 1 {
+			t.Errorf("capacity %d: got %q, %t after deleting, want d", capacity, v, ok)
+		}
+	}
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestTTL(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 2, 6, 21, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	c := cache.New[int](100, cache.TTL(time.Minute), cache.Clock(clock.Now), cache.Shards(4))
+
+	// ULIDs from one to ten minutes ago, one of which is put out of order.
+	keys := make([]ulid.ULID, 10)
+	for i := range keys {
+		keys[i] = ulid.MustNew(ulid.Timestamp(start.Add(-time.Duration(10-i)*time.Minute)), ulid.DefaultEntropy())
+	}
+	keys[3], keys[7] = keys[7], keys[3]
+	for i, id := range keys {
+		c.Put(id, i)
+	}
+
+	// ULIDs older than the TTL expire even though they were just put.
+	fresh := ulid.MustNew(ulid.Timestamp(start.Add(-30*time.Second)), ulid.DefaultEntropy())
+	c.Put(fresh, 42)
+
+	if _, ok := c.Get(keys[0]); ok {
+		t.Error("expected a ULID older than the TTL to have expired")
+	}
+
+	if v, ok := c.Get(fresh); !ok || v != 42 {
+		t.Errorf("got %d, %t, want the unexpired ULID", v, ok)
+	}
+
+	if n := c.Expire(); n != len(keys)-1 || c.Len() != 1 {
+		t.Errorf("expected all %d of the remaining old ULIDs to be expired, got %d with %d left", len(keys)-1, n, c.Len())
+	}
+
+	clock.Advance(30 * time.Second)
+	if _, ok := c.Get(fresh); ok {
+		t.Error("expected the ULID to expire with the clock")
+	}
+
+	// Expired entries are removed before evicting the least recently used.
+	c = cache.New[int](2, cache.TTL(time.Minute), cache.Clock(clock.Now), cache.Shards(1))
+	old := ulid.MustNew(ulid.Timestamp(clock.Now().Add(-55*time.Second)), ulid.DefaultEntropy())
+	newer := ulid.MustNew(ulid.Timestamp(clock.Now().Add(-5*time.Second)), ulid.DefaultEntropy())
+	c.Put(newer, 1)
+	c.Put(old, 0)
+	c.Get(newer)
+
+	clock.Advance(10 * time.Second)
+	c.Put(ulid.MustNew(ulid.Timestamp(clock.Now()), ulid.DefaultEntropy()), 2)
+	if _, ok := c.Get(newer); !ok || c.Len() != 2 {
+		t.Error("expected the expired entry to be removed instead of the least recently used entry")
+	}
+
+	if n := cache.New[int](2).Expire(); n != 0 {
+		t.Errorf("expected nothing to expire without a TTL, got %d", n)
+	}
+
+	// ULIDs with timestamps after the year 2262 do not expire immediately.
+	c = cache.New[int](2, cache.TTL(time.Minute), cache.Clock(clock.Now), cache.Shards(1))
+	future := ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	c.Put(future, 1)
+	if v, ok := c.Get(future); !ok || v != 1 || c.Expire() != 0 {
+		t.Errorf("got %d, %t, want the ULID from the far future", v, ok)
+	}
+}
+
+func TestInsertionTTL(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Now()}
+	c := cache.New[int](100, cache.InsertionTTL(time.Minute), cache.Clock(clock.Now), cache.Shards(1))
+
+	// The timestamps of the ULIDs do not matter.
+	keys := []ulid.ULID{ulid.MustNew(0, nil), ulid.MustNew(1, nil), ulid.Make()}
+	for i, id := range keys {
+		c.Put(id, i)
+		clock.Advance(20 * time.Second)
+	}
+
+	// Putting a ULID again extends its deadline.
+	c.Put(keys[0], 3)
+	clock.Advance(25 * time.Second)
+
+	if n := c.Expire(); n != 1 {
+		t.Errorf("expected one entry to be expired, got %d", n)
+	}
+
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.Get(keys[i]); ok != want {
+			t.Errorf("entry %d: got %t, want %t", i, ok, want)
+		}
+	}
+
+	clock.Advance(time.Minute)
+	if n := c.Expire(); n != 2 || c.Len() != 0 {
+		t.Errorf("expected all of the entries to be expired, got %d with %d left", n, c.Len())
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	t.Parallel()
+
+	keys := ids(10000, time.Now().Add(-time.Second))
+	c := cache.New[int](1000, cache.TTL(time.Hour))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < 10000; i++ {
+				k := rng.Intn(len(keys))
+				switch rng.Intn(10) {
+				case 0:
+					c.Delete(keys[k])
+				case 1, 2, 3:
+					c.Put(keys[k], k)
+				case 4:
+					c.Expire()
+					c.Len()
+				default:
+					if v, ok := c.Get(keys[k]); ok && v != k {
+						t.Errorf("got %d for entry %d", v, k)
+						return
+					}
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	if n := c.Len(); n > 1000 {
+		t.Errorf("expected at most 1000 entries, got %d", n)
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	keys := ids(1000, time.Now())
+	c := cache.New[int](100, cache.TTL(time.Hour))
+	for i, id := range keys {
+		c.Put(id, i)
+	}
+
+	// Once the map has grown, evicting and putting does not allocate.
+	i := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		c.Put(keys[i%len(keys)], i)
+		c.Get(keys[(i+500)%len(keys)])
+		i++
+	})
+
+	if allocs > 0 {
+		t.Errorf("expected no allocations, got %.1f", allocs)
+	}
+}
+
+// listLRU is the common container/list and map LRU that the cache replaces.
+type listLRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[ulid.ULID]*list.Element
+}
+
+type listEntry struct {
+	key   ulid.ULID
+	value any
+}
+
+func (c *listLRU) Get(id ulid.ULID) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[id]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*listEntry).value, true
+	}
+	return nil, false
+}
+
+func (c *listLRU) Put(id ulid.ULID, v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[id]; ok {
+		e.Value.(*listEntry).value = v
+		c.order.MoveToFront(e)
+		return
+	}
+
+	if c.order.Len() == c.capacity {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.items, e.Value.(*listEntry).key)
+	}
+	c.items[id] = c.order.PushFront(&listEntry{id, v})
+}
+
+// BenchmarkCache compares the cache with a container/list LRU for a workload of
+// three gets for every put to a cache of a tenth of the keys. The comparison with
+// hashicorp/golang-lru is in the lrubench module, which keeps the dependency out
+// of this module.
+func BenchmarkCache(b *testing.B) {
+	const capacity = 10000
+	keys := ids(capacity*10, time.Now())
+
+	run := func(b *testing.B, get func(ulid.ULID), put func(ulid.ULID, int)) {
+		for _, id := range keys[:capacity] {
+			put(id, 0)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			rng := rand.New(rand.NewSource(rand.Int63()))
+			for i := 0; pb.Next(); i++ {
+				id := keys[rng.Intn(len(keys))]
+				if i%4 == 0 {
+					put(id, i)
+				} else {
+					get(id)
+				}
+			}
+		})
+	}
+
+	b.Run("Cache", func(b *testing.B) {
+		c := cache.New[int](capacity)
+		run(b, func(id ulid.ULID) { c.Get(id) }, c.Put)
+	})
+
+	b.Run("SingleShard", func(b *testing.B) {
+		c := cache.New[int](capacity, cache.Shards(1))
+		run(b, func(id ulid.ULID) { c.Get(id) }, c.Put)
+	})
+
+	b.Run("ContainerList", func(b *testing.B) {
+		c := &listLRU{capacity: capacity, order: list.New(), items: make(map[ulid.ULID]*list.Element, capacity)}
+		run(b, func(id ulid.ULID) { c.Get(id) }, func(id ulid.ULID, v int) { c.Put(id, v) })
+	})
+}
@@ -0,0 +1,10 @@
+module go.rtnl.ai/ulid/cache/lrubench
+
+go 1.23.3
+
+require (
+	github.com/hashicorp/golang-lru/v2 v2.0.7
+	go.rtnl.ai/ulid v0.0.0-00010101000000-000000000000
+)
+
+replace go.rtnl.ai/ulid => ../../
@@ -0,0 +1,6 @@
+// Package lrubench compares the cache package with hashicorp/golang-lru. It is a
+// separate module so that neither the ulid module nor its users depend on
+// golang-lru; run the comparison from this directory with
+//
+//	go test -bench . -cpu 1,4,8
+package lrubench
@@ -0,0 +1,52 @@
+package lrubench_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/cache"
+)
+
+// BenchmarkCache compares the cache with hashicorp/golang-lru for the workload of
+// the BenchmarkCache of the cache package: three gets for every put to a cache of
+// a tenth of the keys.
+func BenchmarkCache(b *testing.B) {
+	const capacity = 10000
+	keys := make([]ulid.ULID, capacity*10)
+	for i, ms := 0, ulid.Timestamp(time.Now()); i < len(keys); i++ {
+		keys[i] = ulid.MustNew(ms+uint64(i), ulid.DefaultEntropy())
+	}
+
+	run := func(b *testing.B, get func(ulid.ULID), put func(ulid.ULID, int)) {
+		for _, id := range keys[:capacity] {
+			put(id, 0)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			rng := rand.New(rand.NewSource(rand.Int63()))
+			for i := 0; pb.Next(); i++ {
+				id := keys[rng.Intn(len(keys))]
+				if i%4 == 0 {
+					put(id, i)
+				} else {
+					get(id)
+				}
+			}
+		})
+	}
+
+	b.Run("Cache", func(b *testing.B) {
+		c := cache.New[int](capacity)
+		run(b, func(id ulid.ULID) { c.Get(id) }, c.Put)
+	})
+
+	b.Run("GolangLRU", func(b *testing.B) {
+		c, _ := lru.New[ulid.ULID, int](capacity)
+		run(b, func(id ulid.ULID) { c.Get(id) }, func(id ulid.ULID, v int) { c.Add(id, v) })
+	})
+}
@@ -0,0 +1,317 @@
+package ulid_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+
+	"go.rtnl.ai/ulid"
+)
+
+// repeatedBatch returns n ULIDs of which about 95% repeat one of a small set of
+// distinct ULIDs, like a foreign key in row-oriented JSON, and the number of
+// distinct ULIDs in the batch.
+func repeatedBatch(rng *rand.Rand, n int) ([]ulid.ULID, int) {
+	keys := randomBatch(rng, 16)
+	ids := make([]ulid.ULID, n)
+	distinct := make(map[ulid.ULID]struct{})
+	for i := range ids {
+		ids[i] = keys[rng.Intn(len(keys))]
+		if rng.Intn(20) == 0 {
+			ids[i] = ulid.MustNew(uint64(rng.Int63n(int64(ulid.MaxTime()))), rng)
+		}
+		distinct[ids[i]] = struct{}{}
+	}
+	return ids, len(distinct)
+}
+
+// cachedRow decodes the ULID field of a row with a cache.
+type cachedRow struct {
+	Account ulid.ULID `json:"account"`
+	Amount  int       `json:"amount"`
+}
+
+var rowCache = ulid.NewDecodingCache(0)
+
+func (r *cachedRow) UnmarshalJSON(data []byte) error {
+	var row struct {
+		Account json.RawMessage `json:"account"`
+		Amount  int             `json:"amount"`
+	}
+
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+
+	r.Amount = row.Amount
+	return rowCache.Unmarshal(row.Account, &r.Account)
+}
+
+func TestDecodingCache(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1435))
+	ids, distinct := repeatedBatch(rng, 10000)
+	data, _ := json.Marshal(ids)
+
+	// The cached ULIDs are identical to decoding without a cache.
+	const slots = 1 << 20
+	cache := ulid.NewDecodingCache(slots)
+	got, err := cache.DecodeJSONArray(json.NewDecoder(bytes.NewReader(data)), nil)
+	if err != nil || !slices.Equal(got, ids) {
+		t.Fatalf("cached ULIDs do not match (%v)", err)
+	}
+
+	// Distinct texts may replace each other in the table, so there are at least as
+	// many misses as distinct ULIDs. Each rare ULID displaces at most one of the 16
+	// repeated ULIDs, which then misses once more, so there are at most twice as
+	// many misses as distinct ULIDs unless two of the repeated ULIDs share a slot
+	// and replace each other on every occurrence, which the hash of the table only
+	// does with a probability of about 16*15/2/slots (0.01%).
+	minHits := uint64(len(ids) - 2*distinct)
+	stats := cache.Stats()
+	if stats.Misses < uint64(distinct) || stats.Hits+stats.Misses != uint64(len(ids)) || stats.Entries > distinct || stats.Hits < minHits {
+		t.Errorf("got %+v for %d distinct ULIDs", stats, distinct)
+	}
+
+	// Decoding again mostly hits the cache.
+	if got, err = cache.DecodeJSONArray(json.NewDecoder(bytes.NewReader(data)), got[:0]); err != nil || !slices.Equal(got, ids) {
+		t.Fatalf("cached ULIDs do not match (%v)", err)
+	}
+
+	if again := cache.Stats(); again.Hits-stats.Hits < minHits {
+		t.Errorf("expected mostly hits, got %+v", again)
+	}
+
+	// The canonical strings are interned.
+	for _, id := range ids {
+		s := id.String()
+		var prev string
+		for i := 0; i < 2; i++ {
+			got, interned, err := cache.ParseString([]byte(s))
+			if err != nil || got != id || interned != s {
+				t.Fatalf("got %s and %q (%v), want %s", got, interned, err, id)
+			}
+
+			if i > 0 && unsafe.StringData(prev) != unsafe.StringData(interned) {
+				t.Fatalf("expected the string of %s to be interned", id)
+			}
+			prev = interned
+		}
+	}
+
+	// A custom unmarshaler decodes the fields with the cache.
+	var rows []cachedRow
+	if err := json.Unmarshal([]byte(`[{"account":"01JKEHNQPA0END3NHMFKB2Y6SE","amount":1},{"account":"01jkehnqpa0end3nhmfkb2y6se","amount":2},{"account":null}]`), &rows); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []cachedRow{{ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), 1}, {ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), 2}, {}}
+	if !slices.Equal(rows, want) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+}
+
+func TestDecodingCacheUncached(t *testing.T) {
+	t.Parallel()
+
+	// Parsing with and without a cache, the first time and when the text is cached,
+	// returns the same ULIDs and errors as UnmarshalText.
+	texts := []string{
+		"01JKEHNQPA0END3NHMFKB2Y6SE",
+		"01jkehnqpa0end3nhmfkb2y6se",
+		"01JKEHNQPA0END3NHMFKB2Y6SU",
+		"81JKEHNQPA0END3NHMFKB2Y6SE",
+		"7ZZZZZZZZZZZZZZZZZZZZZZZZZ",
+		"01JKEHNQPA0END3NHMFKB2Y6S",
+		"",
+	}
+
+	for _, cache := range []*ulid.DecodingCache{nil, ulid.NewDecodingCache(2)} {
+		for i := 0; i < 2; i++ {
+			for _, text := range texts {
+				var want ulid.ULID
+				wantErr := want.UnmarshalText([]byte(text))
+
+				id, err := cache.Parse([]byte(text))
+				if id != want || err != wantErr {
+					t.Errorf("%q: got %s (%v), want %s (%v)", text, id, err, want, wantErr)
+				}
+
+				id, s, err := cache.ParseString([]byte(text))
+				if id != want || err != wantErr || (err == nil && s != want.String()) || (err != nil && s != "") {
+					t.Errorf("%q: got %s and %q (%v), want %s (%v)", text, id, s, err, want, wantErr)
+				}
+			}
+		}
+
+		if stats := cache.Stats(); stats.Entries > 2 {
+			t.Errorf("expected at most 2 entries, got %+v", stats)
+		}
+	}
+
+	var cache *ulid.DecodingCache
+	if got, err := cache.DecodeJSONArray(json.NewDecoder(strings.NewReader(`["01JKEHNQPA0END3NHMFKB2Y6SE"]`)), nil); err != nil || len(got) != 1 {
+		t.Errorf("got %v (%v) from a nil cache", got, err)
+	}
+
+	if stats := cache.Stats(); stats != (ulid.DecodingCacheStats{}) {
+		t.Errorf("expected a nil cache to have no stats, got %+v", stats)
+	}
+}
+
+func TestDecodingCacheErrors(t *testing.T) {
+	t.Parallel()
+
+	cache := ulid.NewDecodingCache(0)
+	testCases := []struct {
+		data string
+		err  error
+	}{
+		{`"01JKEHNQPA0END3NHMFKB2Y6SE"`, nil},
+		{`"01JKEHNQPA0END3NHMFKB2Y6SE"`, nil},
+		{`null`, nil},
+		{`"01JKEHNQPA0END3NHMFKB2Y6S"`, ulid.ErrDataSize},
+		{`"81JKEHNQPA0END3NHMFKB2Y6SE"`, ulid.ErrOverflow},
+		{`42`, ulid.ErrUnknownType},
+	}
+
+	for _, tc := range testCases {
+		// The ULID is decoded like encoding/json decodes it without a cache.
+		want := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+		wantErr := json.Unmarshal([]byte(tc.data), &want)
+
+		id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+		err := cache.Unmarshal([]byte(tc.data), &id)
+		if id != want || !errors.Is(err, tc.err) || (err == nil) != (wantErr == nil) {
+			t.Errorf("%s: got %s (%v), want %s (%v)", tc.data, id, err, want, tc.err)
+		}
+	}
+
+	// Element errors of arrays are reported like DecodeJSONArray.
+	doc := `["01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFKB2Y6S"]`
+	got, err := cache.DecodeJSONArray(json.NewDecoder(strings.NewReader(doc)), nil)
+	want, wantErr := ulid.DecodeJSONArray(json.NewDecoder(strings.NewReader(doc)), nil)
+	if !slices.Equal(got, want) || err.Error() != wantErr.Error() {
+		t.Errorf("got %v (%v), want %v (%v)", got, err, want, wantErr)
+	}
+}
+
+func TestDecodingCacheConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// Concurrent decoders share a cache that is too small for the batch, so that
+	// entries are evicted while they are looked up.
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids, _ := repeatedBatch(rng, 2000)
+	data, _ := json.Marshal(ids)
+	cache := ulid.NewDecodingCache(32)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(intern bool) {
+			defer wg.Done()
+			got, err := cache.DecodeJSONArray(json.NewDecoder(bytes.NewReader(data)), nil)
+			if err != nil || !slices.Equal(got, ids) {
+				t.Errorf("cached ULIDs do not match (%v)", err)
+			}
+
+			if intern {
+				for _, id := range ids {
+					if _, s, err := cache.ParseString([]byte(id.String())); err != nil || s != id.String() {
+						t.Errorf("got %q (%v), want %s", s, err, id)
+						return
+					}
+				}
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+
+	if stats := cache.Stats(); stats.Entries > 32 || stats.Hits+stats.Misses != 8*2000+4*2000 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDecodingCacheAllocs(t *testing.T) {
+	cache := ulid.NewDecodingCache(0)
+	text := []byte("01JKEHNQPA0END3NHMFKB2Y6SE")
+	cache.ParseString(text)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		cache.Parse(text)
+		cache.ParseString(text)
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected cached texts not to allocate, got %v allocations", allocs)
+	}
+}
+
+func BenchmarkDecodingCache(b *testing.B) {
+	ids, _ := repeatedBatch(rand.New(rand.NewSource(time.Now().UnixNano())), 10000)
+	data, _ := json.Marshal(ids)
+	dst := make([]ulid.ULID, 0, len(ids))
+	texts := make([][]byte, len(ids))
+	for i, id := range ids {
+		texts[i] = []byte(id.String())
+	}
+
+	b.Run("DecodeJSONArray", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			dst, _ = ulid.DecodeJSONArray(json.NewDecoder(bytes.NewReader(data)), dst[:0])
+		}
+	})
+
+	b.Run("CachedDecodeJSONArray", func(b *testing.B) {
+		cache := ulid.NewDecodingCache(0)
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			dst, _ = cache.DecodeJSONArray(json.NewDecoder(bytes.NewReader(data)), dst[:0])
+		}
+	})
+
+	// Decoding the canonical strings, e.g. to re-encode them.
+	strs := make([]string, len(texts))
+	b.Run("ParseString", func(b *testing.B) {
+		b.SetBytes(int64(len(texts) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			for j, text := range texts {
+				var id ulid.ULID
+				_ = id.UnmarshalText(text)
+				strs[j] = id.String()
+			}
+		}
+	})
+
+	b.Run("CachedParseString", func(b *testing.B) {
+		cache := ulid.NewDecodingCache(0)
+		b.SetBytes(int64(len(texts) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			for j, text := range texts {
+				_, strs[j], _ = cache.ParseString(text)
+			}
+		}
+	})
+
+	b.Run("CachedParseStringParallel", func(b *testing.B) {
+		cache := ulid.NewDecodingCache(0)
+		b.SetBytes(int64(len(texts) * ulid.EncodedSize))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, text := range texts {
+					_, _, _ = cache.ParseString(text)
+				}
+			}
+		})
+	})
+}
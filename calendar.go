@@ -0,0 +1,158 @@
+package ulid
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+// CalendarUnit is a unit of the calendar of a location, e.g. the days in
+// America/New_York, whose buckets start at local midnight and so are 23 or 25
+// hours long on the days of daylight saving time transitions, unlike buckets of
+// a fixed duration such as those of TruncateTime. The zero value is Day, so that
+// an unset unit, e.g. of a configuration, buckets by day.
+type CalendarUnit uint8
+
+// The calendar units; weeks start on Monday as in ISO 8601, use WeekStarting for
+// weeks that start on another day.
+const (
+	Day   CalendarUnit = 0
+	Month CalendarUnit = 1
+	Week               = weekUnit + CalendarUnit(time.Monday)
+)
+
+// weekUnit is the unit of weeks that start on Sunday; the units of weeks that
+// start on the other days follow it.
+const weekUnit CalendarUnit = 8
+
+// WeekStarting returns the unit of weeks that start on the weekday, e.g.
+// WeekStarting(time.Sunday) for calendars in the United States. WeekStarting
+// returns Week for time.Monday.
+func WeekStarting(day time.Weekday) CalendarUnit {
+	return weekUnit + CalendarUnit(day%7)
+}
+
+// String returns the name of the unit, e.g. "day" or "week (Sunday)" for weeks
+// that do not start on Monday.
+func (u CalendarUnit) String() string {
+	switch {
+	case u == Day:
+		return "day"
+	case u == Month:
+		return "month"
+	case u == Week:
+		return "week"
+	case u.valid():
+		return fmt.Sprintf("week (%s)", time.Weekday(u-weekUnit))
+	default:
+		return fmt.Sprintf("CalendarUnit(%d)", uint8(u))
+	}
+}
+
+func (u CalendarUnit) valid() bool {
+	return u == Day || u == Month || (u >= weekUnit && u < weekUnit+7)
+}
+
+// start returns the local midnight that starts the bucket of the unit that
+// contains t.
+func (u CalendarUnit) start(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	year, month, day := local.Date()
+	switch u {
+	case Day:
+	case Month:
+		day = 1
+	default:
+		day -= (int(local.Weekday()) - int(u-weekUnit) + 7) % 7
+	}
+	return midnight(year, month, day, loc)
+}
+
+// next returns the start of the bucket after the bucket that starts at start.
+func (u CalendarUnit) next(start time.Time, loc *time.Location) time.Time {
+	year, month, day := start.In(loc).Date()
+	switch u {
+	case Day:
+		day++
+	case Month:
+		month++
+	default:
+		day += 7
+	}
+	return midnight(year, month, day, loc)
+}
+
+// midnight returns the first instant of the local date, normalized like
+// time.Date. If the clocks skip midnight for daylight saving time, e.g. in
+// America/Havana, the day starts when the clocks skip to 01:00.
+func midnight(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if t.Hour() != 0 && t.Day() != time.Date(year, month, day, 12, 0, 0, 0, loc).Day() {
+		// time.Date returns the skipped midnight in the offset after the
+		// transition, which is the evening before.
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// BucketOf returns the start of the bucket of the unit in the location that
+// contains the timestamp of the ULID, e.g. the local midnight of its day to name
+// the daily partition of the ID. The location nil is UTC. It panics with a
+// *PanicError wrapping ErrInvalidCalendarUnit if the unit is not valid.
+func BucketOf(id ULID, unit CalendarUnit, loc *time.Location) time.Time {
+	if !unit.valid() {
+		panic(newPanicError("BucketOf", unit, ErrInvalidCalendarUnit))
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	return unit.start(id.Timestamp(), loc)
+}
+
+// CalendarRange returns the inclusive ULIDRange of each bucket of the unit in the
+// location from the bucket that contains from to the bucket that contains to, in
+// order, e.g. to scan a table partitioned by local day one partition at a time.
+// Each range covers the ULIDs from the local midnight that starts the bucket to
+// the millisecond before the next one, so the ranges are contiguous and the first
+// and last ranges may extend beyond from and to. The buckets are clamped to the
+// range of ULID timestamps, as are from and to; no ranges are returned if from is
+// after to. The location nil is UTC. It panics with a *PanicError wrapping
+// ErrInvalidCalendarUnit if the unit is not valid.
+func CalendarRange(from, to time.Time, unit CalendarUnit, loc *time.Location) iter.Seq[ULIDRange] {
+	if !unit.valid() {
+		panic(newPanicError("CalendarRange", unit, ErrInvalidCalendarUnit))
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	lo, hi := cutoffTime(from), min(cutoffTime(to), maxTime)
+	return func(yield func(ULIDRange) bool) {
+		if from.After(to) {
+			return
+		}
+
+		for start := unit.start(Time(min(lo, maxTime)), loc); start.UnixMilli() <= int64(hi); {
+			next := unit.next(start, loc)
+			first := uint64(max(start.UnixMilli(), 0))
+			last := min(uint64(next.UnixMilli()-1), maxTime)
+			if !yield(timeRange(first, last)) {
+				return
+			}
+			start = next
+		}
+	}
+}
+
+// timeRange returns the inclusive ULIDRange of the ULIDs whose timestamp is from
+// the millisecond lo to the millisecond hi, which must be timestamps of ULIDs.
+func timeRange(lo, hi uint64) (r ULIDRange) {
+	_ = r.Lo.SetTime(lo)
+	_ = r.Hi.SetTime(hi)
+	for i := 6; i < len(r.Hi); i++ {
+		r.Hi[i] = 0xFF
+	}
+	return r
+}
@@ -0,0 +1,242 @@
+package ulid_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"go.rtnl.ai/ulid"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// bucketStarts returns the start of each range in the location and checks that
+// the ranges are contiguous with full entropy bounds.
+func bucketStarts(t *testing.T, ranges []ulid.ULIDRange, loc *time.Location) []time.Time {
+	t.Helper()
+	starts := make([]time.Time, len(ranges))
+	for i, r := range ranges {
+		if !isZeros(r.Lo.Entropy()) || r.Hi.Entropy()[0] != 0xFF || r.Hi.Entropy()[9] != 0xFF {
+			t.Errorf("range %d: expected the bounds of the entropy, got %s to %s", i, r.Lo, r.Hi)
+		}
+
+		if i > 0 && r.Lo.Time() != ranges[i-1].Hi.Time()+1 {
+			t.Errorf("range %d: starts at %d, want %d", i, r.Lo.Time(), ranges[i-1].Hi.Time()+1)
+		}
+		starts[i] = r.Lo.Timestamp().In(loc)
+	}
+	return starts
+}
+
+func TestCalendarRangeDST(t *testing.T) {
+	t.Parallel()
+
+	ny := mustLoadLocation(t, "America/New_York")
+	testCases := []struct {
+		name     string
+		from, to time.Time
+		unit     ulid.CalendarUnit
+		starts   []time.Time
+		hours    []time.Duration
+	}{
+		{
+			// Clocks spring forward at 2:00 on Sunday, March 9, 2025, so the day is
+			// 23 hours long.
+			"SpringForward",
+			time.Date(2025, 3, 8, 12, 0, 0, 0, ny), time.Date(2025, 3, 10, 0, 0, 0, 0, ny),
+			ulid.Day,
+			[]time.Time{time.Date(2025, 3, 8, 5, 0, 0, 0, time.UTC), time.Date(2025, 3, 9, 5, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 4, 0, 0, 0, time.UTC)},
+			[]time.Duration{24, 23, 24},
+		},
+		{
+			// Clocks fall back at 2:00 on Sunday, November 2, 2025, so the day is 25
+			// hours long.
+			"FallBack",
+			time.Date(2025, 11, 2, 1, 30, 0, 0, ny), time.Date(2025, 11, 2, 1, 30, 0, 0, ny).Add(time.Hour),
+			ulid.Day,
+			[]time.Time{time.Date(2025, 11, 2, 4, 0, 0, 0, time.UTC)},
+			[]time.Duration{25},
+		},
+		{
+			"WeekMonday",
+			time.Date(2025, 3, 9, 12, 0, 0, 0, ny), time.Date(2025, 3, 10, 12, 0, 0, 0, ny),
+			ulid.Week,
+			[]time.Time{time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 4, 0, 0, 0, time.UTC)},
+			[]time.Duration{7*24 - 1, 7 * 24},
+		},
+		{
+			"WeekSunday",
+			time.Date(2025, 3, 9, 12, 0, 0, 0, ny), time.Date(2025, 3, 10, 12, 0, 0, 0, ny),
+			ulid.WeekStarting(time.Sunday),
+			[]time.Time{time.Date(2025, 3, 9, 5, 0, 0, 0, time.UTC)},
+			[]time.Duration{7*24 - 1},
+		},
+		{
+			"Month",
+			time.Date(2025, 10, 31, 23, 0, 0, 0, ny), time.Date(2025, 11, 1, 0, 0, 0, 0, ny),
+			ulid.Month,
+			[]time.Time{time.Date(2025, 10, 1, 4, 0, 0, 0, time.UTC), time.Date(2025, 11, 1, 4, 0, 0, 0, time.UTC)},
+			[]time.Duration{31 * 24, 30*24 + 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		ranges := slices.Collect(ulid.CalendarRange(tc.from, tc.to, tc.unit, ny))
+		starts := bucketStarts(t, ranges, ny)
+		if len(starts) != len(tc.starts) {
+			t.Errorf("%s: got buckets starting at %v, want %v", tc.name, starts, tc.starts)
+			continue
+		}
+
+		for i, r := range ranges {
+			if !starts[i].Equal(tc.starts[i]) {
+				t.Errorf("%s: bucket %d starts at %s, want %s", tc.name, i, starts[i], tc.starts[i].In(ny))
+			}
+
+			if h, want := time.Duration(r.Hi.Time()+1-r.Lo.Time())*time.Millisecond, tc.hours[i]*time.Hour; h != want {
+				t.Errorf("%s: bucket %d is %s long, want %s", tc.name, i, h, want)
+			}
+
+			// Every ULID of the range is in the bucket.
+			for _, id := range []ulid.ULID{r.Lo, r.Hi} {
+				if got := ulid.BucketOf(id, tc.unit, ny); !got.Equal(starts[i]) || got.Location() != ny {
+					t.Errorf("%s: %s is in the bucket of %s, want %s", tc.name, id.Timestamp(), got, starts[i])
+				}
+			}
+		}
+	}
+}
+
+func TestCalendarRangeLeapDay(t *testing.T) {
+	t.Parallel()
+
+	// February 2024 has 29 days, and February 29 is a Thursday.
+	leap := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	months := slices.Collect(ulid.CalendarRange(leap, leap, ulid.Month, nil))
+	if len(months) != 1 || !months[0].Lo.Timestamp().Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) || !months[0].Hi.Timestamp().Equal(time.Date(2024, 2, 29, 23, 59, 59, 999e6, time.UTC)) {
+		t.Errorf("got months %v", months)
+	}
+
+	days := slices.Collect(ulid.CalendarRange(time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), ulid.Day, time.UTC))
+	starts := bucketStarts(t, days, time.UTC)
+	if len(starts) != 3 || starts[1].Day() != 29 || starts[2].Month() != time.March {
+		t.Errorf("got days starting at %v", starts)
+	}
+
+	id := ulid.MustNew(ulid.Timestamp(leap), nil)
+	testCases := []struct {
+		unit     ulid.CalendarUnit
+		expected time.Time
+	}{
+		{ulid.Day, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{ulid.Week, time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC)},
+		{ulid.WeekStarting(time.Sunday), time.Date(2024, 2, 25, 0, 0, 0, 0, time.UTC)},
+		{ulid.WeekStarting(time.Saturday), time.Date(2024, 2, 24, 0, 0, 0, 0, time.UTC)},
+		{ulid.WeekStarting(time.Thursday), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{ulid.WeekStarting(time.Friday), time.Date(2024, 2, 23, 0, 0, 0, 0, time.UTC)},
+		{ulid.Month, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		if got := ulid.BucketOf(id, tc.unit, nil); !got.Equal(tc.expected) {
+			t.Errorf("%s: got %s, want %s", tc.unit, got, tc.expected)
+		}
+	}
+
+	// A year of days and weeks in a location with daylight saving time.
+	ny := mustLoadLocation(t, "America/New_York")
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, ny), time.Date(2024, 12, 31, 23, 0, 0, 0, ny)
+	if starts := bucketStarts(t, slices.Collect(ulid.CalendarRange(from, to, ulid.Day, ny)), ny); len(starts) != 366 || starts[59] != time.Date(2024, 2, 29, 0, 0, 0, 0, ny) {
+		t.Errorf("got %d days", len(starts))
+	}
+
+	for _, start := range bucketStarts(t, slices.Collect(ulid.CalendarRange(from, to, ulid.Week, ny)), ny) {
+		if start.Weekday() != time.Monday || start.Hour() != 0 {
+			t.Errorf("got a week starting at %s", start)
+		}
+	}
+}
+
+func TestCalendarRangeSkippedMidnight(t *testing.T) {
+	t.Parallel()
+
+	// On March 12, 2023 the clocks of Havana skipped from midnight to 1:00, so the
+	// day starts at 1:00.
+	havana := mustLoadLocation(t, "America/Havana")
+	id := ulid.MustNew(ulid.Timestamp(time.Date(2023, 3, 12, 12, 0, 0, 0, havana)), nil)
+	start := ulid.BucketOf(id, ulid.Day, havana)
+	if want := time.Date(2023, 3, 12, 5, 0, 0, 0, time.UTC); !start.Equal(want) || start.Hour() != 1 {
+		t.Errorf("got %s, want %s", start, want.In(havana))
+	}
+
+	ranges := slices.Collect(ulid.CalendarRange(time.Date(2023, 3, 11, 12, 0, 0, 0, havana), id.Timestamp(), ulid.Day, havana))
+	if starts := bucketStarts(t, ranges, havana); len(starts) != 2 || !starts[1].Equal(start) {
+		t.Errorf("got days starting at %v", starts)
+	}
+}
+
+func TestCalendarRangeBounds(t *testing.T) {
+	t.Parallel()
+
+	ny := mustLoadLocation(t, "America/New_York")
+
+	// The bucket of the Unix epoch starts before it in New York and is clamped.
+	ranges := slices.Collect(ulid.CalendarRange(time.Unix(-86400, 0), time.Unix(0, 0), ulid.Day, ny))
+	if len(ranges) != 1 || ranges[0].Lo != ulid.Zero || !ranges[0].Hi.Timestamp().Equal(time.Date(1970, 1, 1, 5, 0, 0, 0, time.UTC).Add(-time.Millisecond)) {
+		t.Errorf("got ranges %v", ranges)
+	}
+
+	// The last bucket ends at the maximum timestamp.
+	ranges = slices.Collect(ulid.CalendarRange(ulid.MaxTimestampTime().Add(-time.Hour), ulid.MaxTimestampTime().Add(time.Hour), ulid.Month, nil))
+	if len(ranges) != 1 || ranges[0].Hi != ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ") {
+		t.Errorf("got ranges %v", ranges)
+	}
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, ny)
+	if ranges := slices.Collect(ulid.CalendarRange(from, from.Add(-time.Millisecond), ulid.Day, ny)); len(ranges) != 0 {
+		t.Errorf("expected no ranges when from is after to, got %v", ranges)
+	}
+
+	// The iteration can stop early.
+	n := 0
+	for range ulid.CalendarRange(from, from.AddDate(1, 0, 0), ulid.Day, ny) {
+		if n++; n == 3 {
+			break
+		}
+	}
+
+	if n != 3 {
+		t.Errorf("got %d ranges, want 3", n)
+	}
+
+	// The zero value is a day.
+	var unit ulid.CalendarUnit
+	if id := ulid.MustNew(ulid.Timestamp(from), nil); unit != ulid.Day || !ulid.BucketOf(id, unit, ny).Equal(ulid.BucketOf(id, ulid.Day, ny)) {
+		t.Errorf("expected the zero value to be %s, got %s", ulid.Day, unit)
+	}
+
+	for _, unit := range []ulid.CalendarUnit{2, 3, 15} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ulid.ErrInvalidCalendarUnit) {
+					t.Errorf("%s: got panic %v, want %v", unit, err, ulid.ErrInvalidCalendarUnit)
+				}
+			}()
+			ulid.CalendarRange(from, from, unit, ny)
+		}()
+	}
+
+	if s := ulid.WeekStarting(time.Sunday).String(); s != "week (Sunday)" || ulid.WeekStarting(time.Monday) != ulid.Week || ulid.Week.String() != "week" {
+		t.Errorf("got %s", s)
+	}
+}
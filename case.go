@@ -0,0 +1,172 @@
+package ulid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StringCase is the case of the letters of an encoded ULID, as reported by
+// ReportCase, e.g. to audit a text column written by a legacy lowercase writer.
+type StringCase uint8
+
+const (
+	CaseInvalid StringCase = iota // The string is not an encoded ULID
+	CaseUpper                     // All letters are uppercase, as in the canonical encoding
+	CaseLower                     // All letters are lowercase
+	CaseMixed                     // There are both uppercase and lowercase letters
+)
+
+var stringCaseNames = [...]string{"invalid", "upper", "lower", "mixed"}
+
+func (c StringCase) String() string {
+	if int(c) < len(stringCaseNames) {
+		return stringCaseNames[c]
+	}
+	return fmt.Sprintf("StringCase(%d)", c)
+}
+
+// ReportCase returns the case of the letters of the encoded ULID, or CaseInvalid
+// if s is not a valid encoded ULID as with ParseStrict. A ULID without letters,
+// e.g. the zero ULID, is reported as CaseUpper since it is canonical. ReportCase
+// validates the characters without decoding the ULID and does not allocate, so
+// it is cheaper than parsing to count the affected rows of a large table.
+func ReportCase(s string) StringCase {
+	return reportCase(s)
+}
+
+func reportCase[T string | []byte](s T) StringCase {
+	if len(s) != EncodedSize || s[0] > '7' {
+		return CaseInvalid
+	}
+
+	// Accumulate the decoded values and the classes of the characters without
+	// branching, since the case of each letter is unpredictable in mixed input:
+	// the top three bits of a character are 1 for digits, 2 for uppercase letters,
+	// and 3 for lowercase letters.
+	var bad byte
+	var classes uint8
+	for i := 0; i < len(s); i++ {
+		bad |= dec[s[i]]
+		classes |= 1 << (s[i] >> 5)
+	}
+
+	if bad == 0xFF {
+		return CaseInvalid
+	}
+
+	switch classes & 0b1100 {
+	case 0b1100:
+		return CaseMixed
+	case 0b1000:
+		return CaseLower
+	}
+	return CaseUpper
+}
+
+// NormalizeCaseBatch rewrites the encoded ULIDs of the slice in place to their
+// canonical uppercase encoding and returns the number of strings that changed,
+// e.g. to migrate the rows of a column written by a legacy lowercase writer. The
+// strings that are already uppercase are not copied and the strings that changed
+// share a single allocation, however many of them there are.
+//
+// Strings that are not valid encoded ULIDs are left unchanged and the rest of the
+// batch is still normalized; the first invalid string is reported with a
+// *BatchError with its index and the total length of the strings before it,
+// wrapping the error that ParseStrict would return.
+func NormalizeCaseBatch(ss []string) (changed int, err error) {
+	var offset int64
+	for i, s := range ss {
+		switch ReportCase(s) {
+		case CaseLower, CaseMixed:
+			changed++
+		case CaseInvalid:
+			if err == nil {
+				var id ULID
+				err = &BatchError{Index: i, Offset: offset, Err: parse([]byte(s), true, &id)}
+			}
+		}
+		offset += int64(len(s))
+	}
+
+	if changed == 0 {
+		return 0, err
+	}
+
+	// The builder never grows, so the strings of its contents share one buffer.
+	var sb strings.Builder
+	sb.Grow(changed * EncodedSize)
+	for i, s := range ss {
+		if c := ReportCase(s); c == CaseLower || c == CaseMixed {
+			// Clear the lowercase bit of letters without branching; the bit that
+			// is shifted into its place is only set for lowercase letters.
+			var buf [EncodedSize]byte
+			for j := 0; j < EncodedSize; j++ {
+				buf[j] = s[j] &^ (s[j] >> 1 & 0x20)
+			}
+
+			n := sb.Len()
+			sb.Write(buf[:])
+			ss[i] = sb.String()[n:]
+		}
+	}
+	return changed, err
+}
+
+// ScanCaseInsensitive scans a ULID from a text column that contains both
+// uppercase and lowercase encodings of ULIDs, e.g. written by a legacy lowercase
+// writer to a column with a case-insensitive collation. The text is parsed
+// strictly like ParseStrict in either case, unlike ULID.Scan which decodes a
+// []byte as the binary encoding, and the case of the text is recorded so that
+// migrations can rewrite the rows that are not canonical.
+//
+// A []byte of 16 bytes is scanned as the binary encoding and other values are
+// scanned like ULID.Scan, with a Case of CaseUpper. A NULL is scanned as the zero
+// ULID with a Case of CaseInvalid.
+type ScanCaseInsensitive struct {
+	// ULID is the scanned ULID.
+	ULID ULID
+
+	// Case is the case of the text that was scanned.
+	Case StringCase
+}
+
+// Scan implements the sql.Scanner interface. If an error is returned, the ULID
+// and Case are not modified.
+func (s *ScanCaseInsensitive) Scan(src any) error {
+	var text []byte
+	switch x := src.(type) {
+	case nil:
+		s.ULID, s.Case = Zero, CaseInvalid
+		return nil
+	case string:
+		// Copy the text to the stack rather than allocating a []byte.
+		if len(x) != EncodedSize {
+			return ErrDataSize
+		}
+
+		var buf [EncodedSize]byte
+		copy(buf[:], x)
+		text = buf[:]
+	case []byte:
+		if len(x) != 16 {
+			text = x
+		}
+	}
+
+	var id ULID
+	if text == nil {
+		if err := id.Scan(src); err != nil {
+			return err
+		}
+
+		s.ULID, s.Case = id, CaseUpper
+		return nil
+	}
+
+	if err := parse(text, true, &id); err != nil {
+		return err
+	}
+
+	s.ULID, s.Case = id, reportCase(text)
+	return nil
+}
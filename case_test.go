@@ -0,0 +1,245 @@
+package ulid_test
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"go.rtnl.ai/ulid"
+)
+
+// mixedCorpus returns the encodings of n random ULIDs of which about a third are
+// lowercase, a third have the case of each letter chosen at random, and the rest
+// are uppercase.
+func mixedCorpus(rng *rand.Rand, n int) []string {
+	ss := make([]string, n)
+	for i, id := range randomBatch(rng, n) {
+		s := id.String()
+		switch rng.Intn(3) {
+		case 0:
+			s = strings.ToLower(s)
+		case 1:
+			s = strings.Map(func(r rune) rune {
+				if rng.Intn(2) == 0 {
+					return unicode.ToLower(r)
+				}
+				return r
+			}, s)
+		}
+		ss[i] = s
+	}
+	return ss
+}
+
+func TestReportCase(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		s        string
+		expected ulid.StringCase
+	}{
+		{"01HTNMW2JAW89YSBG7NFPHABA4", ulid.CaseUpper},
+		{"00000000000000000000000000", ulid.CaseUpper},
+		{"01htnmw2jaw89ysbg7nfphaba4", ulid.CaseLower},
+		{"01HTNMW2JAW89YSBG7NFPHABa4", ulid.CaseMixed},
+		{"01htnmw2jaw89ysbg7nfphabA4", ulid.CaseMixed},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", ulid.CaseUpper},
+		{"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", ulid.CaseInvalid},
+		{"01HTNMW2JAW89YSBG7NFPHABAU", ulid.CaseInvalid},
+		{"01htnmw2jaw89ysbg7nfphabal", ulid.CaseInvalid},
+		{"01HTNMW2JAW89YSBG7NFPHABA", ulid.CaseInvalid},
+		{"01HTNMW2JAW89YSBG7NFPHABA4 ", ulid.CaseInvalid},
+		{"", ulid.CaseInvalid},
+	}
+
+	for _, tc := range testCases {
+		if got := ulid.ReportCase(tc.s); got != tc.expected {
+			t.Errorf("%q: got %s, want %s", tc.s, got, tc.expected)
+		}
+
+		// Strings are valid exactly when ParseStrict parses them.
+		if _, err := ulid.ParseStrict(tc.s); (err == nil) != (tc.expected != ulid.CaseInvalid) {
+			t.Errorf("%q: reported %s but ParseStrict returned %v", tc.s, tc.expected, err)
+		}
+	}
+
+	if s := ulid.StringCase(42).String(); s != "StringCase(42)" {
+		t.Errorf("got %q for an unknown case", s)
+	}
+}
+
+func TestNormalizeCaseBatch(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ss := mixedCorpus(rng, 1000)
+	want := make([]string, len(ss))
+	var lowered int
+	for i, s := range ss {
+		want[i] = strings.ToUpper(s)
+		if s != want[i] {
+			lowered++
+		}
+	}
+
+	changed, err := ulid.NormalizeCaseBatch(ss)
+	if err != nil || changed != lowered {
+		t.Fatalf("got %d changed (%v), want %d", changed, err, lowered)
+	}
+
+	for i, s := range ss {
+		if s != want[i] || !ulid.IsCanonical(s) {
+			t.Fatalf("string %d: got %q, want %q", i, s, want[i])
+		}
+	}
+
+	// Normalizing again changes nothing.
+	if changed, err := ulid.NormalizeCaseBatch(ss); err != nil || changed != 0 {
+		t.Errorf("got %d changed (%v) for a normalized batch", changed, err)
+	}
+
+	if changed, err := ulid.NormalizeCaseBatch(nil); err != nil || changed != 0 {
+		t.Errorf("got %d changed (%v) for an empty batch", changed, err)
+	}
+}
+
+func TestNormalizeCaseBatchErrors(t *testing.T) {
+	t.Parallel()
+
+	ss := []string{"01htnmw2jaw89ysbg7nfphaba4", "not a ulid", "01JKEHNQPA0END3NHMFKB2Y6SE", "01jkehnqpa0end3nhmfkb2y6su", "01jkehnqpa0end3nhmfkb2y6se"}
+	changed, err := ulid.NormalizeCaseBatch(ss)
+	if changed != 2 {
+		t.Errorf("got %d changed, want 2", changed)
+	}
+
+	// The first invalid string is reported and the rest of the batch is normalized.
+	var berr *ulid.BatchError
+	if !errors.As(err, &berr) || !errors.Is(err, ulid.ErrDataSize) || berr.Index != 1 || berr.Offset != 26 {
+		t.Fatalf("got error %v, want a *BatchError for record 1", err)
+	}
+
+	want := []string{"01HTNMW2JAW89YSBG7NFPHABA4", "not a ulid", "01JKEHNQPA0END3NHMFKB2Y6SE", "01jkehnqpa0end3nhmfkb2y6su", "01JKEHNQPA0END3NHMFKB2Y6SE"}
+	for i, s := range ss {
+		if s != want[i] {
+			t.Errorf("string %d: got %q, want %q", i, s, want[i])
+		}
+	}
+}
+
+func TestScanCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	testCases := []struct {
+		name     string
+		src      any
+		expected ulid.ULID
+		kind     ulid.StringCase
+	}{
+		{"Upper", "01HTNMW2JAW89YSBG7NFPHABA4", id, ulid.CaseUpper},
+		{"Lower", "01htnmw2jaw89ysbg7nfphaba4", id, ulid.CaseLower},
+		{"Mixed", []byte("01HTNMW2JAW89YSBG7NFphaba4"), id, ulid.CaseMixed},
+		{"Binary", id.Bytes(), id, ulid.CaseUpper},
+		{"Timestamp", int64(1000), ulid.MustNew(1000, nil), ulid.CaseUpper},
+		{"Null", nil, ulid.Zero, ulid.CaseInvalid},
+	}
+
+	for _, tc := range testCases {
+		s := ulid.ScanCaseInsensitive{ULID: ulid.Make(), Case: ulid.CaseMixed}
+		if err := s.Scan(tc.src); err != nil || s.ULID != tc.expected || s.Case != tc.kind {
+			t.Errorf("%s: got %s (%s, %v), want %s (%s)", tc.name, s.ULID, s.Case, err, tc.expected, tc.kind)
+		}
+	}
+
+	for _, tc := range []struct {
+		src any
+		err error
+	}{
+		{"01htnmw2jaw89ysbg7nfphabau", ulid.ErrInvalidCharacters},
+		{[]byte("01htnmw2jaw89ysbg7nfphaba"), ulid.ErrDataSize},
+		{"01HTNMW2JAW89YSBG7NFPHABA4 ", ulid.ErrDataSize},
+		{"81HTNMW2JAW89YSBG7NFPHABA4", ulid.ErrOverflow},
+		{3.14, ulid.ErrScanValue},
+	} {
+		s := ulid.ScanCaseInsensitive{ULID: id, Case: ulid.CaseLower}
+		if err := s.Scan(tc.src); err != tc.err || s.ULID != id || s.Case != ulid.CaseLower {
+			t.Errorf("%v: got %s (%s, %v), want error %v", tc.src, s.ULID, s.Case, err, tc.err)
+		}
+	}
+}
+
+func TestCaseAllocs(t *testing.T) {
+	ss := mixedCorpus(rand.New(rand.NewSource(1)), 1024)
+	batch := make([]string, len(ss))
+	var s ulid.ScanCaseInsensitive
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, x := range ss {
+			ulid.ReportCase(x)
+			_ = s.Scan(x)
+		}
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected ReportCase and Scan not to allocate, got %v allocations", allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() {
+		copy(batch, ss)
+		ulid.NormalizeCaseBatch(batch)
+	})
+
+	if allocs != 1 {
+		t.Errorf("expected one allocation for a batch, got %v", allocs)
+	}
+}
+
+func BenchmarkReportCase(b *testing.B) {
+	ss := mixedCorpus(rand.New(rand.NewSource(time.Now().UnixNano())), 1024)
+
+	b.Run("ReportCase", func(b *testing.B) {
+		b.SetBytes(int64(len(ss) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			for _, s := range ss {
+				_ = ulid.ReportCase(s)
+			}
+		}
+	})
+
+	b.Run("Canonicalize", func(b *testing.B) {
+		b.SetBytes(int64(len(ss) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			for _, s := range ss {
+				_, _ = ulid.Canonicalize(s)
+			}
+		}
+	})
+}
+
+func BenchmarkNormalizeCaseBatch(b *testing.B) {
+	ss := mixedCorpus(rand.New(rand.NewSource(time.Now().UnixNano())), 1024)
+	batch := make([]string, len(ss))
+
+	b.Run("Batch", func(b *testing.B) {
+		b.SetBytes(int64(len(ss) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			copy(batch, ss)
+			_, _ = ulid.NormalizeCaseBatch(batch)
+		}
+	})
+
+	b.Run("Canonicalize", func(b *testing.B) {
+		b.SetBytes(int64(len(ss) * ulid.EncodedSize))
+		for i := 0; i < b.N; i++ {
+			copy(batch, ss)
+			for j, s := range batch {
+				if c, err := ulid.Canonicalize(s); err == nil && c != s {
+					batch[j] = c
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package ulid
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Sizes of the checked envelopes of a ULID.
+const (
+	CheckedSize        = 20
+	CheckedEncodedSize = 32
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// EncodeChecked returns the checked envelope of the ULID for transmission over
+// channels that may corrupt the data, e.g. a radio link where a flipped bit would
+// otherwise yield a different but valid ULID. The envelope is the 16 bytes of the
+// ULID followed by the CRC-32C (Castagnoli, polynomial 0x1EDC6F41, reflected
+// 0x82F63B78, as computed by hash/crc32 with the crc32.Castagnoli table) of the 16
+// bytes as a big endian uint32.
+//
+// The checksum detects every corruption of one or two bits and every burst of up
+// to 32 bits, but other corruption is only detected with a probability of about
+// 1 - 2^-32; it is not a cryptographic integrity check.
+func (id ULID) EncodeChecked() (envelope [CheckedSize]byte) {
+	copy(envelope[:], id[:])
+	binary.BigEndian.PutUint32(envelope[len(id):], crc32.Checksum(id[:], castagnoli))
+	return envelope
+}
+
+// DecodeChecked decodes a checked envelope returned by EncodeChecked. ErrDataSize
+// is returned if b is not 20 bytes long and ErrChecksumMismatch is returned if the
+// checksum does not match the ULID.
+func DecodeChecked(b []byte) (id ULID, err error) {
+	if len(b) != CheckedSize {
+		return Zero, ErrDataSize
+	}
+
+	copy(id[:], b)
+	if binary.BigEndian.Uint32(b[len(id):]) != crc32.Checksum(id[:], castagnoli) {
+		return Zero, ErrChecksumMismatch
+	}
+	return id, nil
+}
+
+// EncodeCheckedText returns the checked envelope of the ULID as text: the 160 bits
+// of the envelope returned by EncodeChecked encoded with the base32 alphabet of
+// ULIDs, most significant bits first and without padding (32 characters). Since
+// exactly 5 bits are encoded by each character, a corrupted character is a burst
+// error that is always detected. The text sorts in the same order as the ULIDs
+// but, because of the different alignment, does not start with the string
+// encoding of the ULID.
+func (id ULID) EncodeCheckedText() string {
+	envelope := id.EncodeChecked()
+
+	var dst [CheckedEncodedSize]byte
+	for i := 0; i < len(dst)/8; i++ {
+		// Each group of 5 bytes is encoded as 8 characters.
+		var group uint64
+		for _, b := range envelope[i*5 : i*5+5] {
+			group = group<<8 | uint64(b)
+		}
+
+		for j := 7; j >= 0; j-- {
+			dst[i*8+j] = Encoding[group&31]
+			group >>= 5
+		}
+	}
+	return string(dst[:])
+}
+
+// DecodeCheckedText decodes a checked envelope returned by EncodeCheckedText,
+// accepting lowercase characters like Parse. ErrDataSize is returned if s is not
+// 32 characters long, ErrInvalidCharacters is returned if s contains characters
+// that are not in the base32 alphabet, and ErrChecksumMismatch is returned if the
+// checksum does not match the ULID.
+func DecodeCheckedText(s string) (ULID, error) {
+	if len(s) != CheckedEncodedSize {
+		return Zero, ErrDataSize
+	}
+
+	var envelope [CheckedSize]byte
+	for i := 0; i < len(s)/8; i++ {
+		var group uint64
+		for j := 0; j < 8; j++ {
+			v := dec[s[i*8+j]]
+			if v == 0xFF {
+				return Zero, ErrInvalidCharacters
+			}
+			group = group<<5 | uint64(v)
+		}
+
+		for j := 4; j >= 0; j-- {
+			envelope[i*5+j] = byte(group)
+			group >>= 8
+		}
+	}
+	return DecodeChecked(envelope[:])
+}
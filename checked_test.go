@@ -0,0 +1,157 @@
+package ulid_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestCheckedGolden(t *testing.T) {
+	t.Parallel()
+
+	// The CRC-32C check value pins the polynomial.
+	if sum := crc32.Checksum([]byte("123456789"), crc32.MakeTable(crc32.Castagnoli)); sum != 0xE3069283 {
+		t.Fatalf("unexpected CRC-32C check value %08x", sum)
+	}
+
+	for _, tc := range []struct {
+		id       ulid.ULID
+		envelope string
+		text     string
+	}{
+		{ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV"), "01563e3ab5d3d6764c61efb99302bd5b31604de9", "05B3WENNTFB7CK31XYWS60NXBCRP0KF9"},
+		{ulid.Zero, "0000000000000000000000000000000042709aea", "000000000000000000000000011716QA"},
+		{ulid.ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, "ffffffffffffffffffffffffffffffffef2f4c10", "ZZZZZZZZZZZZZZZZZZZZZZZZZZQJYK0G"},
+	} {
+		envelope := tc.id.EncodeChecked()
+		if got := hex.EncodeToString(envelope[:]); got != tc.envelope {
+			t.Errorf("%s: got envelope %s, want %s", tc.id, got, tc.envelope)
+		}
+
+		if got := tc.id.EncodeCheckedText(); got != tc.text {
+			t.Errorf("%s: got text %s, want %s", tc.id, got, tc.text)
+		}
+
+		if id, err := ulid.DecodeChecked(envelope[:]); err != nil || id != tc.id {
+			t.Errorf("%s: got %s (%v) decoding the envelope", tc.id, id, err)
+		}
+
+		for _, text := range []string{tc.text, strings.ToLower(tc.text)} {
+			if id, err := ulid.DecodeCheckedText(text); err != nil || id != tc.id {
+				t.Errorf("%s: got %s (%v) decoding %s", tc.id, id, err, text)
+			}
+		}
+	}
+}
+
+func TestChecked(t *testing.T) {
+	t.Parallel()
+
+	prop := func(id ulid.ULID) bool {
+		envelope := id.EncodeChecked()
+		decoded, err := ulid.DecodeChecked(envelope[:])
+		if err != nil || decoded != id {
+			return false
+		}
+
+		text := id.EncodeCheckedText()
+		decoded, err = ulid.DecodeCheckedText(text)
+		return err == nil && decoded == id && len(text) == ulid.CheckedEncodedSize
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The text encoding sorts in the same order as the ULIDs.
+	order := func(a, b ulid.ULID) bool {
+		return a.Compare(b) == strings.Compare(a.EncodeCheckedText(), b.EncodeCheckedText())
+	}
+
+	if err := quick.Check(order, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckedErrors(t *testing.T) {
+	t.Parallel()
+
+	envelope := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV").EncodeChecked()
+	for _, tc := range []struct {
+		data []byte
+		err  error
+	}{
+		{nil, ulid.ErrDataSize},
+		{envelope[:16], ulid.ErrDataSize},
+		{append(envelope[:], 0), ulid.ErrDataSize},
+		{append(envelope[:16:16], 0, 0, 0, 0), ulid.ErrChecksumMismatch},
+	} {
+		if _, err := ulid.DecodeChecked(tc.data); err != tc.err {
+			t.Errorf("%x: got %v, want %v", tc.data, err, tc.err)
+		}
+	}
+
+	const text = "05B3WENNTFB7CK31XYWS60NXBCRP0KF9"
+	for _, tc := range []struct {
+		text string
+		err  error
+	}{
+		{"", ulid.ErrDataSize},
+		{text[:26], ulid.ErrDataSize},
+		{text + "0", ulid.ErrDataSize},
+		{"U" + text[1:], ulid.ErrInvalidCharacters},
+		{text[:31] + "!", ulid.ErrInvalidCharacters},
+		{"1" + text[1:], ulid.ErrChecksumMismatch},
+		{text[:31] + "8", ulid.ErrChecksumMismatch},
+	} {
+		if _, err := ulid.DecodeCheckedText(tc.text); err != tc.err {
+			t.Errorf("%q: got %v, want %v", tc.text, err, tc.err)
+		}
+	}
+}
+
+// FuzzCheckedBitFlips flips one or two bits of valid envelopes, which CRC-32C
+// always detects. Corruption of more bits is only detected with high probability
+// and is not asserted.
+func FuzzCheckedBitFlips(f *testing.F) {
+	f.Add(make([]byte, 16), uint8(0), uint8(0))
+	f.Add(bytes.Repeat([]byte{0xFF}, 16), uint8(159), uint8(0))
+	f.Add([]byte("0123456789abcdef"), uint8(3), uint8(130))
+
+	f.Fuzz(func(t *testing.T, data []byte, first, second uint8) {
+		if len(data) != 16 {
+			return
+		}
+
+		var id ulid.ULID
+		copy(id[:], data)
+		envelope := id.EncodeChecked()
+
+		// Flip one bit, then a second distinct bit.
+		i, j := int(first)%(8*ulid.CheckedSize), int(second)%(8*ulid.CheckedSize)
+		envelope[i/8] ^= 1 << (i % 8)
+		if _, err := ulid.DecodeChecked(envelope[:]); err != ulid.ErrChecksumMismatch {
+			t.Fatalf("bit %d: got %v, want a checksum mismatch", i, err)
+		}
+
+		if i != j {
+			envelope[j/8] ^= 1 << (j % 8)
+			if _, err := ulid.DecodeChecked(envelope[:]); err != ulid.ErrChecksumMismatch {
+				t.Fatalf("bits %d and %d: got %v, want a checksum mismatch", i, j, err)
+			}
+		}
+
+		// Replacing one character of the text is a burst error of at most 5 bits.
+		text := []byte(id.EncodeCheckedText())
+		k := int(first) % len(text)
+		text[k] = ulid.Encoding[(strings.IndexByte(ulid.Encoding, text[k])+1+int(second)%31)%32]
+		if _, err := ulid.DecodeCheckedText(string(text)); err != ulid.ErrChecksumMismatch {
+			t.Fatalf("character %d: got %v, want a checksum mismatch", k, err)
+		}
+	})
+}
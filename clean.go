@@ -0,0 +1,111 @@
+package ulid
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CleanError describes a string that is not an encoded ULID even after CleanString
+// removed invisible characters from it, e.g. because the string was truncated.
+type CleanError struct {
+	Input   string // The string passed to CleanString
+	Removed []rune // The distinct code points removed, in order of appearance
+	Err     error  // The underlying error
+}
+
+func (e *CleanError) Error() string {
+	names := make([]string, len(e.Removed))
+	for i, r := range e.Removed {
+		names[i] = fmt.Sprintf("%U", r)
+	}
+	return fmt.Sprintf("%s: %q after removing %s", e.Err, e.Input, strings.Join(names, ", "))
+}
+
+func (e *CleanError) Unwrap() error {
+	return e.Err
+}
+
+// CleanString removes the invisible characters that are often added to ULIDs that
+// are copied from chat tools, documents, and spreadsheets: byte order marks,
+// zero-width spaces and joiners, and the other Unicode format characters (category
+// Cf) anywhere in the string, and whitespace, including non-breaking spaces, at
+// either end of it. The remainder must be exactly EncodedSize characters, otherwise
+// ErrDataSize is returned, as a *CleanError naming the removed code points if any
+// were removed. A string that does not need cleaning is returned as is.
+//
+// CleanString does not validate the characters of the remainder; use ParseClean
+// to clean and strictly parse the string.
+func CleanString(s string) (string, error) {
+	clean, removed := cleanString(s)
+	if utf8.RuneCountInString(clean) != EncodedSize {
+		if len(removed) == 0 {
+			return clean, ErrDataSize
+		}
+		return clean, &CleanError{Input: s, Removed: removed, Err: ErrDataSize}
+	}
+	return clean, nil
+}
+
+// ParseClean removes invisible characters from the string with CleanString and then
+// parses it with ParseStrict. If characters were removed and the remainder cannot be
+// parsed, the error is a *CleanError wrapping the error from ParseStrict.
+func ParseClean(s string) (id ULID, err error) {
+	clean, removed := cleanString(s)
+	if err = parse([]byte(clean), true, &id); err != nil && len(removed) > 0 {
+		err = &CleanError{Input: s, Removed: removed, Err: err}
+	}
+	return id, err
+}
+
+// cleanString returns s without format characters and surrounding whitespace and
+// the distinct code points that were removed from it.
+func cleanString(s string) (string, []rune) {
+	if isClean(s) {
+		return s, nil
+	}
+
+	var (
+		removed []rune
+		sb      strings.Builder
+	)
+
+	remove := func(r rune) {
+		for _, c := range removed {
+			if c == r {
+				return
+			}
+		}
+		removed = append(removed, r)
+	}
+
+	sb.Grow(len(s))
+	for _, r := range s {
+		if unicode.Is(unicode.Cf, r) {
+			remove(r)
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	clean := strings.TrimFunc(sb.String(), func(r rune) bool {
+		if unicode.IsSpace(r) {
+			remove(r)
+			return true
+		}
+		return false
+	})
+	return clean, removed
+}
+
+// isClean returns true if s is printable ASCII without surrounding spaces, the
+// common case that does not need to be cleaned.
+func isClean(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
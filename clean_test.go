@@ -0,0 +1,109 @@
+package ulid_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestCleanString(t *testing.T) {
+	t.Parallel()
+
+	const id = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	for _, tc := range []struct {
+		name  string
+		input string
+	}{
+		{"Clean", id},
+		{"Lowercase", strings.ToLower(id)},
+		{"BOM", "\uFEFF" + id},
+		{"NBSP", "\u00A0" + id + "\u00A0\u00A0"},
+		{"Whitespace", " \t" + id + "\r\n"},
+		{"ZeroWidthSpace", id[:10] + "\u200B" + id[10:]},
+		{"ZeroWidthJoined", strings.Join(strings.Split(id, ""), "\u200D")},
+		{"BidiMarks", "\u202A\u200E" + id + "\u200F\u202C"},
+		{"WordJoiner", "\u2060" + id + "\u2060"},
+		{"SoftHyphen", id[:13] + "\u00AD" + id[13:]},
+		{"Everything", "\uFEFF\u00A0 " + id[:5] + "\u200B\u200C" + id[5:] + "\u2060\u3000\n"},
+	} {
+		clean, err := ulid.CleanString(tc.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		if !strings.EqualFold(clean, id) {
+			t.Errorf("%s: got %q, want %q", tc.name, clean, id)
+		}
+
+		if parsed, err := ulid.ParseClean(tc.input); err != nil || parsed.String() != id {
+			t.Errorf("%s: got %s (%v), want %s", tc.name, parsed, err, id)
+		}
+	}
+}
+
+func TestCleanStringAllocs(t *testing.T) {
+	// Strings that do not need cleaning are returned untouched.
+	const id = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	if clean, _ := ulid.CleanString(id); clean != id || testing.AllocsPerRun(10, func() { _, _ = ulid.CleanString(id) }) != 0 {
+		t.Error("expected a clean string to be returned without allocating")
+	}
+}
+
+func TestCleanStringErrors(t *testing.T) {
+	t.Parallel()
+
+	const id = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	for _, tc := range []struct {
+		name    string
+		input   string
+		removed []rune
+		err     error
+	}{
+		{"Short", "\uFEFF" + id[:25], []rune{0xFEFF}, ulid.ErrDataSize},
+		{"Long", "\u00A0" + id + "0\u200B", []rune{0x200B, 0xA0}, ulid.ErrDataSize},
+		{"InnerSpace", "\u200B" + id[:10] + " " + id[10:], []rune{0x200B}, ulid.ErrDataSize},
+		{"Repeated", "\u200B\u200B" + id[:20] + "\u200B", []rune{0x200B}, ulid.ErrDataSize},
+		{"NotASCII", "\uFEFF" + id[:25] + "\u00E9", []rune{0xFEFF}, nil},
+		{"Unclean", id[:25], nil, ulid.ErrDataSize},
+		{"Empty", "", nil, ulid.ErrDataSize},
+		{"Invisible", "\uFEFF\u200B\u00A0", []rune{0xFEFF, 0x200B, 0xA0}, ulid.ErrDataSize},
+	} {
+		_, err := ulid.CleanString(tc.input)
+		if tc.err == nil {
+			// Cleaning yields 26 characters, so only parsing the remainder fails.
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+		} else if !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+
+		_, perr := ulid.ParseClean(tc.input)
+		if perr == nil {
+			t.Errorf("%s: expected ParseClean to fail", tc.name)
+			continue
+		}
+
+		var cerr *ulid.CleanError
+		if !errors.As(perr, &cerr) {
+			if tc.removed != nil {
+				t.Errorf("%s: expected a clean error, got %v", tc.name, perr)
+			}
+			continue
+		}
+
+		if cerr.Input != tc.input || !slices.Equal(cerr.Removed, tc.removed) {
+			t.Errorf("%s: got input %q and removed %U, want %U", tc.name, cerr.Input, cerr.Removed, tc.removed)
+		}
+	}
+
+	// The error names the code points that were removed.
+	_, err := ulid.CleanString("\uFEFF" + id[:25] + "\u200B")
+	if want := `ulid: bad data size when unmarshaling: "\ufeff01JKEHNQPA0END3NHMFKB2Y6S\u200b" after removing U+FEFF, U+200B`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,118 @@
+package ulid
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultReanchorInterval is the interval at which a MonotonicClock compares its
+// time to the wall clock if its ReanchorInterval is not set.
+const DefaultReanchorInterval = time.Second
+
+// processStart is the monotonic clock reading that elapsed time is measured from.
+var processStart = time.Now()
+
+// defaultClock is the process-global clock used by NowMonotonic.
+var defaultClock = &MonotonicClock{}
+
+// MonotonicClock returns Unix milliseconds that never decrease, even if the wall
+// clock is stepped backwards, e.g. by an NTP correction or a VM migration. It
+// reads the wall clock once as an anchor and then derives the time from the
+// elapsed time of the monotonic clock of the runtime, which is not affected by
+// changes to the wall clock. Every ReanchorInterval the time is compared to the
+// wall clock and, if the wall clock is ahead, the clock is re-anchored to it so
+// that drift of the monotonic clock behind the wall clock is bounded. The clock
+// is never re-anchored backwards: if the wall clock falls behind, the clock keeps
+// running from its anchor and stays ahead of the wall clock by the amount it was
+// stepped back until the wall clock catches up.
+//
+// The zero value is ready to use and is anchored by its first call to Now. A
+// MonotonicClock is safe for concurrent use.
+type MonotonicClock struct {
+	// Wall returns the wall clock time used to anchor the clock; it defaults to
+	// time.Now and may be replaced, e.g. to simulate a clock in tests, but must not
+	// be modified concurrently with calls to Now.
+	Wall func() time.Time
+
+	// Elapsed returns the elapsed time of a monotonic clock from an arbitrary but
+	// fixed point; it defaults to the time since the start of the process. It has
+	// the same restrictions as Wall.
+	Elapsed func() time.Duration
+
+	// ReanchorInterval is the interval of monotonic time between comparisons to the
+	// wall clock; it defaults to DefaultReanchorInterval.
+	ReanchorInterval time.Duration
+
+	mu       sync.Mutex
+	anchored bool
+	anchor   uint64
+	since    time.Duration
+	checked  time.Duration
+	last     uint64
+}
+
+// Now returns the current time of the clock in Unix milliseconds, which is never
+// less than the time returned by a previous call.
+func (c *MonotonicClock) Now() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elapsed := c.elapsed()
+	if !c.anchored {
+		c.reanchor(Timestamp(c.wall()), elapsed)
+		c.anchored = true
+	}
+
+	ms := c.anchor + uint64((elapsed - c.since).Milliseconds())
+	if elapsed-c.checked >= c.interval() {
+		c.checked = elapsed
+		if wall := Timestamp(c.wall()); wall > ms {
+			c.reanchor(wall, elapsed)
+			ms = wall
+		}
+	}
+
+	// The elapsed time is monotonic but guard against a misbehaving Elapsed.
+	c.last = max(c.last, ms)
+	return c.last
+}
+
+func (c *MonotonicClock) reanchor(wall uint64, elapsed time.Duration) {
+	c.anchor, c.since, c.checked = wall, elapsed, elapsed
+}
+
+func (c *MonotonicClock) wall() time.Time {
+	if c.Wall != nil {
+		return c.Wall().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (c *MonotonicClock) elapsed() time.Duration {
+	if c.Elapsed != nil {
+		return c.Elapsed()
+	}
+	return time.Since(processStart)
+}
+
+func (c *MonotonicClock) interval() time.Duration {
+	if c.ReanchorInterval > 0 {
+		return c.ReanchorInterval
+	}
+	return DefaultReanchorInterval
+}
+
+// NowMonotonic returns the current time in Unix milliseconds from a process-global
+// MonotonicClock. Unlike Now, which reads the wall clock on every call and so
+// returns an earlier time if the wall clock is stepped backwards, the time returned
+// by NowMonotonic never decreases within the process. It only protects the order
+// of timestamps within a process: ULIDs created by different processes or hosts
+// are still ordered by their wall clocks, which may disagree.
+func NowMonotonic() uint64 { return defaultClock.Now() }
+
+// MakeMonotonicTime is like Make but uses NowMonotonic for the timestamp, so that
+// the timestamps of the ULIDs it returns never decrease within the process even if
+// the wall clock is stepped backwards.
+func MakeMonotonicTime() ULID {
+	return recordMade(&health.made, MustNew(NowMonotonic(), DefaultEntropy()))
+}
@@ -0,0 +1,146 @@
+package ulid_test
+
+import (
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// steppedClock simulates a wall clock that can be stepped independently of the
+// monotonic clock.
+type steppedClock struct {
+	wall    time.Time
+	elapsed time.Duration
+}
+
+func (c *steppedClock) Wall() time.Time         { return c.wall }
+func (c *steppedClock) Elapsed() time.Duration  { return c.elapsed }
+func (c *steppedClock) Step(d time.Duration)    { c.wall = c.wall.Add(d) }
+func (c *steppedClock) Advance(d time.Duration) { c.wall = c.wall.Add(d); c.elapsed += d }
+
+func monotonicClock() (*ulid.MonotonicClock, *steppedClock) {
+	sc := &steppedClock{wall: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), elapsed: time.Hour}
+	return &ulid.MonotonicClock{Wall: sc.Wall, Elapsed: sc.Elapsed}, sc
+}
+
+func TestMonotonicClock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Anchor", func(t *testing.T) {
+		t.Parallel()
+
+		c, sc := monotonicClock()
+		if ms := c.Now(); ms != ulid.Timestamp(sc.wall) {
+			t.Fatalf("expected the clock to be anchored at %d, got %d", ulid.Timestamp(sc.wall), ms)
+		}
+
+		sc.Advance(1500 * time.Millisecond)
+		if ms := c.Now(); ms != ulid.Timestamp(sc.wall) {
+			t.Errorf("expected the clock to follow the monotonic clock to %d, got %d", ulid.Timestamp(sc.wall), ms)
+		}
+	})
+
+	t.Run("BackwardsStep", func(t *testing.T) {
+		t.Parallel()
+
+		c, sc := monotonicClock()
+		start := c.Now()
+
+		// The wall clock is stepped back an hour while the monotonic clock advances.
+		var last uint64
+		sc.Step(-time.Hour)
+		for i := 0; i < 10000; i++ {
+			sc.Advance(time.Millisecond)
+			ms := c.Now()
+			if ms < last {
+				t.Fatalf("expected the clock to never decrease, got %d after %d", ms, last)
+			}
+			last = ms
+		}
+
+		if want := start + 10000; last != want {
+			t.Errorf("expected the clock to continue from its anchor to %d, got %d", want, last)
+		}
+
+		// Once the wall clock catches up the clock is re-anchored to it again.
+		sc.Step(time.Hour + time.Minute)
+		sc.Advance(time.Second)
+		if ms := c.Now(); ms != ulid.Timestamp(sc.wall) {
+			t.Errorf("expected the clock to re-anchor to %d, got %d", ulid.Timestamp(sc.wall), ms)
+		}
+	})
+
+	t.Run("ForwardStep", func(t *testing.T) {
+		t.Parallel()
+
+		c, sc := monotonicClock()
+		start := c.Now()
+		sc.Step(time.Minute)
+
+		// The wall clock is not compared until the reanchor interval has elapsed.
+		sc.Advance(ulid.DefaultReanchorInterval / 2)
+		if ms, want := c.Now(), start+uint64((ulid.DefaultReanchorInterval/2).Milliseconds()); ms != want {
+			t.Errorf("expected %d before the reanchor interval, got %d", want, ms)
+		}
+
+		sc.Advance(ulid.DefaultReanchorInterval / 2)
+		if ms := c.Now(); ms != ulid.Timestamp(sc.wall) {
+			t.Errorf("expected the clock to re-anchor forward to %d, got %d", ulid.Timestamp(sc.wall), ms)
+		}
+	})
+
+	t.Run("Drift", func(t *testing.T) {
+		t.Parallel()
+
+		// Over a simulated day the monotonic clock runs 100ppm slower than the wall
+		// clock, so without re-anchoring it would fall more than 8s behind.
+		c, sc := monotonicClock()
+		c.ReanchorInterval = 10 * time.Second
+		c.Now()
+
+		var last uint64
+		for i := 0; i < 24*60*60*10; i++ {
+			sc.wall = sc.wall.Add(100 * time.Millisecond)
+			sc.elapsed += 100*time.Millisecond - 10*time.Microsecond
+
+			ms := c.Now()
+			if ms < last {
+				t.Fatalf("expected the clock to never decrease, got %d after %d", ms, last)
+			}
+			last = ms
+
+			if behind := ulid.Timestamp(sc.wall) - ms; behind > 2 {
+				t.Fatalf("expected the clock to stay within 2ms of the wall clock, got %dms behind", behind)
+			}
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+
+		var c ulid.MonotonicClock
+		before := ulid.Now()
+		ms := c.Now()
+		if ms < before || ms > ulid.Now() {
+			t.Errorf("expected the zero value to follow the wall clock, got %d", ms)
+		}
+	})
+}
+
+func TestMakeMonotonicTime(t *testing.T) {
+	t.Parallel()
+
+	prev := ulid.MakeMonotonicTime()
+	for i := 0; i < 10000; i++ {
+		id := ulid.MakeMonotonicTime()
+		if id.Time() < prev.Time() {
+			t.Fatalf("expected timestamps to never decrease, got %d after %d", id.Time(), prev.Time())
+		}
+		prev = id
+	}
+
+	if ms := ulid.NowMonotonic(); ms < prev.Time() {
+		t.Errorf("expected NowMonotonic to be at least %d, got %d", prev.Time(), ms)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/internal/stats"
+	"go.rtnl.ai/ulid/stress"
+)
+
+// stdio are the streams of a command, which are replaced in tests.
+type stdio struct {
+	in  io.Reader
+	out io.Writer
+	err io.Writer
+}
+
+// options are the values of the flags of all of the commands; each command only
+// binds the flags that it uses.
+type options struct {
+	num     int
+	quick   bool
+	mono    bool
+	zero    bool
+	format  string
+	local   bool
+	path    bool
+	after   *ulid.NullULID
+	words   bool
+	explain bool
+	encName string
+	help    bool
+	version bool
+
+	outTmpl string
+	mkdir   bool
+	touch   bool
+	force   bool
+
+	qr      bool
+	braille bool
+	invert  bool
+
+	statistics bool
+	bucket     time.Duration
+	maxTracked int
+
+	classify   bool
+	jsonOutput bool
+
+	order     bool
+	tolerance time.Duration
+
+	convert string
+
+	col          int
+	canonicalize bool
+	mapFile      string
+	header       bool
+	lenient      bool
+	skipBad      bool
+
+	selftest bool
+
+	stress   bool
+	entropy  string
+	inc      uint64
+	duration time.Duration
+	limit    uint64
+}
+
+// command is a subcommand of the CLI, e.g. ulid new.
+type command struct {
+	name    string
+	summary string
+	usage   string
+	args    []string // the values of the positional arguments, for completion
+	flags   func(*options, *flag.FlagSet)
+	run     func(*options, []string, stdio) error
+}
+
+// commands are the subcommands in the order they are listed in the usage.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:    "new",
+			summary: "generate ULIDs",
+			usage:   newUsage,
+			flags:   (*options).newCommandFlags,
+			run:     runNew,
+		},
+		{
+			name:    "inspect",
+			summary: "print the time, layout, or ULID of each argument",
+			usage:   inspectUsage,
+			flags:   (*options).inspectCommandFlags,
+			run:     runInspect,
+		},
+		{
+			name:    "check",
+			summary: "report statistics, classify the entropy, or audit the order of ULIDs read from stdin",
+			usage:   checkUsage,
+			flags:   (*options).checkCommandFlags,
+			run:     runCheck,
+		},
+		{
+			name:    "stress",
+			summary: "simulate a burst of ULIDs in a single millisecond until the entropy overflows",
+			usage:   stressUsage,
+			flags:   (*options).stressCommandFlags,
+			run:     runStress,
+		},
+		{
+			name:    "convert",
+			summary: "convert between newline delimited ULIDs and binary records",
+			usage:   convertUsage,
+			args:    []string{"text-to-bin", "bin-to-text"},
+			run:     runConvert,
+		},
+		{
+			name:    "csv",
+			summary: "rewrite a ULID column of a CSV file read from stdin",
+			usage:   csvUsage,
+			args:    []string{"rewrite"},
+			flags:   (*options).csvFlags,
+			run:     runCSV,
+		},
+		{
+			name:    "completion",
+			summary: "print a shell completion script",
+			usage:   completionUsage,
+			args:    []string{"bash", "zsh", "fish"},
+			run:     runCompletion,
+		},
+	}
+}
+
+// lookup returns the command with the name or nil if there is no such command.
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// run dispatches the arguments to a subcommand if the first argument names one
+// and otherwise runs the legacy flag-only invocation.
+func run(args []string, s stdio) error {
+	if len(args) > 0 {
+		if cmd := lookup(args[0]); cmd != nil {
+			return cmd.exec(args[1:], s)
+		}
+	}
+	return legacy(args, s)
+}
+
+// flagSet returns the flags of the command bound to the options.
+func (c *command) flagSet(o *options) *flag.FlagSet {
+	fs := newFlagSet(c.name)
+	if c.flags != nil {
+		c.flags(o, fs)
+	}
+	return fs
+}
+
+// exec parses the flags of the command and runs it with the remaining arguments.
+func (c *command) exec(args []string, s stdio) error {
+	o := &options{}
+	fs := c.flagSet(o)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(s.err, c.usage)
+			return nil
+		}
+		return fmt.Errorf("ulid %s: %w", c.name, err)
+	}
+	return c.run(o, fs.Args(), s)
+}
+
+// legacy runs the flag-only invocation of the CLI from before the subcommands:
+// the mode flags select a command and otherwise ULIDs are generated if there are
+// no arguments and inspected if there are.
+func legacy(args []string, s stdio) error {
+	o := &options{}
+	fs := legacyFlagSet(o)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case o.help:
+		fmt.Fprint(s.err, usageText)
+		return nil
+	case o.statistics:
+		return runStats(o, s)
+	case o.classify:
+		return runClassify(o, s)
+	case o.order:
+		return runOrder(o, s)
+	case o.convert != "":
+		return convert(o.convert, s)
+	case o.stress:
+		return runStress(o, fs.Args(), s)
+	case o.selftest:
+		return selfTest(s)
+	case fs.NArg() == 0:
+		return runNew(o, nil, s)
+	default:
+		return runInspect(o, fs.Args(), s)
+	}
+}
+
+func legacyFlagSet(o *options) *flag.FlagSet {
+	fs := newFlagSet("ulid")
+	o.generateFlags(fs)
+	o.inspectFlags(fs)
+	o.sharedFlags(fs, "require ULIDs to")
+	o.checkFlags(fs)
+	o.stressFlags(fs)
+	o.jsonFlag(fs)
+
+	fs.BoolVar(&o.stress, "stress", false, "simulate a burst of ULIDs in a single millisecond")
+	fs.BoolVar(&o.statistics, "stats", false, "report collision and ordering statistics for ULIDs read from stdin")
+	alias(fs, "s", "stats")
+	fs.StringVar(&o.convert, "convert", "", "convert between newline delimited ULIDs and binary records")
+	alias(fs, "c", "convert")
+	fs.BoolVar(&o.help, "help", false, "display help and exit")
+	alias(fs, "h", "help")
+	return fs
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// alias defines short as another name for the long flag.
+func alias(fs *flag.FlagSet, short, long string) {
+	f := fs.Lookup(long)
+	fs.Var(f.Value, short, f.Usage)
+}
+
+func (o *options) newCommandFlags(fs *flag.FlagSet) {
+	o.generateFlags(fs)
+	o.sharedFlags(fs, "fail if a generated ULID does not")
+}
+
+func (o *options) inspectCommandFlags(fs *flag.FlagSet) {
+	o.inspectFlags(fs)
+	o.sharedFlags(fs, "only accept ULIDs that")
+	o.jsonFlag(fs)
+}
+
+func (o *options) checkCommandFlags(fs *flag.FlagSet) {
+	o.checkFlags(fs)
+	o.jsonFlag(fs)
+}
+
+func (o *options) stressCommandFlags(fs *flag.FlagSet) {
+	o.stressFlags(fs)
+	o.jsonFlag(fs)
+}
+
+func (o *options) generateFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.num, "num", 1, "number of ULIDs to generate")
+	alias(fs, "n", "num")
+	fs.BoolVar(&o.quick, "quick", false, "use quick entropy (not cryptographic)")
+	alias(fs, "q", "quick")
+	fs.BoolVar(&o.mono, "mono", false, "use monotonic entropy")
+	alias(fs, "m", "mono")
+	fs.BoolVar(&o.zero, "zero", false, "use zero entropy")
+	alias(fs, "z", "zero")
+	fs.StringVar(&o.outTmpl, "out-template", "", "render each ULID with a Go text/template")
+	fs.BoolVar(&o.mkdir, "mkdir", false, "create a directory at each rendered path")
+	fs.BoolVar(&o.touch, "touch", false, "create an empty file at each rendered path")
+	fs.BoolVar(&o.force, "force", false, "allow paths that already exist")
+	fs.BoolVar(&o.version, "version", false, "print the version and the entropy that would be used")
+}
+
+func (o *options) inspectFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "default", "time format (default, rfc3339, unix, ms)")
+	alias(fs, "f", "format")
+	fs.BoolVar(&o.local, "local", false, "use local time instead of UTC")
+	alias(fs, "l", "local")
+	fs.BoolVar(&o.path, "path", false, "strip the directory and extension of each argument")
+	alias(fs, "p", "path")
+	fs.BoolVar(&o.explain, "explain", false, "print the bit-level layout of each ULID")
+	alias(fs, "e", "explain")
+}
+
+// sharedFlags are the flags of both generate and inspect; the prefix completes
+// the usage of --after for the command.
+func (o *options) sharedFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&o.encName, "encoding", "", "encoding of ULIDs (base32, base64, hex, or uuid)")
+	o.after = ulid.NullULIDFlag(fs, "after", prefix+" sort strictly after the ULID")
+	alias(fs, "a", "after")
+	fs.BoolVar(&o.words, "words", false, "use 12 word mnemonics")
+	alias(fs, "w", "words")
+	fs.BoolVar(&o.qr, "qr", false, "also print each ULID as a QR code")
+	fs.BoolVar(&o.braille, "braille", false, "also print each ULID as a QR code of braille patterns")
+	fs.BoolVar(&o.invert, "invert", false, "draw the light modules of QR codes for dark terminals")
+}
+
+func (o *options) checkFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.bucket, "bucket", 24*time.Hour, "histogram bucket size")
+	alias(fs, "b", "bucket")
+	fs.IntVar(&o.maxTracked, "max-tracked", stats.DefaultMaxTracked, "distinct ULIDs tracked for exact duplicate detection")
+	fs.BoolVar(&o.classify, "classify", false, "classify how the entropy of the ULIDs was generated")
+	fs.BoolVar(&o.order, "order", false, "audit the insertion order of seq,ulid pairs against the order of their timestamps")
+	fs.DurationVar(&o.tolerance, "tolerance", 0, "regression of a timestamp that is not an inversion")
+	fs.BoolVar(&o.selftest, "selftest", false, "verify the implementation against the embedded test vectors")
+}
+
+func (o *options) stressFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.entropy, "entropy", string(stress.Monotonic), "entropy mode (default, secure, monotonic, or sequential)")
+	fs.Uint64Var(&o.inc, "inc", 0, "maximum increment of monotonic entropy")
+	fs.DurationVar(&o.duration, "duration", stress.DefaultDuration, "duration of the burst")
+	fs.Uint64Var(&o.limit, "limit", 0, "maximum number of ULIDs to generate")
+}
+
+// csvFlags are the flags of csv, which may also follow its mode; each flag keeps
+// its value as its default so that the flags before the mode are kept when the
+// flags after it are parsed.
+func (o *options) csvFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.col, "col", o.col, "column of the ULIDs, from 1")
+	fs.BoolVar(&o.canonicalize, "canonicalize", o.canonicalize, "rewrite the ULIDs in canonical form")
+	fs.StringVar(&o.mapFile, "map", o.mapFile, "rewrite the ULIDs with the old,new pairs of a CSV file")
+	fs.BoolVar(&o.header, "header", o.header, "copy the first line as a header")
+	fs.BoolVar(&o.lenient, "lenient", o.lenient, "remove invisible characters and whitespace around the ULIDs")
+	fs.BoolVar(&o.skipBad, "skip-bad", o.skipBad, "copy bad rows unchanged rather than stopping")
+	fs.BoolVar(&o.jsonOutput, "json", o.jsonOutput, "print the output as JSON")
+}
+
+func (o *options) jsonFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&o.jsonOutput, "json", false, "print the output as JSON")
+}
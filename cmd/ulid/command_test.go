@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// execute runs the CLI with the arguments and stdin, returning stdout and stderr.
+func execute(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	err := run(args, stdio{in: strings.NewReader(stdin), out: &out, err: &errOut})
+	return out.String(), errOut.String(), err
+}
+
+func TestRunDispatch(t *testing.T) {
+	const id = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	ids := strings.Join([]string{"01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFNPBB9WE"}, "\n")
+	pairs := "id,ulid\n1,01JKEHNQPA0END3NHMFKB2Y6SE\n2, 01JKEHMRSH3HXYCYYZ1HZR2JBS\n"
+
+	testCases := []struct {
+		name   string
+		stdin  string
+		args   []string
+		stdout string
+		stderr string
+	}{
+		{"LegacyInspect", "", []string{id}, "", "Thu Feb 06 21:11:53.29 UTC 2025\n"},
+		{"Inspect", "", []string{"inspect", id}, "", "Thu Feb 06 21:11:53.29 UTC 2025\n"},
+		{"LegacyFormat", "", []string{"-f", "ms", id}, "", "1738876313290\n"},
+		{"InspectClean", "", []string{"inspect", "\uFEFF" + id + "\u00A0"}, "", "note: removed invisible characters from \"\\ufeff" + id + "\\u00a0\"\nThu Feb 06 21:11:53.29 UTC 2025\n"},
+		{"InspectCleanZWSP", "", []string{"inspect", "\u200B" + id + "\u200B"}, "", "note: removed invisible characters from \"\\u200b" + id + "\\u200b\"\nThu Feb 06 21:11:53.29 UTC 2025\n"},
+		{"InspectCleanHex", "", []string{"inspect", "\uFEFF0194dd1adeca03aad1d6347cd62f1b2e"}, "", "note: removed invisible characters from \"\\ufeff0194dd1adeca03aad1d6347cd62f1b2e\"\nThu Feb 06 21:11:53.29 UTC 2025\n"},
+		{"InspectFormat", "", []string{"inspect", "--format", "ms", id}, "", "1738876313290\n"},
+		{"LegacyPath", "", []string{"--path", "dir/" + id + ".json"}, "", "Thu Feb 06 21:11:53.29 UTC 2025\n"},
+		{"LegacyStats", ids, []string{"--stats"}, "total:        2", ""},
+		{"Check", ids, []string{"check", "-b", "1h"}, "total:        2", ""},
+		{"LegacyClassify", ids, []string{"--classify"}, "class:             monotonic", ""},
+		{"CheckClassify", ids, []string{"check", "--classify"}, "class:             monotonic", ""},
+		{"CheckOrder", pairs, []string{"check", "--order"}, "pairs:          2\nunordered:      0\ninversions:     1\n", ""},
+		{"LegacyOrder", pairs, []string{"--order", "--tolerance", "1h"}, "pairs:          2\nunordered:      0\ninversions:     0\ntolerance:      1h0m0s\n", ""},
+		{"CheckOrderJSON", pairs, []string{"check", "--order", "--json"}, "{\n  \"pairs\": 2,\n", ""},
+		{"LegacySelfTest", "", []string{"--selftest"}, "selftest passed\n", ""},
+		{"CheckSelfTest", "", []string{"check", "--selftest"}, "selftest passed\n", ""},
+		{"LegacyStress", "", []string{"--stress", "--inc", "1024", "--duration", "1ms"}, "entropy:      monotonic\ninc:          1024\n", ""},
+		{"Stress", "", []string{"stress", "--entropy", "sequential", "--limit", "10"}, "entropy:      sequential\ntimestamp:", ""},
+		{"StressJSON", "", []string{"stress", "--limit", "10", "--json"}, "{\n  \"mode\": \"monotonic\",\n", ""},
+		{"LegacyVersion", "", []string{"--version", "-q", "-m"}, "go.rtnl.ai/ulid " + ulid.Version() + " (math/rand entropy, monotonic with inc 4294967295)\n", ""},
+		{"NewVersion", "", []string{"new", "--version"}, "go.rtnl.ai/ulid " + ulid.Version() + " (crypto/rand entropy, not monotonic)\n", ""},
+		{"NewVersionZero", "", []string{"new", "--version", "-z"}, "go.rtnl.ai/ulid " + ulid.Version() + " (zero entropy, not monotonic)\n", ""},
+		{"CSV", "1,01jkehnqpa0end3nhmfkb2y6se\n", []string{"csv", "rewrite", "--col", "2", "--canonicalize"}, "1," + id + "\n", "rows:     1\nchanged:  1\nskipped:  0\nfailed:   0\n"},
+		{"CSVFlagsBeforeMode", "id\r\n\"" + id + "\"\r\n", []string{"csv", "--col", "1", "rewrite", "--header", "--canonicalize", "--json"}, "id\r\n\"" + id + "\"\r\n", "{\n  \"rows\": 1,\n  \"changed\": 0,\n  \"skipped\": 0,\n  \"failed\": 0,\n  \"bad_rows\": []\n}\n"},
+		{"LegacyHelp", "", []string{"-h"}, "", usageText},
+		{"InspectHelp", "", []string{"inspect", "-h"}, "", inspectUsage},
+		{"CSVHelp", "", []string{"csv", "rewrite", "-h"}, "", csvUsage},
+	}
+
+	for _, tc := range testCases {
+		stdout, stderr, err := execute(t, tc.stdin, tc.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		if !strings.HasPrefix(stdout, tc.stdout) {
+			t.Errorf("%s: got stdout %q, want prefix %q", tc.name, stdout, tc.stdout)
+		}
+
+		if stderr != tc.stderr {
+			t.Errorf("%s: got stderr %q, want %q", tc.name, stderr, tc.stderr)
+		}
+	}
+}
+
+func TestRunNew(t *testing.T) {
+	for _, args := range [][]string{{"-n", "3", "-m"}, {"new", "-n", "3", "-m"}, {"new", "--num=3", "--mono"}} {
+		stdout, _, err := execute(t, "", args...)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", args, err)
+			continue
+		}
+
+		lines := strings.Fields(stdout)
+		if len(lines) != 3 {
+			t.Errorf("%q: expected 3 ULIDs, got %q", args, stdout)
+			continue
+		}
+
+		var prev ulid.ULID
+		for _, line := range lines {
+			id, err := ulid.Parse(line)
+			if err != nil {
+				t.Errorf("%q: could not parse %q: %v", args, line, err)
+				continue
+			}
+
+			if id.Compare(prev) <= 0 {
+				t.Errorf("%q: expected monotonic ULIDs, got %q", args, stdout)
+			}
+			prev = id
+		}
+	}
+
+	stdout, _, err := execute(t, "", "new", "--encoding", "uuid", "--zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := strings.TrimSpace(stdout); len(s) != 36 || !strings.HasSuffix(s, "-0000-000000000000") {
+		t.Errorf("expected a uuid with zero entropy, got %q", s)
+	}
+}
+
+func TestRunQR(t *testing.T) {
+	stdout, _, err := execute(t, "", "new", "--qr", "-z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ULID is followed by its QR code.
+	line, code, _ := strings.Cut(stdout, "\n")
+	var want strings.Builder
+	if err := ulid.RenderQR(ulid.MustParse(line), &want); err != nil || code != want.String() {
+		t.Errorf("got %q (%v), want %q", code, err, want.String())
+	}
+
+	const id = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	stdout, stderr, err := execute(t, "", "inspect", "--braille", "--invert", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want.Reset()
+	ulid.RenderQR(ulid.MustParse(id), &want, ulid.QRBraille(), ulid.QRInvert())
+	if stdout != want.String() || stderr != "Thu Feb 06 21:11:53.29 UTC 2025\n" {
+		t.Errorf("got %q and %q, want %q", stdout, stderr, want.String())
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	const id = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	for _, args := range [][]string{{"--convert", "text-to-bin"}, {"-c", "text-to-bin"}, {"convert", "text-to-bin"}} {
+		stdout, _, err := execute(t, id+"\n", args...)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", args, err)
+			continue
+		}
+
+		if want := ulid.MustParse(id); stdout != string(want[:]) {
+			t.Errorf("%q: got %x, want %x", args, stdout, want[:])
+		}
+	}
+
+	binary := ulid.MustParse(id)
+	stdout, _, err := execute(t, string(binary[:]), "convert", "bin-to-text")
+	if err != nil || stdout != id+"\n" {
+		t.Errorf("got %q (%v), want %q", stdout, err, id+"\n")
+	}
+}
+
+func TestRunCSV(t *testing.T) {
+	const old, replacement = "01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHMRSH3HXYCYYZ1HZR2JBS"
+	path := filepath.Join(t.TempDir(), "map.csv")
+	if err := os.WriteFile(path, []byte("old,new\n"+old+", "+replacement+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "name,id\n\"a, b\",01jkehnqpa0end3nhmfkb2y6se\nc,01jkehnqpa0end3nhmfnpbb9we\nd\n"
+	stdout, stderr, err := execute(t, input, "csv", "rewrite", "--col", "2", "--map", path, "--header", "--skip-bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "name,id\n\"a, b\"," + replacement + "\nc,01JKEHNQPA0END3NHMFNPBB9WE\nd\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+
+	if want := "rows:     3\nchanged:  2\nskipped:  1\nfailed:   0\nbad rows: 4\n"; stderr != want {
+		t.Errorf("got stderr %q, want %q", stderr, want)
+	}
+
+	// Without --skip-bad the rewrite stops at the bad row.
+	_, stderr, err = execute(t, input, "csv", "rewrite", "--col", "2", "--canonicalize", "--header")
+	if err == nil || !strings.Contains(err.Error(), "row 4 (line 4)") || !strings.Contains(stderr, "failed:   1\nbad rows: 4\n") {
+		t.Errorf("got %v with stderr %q", err, stderr)
+	}
+
+	if err := os.WriteFile(path, []byte(old+",bad\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := execute(t, input, "csv", "rewrite", "--col", "2", "--map", path); err == nil || !strings.Contains(err.Error(), "--map line 1: ulid: bad data size") {
+		t.Errorf("got %v, want an error for the map", err)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	testCases := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"new", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "unexpected arguments"},
+		{[]string{"new", "--format", "ms"}, "ulid new: flag provided but not defined: -format"},
+		{[]string{"new", "-n", "0"}, "invalid --num 0"},
+		{[]string{"new", "--mkdir"}, "require --out-template"},
+		{[]string{"inspect"}, "expected at least one ULID"},
+		{[]string{"inspect", "\u200B01JKEHNQPA0END3NHMFKB2Y6S"}, "after removing U+200B"},
+		{[]string{"inspect", "--num", "3", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "ulid inspect: flag provided but not defined: -num"},
+		{[]string{"inspect", "--format", "iso", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "invalid --format iso"},
+		{[]string{"inspect", "--after", "01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "is not after"},
+		{[]string{"inspect", "--invert", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "--invert requires --qr or --braille"},
+		{[]string{"inspect", "--qr", "-e", "--json", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "cannot be used with --json"},
+		{[]string{"new", "--qr", "--braille"}, "cannot be used together"},
+		{[]string{"new", "--qr", "-w"}, "cannot be used with --words"},
+		{[]string{"new", "--braille", "--encoding", "hex"}, "cannot be used with --encoding hex"},
+		{[]string{"check", "--stats"}, "ulid check: flag provided but not defined: -stats"},
+		{[]string{"check", "--classify", "--selftest"}, "cannot be used together"},
+		{[]string{"check", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "unexpected arguments"},
+		{[]string{"check", "--bucket", "0s"}, "invalid --bucket 0s"},
+		{[]string{"check", "--order", "--classify"}, "--order cannot be used with --classify or --selftest"},
+		{[]string{"check", "--order", "--tolerance", "-1s"}, "invalid --tolerance -1s"},
+		{[]string{"stress", "01JKEHNQPA0END3NHMFKB2Y6SE"}, "unexpected arguments"},
+		{[]string{"stress", "--entropy", "quick"}, "invalid --entropy quick"},
+		{[]string{"stress", "--duration", "0s"}, "invalid --duration 0s"},
+		{[]string{"stress", "--num", "3"}, "ulid stress: flag provided but not defined: -num"},
+		{[]string{"convert"}, "expected a conversion mode"},
+		{[]string{"convert", "--convert", "text-to-bin"}, "ulid convert: flag provided but not defined: -convert"},
+		{[]string{"convert", "text-to-hex"}, "invalid conversion mode text-to-hex"},
+		{[]string{"csv"}, "expected a csv mode (rewrite)"},
+		{[]string{"csv", "rewrite", "--canonicalize"}, "invalid --col 0 (the first column is 1)"},
+		{[]string{"csv", "rewrite", "--col", "1"}, "expected --canonicalize or --map"},
+		{[]string{"csv", "rewrite", "--col", "1", "--canonicalize", "--map", "ids.csv"}, "--canonicalize cannot be used with --map"},
+		{[]string{"csv", "rewrite", "--col", "1", "--map", "testdata/missing.csv"}, "no such file or directory"},
+		{[]string{"csv", "rewrite", "--num", "3"}, "ulid csv: flag provided but not defined: -num"},
+		{[]string{"csv", "rewrite", "--col", "1", "--canonicalize", "ids.csv"}, "unexpected arguments"},
+		{[]string{"completion"}, "expected a shell"},
+		{[]string{"completion", "powershell"}, "invalid shell powershell"},
+		{[]string{"--unknown"}, "flag provided but not defined: -unknown"},
+		{[]string{"-n", "0"}, "invalid --num 0"},
+		{[]string{"newer"}, "ulid: bad data size"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := execute(t, "", tc.args...)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: got error %v, want %q", tc.args, err, tc.err)
+		}
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		commands int
+		check    func(*options) bool
+	}{
+		{[]string{"-n", "5", "-q", "-m", "-z", "-w", "--encoding", "hex", "--version"}, 1, func(o *options) bool {
+			return o.num == 5 && o.quick && o.mono && o.zero && o.words && o.encName == "hex" && o.version
+		}},
+		{[]string{"--out-template", "{{.ULID}}", "--touch", "--force"}, 1, func(o *options) bool {
+			return o.outTmpl == "{{.ULID}}" && o.touch && o.force && !o.mkdir
+		}},
+		{[]string{"-f", "unix", "-l", "-p", "-e", "--json"}, 1, func(o *options) bool {
+			return o.format == "unix" && o.local && o.path && o.explain && o.jsonOutput
+		}},
+		{[]string{"-a", "01JKEHNQPA0END3NHMFKB2Y6SE", "-w", "--encoding", "base64"}, 2, func(o *options) bool {
+			return o.after.Valid && o.after.ULID == ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE") && o.words && o.encName == "base64"
+		}},
+		{[]string{"--qr", "--braille", "--invert"}, 2, func(o *options) bool {
+			return o.qr && o.braille && o.invert
+		}},
+		{[]string{"-b", "1h", "--max-tracked", "10", "--classify"}, 1, func(o *options) bool {
+			return o.bucket == time.Hour && o.maxTracked == 10 && o.classify
+		}},
+		{[]string{"--order", "--tolerance", "1s"}, 1, func(o *options) bool {
+			return o.order && o.tolerance == time.Second
+		}},
+		{[]string{"--entropy", "secure", "--inc", "8", "--duration", "5ms", "--limit", "100"}, 1, func(o *options) bool {
+			return o.entropy == "secure" && o.inc == 8 && o.duration == 5*time.Millisecond && o.limit == 100
+		}},
+		{[]string{}, 0, func(o *options) bool {
+			return o.num == 1 && o.format == "default" && o.bucket == 24*time.Hour && !o.after.Valid &&
+				o.entropy == "monotonic" && o.duration == time.Second
+		}},
+	}
+
+	// The flags of csv are only accepted by csv, not by the legacy invocation.
+	csvArgs := []string{"--col", "3", "--canonicalize", "--map", "ids.csv", "--header", "--lenient", "--skip-bad"}
+	for _, cmd := range commands {
+		o := &options{}
+		err := cmd.flagSet(o).Parse(csvArgs)
+		if accepted := err == nil && o.col == 3 && o.canonicalize && o.mapFile == "ids.csv" && o.header && o.lenient && o.skipBad; accepted != (cmd.name == "csv") {
+			t.Errorf("%s: got %v accepting the csv flags (%v)", cmd.name, accepted, err)
+		}
+	}
+
+	if err := legacyFlagSet(&options{}).Parse(csvArgs); err == nil {
+		t.Error("expected the legacy invocation to reject the csv flags")
+	}
+
+	for _, tc := range testCases {
+		// The legacy invocation accepts the flags of all of the commands.
+		o := &options{}
+		if err := legacyFlagSet(o).Parse(tc.args); err != nil || !tc.check(o) {
+			t.Errorf("legacy %q: unexpected options %+v (%v)", tc.args, o, err)
+		}
+
+		// Other than the shared flags of new and inspect, each flag is accepted by
+		// exactly one command.
+		if len(tc.args) == 0 {
+			continue
+		}
+
+		accepted := 0
+		for _, cmd := range commands {
+			o := &options{}
+			if err := cmd.flagSet(o).Parse(tc.args); err == nil && tc.check(o) {
+				accepted++
+			}
+		}
+
+		if accepted != tc.commands {
+			t.Errorf("%q: expected %d commands to accept the flags, got %d", tc.args, tc.commands, accepted)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		stdout, _, err := execute(t, "", "completion", shell)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", shell, err)
+			continue
+		}
+
+		for _, want := range []string{"new", "inspect", "check", "stress", "convert", "csv", "rewrite", "completion", "text-to-bin", "fish"} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: expected the script to complete %q", shell, want)
+			}
+		}
+
+		if shell == "fish" {
+			if !strings.Contains(stdout, "-s n -l num -r -d 'number of ULIDs to generate'") {
+				t.Errorf("fish: expected the script to complete -n and --num together")
+			}
+			continue
+		}
+
+		for _, flag := range []string{"-n", "--num", "--out-template", "--max-tracked", "--explain", "--skip-bad"} {
+			if !strings.Contains(stdout, " "+flag+" ") {
+				t.Errorf("%s: expected the script to complete %s", shell, flag)
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completions are the generators of the completion scripts for each shell.
+var completions = map[string]func(io.Writer){
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+func runCompletion(_ *options, args []string, s stdio) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a shell (bash, zsh, or fish)")
+	}
+
+	gen, ok := completions[args[0]]
+	if !ok {
+		return fmt.Errorf("invalid shell %s (expected bash, zsh, or fish)", args[0])
+	}
+
+	gen(s.out)
+	return nil
+}
+
+// completionFlag is a flag of a command with all of its names, e.g. -n and --num.
+type completionFlag struct {
+	names   []string
+	usage   string
+	boolean bool
+}
+
+// completionFlags returns the flags of the command, grouping the aliases of each
+// flag together by their shared value.
+func completionFlags(cmd *command) []completionFlag {
+	var (
+		flags []completionFlag
+		index = make(map[flag.Value]int)
+	)
+
+	cmd.flagSet(&options{}).VisitAll(func(f *flag.Flag) {
+		i, ok := index[f.Value]
+		if !ok {
+			i = len(flags)
+			index[f.Value] = i
+
+			b, _ := f.Value.(interface{ IsBoolFlag() bool })
+			flags = append(flags, completionFlag{usage: f.Usage, boolean: b != nil && b.IsBoolFlag()})
+		}
+		flags[i].names = append(flags[i].names, f.Name)
+	})
+	return flags
+}
+
+// completionWords returns the words completed after the command: its flags, with
+// a single dash for short names and a double dash for long names, and its
+// arguments.
+func completionWords(cmd *command) []string {
+	var words []string
+	for _, f := range completionFlags(cmd) {
+		for _, name := range f.names {
+			words = append(words, dashes(name)+name)
+		}
+	}
+	return append(words, cmd.args...)
+}
+
+func dashes(name string) string {
+	if len(name) == 1 {
+		return "-"
+	}
+	return "--"
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+func bashCompletion(w io.Writer) {
+	fmt.Fprint(w, `# bash completion for ulid, generated by "ulid completion bash"
+_ulid() {
+    local cur=${COMP_WORDS[COMP_CWORD]}
+    if [[ $COMP_CWORD -eq 1 ]]; then
+`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " ")+" --help")
+	fmt.Fprint(w, `        return
+    fi
+
+    case ${COMP_WORDS[1]} in
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "    %s)\n", cmd.name)
+		fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(completionWords(cmd), " "))
+		fmt.Fprint(w, "        ;;\n")
+	}
+	fmt.Fprint(w, `    esac
+}
+complete -o default -F _ulid ulid
+`)
+}
+
+func zshCompletion(w io.Writer) {
+	fmt.Fprint(w, `#compdef ulid
+# zsh completion for ulid, generated by "ulid completion zsh"
+_ulid() {
+    local -a commands
+    commands=(
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        %s\n", shellQuote(cmd.name+":"+cmd.summary))
+	}
+	fmt.Fprint(w, `    )
+
+    if (( CURRENT == 2 )); then
+        _describe -t commands 'ulid command' commands
+        return
+    fi
+
+    case $words[2] in
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "    %s)\n", cmd.name)
+		fmt.Fprintf(w, "        compadd -- %s\n", strings.Join(completionWords(cmd), " "))
+		fmt.Fprint(w, "        ;;\n")
+	}
+	fmt.Fprint(w, `    *)
+        _files
+        ;;
+    esac
+}
+
+if [ "$funcstack[1]" = "_ulid" ]; then
+    _ulid "$@"
+else
+    compdef _ulid ulid
+fi
+`)
+}
+
+func fishCompletion(w io.Writer) {
+	fmt.Fprint(w, `# fish completion for ulid, generated by "ulid completion fish"
+complete -c ulid -f
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c ulid -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+
+	for _, cmd := range commands {
+		cond := fishQuote("__fish_seen_subcommand_from " + cmd.name)
+		for _, f := range completionFlags(cmd) {
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "complete -c ulid -n %s", cond)
+			for _, name := range f.names {
+				if len(name) == 1 {
+					fmt.Fprintf(&sb, " -s %s", name)
+				} else {
+					fmt.Fprintf(&sb, " -l %s", name)
+				}
+			}
+
+			if !f.boolean {
+				sb.WriteString(" -r")
+			}
+			fmt.Fprintf(w, "%s -d %s\n", sb.String(), fishQuote(f.usage))
+		}
+
+		if len(cmd.args) > 0 {
+			fmt.Fprintf(w, "complete -c ulid -n %s -a %s\n", cond, fishQuote(strings.Join(cmd.args, " ")))
+		}
+	}
+}
+
+// shellQuote single quotes s for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single quotes s for fish, which escapes quotes with a backslash.
+func fishQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+	return "'" + r.Replace(s) + "'"
+}
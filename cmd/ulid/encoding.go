@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.rtnl.ai/ulid"
+)
+
+// encoding formats and parses a text encoding of ULIDs for --encoding.
+type encoding struct {
+	size   int
+	format func(ulid.ULID) string
+	parse  func(string) (ulid.ULID, error)
+}
+
+// encodings are the values of --encoding. Each encoding has a distinct length so
+// that the encoding of an argument to inspect can be detected from its length:
+// 22 is base64, 26 is base32, 32 is hex and 36 is uuid.
+var encodings = map[string]encoding{
+	"base32": {ulid.EncodedSize, ulid.ULID.String, parseBase32},
+	"base64": {ulid.QREncodedSize, ulid.ULID.EncodeQR, parseAny},
+	"hex":    {32, formatHex, parseAny},
+	"uuid":   {36, formatUUID, parseAny},
+}
+
+// lookupEncoding returns the named encoding, defaulting to base32 for generate.
+func lookupEncoding(name string) (encoding, error) {
+	if name == "" {
+		name = "base32"
+	}
+
+	enc, ok := encodings[strings.ToLower(name)]
+	if !ok {
+		return encoding{}, fmt.Errorf("invalid --encoding %s", name)
+	}
+	return enc, nil
+}
+
+// decode parses s with the named encoding or, if name is empty, with the encoding
+// detected from the length of s. Strings of any other length are parsed as base32
+// so that the errors (and the empty string) are handled like ulid.Parse.
+func decode(s, name string) (ulid.ULID, error) {
+	if name != "" {
+		enc, err := lookupEncoding(name)
+		if err != nil {
+			return ulid.Zero, err
+		}
+
+		if len(s) != enc.size {
+			return ulid.Zero, ulid.ErrDataSize
+		}
+		return enc.parse(s)
+	}
+
+	for _, enc := range encodings {
+		if len(s) == enc.size {
+			return enc.parse(s)
+		}
+	}
+	return parseBase32(s)
+}
+
+// decodeClean is like decode except that invisible characters are removed from
+// the argument before its encoding is detected from its length and base32
+// arguments are parsed with ulid.ParseClean, so that ULIDs pasted with invisible
+// characters can be inspected, printing a note to the error output when
+// characters were removed.
+func decodeClean(arg, name string, s stdio) (id ulid.ULID, err error) {
+	clean, _ := ulid.CleanString(arg)
+	if name == "" {
+		for n, enc := range encodings {
+			if len(clean) == enc.size {
+				name = n
+			}
+		}
+	}
+
+	if name != "" && !strings.EqualFold(name, "base32") {
+		id, err = decode(clean, name)
+	} else {
+		id, err = ulid.ParseClean(arg)
+	}
+
+	if err == nil && clean != arg {
+		fmt.Fprintf(s.err, "note: removed invisible characters from %q\n", arg)
+	}
+	return id, err
+}
+
+func parseBase32(s string) (ulid.ULID, error) {
+	return ulid.Parse(s)
+}
+
+// parseAny parses the base64, hex, and uuid encodings with ulid.DecodeAny, which
+// detects the encoding from the length of s that decode has already checked.
+func parseAny(s string) (ulid.ULID, error) {
+	return ulid.DecodeAny([]byte(s))
+}
+
+func formatHex(id ulid.ULID) string {
+	return hex.EncodeToString(id[:])
+}
+
+func formatUUID(id ulid.ULID) string {
+	s := formatHex(id)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestDecodeDispatch(t *testing.T) {
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+
+	testCases := []struct {
+		s    string
+		name string
+		want ulid.ULID
+		err  error
+	}{
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "", id, nil},
+		{"01arz3ndektsv4rrffq69g5fav", "", id, nil},
+		{"AVY-OrXT1nZMYe-5kwK9Ww", "", id, nil},
+		{"01563e3ab5d3d6764c61efb99302bd5b", "", id, nil},
+		{"01563E3AB5D3D6764C61EFB99302BD5B", "", id, nil},
+		{"01563e3a-b5d3-d676-4c61-efb99302bd5b", "", id, nil},
+		{"", "", ulid.Zero, nil},
+		{"01ARZ3NDEK", "", ulid.Zero, ulid.ErrDataSize},
+		{"AVY-OrXT1nZMYe-5kwK9Ww==", "", ulid.Zero, ulid.ErrDataSize},
+		{"01563e3ab5d3d6764c61efb99302bd5g", "", ulid.Zero, ulid.ErrInvalidCharacters},
+		{"01563e3a_b5d3_d676_4c61_efb99302bd5b", "", ulid.Zero, ulid.ErrInvalidCharacters},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "base32", id, nil},
+		{"AVY-OrXT1nZMYe-5kwK9Ww", "base64", id, nil},
+		{"01563e3ab5d3d6764c61efb99302bd5b", "HEX", id, nil},
+		{"01563e3a-b5d3-d676-4c61-efb99302bd5b", "uuid", id, nil},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "base64", ulid.Zero, ulid.ErrDataSize},
+		{"AVY-OrXT1nZMYe-5kwK9Ww", "hex", ulid.Zero, ulid.ErrDataSize},
+		{"01563e3ab5d3d6764c61efb99302bd5b", "base32", ulid.Zero, ulid.ErrDataSize},
+	}
+
+	for i, tc := range testCases {
+		got, err := decode(tc.s, tc.name)
+		if err != tc.err || got != tc.want {
+			t.Errorf("test case %d: decode(%q, %q) = %s (%v), want %s (%v)", i, tc.s, tc.name, got, err, tc.want, tc.err)
+		}
+	}
+
+	if _, err := decode("01ARZ3NDEKTSV4RRFFQ69G5FAV", "base58"); err == nil {
+		t.Errorf("expected an error for an unknown encoding")
+	}
+}
+
+func TestEncodingRoundTrips(t *testing.T) {
+	sizes := make(map[int]string)
+	for name, enc := range encodings {
+		if other, ok := sizes[enc.size]; ok {
+			t.Fatalf("%s and %s have the same length so cannot be detected", name, other)
+		}
+		sizes[enc.size] = name
+	}
+
+	for i := 0; i < 1000; i++ {
+		id := ulid.Make()
+		for from, fenc := range encodings {
+			for to, tenc := range encodings {
+				s := fenc.format(id)
+				if len(s) != fenc.size {
+					t.Fatalf("%s: got %q with length %d, want %d", from, s, len(s), fenc.size)
+				}
+
+				// Detect the encoding, convert to the other encoding, and parse it back.
+				decoded, err := decode(s, "")
+				if err != nil {
+					t.Fatalf("%s: could not decode %q: %v", from, s, err)
+				}
+
+				converted, err := decode(tenc.format(decoded), to)
+				if err != nil || converted != id {
+					t.Fatalf("%s to %s: got %s (%v), want %s", from, to, converted, err, id)
+				}
+			}
+		}
+	}
+}
+
+func TestLookupEncoding(t *testing.T) {
+	enc, err := lookupEncoding("")
+	if err != nil || enc.size != ulid.EncodedSize {
+		t.Errorf("expected base32 to be the default encoding")
+	}
+
+	if _, err := lookupEncoding("base58"); err == nil {
+		t.Errorf("expected an error for an unknown encoding")
+	}
+}
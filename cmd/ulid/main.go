@@ -1,41 +1,188 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	cryptorand "crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	mathrand "math/rand"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/csvtool"
+	"go.rtnl.ai/ulid/forensics"
+	"go.rtnl.ai/ulid/internal/stats"
+	"go.rtnl.ai/ulid/stress"
+	"go.rtnl.ai/ulid/vectors"
 )
 
 const usageText = `Rotational ULID debugging utility
-Usage: generate or inspect a ULID
+Usage: ulid COMMAND [options] [arguments]
 
-Generate:
+Commands:
 
-    ulid [options]
+    new                   generate ULIDs
+    inspect               print the time, layout, or ULID of each argument
+    check                 report statistics, classify the entropy, or audit the order of ULIDs read from stdin
+    stress                simulate a burst of ULIDs in a single millisecond until the entropy overflows
+    convert               convert between newline delimited ULIDs and binary records
+    csv                   rewrite a ULID column of a CSV file read from stdin
+    completion            print a shell completion script
+
+Run "ulid COMMAND -h" for the usage of a command.
+
+` + newUsage + `
+` + inspectUsage + `
+` + checkUsage + `
+` + stressUsage + `
+` + convertUsage + `
+` + csvUsage + `
+` + completionUsage + `
+` + legacyUsage + `
+Options:
+
+    -h, --help            display this help and exit
+`
+
+const newUsage = `New:
+
+    ulid new [options]
 
     -n INT, --num INT     number of ULIDs to generate
     -q, --quick           use quick entropy (not cryptographic)
     -m, --mono            use monotonic entropy (for more than one ULID)
     -z, --zero            use zero entropy
+    -a, --after ULID      fail if a generated ULID does not sort strictly after the given ULID
+    -w, --words           print each ULID as a 12 word mnemonic (display only)
+    --encoding ENC        output encoding: base32 (default), base64, hex, or uuid
+    --out-template TMPL   render each ULID with a Go text/template and print it; the fields
+                          are {{.ULID}} (in the --encoding), {{.Time}} (UTC) and {{.Index}} (from 0)
+    --mkdir               create a directory at each rendered --out-template path
+    --touch               create an empty file at each rendered --out-template path
+    --force               allow --mkdir and --touch paths that already exist
+    --version             print the version and the entropy that the other flags select, then exit
+` + qrUsage
 
-Inspect:
+const inspectUsage = `Inspect:
 
-    ulid [options] ULID [ULID ...]
+    ulid inspect [options] ULID [ULID ...]
+    ulid inspect --words [options] WORD [WORD ...]
 
     -f, --format string   time format (default, rfc3339, unix, ms)
     -l, --local           use local time instead of UTC
     -p, --path            assumes argument is a path with a ULID filename (strips directory and extension)
+    -a, --after ULID      only accept ULIDs that sort strictly after the given ULID
+    -w, --words           decode 12 word mnemonics and print the ULID for each
+    -e, --explain         print the bit-level layout of each ULID instead of its time
+    --encoding ENC        only accept ULIDs in the encoding (base32, base64, hex, or uuid);
+                          by default the encoding is detected from the length of each ULID:
+                          22 is base64, 26 is base32, 32 is hex, and 36 is uuid; byte order
+                          marks, zero-width characters, and surrounding whitespace are removed
+                          from base32 ULIDs
+    --json                print the --explain output as JSON
+` + qrUsage
 
-Options:
+// qrUsage are the flags of new and inspect that print QR codes.
+const qrUsage = `    --qr                  also print each ULID as a QR code of its base32 encoding drawn
+                          with Unicode half blocks, e.g. to scan it from a console with a phone
+    --braille             like --qr but drawn with braille patterns, which take half of the lines
+    --invert              draw the light modules of --qr and --braille rather than the dark
+                          modules, for terminals with light text on a dark background
+`
 
-    -h, --help            display this help and exit
+const checkUsage = `Check:
+
+    ulid check [options] < ids.txt
+    ulid check --classify [options] < ids.txt
+    ulid check --order [options] < pairs.csv
+    ulid check --selftest
+
+    -b, --bucket duration histogram or --order bucket size (default 24h)
+    --max-tracked INT     distinct ULIDs tracked for exact duplicate detection (default 1000000)
+    --classify            classify how the entropy of ULIDs read from stdin was generated (zero, sequential,
+                          monotonic, random) instead of reporting collision and ordering statistics
+    --order               read seq,ulid lines from stdin in insertion order, e.g. exported with an
+                          autoincrement column, and report the ULIDs whose timestamp is earlier than
+                          a ULID inserted before them (time travel); a header line is skipped
+    --tolerance duration  how far a timestamp may regress before it is reported by --order (default 0s)
+    --json                print the full classification or --order report as JSON
+    --selftest            verify the ULID implementation against the embedded test vectors
+`
+
+const stressUsage = `Stress:
+
+    ulid stress [options]
+
+    --entropy MODE        entropy mode: default, secure, monotonic (default), or sequential
+    --inc INT             maximum increment of monotonic entropy (default math.MaxUint32)
+    --duration duration   how long to generate ULIDs if the entropy does not overflow (default 1s)
+    --limit INT           stop after generating INT ULIDs (default no limit)
+    --json                print the report as JSON
+
+    generate ULIDs with a frozen millisecond as fast as possible until the entropy overflows
+    or the duration elapses, then report the rate, the ULIDs before the first overflow, and
+    the allocations, e.g. ulid stress --inc 1024 --duration 2s
+`
+
+const convertUsage = `Convert:
+
+    ulid convert MODE < in > out
+
+    convert newline delimited ULIDs to 16 byte binary records (text-to-bin) or back (bin-to-text)
+`
+
+const csvUsage = `CSV:
+
+    ulid csv rewrite --col INT --canonicalize [options] < in.csv > out.csv
+    ulid csv rewrite --col INT --map FILE [options] < in.csv > out.csv
+
+    --col INT             the column of the ULIDs, from 1 as with cut -f
+    --canonicalize        rewrite the ULIDs in canonical form (uppercase)
+    --map FILE            rewrite the ULIDs with the old,new pairs of a CSV file, e.g. to replace
+                          duplicated IDs; ULIDs that are not in the file are only canonicalized
+    --header              copy the first line as a header without parsing it
+    --lenient             remove invisible characters and whitespace around the ULIDs before
+                          parsing them, e.g. of IDs pasted into a spreadsheet
+    --skip-bad            copy bad rows (without the column, with an invalid ULID) unchanged and
+                          continue, rather than stopping at the first bad row
+    --json                print the counts of the rewrite as JSON
+
+    the records are streamed from stdin to stdout, replacing only the bytes of the ULIDs, so the
+    quoting and line endings of the file are preserved; the counts of rows that were rewritten,
+    changed, skipped, or failed, and the numbers of the bad rows, are printed to stderr
+`
+
+const completionUsage = `Completion:
+
+    ulid completion SHELL
+
+    print the completion script for the shell (bash, zsh, or fish), e.g. source <(ulid completion bash)
+`
+
+const legacyUsage = `Legacy:
+
+    The command may be omitted for backwards compatibility, in which case the flags of new,
+    inspect, check, and stress are all accepted and the mode is selected by the flags and
+    arguments:
+
+    ulid [options]                    ulid new [options]
+    ulid [options] ULID [ULID ...]    ulid inspect [options] ULID [ULID ...]
+    ulid -s, --stats [options]        ulid check [options]
+    ulid --classify [options]         ulid check --classify [options]
+    ulid --order [options]            ulid check --order [options]
+    ulid --stress [options]           ulid stress [options]
+    ulid -c, --convert MODE           ulid convert MODE
+    ulid --selftest                   ulid check --selftest
 `
 
 const (
@@ -43,95 +190,176 @@ const (
 	rfc3339ms = "2006-01-02T15:04:05.000Z07:00"
 )
 
-var (
-	num    int
-	quick  bool
-	mono   bool
-	zero   bool
-	format string
-	local  bool
-	path   bool
-	help   bool
-)
-
 func main() {
-	// Set command line flags
-	// Generate Options
-	flag.IntVar(&num, "num", 1, "")
-	flag.IntVar(&num, "n", 1, "")
-	flag.BoolVar(&quick, "quick", false, "")
-	flag.BoolVar(&quick, "q", false, "")
-	flag.BoolVar(&mono, "mono", false, "")
-	flag.BoolVar(&mono, "m", false, "")
-	flag.BoolVar(&zero, "zero", false, "")
-	flag.BoolVar(&zero, "z", false, "")
-
-	// Inspect Options
-	flag.StringVar(&format, "format", "default", "")
-	flag.StringVar(&format, "f", "default", "")
-	flag.BoolVar(&local, "local", false, "")
-	flag.BoolVar(&local, "l", false, "")
-	flag.BoolVar(&path, "path", false, "")
-	flag.BoolVar(&path, "p", false, "")
-
-	// General Options
-	flag.BoolVar(&help, "help", false, "")
-	flag.BoolVar(&help, "h", false, "")
-
-	// Parse command line flags
-	flag.Parse()
-	if help {
-		usage()
-		os.Exit(0)
-	}
-
-	switch flag.NArg() {
-	case 0:
-		generate()
-	default:
-		parse()
+	if err := run(os.Args[1:], stdio{in: os.Stdin, out: os.Stdout, err: os.Stderr}); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 }
 
-func usage() {
-	fmt.Fprint(os.Stderr, usageText)
+func runNew(o *options, args []string, s stdio) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q (use ulid inspect to inspect ULIDs)", args)
+	}
+
+	if o.version {
+		fmt.Fprintln(s.out, versionInfo(o, newEntropy(o)))
+		return nil
+	}
+
+	if o.num < 1 {
+		return fmt.Errorf("invalid --num %d", o.num)
+	}
+
+	enc, err := lookupEncoding(o.encName)
+	if err != nil {
+		return err
+	}
+
+	out, err := outputTemplate(o, enc)
+	if err != nil {
+		return err
+	}
+
+	if err := checkQR(o); err != nil {
+		return err
+	}
+
+	if o.qr || o.braille {
+		switch {
+		case o.words || out != nil:
+			return fmt.Errorf("--qr and --braille cannot be used with --words or --out-template")
+		case o.encName != "" && !strings.EqualFold(o.encName, "base32"):
+			return fmt.Errorf("--qr and --braille cannot be used with --encoding %s (QR codes are base32)", o.encName)
+		}
+	}
+
+	entropy := newEntropy(o)
+	// Generate ULIDs
+	for i := 0; i < o.num; i++ {
+		id, err := ulid.NewAt(time.Now(), entropy)
+		if err != nil {
+			return err
+		}
+
+		if err := checkAfter(o, id); err != nil {
+			return err
+		}
+
+		if out != nil {
+			if err := out.emit(s.out, id, i); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if o.words {
+			fmt.Fprintf(s.out, "%s\n", strings.Join(id.Words(), " "))
+			continue
+		}
+		fmt.Fprintf(s.out, "%s\n", enc.format(id))
+		if err := printQR(o, id, s); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func generate() {
-	if num < 1 {
-		fmt.Fprintf(os.Stderr, "invalid --num %d\n", num)
-		os.Exit(1)
+// checkQR returns an error if the flags that print QR codes conflict.
+func checkQR(o *options) error {
+	switch {
+	case o.invert && !o.qr && !o.braille:
+		return fmt.Errorf("--invert requires --qr or --braille")
+	case o.qr && o.braille:
+		return fmt.Errorf("--qr and --braille cannot be used together")
+	case (o.qr || o.braille) && o.jsonOutput:
+		return fmt.Errorf("--qr and --braille cannot be used with --json")
+	}
+	return nil
+}
+
+// printQR prints the QR code of the ULID if --qr or --braille is set.
+func printQR(o *options, id ulid.ULID, s stdio) error {
+	if !o.qr && !o.braille {
+		return nil
 	}
 
-	// Create entropy from options
+	var opts []ulid.QROption
+	if o.braille {
+		opts = append(opts, ulid.QRBraille())
+	}
+	if o.invert {
+		opts = append(opts, ulid.QRInvert())
+	}
+	return ulid.RenderQR(id, s.out, opts...)
+}
+
+// newEntropy returns the entropy selected by the flags of new.
+func newEntropy(o *options) io.Reader {
 	entropy := cryptorand.Reader
-	if quick {
+	if o.quick {
 		seed := time.Now().UnixNano()
 		source := mathrand.NewSource(seed)
 		entropy = mathrand.New(source)
 	}
-	if zero {
+	if o.zero {
 		entropy = zeroReader{}
 	}
-	if mono {
+	if o.mono {
 		entropy = ulid.Monotonic(entropy, 0)
 	}
+	return entropy
+}
 
-	// Generate ULIDs
-	for i := 0; i < num; i++ {
-		id, err := ulid.New(ulid.Timestamp(time.Now()), entropy)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			os.Exit(1)
+// versionInfo describes the version of the package and the entropy that new
+// would use, e.g. go.rtnl.ai/ulid v1.2.0 (crypto/rand entropy, monotonic with inc 1).
+func versionInfo(o *options, entropy io.Reader) string {
+	info := (&ulid.Generator{Entropy: entropy}).Info()
+	if o.zero {
+		info.Entropy = "zero"
+	}
+
+	mode := "not monotonic"
+	if info.Monotonic {
+		mode = fmt.Sprintf("monotonic with inc %d", info.Inc)
+	}
+	return fmt.Sprintf("go.rtnl.ai/ulid %s (%s entropy, %s)", info.Version, info.Entropy, mode)
+}
+
+// outputTemplate returns the --out-template for new, or nil if it is not set.
+func outputTemplate(o *options, enc encoding) (*outTemplate, error) {
+	if o.outTmpl == "" {
+		if o.mkdir || o.touch || o.force {
+			return nil, fmt.Errorf("--mkdir, --touch, and --force require --out-template")
 		}
+		return nil, nil
+	}
+
+	switch {
+	case o.words:
+		return nil, fmt.Errorf("--words cannot be used with --out-template")
+	case o.mkdir && o.touch:
+		return nil, fmt.Errorf("--mkdir and --touch cannot be used together")
+	}
 
-		fmt.Fprintf(os.Stdout, "%s\n", id)
+	out, err := newOutTemplate(o.outTmpl, enc)
+	if err != nil {
+		return nil, err
 	}
+
+	if o.mkdir || o.touch {
+		out.fs, out.touch, out.force = osFS{}, o.touch, o.force
+	}
+	return out, nil
 }
 
-func parse() {
+func runInspect(o *options, args []string, s stdio) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one ULID to inspect")
+	}
+
 	var formatFunc func(time.Time) string
-	switch strings.ToLower(format) {
+	switch strings.ToLower(o.format) {
 	case "default":
 		formatFunc = func(t time.Time) string { return t.Format(defaultms) }
 	case "rfc3339":
@@ -141,28 +369,473 @@ func parse() {
 	case "ms":
 		formatFunc = func(t time.Time) string { return fmt.Sprint(t.UnixNano() / 1e6) }
 	default:
-		fmt.Fprintf(os.Stderr, "invalid --format %s\n", format)
-		os.Exit(1)
+		return fmt.Errorf("invalid --format %s", o.format)
+	}
+
+	if _, err := lookupEncoding(o.encName); err != nil {
+		return err
+	}
+
+	if err := checkQR(o); err != nil {
+		return err
+	}
+
+	if o.words {
+		var err error
+		if args, err = mnemonics(args); err != nil {
+			return err
+		}
+	}
+
+	for _, arg := range args {
+		var (
+			id  ulid.ULID
+			err error
+		)
+
+		switch {
+		case o.words:
+			id, err = ulid.ParseWords(arg)
+		case o.path:
+			arg = filepath.Base(arg)
+			arg = strings.TrimSuffix(arg, filepath.Ext(arg))
+			fallthrough
+		default:
+			id, err = decodeClean(arg, o.encName, s)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := checkAfter(o, id); err != nil {
+			return err
+		}
+
+		if o.explain {
+			if err := explanation(o, id, s); err != nil {
+				return err
+			}
+		} else {
+			if o.words {
+				fmt.Fprintf(s.out, "%s\n", id)
+			}
+
+			t := ulid.Time(id.Time())
+			if !o.local {
+				t = t.UTC()
+			}
+			fmt.Fprintf(s.err, "%s\n", formatFunc(t))
+		}
+
+		if err := printQR(o, id, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explanation prints the bit-level layout of the ULID as a table or as JSON.
+func explanation(o *options, id ulid.ULID, s stdio) error {
+	ex := ulid.Explain(id)
+	if o.jsonOutput {
+		return json.NewEncoder(s.out).Encode(ex)
+	}
+
+	fmt.Fprint(s.out, ex)
+	return nil
+}
+
+// mnemonics regroups the words in the arguments into one mnemonic per ULID so
+// that words may be given either quoted together or as separate arguments.
+func mnemonics(args []string) ([]string, error) {
+	fields := strings.Fields(strings.Join(args, " "))
+	if len(fields)%ulid.WordCount != 0 {
+		return nil, fmt.Errorf("expected a multiple of %d words, got %d", ulid.WordCount, len(fields))
+	}
+
+	groups := make([]string, 0, len(fields)/ulid.WordCount)
+	for i := 0; i < len(fields); i += ulid.WordCount {
+		groups = append(groups, strings.Join(fields[i:i+ulid.WordCount], " "))
+	}
+	return groups, nil
+}
+
+// checkAfter returns an error if --after is set and the id does not sort
+// strictly after it.
+func checkAfter(o *options, id ulid.ULID) error {
+	if o.after.Valid && id.Compare(o.after.ULID) <= 0 {
+		return fmt.Errorf("%s is not after %s", id, o.after.ULID)
+	}
+	return nil
+}
+
+func runCheck(o *options, args []string, s stdio) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q (ULIDs are read from stdin)", args)
+	}
+
+	switch {
+	case o.classify && o.selftest:
+		return fmt.Errorf("--classify and --selftest cannot be used together")
+	case o.order && (o.classify || o.selftest):
+		return fmt.Errorf("--order cannot be used with --classify or --selftest")
+	case o.order:
+		return runOrder(o, s)
+	case o.classify:
+		return runClassify(o, s)
+	case o.selftest:
+		return selfTest(s)
+	default:
+		return runStats(o, s)
+	}
+}
+
+func runStats(o *options, s stdio) error {
+	if o.bucket <= 0 {
+		return fmt.Errorf("invalid --bucket %s", o.bucket)
+	}
+
+	if o.maxTracked < 1 {
+		return fmt.Errorf("invalid --max-tracked %d", o.maxTracked)
+	}
+
+	report, err := stats.Analyze(s.in, o.bucket, o.maxTracked)
+	if err != nil {
+		return err
+	}
+
+	distinct := fmt.Sprint(report.Distinct)
+	if !report.Exact {
+		distinct = fmt.Sprintf("%d (upper bound, more than %d distinct ULIDs)", report.Distinct, o.maxTracked)
 	}
 
-	for _, s := range flag.Args() {
-		if path {
-			s = filepath.Base(s)
-			s = strings.TrimSuffix(s, filepath.Ext(s))
+	fmt.Fprintf(s.out, "total:        %d\n", report.Total)
+	fmt.Fprintf(s.out, "invalid:      %d\n", report.Invalid)
+	fmt.Fprintf(s.out, "distinct:     %s\n", distinct)
+	fmt.Fprintf(s.out, "duplicates:   %d\n", len(report.Duplicates))
+	fmt.Fprintf(s.out, "zero entropy: %d\n", report.ZeroEntropy)
+	fmt.Fprintf(s.out, "out of order: %d\n", report.OutOfOrder)
+
+	if report.Total == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(s.out, "min time:     %s\n", report.MinTime.Format(rfc3339ms))
+	fmt.Fprintf(s.out, "max time:     %s\n", report.MaxTime.Format(rfc3339ms))
+
+	if len(report.Duplicates) > 0 {
+		fmt.Fprintln(s.out, "\nduplicates:")
+		for _, id := range report.DuplicateIDs() {
+			fmt.Fprintf(s.out, "    %s  %d\n", id, report.Duplicates[id])
 		}
+	}
+
+	fmt.Fprintf(s.out, "\nhistogram (%s buckets):\n", report.Bucket)
+	for _, ts := range report.Buckets() {
+		fmt.Fprintf(s.out, "    %s  %d\n", ts.Format(rfc3339ms), report.Histogram[ts])
+	}
+
+	if report.Unbucketed > 0 {
+		fmt.Fprintf(s.out, "    (%d ULIDs beyond the first %d buckets)\n", report.Unbucketed, stats.MaxBuckets)
+	}
+	return nil
+}
+
+func runClassify(o *options, s stdio) error {
+	var ids []ulid.ULID
+	scanner := bufio.NewScanner(s.in)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var id ulid.ULID
+		if err := id.UnmarshalText(line); err != nil {
+			return fmt.Errorf("%w: %q", err, line)
+		}
+		ids = append(ids, id)
+	}
 
-		id, err := ulid.Parse(s)
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	report := forensics.Classify(ids)
+	if o.jsonOutput {
+		encoder := json.NewEncoder(s.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Fprint(s.out, report)
+	return nil
+}
+
+// runOrder audits the insertion order of the seq,ulid lines read from stdin.
+func runOrder(o *options, s stdio) error {
+	if o.bucket <= 0 {
+		return fmt.Errorf("invalid --bucket %s", o.bucket)
+	}
+
+	if o.tolerance < 0 {
+		return fmt.Errorf("invalid --tolerance %s", o.tolerance)
+	}
+
+	audit := ulid.NewOrderAudit(ulid.AuditTolerance(o.tolerance), ulid.AuditBucket(o.bucket))
+	reader := csv.NewReader(s.in)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		seq, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			os.Exit(1)
+			if line == 1 {
+				// The header of the export, e.g. id,ulid.
+				continue
+			}
+			return fmt.Errorf("line %d: invalid seq %q", line, record[0])
 		}
 
-		t := ulid.Time(id.Time())
-		if !local {
-			t = t.UTC()
+		var id ulid.ULID
+		if err := id.UnmarshalText([]byte(strings.TrimSpace(record[1]))); err != nil {
+			return fmt.Errorf("line %d: %w: %q", line, err, record[1])
 		}
-		fmt.Fprintf(os.Stderr, "%s\n", formatFunc(t))
+		audit.Add(seq, id)
+	}
+
+	report := audit.Report()
+	if o.jsonOutput {
+		encoder := json.NewEncoder(s.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Fprintf(s.out, "pairs:          %d\n", report.Pairs)
+	fmt.Fprintf(s.out, "unordered:      %d\n", report.Unordered)
+	fmt.Fprintf(s.out, "inversions:     %d\n", report.Inversions)
+	fmt.Fprintf(s.out, "tolerance:      %s\n", report.Tolerance)
+	if report.Inversions == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(s.out, "max regression: %s\n", report.MaxRegression)
+	fmt.Fprintf(s.out, "\ninversions (%s buckets):\n", report.Bucket)
+	for _, b := range report.Buckets {
+		fmt.Fprintf(s.out, "    %s  %d\n", b.Start.Format(rfc3339ms), b.Count)
+	}
+
+	fmt.Fprintf(s.out, "\nfirst %d inversions:\n", len(report.Samples))
+	for _, inv := range report.Samples {
+		fmt.Fprintf(s.out, "    %d %s  %s before %d %s\n", inv.Seq, inv.ID, inv.Regression, inv.MaxSeq, inv.MaxID)
+	}
+	return nil
+}
+
+func runStress(o *options, args []string, s stdio) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+
+	if !slices.Contains(stress.Modes, stress.Mode(o.entropy)) {
+		return fmt.Errorf("invalid --entropy %s", o.entropy)
+	}
+
+	if o.duration <= 0 {
+		return fmt.Errorf("invalid --duration %s", o.duration)
+	}
+
+	report, err := stress.SimulateBurst(stress.Options{
+		Mode:     stress.Mode(o.entropy),
+		Inc:      o.inc,
+		Duration: o.duration,
+		Limit:    o.limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.jsonOutput {
+		encoder := json.NewEncoder(s.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	overflow := "none"
+	if report.Overflowed {
+		overflow = fmt.Sprintf("after %d ULIDs", report.FirstOverflow)
+	}
+
+	fmt.Fprintf(s.out, "entropy:      %s\n", report.Mode)
+	if report.Mode == stress.Monotonic {
+		fmt.Fprintf(s.out, "inc:          %d\n", o.inc)
+	}
+	fmt.Fprintf(s.out, "timestamp:    %s\n", ulid.Time(report.Timestamp).UTC().Format(rfc3339ms))
+	fmt.Fprintf(s.out, "generated:    %d\n", report.Generated)
+	fmt.Fprintf(s.out, "overflow:     %s\n", overflow)
+	fmt.Fprintf(s.out, "elapsed:      %s\n", report.Elapsed)
+	fmt.Fprintf(s.out, "rate:         %.0f ULIDs/s\n", report.Rate)
+	fmt.Fprintf(s.out, "allocations:  %d (%.2f per ULID, %d bytes)\n", report.Allocs, report.AllocsPerID, report.AllocBytes)
+	return nil
+}
+
+func runConvert(_ *options, args []string, s stdio) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a conversion mode (text-to-bin or bin-to-text)")
+	}
+	return convert(args[0], s)
+}
+
+func convert(mode string, s stdio) error {
+	var convertFunc func(io.Reader, io.Writer) (int64, error)
+	switch strings.ToLower(mode) {
+	case "text-to-bin":
+		convertFunc = ulid.ConvertTextToBinary
+	case "bin-to-text":
+		convertFunc = ulid.ConvertBinaryToText
+	default:
+		return fmt.Errorf("invalid conversion mode %s", mode)
+	}
+
+	_, err := convertFunc(s.in, s.out)
+	return err
+}
+
+// runCSV rewrites the ULID column of the CSV records read from stdin.
+func runCSV(o *options, args []string, s stdio) error {
+	if len(args) == 0 || args[0] != "rewrite" {
+		return fmt.Errorf("expected a csv mode (rewrite)")
+	}
+
+	// The flags may also follow the mode, e.g. ulid csv rewrite --col 3.
+	fs := newFlagSet("csv")
+	o.csvFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(s.err, csvUsage)
+			return nil
+		}
+		return fmt.Errorf("ulid csv: %w", err)
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	if o.col < 1 {
+		return fmt.Errorf("invalid --col %d (the first column is 1)", o.col)
+	}
+
+	rewrite := func(id ulid.ULID) (ulid.ULID, error) { return id, nil }
+	switch {
+	case o.canonicalize && o.mapFile != "":
+		return fmt.Errorf("--canonicalize cannot be used with --map")
+	case o.mapFile != "":
+		mapping, err := readMapping(o.mapFile)
+		if err != nil {
+			return err
+		}
+
+		rewrite = func(id ulid.ULID) (ulid.ULID, error) {
+			if to, ok := mapping[id]; ok {
+				return to, nil
+			}
+			return id, nil
+		}
+	case !o.canonicalize:
+		return fmt.Errorf("expected --canonicalize or --map")
+	}
+
+	var opts []csvtool.Option
+	if o.header {
+		opts = append(opts, csvtool.Header())
+	}
+
+	if o.lenient {
+		opts = append(opts, csvtool.Lenient())
+	}
+
+	if o.skipBad {
+		opts = append(opts, csvtool.SkipBadRows())
+	}
+
+	stats, err := csvtool.RewriteColumn(s.in, s.out, o.col-1, rewrite, opts...)
+	if o.jsonOutput {
+		encoder := json.NewEncoder(s.err)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(stats)
+	} else {
+		fmt.Fprintf(s.err, "rows:     %d\n", stats.Rows)
+		fmt.Fprintf(s.err, "changed:  %d\n", stats.Changed)
+		fmt.Fprintf(s.err, "skipped:  %d\n", stats.Skipped)
+		fmt.Fprintf(s.err, "failed:   %d\n", stats.Failed)
+		if len(stats.BadRows) > 0 {
+			rows := make([]string, len(stats.BadRows))
+			for i, row := range stats.BadRows {
+				rows[i] = strconv.Itoa(row)
+			}
+			fmt.Fprintf(s.err, "bad rows: %s\n", strings.Join(rows, ", "))
+		}
+	}
+	return err
+}
+
+// readMapping reads the old,new pairs of ULIDs of a CSV file; a header line is
+// skipped.
+func readMapping(path string) (map[ulid.ULID]ulid.ULID, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mapping := make(map[ulid.ULID]ulid.ULID)
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return mapping, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("--map: %w", err)
+		}
+
+		from, err := ulid.ParseStrict(strings.TrimSpace(record[0]))
+		if err != nil && line == 1 {
+			// The header of the file, e.g. old,new.
+			continue
+		}
+
+		var to ulid.ULID
+		if err == nil {
+			to, err = ulid.ParseStrict(strings.TrimSpace(record[1]))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("--map line %d: %w", line, err)
+		}
+		mapping[from] = to
+	}
+}
+
+func selfTest(s stdio) error {
+	if err := vectors.SelfTest(); err != nil {
+		return fmt.Errorf("selftest failed:\n%w", err)
 	}
+	fmt.Fprintln(s.out, "selftest passed")
+	return nil
 }
 
 type zeroReader struct{}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// fixture is the data passed to the --out-template for each generated ULID.
+type fixture struct {
+	ULID  string    // The ULID in the --encoding
+	Time  time.Time // The timestamp of the ULID in UTC
+	Index int       // The zero-based index of the ULID in the generated sequence
+}
+
+// filesystem performs the effects of --mkdir and --touch so that they can be
+// faked in tests.
+type filesystem interface {
+	Exists(path string) (bool, error)
+	Mkdir(path string) error
+	Touch(path string) error
+}
+
+// outTemplate renders each generated ULID with the --out-template and either
+// prints the result or, if fs is not nil, creates the directory or file at the
+// rendered path.
+type outTemplate struct {
+	tmpl  *template.Template
+	enc   encoding
+	fs    filesystem
+	touch bool
+	force bool
+}
+
+func newOutTemplate(text string, enc encoding) (*outTemplate, error) {
+	tmpl, err := template.New("out").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --out-template: %w", err)
+	}
+	return &outTemplate{tmpl: tmpl, enc: enc}, nil
+}
+
+// render executes the template for the ULID at index i of the generated sequence.
+func (o *outTemplate) render(id ulid.ULID, i int) (string, error) {
+	data := fixture{
+		ULID:  o.enc.format(id),
+		Time:  ulid.Time(id.Time()).UTC(),
+		Index: i,
+	}
+
+	var sb strings.Builder
+	if err := o.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("invalid --out-template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// emit renders the template for the ULID and prints the result to w. If a
+// filesystem is set the result is first checked and created as a path.
+func (o *outTemplate) emit(w io.Writer, id ulid.ULID, i int) error {
+	out, err := o.render(id, i)
+	if err != nil {
+		return err
+	}
+
+	if o.fs != nil {
+		if err = o.create(out); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w, out)
+	return err
+}
+
+// create makes the directory or file at path, returning an error if the path
+// is not safe or, unless force is set, if it already exists.
+func (o *outTemplate) create(path string) error {
+	if err := checkPath(path); err != nil {
+		return err
+	}
+
+	if !o.force {
+		exists, err := o.fs.Exists(path)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+	}
+
+	if o.touch {
+		return o.fs.Touch(path)
+	}
+	return o.fs.Mkdir(path)
+}
+
+// checkPath rejects rendered paths that are empty, absolute, or that escape the
+// current directory so that a template cannot create files elsewhere.
+func checkPath(path string) error {
+	if !filepath.IsLocal(path) {
+		return fmt.Errorf("unsafe path %q: paths must be relative and within the current directory", path)
+	}
+	return nil
+}
+
+// osFS is the filesystem used by the command.
+type osFS struct{}
+
+func (osFS) Exists(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (osFS) Mkdir(path string) error {
+	return os.MkdirAll(path, 0o755)
+}
+
+// Touch creates the file and any parent directories, or updates the modification
+// time of the file if it already exists without truncating it.
+func (osFS) Touch(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return os.Chtimes(path, now, now)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+// fakeFS records the paths created by an outTemplate.
+type fakeFS struct {
+	dirs  map[string]bool
+	files map[string]bool
+	err   error
+}
+
+func newFakeFS(existing ...string) *fakeFS {
+	fsys := &fakeFS{dirs: make(map[string]bool), files: make(map[string]bool)}
+	for _, path := range existing {
+		fsys.files[path] = true
+	}
+	return fsys
+}
+
+func (f *fakeFS) Exists(path string) (bool, error) {
+	return f.dirs[path] || f.files[path], f.err
+}
+
+func (f *fakeFS) Mkdir(path string) error {
+	f.dirs[path] = true
+	return nil
+}
+
+func (f *fakeFS) Touch(path string) error {
+	f.files[path] = true
+	return nil
+}
+
+func TestOutTemplateRender(t *testing.T) {
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+
+	testCases := []struct {
+		text string
+		enc  string
+		want string
+	}{
+		{"{{.ULID}}", "", "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"fixtures/{{.Index}}-{{.ULID}}", "", "fixtures/7-01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"{{.ULID}}", "hex", "01563e3ab5d3d6764c61efb99302bd5b"},
+		{`{{.Time.Format "2006-01-02"}}/{{.ULID}}`, "", "2016-07-30/01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"{{.Time.Location}}", "", "UTC"},
+		{"static", "", "static"},
+	}
+
+	for i, tc := range testCases {
+		enc, _ := lookupEncoding(tc.enc)
+		out, err := newOutTemplate(tc.text, enc)
+		if err != nil {
+			t.Fatalf("test case %d: %v", i, err)
+		}
+
+		got, err := out.render(id, 7)
+		if err != nil || got != tc.want {
+			t.Errorf("test case %d: render(%q) = %q (%v), want %q", i, tc.text, got, err, tc.want)
+		}
+	}
+
+	if _, err := newOutTemplate("{{.ULID", encodings["base32"]); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+
+	out, _ := newOutTemplate("{{.Missing}}", encodings["base32"])
+	if _, err := out.render(id, 0); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	valid := []string{"a", "fixtures/01ARZ3NDEKTSV4RRFFQ69G5FAV", "a/../b", "./a", "a/b/c.json"}
+	for _, path := range valid {
+		if err := checkPath(path); err != nil {
+			t.Errorf("expected %q to be valid, got %v", path, err)
+		}
+	}
+
+	invalid := []string{"", "..", "../a", "a/../../b", "/tmp/a", "/", "a/../.."}
+	for _, path := range invalid {
+		if err := checkPath(path); err == nil {
+			t.Errorf("expected %q to be rejected", path)
+		}
+	}
+}
+
+func TestOutTemplateEmit(t *testing.T) {
+	ids := []ulid.ULID{ulid.Make(), ulid.Make(), ulid.Make()}
+
+	t.Run("Print", func(t *testing.T) {
+		out, _ := newOutTemplate("{{.Index}} {{.ULID}}", encodings["base32"])
+
+		var buf bytes.Buffer
+		for i, id := range ids {
+			if err := out.emit(&buf, id, i); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := "0 " + ids[0].String() + "\n1 " + ids[1].String() + "\n2 " + ids[2].String() + "\n"
+		if buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("Mkdir", func(t *testing.T) {
+		fsys := newFakeFS()
+		out, _ := newOutTemplate("fixtures/{{.ULID}}", encodings["base32"])
+		out.fs = fsys
+
+		var buf bytes.Buffer
+		for i, id := range ids {
+			if err := out.emit(&buf, id, i); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, id := range ids {
+			if !fsys.dirs["fixtures/"+id.String()] {
+				t.Errorf("expected a directory for %s", id)
+			}
+		}
+
+		if len(fsys.files) != 0 || strings.Count(buf.String(), "\n") != len(ids) {
+			t.Errorf("expected only directories to be created and each path printed")
+		}
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		fsys := newFakeFS()
+		out, _ := newOutTemplate("{{.ULID}}.json", encodings["base32"])
+		out.fs, out.touch = fsys, true
+
+		if err := out.emit(&bytes.Buffer{}, ids[0], 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if !fsys.files[ids[0].String()+".json"] || len(fsys.dirs) != 0 {
+			t.Errorf("expected only a file to be created")
+		}
+	})
+
+	t.Run("Collisions", func(t *testing.T) {
+		fsys := newFakeFS("existing")
+		out, _ := newOutTemplate("existing", encodings["base32"])
+		out.fs, out.touch = fsys, true
+
+		var buf bytes.Buffer
+		if err := out.emit(&buf, ids[0], 0); err == nil || buf.Len() != 0 {
+			t.Errorf("expected an error for an existing path, got %v", err)
+		}
+
+		// Paths that collide within a run are also rejected.
+		out, _ = newOutTemplate("same", encodings["base32"])
+		out.fs = fsys
+		if err := out.emit(&buf, ids[0], 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := out.emit(&buf, ids[1], 1); err == nil {
+			t.Errorf("expected an error for a colliding path")
+		}
+
+		out.force = true
+		if err := out.emit(&buf, ids[1], 1); err != nil {
+			t.Errorf("expected --force to allow an existing path, got %v", err)
+		}
+	})
+
+	t.Run("Unsafe", func(t *testing.T) {
+		fsys := newFakeFS()
+		for _, text := range []string{"../{{.ULID}}", "/tmp/{{.ULID}}", "{{if .Index}}x{{end}}"} {
+			out, _ := newOutTemplate(text, encodings["base32"])
+			out.fs, out.force = fsys, true
+			if err := out.emit(&bytes.Buffer{}, ids[0], 0); err == nil {
+				t.Errorf("expected %q to be rejected", text)
+			}
+		}
+
+		if len(fsys.dirs) != 0 || len(fsys.files) != 0 {
+			t.Errorf("expected nothing to be created")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		fsys := newFakeFS()
+		fsys.err = errors.New("permission denied")
+		out, _ := newOutTemplate("{{.ULID}}", encodings["base32"])
+		out.fs = fsys
+
+		if err := out.emit(&bytes.Buffer{}, ids[0], 0); !errors.Is(err, fsys.err) {
+			t.Errorf("expected the filesystem error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Command ulidgen generates typed ID types over ULIDs with the gen package, e.g.
+// UserID and OrderID types that cannot be mixed up. Each argument is the name of
+// a type, optionally followed by a colon and the prefix of its text form. Run it
+// from a go:generate directive, which sets the package from $GOPACKAGE:
+//
+//	//go:generate go run go.rtnl.ai/ulid/cmd/ulidgen -o ids_gen.go -test ids_gen_test.go UserID:usr OrderID:ord
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go.rtnl.ai/ulid/gen"
+)
+
+func main() {
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "the package of the generated code (default $GOPACKAGE)")
+	out := flag.String("o", "", "write the types to the file instead of stdout")
+	test := flag.String("test", "", "also write the tests of the types to the file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ulidgen [-package name] [-o file] [-test file] Type[:prefix]...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*pkg, *out, *test, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "ulidgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(pkg, out, test string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no types to generate")
+	}
+
+	types := make([]gen.Type, 0, len(args))
+	for _, arg := range args {
+		t, err := gen.ParseType(arg)
+		if err != nil {
+			return err
+		}
+		types = append(types, t)
+	}
+
+	src, err := gen.Generate(pkg, types)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(out, src, 0o644)
+	}
+
+	if err != nil || test == "" {
+		return err
+	}
+
+	if src, err = gen.GenerateTests(pkg, types); err != nil {
+		return err
+	}
+	return os.WriteFile(test, src, 0o644)
+}
@@ -0,0 +1,123 @@
+package ulid
+
+import "fmt"
+
+// invalidChar is translated from characters that are not in the alphabet of a
+// Codec; it is not in the Encoding of this package either, so the standard
+// decoding reports it as it would any other invalid character.
+const invalidChar = 0xFF
+
+// Codec encodes and decodes ULIDs as strings with a custom alphabet of 32
+// characters, e.g. to read and write the IDs of a legacy system without
+// re-encoding them. Because the characters of the alphabet are in increasing
+// byte order, the encoded strings sort in the same order as the ULIDs, like the
+// standard encoding.
+//
+// A Codec translates between its alphabet and Encoding and otherwise uses the
+// optimized encoding and decoding of this package, so its behavior mirrors
+// String, Parse, and ParseStrict: the leading character of a string may be at
+// most the eighth character of the alphabet, since 26 characters encode 130
+// bits, and decoding is case-insensitive for the letters of the alphabet whose
+// other case is not in the alphabet itself. The package functions do not use a
+// Codec. A Codec is safe for concurrent use.
+type Codec struct {
+	alphabet string
+	enc      [32]byte  // the character of the alphabet for each value
+	dec      [256]byte // the character of Encoding for each character of the alphabet
+}
+
+// NewCodec returns a Codec for the alphabet, which must have 32 printable ASCII
+// characters in strictly increasing byte order so that the encoding preserves
+// the order of ULIDs; otherwise an error wrapping ErrInvalidAlphabet is returned.
+func NewCodec(alphabet string) (*Codec, error) {
+	if len(alphabet) != len(Encoding) {
+		return nil, fmt.Errorf("%w: got %d characters, want %d", ErrInvalidAlphabet, len(alphabet), len(Encoding))
+	}
+
+	c := &Codec{alphabet: alphabet}
+	for i := range c.dec {
+		c.dec[i] = invalidChar
+	}
+
+	for i := 0; i < len(alphabet); i++ {
+		ch := alphabet[i]
+		switch {
+		case ch < '!' || ch > '~':
+			return nil, fmt.Errorf("%w: %q is not a printable ASCII character", ErrInvalidAlphabet, ch)
+		case i > 0 && ch == alphabet[i-1]:
+			return nil, fmt.Errorf("%w: %q is repeated", ErrInvalidAlphabet, ch)
+		case i > 0 && ch < alphabet[i-1]:
+			return nil, fmt.Errorf("%w: %q is before %q", ErrInvalidAlphabet, ch, alphabet[i-1])
+		}
+
+		c.enc[i] = ch
+		c.dec[ch] = Encoding[i]
+	}
+
+	// Fold the letters whose other case is not in the alphabet into the alphabet.
+	for upper := byte('A'); upper <= 'Z'; upper++ {
+		lower := upper + 'a' - 'A'
+		switch {
+		case c.dec[lower] == invalidChar:
+			c.dec[lower] = c.dec[upper]
+		case c.dec[upper] == invalidChar:
+			c.dec[upper] = c.dec[lower]
+		}
+	}
+	return c, nil
+}
+
+// Alphabet returns the alphabet of the codec.
+func (c *Codec) Alphabet() string {
+	return c.alphabet
+}
+
+// Encode returns the ULID encoded with the alphabet of the codec.
+func (c *Codec) Encode(id ULID) string {
+	var buf [EncodedSize]byte
+	c.encode(&buf, id)
+	return string(buf[:])
+}
+
+// AppendEncode appends the ULID encoded with the alphabet of the codec to dst
+// and returns the extended buffer.
+func (c *Codec) AppendEncode(dst []byte, id ULID) []byte {
+	var buf [EncodedSize]byte
+	c.encode(&buf, id)
+	return append(dst, buf[:]...)
+}
+
+func (c *Codec) encode(dst *[EncodedSize]byte, id ULID) {
+	id.encodeTime(dst)
+	id.encodeEntropy(dst)
+	for i, ch := range dst {
+		dst[i] = c.enc[dec[ch]]
+	}
+}
+
+// Decode parses a ULID encoded with the alphabet of the codec like Parse parses
+// the standard encoding: ErrDataSize is returned if s is not 26 characters long
+// and ErrOverflow if the leading character exceeds the 128 bits of a ULID, while
+// invalid characters produce undefined ULIDs.
+func (c *Codec) Decode(s string) (id ULID, err error) {
+	return id, c.decode(s, false, &id)
+}
+
+// DecodeStrict parses a ULID encoded with the alphabet of the codec like
+// ParseStrict, additionally returning ErrInvalidCharacters if s contains a
+// character that is not in the alphabet.
+func (c *Codec) DecodeStrict(s string) (id ULID, err error) {
+	return id, c.decode(s, true, &id)
+}
+
+func (c *Codec) decode(s string, strict bool, id *ULID) error {
+	if len(s) != EncodedSize {
+		return ErrDataSize
+	}
+
+	var buf [EncodedSize]byte
+	for i := 0; i < len(s); i++ {
+		buf[i] = c.dec[s[i]]
+	}
+	return parse(buf[:], strict, id)
+}
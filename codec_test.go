@@ -0,0 +1,155 @@
+package ulid_test
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+// Alternate alphabets in increasing byte order: the lowercase base32hex alphabet
+// of RFC 4648 and an alphabet of punctuation, digits, and uppercase letters.
+const (
+	hexAlphabet   = "0123456789abcdefghijklmnopqrstuv"
+	punctAlphabet = "!#$%&()*+,-./0123456789:;<=>?@AB"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	ids := append(randomBatch(rand.New(rand.NewSource(42)), 1000), ulid.Zero, ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"))
+	for _, alphabet := range []string{ulid.Encoding, hexAlphabet, punctAlphabet} {
+		codec, err := ulid.NewCodec(alphabet)
+		if err != nil {
+			t.Fatalf("%s: %v", alphabet, err)
+		}
+
+		if codec.Alphabet() != alphabet {
+			t.Errorf("got alphabet %q, want %q", codec.Alphabet(), alphabet)
+		}
+
+		encoded := make([]string, len(ids))
+		for i, id := range ids {
+			s := codec.Encode(id)
+			if len(s) != ulid.EncodedSize || strings.Trim(s, alphabet) != "" {
+				t.Fatalf("%s: %s encoded as %q", alphabet, id, s)
+			}
+
+			if alphabet == ulid.Encoding && s != id.String() {
+				t.Fatalf("expected the standard alphabet to encode %s, got %s", id, s)
+			}
+
+			if dst := codec.AppendEncode([]byte("id:"), id); string(dst) != "id:"+s {
+				t.Fatalf("%s: AppendEncode returned %q, want %q", alphabet, dst, "id:"+s)
+			}
+
+			for name, decode := range map[string]func(string) (ulid.ULID, error){"Decode": codec.Decode, "DecodeStrict": codec.DecodeStrict} {
+				if got, err := decode(s); err != nil || got != id {
+					t.Fatalf("%s: %s(%q) returned %s (%v), want %s", alphabet, name, s, got, err, id)
+				}
+			}
+			encoded[i] = s
+		}
+
+		// The order of the strings is the order of the ULIDs.
+		sorted := slices.Clone(ids)
+		slices.SortFunc(sorted, ulid.ULID.Compare)
+		slices.Sort(encoded)
+		for i, s := range encoded {
+			if id, _ := codec.Decode(s); id != sorted[i] {
+				t.Fatalf("%s: expected the sorted strings to decode to the sorted ULIDs at %d", alphabet, i)
+			}
+		}
+	}
+}
+
+func TestCodecDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	hex, err := ulid.NewCodec(hexAlphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	max := hex.Encode(ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"))
+	if max != "7vvvvvvvvvvvvvvvvvvvvvvvvv" {
+		t.Fatalf("unexpected encoding of the largest ULID %q", max)
+	}
+
+	testCases := []struct {
+		s      string
+		err    error
+		strict error
+	}{
+		{"7vvvvvvvvvvvvvvvvvvvvvvvvv", nil, nil},
+		{"7VVVVVVVVVVVVVVVVVVVVVVVVV", nil, nil},
+		{"8vvvvvvvvvvvvvvvvvvvvvvvvv", ulid.ErrOverflow, ulid.ErrOverflow},
+		{"avvvvvvvvvvvvvvvvvvvvvvvvv", ulid.ErrOverflow, ulid.ErrOverflow},
+		{"7vvvvvvvvvvvvvvvvvvvvvvvvw", nil, ulid.ErrInvalidCharacters},
+		{"7vvvvvvvvvvvvvvvvvvvvvvvv", ulid.ErrDataSize, ulid.ErrDataSize},
+		{"", ulid.ErrDataSize, ulid.ErrDataSize},
+	}
+
+	for _, tc := range testCases {
+		if _, err := hex.Decode(tc.s); !errors.Is(err, tc.err) {
+			t.Errorf("Decode(%q): got error %v, want %v", tc.s, err, tc.err)
+		}
+
+		id, err := hex.DecodeStrict(tc.s)
+		if !errors.Is(err, tc.strict) {
+			t.Errorf("DecodeStrict(%q): got error %v, want %v", tc.s, err, tc.strict)
+		}
+
+		if tc.strict == nil && hex.Encode(id) != max {
+			t.Errorf("DecodeStrict(%q): got %s", tc.s, id)
+		}
+	}
+
+	// Letters are case-insensitive only if their other case is not in the alphabet.
+	mixed, err := ulid.NewCodec("0123456789ABCDEFGHIJKLMNOPQRSTab")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := mixed.DecodeStrict("0000000000000000000000000c"); err != nil || mixed.Encode(id) != "0000000000000000000000000C" {
+		t.Errorf("expected c to decode as C, got %s (%v)", id, err)
+	}
+
+	if id, err := mixed.DecodeStrict("0000000000000000000000000a"); err != nil || id[15] != 30 {
+		t.Errorf("expected a to decode as the 31st character, got %s (%v)", id, err)
+	}
+}
+
+func TestNewCodecErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		alphabet string
+		message  string
+	}{
+		{"Empty", "", "got 0 characters, want 32"},
+		{"Short", hexAlphabet[1:], "got 31 characters, want 32"},
+		{"Long", hexAlphabet + "w", "got 33 characters, want 32"},
+		{"Duplicate", "00123456789abcdefghijklmnopqrstu", `'0' is repeated`},
+		{"Unsorted", "0123456789abcdefghijklmnopqrstvu", `'u' is before 'v'`},
+		{"Descending", "vutsrqponmlkjihgfedcba9876543210", `'u' is before 'v'`},
+		{"Space", " 123456789abcdefghijklmnopqrstuv", `' ' is not a printable ASCII character`},
+		{"NonASCII", "0123456789abcdefghijklmnopqrstu\xe9", `'é' is not a printable ASCII character`},
+	}
+
+	for _, tc := range testCases {
+		codec, err := ulid.NewCodec(tc.alphabet)
+		if !errors.Is(err, ulid.ErrInvalidAlphabet) || codec != nil {
+			t.Errorf("%s: got %v, %v, want %v", tc.name, codec, err, ulid.ErrInvalidAlphabet)
+			continue
+		}
+
+		if want := ulid.ErrInvalidAlphabet.Error() + ": " + tc.message; err.Error() != want {
+			t.Errorf("%s: got error %q, want %q", tc.name, err, want)
+		}
+	}
+}
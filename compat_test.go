@@ -0,0 +1,301 @@
+//go:build compat
+
+package ulid_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	oklog "github.com/oklog/ulid/v2"
+	"go.rtnl.ai/ulid"
+)
+
+// The differential tests in this file assert that this package is compatible with
+// github.com/oklog/ulid/v2 so that code can be migrated with confidence. They are
+// behind the compat build tag to keep the oklog dependency out of normal builds:
+//
+//	go test -tags compat ./...
+//
+// Where this package intentionally diverges from oklog/ulid, the tests assert the
+// documented difference.
+
+func TestCompatEncoding(t *testing.T) {
+	t.Parallel()
+
+	prop := func(b [16]byte) bool {
+		id, ok := ulid.ULID(b), oklog.ULID(b)
+
+		if id.String() != ok.String() || id.Time() != ok.Time() || !id.Timestamp().Equal(ok.Timestamp()) {
+			return false
+		}
+
+		ib, ierr := id.MarshalBinary()
+		ob, oerr := ok.MarshalBinary()
+		if !bytes.Equal(ib, ob) || ierr != nil || oerr != nil {
+			return false
+		}
+
+		it, _ := id.MarshalText()
+		ot, _ := ok.MarshalText()
+		if !bytes.Equal(it, ot) {
+			return false
+		}
+
+		iv, _ := id.Value()
+		ov, _ := ok.Value()
+		return bytes.Equal(iv.([]byte), ov.([]byte)) && id.Compare(ulid.ULID(b)) == ok.Compare(oklog.ULID(b))
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 1e5}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompatParse(t *testing.T) {
+	t.Parallel()
+
+	// Random strings of the encoded length drawn from the base32 alphabet in both
+	// cases and a few invalid characters, so that both valid and invalid strings
+	// are generated.
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZabcdefghjkmnpqrstvwxyzILOUilou-_ \x00\xff"
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < 1e5; i++ {
+		n := ulid.EncodedSize
+		if i%10 == 0 {
+			n = rng.Intn(ulid.EncodedSize * 2)
+		}
+
+		buf := make([]byte, n)
+		for j := range buf {
+			buf[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+
+		// The first character must be 0-7 to avoid overflow most of the time.
+		if n > 0 && i%4 != 0 {
+			buf[0] = byte('0' + rng.Intn(8))
+		}
+
+		assertCompatParse(t, string(buf))
+	}
+}
+
+func TestCompatMonotonic(t *testing.T) {
+	t.Parallel()
+
+	for _, inc := range []uint64{0, 1, 2, 1 << 16, 1 << 32} {
+		seed := time.Now().UnixNano()
+		ie := ulid.Monotonic(rand.New(rand.NewSource(seed)), inc)
+		oe := oklog.Monotonic(rand.New(rand.NewSource(seed)), inc)
+
+		// Generate bursts within the same millisecond and across milliseconds.
+		ms := ulid.Now()
+		for i := 0; i < 10000; i++ {
+			if i%1000 == 0 {
+				ms++
+			}
+
+			id, ierr := ulid.New(ms, ie)
+			ok, oerr := oklog.New(ms, oe)
+			if id != ulid.ULID(ok) || !sameError(ierr, oerr) {
+				t.Fatalf("inc=%d: got %s (%v), oklog %s (%v)", inc, id, ierr, ok, oerr)
+			}
+		}
+	}
+}
+
+// Both packages read the same entropy and produce the same ULIDs from the same
+// timestamps, including on overflow of the timestamp.
+func TestCompatNew(t *testing.T) {
+	t.Parallel()
+
+	for _, ms := range []uint64{0, 1, ulid.Now(), ulid.MaxTime(), ulid.MaxTime() + 1} {
+		entropy := bytes.Repeat([]byte{0xA5}, 10)
+		id, ierr := ulid.New(ms, bytes.NewReader(entropy))
+		ok, oerr := oklog.New(ms, bytes.NewReader(entropy))
+		if id != ulid.ULID(ok) || !sameError(ierr, oerr) {
+			t.Errorf("ms=%d: got %s (%v), oklog %s (%v)", ms, id, ierr, ok, oerr)
+		}
+	}
+
+	if ulid.Timestamp(time.Unix(1e9, 5e6)) != oklog.Timestamp(time.Unix(1e9, 5e6)) {
+		t.Errorf("expected timestamps to be equal")
+	}
+}
+
+// Divergence: Parse accepts any type rather than only strings. ULIDs and
+// [16]byte arrays are returned as is and byte slices are unmarshaled as the 16
+// byte binary encoding rather than parsed as text.
+func TestCompatParseAny(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.Make()
+	for _, v := range []any{id, [16]byte(id), id[:]} {
+		if got, err := ulid.Parse(v); err != nil || got != id {
+			t.Errorf("Parse(%T): got %s (%v), want %s", v, got, err, id)
+		}
+	}
+
+	// oklog would parse the text; the byte slice is the wrong size for binary.
+	if _, err := ulid.Parse([]byte(id.String())); err != ulid.ErrDataSize {
+		t.Errorf("expected a text byte slice to be unmarshaled as binary, got %v", err)
+	}
+
+	if _, err := ulid.Parse(42); err != ulid.ErrUnknownType {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
+
+// Divergence: Parse returns the zero ULID for an empty string, while oklog and
+// ParseStrict return ErrDataSize.
+func TestCompatParseEmpty(t *testing.T) {
+	t.Parallel()
+
+	if _, err := oklog.Parse(""); err != oklog.ErrDataSize {
+		t.Errorf("expected oklog.Parse to return ErrDataSize, got %v", err)
+	}
+
+	if id, err := ulid.Parse(""); err != nil || !id.IsZero() {
+		t.Errorf("expected Parse to return the zero ULID, got %s (%v)", id, err)
+	}
+
+	if _, err := ulid.ParseStrict(""); err != ulid.ErrDataSize {
+		t.Errorf("expected ParseStrict to return ErrDataSize, got %v", err)
+	}
+}
+
+// Divergence: Scan unmarshals byte slices as the binary encoding only, while oklog
+// also accepts the text encoding in a byte slice.
+func TestCompatScan(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.Make()
+	for _, src := range []any{nil, id.String(), id[:]} {
+		var got ulid.ULID
+		var ok oklog.ULID
+		ierr, oerr := got.Scan(src), ok.Scan(src)
+		if got != ulid.ULID(ok) || !sameError(ierr, oerr) {
+			t.Errorf("Scan(%T): got %s (%v), oklog %s (%v)", src, got, ierr, ok, oerr)
+		}
+	}
+
+	text := []byte(id.String())
+	var ok oklog.ULID
+	if err := ok.Scan(text); err != nil || ulid.ULID(ok) != id {
+		t.Errorf("expected oklog to scan text bytes, got %s (%v)", ok, err)
+	}
+
+	var got ulid.ULID
+	if err := got.Scan(text); err != ulid.ErrDataSize {
+		t.Errorf("expected Scan to reject text bytes, got %v", err)
+	}
+}
+
+// Divergence: oklog has no NullULID; the zero NullULID is marshaled as JSON null and
+// scanned from a SQL NULL, while the zero ULID is marshaled as zeros by both.
+func TestCompatNullULID(t *testing.T) {
+	t.Parallel()
+
+	var null ulid.NullULID
+	if data, err := json.Marshal(&null); err != nil || string(data) != "null" {
+		t.Errorf("expected null, got %s (%v)", data, err)
+	}
+
+	if err := null.Scan(nil); err != nil || null.Valid {
+		t.Errorf("expected an invalid NullULID from a SQL NULL, got %+v (%v)", null, err)
+	}
+
+	idata, _ := json.Marshal(ulid.Zero)
+	odata, _ := json.Marshal(oklog.ULID{})
+	if !bytes.Equal(idata, odata) {
+		t.Errorf("got %s, oklog %s", idata, odata)
+	}
+}
+
+func FuzzParseCompat(f *testing.F) {
+	for _, s := range loadCorpus(f, "FuzzParse") {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		assertCompatParse(t, s)
+	})
+}
+
+// assertCompatParse checks that both packages parse s identically except for the
+// documented divergence of Parse on the empty string.
+func assertCompatParse(t *testing.T, s string) {
+	t.Helper()
+
+	id, ierr := ulid.ParseStrict(s)
+	ok, oerr := oklog.ParseStrict(s)
+	if id != ulid.ULID(ok) || !sameError(ierr, oerr) {
+		t.Fatalf("ParseStrict(%q): got %s (%v), oklog %s (%v)", s, id, ierr, ok, oerr)
+	}
+
+	if s == "" {
+		return
+	}
+
+	// Parse results are undefined for invalid characters but must still agree.
+	id, ierr = ulid.Parse(s)
+	ok, oerr = oklog.Parse(s)
+	if id != ulid.ULID(ok) || !sameError(ierr, oerr) {
+		t.Fatalf("Parse(%q): got %s (%v), oklog %s (%v)", s, id, ierr, ok, oerr)
+	}
+
+	var text ulid.ULID
+	var otext oklog.ULID
+	ierr, oerr = text.UnmarshalText([]byte(s)), otext.UnmarshalText([]byte(s))
+	if text != ulid.ULID(otext) || !sameError(ierr, oerr) {
+		t.Fatalf("UnmarshalText(%q): got %s (%v), oklog %s (%v)", s, text, ierr, otext, oerr)
+	}
+}
+
+// sameError compares errors from both packages by message since the sentinel
+// errors are distinct values with the same text.
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+// loadCorpus reads the string values of a native Go fuzz corpus in testdata.
+func loadCorpus(tb testing.TB, target string) (seeds []string) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "fuzz", target, "*"))
+	if err != nil || len(paths) == 0 {
+		tb.Fatalf("could not find fuzz corpus for %s: %v", target, err)
+	}
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "string(") {
+				continue
+			}
+
+			s, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(line, "string("), ")"))
+			if err != nil {
+				tb.Fatalf("could not parse corpus entry %s: %v", path, err)
+			}
+			seeds = append(seeds, s)
+		}
+		f.Close()
+	}
+	return seeds
+}
@@ -0,0 +1,112 @@
+package ulid
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// NewContext is like New but returns early if the context is canceled or its
+// deadline is exceeded before the entropy has been read, e.g. when crypto/rand
+// blocks on a system that has not yet gathered enough entropy during boot. The
+// returned error wraps both ErrEntropyCanceled and the context's error.
+//
+// Since most readers do not support cancellation, the read is performed in a
+// separate goroutine that is abandoned when the context is done. The abandoned
+// read still completes when the reader eventually returns and its result is
+// discarded; however, it may still advance the state of a monotonic reader.
+// Because an abandoned read may overlap with later calls, the entropy must be
+// safe for concurrent use if calls may time out and be retried with the same
+// reader, as DefaultEntropy and SecureEntropy are.
+func NewContext(ctx context.Context, ms uint64, entropy io.Reader) (id ULID, err error) {
+	if err = ctx.Err(); err != nil {
+		return id, fmt.Errorf("%w: %w", ErrEntropyCanceled, err)
+	}
+
+	// If the context cannot be canceled there is no need for a goroutine.
+	if ctx.Done() == nil || entropy == nil {
+		return New(ms, entropy)
+	}
+
+	if err = id.SetTime(ms); err != nil {
+		return id, err
+	}
+
+	read := contextReads.Get().(*contextRead)
+	go read.run(ms, entropy)
+
+	select {
+	case <-read.done:
+	case <-ctx.Done():
+		// If the read has not completed, it now owns the state and returns it to
+		// the pool; otherwise the result is already available so use it.
+		if read.state.CompareAndSwap(readPending, readAbandoned) {
+			return Zero, fmt.Errorf("%w: %w", ErrEntropyCanceled, ctx.Err())
+		}
+		<-read.done
+	}
+
+	copy(id[6:], read.entropy[:])
+	err = read.err
+	read.release()
+
+	if err != nil {
+		return Zero, err
+	}
+	return id, nil
+}
+
+// MakeSecureContext is like MakeSecure but returns an error wrapping
+// ErrEntropyCanceled if the context is done before the entropy has been read.
+func MakeSecureContext(ctx context.Context) (ULID, error) {
+	return NewContext(ctx, Now(), SecureEntropy())
+}
+
+const (
+	readPending int32 = iota
+	readCompleted
+	readAbandoned
+)
+
+// contextRead holds the result of a single entropy read performed on behalf of
+// NewContext. Reads are pooled so that timed out calls do not leak allocations;
+// the state determines whether the caller or the reading goroutine returns the
+// read to the pool once both are finished with it.
+type contextRead struct {
+	state   atomic.Int32
+	done    chan struct{}
+	entropy [10]byte
+	err     error
+}
+
+var contextReads = sync.Pool{
+	New: func() any {
+		return &contextRead{done: make(chan struct{}, 1)}
+	},
+}
+
+func (r *contextRead) run(ms uint64, entropy io.Reader) {
+	switch e := entropy.(type) {
+	case MonotonicReader:
+		r.err = e.MonotonicRead(ms, r.entropy[:])
+	default:
+		_, r.err = io.ReadFull(e, r.entropy[:])
+	}
+
+	if r.state.CompareAndSwap(readPending, readCompleted) {
+		r.done <- struct{}{}
+		return
+	}
+
+	// The caller has abandoned the read so the result is discarded.
+	r.release()
+}
+
+func (r *contextRead) release() {
+	r.entropy = [10]byte{}
+	r.err = nil
+	r.state.Store(readPending)
+	contextReads.Put(r)
+}
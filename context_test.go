@@ -0,0 +1,147 @@
+package ulid_test
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// blockingReader blocks every read until it is unblocked.
+type blockingReader struct {
+	unblock chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	<-r.unblock
+	return crand.Reader.Read(p)
+}
+
+func TestNewContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		ms := ulid.Now()
+		id, err := ulid.NewContext(ctx, ms, bytes.NewReader(bytes.Repeat([]byte{0x42}, 10)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id.Time() != ms || !bytes.Equal(id.Entropy(), bytes.Repeat([]byte{0x42}, 10)) {
+			t.Errorf("unexpected ulid %s", id)
+		}
+
+		if id, err = ulid.MakeSecureContext(ctx); err != nil || id.IsZero() {
+			t.Errorf("could not make secure ulid: %v", err)
+		}
+
+		// A context without a deadline does not need a goroutine.
+		if _, err = ulid.NewContext(context.Background(), ms, crand.Reader); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if _, err := ulid.NewContext(ctx, ulid.MaxTime()+1, crand.Reader); err != ulid.ErrBigTime {
+			t.Errorf("got err %v, want %v", err, ulid.ErrBigTime)
+		}
+
+		if _, err := ulid.NewContext(ctx, ulid.Now(), bytes.NewReader(nil)); err != io.EOF {
+			t.Errorf("got err %v, want %v", err, io.EOF)
+		}
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := ulid.NewContext(canceled, ulid.Now(), crand.Reader)
+		if !errors.Is(err, ulid.ErrEntropyCanceled) || !errors.Is(err, context.Canceled) {
+			t.Errorf("expected canceled error, got %v", err)
+		}
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		r := &blockingReader{unblock: make(chan struct{})}
+		defer close(r.unblock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		id, err := ulid.NewContext(ctx, ulid.Now(), r)
+		if !errors.Is(err, ulid.ErrEntropyCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded error, got %v", err)
+		}
+
+		if !id.IsZero() {
+			t.Errorf("expected zero ulid on timeout, got %s", id)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("deadline was not honored, returned after %s", elapsed)
+		}
+	})
+}
+
+// NOTE: this test is not parallel since it counts the running goroutines.
+func TestNewContextLeaks(t *testing.T) {
+	before := runtime.NumGoroutine()
+	r := &blockingReader{unblock: make(chan struct{})}
+
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if _, err := ulid.NewContext(ctx, ulid.Now(), r); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded error, got %v", err)
+		}
+		cancel()
+	}
+
+	if n := runtime.NumGoroutine(); n < before+100 {
+		t.Fatalf("expected the blocked reads to still be running, got %d goroutines (from %d)", n, before)
+	}
+
+	// Once the reader unblocks, the abandoned reads must discard their results and
+	// exit rather than blocking forever.
+	close(r.unblock)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: %d running, expected %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The pooled reads are reusable after being abandoned.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		if _, err := ulid.NewContext(ctx, ulid.Now(), crand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewContext(b *testing.B) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	entropy := ulid.SecureEntropy()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ulid.NewContext(ctx, ulid.Now(), entropy); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
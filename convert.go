@@ -0,0 +1,164 @@
+package ulid
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Converter streams ULIDs between newline delimited text records and raw 16 byte
+// binary records, e.g. for bulk migrating a database column from CHAR(26) to
+// BINARY(16) and back. Conversion uses constant memory regardless of the input
+// size. The zero value is ready to use and does not report progress.
+type Converter struct {
+	// If Progress is not nil it is called with the total number of records
+	// converted every ProgressEvery records (default 10000) and once more when
+	// the conversion completes successfully.
+	Progress      func(records int64)
+	ProgressEvery int64
+}
+
+// ConvertTextToBinary reads newline delimited ULID strings from r and writes the
+// raw 16 byte binary ULIDs to w. See Converter.TextToBinary.
+func ConvertTextToBinary(r io.Reader, w io.Writer) (n int64, err error) {
+	return (&Converter{}).TextToBinary(r, w)
+}
+
+// ConvertBinaryToText reads raw 16 byte binary ULIDs from r and writes newline
+// delimited ULID strings to w. See Converter.BinaryToText.
+func ConvertBinaryToText(r io.Reader, w io.Writer) (n int64, err error) {
+	return (&Converter{}).BinaryToText(r, w)
+}
+
+// TextToBinary reads newline delimited ULID strings from r and writes the raw
+// 16 byte binary ULIDs to w, returning the number of records converted. Each line
+// is strictly parsed (a trailing carriage return is allowed); blank lines are
+// not allowed except for a single trailing newline at the end of the input. If a
+// line is invalid, a *BatchError is returned with the zero-based record number
+// and byte offset of the line; records before the invalid line are written.
+func (c *Converter) TextToBinary(r io.Reader, w io.Writer) (n int64, err error) {
+	var (
+		offset int64
+		id     ULID
+		line   []byte
+	)
+
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	for {
+		if line, err = br.ReadSlice('\n'); err != nil {
+			if err == bufio.ErrBufferFull {
+				// A line longer than the buffer can never be a valid ULID.
+				return n, c.fail(bw, n, offset, ErrDataSize)
+			}
+
+			if err != io.EOF {
+				return n, c.fail(bw, n, offset, err)
+			}
+
+			if len(line) == 0 {
+				break
+			}
+		}
+
+		size := int64(len(line))
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+
+		if perr := parse(line, true, &id); perr != nil {
+			return n, c.fail(bw, n, offset, perr)
+		}
+
+		if _, werr := bw.Write(id[:]); werr != nil {
+			return n, werr
+		}
+
+		n++
+		offset += size
+		c.progress(n, false)
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if err = bw.Flush(); err != nil {
+		return n, err
+	}
+
+	c.progress(n, true)
+	return n, nil
+}
+
+// BinaryToText reads raw 16 byte binary ULIDs from r and writes newline delimited
+// ULID strings to w, returning the number of records converted. If the input
+// ends with a truncated record, a *BatchError wrapping ErrDataSize is returned
+// with the zero-based record number and byte offset of the truncated record.
+func (c *Converter) BinaryToText(r io.Reader, w io.Writer) (n int64, err error) {
+	var (
+		id  ULID
+		buf [EncodedSize + 1]byte
+	)
+
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	buf[EncodedSize] = '\n'
+
+	for {
+		if _, err = io.ReadFull(br, id[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = ErrDataSize
+			}
+			return n, c.fail(bw, n, n*int64(len(id)), err)
+		}
+
+		_ = id.MarshalTextTo(buf[:EncodedSize])
+		if _, err = bw.Write(buf[:]); err != nil {
+			return n, err
+		}
+
+		n++
+		c.progress(n, false)
+	}
+
+	if err = bw.Flush(); err != nil {
+		return n, err
+	}
+
+	c.progress(n, true)
+	return n, nil
+}
+
+// fail flushes the records converted before the error and returns a BatchError.
+func (c *Converter) fail(bw *bufio.Writer, n, offset int64, err error) error {
+	if ferr := bw.Flush(); ferr != nil {
+		return ferr
+	}
+	return &BatchError{Index: int(n), Offset: offset, Err: err}
+}
+
+func (c *Converter) progress(n int64, done bool) {
+	if c.Progress == nil {
+		return
+	}
+
+	every := c.ProgressEvery
+	if every <= 0 {
+		every = 10000
+	}
+
+	// Avoid reporting the final count twice if it was just reported.
+	if n%every == 0 {
+		if !done || n == 0 {
+			c.Progress(n)
+		}
+	} else if done {
+		c.Progress(n)
+	}
+}
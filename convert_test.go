@@ -0,0 +1,146 @@
+package ulid_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	// Multi-megabyte input: 250k records are 6.75MB of text and 4MB of binary.
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 250000)
+	text, _ := ulid.EncodeBatchText(ids, nil, '\n')
+	text = append(text, '\n')
+
+	var progress []int64
+	conv := &ulid.Converter{
+		Progress:      func(n int64) { progress = append(progress, n) },
+		ProgressEvery: 100000,
+	}
+
+	bin := &bytes.Buffer{}
+	n, err := conv.TextToBinary(bytes.NewReader(text), bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(ids)) || bin.Len() != len(ids)*16 {
+		t.Fatalf("converted %d records into %d bytes, want %d records", n, bin.Len(), len(ids))
+	}
+
+	for i, id := range ids {
+		if !bytes.Equal(bin.Bytes()[i*16:(i+1)*16], id[:]) {
+			t.Fatalf("record %d: binary does not match ulid %s", i, id)
+		}
+	}
+
+	if want := []int64{100000, 200000, 250000}; !slices.Equal(progress, want) {
+		t.Errorf("got progress %v, want %v", progress, want)
+	}
+
+	out := &bytes.Buffer{}
+	if n, err = ulid.ConvertBinaryToText(bytes.NewReader(bin.Bytes()), out); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(ids)) || !bytes.Equal(out.Bytes(), text) {
+		t.Fatalf("round trip of %d records did not reproduce the input", n)
+	}
+}
+
+func TestConvertTextToBinary(t *testing.T) {
+	t.Parallel()
+
+	a, b := "01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFNPBB9WE"
+	for _, tc := range []struct {
+		name   string
+		input  string
+		n      int64
+		offset int64
+		err    error
+	}{
+		{"Empty", "", 0, 0, nil},
+		{"NoTrailingNewline", a + "\n" + b, 2, 0, nil},
+		{"CRLF", a + "\r\n" + b + "\r\n", 2, 0, nil},
+		{"Lowercase", strings.ToLower(a) + "\n", 1, 0, nil},
+		{"InvalidMiddle", a + "\nfoo\n" + b + "\n", 1, 27, ulid.ErrDataSize},
+		{"InvalidCharacters", a + "\n" + b + "\n" + a[:25] + "U\n" + b, 2, 54, ulid.ErrInvalidCharacters},
+		{"BlankLine", a + "\n\n" + b + "\n", 1, 27, ulid.ErrDataSize},
+		{"DoubleTrailingNewline", a + "\n\n", 1, 27, ulid.ErrDataSize},
+		{"VeryLongLine", a + "\n" + strings.Repeat("A", 10000) + "\n", 1, 27, ulid.ErrDataSize},
+		{"TruncatedFinal", a + "\n" + b[:10], 1, 27, ulid.ErrDataSize},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			n, err := ulid.ConvertTextToBinary(strings.NewReader(tc.input), out)
+			if n != tc.n {
+				t.Errorf("got %d records, want %d", n, tc.n)
+			}
+
+			if out.Len() != int(tc.n)*16 {
+				t.Errorf("expected records before the error to be written, got %d bytes", out.Len())
+			}
+
+			if tc.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var berr *ulid.BatchError
+			if !errors.As(err, &berr) || !errors.Is(err, tc.err) {
+				t.Fatalf("got err %v, want a batch error wrapping %v", err, tc.err)
+			}
+
+			if int64(berr.Index) != tc.n || berr.Offset != tc.offset {
+				t.Errorf("got record %d at offset %d, want record %d at offset %d", berr.Index, berr.Offset, tc.n, tc.offset)
+			}
+		})
+	}
+}
+
+func TestConvertBinaryToText(t *testing.T) {
+	t.Parallel()
+
+	a, b := ulid.Make(), ulid.Make()
+	input := append(a.Bytes(), b.Bytes()...)
+
+	out := &bytes.Buffer{}
+	n, err := ulid.ConvertBinaryToText(bytes.NewReader(append(input, 0x01, 0x02, 0x03)), out)
+	if n != 2 {
+		t.Errorf("got %d records, want 2", n)
+	}
+
+	if want := a.String() + "\n" + b.String() + "\n"; out.String() != want {
+		t.Errorf("got output %q, want %q", out.String(), want)
+	}
+
+	var berr *ulid.BatchError
+	if !errors.As(err, &berr) || !errors.Is(err, ulid.ErrDataSize) {
+		t.Fatalf("got err %v, want a batch error wrapping %v", err, ulid.ErrDataSize)
+	}
+
+	if berr.Index != 2 || berr.Offset != 32 {
+		t.Errorf("got record %d at offset %d, want record 2 at offset 32", berr.Index, berr.Offset)
+	}
+
+	var calls []int64
+	conv := &ulid.Converter{Progress: func(n int64) { calls = append(calls, n) }}
+	if n, err := conv.BinaryToText(bytes.NewReader(nil), &bytes.Buffer{}); n != 0 || err != nil {
+		t.Errorf("got %d records (%v) for empty input", n, err)
+	}
+
+	if !slices.Equal(calls, []int64{0}) {
+		t.Errorf("expected progress to be reported once on completion, got %v", calls)
+	}
+}
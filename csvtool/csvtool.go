@@ -0,0 +1,316 @@
+// Package csvtool rewrites a ULID column of CSV files for bulk data fixes, e.g. to
+// canonicalize the case of the IDs of an export or to map old IDs to new IDs with a
+// lookup table, streaming the records so that files of any size are rewritten in
+// bounded memory.
+//
+// The records are read with encoding/csv, but the output is copied from the bytes
+// of the input rather than encoded again from the records: only the bytes of the
+// rewritten field are replaced, so the quoting of the other fields, the line
+// endings (LF or CRLF), and blank lines are preserved as they were, and a quoted
+// ULID stays quoted.
+package csvtool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"go.rtnl.ai/ulid"
+)
+
+var (
+	// Returned for a negative column.
+	ErrInvalidColumn = errors.New("csvtool: invalid column")
+
+	// Returned, as a *RowError, for a row that has fewer fields than the column.
+	ErrMissingColumn = errors.New("csvtool: row does not have the column")
+)
+
+// DefaultMaxBadRows is the default number of row numbers of bad rows that are kept
+// in the stats of a rewrite.
+const DefaultMaxBadRows = 10000
+
+// RowError describes a bad row: a row without the column, whose field is not a
+// ULID, or whose ULID could not be rewritten by the rewrite function.
+type RowError struct {
+	Row  int   // The number of the record in the input, starting at 1 with the header if any
+	Line int   // The line of the input on which the field starts
+	Err  error // The error of the row, e.g. ErrMissingColumn or ulid.ErrDataSize
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("csvtool: row %d (line %d): %v", e.Row, e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// RewriteStats are the counts of a rewrite.
+type RewriteStats struct {
+	Rows    int   `json:"rows"`     // The number of rows read, excluding the header
+	Changed int   `json:"changed"`  // The number of rows whose field was replaced
+	Skipped int   `json:"skipped"`  // The number of bad rows that were copied unchanged
+	Failed  int   `json:"failed"`   // The number of bad rows that stopped the rewrite
+	BadRows []int `json:"bad_rows"` // The row numbers of the first bad rows, skipped or failed
+}
+
+// Option configures RewriteColumn.
+type Option func(*rewriter)
+
+// Header copies the first record of the input to the output as is, without
+// parsing its field or counting it as a row.
+func Header() Option {
+	return func(rw *rewriter) {
+		rw.header = true
+	}
+}
+
+// Lenient parses the fields with ulid.ParseClean rather than ulid.ParseStrict, so
+// that byte order marks, zero-width characters, and surrounding whitespace, e.g.
+// of IDs pasted into a spreadsheet, are removed. The cleaned ULIDs are written in
+// canonical form.
+func Lenient() Option {
+	return func(rw *rewriter) {
+		rw.lenient = true
+	}
+}
+
+// SkipBadRows copies bad rows to the output unchanged and continues with the next
+// row, rather than stopping the rewrite with a *RowError at the first bad row.
+func SkipBadRows() Option {
+	return func(rw *rewriter) {
+		rw.skip = true
+	}
+}
+
+// Comma sets the field delimiter (default ','), e.g. '\t' for TSV files.
+func Comma(r rune) Option {
+	return func(rw *rewriter) {
+		rw.comma = r
+	}
+}
+
+// MaxBadRows sets the number of row numbers of bad rows that are kept in the stats
+// (default DefaultMaxBadRows); a negative number keeps none. The bad rows are
+// counted regardless.
+func MaxBadRows(n int) Option {
+	return func(rw *rewriter) {
+		rw.maxBadRows = max(n, 0)
+	}
+}
+
+// OnBadRow sets a hook that is called with the error of each bad row, skipped or
+// failed, e.g. to log the bad rows of a rewrite.
+func OnBadRow(hook func(*RowError)) Option {
+	return func(rw *rewriter) {
+		rw.onBadRow = hook
+	}
+}
+
+type rewriter struct {
+	comma      rune
+	header     bool
+	lenient    bool
+	skip       bool
+	maxBadRows int
+	onBadRow   func(*RowError)
+}
+
+// RewriteColumn copies the CSV records of r to w, replacing the ULID of the field
+// at the column, from 0, of each row with the ULID returned by fn for it, in the
+// canonical form. The fields are parsed strictly unless the Lenient option is
+// used. The rows without the column, whose field is not a ULID, or for which fn
+// returns an error are bad rows: by default the rewrite stops at the first bad row
+// and returns a *RowError, otherwise the bad rows are copied unchanged with
+// SkipBadRows. Records that are not valid CSV, e.g. with an unterminated quoted
+// field, stop the rewrite with the error of encoding/csv.
+//
+// The output is written as each record is read, so if an error is returned the
+// rows before it have already been written. The stats count the rows up to the
+// error.
+func RewriteColumn(r io.Reader, w io.Writer, col int, fn func(ulid.ULID) (ulid.ULID, error), opts ...Option) (stats RewriteStats, err error) {
+	rw := &rewriter{comma: ',', maxBadRows: DefaultMaxBadRows}
+	for _, opt := range opts {
+		opt(rw)
+	}
+
+	stats.BadRows = []int{}
+	if col < 0 {
+		return stats, ErrInvalidColumn
+	}
+
+	src := &recorder{r: r}
+	cr := csv.NewReader(src)
+	cr.Comma = rw.comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	out := bufio.NewWriter(w)
+	defer func() {
+		if ferr := out.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+
+	var (
+		sep  = utf8.AppendRune(nil, rw.comma)
+		text = make([]byte, ulid.EncodedSize)
+	)
+
+	for row := 1; ; row++ {
+		var record []string
+		if record, err = cr.Read(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return stats, err
+		}
+
+		raw := src.next(cr.InputOffset())
+		if row == 1 && rw.header {
+			if _, err = out.Write(raw); err != nil {
+				return stats, err
+			}
+			continue
+		}
+
+		stats.Rows++
+		id, rerr := rw.rewrite(record, col, fn)
+		if rerr != nil {
+			line, _ := cr.FieldPos(min(col, len(record)-1))
+			bad := &RowError{Row: row, Line: line, Err: rerr}
+			if len(stats.BadRows) < rw.maxBadRows {
+				stats.BadRows = append(stats.BadRows, row)
+			}
+
+			if rw.onBadRow != nil {
+				rw.onBadRow(bad)
+			}
+
+			if !rw.skip {
+				stats.Failed++
+				return stats, bad
+			}
+
+			stats.Skipped++
+			if _, err = out.Write(raw); err != nil {
+				return stats, err
+			}
+			continue
+		}
+
+		id.MarshalTextTo(text)
+		start, end := fieldSpan(raw, sep, col)
+		if bytes.Equal(raw[start:end], text) {
+			_, err = out.Write(raw)
+		} else {
+			stats.Changed++
+			out.Write(raw[:start])
+			out.Write(text)
+			_, err = out.Write(raw[end:])
+		}
+
+		if err != nil {
+			return stats, err
+		}
+	}
+}
+
+// rewrite parses the field of the record at the column and returns the ULID that
+// replaces it.
+func (rw *rewriter) rewrite(record []string, col int, fn func(ulid.ULID) (ulid.ULID, error)) (id ulid.ULID, err error) {
+	if col >= len(record) {
+		return ulid.Zero, ErrMissingColumn
+	}
+
+	if rw.lenient {
+		id, err = ulid.ParseClean(record[col])
+	} else {
+		id, err = ulid.ParseStrict(record[col])
+	}
+
+	if err != nil {
+		return ulid.Zero, err
+	}
+	return fn(id)
+}
+
+// fieldSpan returns the span of the contents of the field at the column in the
+// bytes of a record, inside the quotes if the field is quoted, skipping the blank
+// lines that encoding/csv skips before the record. The record must have the
+// column and must have been read without LazyQuotes, so that quotes only start
+// and end quoted fields or are doubled inside them.
+func fieldSpan(raw, sep []byte, col int) (start, end int) {
+	for {
+		if bytes.HasPrefix(raw[start:], []byte("\n")) {
+			start++
+		} else if bytes.HasPrefix(raw[start:], []byte("\r\n")) {
+			start += 2
+		} else {
+			break
+		}
+	}
+
+	field, quoted := 0, false
+	for end = start; end < len(raw); end++ {
+		c := raw[end]
+		if c == '"' {
+			quoted = !quoted
+			continue
+		}
+
+		if quoted {
+			continue
+		}
+
+		if c == '\n' || bytes.HasPrefix(raw[end:], sep) {
+			if field == col {
+				break
+			}
+			field++
+			end += len(sep) - 1
+			start = end + 1
+		}
+	}
+
+	// The line ending of the last field is LF or CRLF, or a CR at the end of the
+	// input.
+	if end > start && raw[end-1] == '\r' && (end == len(raw) || raw[end] == '\n') {
+		end--
+	}
+
+	if end-start >= 2 && raw[start] == '"' {
+		start, end = start+1, end-1
+	}
+	return start, end
+}
+
+// recorder keeps the bytes of the input that a csv.Reader has read ahead, so that
+// the bytes of each record can be copied to the output.
+type recorder struct {
+	r   io.Reader
+	buf []byte
+	off int64 // The offset in the input of buf[0]
+}
+
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// next returns the bytes of the input from the end of the previous record to the
+// offset and drops them from the buffer. The returned bytes are only valid until
+// the next read.
+func (r *recorder) next(offset int64) []byte {
+	n := int(offset - r.off)
+	raw := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	r.off = offset
+	return raw
+}
@@ -0,0 +1,320 @@
+package csvtool_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/csvtool"
+)
+
+const (
+	lower = "01jkehnqpa0end3nhmfkb2y6se"
+	upper = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	other = "01JKEHMRSH3HXYCYYZ1HZR2JBS"
+)
+
+func identity(id ulid.ULID) (ulid.ULID, error) {
+	return id, nil
+}
+
+func rewrite(t *testing.T, input string, col int, fn func(ulid.ULID) (ulid.ULID, error), opts ...csvtool.Option) (string, csvtool.RewriteStats, error) {
+	t.Helper()
+	var out bytes.Buffer
+	stats, err := csvtool.RewriteColumn(strings.NewReader(input), &out, col, fn, opts...)
+	return out.String(), stats, err
+}
+
+func TestRewriteColumn(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		input    string
+		col      int
+		opts     []csvtool.Option
+		expected string
+		changed  int
+	}{
+		{
+			"Simple",
+			"1," + lower + ",a\n2," + upper + ",b\n",
+			1,
+			nil,
+			"1," + upper + ",a\n2," + upper + ",b\n",
+			1,
+		},
+		{
+			"QuotedCommas",
+			`"1,000",` + lower + `,"hello, ""world"""` + "\n" + `"a,b","` + lower + `",` + "\n",
+			1,
+			nil,
+			`"1,000",` + upper + `,"hello, ""world"""` + "\n" + `"a,b","` + upper + `",` + "\n",
+			2,
+		},
+		{
+			"QuotedNewlines",
+			"\"line 1\nline 2\"," + lower + "\n\"x\ny\"," + lower + "\n",
+			1,
+			nil,
+			"\"line 1\nline 2\"," + upper + "\n\"x\ny\"," + upper + "\n",
+			2,
+		},
+		{
+			"CRLF",
+			"id,name\r\n" + lower + ",a\r\n\r\n" + upper + ",\"b\r\nc\"\r\n" + lower + "\r\n",
+			0,
+			[]csvtool.Option{csvtool.Header()},
+			"id,name\r\n" + upper + ",a\r\n\r\n" + upper + ",\"b\r\nc\"\r\n" + upper + "\r\n",
+			2,
+		},
+		{
+			"LastColumnCRLF",
+			"a," + lower + "\r\nb,\"" + lower + "\"\r\nc," + lower,
+			1,
+			nil,
+			"a," + upper + "\r\nb,\"" + upper + "\"\r\nc," + upper,
+			3,
+		},
+		{
+			"TrailingCR",
+			"a," + lower + "\r",
+			1,
+			nil,
+			"a," + upper + "\r",
+			1,
+		},
+		{
+			"Unchanged",
+			"\n" + upper + ",\"x\"\n\n" + upper + "\n",
+			0,
+			nil,
+			"\n" + upper + ",\"x\"\n\n" + upper + "\n",
+			0,
+		},
+	}
+
+	for _, tc := range testCases {
+		out, stats, err := rewrite(t, tc.input, tc.col, identity, tc.opts...)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("%s: got %q, want %q", tc.name, out, tc.expected)
+		}
+
+		if stats.Changed != tc.changed || stats.Skipped != 0 || stats.Failed != 0 || len(stats.BadRows) != 0 {
+			t.Errorf("%s: got stats %+v, want %d changed", tc.name, stats, tc.changed)
+		}
+	}
+}
+
+func TestRewriteColumnMap(t *testing.T) {
+	t.Parallel()
+
+	// Old IDs are mapped to new IDs in a tab separated file with a header.
+	mapping := map[ulid.ULID]ulid.ULID{ulid.MustParse(upper): ulid.MustParse(other)}
+	lookup := func(id ulid.ULID) (ulid.ULID, error) {
+		if to, ok := mapping[id]; ok {
+			return to, nil
+		}
+		return id, nil
+	}
+
+	input := "name\tid\n\"a\tb\"\t" + lower + "\nc\t" + other + "\n"
+	out, stats, err := rewrite(t, input, 1, lookup, csvtool.Comma('\t'), csvtool.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "name\tid\n\"a\tb\"\t" + other + "\nc\t" + other + "\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	if stats.Rows != 2 || stats.Changed != 1 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestRewriteColumnBadRows(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("not found")
+	lookup := func(id ulid.ULID) (ulid.ULID, error) {
+		if id.String() == other {
+			return ulid.Zero, errNotFound
+		}
+		return id, nil
+	}
+
+	// Row 1 is the header, row 3 is missing the column, row 4 is not a ULID, row 5
+	// is not found, and row 6 has invisible characters.
+	input := "n,id\n1," + lower + "\n2\n3,\"not-a-ulid\"\n4," + other + "\n5,\"\uFEFF" + lower + " \"\n6," + lower + "\n"
+	want := []int{3, 4, 5, 6}
+
+	var logged []int
+	out, stats, err := rewrite(t, input, 1, lookup, csvtool.Header(), csvtool.SkipBadRows(), csvtool.OnBadRow(func(err *csvtool.RowError) {
+		logged = append(logged, err.Row)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bad rows are copied unchanged.
+	expected := "n,id\n1," + upper + "\n2\n3,\"not-a-ulid\"\n4," + other + "\n5,\"\uFEFF" + lower + " \"\n6," + upper + "\n"
+	if out != expected {
+		t.Errorf("got %q, want %q", out, expected)
+	}
+
+	if stats.Rows != 6 || stats.Changed != 2 || stats.Skipped != 4 || stats.Failed != 0 || !slices.Equal(stats.BadRows, want) || !slices.Equal(logged, want) {
+		t.Errorf("got stats %+v and logged %v", stats, logged)
+	}
+
+	// Leniently, the invisible characters are removed and the row is rewritten.
+	out, stats, err = rewrite(t, input, 1, lookup, csvtool.Header(), csvtool.SkipBadRows(), csvtool.Lenient(), csvtool.MaxBadRows(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out, "\n5,\""+upper+"\"\n") || stats.Changed != 3 || stats.Skipped != 3 || !slices.Equal(stats.BadRows, want[:2]) {
+		t.Errorf("got %q with stats %+v", out, stats)
+	}
+
+	// By default the rewrite stops at the first bad row, after writing the rows
+	// before it.
+	testCases := []struct {
+		input string
+		row   int
+		line  int
+		err   error
+	}{
+		{input, 3, 3, csvtool.ErrMissingColumn},
+		{"n,id\n1,\"a\nb\",\"" + lower[1:] + "\"\n", 2, 2, ulid.ErrDataSize},
+		{"n,id\n1," + upper + "\n\n2," + other + "\n", 3, 4, errNotFound},
+		{"n,id\n1,0" + upper[1:25] + "!\n", 2, 2, ulid.ErrInvalidCharacters},
+	}
+
+	for _, tc := range testCases {
+		out, stats, err := rewrite(t, tc.input, 1, lookup, csvtool.Header())
+		var rerr *csvtool.RowError
+		if !errors.As(err, &rerr) || rerr.Row != tc.row || rerr.Line != tc.line || !errors.Is(err, tc.err) {
+			t.Errorf("%q: got error %v, want row %d on line %d: %v", tc.input, err, tc.row, tc.line, tc.err)
+			continue
+		}
+
+		if stats.Rows != tc.row-1 || stats.Failed != 1 || stats.Skipped != 0 || !slices.Equal(stats.BadRows, []int{tc.row}) {
+			t.Errorf("%q: got stats %+v", tc.input, stats)
+		}
+
+		if !strings.HasPrefix(out, "n,id\n") {
+			t.Errorf("%q: expected the header to be written, got %q", tc.input, out)
+		}
+	}
+
+	_, _, err = rewrite(t, input, 1, lookup, csvtool.Header())
+	if want := "csvtool: row 3 (line 3): csvtool: row does not have the column"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
+
+func TestRewriteColumnErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := rewrite(t, lower+"\n", -1, identity); !errors.Is(err, csvtool.ErrInvalidColumn) {
+		t.Errorf("got %v, want %v", err, csvtool.ErrInvalidColumn)
+	}
+
+	// Invalid CSV stops the rewrite even when skipping bad rows.
+	out, stats, err := rewrite(t, lower+"\n"+lower+",a\"b\n", 0, identity, csvtool.SkipBadRows())
+	if err == nil || !strings.Contains(err.Error(), "bare \"") {
+		t.Errorf("got %v, want a csv parse error", err)
+	}
+
+	if out != upper+"\n" || stats.Rows != 1 {
+		t.Errorf("got %q with stats %+v", out, stats)
+	}
+
+	// Write errors are returned.
+	errWrite := errors.New("disk full")
+	if _, err := csvtool.RewriteColumn(strings.NewReader(lower+"\n"), failingWriter{errWrite}, 0, identity); err != errWrite {
+		t.Errorf("got %v, want %v", err, errWrite)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+// generatedCSV generates rows of CSV with lowercase ULIDs without holding them in
+// memory and counts the bytes that have been read.
+type generatedCSV struct {
+	rows int
+	row  int
+	buf  []byte
+	read int
+}
+
+func (g *generatedCSV) Read(p []byte) (n int, err error) {
+	for len(g.buf) == 0 {
+		if g.row == g.rows {
+			return 0, io.EOF
+		}
+		g.row++
+		g.buf = fmt.Appendf(g.buf[:0], "%d,\"%d, %d\",%s\r\n", g.row, g.row, g.row, strings.ToLower(ulid.Make().String()))
+	}
+
+	n = copy(p, g.buf)
+	g.buf = g.buf[n:]
+	g.read += n
+	return n, nil
+}
+
+// laggingWriter checks that the output keeps up with the input, which bounds the
+// memory of the rewrite.
+type laggingWriter struct {
+	t       *testing.T
+	input   *generatedCSV
+	written int
+	maxLag  int
+}
+
+func (w *laggingWriter) Write(p []byte) (int, error) {
+	if bytes.ContainsAny(p, "abcdefghjkmnpqrstvwxyz") {
+		w.t.Fatalf("expected the ULIDs to be canonicalized, got %q", p)
+	}
+	w.written += len(p)
+	w.maxLag = max(w.maxLag, w.input.read-w.written)
+	return len(p), nil
+}
+
+func TestRewriteColumnStreaming(t *testing.T) {
+	t.Parallel()
+
+	// About 6MB of CSV is rewritten while the output lags behind the input by no
+	// more than the buffers of the reader and writer.
+	input := &generatedCSV{rows: 100000}
+	output := &laggingWriter{t: t, input: input}
+	stats, err := csvtool.RewriteColumn(input, output, 2, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Rows != input.rows || stats.Changed != input.rows || output.written != input.read {
+		t.Errorf("got stats %+v with %d bytes written of %d", stats, output.written, input.read)
+	}
+
+	if output.maxLag > 16<<10 {
+		t.Errorf("expected the output to lag the input by at most 16KiB, got %d bytes", output.maxLag)
+	}
+}
@@ -0,0 +1,227 @@
+package ulid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// CursorVersion is the version of the pagination cursors encoded by EncodeCursor.
+const CursorVersion = 1
+
+// A cursor is the version byte, a flags byte, the 16 bytes of the ULID, the expiry
+// in Unix milliseconds as a uvarint if it has one, the number of extras as a
+// uvarint with the uvarint length prefixed key and value of each extra in key
+// order, and the first 16 bytes of the HMAC-SHA256 of the preceding bytes if it
+// is authenticated, encoded as unpadded base64url.
+const (
+	cursorFlagMAC    = 1 << 0
+	cursorFlagExpiry = 1 << 1
+	cursorHeaderSize = 2 + 16
+	cursorMACSize    = 16
+)
+
+var cursorEncoding = base64.RawURLEncoding.Strict()
+
+// CursorOption configures the pagination cursor returned by EncodeCursor and
+// NextPage.
+type CursorOption func(*cursorConfig)
+
+type cursorConfig struct {
+	key     []byte
+	expires time.Time
+}
+
+// CursorKey authenticates the cursor with an HMAC-SHA256 using the key, so that
+// DecodeCursor with the same key detects a cursor that was modified or forged by
+// a client. Without a key, or with an empty key, the cursor is not authenticated:
+// a client can decode it and change the ULID, the extras, and the expiry at will.
+func CursorKey(key []byte) CursorOption {
+	return func(c *cursorConfig) {
+		c.key = key
+	}
+}
+
+// CursorExpires embeds the expiry time in the cursor, after which DecodeCursor
+// returns ErrCursorExpired. The expiry is stored with millisecond precision and
+// should be combined with CursorKey, since otherwise a client can remove it.
+func CursorExpires(t time.Time) CursorOption {
+	return func(c *cursorConfig) {
+		c.expires = t
+	}
+}
+
+// EncodeCursor returns a compact, URL-safe pagination cursor of the last ULID of
+// a page and the opaque extra state of the query, e.g. the sort order or filters,
+// which are returned by DecodeCursor. The ULID is stored in binary, so a cursor
+// without extras is 26 characters, or 47 characters with CursorKey. The format is
+// versioned by CursorVersion.
+//
+// The cursor is only tamper-evident with CursorKey; without a key it is merely
+// encoded, not encrypted or authenticated, and the extras are always readable by
+// the client, so they must not contain secrets.
+func EncodeCursor(id ULID, extra map[string]string, opts ...CursorOption) string {
+	var conf cursorConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	buf := make([]byte, 2, 64)
+	buf[0] = CursorVersion
+	buf = append(buf, id[:]...)
+
+	if !conf.expires.IsZero() {
+		buf[1] |= cursorFlagExpiry
+		buf = binary.AppendUvarint(buf, uint64(max(conf.expires.UnixMilli(), 0)))
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	buf = binary.AppendUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = binary.AppendUvarint(buf, uint64(len(k)))
+		buf = append(buf, k...)
+		buf = binary.AppendUvarint(buf, uint64(len(extra[k])))
+		buf = append(buf, extra[k]...)
+	}
+
+	if len(conf.key) > 0 {
+		buf[1] |= cursorFlagMAC
+		buf = append(buf, cursorMAC(conf.key, buf)...)
+	}
+	return cursorEncoding.EncodeToString(buf)
+}
+
+// DecodeCursor decodes a pagination cursor returned by EncodeCursor, returning the
+// ULID and the extras, which are nil if the cursor has none. The key must be the
+// key of CursorKey, or nil for unauthenticated cursors.
+//
+// ErrCursorMalformed is returned if the token is not a cursor or has an unknown
+// version, ErrCursorTampered if it does not match the key, including if the
+// cursor is unauthenticated but a key is given (a client may have removed the
+// HMAC) or authenticated but no key is given, and ErrCursorExpired if it has an
+// expiry that has passed. The contents of a cursor are only parsed after it has
+// been authenticated.
+func DecodeCursor(token string, key []byte) (id ULID, extra map[string]string, err error) {
+	var buf []byte
+	if buf, err = cursorEncoding.DecodeString(token); err != nil || len(buf) < cursorHeaderSize {
+		return Zero, nil, ErrCursorMalformed
+	}
+
+	if buf[0] != CursorVersion {
+		return Zero, nil, fmt.Errorf("%w: unknown version %d", ErrCursorMalformed, buf[0])
+	}
+
+	flags := buf[1]
+	if flags&^(cursorFlagMAC|cursorFlagExpiry) != 0 {
+		return Zero, nil, ErrCursorMalformed
+	}
+
+	if authenticated := flags&cursorFlagMAC != 0; authenticated != (len(key) > 0) {
+		return Zero, nil, ErrCursorTampered
+	} else if authenticated {
+		if len(buf) < cursorHeaderSize+cursorMACSize {
+			return Zero, nil, ErrCursorMalformed
+		}
+
+		mac := buf[len(buf)-cursorMACSize:]
+		if buf = buf[:len(buf)-cursorMACSize]; !hmac.Equal(mac, cursorMAC(key, buf)) {
+			return Zero, nil, ErrCursorTampered
+		}
+	}
+
+	copy(id[:], buf[2:cursorHeaderSize])
+	data := buf[cursorHeaderSize:]
+
+	// uvarint reads the next uvarint from the data, returning false if it is
+	// truncated or larger than the remaining data for a length.
+	uvarint := func() (uint64, bool) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, false
+		}
+		data = data[n:]
+		return v, true
+	}
+
+	str := func() (string, bool) {
+		n, ok := uvarint()
+		if !ok || n > uint64(len(data)) {
+			return "", false
+		}
+
+		s := string(data[:n])
+		data = data[n:]
+		return s, true
+	}
+
+	var expires uint64
+	if flags&cursorFlagExpiry != 0 {
+		var ok bool
+		if expires, ok = uvarint(); !ok || expires > math.MaxInt64 {
+			return Zero, nil, ErrCursorMalformed
+		}
+	}
+
+	count, ok := uvarint()
+	if !ok || count > uint64(len(data)) {
+		return Zero, nil, ErrCursorMalformed
+	}
+
+	if count > 0 {
+		extra = make(map[string]string, count)
+	}
+
+	for i := uint64(0); i < count; i++ {
+		k, ok := str()
+		if !ok {
+			return Zero, nil, ErrCursorMalformed
+		}
+
+		if extra[k], ok = str(); !ok {
+			return Zero, nil, ErrCursorMalformed
+		}
+	}
+
+	if len(data) != 0 || uint64(len(extra)) != count {
+		return Zero, nil, ErrCursorMalformed
+	}
+
+	if flags&cursorFlagExpiry != 0 && time.Now().UnixMilli() >= int64(expires) {
+		return Zero, nil, ErrCursorExpired
+	}
+	return id, extra, nil
+}
+
+// NextPage returns the first limit ULIDs as the page and whether there are more
+// ULIDs after it, in which case the cursor is the cursor of the last ULID of the
+// page, encoded with the options and no extras; otherwise the cursor is empty.
+// Query one more ULID than the limit, e.g. with LIMIT limit+1, so that NextPage
+// can tell whether there is another page. A limit less than 1 returns no page.
+func NextPage(ids []ULID, limit int, opts ...CursorOption) (page []ULID, cursor string, hasMore bool) {
+	if limit < 1 {
+		return nil, "", false
+	}
+
+	if len(ids) <= limit {
+		return ids, "", false
+	}
+
+	page = ids[:limit]
+	return page, EncodeCursor(page[limit-1], nil, opts...), true
+}
+
+func cursorMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)[:cursorMACSize]
+}
@@ -0,0 +1,203 @@
+package ulid_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"maps"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestCursor(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.Make()
+	key := []byte("secret")
+	for _, extra := range []map[string]string{
+		nil,
+		{"sort": "-created"},
+		{"sort": "created", "filter": "status=open&owner=me", "": "empty key", "unicode": "ünïcödé"},
+	} {
+		for _, opts := range [][]ulid.CursorOption{
+			nil,
+			{ulid.CursorKey(key)},
+			{ulid.CursorKey(key), ulid.CursorExpires(time.Now().Add(time.Hour))},
+		} {
+			token := ulid.EncodeCursor(id, extra, opts...)
+			if url.QueryEscape(token) != token {
+				t.Errorf("expected a URL-safe token, got %q", token)
+			}
+
+			var decodeKey []byte
+			if len(opts) > 0 {
+				decodeKey = key
+			}
+
+			got, gotExtra, err := ulid.DecodeCursor(token, decodeKey)
+			if err != nil {
+				t.Fatalf("could not decode %q: %v", token, err)
+			}
+
+			if got != id || !maps.Equal(gotExtra, extra) || (extra == nil) != (gotExtra == nil) {
+				t.Errorf("%q: got %s %v, want %s %v", token, got, gotExtra, id, extra)
+			}
+		}
+	}
+
+	// The ULID is stored in binary.
+	if token := ulid.EncodeCursor(id, nil); len(token) != 26 {
+		t.Errorf("expected a 26 character cursor, got %q", token)
+	}
+
+	if token := ulid.EncodeCursor(id, nil, ulid.CursorKey(key)); len(token) != 47 {
+		t.Errorf("expected a 47 character authenticated cursor, got %q", token)
+	}
+
+	// Extras are encoded in key order.
+	a := ulid.EncodeCursor(id, map[string]string{"a": "1", "b": "2", "c": "3"}, ulid.CursorKey(key))
+	for i := 0; i < 10; i++ {
+		if b := ulid.EncodeCursor(id, map[string]string{"c": "3", "b": "2", "a": "1"}, ulid.CursorKey(key)); a != b {
+			t.Fatalf("expected deterministic cursors, got %q and %q", a, b)
+		}
+	}
+}
+
+func TestCursorErrors(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.Make()
+	key := []byte("secret")
+	token := ulid.EncodeCursor(id, map[string]string{"sort": "-created"}, ulid.CursorKey(key))
+	data, _ := base64.RawURLEncoding.DecodeString(token)
+
+	// tamper returns the token with a bit flipped in byte i of the decoded cursor.
+	tamper := func(i int) string {
+		b := append([]byte(nil), data...)
+		b[i] ^= 0x01
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+		key   []byte
+		err   error
+	}{
+		{"WrongKey", token, []byte("other"), ulid.ErrCursorTampered},
+		{"NoKey", token, nil, ulid.ErrCursorTampered},
+		{"ModifiedULID", tamper(10), key, ulid.ErrCursorTampered},
+		{"ModifiedExtra", tamper(len(data) - 20), key, ulid.ErrCursorTampered},
+		{"ModifiedMAC", tamper(len(data) - 1), key, ulid.ErrCursorTampered},
+		{"RemovedMAC", ulid.EncodeCursor(id, map[string]string{"sort": "-created"}), key, ulid.ErrCursorTampered},
+		{"Truncated", base64.RawURLEncoding.EncodeToString(data[:len(data)-3]), key, ulid.ErrCursorTampered},
+		{"Empty", "", nil, ulid.ErrCursorMalformed},
+		{"Short", token[:20], key, ulid.ErrCursorMalformed},
+		{"NotBase64", "not a cursor!", nil, ulid.ErrCursorMalformed},
+		{"Padded", ulid.EncodeCursor(id, nil) + "==", nil, ulid.ErrCursorMalformed},
+		{"Flags", tamper(1), key, ulid.ErrCursorTampered},
+		{"Version", tamper(0), key, ulid.ErrCursorMalformed},
+		{"Expired", ulid.EncodeCursor(id, nil, ulid.CursorKey(key), ulid.CursorExpires(time.Now().Add(-time.Second))), key, ulid.ErrCursorExpired},
+		{"ExpiredUnauthenticated", ulid.EncodeCursor(id, nil, ulid.CursorExpires(time.Unix(0, 0))), nil, ulid.ErrCursorExpired},
+	}
+
+	for _, tc := range testCases {
+		if got, extra, err := ulid.DecodeCursor(tc.token, tc.key); !errors.Is(err, tc.err) || !got.IsZero() || extra != nil {
+			t.Errorf("%s: got %s %v (%v), want %v", tc.name, got, extra, err, tc.err)
+		}
+	}
+
+	// Unauthenticated cursors are parsed strictly.
+	unauthenticated := []byte{ulid.CursorVersion, 0}
+	unauthenticated = append(unauthenticated, id[:]...)
+	for name, suffix := range map[string][]byte{
+		"MissingCount":    {},
+		"MissingExtra":    {1},
+		"TruncatedKey":    {1, 4, 'a'},
+		"MissingValue":    {1, 1, 'a'},
+		"DuplicateKey":    {2, 1, 'a', 0, 1, 'a', 0},
+		"TrailingBytes":   {0, 0},
+		"CountOverflow":   {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"UnknownFlags":    nil,
+		"ExpiryTruncated": nil,
+	} {
+		b := append(append([]byte(nil), unauthenticated...), suffix...)
+		switch name {
+		case "UnknownFlags":
+			b[1] = 0x80
+			b = append(b, 0)
+		case "ExpiryTruncated":
+			b[1] = 0x02
+			b = append(b, 0xff)
+		}
+
+		if _, _, err := ulid.DecodeCursor(base64.RawURLEncoding.EncodeToString(b), nil); !errors.Is(err, ulid.ErrCursorMalformed) {
+			t.Errorf("%s: got %v, want %v", name, err, ulid.ErrCursorMalformed)
+		}
+	}
+}
+
+func TestCursorFixtures(t *testing.T) {
+	t.Parallel()
+
+	// Cursors encoded by version 1 must always decode to the same ULID and extras.
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	testCases := []struct {
+		token string
+		key   []byte
+		extra map[string]string
+	}{
+		{"AQABVj46tdPWdkxh77mTAr1bAA", nil, nil},
+		{"AQEBVj46tdPWdkxh77mTAr1bAFD3bOYqMa7Nx9NrOv5ceqA", []byte("k"), nil},
+		{
+			"AQMBVj46tdPWdkxh77mTAr1bgLCP5rJ3AgFxBG9wZW4Ec29ydAgtY3JlYXRlZJIZAHLYXWwSt9A3GaCwRhQ",
+			[]byte("cursor-fixture-key"),
+			map[string]string{"sort": "-created", "q": "open"},
+		},
+	}
+
+	for _, tc := range testCases {
+		got, extra, err := ulid.DecodeCursor(tc.token, tc.key)
+		if err != nil || got != id || !maps.Equal(extra, tc.extra) {
+			t.Errorf("%q: got %s %v (%v), want %s %v", tc.token, got, extra, err, id, tc.extra)
+		}
+	}
+
+	// The last fixture expires at the start of 2100.
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	token := ulid.EncodeCursor(id, map[string]string{"sort": "-created", "q": "open"}, ulid.CursorKey([]byte("cursor-fixture-key")), ulid.CursorExpires(expires))
+	if token != testCases[2].token {
+		t.Errorf("expected the version 1 encoding to be stable, got %q", token)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]ulid.ULID, 11)
+	for i := range ids {
+		ids[i] = ulid.Make()
+	}
+
+	key := []byte("secret")
+	page, cursor, hasMore := ulid.NextPage(ids, 10, ulid.CursorKey(key))
+	if len(page) != 10 || !hasMore || cursor == "" {
+		t.Fatalf("expected a page of 10 with more, got %d %t %q", len(page), hasMore, cursor)
+	}
+
+	if last, extra, err := ulid.DecodeCursor(cursor, key); err != nil || last != ids[9] || extra != nil {
+		t.Errorf("expected the cursor of the last ULID of the page, got %s %v (%v)", last, extra, err)
+	}
+
+	for _, n := range []int{10, 5, 0} {
+		if page, cursor, hasMore := ulid.NextPage(ids[:n], 10); len(page) != n || hasMore || cursor != "" {
+			t.Errorf("%d: expected the last page, got %d %t %q", n, len(page), hasMore, cursor)
+		}
+	}
+
+	if page, cursor, hasMore := ulid.NextPage(ids, 0); page != nil || hasMore || cursor != "" {
+		t.Errorf("expected no page for a limit of 0, got %d %t %q", len(page), hasMore, cursor)
+	}
+}
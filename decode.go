@@ -0,0 +1,80 @@
+package ulid
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Lengths of the encodings of a ULID accepted by DecodeAny.
+const (
+	hexEncodedSize  = 32
+	uuidEncodedSize = 36
+)
+
+// DecodeAny decodes a ULID from any of its encodings, dispatching on the length
+// of data since the lengths of the encodings are distinct:
+//
+//	16 bytes: the binary encoding
+//	22 bytes: the unpadded base64url encoding returned by EncodeQR
+//	26 bytes: the base32 text encoding, parsed strictly
+//	32 bytes: the hex encoding of the 16 bytes in either case
+//	36 bytes: the hex encoding of the 16 bytes in the hyphenated UUID format
+//
+// It is intended for consumers during the migration of producers from one wire
+// format to another; use the strict decoding method of the format once the
+// migration is complete. An error wrapping ErrDataSize that lists the expected
+// lengths is returned for data of any other length, and ErrInvalidCharacters is
+// returned if data is not a valid encoding of its length.
+func DecodeAny(data []byte) (id ULID, err error) {
+	err = id.UnmarshalAny(data)
+	return id, err
+}
+
+// UnmarshalAny decodes the ULID from any of its encodings like DecodeAny, leaving
+// the ULID unmodified on error. UnmarshalBinary and UnmarshalText are unchanged
+// and continue to accept only their own encoding.
+func (id *ULID) UnmarshalAny(data []byte) (err error) {
+	var u ULID
+	switch len(data) {
+	case len(u):
+		copy(u[:], data)
+	case QREncodedSize:
+		u, err = ParseQR(string(data))
+	case EncodedSize:
+		err = parse(data, true, &u)
+	case hexEncodedSize:
+		err = decodeHex(&u, data)
+	case uuidEncodedSize:
+		err = decodeUUID(&u, data)
+	default:
+		return fmt.Errorf("%w: got %d bytes, expected 16 (binary), 22 (base64url), 26 (text), 32 (hex), or 36 (uuid)", ErrDataSize, len(data))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	*id = u
+	return nil
+}
+
+func decodeHex(id *ULID, data []byte) error {
+	if n, err := hex.Decode(id[:], data); err != nil || n != len(id) {
+		return ErrInvalidCharacters
+	}
+	return nil
+}
+
+func decodeUUID(id *ULID, data []byte) error {
+	if data[8] != '-' || data[13] != '-' || data[18] != '-' || data[23] != '-' {
+		return ErrInvalidCharacters
+	}
+
+	var buf [hexEncodedSize]byte
+	copy(buf[0:8], data[0:8])
+	copy(buf[8:12], data[9:13])
+	copy(buf[12:16], data[14:18])
+	copy(buf[16:20], data[19:23])
+	copy(buf[20:], data[24:])
+	return decodeHex(id, buf[:])
+}
@@ -0,0 +1,96 @@
+package ulid_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestDecodeAny(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	hexid := hex.EncodeToString(id[:])
+	uuid := hexid[:8] + "-" + hexid[8:12] + "-" + hexid[12:16] + "-" + hexid[16:20] + "-" + hexid[20:]
+
+	valid := map[string][]byte{
+		"binary":    id[:],
+		"base64url": []byte(id.EncodeQR()),
+		"text":      []byte(id.String()),
+		"lowercase": []byte(strings.ToLower(id.String())),
+		"hex":       []byte(hexid),
+		"uppercase": []byte(strings.ToUpper(hexid)),
+		"uuid":      []byte(uuid),
+	}
+
+	for name, data := range valid {
+		got, err := ulid.DecodeAny(data)
+		if err != nil || got != id {
+			t.Errorf("%s: got %s (%v), want %s", name, got, err, id)
+		}
+
+		var u ulid.ULID
+		if err := u.UnmarshalAny(data); err != nil || u != id {
+			t.Errorf("%s: UnmarshalAny got %s (%v), want %s", name, u, err, id)
+		}
+	}
+
+	// Corrupt a single character of each text encoding.
+	corrupt := func(s string, i int, c byte) []byte {
+		b := []byte(s)
+		b[i] = c
+		return b
+	}
+
+	invalid := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"base64url": {corrupt(id.EncodeQR(), 3, '+'), ulid.ErrInvalidCharacters},
+		"base64pad": {[]byte(id.EncodeQR()[:20] + "=="), ulid.ErrDataSize},
+		"base64bit": {corrupt(id.EncodeQR(), 21, 'x'), ulid.ErrInvalidCharacters},
+		"text":      {corrupt(id.String(), 25, 'U'), ulid.ErrInvalidCharacters},
+		"overflow":  {corrupt(id.String(), 0, '8'), ulid.ErrOverflow},
+		"hex":       {corrupt(hexid, 31, 'g'), ulid.ErrInvalidCharacters},
+		"uuid":      {corrupt(uuid, 35, 'g'), ulid.ErrInvalidCharacters},
+		"hyphen":    {corrupt(uuid, 8, '_'), ulid.ErrInvalidCharacters},
+		"hyphens":   {[]byte(hexid + "----"), ulid.ErrInvalidCharacters},
+	}
+
+	for name, tc := range invalid {
+		if _, err := ulid.DecodeAny(tc.data); err != tc.err {
+			t.Errorf("%s: got err %v, want %v", name, err, tc.err)
+		}
+
+		u := id
+		if err := u.UnmarshalAny(tc.data); err != tc.err || u != id {
+			t.Errorf("%s: expected the ULID to be unmodified on error, got %s (%v)", name, u, err)
+		}
+	}
+
+	for _, n := range []int{0, 1, 15, 17, 21, 23, 25, 27, 31, 33, 35, 37, 64} {
+		_, err := ulid.DecodeAny(make([]byte, n))
+		if !errors.Is(err, ulid.ErrDataSize) || !strings.Contains(err.Error(), "16 (binary), 22 (base64url), 26 (text), 32 (hex), or 36 (uuid)") {
+			t.Errorf("length %d: expected ErrDataSize listing the expected lengths, got %v", n, err)
+		}
+	}
+}
+
+func TestDecodeAnyRoundTrips(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 1000; i++ {
+		id := ulid.Make()
+		text, _ := id.MarshalText()
+		binary, _ := id.MarshalBinary()
+
+		for _, data := range [][]byte{text, binary, []byte(id.EncodeQR()), []byte(hex.EncodeToString(id[:]))} {
+			if got, err := ulid.DecodeAny(data); err != nil || got != id {
+				t.Fatalf("got %s (%v), want %s", got, err, id)
+			}
+		}
+	}
+}
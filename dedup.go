@@ -0,0 +1,288 @@
+package ulid
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultDedupSkew is the default skew allowance of a Deduplicator.
+const DefaultDedupSkew = time.Minute
+
+// DefaultDedupBuckets is the default number of buckets a Deduplicator divides its
+// window into when no bucket size is specified.
+const DefaultDedupBuckets = 24
+
+// Deduplicator detects duplicate ULIDs within a trailing time window with bounded
+// memory, e.g. to reject duplicates in a streaming ingestion pipeline. IDs are
+// stored in buckets partitioned by the timestamp embedded in the ULID (not the
+// time the ID arrived) so that whole buckets can be dropped once they age out
+// of the window.
+//
+// The window trails the latest timestamp seen, which is capped at the current
+// time plus the skew allowance so that a single ID from the far future cannot
+// expire the entire window. Buckets are retained for the window plus the skew
+// allowance so that IDs arriving out of order by up to the skew are still
+// detected. IDs with a timestamp older than the retained window cannot be
+// detected as duplicates and are neither reported nor recorded.
+//
+// By default IDs are stored in maps and duplicate detection is exact. With the
+// DedupBloom option each bucket is a Bloom filter instead, which uses a fraction
+// of the memory but may report false positives: a ULID that has not been seen
+// may be reported as a duplicate at the specified false positive rate.
+//
+// A Deduplicator is safe for concurrent use.
+type Deduplicator struct {
+	mu      sync.Mutex
+	window  uint64
+	skew    uint64
+	size    uint64
+	bloom   *bloomParams
+	now     func() time.Time
+	seed    maphash.Seed
+	buckets map[uint64]dedupBucket
+	latest  uint64
+	oldest  uint64
+	count   int
+}
+
+// DedupOption configures a Deduplicator.
+type DedupOption func(*Deduplicator)
+
+// DedupSkew sets the skew allowance of the deduplicator, which is how far out of
+// order IDs may arrive and how far in the future the latest timestamp may be
+// ahead of the current time (default DefaultDedupSkew).
+func DedupSkew(skew time.Duration) DedupOption {
+	return func(d *Deduplicator) {
+		d.skew = durationMillis(skew)
+	}
+}
+
+// DedupBucket sets the size of the time buckets IDs are partitioned into, which
+// is the granularity at which IDs expire (default window / DefaultDedupBuckets).
+func DedupBucket(size time.Duration) DedupOption {
+	return func(d *Deduplicator) {
+		d.size = durationMillis(size)
+	}
+}
+
+// DedupBloom stores IDs in per-bucket Bloom filters sized for the expected number
+// of IDs per bucket at the specified false positive rate instead of in maps. If
+// more IDs than expected are stored in a bucket the false positive rate is
+// higher than specified.
+func DedupBloom(expected int, falsePositiveRate float64) DedupOption {
+	return func(d *Deduplicator) {
+		d.bloom = newBloomParams(expected, falsePositiveRate)
+	}
+}
+
+// DedupClock sets the function used to get the current time (default time.Now).
+func DedupClock(now func() time.Time) DedupOption {
+	return func(d *Deduplicator) {
+		d.now = now
+	}
+}
+
+// NewDeduplicator creates a deduplicator that detects duplicate ULIDs within the
+// trailing window, configured by the options.
+func NewDeduplicator(window time.Duration, opts ...DedupOption) *Deduplicator {
+	d := &Deduplicator{
+		window:  durationMillis(window),
+		skew:    durationMillis(DefaultDedupSkew),
+		now:     time.Now,
+		buckets: make(map[uint64]dedupBucket),
+		seed:    maphash.MakeSeed(),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.size == 0 {
+		d.size = d.window / DefaultDedupBuckets
+	}
+	d.size = max(d.size, 1)
+	return d
+}
+
+// Seen records the ULID and returns true if it has already been seen within the
+// window.
+func (d *Deduplicator) Seen(id ULID) bool {
+	ms := id.Time()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ms > d.latest {
+		d.advance(ms)
+	}
+
+	key := ms / d.size
+	if key < d.oldest {
+		return false
+	}
+
+	bucket, ok := d.buckets[key]
+	if !ok {
+		if d.bloom != nil {
+			bucket = d.bloom.newFilter(d.seed)
+		} else {
+			bucket = make(mapBucket)
+		}
+		d.buckets[key] = bucket
+	}
+
+	if bucket.add(id) {
+		return true
+	}
+
+	d.count++
+	return false
+}
+
+// advance moves the latest timestamp forward, capped at the current time plus
+// the skew, and drops the buckets that are entirely outside the retained window.
+func (d *Deduplicator) advance(ms uint64) {
+	if limit := Timestamp(d.now()) + d.skew; ms > limit {
+		ms = limit
+	}
+
+	if ms <= d.latest {
+		return
+	}
+	d.latest = ms
+
+	var cutoff uint64
+	if retain := d.window + d.skew; ms > retain {
+		cutoff = ms - retain
+	}
+
+	oldest := cutoff / d.size
+	if oldest <= d.oldest {
+		return
+	}
+	d.oldest = oldest
+
+	for key, bucket := range d.buckets {
+		if key < oldest {
+			d.count -= bucket.len()
+			delete(d.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of distinct IDs currently stored by the deduplicator.
+// When using Bloom filters, IDs that were false positives are not counted.
+func (d *Deduplicator) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+// MemoryUsage returns an estimate of the number of bytes used to store IDs.
+func (d *Deduplicator) MemoryUsage() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var size int
+	for _, bucket := range d.buckets {
+		size += bucket.size()
+	}
+	return size
+}
+
+func durationMillis(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64(d / time.Millisecond)
+}
+
+//===========================================================================
+// Deduplicator Buckets
+//===========================================================================
+
+type dedupBucket interface {
+	add(id ULID) (seen bool)
+	len() int
+	size() int
+}
+
+// mapEntrySize is the estimated number of bytes per ULID in a map including the
+// map's control bytes, load factor, and growth overhead.
+const mapEntrySize = 32
+
+type mapBucket map[ULID]struct{}
+
+func (b mapBucket) add(id ULID) bool {
+	if _, ok := b[id]; ok {
+		return true
+	}
+	b[id] = struct{}{}
+	return false
+}
+
+func (b mapBucket) len() int  { return len(b) }
+func (b mapBucket) size() int { return len(b) * mapEntrySize }
+
+type bloomParams struct {
+	bits   uint64
+	hashes uint64
+}
+
+// newBloomParams computes the optimal number of bits and hashes of a Bloom filter
+// for n elements with false positive rate p.
+func newBloomParams(n int, p float64) *bloomParams {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(bits / float64(n) * math.Ln2)
+	return &bloomParams{
+		bits:   max(uint64(bits), 64),
+		hashes: max(uint64(hashes), 1),
+	}
+}
+
+func (p *bloomParams) newFilter(seed maphash.Seed) *bloomFilter {
+	return &bloomFilter{
+		bloomParams: p,
+		seed:        seed,
+		words:       make([]uint64, (p.bits+63)/64),
+	}
+}
+
+type bloomFilter struct {
+	*bloomParams
+	seed  maphash.Seed
+	words []uint64
+	count int
+}
+
+// add sets the bits of the ULID using double hashing, returning true if all of
+// the bits were already set.
+func (f *bloomFilter) add(id ULID) bool {
+	h := maphash.Bytes(f.seed, id[:])
+	h1, h2 := h&math.MaxUint32, h>>32|1
+
+	seen := true
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.bits
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if f.words[word]&mask == 0 {
+			seen = false
+			f.words[word] |= mask
+		}
+	}
+
+	if !seen {
+		f.count++
+	}
+	return seen
+}
+
+func (f *bloomFilter) len() int  { return f.count }
+func (f *bloomFilter) size() int { return len(f.words) * 8 }
@@ -0,0 +1,204 @@
+package ulid_test
+
+import (
+	crand "crypto/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestDeduplicator(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return start.Add(24 * time.Hour) }
+	at := func(d time.Duration) ulid.ULID {
+		return ulid.MustNew(ulid.Timestamp(start.Add(d)), crand.Reader)
+	}
+
+	newDedup := func(opts ...ulid.DedupOption) *ulid.Deduplicator {
+		opts = append([]ulid.DedupOption{ulid.DedupBucket(time.Minute), ulid.DedupSkew(time.Minute), ulid.DedupClock(clock)}, opts...)
+		return ulid.NewDeduplicator(time.Hour, opts...)
+	}
+
+	t.Run("Duplicates", func(t *testing.T) {
+		d := newDedup()
+		a, b := at(0), at(0)
+
+		if d.Seen(a) || d.Seen(b) {
+			t.Fatal("expected new ulids to not be seen")
+		}
+
+		if !d.Seen(a) || !d.Seen(b) {
+			t.Fatal("expected duplicate ulids to be seen")
+		}
+
+		if d.Len() != 2 {
+			t.Errorf("expected 2 ulids stored, got %d", d.Len())
+		}
+
+		if d.MemoryUsage() <= 0 {
+			t.Errorf("expected a positive memory usage estimate")
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		d := newDedup()
+		old := at(0)
+		d.Seen(old)
+
+		// An ID more than the window plus the skew later expires the old bucket.
+		d.Seen(at(time.Hour + 3*time.Minute))
+		if d.Len() != 1 {
+			t.Fatalf("expected the old bucket to be dropped, got %d ulids", d.Len())
+		}
+
+		// IDs older than the retained window are not detected or recorded.
+		if d.Seen(old) || d.Seen(old) {
+			t.Error("expected expired ulid to not be detected")
+		}
+
+		if d.Len() != 1 {
+			t.Errorf("expected expired ulid to not be recorded, got %d ulids", d.Len())
+		}
+	})
+
+	t.Run("Skew", func(t *testing.T) {
+		d := newDedup()
+		early := at(0)
+		d.Seen(early)
+
+		// The latest timestamp is past the window but within the skew allowance,
+		// so an ID arriving out of order is still detected.
+		d.Seen(at(time.Hour + 30*time.Second))
+		if !d.Seen(early) {
+			t.Error("expected out of order duplicate within the skew to be seen")
+		}
+
+		late := at(time.Hour - time.Minute)
+		if d.Seen(late) || !d.Seen(late) {
+			t.Error("expected out of order ulid within the window to be recorded")
+		}
+	})
+
+	t.Run("Future", func(t *testing.T) {
+		d := newDedup()
+		id := at(23 * time.Hour)
+		d.Seen(id)
+
+		// A far future ID cannot advance the window beyond now plus the skew.
+		d.Seen(at(1000 * time.Hour))
+		if !d.Seen(id) {
+			t.Error("expected far future ulid to not expire the window")
+		}
+	})
+
+	t.Run("BucketBoundaries", func(t *testing.T) {
+		d := newDedup()
+		ids := []ulid.ULID{
+			at(time.Minute - time.Millisecond),
+			at(time.Minute),
+			at(2*time.Minute - time.Millisecond),
+			at(2 * time.Minute),
+		}
+
+		for _, id := range ids {
+			if d.Seen(id) {
+				t.Fatalf("expected %s to not be seen", id)
+			}
+		}
+
+		for _, id := range ids {
+			if !d.Seen(id) {
+				t.Fatalf("expected %s to be seen", id)
+			}
+		}
+
+		// Expiring the first bucket only drops the IDs before its boundary.
+		d.Seen(at(time.Hour + 2*time.Minute + time.Millisecond))
+		if d.Len() != 4 {
+			t.Fatalf("expected the first bucket to be dropped, got %d ulids", d.Len())
+		}
+
+		if d.Seen(ids[0]) || !d.Seen(ids[1]) {
+			t.Error("expected only ids before the boundary to expire")
+		}
+	})
+
+	t.Run("Bloom", func(t *testing.T) {
+		const n = 10000
+		exact := newDedup()
+		bloom := newDedup(ulid.DedupBloom(n, 0.01))
+
+		for i := 0; i < n; i++ {
+			id := at(time.Duration(i) * time.Millisecond)
+			exact.Seen(id)
+			if bloom.Seen(id) {
+				// A false positive is possible but not recorded.
+				continue
+			}
+
+			if !bloom.Seen(id) {
+				t.Fatal("expected bloom filter to have no false negatives")
+			}
+		}
+
+		// Probing records the probes, so only probe a fraction of the capacity to
+		// keep the filter close to the expected size.
+		const probes = n / 10
+		var fp int
+		for i := 0; i < probes; i++ {
+			if bloom.Seen(at(time.Duration(i) * time.Millisecond)) {
+				fp++
+			}
+		}
+
+		if rate := float64(fp) / probes; rate > 0.03 {
+			t.Errorf("expected false positive rate near 1%%, got %.2f%%", rate*100)
+		}
+
+		if bloom.MemoryUsage() >= exact.MemoryUsage() {
+			t.Errorf("expected bloom filter to use less memory: %d >= %d", bloom.MemoryUsage(), exact.MemoryUsage())
+		}
+	})
+}
+
+func TestDeduplicatorConcurrency(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]ulid.ULID, 1000)
+	for i := range ids {
+		ids[i] = ulid.MustNew(ulid.Now()-uint64(i), crand.Reader)
+	}
+
+	for name, d := range map[string]*ulid.Deduplicator{
+		"Exact": ulid.NewDeduplicator(time.Hour),
+		"Bloom": ulid.NewDeduplicator(time.Hour, ulid.DedupBloom(len(ids), 1e-9)),
+	} {
+		var (
+			wg    sync.WaitGroup
+			fresh atomic.Int64
+		)
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(offset int) {
+				defer wg.Done()
+				for j := range ids {
+					if !d.Seen(ids[(j+offset*97)%len(ids)]) {
+						fresh.Add(1)
+					}
+					d.Len()
+				}
+			}(i)
+		}
+
+		wg.Wait()
+		if fresh.Load() != int64(len(ids)) || d.Len() != len(ids) {
+			t.Errorf("%s: expected each ulid to be new exactly once, got %d new and %d stored", name, fresh.Load(), d.Len())
+		}
+	}
+}
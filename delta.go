@@ -0,0 +1,341 @@
+package ulid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+)
+
+// deltaVersion is the version of the delta encoding of sorted sets of ULIDs.
+const deltaVersion = 1
+
+// The markers of the delta encoding: the start of the first ULID, and the end of
+// the ULIDs, which is never a delta since the ULIDs are unique.
+const (
+	deltaEnd   = 0x00
+	deltaFirst = 0x01
+)
+
+// maxDeltaLen is the length in bytes of the longest 128 bit uvarint.
+const maxDeltaLen = 19
+
+// errTruncatedDelta is returned when delta encoded data ends before its checksum.
+var errTruncatedDelta = fmt.Errorf("%w: %w", ErrInvalidDelta, io.ErrUnexpectedEOF)
+
+// EncodeDelta encodes a sorted set of ULIDs compactly for transfer, e.g. between
+// services. Sorted ULIDs share long prefixes, especially the ULIDs of the same
+// day or millisecond, so rather than 16 bytes each, the first ULID is encoded as
+// is and each ULID after it as the difference from the previous ULID, a 128 bit
+// uvarint: the ULIDs of SequentialEntropy take 1 byte, and the ULIDs of monotonic
+// entropy in the same millisecond take about 5 bytes with the default increment.
+// ULIDs that are far apart can take up to 19 bytes.
+//
+// The encoding is a version byte, the marker 0x01 and the 16 bytes of the first
+// ULID if there is one, the differences, the marker 0x00, and a CRC-32C
+// (Castagnoli) checksum of the preceding bytes, big-endian. ErrUnsortedSet is
+// returned if the ULIDs are not in strictly increasing order.
+func EncodeDelta(ids []ULID) ([]byte, error) {
+	var (
+		enc deltaEncoder
+		err error
+	)
+
+	data := make([]byte, 0, 1+len(ids)*6+len(ULID{})+5)
+	data = append(data, deltaVersion)
+	for _, id := range ids {
+		if data, err = enc.append(data, id); err != nil {
+			return nil, err
+		}
+	}
+
+	data = append(data, deltaEnd)
+	return binary.BigEndian.AppendUint32(data, crc32.Checksum(data, castagnoli)), nil
+}
+
+// DecodeDelta decodes the ULIDs encoded by EncodeDelta or a DeltaWriter. An error
+// wrapping ErrInvalidDelta is returned if the data is not delta encoded, has an
+// unknown version, is truncated (wrapping io.ErrUnexpectedEOF as well), or has
+// bytes after its checksum, and ErrDeltaChecksum if its checksum does not match.
+func DecodeDelta(data []byte) ([]ULID, error) {
+	r := NewDeltaReader(bytes.NewReader(data))
+	ids := make([]ULID, 0)
+	for {
+		id, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := r.r.Peek(1); err != io.EOF {
+		return nil, fmt.Errorf("%w: data after the checksum", ErrInvalidDelta)
+	}
+	return ids, nil
+}
+
+// deltaEncoder appends the ULIDs of a sorted set to the delta encoding.
+type deltaEncoder struct {
+	prev  ULID
+	start bool
+}
+
+func (e *deltaEncoder) append(b []byte, id ULID) ([]byte, error) {
+	if !e.start {
+		e.start, e.prev = true, id
+		b = append(b, deltaFirst)
+		return append(b, id[:]...), nil
+	}
+
+	if id.Compare(e.prev) <= 0 {
+		return b, ErrUnsortedSet
+	}
+
+	hi, lo := sub128(id, e.prev)
+	e.prev = id
+	return appendUvarint128(b, hi, lo), nil
+}
+
+//===========================================================================
+// Delta Streams
+//===========================================================================
+
+// DeltaWriter writes a sorted set of ULIDs to a stream in the encoding of
+// EncodeDelta, e.g. to send a set that is too large to encode in memory. Writes
+// are buffered, so Close must be called to write the end of the set and its
+// checksum. A DeltaWriter is not safe for concurrent use.
+type DeltaWriter struct {
+	w   *bufio.Writer
+	enc deltaEncoder
+	buf []byte
+	crc uint32
+}
+
+// NewDeltaWriter returns a buffered writer of a delta encoded set of ULIDs to w.
+func NewDeltaWriter(w io.Writer) *DeltaWriter {
+	return &DeltaWriter{w: bufio.NewWriter(w), buf: []byte{deltaVersion}}
+}
+
+// Write writes the ULID to the stream. ErrUnsortedSet is returned if the ULID is
+// not after the previous ULID, in which case it is not written and the stream can
+// continue with another ULID.
+func (w *DeltaWriter) Write(id ULID) (err error) {
+	if w.buf, err = w.enc.append(w.buf, id); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// WriteAll writes every ULID in seq to the stream, returning the number of ULIDs
+// written, e.g. to write the output of Merge. The stream is not closed.
+func (w *DeltaWriter) WriteAll(seq iter.Seq[ULID]) (n int64, err error) {
+	for id := range seq {
+		if err = w.Write(id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Close writes the end of the set and its checksum and flushes the stream. It
+// does not close the underlying writer. The DeltaWriter must not be used after
+// Close.
+func (w *DeltaWriter) Close() error {
+	w.buf = append(w.buf, deltaEnd)
+	crc := crc32.Update(w.crc, castagnoli, w.buf)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, crc)
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *DeltaWriter) flush() error {
+	w.crc = crc32.Update(w.crc, castagnoli, w.buf)
+	_, err := w.w.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// The states of a DeltaReader.
+const (
+	deltaReadVersion = iota
+	deltaReadFirst
+	deltaReadNext
+	deltaReadDone
+)
+
+// DeltaReader reads a delta encoded set of ULIDs from a stream, e.g. written by a
+// DeltaWriter. The ULIDs are returned as they are read, before the checksum at
+// the end of the stream is verified, so the stream is only known to be intact
+// once Read returns io.EOF. A DeltaReader is not safe for concurrent use.
+type DeltaReader struct {
+	r     *bufio.Reader
+	state int
+	prev  ULID
+	buf   []byte
+	crc   uint32
+	err   error
+}
+
+// NewDeltaReader returns a buffered reader of the delta encoded set in r. The
+// reader may read past the end of the set.
+func NewDeltaReader(r io.Reader) *DeltaReader {
+	return &DeltaReader{r: bufio.NewReader(r), buf: make([]byte, 0, len(ULID{}))}
+}
+
+// Read returns the next ULID of the set. It returns io.EOF after the last ULID
+// once the checksum has been verified, and the errors of DecodeDelta otherwise,
+// except for data after the checksum. Errors are sticky.
+func (r *DeltaReader) Read() (id ULID, err error) {
+	if r.err != nil {
+		return Zero, r.err
+	}
+
+	if id, err = r.read(); err != nil {
+		r.err = err
+	}
+	return id, err
+}
+
+// All returns an iterator over the remaining ULIDs of the set. Iteration stops
+// at the end of the set or at the first error, which is then returned by Err.
+func (r *DeltaReader) All() iter.Seq[ULID] {
+	return func(yield func(ULID) bool) {
+		for {
+			id, err := r.Read()
+			if err != nil {
+				return
+			}
+
+			if !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the first error other than io.EOF encountered by Read or All.
+func (r *DeltaReader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+func (r *DeltaReader) read() (id ULID, err error) {
+	switch r.state {
+	case deltaReadVersion:
+		if err = r.fill(1); err != nil {
+			return Zero, err
+		}
+
+		if r.buf[0] != deltaVersion {
+			return Zero, fmt.Errorf("%w: unknown version %d", ErrInvalidDelta, r.buf[0])
+		}
+
+		r.state = deltaReadFirst
+		return r.read()
+
+	case deltaReadFirst:
+		if err = r.fill(1); err != nil {
+			return Zero, err
+		}
+
+		switch r.buf[0] {
+		case deltaEnd:
+			return Zero, r.end()
+		case deltaFirst:
+		default:
+			return Zero, fmt.Errorf("%w: unknown marker 0x%02x", ErrInvalidDelta, r.buf[0])
+		}
+
+		if err = r.fill(len(id)); err != nil {
+			return Zero, err
+		}
+
+		copy(id[:], r.buf)
+		r.state, r.prev = deltaReadNext, id
+		return id, nil
+
+	case deltaReadNext:
+		r.buf = r.buf[:0]
+		for {
+			c, err := r.r.ReadByte()
+			if err != nil {
+				return Zero, truncated(err)
+			}
+
+			r.buf = append(r.buf, c)
+			if c < 0x80 {
+				break
+			}
+
+			if len(r.buf) == maxDeltaLen {
+				return Zero, fmt.Errorf("%w: delta overflows", ErrInvalidDelta)
+			}
+		}
+		r.crc = crc32.Update(r.crc, castagnoli, r.buf)
+
+		if len(r.buf) == 1 && r.buf[0] == deltaEnd {
+			return Zero, r.end()
+		}
+
+		hi, lo, n := uvarint128(r.buf)
+		if n <= 0 || hi|lo == 0 {
+			return Zero, fmt.Errorf("%w: invalid delta", ErrInvalidDelta)
+		}
+
+		var overflow bool
+		if id, overflow = add128(r.prev, hi, lo); overflow {
+			return Zero, fmt.Errorf("%w: delta overflows", ErrInvalidDelta)
+		}
+
+		r.prev = id
+		return id, nil
+
+	default:
+		return Zero, io.EOF
+	}
+}
+
+// fill reads the next n bytes into the buffer and adds them to the checksum.
+func (r *DeltaReader) fill(n int) error {
+	r.buf = r.buf[:n]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return truncated(err)
+	}
+	r.crc = crc32.Update(r.crc, castagnoli, r.buf)
+	return nil
+}
+
+// end verifies the checksum after the end marker.
+func (r *DeltaReader) end() error {
+	crc := r.crc
+	if err := r.fill(4); err != nil {
+		return err
+	}
+
+	if binary.BigEndian.Uint32(r.buf) != crc {
+		return ErrDeltaChecksum
+	}
+
+	r.state = deltaReadDone
+	return io.EOF
+}
+
+// truncated returns the error for the end of the stream before the end of the set.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errTruncatedDelta
+	}
+	return err
+}
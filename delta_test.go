@@ -0,0 +1,230 @@
+package ulid_test
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// maxULID is the largest ULID.
+var maxULID = ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+// sameMillisecond returns n sorted ULIDs of monotonic entropy in a few
+// milliseconds, e.g. of a burst of inserts.
+func sameMillisecond(n int) []ulid.ULID {
+	entropy := ulid.Monotonic(crand.Reader, 0)
+	ms := ulid.Now()
+	ids := make([]ulid.ULID, n)
+	for i := range ids {
+		ids[i] = ulid.MustNew(ms+uint64(i/1000), entropy)
+	}
+	return ids
+}
+
+// spreadOut returns n sorted random ULIDs over a day.
+func spreadOut(rng *rand.Rand, n int) []ulid.ULID {
+	start := ulid.Now()
+	ids := make([]ulid.ULID, n)
+	for i := range ids {
+		ids[i] = ulid.MustNew(start+uint64(rng.Int63n(int64(24*time.Hour/time.Millisecond))), rng)
+	}
+	slices.SortFunc(ids, ulid.ULID.Compare)
+	return slices.Compact(ids)
+}
+
+func TestDelta(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	sequential := make([]ulid.ULID, 100)
+	entropy := ulid.SequentialEntropy([10]byte{})
+	for i := range sequential {
+		sequential[i] = ulid.MustNew(1, entropy)
+	}
+
+	testCases := []struct {
+		name   string
+		ids    []ulid.ULID
+		maxLen int
+	}{
+		{"Empty", []ulid.ULID{}, 6},
+		{"Single", []ulid.ULID{ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")}, 23},
+		{"Bounds", []ulid.ULID{ulid.Zero, ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), maxULID}, 23 + 2*19},
+		{"Sequential", sequential, 23 + 99},
+		{"SameMillisecond", sameMillisecond(5000), 23 + 4999*6},
+		{"SpreadOut", spreadOut(rng, 5000), 23 + 4999*16},
+	}
+
+	for _, tc := range testCases {
+		data, err := ulid.EncodeDelta(tc.ids)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		if len(data) > tc.maxLen {
+			t.Errorf("%s: got %d bytes, want at most %d", tc.name, len(data), tc.maxLen)
+		}
+
+		got, err := ulid.DecodeDelta(data)
+		if err != nil || !slices.Equal(got, tc.ids) {
+			t.Errorf("%s: got %d ULIDs (%v), want %d", tc.name, len(got), err, len(tc.ids))
+		}
+
+		// The stream encoding is the same.
+		var buf bytes.Buffer
+		w := ulid.NewDeltaWriter(&buf)
+		if n, err := w.WriteAll(slices.Values(tc.ids)); err != nil || n != int64(len(tc.ids)) {
+			t.Errorf("%s: wrote %d ULIDs (%v)", tc.name, n, err)
+		}
+
+		if err := w.Close(); err != nil || !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%s: the stream does not match the encoding (%v)", tc.name, err)
+		}
+
+		r := ulid.NewDeltaReader(iotest.OneByteReader(&buf))
+		if got := slices.Collect(r.All()); r.Err() != nil || !slices.Equal(got, tc.ids) {
+			t.Errorf("%s: read %d ULIDs from the stream (%v), want %d", tc.name, len(got), r.Err(), len(tc.ids))
+		}
+
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("%s: got %v after the end of the stream, want EOF", tc.name, err)
+		}
+	}
+}
+
+func TestDeltaUnsorted(t *testing.T) {
+	t.Parallel()
+
+	a, b := ulid.MustParse("01JKEHMRSH3HXYCYYZ1HZR2JBS"), ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	for _, ids := range [][]ulid.ULID{{b, a}, {a, b, b}, {a, a}, {a, b, ulid.Zero}} {
+		if data, err := ulid.EncodeDelta(ids); !errors.Is(err, ulid.ErrUnsortedSet) || data != nil {
+			t.Errorf("%v: got %x (%v), want %v", ids, data, err, ulid.ErrUnsortedSet)
+		}
+	}
+
+	// The writer rejects the ULID and continues.
+	var buf bytes.Buffer
+	w := ulid.NewDeltaWriter(&buf)
+	for i, id := range []ulid.ULID{a, a, ulid.Zero, b} {
+		if err := w.Write(id); (err != nil) != (i == 1 || i == 2) {
+			t.Errorf("write %d: unexpected error %v", i, err)
+		}
+	}
+	w.Close()
+
+	if got, err := ulid.DecodeDelta(buf.Bytes()); err != nil || !slices.Equal(got, []ulid.ULID{a, b}) {
+		t.Errorf("got %v (%v)", got, err)
+	}
+}
+
+func TestDeltaCorrupt(t *testing.T) {
+	t.Parallel()
+
+	ids := sameMillisecond(100)
+	data, err := ulid.EncodeDelta(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every truncation is detected.
+	for n := 0; n < len(data); n++ {
+		if _, err := ulid.DecodeDelta(data[:n]); !errors.Is(err, ulid.ErrInvalidDelta) || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("truncated to %d bytes: got %v, want %v", n, err, io.ErrUnexpectedEOF)
+		}
+	}
+
+	// Every flipped bit is detected, by the checksum or as invalid data.
+	for i := 0; i < len(data)*8; i++ {
+		corrupt := bytes.Clone(data)
+		corrupt[i/8] ^= 1 << (i % 8)
+		if got, err := ulid.DecodeDelta(corrupt); err == nil {
+			t.Fatalf("bit %d: decoded %d ULIDs from corrupt data", i, len(got))
+		}
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"Version", append([]byte{2}, data[1:]...), ulid.ErrInvalidDelta},
+		{"Marker", append([]byte{1, 2}, data[2:]...), ulid.ErrInvalidDelta},
+		{"Checksum", append(bytes.Clone(data[:len(data)-1]), data[len(data)-1]^0xFF), ulid.ErrDeltaChecksum},
+		{"Trailing", append(bytes.Clone(data), 0), ulid.ErrInvalidDelta},
+		{"ZeroDelta", append(bytes.Clone(data[:18]), 0x80, 0x00), ulid.ErrInvalidDelta},
+		{"OverlongDelta", append(bytes.Clone(data[:18]), 0x81, 0x00), ulid.ErrInvalidDelta},
+		{"Overflow", append(append([]byte{1, 1}, maxULID.Bytes()...), 0x01), ulid.ErrInvalidDelta},
+		{"LongDelta", append(append([]byte{1, 1}, make([]byte, 16)...), bytes.Repeat([]byte{0xFF}, 20)...), ulid.ErrInvalidDelta},
+	}
+
+	for _, tc := range testCases {
+		if got, err := ulid.DecodeDelta(tc.data); !errors.Is(err, tc.err) || got != nil {
+			t.Errorf("%s: got %d ULIDs (%v), want %v", tc.name, len(got), err, tc.err)
+		}
+	}
+
+	// The ULIDs before an error are returned by the stream, and the error is
+	// sticky.
+	corrupt := bytes.Clone(data)
+	corrupt[len(corrupt)-1] ^= 0xFF
+	r := ulid.NewDeltaReader(bytes.NewReader(corrupt))
+	if got := slices.Collect(r.All()); !slices.Equal(got, ids) || !errors.Is(r.Err(), ulid.ErrDeltaChecksum) {
+		t.Errorf("got %d ULIDs (%v)", len(got), r.Err())
+	}
+
+	if _, err := r.Read(); !errors.Is(err, ulid.ErrDeltaChecksum) {
+		t.Errorf("got %v, want %v", err, ulid.ErrDeltaChecksum)
+	}
+}
+
+func BenchmarkDelta(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	corpora := []struct {
+		name string
+		ids  []ulid.ULID
+	}{
+		{"SameMillisecond", sameMillisecond(100000)},
+		{"SpreadOut", spreadOut(rng, 100000)},
+	}
+
+	for _, corpus := range corpora {
+		raw := len(corpus.ids) * 16
+		b.Run(corpus.name+"/Raw", func(b *testing.B) {
+			b.SetBytes(int64(raw))
+			for i := 0; i < b.N; i++ {
+				out := make([]byte, 0, raw)
+				for _, id := range corpus.ids {
+					out = append(out, id[:]...)
+				}
+			}
+			b.ReportMetric(16, "bytes/id")
+		})
+
+		b.Run(corpus.name+"/Delta", func(b *testing.B) {
+			var data []byte
+			b.SetBytes(int64(raw))
+			for i := 0; i < b.N; i++ {
+				data, _ = ulid.EncodeDelta(corpus.ids)
+			}
+			b.ReportMetric(float64(len(data))/float64(len(corpus.ids)), "bytes/id")
+			b.ReportMetric(float64(raw)/float64(len(data)), "ratio")
+		})
+
+		b.Run(corpus.name+"/Decode", func(b *testing.B) {
+			data, _ := ulid.EncodeDelta(corpus.ids)
+			b.SetBytes(int64(raw))
+			for i := 0; i < b.N; i++ {
+				ulid.DecodeDelta(data)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package ulid
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMaxDrift is the default number of milliseconds that a BumpingReader
+// may advance the timestamp beyond the requested time on monotonic overflow.
+const DefaultMaxDrift = 10
+
+// BumpOnOverflow wraps a MonotonicReader so that ULIDs generated with its New
+// method do not fail with ErrMonotonicOverflow when a burst exhausts the entropy
+// space of a single millisecond. Instead, the timestamp is advanced by 1ms and
+// fresh entropy is read for the new millisecond, preserving strict ordering at
+// the cost of slightly-future timestamps. Once the timestamp has been advanced,
+// subsequent ULIDs continue to use the advanced timestamp until the requested
+// time catches up, otherwise they would sort before previously issued ULIDs.
+//
+// The timestamp may drift at most maxDrift milliseconds ahead of the requested
+// time; beyond that ErrMonotonicOverflow is returned if the burst exhausted the
+// entropy of every millisecond up to the maximum drift, or ErrClockRegressed if
+// the requested time is already further behind the previous ULID, e.g. because
+// the clock was stepped back. Passing maxDrift == 0 results in the default of
+// DefaultMaxDrift.
+//
+// The returned type is safe for concurrent use so long as the wrapped reader is
+// not used elsewhere.
+func BumpOnOverflow(entropy MonotonicReader, maxDrift uint64) *BumpingReader {
+	if maxDrift == 0 {
+		maxDrift = DefaultMaxDrift
+	}
+
+	return &BumpingReader{
+		entropy:  entropy,
+		maxDrift: maxDrift,
+	}
+}
+
+// BumpingReader owns both the timestamp and the entropy of the ULIDs it
+// generates so that it can advance the timestamp on monotonic overflow. It does
+// not implement io.Reader since New cannot modify the timestamp of a ULID; use
+// the New method or NewMonotonicSafe instead.
+type BumpingReader struct {
+	mu       sync.Mutex
+	entropy  MonotonicReader
+	maxDrift uint64
+	last     uint64
+}
+
+// New returns a ULID with a timestamp of at least ms and monotonic entropy.
+// The timestamp is never less than the timestamp of the previous ULID returned
+// by the reader so that ULIDs are strictly increasing.
+func (r *BumpingReader) New(ms uint64) (id ULID, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := ms
+	if r.last > ts {
+		if r.last-ms > r.maxDrift {
+			return Zero, ErrClockRegressed
+		}
+		ts = r.last
+	}
+
+	for {
+		if ts-ms > r.maxDrift {
+			return Zero, ErrMonotonicOverflow
+		}
+
+		if err = id.SetTime(ts); err != nil {
+			return Zero, err
+		}
+
+		if err = r.entropy.MonotonicRead(ts, id[6:]); err == ErrMonotonicOverflow {
+			ts++
+			continue
+		}
+
+		if err != nil {
+			return Zero, err
+		}
+
+		r.last = ts
+		return id, nil
+	}
+}
+
+// Drift returns how many milliseconds the timestamp of the last issued ULID is
+// ahead of the given time in Unix milliseconds, or 0 if it is not ahead.
+func (r *BumpingReader) Drift(ms uint64) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last > ms {
+		return r.last - ms
+	}
+	return 0
+}
+
+// NewMonotonicSafe returns a ULID for the given time using the BumpingReader,
+// advancing the timestamp rather than failing on monotonic overflow. The time is
+// converted using TimestampChecked so times that cannot be represented in a ULID
+// return ErrSmallTime or ErrBigTime.
+func NewMonotonicSafe(t time.Time, entropy *BumpingReader) (ULID, error) {
+	ms, err := TimestampChecked(t)
+	if err != nil {
+		return Zero, err
+	}
+	return entropy.New(ms)
+}
@@ -0,0 +1,180 @@
+package ulid_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestMonotonicSafe(t *testing.T) {
+	t.Parallel()
+
+	var (
+		rng  = rand.New(rand.NewSource(time.Now().UnixNano()))
+		safe = &ulid.LockedMonotonicReader{MonotonicReader: ulid.Monotonic(rng, 0)}
+		t0   = ulid.Timestamp(time.Now())
+	)
+
+	errs := make(chan error, 100)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			u0 := ulid.MustNew(t0, safe)
+			u1 := u0
+			for j := 0; j < 1024; j++ {
+				u0, u1 = u1, ulid.MustNew(t0, safe)
+				if u0.String() >= u1.String() {
+					errs <- fmt.Errorf(
+						"%s (%d %x) >= %s (%d %x)",
+						u0.String(), u0.Time(), u0.Entropy(),
+						u1.String(), u1.Time(), u1.Entropy(),
+					)
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBumpOnOverflow(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Ordering", func(t *testing.T) {
+		// Entropy of all 0xFF bytes overflows on every increment.
+		entropy := ulid.BumpOnOverflow(ulid.Monotonic(&constReader{0xFF}, 1), 0)
+
+		var prev ulid.ULID
+		for i := uint64(0); i <= ulid.DefaultMaxDrift; i++ {
+			id, err := entropy.New(100)
+			if err != nil {
+				t.Fatalf("ulid %d: %v", i, err)
+			}
+
+			if id.Time() != 100+i {
+				t.Fatalf("ulid %d: got timestamp %d, want %d", i, id.Time(), 100+i)
+			}
+
+			if entropy.Drift(100) != i {
+				t.Fatalf("ulid %d: got drift %d, want %d", i, entropy.Drift(100), i)
+			}
+
+			if prev.Compare(id) >= 0 {
+				t.Fatalf("expected %s < %s", prev, id)
+			}
+			prev = id
+		}
+
+		// Past the drift cap the original error is returned.
+		if _, err := entropy.New(100); err != ulid.ErrMonotonicOverflow {
+			t.Fatalf("got err %v, want %v", err, ulid.ErrMonotonicOverflow)
+		}
+
+		// Once the clock catches up, IDs are generated without drift.
+		id, err := entropy.New(200)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id.Time() != 200 || entropy.Drift(200) != 0 || prev.Compare(id) >= 0 {
+			t.Errorf("unexpected ulid %s at %d after the clock caught up", id, id.Time())
+		}
+	})
+
+	t.Run("StaysAhead", func(t *testing.T) {
+		// Entropy that overflows on the second read in each millisecond.
+		entropy := ulid.BumpOnOverflow(ulid.Monotonic(&constReader{0xFF}, 1), 3)
+
+		a, err := entropy.New(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := entropy.New(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// An earlier requested time does not move the timestamp backwards.
+		c, err := entropy.New(9)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !(a.Compare(b) < 0 && b.Compare(c) < 0) {
+			t.Errorf("expected %s < %s < %s", a, b, c)
+		}
+
+		if c.Time() != 12 {
+			t.Errorf("got timestamp %d, want 12", c.Time())
+		}
+
+		// A clock that is stepped back further than the drift is not an overflow.
+		if _, err := entropy.New(8); err != ulid.ErrClockRegressed {
+			t.Errorf("got err %v, want %v", err, ulid.ErrClockRegressed)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		entropy := ulid.BumpOnOverflow(ulid.Monotonic(bytes.NewReader(nil), 0), 0)
+		if _, err := entropy.New(10); err != io.EOF {
+			t.Errorf("got err %v, want %v", err, io.EOF)
+		}
+
+		if _, err := ulid.NewMonotonicSafe(time.Time{}, entropy); err != ulid.ErrSmallTime {
+			t.Errorf("got err %v, want %v", err, ulid.ErrSmallTime)
+		}
+
+		entropy = ulid.BumpOnOverflow(ulid.Monotonic(&constReader{0xFF}, 1), 0)
+		if _, err := entropy.New(ulid.MaxTime()); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := entropy.New(ulid.MaxTime()); err != ulid.ErrBigTime {
+			t.Errorf("got err %v, want %v", err, ulid.ErrBigTime)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		entropy := ulid.BumpOnOverflow(ulid.Monotonic(rand.New(rand.NewSource(1)), 1<<60), 1000)
+		ids := make(chan ulid.ULID, 8*128)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 128; j++ {
+					id, err := ulid.NewMonotonicSafe(time.Unix(1, 0), entropy)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					ids <- id
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[ulid.ULID]struct{})
+		for id := range ids {
+			if _, ok := seen[id]; ok {
+				t.Fatalf("duplicate ulid %s", id)
+			}
+			seen[id] = struct{}{}
+		}
+	})
+}
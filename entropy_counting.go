@@ -0,0 +1,152 @@
+package ulid
+
+import (
+	crand "crypto/rand"
+	"io"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// EntropyStats are the number of bytes and reads consumed from an entropy
+// source, e.g. for capacity planning on devices with a slow hardware RNG.
+type EntropyStats struct {
+	BytesRead uint64 `json:"bytes_read"`
+	Reads     uint64 `json:"reads"`
+}
+
+// entropyCounts are the counters of one or more CountingReaders.
+type entropyCounts struct {
+	bytes atomic.Uint64
+	reads atomic.Uint64
+}
+
+func (c *entropyCounts) stats() EntropyStats {
+	if c == nil {
+		return EntropyStats{}
+	}
+	return EntropyStats{BytesRead: c.bytes.Load(), Reads: c.reads.Load()}
+}
+
+// CountingEntropy returns a reader that counts the bytes and reads consumed from
+// the entropy source. The counters are atomic, so the reader is safe for
+// concurrent use if the source is, e.g. when shared by the readers of a Pool.
+//
+// To attribute the entropy consumed by monotonic entropy correctly, wrap the
+// source that is passed to Monotonic rather than the monotonic entropy: the
+// bytes pulled from the source are counted, including the read-ahead of its
+// bufio.Reader and the randomness of increments, rather than the bytes
+// delivered to ULIDs. Wrap the source of a RateLimitedReader rather than the
+// RateLimitedReader so that Monotonic still rate limits each MonotonicRead. A
+// *rand.Rand that is counted is read as bytes rather than with Int63n, so its
+// increments consume the same bytes as any other source.
+func CountingEntropy(entropy io.Reader) *CountingReader {
+	return &CountingReader{entropy: entropy, counts: &entropyCounts{}}
+}
+
+// CountingReader counts the bytes and reads consumed from an entropy source,
+// returned by CountingEntropy.
+type CountingReader struct {
+	entropy io.Reader
+	counts  *entropyCounts
+}
+
+var _ io.Reader = &CountingReader{}
+
+// Read reads from the entropy source and counts the bytes read, including the
+// bytes of a short read that returns an error.
+func (r *CountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.entropy.Read(p)
+	r.counts.bytes.Add(uint64(n))
+	r.counts.reads.Add(1)
+	return n, err
+}
+
+// BytesRead returns the number of bytes read from the entropy source.
+func (r *CountingReader) BytesRead() uint64 {
+	return r.counts.bytes.Load()
+}
+
+// Reads returns the number of calls to Read of the entropy source.
+func (r *CountingReader) Reads() uint64 {
+	return r.counts.reads.Load()
+}
+
+// Stats returns the bytes and reads consumed from the entropy source.
+func (r *CountingReader) Stats() EntropyStats {
+	return r.counts.stats()
+}
+
+// Unwrap returns the entropy source that is counted.
+func (r *CountingReader) Unwrap() io.Reader {
+	if r == nil {
+		return nil
+	}
+	return r.entropy
+}
+
+// Stats returns the bytes and reads consumed by the readers of a pool created
+// with the CountEntropy option; for other pools the stats are always zero.
+func (e *PoolEntropy) Stats() EntropyStats {
+	if e == nil {
+		return EntropyStats{}
+	}
+	return e.counts.stats()
+}
+
+// EntropyOption configures the entropy returned by NewDefaultEntropy and
+// NewSecureEntropy.
+type EntropyOption func(*entropyConfig)
+
+type entropyConfig struct {
+	counts *entropyCounts
+}
+
+// CountEntropy counts the bytes and reads consumed from the sources of all of
+// the pooled readers, which are returned by the Stats method of the pool.
+func CountEntropy() EntropyOption {
+	return func(c *entropyConfig) {
+		c.counts = &entropyCounts{}
+	}
+}
+
+// NewDefaultEntropy returns a new pool of entropy like the initial DefaultEntropy,
+// configured by the options: each pooled reader is monotonic entropy over its own
+// math/rand source seeded with the current time. With CountEntropy the pool can
+// replace the default entropy to observe the entropy consumed by Make, e.g.
+//
+//	entropy := ulid.NewDefaultEntropy(ulid.CountEntropy())
+//	ulid.SetDefaultEntropy(entropy)
+func NewDefaultEntropy(opts ...EntropyOption) *PoolEntropy {
+	return newEntropyPool(mathRandSource, opts, func() io.Reader {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	})
+}
+
+// NewSecureEntropy returns a new pool of entropy like the initial SecureEntropy,
+// configured by the options: each pooled reader is monotonic entropy over
+// crypto/rand.Reader. With CountEntropy, the bytes consumed from crypto/rand by
+// the pool are counted.
+func NewSecureEntropy(opts ...EntropyOption) *PoolEntropy {
+	return newEntropyPool(cryptoRandSource, opts, func() io.Reader {
+		return crand.Reader
+	})
+}
+
+func newEntropyPool(source string, opts []EntropyOption, newSource func() io.Reader) *PoolEntropy {
+	var conf entropyConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	pool := Pool(func() io.Reader {
+		entropy := newSource()
+		if conf.counts != nil {
+			entropy = &CountingReader{entropy: entropy, counts: conf.counts}
+		}
+		return Monotonic(entropy, 0)
+	})
+
+	pool.counts, pool.source = conf.counts, source
+	return pool
+}
@@ -0,0 +1,100 @@
+package ulid_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestCountingEntropy(t *testing.T) {
+	t.Parallel()
+
+	const n = 5
+	testCases := []struct {
+		name  string
+		opts  []ulid.MonotonicOption
+		inc   uint64
+		ms    func(i int) uint64
+		want  ulid.EntropyStats
+		count int
+	}{
+		// The first read fills the 4096 byte buffer and increments by 1 read nothing.
+		{"Buffered", nil, 1, func(int) uint64 { return 1 }, ulid.EntropyStats{BytesRead: 4096, Reads: 1}, n},
+		{"Unbuffered", []ulid.MonotonicOption{ulid.NoBuffer()}, 1, func(int) uint64 { return 1 }, ulid.EntropyStats{BytesRead: 10, Reads: 1}, n},
+
+		// Each new millisecond reads 10 fresh bytes.
+		{"UnbufferedTimestamps", []ulid.MonotonicOption{ulid.NoBuffer()}, 1, func(i int) uint64 { return uint64(i + 1) }, ulid.EntropyStats{BytesRead: 10 * n, Reads: n}, n},
+
+		// The default inc reads 4 bytes of randomness for each increment.
+		{"UnbufferedIncrements", []ulid.MonotonicOption{ulid.NoBuffer()}, 0, func(int) uint64 { return 1 }, ulid.EntropyStats{BytesRead: 10 + 4*(n-1), Reads: n}, n},
+		{"BufferedIncrements", nil, 0, func(int) uint64 { return 1 }, ulid.EntropyStats{BytesRead: 4096, Reads: 1}, n},
+	}
+
+	for _, tc := range testCases {
+		counter := ulid.CountingEntropy(&constReader{0x01})
+		entropy := ulid.MonotonicWithOptions(counter, tc.inc, tc.opts...)
+		for i := 0; i < tc.count; i++ {
+			if _, err := ulid.New(tc.ms(i), entropy); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+
+		if got := counter.Stats(); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+
+		if counter.BytesRead() != tc.want.BytesRead || counter.Reads() != tc.want.Reads {
+			t.Errorf("%s: got %d bytes in %d reads, want %+v", tc.name, counter.BytesRead(), counter.Reads(), tc.want)
+		}
+	}
+
+	// Short reads and errors are counted.
+	counter := ulid.CountingEntropy(bytes.NewReader([]byte("short")))
+	if _, err := ulid.New(1, counter); err == nil {
+		t.Fatal("expected an error for short entropy")
+	}
+
+	if got, want := counter.Stats(), (ulid.EntropyStats{BytesRead: 5, Reads: 2}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCountEntropy(t *testing.T) {
+	t.Parallel()
+
+	// The pooled readers share the counters of the pool and each fills its buffer
+	// from the source, so the bytes are a multiple of the buffer size.
+	for name, pool := range map[string]*ulid.PoolEntropy{
+		"Default": ulid.NewDefaultEntropy(ulid.CountEntropy()),
+		"Secure":  ulid.NewSecureEntropy(ulid.CountEntropy()),
+	} {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					ulid.MustNew(ulid.Now(), pool)
+				}
+			}()
+		}
+		wg.Wait()
+
+		stats := pool.Stats()
+		if stats.Reads == 0 || stats.BytesRead != 4096*stats.Reads {
+			t.Errorf("%s: expected whole buffers to be read, got %+v", name, stats)
+		}
+	}
+
+	// Pools without counting report no stats.
+	if stats := ulid.NewSecureEntropy().Stats(); stats != (ulid.EntropyStats{}) {
+		t.Errorf("expected no stats without counting, got %+v", stats)
+	}
+
+	var pool *ulid.PoolEntropy
+	if stats := pool.Stats(); stats != (ulid.EntropyStats{}) {
+		t.Errorf("expected no stats for a nil pool, got %+v", stats)
+	}
+}
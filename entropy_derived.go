@@ -0,0 +1,120 @@
+package ulid
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"io"
+	"slices"
+	"sync"
+)
+
+// derivedSalt is the HKDF salt of derived entropy; changing it changes every
+// derived stream.
+const derivedSalt = "go.rtnl.ai/ulid derived entropy v1"
+
+// Each refill of a derived reader extracts a key from derivedSeedSize bytes of
+// the root and expands it into derivedBlockSize bytes of output.
+const (
+	derivedSeedSize  = sha256.Size
+	derivedBlockSize = 8 * sha256.Size
+)
+
+// domains records the domains of the derived readers created by the process.
+var domains = struct {
+	sync.Mutex
+	names map[string]struct{}
+}{names: make(map[string]struct{})}
+
+// DerivedEntropy returns an entropy source for the domain, e.g. "session" or
+// "api-key", derived from the root source so that the entropy of different
+// purposes does not share a single raw consumer path of the root. The stream of
+// each domain is independent: every block of 256 bytes is produced by HKDF with
+// HMAC-SHA256, extracting a key from 32 fresh bytes read from the root and
+// expanding it with the domain as the info, so the same root bytes produce
+// unrelated output in different domains and the compromise of the state of one
+// derived reader reveals at most its current block. The output is only as
+// unpredictable as the root; the derivation is deterministic in its domain
+// separation alone. If root is nil crypto/rand.Reader is used.
+//
+// The returned reader is not safe for concurrent use. Wrap it the same way as any
+// other source, e.g. with Monotonic and LockedMonotonicReader, or create one per
+// pool member with Pool, in which case the root must be safe for concurrent use
+// (as crypto/rand.Reader is). The domain is recorded for Domains.
+func DerivedEntropy(root io.Reader, domain string) io.Reader {
+	if root == nil {
+		root = crand.Reader
+	}
+
+	domains.Lock()
+	domains.names[domain] = struct{}{}
+	domains.Unlock()
+
+	return &derivedReader{root: root, domain: domain, off: derivedBlockSize}
+}
+
+// Domains returns the sorted domains of the derived entropy sources created by
+// DerivedEntropy in this process, e.g. so that a service can report which
+// domains it uses in an audit.
+func Domains() []string {
+	domains.Lock()
+	defer domains.Unlock()
+
+	names := make([]string, 0, len(domains.names))
+	for name := range domains.names {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type derivedReader struct {
+	root   io.Reader
+	domain string
+	block  [derivedBlockSize]byte
+	off    int
+}
+
+// Read fills p from the current block, refilling it from the root as needed.
+func (r *derivedReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if r.off == len(r.block) {
+			if err = r.refill(); err != nil {
+				return n, err
+			}
+		}
+
+		c := copy(p[n:], r.block[r.off:])
+		r.off += c
+		n += c
+	}
+	return n, nil
+}
+
+// refill replaces the block with the HKDF expansion of a key extracted from fresh
+// root bytes: PRK = HMAC(salt, seed) and T(i) = HMAC(PRK, T(i-1) | domain | i).
+func (r *derivedReader) refill() error {
+	var seed [derivedSeedSize]byte
+	if _, err := io.ReadFull(r.root, seed[:]); err != nil {
+		return err
+	}
+
+	extract := hmac.New(sha256.New, []byte(derivedSalt))
+	extract.Write(seed[:])
+	prk := extract.Sum(nil)
+	clear(seed[:])
+
+	expand := hmac.New(sha256.New, prk)
+	var prev []byte
+	for i := 0; i < len(r.block)/sha256.Size; i++ {
+		expand.Reset()
+		expand.Write(prev)
+		io.WriteString(expand, r.domain)
+		expand.Write([]byte{byte(i + 1)})
+		prev = expand.Sum(r.block[i*sha256.Size : i*sha256.Size])
+	}
+
+	clear(prk)
+	r.off = 0
+	return nil
+}
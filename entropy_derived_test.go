@@ -0,0 +1,158 @@
+package ulid_test
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestDerivedEntropy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("HKDF", func(t *testing.T) {
+		t.Parallel()
+
+		// The first block is HKDF-SHA256 of 32 zero root bytes with the domain as info.
+		var block [256]byte
+		if _, err := io.ReadFull(ulid.DerivedEntropy(&constReader{0}, "session"), block[:]); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := hex.EncodeToString(block[:16]); got != "e1793e56b73d97b17d7f7155022601e5" {
+			t.Errorf("unexpected start of the first block %s", got)
+		}
+
+		if got := hex.EncodeToString(block[240:]); got != "1aad1993905dfd7216d31091a642d47e" {
+			t.Errorf("unexpected end of the first block %s", got)
+		}
+	})
+
+	t.Run("Separation", func(t *testing.T) {
+		t.Parallel()
+
+		// Even when the roots of the domains produce identical bytes, no 10 byte
+		// block of entropy is shared between the domains.
+		const samples = 100000
+		session := ulid.DerivedEntropy(rand.New(rand.NewSource(7)), "test-session")
+		apikey := ulid.DerivedEntropy(rand.New(rand.NewSource(7)), "test-api-key")
+
+		seen := make(map[[10]byte]struct{}, samples)
+		var block [10]byte
+		for i := 0; i < samples; i++ {
+			if _, err := io.ReadFull(session, block[:]); err != nil {
+				t.Fatal(err)
+			}
+			seen[block] = struct{}{}
+		}
+
+		for i := 0; i < samples; i++ {
+			if _, err := io.ReadFull(apikey, block[:]); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := seen[block]; ok {
+				t.Fatalf("domains produced the identical block %x", block)
+			}
+		}
+
+		// The same domain over the same root bytes is deterministic.
+		a := ulid.DerivedEntropy(rand.New(rand.NewSource(7)), "test-session")
+		b := ulid.DerivedEntropy(rand.New(rand.NewSource(7)), "test-session")
+		for i := 0; i < 100; i++ {
+			if ulid.MustNew(1, a) != ulid.MustNew(1, b) {
+				t.Fatal("expected the same domain and root to produce the same entropy")
+			}
+		}
+	})
+
+	t.Run("Reads", func(t *testing.T) {
+		t.Parallel()
+
+		// Reads of any size consume the blocks in order without gaps.
+		want := make([]byte, 1000)
+		if _, err := io.ReadFull(ulid.DerivedEntropy(rand.New(rand.NewSource(1)), "test-reads"), want); err != nil {
+			t.Fatal(err)
+		}
+
+		r := ulid.DerivedEntropy(rand.New(rand.NewSource(1)), "test-reads")
+		var got []byte
+		for _, n := range []int{1, 10, 255, 256, 257, 221} {
+			p := make([]byte, n)
+			if _, err := r.Read(p); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, p...)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Error("expected reads of different sizes to produce the same stream")
+		}
+
+		// Errors from the root are returned.
+		if _, err := ulid.DerivedEntropy(iotest.ErrReader(io.ErrUnexpectedEOF), "test-reads").Read(make([]byte, 10)); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected the error of the root, got %v", err)
+		}
+	})
+
+	t.Run("Compose", func(t *testing.T) {
+		t.Parallel()
+
+		pool := ulid.Pool(func() io.Reader {
+			return &ulid.LockedMonotonicReader{MonotonicReader: ulid.Monotonic(ulid.DerivedEntropy(nil, "test-pool"), 0)}
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					r := pool.Get().(ulid.MonotonicReader)
+					_, err := ulid.New(ulid.Now(), r)
+					pool.Put(r)
+
+					if err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		if !slices.Contains(ulid.Domains(), "test-pool") {
+			t.Errorf("expected test-pool to be a registered domain, got %v", ulid.Domains())
+		}
+
+		if !slices.IsSorted(ulid.Domains()) {
+			t.Errorf("expected the domains to be sorted, got %v", ulid.Domains())
+		}
+	})
+}
+
+func BenchmarkDerivedEntropy(b *testing.B) {
+	for _, bench := range []struct {
+		name    string
+		entropy io.Reader
+	}{
+		{"CryptoRand", crand.Reader},
+		{"Derived", ulid.DerivedEntropy(crand.Reader, "benchmark")},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var p [10]byte
+			b.SetBytes(int64(len(p)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = bench.entropy.Read(p[:])
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package ulid
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// MonotonicReader is an interface that should yield monotonically increasing
+// entropy into the provided slice for all calls with the same ms parameter. If
+// a MonotonicReader is provided to the New constructor, its MonotonicRead
+// method will be used instead of Read.
+type MonotonicReader interface {
+	io.Reader
+	MonotonicRead(ms uint64, p []byte) error
+}
+
+// Monotonic returns a source of entropy that yields strictly increasing entropy
+// bytes, to a limit governeed by the `inc` parameter.
+//
+// Specifically, calls to MonotonicRead within the same ULID timestamp return
+// entropy incremented by a random number between 1 and `inc` inclusive. If an
+// increment results in entropy that would overflow available space,
+// MonotonicRead returns ErrMonotonicOverflow.
+//
+// Passing `inc == 0` results in the reasonable default `math.MaxUint32`. Lower
+// values of `inc` provide more monotonic entropy in a single millisecond, at
+// the cost of easier "guessability" of generated ULIDs. If your code depends on
+// ULIDs having secure entropy bytes, then it's recommended to use the secure
+// default value of `inc == 0`, unless you know what you're doing.
+// Use MonotonicWithOptions and WithIncrement for other distributions of the
+// increments.
+//
+// The provided entropy source must actually yield random bytes. Otherwise,
+// monotonic reads are not guaranteed to terminate, since there isn't enough
+// randomness to compute an increment number.
+//
+// If entropy is a RateLimitedReader, each MonotonicRead consumes one token from
+// its rate limit and the entropy is read directly from its underlying source.
+//
+// The entropy source is wrapped in a bufio.Reader, which reads ahead from the
+// source; use MonotonicWithOptions and NoBuffer if the source must not be read
+// beyond the bytes actually used.
+//
+// The returned type isn't safe for concurrent use.
+func Monotonic(entropy io.Reader, inc uint64) *MonotonicEntropy {
+	return MonotonicWithOptions(entropy, inc)
+}
+
+// MonotonicOption configures the monotonic entropy returned by
+// MonotonicWithOptions.
+type MonotonicOption func(*monotonicConfig)
+
+type monotonicConfig struct {
+	bufferSize int
+	strategy   IncrementStrategy
+}
+
+// NoBuffer reads directly from the entropy source rather than through a
+// bufio.Reader so that no bytes are read from the source beyond those used, e.g.
+// for finite streams, an io.LimitedReader or a hardware RNG with strict read
+// accounting.
+//
+// Unbuffered, each MonotonicRead at a new timestamp reads exactly the 10 bytes
+// of entropy from the source, while a MonotonicRead at the same timestamp as
+// the previous read reads the random increment: no bytes if inc is 1 or the
+// source is a *rand.Rand, otherwise (bits.Len64(inc)+7)/8 bytes (4 bytes for
+// the default inc), repeated in the rare case that the random value is outside
+// of the range of the increment and is rejected. With an IncrementStrategy, an
+// increment reads the bytes read by the strategy.
+func NoBuffer() MonotonicOption {
+	return BufferSize(0)
+}
+
+// BufferSize sets the size of the bufio.Reader that wraps the entropy source
+// (default 4096 bytes). A size less than or equal to zero disables buffering,
+// equivalent to NoBuffer.
+func BufferSize(n int) MonotonicOption {
+	return func(c *monotonicConfig) {
+		c.bufferSize = n
+	}
+}
+
+// MonotonicWithOptions returns monotonic entropy like Monotonic, configured by
+// the options. With no options it is equivalent to Monotonic.
+func MonotonicWithOptions(entropy io.Reader, inc uint64, opts ...MonotonicOption) *MonotonicEntropy {
+	conf := monotonicConfig{bufferSize: defaultBufferSize}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	m := MonotonicEntropy{inc: inc, strategy: conf.strategy, source: entropy}
+
+	// Rate limit each MonotonicRead rather than every read of the underlying
+	// source, which includes buffering and the randomness for increments.
+	if limited, ok := entropy.(readLimiter); ok {
+		m.limiter = limited
+		entropy = limited.Unwrap()
+	}
+
+	if conf.bufferSize > 0 {
+		m.Reader = bufio.NewReaderSize(entropy, conf.bufferSize)
+		if m.Reader != entropy {
+			m.buffer = m.Reader.(*bufio.Reader)
+		}
+	} else {
+		m.Reader = entropy
+	}
+
+	if m.inc == 0 {
+		m.inc = math.MaxUint32
+	}
+
+	// A math/rand source is used directly rather than through the buffer so that
+	// the increments can use its Int63n method.
+	m.incs = m.Reader
+	if rng, ok := entropy.(rng); ok {
+		m.rng, m.incs = rng, entropy
+	}
+
+	return &m
+}
+
+type rng interface{ Int63n(n int64) int64 }
+
+// entropyWrapper is implemented by the entropy sources of this package that wrap
+// another source, such as a CountingReader, so that the wrapped source can be
+// reached without a type switch on every wrapper.
+type entropyWrapper interface {
+	Unwrap() io.Reader
+}
+
+// readLimiter is implemented by entropy sources that limit the number of reads,
+// such as a RateLimitedReader. Monotonic entropy reads from the wrapped source
+// directly and reserves one read for each MonotonicRead instead, which it
+// releases if the MonotonicRead fails.
+type readLimiter interface {
+	entropyWrapper
+	reserve() error
+	release()
+}
+
+// defaultBufferSize is the default size of the buffer of monotonic entropy,
+// matching the default size of a bufio.Reader.
+const defaultBufferSize = 4096
+
+// LockedMonotonicReader wraps a MonotonicReader with a sync.Mutex for safe
+// concurrent use.
+type LockedMonotonicReader struct {
+	mu sync.Mutex
+	MonotonicReader
+}
+
+// MonotonicRead synchronizes calls to the wrapped MonotonicReader. It returns
+// ErrNilEntropy if r is nil or does not wrap a MonotonicReader.
+func (r *LockedMonotonicReader) MonotonicRead(ms uint64, p []byte) (err error) {
+	if r == nil || r.MonotonicReader == nil {
+		return ErrNilEntropy
+	}
+
+	r.mu.Lock()
+	err = r.MonotonicReader.MonotonicRead(ms, p)
+	r.mu.Unlock()
+	return err
+}
+
+// MonotonicEntropy is an opaque type that provides monotonic entropy.
+type MonotonicEntropy struct {
+	io.Reader
+	ms       uint64
+	inc      uint64
+	entropy  uint80
+	rand     [8]byte
+	rng      rng
+	limiter  readLimiter
+	strategy IncrementStrategy
+	incs     io.Reader // the entropy of the increments of the strategy
+	last     uint64
+	source   io.Reader     // the entropy as passed to Monotonic, for GeneratorInfo
+	buffer   *bufio.Reader // the buffer of the source if it was created by Monotonic
+	epoch    uint64        // the epoch of the pool the entropy was last taken from
+}
+
+// MonotonicRead implements the MonotonicReader interface. It returns
+// ErrNilEntropy if m is nil.
+func (m *MonotonicEntropy) MonotonicRead(ms uint64, entropy []byte) (err error) {
+	if m == nil {
+		return ErrNilEntropy
+	}
+
+	if m.limiter != nil {
+		if err = m.limiter.reserve(); err != nil {
+			return err
+		}
+
+		defer func() {
+			if err != nil {
+				m.limiter.release()
+			}
+		}()
+	}
+
+	if !m.entropy.IsZero() && m.ms == ms {
+		if err = m.increment(); err == ErrMonotonicOverflow {
+			recordOverflow(ms)
+		}
+		m.entropy.AppendTo(entropy)
+	} else if _, err = io.ReadFull(m.Reader, entropy); err == nil {
+		m.ms = ms
+		m.entropy.SetBytes(entropy)
+	}
+	return err
+}
+
+// increment the previous entropy number with a random number
+// of up to m.inc (inclusive), or with the increment of the strategy.
+func (m *MonotonicEntropy) increment() (err error) {
+	var inc uint64
+	if m.strategy != nil {
+		if inc, err = m.strategy.NextIncrement(m.incs); err == nil && inc == 0 {
+			err = ErrZeroIncrement
+		}
+	} else {
+		inc, err = m.random()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	m.last = inc
+	if m.entropy.Add(inc) {
+		return ErrMonotonicOverflow
+	}
+	return nil
+}
+
+// LastIncrement returns the increment of the most recent MonotonicRead within the
+// same millisecond as its previous read, e.g. to observe the distribution of an
+// IncrementStrategy. It is zero until the entropy has been incremented.
+func (m *MonotonicEntropy) LastIncrement() uint64 {
+	return m.last
+}
+
+// random returns a uniform random value in [1, m.inc), reading entropy
+// from m.Reader. When m.inc == 0 || m.inc == 1, it returns 1.
+// Adapted from: https://golang.org/pkg/crypto/rand/#Int
+func (m *MonotonicEntropy) random() (inc uint64, err error) {
+	if m.inc <= 1 {
+		return 1, nil
+	}
+
+	// Fast path for using a underlying rand.Rand directly.
+	if m.rng != nil {
+		// Range: [1, m.inc)
+		return 1 + uint64(m.rng.Int63n(int64(m.inc))), nil
+	}
+
+	// bitLen is the maximum bit length needed to encode a value < m.inc.
+	bitLen := bits.Len64(m.inc)
+
+	// byteLen is the maximum byte length needed to encode a value < m.inc.
+	byteLen := uint(bitLen+7) / 8
+
+	// msbitLen is the number of bits in the most significant byte of m.inc-1.
+	msbitLen := uint(bitLen % 8)
+	if msbitLen == 0 {
+		msbitLen = 8
+	}
+
+	for inc == 0 || inc >= m.inc {
+		if _, err = io.ReadFull(m.Reader, m.rand[:byteLen]); err != nil {
+			return 0, err
+		}
+
+		// Clear bits in the first byte to increase the probability
+		// that the candidate is < m.inc.
+		m.rand[0] &= uint8(int(1<<msbitLen) - 1)
+
+		// Convert the read bytes into an uint64 with byteLen
+		// Optimized unrolled loop.
+		switch byteLen {
+		case 1:
+			inc = uint64(m.rand[0])
+		case 2:
+			inc = uint64(binary.LittleEndian.Uint16(m.rand[:2]))
+		case 3, 4:
+			inc = uint64(binary.LittleEndian.Uint32(m.rand[:4]))
+		case 5, 6, 7, 8:
+			inc = uint64(binary.LittleEndian.Uint64(m.rand[:8]))
+		}
+	}
+
+	// Range: [1, m.inc)
+	return 1 + inc, nil
+}
+
+//===========================================================================
+// Increment Strategies
+//===========================================================================
+
+// IncrementStrategy chooses the increments of monotonic entropy within the same
+// millisecond, trading the number of ULIDs that can be generated per millisecond
+// against how easily the next ULID can be guessed from the previous one. The rng
+// is the (buffered) entropy source of the monotonic entropy so that strategies
+// do not need their own source of randomness; a *math/rand.Rand source is passed
+// unbuffered so that strategies can use its methods. NextIncrement must return an
+// increment of at least 1; an increment of 0 is returned by MonotonicRead as
+// ErrZeroIncrement. Strategies may be shared between monotonic entropy sources
+// and must be safe for concurrent use if the sources are used concurrently.
+type IncrementStrategy interface {
+	NextIncrement(rng io.Reader) (uint64, error)
+}
+
+// WithIncrement sets the strategy for the increments of the monotonic entropy,
+// replacing the uniform increment chosen by the inc argument of
+// MonotonicWithOptions, which is then ignored.
+func WithIncrement(strategy IncrementStrategy) MonotonicOption {
+	return func(c *monotonicConfig) {
+		c.strategy = strategy
+	}
+}
+
+// UniformIncrement returns a strategy like the default of Monotonic: a uniform
+// random increment between 1 and n inclusive, where n == 0 is math.MaxUint32. The
+// increments are unbiased but differ from those of Monotonic with inc n. The next
+// ULID is one of n equally likely values after the previous ULID, so an increment
+// carries log2(n) bits of unpredictability (32 bits by default) and about
+// 2^80/(n/2) ULIDs can be generated per millisecond before overflow.
+func UniformIncrement(n uint64) IncrementStrategy {
+	if n == 0 {
+		n = math.MaxUint32
+	}
+	return uniformIncrement(n)
+}
+
+type uniformIncrement uint64
+
+func (u uniformIncrement) NextIncrement(r io.Reader) (uint64, error) {
+	if u <= 1 {
+		return 1, nil
+	}
+
+	if rng, ok := r.(rng); ok && u <= math.MaxInt64 {
+		return 1 + uint64(rng.Int63n(int64(u))), nil
+	}
+
+	// Rejection sampling of big endian values masked to the bit length of u-1.
+	n := uint64(u)
+	byteLen := (bits.Len64(n-1) + 7) / 8
+	mask := uint64(1)<<bits.Len64(n-1) - 1
+
+	var buf [8]byte
+	for {
+		if _, err := io.ReadFull(r, buf[8-byteLen:]); err != nil {
+			return 0, err
+		}
+
+		if v := binary.BigEndian.Uint64(buf[:]) & mask; v < n {
+			return 1 + v, nil
+		}
+	}
+}
+
+// ConstantIncrement returns a strategy that always increments by n (n == 0 is
+// treated as 1) without reading any entropy. This maximizes the ULIDs per
+// millisecond but the next ULID is entirely predictable from the previous one, so
+// it must not be used where ULIDs need to be unguessable within a millisecond.
+func ConstantIncrement(n uint64) IncrementStrategy {
+	return constantIncrement(max(n, 1))
+}
+
+type constantIncrement uint64
+
+func (c constantIncrement) NextIncrement(io.Reader) (uint64, error) {
+	return uint64(c), nil
+}
+
+// GeometricIncrement returns a strategy with geometrically distributed increments:
+// an increment of k has probability (1-p)^(k-1) * p, so the increment is usually
+// small (1 with probability p) with a mean of 1/p, occasionally jumping up to
+// limit. If p is not in (0, 1] it is 0.5, and limit == 0 is math.MaxUint32. This
+// produces nearly as many ULIDs per millisecond as ConstantIncrement while
+// retaining some unpredictability, but an attacker guessing an increment of 1
+// guesses the next ULID with probability p, and an increment carries only about
+// H(p)/p bits of entropy (2 bits for p = 0.5), so it is not suitable where ULIDs
+// must be unguessable. Each increment reads 8 bytes of entropy.
+func GeometricIncrement(p float64, limit uint64) IncrementStrategy {
+	if !(p > 0 && p <= 1) {
+		p = 0.5
+	}
+
+	if limit == 0 {
+		limit = math.MaxUint32
+	}
+	return geometricIncrement{logq: math.Log1p(-p), limit: limit}
+}
+
+type geometricIncrement struct {
+	logq  float64
+	limit uint64
+}
+
+func (g geometricIncrement) NextIncrement(r io.Reader) (uint64, error) {
+	// p == 1 always increments by 1.
+	if math.IsInf(g.logq, -1) {
+		return 1, nil
+	}
+
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+
+	// Inverse transform sampling with u uniform in (0, 1].
+	u := float64(binary.BigEndian.Uint64(buf[:])>>11+1) / (1 << 53)
+	k := math.Ceil(math.Log(u) / g.logq)
+	switch {
+	case k < 1:
+		return 1, nil
+	case k >= float64(g.limit):
+		return g.limit, nil
+	}
+	return uint64(k), nil
+}
@@ -0,0 +1,283 @@
+package ulid_test
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestMonotonic(t *testing.T) {
+	now := ulid.Now()
+	for _, e := range []struct {
+		name string
+		mk   func() io.Reader
+	}{
+		{"cryptorand", func() io.Reader { return crand.Reader }},
+		{"mathrand", func() io.Reader { return rand.New(rand.NewSource(int64(now))) }},
+	} {
+		for _, inc := range []uint64{
+			0,
+			1,
+			2,
+			math.MaxUint8 + 1,
+			math.MaxUint16 + 1,
+			math.MaxUint32 + 1,
+		} {
+			inc := inc
+			entropy := ulid.Monotonic(e.mk(), uint64(inc))
+
+			t.Run(fmt.Sprintf("entropy=%s/inc=%d", e.name, inc), func(t *testing.T) {
+				t.Parallel()
+
+				var prev ulid.ULID
+				for i := 0; i < 10000; i++ {
+					next, err := ulid.New(123, entropy)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if prev.Compare(next) >= 0 {
+						t.Fatalf("prev: %v %v > next: %v %v",
+							prev.Time(), prev.Entropy(), next.Time(), next.Entropy())
+					}
+
+					prev = next
+				}
+			})
+		}
+	}
+}
+
+func TestMonotonicOverflow(t *testing.T) {
+	t.Parallel()
+
+	entropy := ulid.Monotonic(
+		io.MultiReader(
+			bytes.NewReader(bytes.Repeat([]byte{0xFF}, 10)), // Entropy for first ULID
+			crand.Reader, // Following random entropy
+		),
+		0,
+	)
+
+	prev, err := ulid.New(0, entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := ulid.New(prev.Time(), entropy)
+	if have, want := err, ulid.ErrMonotonicOverflow; have != want {
+		t.Errorf("have ulid: %v %v err: %v, want err: %v",
+			next.Time(), next.Entropy(), have, want)
+	}
+}
+
+func TestMonotonicNoBuffer(t *testing.T) {
+	t.Parallel()
+
+	const n = 32
+
+	// The source is shared with another consumer that reads 6 bytes after every
+	// ULID, so it must not be read ahead by the monotonic entropy.
+	generate := func(opts ...ulid.MonotonicOption) error {
+		src := &io.LimitedReader{R: crand.Reader, N: n * (10 + 6)}
+		entropy := ulid.MonotonicWithOptions(src, 0, opts...)
+
+		for i := uint64(0); i < n; i++ {
+			if _, err := ulid.New(i, entropy); err != nil {
+				return err
+			}
+
+			if _, err := io.ReadFull(src, make([]byte, 6)); err != nil {
+				return err
+			}
+		}
+
+		if src.N != 0 {
+			return fmt.Errorf("%d bytes remaining", src.N)
+		}
+		return nil
+	}
+
+	if err := generate(ulid.NoBuffer()); err != nil {
+		t.Errorf("unbuffered: %s", err)
+	}
+
+	if err := generate(ulid.BufferSize(-1)); err != nil {
+		t.Errorf("zero buffer size: %s", err)
+	}
+
+	if err := generate(); err == nil {
+		t.Errorf("expected the buffered monotonic entropy to read ahead")
+	}
+
+	t.Run("Increments", func(t *testing.T) {
+		t.Parallel()
+
+		// A new timestamp reads 10 bytes and an increment within the same timestamp
+		// reads 4 bytes for the default inc (the seeded values are never rejected) and
+		// no bytes if inc is 1.
+		for inc, size := range map[uint64]int64{0: 4, 1: 0} {
+			src := &io.LimitedReader{R: rand.New(rand.NewSource(42)), N: 10 + (n-1)*size}
+			entropy := ulid.MonotonicWithOptions(src, inc, ulid.NoBuffer())
+
+			var prev ulid.ULID
+			for i := 0; i < n; i++ {
+				next, err := ulid.New(123, entropy)
+				if err != nil {
+					t.Fatalf("inc=%d: %s", inc, err)
+				}
+
+				if prev.Compare(next) >= 0 {
+					t.Fatalf("inc=%d: %s >= %s", inc, prev, next)
+				}
+				prev = next
+			}
+
+			if src.N != 0 {
+				t.Errorf("inc=%d: %d bytes remaining", inc, src.N)
+			}
+		}
+	})
+}
+
+func TestIncrementStrategies(t *testing.T) {
+	t.Parallel()
+
+	strategies := map[string]struct {
+		strategy ulid.IncrementStrategy
+		mean     float64
+	}{
+		"Uniform":          {ulid.UniformIncrement(1000), 500.5},
+		"UniformOne":       {ulid.UniformIncrement(1), 1},
+		"UniformDefault":   {ulid.UniformIncrement(0), math.MaxUint32/2 + 0.5},
+		"Constant":         {ulid.ConstantIncrement(7), 7},
+		"ConstantZero":     {ulid.ConstantIncrement(0), 1},
+		"Geometric":        {ulid.GeometricIncrement(0.25, 0), 4},
+		"GeometricOne":     {ulid.GeometricIncrement(1, 0), 1},
+		"GeometricLimit":   {ulid.GeometricIncrement(0.001, 10), 10},
+		"GeometricInvalid": {ulid.GeometricIncrement(-1, 0), 2},
+	}
+
+	for name, tc := range strategies {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Ordering holds for buffered and unbuffered sources, including the fast
+			// path for a *rand.Rand.
+			for _, opts := range [][]ulid.MonotonicOption{nil, {ulid.NoBuffer()}} {
+				opts = append(opts, ulid.WithIncrement(tc.strategy))
+				entropy := ulid.MonotonicWithOptions(rand.New(rand.NewSource(42)), 0, opts...)
+
+				const n = 20000
+				var sum float64
+				prev := ulid.MustNew(123, entropy)
+				for i := 1; i < n; i++ {
+					next, err := ulid.New(123, entropy)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if prev.Compare(next) >= 0 {
+						t.Fatalf("%s >= %s", prev, next)
+					}
+
+					inc := entropy.LastIncrement()
+					if gap, ok := ulid.Gap(prev, next); ok && gap != inc {
+						t.Fatalf("got gap %d, but last increment %d", gap, inc)
+					}
+
+					sum += float64(inc)
+					prev = next
+				}
+
+				// The sample mean is within 5% of the mean of the distribution.
+				if mean := sum / (n - 1); math.Abs(mean-tc.mean) > 0.05*tc.mean {
+					t.Errorf("got mean increment %.2f, want %.2f", mean, tc.mean)
+				}
+			}
+		})
+	}
+
+	t.Run("Overflow", func(t *testing.T) {
+		t.Parallel()
+
+		for name, strategy := range map[string]ulid.IncrementStrategy{
+			"Uniform":   ulid.UniformIncrement(1 << 20),
+			"Constant":  ulid.ConstantIncrement(2),
+			"Geometric": ulid.GeometricIncrement(0.5, 0),
+		} {
+			max := append(bytes.Repeat([]byte{0xFF}, 9), 0xFE)
+			src := io.MultiReader(bytes.NewReader(max), crand.Reader)
+			entropy := ulid.MonotonicWithOptions(src, 0, ulid.NoBuffer(), ulid.WithIncrement(strategy))
+
+			var err error
+			for i := 0; i < 100 && err == nil; i++ {
+				_, err = ulid.New(123, entropy)
+			}
+
+			if err != ulid.ErrMonotonicOverflow {
+				t.Errorf("%s: expected ErrMonotonicOverflow, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("Rand", func(t *testing.T) {
+		t.Parallel()
+
+		// The uniform increments of a buffered math/rand source use its Int63n.
+		src := &int63nReader{Rand: rand.New(rand.NewSource(42))}
+		entropy := ulid.MonotonicWithOptions(src, 0, ulid.WithIncrement(ulid.UniformIncrement(1000)))
+		for i := 0; i < 10; i++ {
+			ulid.MustNew(123, entropy)
+		}
+
+		if src.calls != 9 {
+			t.Errorf("expected 9 calls of Int63n, got %d", src.calls)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		entropy := ulid.MonotonicWithOptions(crand.Reader, 0, ulid.WithIncrement(ulid.ConstantIncrement(1)))
+		if entropy.LastIncrement() != 0 {
+			t.Errorf("expected no increment before the first read")
+		}
+
+		// An increment of zero from a custom strategy is an error.
+		entropy = ulid.MonotonicWithOptions(crand.Reader, 0, ulid.WithIncrement(zeroIncrement{}))
+		ulid.MustNew(123, entropy)
+		if _, err := ulid.New(123, entropy); err != ulid.ErrZeroIncrement {
+			t.Errorf("expected ErrZeroIncrement, got %v", err)
+		}
+
+		// Errors reading the entropy for an increment are returned.
+		src := io.MultiReader(bytes.NewReader(make([]byte, 9)), bytes.NewReader([]byte{1}))
+		entropy = ulid.MonotonicWithOptions(src, 0, ulid.NoBuffer(), ulid.WithIncrement(ulid.GeometricIncrement(0.5, 0)))
+		ulid.MustNew(123, entropy)
+		if _, err := ulid.New(123, entropy); err != io.EOF {
+			t.Errorf("expected EOF, got %v", err)
+		}
+	})
+}
+
+// int63nReader counts the calls of the Int63n method of a math/rand source.
+type int63nReader struct {
+	*rand.Rand
+	calls int
+}
+
+func (r *int63nReader) Int63n(n int64) int64 {
+	r.calls++
+	return r.Rand.Int63n(n)
+}
+
+type zeroIncrement struct{}
+
+func (zeroIncrement) NextIncrement(io.Reader) (uint64, error) { return 0, nil }
@@ -0,0 +1,75 @@
+package ulid
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Provides a thread-safe source of entropy to assist with fast, concurrent access
+// to random data generation. Specify the type of entropy to use
+
+type PoolEntropy struct {
+	sync.Pool
+	counts  *entropyCounts // the bytes read by the pooled readers if counted
+	source  string         // the source of the pooled readers, for GeneratorInfo
+	epoch   atomic.Uint64  // the number of calls to Zeroize
+	made    atomic.Uint64  // the number of readers made by the pool, for Zeroize
+	tracked bool           // true if made counts the readers, i.e. the pool is from Pool
+}
+
+type MakeEntropy func() io.Reader
+
+var _ io.Reader = &PoolEntropy{}
+
+func Pool(entropy MakeEntropy) *PoolEntropy {
+	e := &PoolEntropy{tracked: true}
+	e.Pool.New = func() any {
+		e.made.Add(1)
+		return entropy()
+	}
+	return e
+}
+
+// Read reads from an entropy source from the pool. Read on a nil *PoolEntropy
+// returns ErrNilEntropy.
+func (e *PoolEntropy) Read(p []byte) (n int, err error) {
+	if e == nil {
+		return 0, ErrNilEntropy
+	}
+
+	epoch := e.epoch.Load()
+	r := e.get(epoch)
+	n, err = r.Read(p)
+	e.release(r, epoch)
+	return n, err
+}
+
+func (e *PoolEntropy) Get() io.Reader {
+	return e.get(e.epoch.Load())
+}
+
+func (e *PoolEntropy) Put(r io.Reader) {
+	e.Pool.Put(r)
+}
+
+// get takes a reader from the pool, scrubbing a monotonic reader that has not
+// been used since the pool was zeroized.
+func (e *PoolEntropy) get(epoch uint64) io.Reader {
+	r := e.Pool.Get().(io.Reader)
+	if m, ok := r.(*MonotonicEntropy); ok && m.epoch != epoch {
+		m.Zeroize()
+		m.epoch = epoch
+	}
+	return r
+}
+
+// release returns the reader to the pool unless the pool was zeroized while it
+// was in use, in which case it is scrubbed and dropped.
+func (e *PoolEntropy) release(r io.Reader, epoch uint64) {
+	if e.epoch.Load() != epoch {
+		zeroize(r)
+		return
+	}
+	e.Pool.Put(r)
+}
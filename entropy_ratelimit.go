@@ -0,0 +1,174 @@
+package ulid
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimitWait is the default maximum time that a RateLimitedReader
+// blocks waiting for the rate limit before returning ErrRateLimited.
+const DefaultRateLimitWait = time.Second
+
+// RateLimitedEntropy wraps an entropy source with a token bucket that allows limit
+// reads per second on average with bursts of up to burst reads, e.g. to cap the
+// rate at which ULIDs are issued on behalf of a tenant. Every Read or
+// MonotonicRead consumes exactly one token, regardless of the number of bytes,
+// so that one token is one ULID. If no token is available the read blocks until
+// one is, unless that would take longer than MaxWait, in which case the read
+// fails immediately with ErrRateLimited. A limit that is not positive allows
+// only the initial burst. The token of a read that fails is returned to the
+// bucket, so that failed reads do not count against the limit.
+//
+// The rate limited reader composes with Monotonic in either order: if it wraps
+// a MonotonicReader, MonotonicRead consumes one token and delegates, and if it
+// is passed to Monotonic, the monotonic reader consumes one token per
+// MonotonicRead and reads increment randomness directly from the underlying
+// source so it is not counted against the limit. To limit a pool of readers,
+// share a single rate limited reader between the readers in the pool or wrap
+// the pool itself.
+//
+// The token bucket is safe for concurrent use; however, the wrapped entropy must
+// also be safe for concurrent use if the rate limited reader is shared.
+func RateLimitedEntropy(entropy io.Reader, limit float64, burst int) *RateLimitedReader {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimitedReader{
+		MaxWait: DefaultRateLimitWait,
+		Now:     time.Now,
+		Sleep:   time.Sleep,
+		entropy: entropy,
+		limit:   limit,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+	}
+}
+
+// RateLimitedReader is a token bucket rate limited entropy source. The exported
+// fields may be modified to change its behavior but must not be modified
+// concurrently with reads.
+type RateLimitedReader struct {
+	// MaxWait is the maximum time a read will block waiting for a token; if the
+	// wait would be longer, the read fails fast with ErrRateLimited. A MaxWait of
+	// zero never blocks.
+	MaxWait time.Duration
+
+	// Now and Sleep are used to measure and wait for the refill of the bucket;
+	// they default to time.Now and time.Sleep and may be replaced, e.g. to
+	// simulate a clock in tests.
+	Now   func() time.Time
+	Sleep func(time.Duration)
+
+	mu      sync.Mutex
+	entropy io.Reader
+	limit   float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+var _ MonotonicReader = &RateLimitedReader{}
+
+// Read consumes a token, waiting up to MaxWait for one, then fills p from the
+// underlying entropy source.
+func (r *RateLimitedReader) Read(p []byte) (int, error) {
+	if err := r.take(r.MaxWait); err != nil {
+		return 0, err
+	}
+	return r.read(p)
+}
+
+// TryRead is like Read but never blocks: if no token is immediately available
+// it returns ErrRateLimited.
+func (r *RateLimitedReader) TryRead(p []byte) (int, error) {
+	if err := r.take(0); err != nil {
+		return 0, err
+	}
+	return r.read(p)
+}
+
+// read fills p from the underlying entropy source after a token was taken,
+// returning the token if the read fails.
+func (r *RateLimitedReader) read(p []byte) (n int, err error) {
+	if n, err = io.ReadFull(r.entropy, p); err != nil {
+		r.release()
+	}
+	return n, err
+}
+
+// MonotonicRead implements the MonotonicReader interface by consuming a token,
+// waiting up to MaxWait for one, and then reading monotonic entropy from the
+// underlying source if it is a MonotonicReader or reading it directly otherwise.
+func (r *RateLimitedReader) MonotonicRead(ms uint64, p []byte) (err error) {
+	if err = r.take(r.MaxWait); err != nil {
+		return err
+	}
+
+	if m, ok := r.entropy.(MonotonicReader); ok {
+		if err = m.MonotonicRead(ms, p); err != nil {
+			r.release()
+		}
+		return err
+	}
+
+	_, err = r.read(p)
+	return err
+}
+
+// Unwrap returns the underlying entropy source.
+func (r *RateLimitedReader) Unwrap() io.Reader {
+	if r == nil {
+		return nil
+	}
+	return r.entropy
+}
+
+func (r *RateLimitedReader) reserve() error {
+	return r.take(r.MaxWait)
+}
+
+// take consumes a token from the bucket, sleeping for the refill if a token will
+// be available within maxWait. The token is reserved before sleeping so that
+// concurrent readers wait in turn rather than racing for the same token.
+func (r *RateLimitedReader) take(maxWait time.Duration) error {
+	r.mu.Lock()
+	now := r.Now()
+	if r.last.IsZero() {
+		r.last = now
+	} else if elapsed := now.Sub(r.last); elapsed > 0 {
+		r.tokens = min(r.burst, r.tokens+elapsed.Seconds()*r.limit)
+		r.last = now
+	}
+
+	if r.tokens >= 1 {
+		r.tokens--
+		r.mu.Unlock()
+		return nil
+	}
+
+	if r.limit <= 0 {
+		r.mu.Unlock()
+		return ErrRateLimited
+	}
+
+	wait := time.Duration((1 - r.tokens) / r.limit * float64(time.Second))
+	if wait > maxWait {
+		r.mu.Unlock()
+		return ErrRateLimited
+	}
+
+	r.tokens--
+	r.mu.Unlock()
+
+	r.Sleep(wait)
+	return nil
+}
+
+// release returns a token that was taken for a read that failed to the bucket.
+func (r *RateLimitedReader) release() {
+	r.mu.Lock()
+	r.tokens = min(r.burst, r.tokens+1)
+	r.mu.Unlock()
+}
@@ -0,0 +1,227 @@
+package ulid_test
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// fakeClock is a simulated clock for rate limiting tests that advances when slept.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func rateLimited(entropy io.Reader, limit float64, burst int) (*ulid.RateLimitedReader, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := ulid.RateLimitedEntropy(entropy, limit, burst)
+	r.Now, r.Sleep = clock.Now, clock.Sleep
+	return r, clock
+}
+
+func TestRateLimitedEntropy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SteadyState", func(t *testing.T) {
+		r, clock := rateLimited(crand.Reader, 10, 1)
+		start := clock.Now()
+
+		p := make([]byte, 10)
+		for i := 0; i < 100; i++ {
+			if _, err := r.Read(p); err != nil {
+				t.Fatalf("read %d: %v", i, err)
+			}
+		}
+
+		// The first read uses the initial token, the other 99 wait 100ms each.
+		if elapsed := clock.Now().Sub(start); elapsed < 9800*time.Millisecond || elapsed > 9900*time.Millisecond {
+			t.Errorf("expected 100 reads at 10/s to take 9.9s, took %s", elapsed)
+		}
+	})
+
+	t.Run("Burst", func(t *testing.T) {
+		r, clock := rateLimited(crand.Reader, 1, 5)
+		p := make([]byte, 10)
+
+		tryReads := func() (n int) {
+			for {
+				if _, err := r.TryRead(p); err != nil {
+					if err != ulid.ErrRateLimited {
+						t.Fatalf("got err %v, want %v", err, ulid.ErrRateLimited)
+					}
+					return n
+				}
+				n++
+			}
+		}
+
+		if n := tryReads(); n != 5 {
+			t.Errorf("expected initial burst of 5 reads, got %d", n)
+		}
+
+		clock.Advance(time.Second)
+		if n := tryReads(); n != 1 {
+			t.Errorf("expected 1 read after 1s, got %d", n)
+		}
+
+		clock.Advance(time.Minute)
+		if n := tryReads(); n != 5 {
+			t.Errorf("expected refill to be capped at the burst of 5, got %d", n)
+		}
+
+		if clock.slept != 0 {
+			t.Errorf("expected TryRead to never sleep, slept %s", clock.slept)
+		}
+	})
+
+	t.Run("MaxWait", func(t *testing.T) {
+		r, clock := rateLimited(crand.Reader, 10, 1)
+		r.MaxWait = 50 * time.Millisecond
+
+		p := make([]byte, 10)
+		if _, err := r.Read(p); err != nil {
+			t.Fatal(err)
+		}
+
+		// The next token is 100ms away which is longer than the maximum wait.
+		if _, err := r.Read(p); err != ulid.ErrRateLimited {
+			t.Fatalf("got err %v, want %v", err, ulid.ErrRateLimited)
+		}
+
+		if clock.slept != 0 {
+			t.Errorf("expected fail fast without sleeping, slept %s", clock.slept)
+		}
+
+		r.MaxWait = 100 * time.Millisecond
+		if _, err := r.Read(p); err != nil {
+			t.Fatal(err)
+		}
+
+		if clock.slept != 100*time.Millisecond {
+			t.Errorf("expected to wait 100ms for the next token, slept %s", clock.slept)
+		}
+
+		r.MaxWait = 0
+		if _, err := ulid.New(ulid.Now(), r); err != ulid.ErrRateLimited {
+			t.Errorf("got err %v, want %v", err, ulid.ErrRateLimited)
+		}
+	})
+
+	t.Run("Monotonic", func(t *testing.T) {
+		// A limit of zero allows only the initial burst so that every token is
+		// accounted for, even though each increment reads randomness.
+		wrapped, _ := rateLimited(ulid.Monotonic(crand.Reader, 0), 0, 10)
+		limited, _ := rateLimited(crand.Reader, 0, 10)
+
+		for name, entropy := range map[string]io.Reader{
+			"LimitedMonotonic": wrapped,
+			"MonotonicLimited": ulid.Monotonic(limited, 0),
+		} {
+			ms := ulid.Now()
+			var prev ulid.ULID
+			for i := 0; i < 10; i++ {
+				id, err := ulid.New(ms, entropy)
+				if err != nil {
+					t.Fatalf("%s: ulid %d: %v", name, i, err)
+				}
+
+				if id.Compare(prev) <= 0 {
+					t.Fatalf("%s: expected monotonic ulids", name)
+				}
+				prev = id
+			}
+
+			if _, err := ulid.New(ms, entropy); err != ulid.ErrRateLimited {
+				t.Errorf("%s: got err %v, want %v", name, err, ulid.ErrRateLimited)
+			}
+		}
+	})
+
+	t.Run("FailedRead", func(t *testing.T) {
+		// A limit of zero allows only the burst of one, so every read after the
+		// first failed read of each reader would be rate limited without a refund.
+		errBroken := errors.New("broken")
+		direct, _ := rateLimited(&scriptedReader{script: []error{errBroken}}, 0, 1)
+		source, _ := rateLimited(&scriptedReader{script: []error{errBroken}}, 0, 1)
+
+		for name, entropy := range map[string]io.Reader{
+			"Read":      direct,
+			"Monotonic": ulid.MonotonicWithOptions(source, 0, ulid.NoBuffer()),
+		} {
+			if _, err := ulid.New(ulid.Now(), entropy); !errors.Is(err, errBroken) {
+				t.Fatalf("%s: got err %v, want %v", name, err, errBroken)
+			}
+
+			if _, err := ulid.New(ulid.Now(), entropy); err != nil {
+				t.Errorf("%s: expected the token of the failed read to be returned: %v", name, err)
+			}
+
+			if _, err := ulid.New(ulid.Now(), entropy); err != ulid.ErrRateLimited {
+				t.Errorf("%s: got err %v, want %v", name, err, ulid.ErrRateLimited)
+			}
+		}
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		shared, _ := rateLimited(crand.Reader, 0, 100)
+		pool := ulid.Pool(func() io.Reader {
+			return &ulid.LockedMonotonicReader{MonotonicReader: ulid.Monotonic(shared, 0)}
+		})
+
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			issued int
+		)
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					r := pool.Get().(ulid.MonotonicReader)
+					_, err := ulid.New(ulid.Now(), r)
+					pool.Put(r)
+
+					if err == nil {
+						mu.Lock()
+						issued++
+						mu.Unlock()
+					} else if err != ulid.ErrRateLimited {
+						t.Error(err)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		if issued != 100 {
+			t.Errorf("expected the pool to share the burst of 100, issued %d", issued)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+package ulid
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Defaults of the options of ResilientEntropy.
+const (
+	DefaultResilientRetries = 3
+	DefaultResilientBackoff = time.Millisecond
+	DefaultResilientMaxWait = 100 * time.Millisecond
+	DefaultBreakerFailures  = 5
+	DefaultBreakerCooldown  = time.Second
+)
+
+// ResilientOption configures a ResilientReader returned by ResilientEntropy.
+type ResilientOption func(*ResilientReader)
+
+// ResilientRetries sets the number of times that a read is retried after a
+// transient error before the error is returned (default
+// DefaultResilientRetries). Zero disables retries; negative values use the
+// default.
+func ResilientRetries(n int) ResilientOption {
+	return func(r *ResilientReader) {
+		if n >= 0 {
+			r.retries = n
+		}
+	}
+}
+
+// ResilientBackoff sets the wait before the first retry of a read, which doubles
+// with each further retry up to maxWait (default DefaultResilientBackoff and
+// DefaultResilientMaxWait). Values that are not positive use the defaults.
+func ResilientBackoff(initial, maxWait time.Duration) ResilientOption {
+	return func(r *ResilientReader) {
+		if initial > 0 {
+			r.backoff = initial
+		}
+		if maxWait > 0 {
+			r.maxWait = maxWait
+		}
+	}
+}
+
+// ResilientTransient sets the function that classifies the errors of the source
+// that are retried (default IsTransientError); other errors are returned
+// immediately. A nil function uses the default.
+func ResilientTransient(transient func(error) bool) ResilientOption {
+	return func(r *ResilientReader) {
+		if transient != nil {
+			r.transient = transient
+		}
+	}
+}
+
+// ResilientBreaker sets the number of consecutive failed reads after which the
+// circuit breaker opens and the time that it stays open before the source is
+// read again (default DefaultBreakerFailures and DefaultBreakerCooldown). Values
+// that are not positive use the defaults.
+func ResilientBreaker(failures int, cooldown time.Duration) ResilientOption {
+	return func(r *ResilientReader) {
+		if failures > 0 {
+			r.failures = failures
+		}
+		if cooldown > 0 {
+			r.cooldown = cooldown
+		}
+	}
+}
+
+// ResilientClock sets the functions used to measure the cooldown of the circuit
+// breaker and to wait between retries (default time.Now and time.Sleep), e.g. to
+// simulate a clock in tests. Nil functions use the defaults.
+func ResilientClock(now func() time.Time, sleep func(time.Duration)) ResilientOption {
+	return func(r *ResilientReader) {
+		if now != nil {
+			r.now = now
+		}
+		if sleep != nil {
+			r.sleep = sleep
+		}
+	}
+}
+
+// IsTransientError reports whether the error is temporary, i.e. if it or an error
+// that it wraps has a Temporary method that returns true, such as the EAGAIN and
+// EINTR errors of a syscall. It is the default classification of ResilientEntropy.
+func IsTransientError(err error) bool {
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// ResilientEntropy wraps an entropy source that intermittently fails, e.g.
+// crypto/rand on platforms that return EAGAIN under entropy pressure, with
+// bounded retries of transient errors and exponential backoff between them, so
+// that callers only see an error if the source keeps failing. Each Read fills p
+// completely or returns the last error of the source once the retries are
+// exhausted or if an error is not transient.
+//
+// After a number of consecutive failed reads the circuit breaker opens: Healthy
+// reports false and reads fail fast with an error wrapping ErrEntropyUnavailable
+// and the last error of the source, without reading it, until the cooldown has
+// elapsed. The next read then tries the source again; if it succeeds the breaker
+// closes and otherwise it opens for another cooldown.
+//
+// Like CountingEntropy, wrap the source that is passed to Monotonic rather than
+// the monotonic entropy so that the retries happen below its buffer, and share a
+// single ResilientReader between the readers of a pool so that the breaker
+// reflects the health of the source, e.g. for MakeSecure:
+//
+//	source := ulid.ResilientEntropy(crand.Reader)
+//	ulid.SetSecureEntropy(ulid.Pool(func() io.Reader {
+//		return ulid.Monotonic(source, 0)
+//	}))
+//
+// MakeSecure still panics if a read fails after the retries or while the breaker
+// is open; use NewAt with SecureEntropy to handle the errors instead. The
+// ResilientReader is safe for concurrent use if the source is.
+func ResilientEntropy(entropy io.Reader, opts ...ResilientOption) *ResilientReader {
+	r := &ResilientReader{
+		entropy:   entropy,
+		retries:   DefaultResilientRetries,
+		backoff:   DefaultResilientBackoff,
+		maxWait:   DefaultResilientMaxWait,
+		transient: IsTransientError,
+		failures:  DefaultBreakerFailures,
+		cooldown:  DefaultBreakerCooldown,
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ResilientReader retries the transient errors of an entropy source and stops
+// reading it while it keeps failing, returned by ResilientEntropy.
+type ResilientReader struct {
+	entropy   io.Reader
+	retries   int
+	backoff   time.Duration
+	maxWait   time.Duration
+	transient func(error) bool
+	failures  int
+	cooldown  time.Duration
+	now       func() time.Time
+	sleep     func(time.Duration)
+
+	mu       sync.Mutex
+	failed   int       // the number of consecutive failed reads
+	openedAt time.Time // when the breaker opened, or zero if it is closed
+	lastErr  error
+	retried  uint64
+}
+
+var _ MonotonicReader = &ResilientReader{}
+
+// Read fills p from the entropy source, retrying transient errors from where the
+// failed read stopped.
+func (r *ResilientReader) Read(p []byte) (n int, err error) {
+	err = r.do(func() error {
+		m, err := io.ReadFull(r.entropy, p[n:])
+		n += m
+		return err
+	})
+	return n, err
+}
+
+// MonotonicRead implements the MonotonicReader interface by reading monotonic
+// entropy from the source if it is a MonotonicReader, retrying transient errors,
+// and by filling p with Read otherwise.
+func (r *ResilientReader) MonotonicRead(ms uint64, p []byte) error {
+	m, ok := r.entropy.(MonotonicReader)
+	if !ok {
+		_, err := r.Read(p)
+		return err
+	}
+
+	return r.do(func() error {
+		return m.MonotonicRead(ms, p)
+	})
+}
+
+// Unwrap returns the entropy source whose errors are retried.
+func (r *ResilientReader) Unwrap() io.Reader {
+	if r == nil {
+		return nil
+	}
+	return r.entropy
+}
+
+// Healthy returns false if the circuit breaker is open, i.e. if the source failed
+// repeatedly and has not been read successfully since.
+func (r *ResilientReader) Healthy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.openedAt.IsZero()
+}
+
+// LastError returns the most recent error of the source, including transient
+// errors that were retried successfully, or nil if it has never failed.
+func (r *ResilientReader) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// Retries returns the number of reads of the source that were retried after a
+// transient error.
+func (r *ResilientReader) Retries() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.retried
+}
+
+// do makes the attempts of a read while the breaker allows it, sleeping between
+// the retries of transient errors, and records the result for the breaker.
+func (r *ResilientReader) do(attempt func() error) (err error) {
+	r.mu.Lock()
+	if !r.openedAt.IsZero() && r.now().Sub(r.openedAt) < r.cooldown {
+		err = fmt.Errorf("%w: %w", ErrEntropyUnavailable, r.lastErr)
+		r.mu.Unlock()
+		return err
+	}
+	r.mu.Unlock()
+
+	wait := r.backoff
+	for i := 0; ; i++ {
+		if err = attempt(); err == nil || i == r.retries || !r.transient(err) {
+			break
+		}
+
+		r.mu.Lock()
+		r.lastErr = err
+		r.retried++
+		r.mu.Unlock()
+
+		r.sleep(wait)
+		wait = min(2*wait, r.maxWait)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.failed, r.openedAt = 0, time.Time{}
+		return nil
+	}
+
+	// A failure after the cooldown opens the breaker again immediately.
+	r.lastErr = err
+	r.failed++
+	if r.failed >= r.failures || !r.openedAt.IsZero() {
+		r.openedAt = r.now()
+	}
+	return err
+}
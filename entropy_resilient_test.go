@@ -0,0 +1,258 @@
+package ulid_test
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// scriptedReader fails the reads of its script with the scripted errors in order
+// and reads from crypto/rand for nil entries and once the script is exhausted.
+type scriptedReader struct {
+	mu     sync.Mutex
+	script []error
+	reads  int
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reads++; r.reads <= len(r.script) && r.script[r.reads-1] != nil {
+		return 0, r.script[r.reads-1]
+	}
+	return crand.Read(p)
+}
+
+func (r *scriptedReader) Reads() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+// transientError is a portable stand-in for the EAGAIN and EINTR errors of a
+// syscall, which are temporary on the platforms that have them.
+type transientError struct {
+	msg     string
+	timeout bool
+}
+
+func (e transientError) Error() string   { return e.msg }
+func (e transientError) Temporary() bool { return true }
+func (e transientError) Timeout() bool   { return e.timeout }
+
+var (
+	errAgain = transientError{"resource temporarily unavailable", true}
+	errIntr  = transientError{"interrupted system call", false}
+)
+
+// repeatErr returns a script of n failures with the error.
+func repeatErr(err error, n int) []error {
+	script := make([]error, n)
+	for i := range script {
+		script[i] = err
+	}
+	return script
+}
+
+func TestResilientEntropy(t *testing.T) {
+	t.Parallel()
+
+	eagain := &os.PathError{Op: "read", Path: "/dev/urandom", Err: errAgain}
+	errBroken := errors.New("entropy device is broken")
+
+	testCases := []struct {
+		name    string
+		script  []error
+		opts    []ulid.ResilientOption
+		err     error
+		reads   int
+		retries uint64
+		waits   []time.Duration
+	}{
+		{"NoErrors", nil, nil, nil, 1, 0, nil},
+		{"Transient", []error{eagain, errIntr}, nil, nil, 3, 2, []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+		{"Exhausted", repeatErr(eagain, 4), nil, errAgain, 4, 3, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}},
+		{"MaxWait", repeatErr(eagain, 5), []ulid.ResilientOption{ulid.ResilientRetries(4), ulid.ResilientBackoff(10*time.Millisecond, 25*time.Millisecond)}, errAgain, 5, 4, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond, 25 * time.Millisecond}},
+		{"NoRetries", []error{eagain}, []ulid.ResilientOption{ulid.ResilientRetries(0)}, errAgain, 1, 0, nil},
+		{"DefaultRetries", repeatErr(eagain, 3), []ulid.ResilientOption{ulid.ResilientRetries(-1), ulid.ResilientBackoff(0, -1)}, nil, 4, 3, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}},
+		{"Permanent", []error{eagain, errBroken}, nil, errBroken, 2, 1, []time.Duration{time.Millisecond}},
+		{"Classified", []error{errBroken, errBroken}, []ulid.ResilientOption{ulid.ResilientTransient(func(err error) bool { return err == errBroken })}, nil, 3, 2, []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := &scriptedReader{script: tc.script}
+			var waits []time.Duration
+			sleep := func(d time.Duration) { waits = append(waits, d) }
+
+			r := ulid.ResilientEntropy(src, append(tc.opts, ulid.ResilientClock(nil, sleep))...)
+			p := make([]byte, 16)
+			n, err := r.Read(p)
+			if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+
+			if err == nil && (n != len(p) || bytes.Equal(p, make([]byte, 16))) {
+				t.Errorf("expected p to be filled, got %d bytes %x", n, p)
+			}
+
+			if src.Reads() != tc.reads || r.Retries() != tc.retries || !slices.Equal(waits, tc.waits) {
+				t.Errorf("got %d reads, %d retries, and waits %v, want %d, %d, and %v", src.Reads(), r.Retries(), waits, tc.reads, tc.retries, tc.waits)
+			}
+
+			if last := r.LastError(); (last == nil) != (tc.script == nil) {
+				t.Errorf("unexpected last error %v", last)
+			}
+
+			if !r.Healthy() {
+				t.Errorf("expected a single failed read not to open the breaker")
+			}
+		})
+	}
+
+	t.Run("PartialReads", func(t *testing.T) {
+		// The retry continues from the bytes already read.
+		data := make([]byte, 16)
+		crand.Read(data)
+
+		src := iotest.TimeoutReader(iotest.HalfReader(bytes.NewReader(data)))
+		r := ulid.ResilientEntropy(src, ulid.ResilientTransient(func(err error) bool { return err == iotest.ErrTimeout }), ulid.ResilientClock(nil, func(time.Duration) {}))
+
+		p := make([]byte, 16)
+		if n, err := r.Read(p); err != nil || n != 16 || !bytes.Equal(p, data) || r.Retries() != 1 {
+			t.Errorf("got %x (%d bytes, %v, %d retries), want %x", p, n, err, r.Retries(), data)
+		}
+	})
+}
+
+func TestResilientEntropyBreaker(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	src := &scriptedReader{script: append(repeatErr(errAgain, 3), nil, errAgain)}
+	r := ulid.ResilientEntropy(src, ulid.ResilientRetries(0), ulid.ResilientBreaker(3, time.Second), ulid.ResilientClock(clock.Now, clock.Sleep))
+
+	read := func() error {
+		_, err := r.Read(make([]byte, 16))
+		return err
+	}
+
+	// The breaker opens after three consecutive failures.
+	for i := 0; i < 3; i++ {
+		if !r.Healthy() {
+			t.Fatalf("expected the breaker to be closed after %d failures", i)
+		}
+
+		if err := read(); err != errAgain {
+			t.Fatalf("read %d: got %v, want %v", i, err, errAgain)
+		}
+	}
+
+	if r.Healthy() || r.LastError() != errAgain {
+		t.Fatalf("expected the breaker to be open with the last error, got %t and %v", r.Healthy(), r.LastError())
+	}
+
+	// While it is open reads fail fast without reading the source.
+	clock.Advance(time.Second - time.Millisecond)
+	if err := read(); !errors.Is(err, ulid.ErrEntropyUnavailable) || !errors.Is(err, errAgain) || src.Reads() != 3 {
+		t.Fatalf("got %v after %d reads, want %v", err, src.Reads(), ulid.ErrEntropyUnavailable)
+	}
+
+	// After the cooldown, a successful read closes the breaker.
+	clock.Advance(time.Millisecond)
+	if err := read(); err != nil || !r.Healthy() || src.Reads() != 4 {
+		t.Fatalf("got %v after %d reads, expected the breaker to close", err, src.Reads())
+	}
+
+	// A single failure does not open the breaker again once it has closed, but a
+	// failure after the cooldown reopens it immediately.
+	if err := read(); err != errAgain || !r.Healthy() {
+		t.Fatalf("got %v, expected the breaker to stay closed", err)
+	}
+
+	src.mu.Lock()
+	src.script = append(src.script, repeatErr(errAgain, 3)...)
+	src.mu.Unlock()
+	read()
+	read()
+	if r.Healthy() {
+		t.Fatal("expected the breaker to open again")
+	}
+
+	clock.Advance(time.Second)
+	if err := read(); err != errAgain || r.Healthy() {
+		t.Fatalf("got %v, expected the breaker to reopen after a failed trial", err)
+	}
+
+	if clock.slept != 0 {
+		t.Errorf("expected no backoff without retries, slept %s", clock.slept)
+	}
+}
+
+func TestResilientEntropyComposition(t *testing.T) {
+	t.Parallel()
+
+	// A monotonic reader of a resilient source generates ULIDs despite the
+	// transient errors.
+	src := &scriptedReader{script: []error{errAgain, nil, errIntr, errAgain}}
+	resilient := ulid.ResilientEntropy(src, ulid.ResilientClock(nil, func(time.Duration) {}))
+	entropy := ulid.MonotonicWithOptions(resilient, 0, ulid.NoBuffer())
+
+	var prev ulid.ULID
+	for i := 0; i < 16; i++ {
+		id, err := ulid.New(1000+uint64(i/4), entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i > 0 && id.Compare(prev) <= 0 {
+			t.Fatalf("expected monotonic ULIDs, got %s after %s", id, prev)
+		}
+		prev = id
+	}
+
+	if retries := resilient.Retries(); retries != 3 {
+		t.Errorf("expected 3 retries, got %d", retries)
+	}
+
+	// Wrapping a MonotonicReader retries its MonotonicRead.
+	seq := ulid.ResilientEntropy(ulid.SequentialEntropy([10]byte{}))
+	for i := byte(0); i < 3; i++ {
+		if id, err := ulid.New(1000, seq); err != nil || id.Entropy()[9] != i {
+			t.Fatalf("got %s (%v), want sequential entropy", id, err)
+		}
+	}
+
+	if !ulid.IsTransientError(fmt.Errorf("read: %w", errAgain)) || ulid.IsTransientError(io.ErrUnexpectedEOF) {
+		t.Error("unexpected classification of errors")
+	}
+}
+
+func TestResilientSecureEntropy(t *testing.T) {
+	// MakeSecure does not panic on the transient errors of its replacement source.
+	src := &scriptedReader{script: []error{errAgain, nil, errIntr}}
+	original := ulid.SetSecureEntropy(ulid.Pool(func() io.Reader {
+		return ulid.MonotonicWithOptions(ulid.ResilientEntropy(src, ulid.ResilientClock(nil, func(time.Duration) {})), 0, ulid.NoBuffer())
+	}))
+	defer ulid.SetSecureEntropy(original)
+
+	for i := 0; i < 8; i++ {
+		if id := ulid.MakeSecureAt(time.UnixMilli(int64(1000 + i))); id.IsZero() {
+			t.Fatal("expected a ULID to be made")
+		}
+	}
+
+	if reads := src.Reads(); reads != 10 {
+		t.Errorf("expected 10 reads with the retries, got %d", reads)
+	}
+}
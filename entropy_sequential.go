@@ -0,0 +1,85 @@
+package ulid
+
+//===========================================================================
+// Sequential Entropy
+//===========================================================================
+
+// SequentialEntropy returns a MonotonicReader whose entropy starts at the given
+// value and increments by exactly 1 on every read, regardless of the timestamp.
+// Unlike Monotonic, the counter carries across milliseconds so that a gap in the
+// entropy sequence proves that an ID is missing (see Gap). Once the 80-bit
+// counter has issued its maximum value, further reads return
+// ErrMonotonicOverflow.
+//
+// Sequential entropy is entirely predictable and should not be used where ULIDs
+// need to be unguessable. The returned type isn't safe for concurrent use; wrap
+// it in a LockedMonotonicReader if it is shared between go routines.
+func SequentialEntropy(start [10]byte) *SequentialReader {
+	r := &SequentialReader{}
+	r.next.SetBytes(start[:])
+	return r
+}
+
+// SequentialReader is an opaque type that provides sequential entropy.
+type SequentialReader struct {
+	next      uint80
+	exhausted bool
+}
+
+var _ MonotonicReader = &SequentialReader{}
+
+// MonotonicRead implements the MonotonicReader interface, writing the next value
+// of the counter into p, which must be 10 bytes long. The ms parameter is
+// ignored since the counter carries across milliseconds.
+func (r *SequentialReader) MonotonicRead(ms uint64, p []byte) error {
+	if len(p) != 10 {
+		return ErrBufferSize
+	}
+
+	if r.exhausted {
+		recordOverflow(ms)
+		return ErrMonotonicOverflow
+	}
+
+	r.next.AppendTo(p)
+	r.exhausted = r.next.Add(1)
+	return nil
+}
+
+// Read implements io.Reader by writing the next value of the counter into p,
+// which must be 10 bytes long.
+func (r *SequentialReader) Read(p []byte) (int, error) {
+	if err := r.MonotonicRead(0, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Counter returns the next entropy value that will be issued by the reader. It
+// can be stored as a checkpoint and passed to SequentialEntropy to resume the
+// sequence. The boolean is false if the counter has been exhausted.
+func (r *SequentialReader) Counter() (next [10]byte, ok bool) {
+	r.next.AppendTo(next[:])
+	return next, !r.exhausted
+}
+
+// Gap returns the entropy distance from a to b, e.g. a distance of 1 means that
+// b immediately follows a in a sequential entropy stream and no IDs are missing.
+// The boolean is false if the ULIDs are not comparable: either the timestamp of
+// b is not equal to or 1ms after the timestamp of a, b's entropy does not follow
+// a's entropy, or the distance does not fit in a uint64.
+func Gap(a, b ULID) (uint64, bool) {
+	if ta, tb := a.Time(), b.Time(); tb != ta && tb != ta+1 {
+		return 0, false
+	}
+
+	var ea, eb uint80
+	ea.SetBytes(a[6:])
+	eb.SetBytes(b[6:])
+
+	d, borrow := eb.Sub(ea)
+	if borrow || d.Hi != 0 || d.Lo == 0 {
+		return 0, false
+	}
+	return d.Lo, true
+}
@@ -0,0 +1,122 @@
+package ulid_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestSequentialEntropy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Continuity", func(t *testing.T) {
+		entropy := ulid.SequentialEntropy([10]byte{9: 0xFE})
+
+		var prev ulid.ULID
+		for i, ms := range []uint64{100, 100, 101, 101, 102, 105} {
+			id, err := ulid.New(ms, entropy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if i == 0 {
+				if want := []byte{9: 0xFE}; !bytes.Equal(id.Entropy(), want) {
+					t.Fatalf("got first entropy %x, want %x", id.Entropy(), want)
+				}
+			} else if gap, ok := ulid.Gap(prev, id); ms-prev.Time() <= 1 && (!ok || gap != 1) {
+				t.Fatalf("expected gap of 1 between %x and %x, got %d (%t)", prev.Entropy(), id.Entropy(), gap, ok)
+			}
+
+			if prev.Compare(id) >= 0 {
+				t.Fatalf("expected %s < %s", prev, id)
+			}
+			prev = id
+		}
+
+		next, ok := entropy.Counter()
+		if !ok {
+			t.Fatal("expected counter to not be exhausted")
+		}
+
+		if want := [10]byte{8: 0x01, 9: 0x04}; next != want {
+			t.Errorf("got counter %x, want %x", next, want)
+		}
+
+		// Resuming from a checkpoint continues the sequence.
+		id := ulid.MustNew(prev.Time(), ulid.SequentialEntropy(next))
+		if gap, ok := ulid.Gap(prev, id); !ok || gap != 1 {
+			t.Errorf("expected resumed sequence to have a gap of 1, got %d (%t)", gap, ok)
+		}
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		var start [10]byte
+		for i := range start {
+			start[i] = 0xFF
+		}
+		start[9] = 0xFE
+
+		entropy := ulid.SequentialEntropy(start)
+		for i := 0; i < 2; i++ {
+			if _, err := ulid.New(1, entropy); err != nil {
+				t.Fatalf("read %d: %v", i, err)
+			}
+		}
+
+		if _, ok := entropy.Counter(); ok {
+			t.Error("expected counter to be exhausted")
+		}
+
+		if _, err := ulid.New(2, entropy); err != ulid.ErrMonotonicOverflow {
+			t.Errorf("got err %v, want %v", err, ulid.ErrMonotonicOverflow)
+		}
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		entropy := ulid.SequentialEntropy([10]byte{})
+		buf := make([]byte, 10)
+		if n, err := entropy.Read(buf); err != nil || n != 10 {
+			t.Fatalf("got n=%d err=%v", n, err)
+		}
+
+		if _, err := entropy.Read(make([]byte, 4)); err != ulid.ErrBufferSize {
+			t.Errorf("got err %v, want %v", err, ulid.ErrBufferSize)
+		}
+	})
+}
+
+func TestGap(t *testing.T) {
+	t.Parallel()
+
+	mk := func(ms uint64, e ...byte) ulid.ULID {
+		var entropy [10]byte
+		copy(entropy[10-len(e):], e)
+		return ulid.MustNew(ms, bytes.NewReader(entropy[:]))
+	}
+
+	for _, tc := range []struct {
+		name string
+		a, b ulid.ULID
+		gap  uint64
+		ok   bool
+	}{
+		{"Consecutive", mk(10, 0x01), mk(10, 0x02), 1, true},
+		{"Missing", mk(10, 0x01), mk(10, 0x05), 4, true},
+		{"AdjacentMillisecond", mk(10, 0x01), mk(11, 0x02), 1, true},
+		{"Carry", mk(10, 0x00, 0xFF), mk(10, 0x01, 0x00), 1, true},
+		{"WideCarry", mk(10, 0x01, 0, 0, 0, 0, 0, 0, 0, 0), mk(10, 0x02, 0, 0, 0, 0, 0, 0, 0, 0), 0, false},
+		{"LargeHigh", mk(10, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF), mk(10, 0x02, 0, 0, 0, 0, 0, 0, 0, 0), 1, true},
+		{"Equal", mk(10, 0x01), mk(10, 0x01), 0, false},
+		{"Reversed", mk(10, 0x05), mk(10, 0x01), 0, false},
+		{"DistantTime", mk(10, 0x01), mk(12, 0x02), 0, false},
+		{"ReversedTime", mk(11, 0x01), mk(10, 0x02), 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gap, ok := ulid.Gap(tc.a, tc.b)
+			if gap != tc.gap || ok != tc.ok {
+				t.Errorf("got (%d, %t), want (%d, %t)", gap, ok, tc.gap, tc.ok)
+			}
+		})
+	}
+}
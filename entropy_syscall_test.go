@@ -0,0 +1,32 @@
+//go:build !plan9
+
+package ulid_test
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestIsTransientErrorSyscall(t *testing.T) {
+	t.Parallel()
+
+	// The EAGAIN and EINTR errors of a read are transient, even when wrapped.
+	for _, err := range []error{
+		syscall.EAGAIN,
+		syscall.EINTR,
+		&os.PathError{Op: "read", Path: "/dev/urandom", Err: syscall.EAGAIN},
+		fmt.Errorf("read: %w", syscall.EINTR),
+	} {
+		if !ulid.IsTransientError(err) {
+			t.Errorf("expected %v to be transient", err)
+		}
+	}
+
+	if ulid.IsTransientError(syscall.EBADF) {
+		t.Errorf("expected %v not to be transient", syscall.EBADF)
+	}
+}
@@ -3,9 +3,7 @@ package ulid_test
 import (
 	"bytes"
 	crand "crypto/rand"
-	"fmt"
 	"io"
-	"math"
 	"math/rand"
 	"sync"
 	"testing"
@@ -14,127 +12,259 @@ import (
 	"go.rtnl.ai/ulid"
 )
 
-func TestPoolEntropy(t *testing.T) {
-	wg := sync.WaitGroup{}
-	entropy := ulid.Pool(func() io.Reader { return rand.New(rand.NewSource(time.Now().UnixNano())) })
+func TestNilEntropyReceivers(t *testing.T) {
+	t.Parallel()
 
-	for i := 0; i < 8; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := 0; i < 128; i++ {
-				uu, err := ulid.New(ulid.Now(), entropy)
-				if err != nil {
-					t.Errorf("could not create ulid: %s", err)
-				}
+	var (
+		monotonic *ulid.MonotonicEntropy
+		locked    *ulid.LockedMonotonicReader
+		pool      *ulid.PoolEntropy
+	)
 
-				if uu.IsZero() {
-					t.Error("expected ulid to not be null")
-				}
-			}
-		}()
+	buf := make([]byte, 10)
+	if err := monotonic.MonotonicRead(1, buf); err != ulid.ErrNilEntropy {
+		t.Errorf("MonotonicEntropy: got %v, want %v", err, ulid.ErrNilEntropy)
 	}
 
-	wg.Wait()
+	if err := locked.MonotonicRead(1, buf); err != ulid.ErrNilEntropy {
+		t.Errorf("LockedMonotonicReader: got %v, want %v", err, ulid.ErrNilEntropy)
+	}
+
+	// A locked reader wrapping a nil reader is also rejected.
+	locked = &ulid.LockedMonotonicReader{MonotonicReader: monotonic}
+	if err := locked.MonotonicRead(1, buf); err != ulid.ErrNilEntropy {
+		t.Errorf("LockedMonotonicReader wrapping nil: got %v, want %v", err, ulid.ErrNilEntropy)
+	}
+
+	if n, err := pool.Read(buf); n != 0 || err != ulid.ErrNilEntropy {
+		t.Errorf("PoolEntropy: got %d, %v, want 0, %v", n, err, ulid.ErrNilEntropy)
+	}
+}
+
+// countingReader is a concurrency safe reader that counts the number of reads.
+type countingReader struct {
+	mu    sync.Mutex
+	reads int
+	r     io.Reader
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads++
+	return c.r.Read(p)
+}
+
+func (c *countingReader) Reads() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reads
 }
 
-func TestMonotonic(t *testing.T) {
-	now := ulid.Now()
-	for _, e := range []struct {
-		name string
-		mk   func() io.Reader
+// NOTE: the tests that replace the package entropy sources are not parallel so
+// that they do not interfere with other tests that call Make or MakeSecure.
+func TestSetEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		set     func(io.Reader) io.Reader
+		get     func() io.Reader
+		make    func() ulid.ULID
+		mustNew func(time.Time) ulid.ULID
 	}{
-		{"cryptorand", func() io.Reader { return crand.Reader }},
-		{"mathrand", func() io.Reader { return rand.New(rand.NewSource(int64(now))) }},
+		{"Default", ulid.SetDefaultEntropy, ulid.DefaultEntropy, ulid.Make, ulid.MustNewDefault},
+		{"Secure", ulid.SetSecureEntropy, ulid.SecureEntropy, ulid.MakeSecure, ulid.MustNewSecure},
 	} {
-		for _, inc := range []uint64{
-			0,
-			1,
-			2,
-			math.MaxUint8 + 1,
-			math.MaxUint16 + 1,
-			math.MaxUint32 + 1,
-		} {
-			inc := inc
-			entropy := ulid.Monotonic(e.mk(), uint64(inc))
-
-			t.Run(fmt.Sprintf("entropy=%s/inc=%d", e.name, inc), func(t *testing.T) {
-				t.Parallel()
-
-				var prev ulid.ULID
-				for i := 0; i < 10000; i++ {
-					next, err := ulid.New(123, entropy)
-					if err != nil {
-						t.Fatal(err)
-					}
+		t.Run(tc.name, func(t *testing.T) {
+			original := tc.get()
+			counter := &countingReader{r: crand.Reader}
 
-					if prev.Compare(next) >= 0 {
-						t.Fatalf("prev: %v %v > next: %v %v",
-							prev.Time(), prev.Entropy(), next.Time(), next.Entropy())
-					}
+			if prev := tc.set(counter); prev != original {
+				t.Fatalf("expected the previous entropy source to be returned")
+			}
 
-					prev = next
-				}
-			})
-		}
-	}
-}
+			if tc.get() != counter {
+				t.Fatalf("expected the entropy source to be replaced")
+			}
 
-func TestMonotonicOverflow(t *testing.T) {
-	t.Parallel()
+			tc.make()
+			tc.mustNew(time.Now())
+			if reads := counter.Reads(); reads != 2 {
+				t.Errorf("expected 2 reads from the replacement entropy, got %d", reads)
+			}
 
-	entropy := ulid.Monotonic(
-		io.MultiReader(
-			bytes.NewReader(bytes.Repeat([]byte{0xFF}, 10)), // Entropy for first ULID
-			crand.Reader, // Following random entropy
-		),
-		0,
-	)
+			if prev := tc.set(original); prev != counter {
+				t.Fatalf("expected the replacement entropy source to be returned")
+			}
 
-	prev, err := ulid.New(0, entropy)
-	if err != nil {
-		t.Fatal(err)
+			tc.make()
+			if reads := counter.Reads(); reads != 2 {
+				t.Errorf("expected no reads after restoring the entropy, got %d", reads)
+			}
+
+			testPanics(t, ulid.ErrNilEntropy, func() { tc.set(nil) })
+			if tc.get() != original {
+				t.Errorf("expected a nil entropy source to be rejected")
+			}
+		})
 	}
+}
+
+// fingerprints is an in-memory fingerprint store for VerifyFreshEntropy.
+type fingerprints struct {
+	fp    uint64
+	ok    bool
+	err   error
+	saves int
+}
 
-	next, err := ulid.New(prev.Time(), entropy)
-	if have, want := err, ulid.ErrMonotonicOverflow; have != want {
-		t.Errorf("have ulid: %v %v err: %v, want err: %v",
-			next.Time(), next.Entropy(), have, want)
+func (f *fingerprints) load() (uint64, bool, error) {
+	return f.fp, f.ok, f.err
+}
+
+func (f *fingerprints) store(fp uint64) error {
+	if f.err != nil {
+		return f.err
 	}
+	f.fp, f.ok = fp, true
+	f.saves++
+	return nil
 }
 
-func TestMonotonicSafe(t *testing.T) {
-	t.Parallel()
+func TestVerifyFreshEntropy(t *testing.T) {
+	original := ulid.DefaultEntropy()
+	defer ulid.SetDefaultEntropy(original)
 
-	var (
-		rng  = rand.New(rand.NewSource(time.Now().UnixNano()))
-		safe = &ulid.LockedMonotonicReader{MonotonicReader: ulid.Monotonic(rng, 0)}
-		t0   = ulid.Timestamp(time.Now())
-	)
+	t.Run("Fresh", func(t *testing.T) {
+		store := &fingerprints{}
+		for i := 0; i < 3; i++ {
+			if err := ulid.VerifyFreshEntropy(store.load, store.store); err != nil {
+				t.Fatalf("expected fresh entropy, got %v", err)
+			}
+		}
+
+		if store.saves != 3 || store.fp == 0 {
+			t.Errorf("expected a new fingerprint to be stored by each verification")
+		}
+
+		if ulid.EntropyFingerprint() == ulid.EntropyFingerprint() {
+			t.Errorf("expected each fingerprint to sample new entropy")
+		}
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		// Restoring a snapshot is simulated by creating readers with the same seed.
+		seeded := func() io.Reader {
+			return ulid.Pool(func() io.Reader { return ulid.Monotonic(rand.New(rand.NewSource(42)), 0) })
+		}
+
+		store := &fingerprints{}
+		ulid.SetDefaultEntropy(seeded())
+		if err := ulid.VerifyFreshEntropy(store.load, store.store); err != nil {
+			t.Fatalf("expected fresh entropy on the first run, got %v", err)
+		}
+
+		ulid.SetDefaultEntropy(seeded())
+		if err := ulid.VerifyFreshEntropy(store.load, store.store); err != ulid.ErrStaleEntropy {
+			t.Fatalf("expected stale entropy after restoring the snapshot, got %v", err)
+		}
+
+		if store.saves != 1 {
+			t.Errorf("expected the stale fingerprint to not be stored")
+		}
+
+		ulid.SetDefaultEntropy(ulid.Pool(func() io.Reader { return ulid.Monotonic(crand.Reader, 0) }))
+		if err := ulid.VerifyFreshEntropy(store.load, store.store); err != nil {
+			t.Fatalf("expected fresh entropy after reseeding, got %v", err)
+		}
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		ulid.SetDefaultEntropy(ulid.Monotonic(rand.New(rand.NewSource(42)), 0))
+		store := &fingerprints{}
+
+		var prev ulid.ULID
+		for i := 0; i < 64; i++ {
+			if i%8 == 0 {
+				if err := ulid.VerifyFreshEntropy(store.load, store.store); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			next := ulid.MustNew(1000, ulid.DefaultEntropy())
+			if next.Compare(prev) <= 0 {
+				t.Fatalf("expected %s to sort after %s", next, prev)
+			}
+			prev = next
+		}
+	})
 
-	errs := make(chan error, 100)
-	for i := 0; i < cap(errs); i++ {
+	t.Run("Sequential", func(t *testing.T) {
+		ulid.SetDefaultEntropy(&ulid.LockedMonotonicReader{MonotonicReader: ulid.SequentialEntropy([10]byte{})})
+		store := &fingerprints{}
+
+		prev := ulid.MustNew(1000, ulid.DefaultEntropy())
+		if fp := ulid.EntropyFingerprint(); fp != 0 {
+			t.Errorf("expected sequential entropy to not be sampled, got %x", fp)
+		}
+
+		if err := ulid.VerifyFreshEntropy(store.load, store.store); err != nil || store.saves != 0 {
+			t.Errorf("expected sequential entropy to not be verified, got %v", err)
+		}
+
+		next := ulid.MustNew(1000, ulid.DefaultEntropy())
+		if gap, ok := ulid.Gap(prev, next); !ok || gap != 1 {
+			t.Errorf("expected no gap in the sequence after sampling")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ulid.SetDefaultEntropy(original)
+		store := &fingerprints{err: io.ErrUnexpectedEOF}
+		if err := ulid.VerifyFreshEntropy(store.load, store.store); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected the load error, got %v", err)
+		}
+
+		fail := func(uint64) error { return io.ErrClosedPipe }
+		if err := ulid.VerifyFreshEntropy((&fingerprints{}).load, fail); err != io.ErrClosedPipe {
+			t.Errorf("expected the store error, got %v", err)
+		}
+
+		ulid.SetDefaultEntropy(bytes.NewReader(nil))
+		if ulid.EntropyFingerprint() != 0 {
+			t.Errorf("expected a zero fingerprint if the entropy cannot be read")
+		}
+	})
+}
+
+func TestSetEntropyConcurrent(t *testing.T) {
+	original := ulid.DefaultEntropy()
+	defer ulid.SetDefaultEntropy(original)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
 		go func() {
-			u0 := ulid.MustNew(t0, safe)
-			u1 := u0
-			for j := 0; j < 1024; j++ {
-				u0, u1 = u1, ulid.MustNew(t0, safe)
-				if u0.String() >= u1.String() {
-					errs <- fmt.Errorf(
-						"%s (%d %x) >= %s (%d %x)",
-						u0.String(), u0.Time(), u0.Entropy(),
-						u1.String(), u1.Time(), u1.Entropy(),
-					)
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
 					return
+				default:
+					if ulid.Make().IsZero() {
+						t.Error("expected ulid to not be zero")
+						return
+					}
 				}
 			}
-			errs <- nil
 		}()
 	}
 
-	for i := 0; i < cap(errs); i++ {
-		if err := <-errs; err != nil {
-			t.Fatal(err)
-		}
+	sources := []io.Reader{original, &countingReader{r: crand.Reader}}
+	for i := 0; i < 1000; i++ {
+		ulid.SetDefaultEntropy(sources[i%len(sources)])
 	}
+
+	close(done)
+	wg.Wait()
 }
@@ -1,6 +1,10 @@
 package ulid
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	// Occurs when parsing or unmarshaling ULIDs that aren't strings or []byte.
@@ -12,12 +16,19 @@ var (
 	// Occurs when parsing or unmarshaling ULIDs with invalid Base32 encodings.
 	ErrInvalidCharacters = errors.New("ulid: bad data characters when unmarshaling")
 
+	// Occurs when parsing a ULID string that is not the canonical encoding of the
+	// ULID it decodes to, e.g. when the string contains lowercase characters.
+	ErrNonCanonical = errors.New("ulid: non-canonical encoding when unmarshaling")
+
 	// Occurs when marshalling ULIDs to a buffer of insufficient size.
 	ErrBufferSize = errors.New("ulid: bad buffer size when marshaling")
 
 	// Occurs when constructing a ULID with a time that is larger than MaxTime.
 	ErrBigTime = errors.New("ulid: time too big")
 
+	// Occurs when constructing a ULID with a time that is before the Unix epoch.
+	ErrSmallTime = errors.New("ulid: time too small")
+
 	// Occurs when unmarshaling a ULID whose first character is
 	// larger than 7, thereby exceeding the valid bit depth of 128.
 	ErrOverflow = errors.New("ulid: overflow when unmarshaling")
@@ -26,6 +37,208 @@ var (
 	// entropy bytes would result in overflow.
 	ErrMonotonicOverflow = errors.New("ulid: monotonic entropy overflow")
 
+	// Returned by a BumpingReader when the requested time is behind the timestamp
+	// of the previous ULID by more than its maximum drift, e.g. because the clock
+	// was stepped back.
+	ErrClockRegressed = errors.New("ulid: clock regressed by more than the maximum drift")
+
+	// Returned by MonotonicRead when an IncrementStrategy returns an increment of 0,
+	// which would not increase the entropy.
+	ErrZeroIncrement = errors.New("ulid: monotonic entropy increment must be at least 1")
+
+	// Returned when a Snowflake ID is negative or when a ULID's timestamp cannot be
+	// represented by a Snowflake ID with the given epoch.
+	ErrInvalidSnowflake = errors.New("ulid: invalid snowflake id")
+
+	// Returned by a RateLimitedReader when no token is available within its
+	// maximum wait.
+	ErrRateLimited = errors.New("ulid: entropy rate limit exceeded")
+
+	// Returned by NewContext when the context is done before the entropy has been
+	// read; the error also wraps the context's error.
+	ErrEntropyCanceled = errors.New("ulid: entropy read canceled")
+
+	// Occurs when decoding a mnemonic that contains a word not in the word list.
+	ErrUnknownWord = errors.New("ulid: unknown word in mnemonic")
+
+	// Returned by VerifyFreshEntropy when the default entropy produces the same
+	// output as a previous run, e.g. after a VM snapshot is restored.
+	ErrStaleEntropy = errors.New("ulid: default entropy is stale")
+
+	// Returned when a nil reader is given as a replacement entropy source, or by New
+	// when the entropy is a nil pointer to one of the entropy readers of this package.
+	ErrNilEntropy = errors.New("ulid: entropy source cannot be nil")
+
+	// Returned by Batch.Next after the batch has been closed.
+	ErrBatchClosed = errors.New("ulid: batch is closed")
+
+	// Returned by EncodeBatchText and DecodeBatchText when the separator is one of
+	// the characters of the base32 encoding.
+	ErrInvalidSeparator = errors.New("ulid: batch separator is a base32 character")
+
+	// Occurs when the lower bound of a range of ULIDs is greater than the upper bound.
+	ErrInvalidRange = errors.New("ulid: lower bound is greater than upper bound")
+
+	// Reported by a Merger when a source sequence of ULIDs is not sorted.
+	ErrUnsorted = errors.New("ulid: merge source is not sorted")
+
 	// Occurs when the value passed to scan cannot be unmarshaled into the ULID.
-	ErrScanValue = errors.New("ulid: source value must be a string or byte slice")
+	ErrScanValue = errors.New("ulid: source value must be a string, byte slice, or integer")
+
+	// Occurs when truncating the timestamp of a ULID to a duration that is not a
+	// positive whole number of milliseconds.
+	ErrInvalidDuration = errors.New("ulid: duration must be a positive whole number of milliseconds")
+
+	// Returned by NewULID when more than one option of the same kind is given.
+	ErrConflictingOptions = errors.New("ulid: conflicting options")
+
+	// Occurs when the node id passed to WithNode does not fit in the node bits.
+	ErrInvalidNode = errors.New("ulid: invalid node id or bits")
+
+	// Detected by a ULIDPool in debug mode when a pooled ULID is used after Put.
+	ErrPoolCorrupted = errors.New("ulid: pooled ulid was modified or put twice")
+
+	// Occurs when decoding a checked envelope whose checksum does not match its ULID.
+	ErrChecksumMismatch = errors.New("ulid: checksum mismatch when decoding checked envelope")
+
+	// Occurs when a calendar unit is not Day, Week, Month, or a unit of WeekStarting.
+	ErrInvalidCalendarUnit = errors.New("ulid: invalid calendar unit")
+
+	// Occurs when unmarshaling data that is not a binary encoded RangeSet.
+	ErrInvalidRangeSet = errors.New("ulid: invalid range set encoding")
+
+	// Returned by EncodeDelta and a DeltaWriter when the ULIDs are not in strictly
+	// increasing order.
+	ErrUnsortedSet = errors.New("ulid: set is not sorted and unique")
+
+	// Occurs when decoding data that is not a delta encoded set of ULIDs, e.g.
+	// because it is truncated or has an unknown version.
+	ErrInvalidDelta = errors.New("ulid: invalid delta encoding")
+
+	// Occurs when the checksum of a delta encoded set does not match its data.
+	ErrDeltaChecksum = errors.New("ulid: delta encoding checksum mismatch")
+
+	// Returned by the Read method of a PrefetchedReader after it is closed.
+	ErrEntropyClosed = errors.New("ulid: entropy reader is closed")
+
+	// Returned by NewCodec when the alphabet is not 32 distinct printable ASCII
+	// characters in increasing byte order.
+	ErrInvalidAlphabet = errors.New("ulid: invalid codec alphabet")
+
+	// Returned by ValidateBinary when the timestamp of a ULID is before MinTime or
+	// too far in the future, which usually indicates corrupt or misaligned data.
+	ErrImplausibleTime = errors.New("ulid: implausible timestamp")
+
+	// Returned by ValidateBinary with RejectZeroEntropy when all of the entropy
+	// bytes of a ULID are zero.
+	ErrZeroEntropy = errors.New("ulid: entropy is all zero")
+
+	// Returned by a FileLockedReader when the lock file is held by another process
+	// for longer than the timeout, e.g. by a process that is hung.
+	ErrFileLockTimeout = errors.New("ulid: timed out waiting for the entropy lock file")
+
+	// Reported by a FileLockedReader when the state in its lock file is corrupt and
+	// is replaced with fresh entropy.
+	ErrCorruptFileState = errors.New("ulid: corrupt state in the entropy lock file")
+
+	// Returned by a FileLockedReader on platforms that do not support file locking.
+	ErrFileLockUnsupported = errors.New("ulid: file locking is not supported on this platform")
+
+	// Returned by a FileLockedReader when the timestamp is before the timestamp of
+	// the last ULID issued with the lock file, which would break the ordering.
+	ErrTimestampBehind = errors.New("ulid: timestamp is before the last issued ulid")
+
+	// Returned by a ResilientReader while its circuit breaker is open because the
+	// entropy source failed repeatedly, wrapping the last error of the source.
+	ErrEntropyUnavailable = errors.New("ulid: entropy source is unavailable")
+
+	// Returned by a FencedGenerator when every ULID it generated within its retry
+	// budget was already in its uniqueness store.
+	ErrDuplicateULID = errors.New("ulid: generated ulid was already issued")
+
+	// Returned by FencedGenerator.Fill when a BatchUniquenessStore does not return
+	// one result for each ULID of a batch.
+	ErrInvalidStoreResult = errors.New("ulid: uniqueness store returned the wrong number of results")
+
+	// Returned by DecodeCursor when the token is not a pagination cursor or has an
+	// unknown version.
+	ErrCursorMalformed = errors.New("ulid: malformed pagination cursor")
+
+	// Returned by DecodeCursor when the HMAC of the cursor does not match the key,
+	// e.g. because a client modified or forged the cursor.
+	ErrCursorTampered = errors.New("ulid: pagination cursor failed authentication")
+
+	// Returned by DecodeCursor when the expiry embedded in the cursor has passed.
+	ErrCursorExpired = errors.New("ulid: pagination cursor has expired")
+
+	// Returned by ParseURN when the string is not a URN with a namespace.
+	ErrNotURN = errors.New("ulid: not a urn")
+
+	// Returned by ParseURL when the string is not an absolute URL.
+	ErrNotURL = errors.New("ulid: not a url")
+
+	// Returned by SkewValidator.Observe when the timestamp of a ULID is ahead of the
+	// time it arrived by more than the skew, e.g. from a source with a fast clock.
+	ErrSkewAhead = errors.New("ulid: timestamp is ahead of arrival by more than the skew")
+
+	// Returned by SkewValidator.Observe when the timestamp of a ULID is behind the
+	// latest timestamp of its source by more than the skew.
+	ErrSkewRegressed = errors.New("ulid: timestamp regressed by more than the skew")
+
+	// Returned by SkewValidator.Observe when the timestamp of a ULID is outside of
+	// the absolute bounds of the validator.
+	ErrSkewOutOfBounds = errors.New("ulid: timestamp is outside of the bounds")
+
+	// Returned by SkewValidator.Observe when a ULID is observed from a new source
+	// but the validator already tracks the maximum number of sources.
+	ErrSkewSources = errors.New("ulid: too many sources to validate skew")
 )
+
+// PanicError is the value passed to panic by the Must* functions. It wraps the
+// underlying error so that a recovered panic can be inspected with errors.Is and
+// errors.As, and records the input that caused the failure.
+//
+// Prior to the introduction of PanicError, the Must* functions panicked with the
+// sentinel error directly; code that compares a recovered value to a sentinel
+// with == should instead assert that it is an error and use errors.Is.
+type PanicError struct {
+	Func  string // The name of the function that panicked, e.g. MustParse
+	Input string // The stringified input, truncated to maxPanicInput bytes
+	Err   error  // The underlying error
+}
+
+// maxPanicInput is the maximum number of bytes of input recorded in PanicError.
+const maxPanicInput = 64
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("ulid.%s(%s): %s", e.Func, e.Input, e.Err)
+}
+
+func (e *PanicError) Unwrap() error {
+	return e.Err
+}
+
+func newPanicError(fn string, input any, err error) *PanicError {
+	var s string
+	switch v := input.(type) {
+	case string:
+		if len(v) > maxPanicInput {
+			s = fmt.Sprintf("%q...", v[:maxPanicInput])
+		} else {
+			s = fmt.Sprintf("%q", v)
+		}
+	case []byte:
+		if len(v) > maxPanicInput/2 {
+			s = fmt.Sprintf("0x%x...", v[:maxPanicInput/2])
+		} else {
+			s = fmt.Sprintf("0x%x", v)
+		}
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	default:
+		if s = fmt.Sprintf("%v", v); len(s) > maxPanicInput {
+			s = s[:maxPanicInput] + "..."
+		}
+	}
+	return &PanicError{Func: fn, Input: s, Err: err}
+}
@@ -0,0 +1,535 @@
+package ulid_test
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// ULIDs generated with the same monotonic entropy source within the same
+// millisecond are strictly increasing; the random component is incremented
+// rather than regenerated.
+func ExampleNew_monotonic() {
+	t := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	ms := ulid.Timestamp(t)
+
+	// math/rand is not safe for concurrent use; see LockedMonotonicReader or
+	// DefaultEntropy when generating ULIDs from multiple goroutines.
+	entropy := ulid.Monotonic(rand.New(rand.NewSource(42)), 0)
+
+	var prev ulid.ULID
+	for i := 0; i < 3; i++ {
+		id, err := ulid.New(ms, entropy)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Println(id, id.Compare(prev) > 0)
+		prev = id
+	}
+	// Output:
+	// 01HTCRFNG0AE67Z5NHCJZHQ5XV true
+	// 01HTCRFNG0AE67Z5NHCN8T55NH true
+	// 01HTCRFNG0AE67Z5NHCRVQZRFF true
+}
+
+// Make is the simplest way to generate a ULID: it uses the current time and a
+// process-global monotonic entropy source that is safe for concurrent use. Use
+// MakeSecure instead when the ULIDs must not be guessable, e.g. when they are
+// used as session identifiers or in URLs that grant access.
+func ExampleMake() {
+	id := ulid.Make()
+	secure := ulid.MakeSecure()
+
+	fmt.Println(len(id.String()), len(secure.String()))
+	fmt.Println(time.Since(id.Timestamp()) < time.Minute)
+	// Output:
+	// 26 26
+	// true
+}
+
+// Using Make, MustNewDefault, or DefaultEntropy from many goroutines is safe and
+// has minimal contention since the entropy sources are pooled.
+func ExampleDefaultEntropy() {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids = make(map[ulid.ULID]struct{})
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := ulid.MustNew(ulid.Now(), ulid.DefaultEntropy())
+				mu.Lock()
+				ids[id] = struct{}{}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	fmt.Println(len(ids))
+	// Output:
+	// 400
+}
+
+// Parse decodes the 26 character string encoding (case-insensitively) as well as
+// the 16 byte binary encoding of a ULID.
+func ExampleParse() {
+	id, err := ulid.Parse("01HTNMW2JAW89YSBG7NFPHABA4")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(id)
+	fmt.Println(id.Timestamp().UTC().Format(time.RFC3339Nano))
+	fmt.Printf("%X\n", id.Entropy())
+
+	lower, _ := ulid.Parse("01htnmw2jaw89ysbg7nfphaba4")
+	fmt.Println(lower == id)
+
+	binary, _ := ulid.Parse(id.Bytes())
+	fmt.Println(binary == id)
+	// Output:
+	// 01HTNMW2JAW89YSBG7NFPHABA4
+	// 2024-04-04T22:50:02.186Z
+	// E213ECAE07ABED152D44
+	// true
+	// true
+}
+
+// Parse does not validate the characters of the encoding so that it is as fast as
+// possible; ParseStrict should be used for untrusted input.
+func ExampleParseStrict_invalid() {
+	for _, s := range []string{
+		"01HTNMW2JAW89YSBG7NFPHABA4",  // valid
+		"01HTNMW2JAW89YSBG7NFPHABAU",  // U is not in the Crockford base32 alphabet
+		"01HTNMW2JAW89YSBG7NFPHABA",   // too short
+		"81HTNMW2JAW89YSBG7NFPHABA4",  // timestamp overflows 48 bits
+		"01HTNMW2JAW89YSBG7NFPHABA4!", // too long
+	} {
+		_, err := ulid.ParseStrict(s)
+		fmt.Printf("%-28s %v\n", s, err)
+	}
+	// Output:
+	// 01HTNMW2JAW89YSBG7NFPHABA4   <nil>
+	// 01HTNMW2JAW89YSBG7NFPHABAU   ulid: bad data characters when unmarshaling
+	// 01HTNMW2JAW89YSBG7NFPHABA    ulid: bad data size when unmarshaling
+	// 81HTNMW2JAW89YSBG7NFPHABA4   ulid: overflow when unmarshaling
+	// 01HTNMW2JAW89YSBG7NFPHABA4!  ulid: bad data size when unmarshaling
+}
+
+// Canonicalize and ParseCanonical reject or normalize lowercase aliases so that
+// ULID strings can be compared or deduplicated as raw strings.
+func ExampleCanonicalize() {
+	s, err := ulid.Canonicalize("01htnmw2jaw89ysbg7nfphaba4")
+	fmt.Println(s, err)
+
+	_, err = ulid.ParseCanonical("01htnmw2jaw89ysbg7nfphaba4")
+	fmt.Println(errors.Is(err, ulid.ErrNonCanonical))
+	// Output:
+	// 01HTNMW2JAW89YSBG7NFPHABA4 <nil>
+	// true
+}
+
+// ULIDs sort lexicographically by time in both their string and binary encodings.
+func ExampleULID_Compare() {
+	a := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	b := ulid.MustParse("01HTNMW2JB0000000000000000")
+
+	fmt.Println(a.Compare(b), b.Compare(a), a.Compare(a))
+	fmt.Println(a.String() < b.String())
+
+	cmp, err := ulid.CompareString(a, "01htnmw2jaw89ysbg7nfphaba4")
+	fmt.Println(cmp, err)
+	// Output:
+	// -1 1 0
+	// true
+	// 0 <nil>
+}
+
+// Timestamp converts a time.Time into the Unix millisecond timestamp of a ULID and
+// Time converts it back; precision finer than a millisecond is truncated.
+func ExampleTimestamp() {
+	t := time.Date(2024, 4, 1, 12, 30, 45, 123456789, time.UTC)
+	ms := ulid.Timestamp(t)
+	fmt.Println(ms)
+
+	id := ulid.MustNew(ms, nil)
+	fmt.Println(id)
+	fmt.Println(id.Timestamp().UTC().Format(time.RFC3339Nano))
+
+	// Times before the Unix epoch or after MaxTimestampTime cannot be represented.
+	_, err := ulid.TimestampChecked(time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC))
+	fmt.Println(err)
+	// Output:
+	// 1711974645123
+	// 01HTCT7ZC30000000000000000
+	// 2024-04-01T12:30:45.123Z
+	// ulid: time too small
+}
+
+// Time converts a Unix millisecond timestamp back into a time.Time.
+func ExampleTime() {
+	t := ulid.Time(1711974645123)
+	fmt.Println(t.UTC().Format(time.RFC3339Nano))
+	fmt.Println(ulid.MaxTimestampTime().UTC().Format(time.RFC3339Nano))
+	// Output:
+	// 2024-04-01T12:30:45.123Z
+	// 10889-08-02T05:31:50.655Z
+}
+
+// Monotonic entropy fails with ErrMonotonicOverflow when it runs out of room to
+// increment within a single millisecond. The next millisecond starts over with
+// entropy freshly read from the underlying source. See BumpOnOverflow to advance the timestamp instead.
+func ExampleMonotonic_overflow() {
+	// An entropy source that starts at the largest possible entropy.
+	entropy := ulid.Monotonic(bytes.NewReader(bytes.Repeat([]byte{0xFF}, 20)), 1)
+	ms := ulid.Timestamp(time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC))
+
+	id, err := ulid.New(ms, entropy)
+	fmt.Println(id, err)
+
+	_, err = ulid.New(ms, entropy)
+	fmt.Println(err)
+
+	id, err = ulid.New(ms+1, entropy)
+	fmt.Println(id, err)
+	// Output:
+	// 01HTCRFNG0ZZZZZZZZZZZZZZZZ <nil>
+	// ulid: monotonic entropy overflow
+	// 01HTCRFNG1ZZZZZZZZZZZZZZZZ <nil>
+}
+
+// SequentialEntropy produces dense, predictable ULIDs that are useful for tests
+// and for detecting gaps in an append only sequence.
+func ExampleSequentialEntropy() {
+	ms := ulid.Timestamp(time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC))
+	entropy := ulid.SequentialEntropy([10]byte{})
+
+	a := ulid.MustNew(ms, entropy)
+	b := ulid.MustNew(ms, entropy)
+	fmt.Println(a)
+	fmt.Println(b)
+
+	gap, ok := ulid.Gap(a, b)
+	fmt.Println(gap, ok)
+	// Output:
+	// 01HTCRFNG00000000000000000
+	// 01HTCRFNG00000000000000001
+	// 1 true
+}
+
+// A ULID is stored in a database as its 16 byte binary encoding and can be
+// scanned from either the binary or string encoding. This example uses a fake
+// in-memory driver in place of a real database.
+func ExampleULID_Scan() {
+	db, err := sql.Open("ulid-example", "")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	if _, err = db.Exec("INSERT INTO events (id) VALUES (?)", id); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var out ulid.ULID
+	if err = db.QueryRow("SELECT id FROM events").Scan(&out); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(out, out == id)
+
+	// Scanning a string column also works.
+	if err = out.Scan("01HTNMW2JAW89YSBG7NFPHABA4"); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(out)
+	// Output:
+	// 01HTNMW2JAW89YSBG7NFPHABA4 true
+	// 01HTNMW2JAW89YSBG7NFPHABA4
+}
+
+// Value returns the binary encoding of the ULID for the sql/driver package.
+func ExampleULID_Value() {
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	value, _ := id.Value()
+	fmt.Printf("%T %X\n", value, value)
+	// Output:
+	// []uint8 018EAB4E0A4AE213ECAE07ABED152D44
+}
+
+// NullULID represents a ULID that may be null in databases and JSON.
+func ExampleNullULID_json() {
+	type Event struct {
+		ID     ulid.ULID     `json:"id"`
+		Parent ulid.NullULID `json:"parent"`
+	}
+
+	events := []Event{
+		{
+			ID:     ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4"),
+			Parent: ulid.NullULID{},
+		},
+		{
+			ID:     ulid.MustParse("01HTNMW2JBC2RQB1XKQ7FDXYRJ"),
+			Parent: ulid.NullULID{ULID: ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4"), Valid: true},
+		},
+	}
+
+	for _, event := range events {
+		// NOTE: NullULID implements json.Marshaler with a pointer receiver, so the
+		// event must be addressable (e.g. marshaled by pointer) to encode as null.
+		data, _ := json.Marshal(&event)
+		fmt.Println(string(data))
+
+		var out Event
+		if err := json.Unmarshal(data, &out); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(out.Parent.Valid, out.Parent.ULID)
+	}
+	// Output:
+	// {"id":"01HTNMW2JAW89YSBG7NFPHABA4","parent":null}
+	// false 00000000000000000000000000
+	// {"id":"01HTNMW2JBC2RQB1XKQ7FDXYRJ","parent":"01HTNMW2JAW89YSBG7NFPHABA4"}
+	// true 01HTNMW2JAW89YSBG7NFPHABA4
+}
+
+// A Batch generates many ULIDs that share a single timestamp and are strictly
+// increasing, e.g. for inserting the rows of a single transaction.
+func ExampleNewBatchAt() {
+	t := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	batch := ulid.NewBatchAt(t, ulid.SequentialEntropy([10]byte{9: 0x10}))
+	defer batch.Close()
+
+	for i := 0; i < 3; i++ {
+		id, err := batch.Next()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(id)
+	}
+	fmt.Println(batch.Count())
+	// Output:
+	// 01HTCRFNG0000000000000000G
+	// 01HTCRFNG0000000000000000H
+	// 01HTCRFNG0000000000000000J
+	// 3
+}
+
+// Words encodes a ULID as a mnemonic that is easier to read aloud; it is for
+// display only and is not the canonical encoding.
+func ExampleULID_Words() {
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	fmt.Println(id.Words())
+
+	rt, err := ulid.ParseWords("abandon skill inner forward skate bubble refuse property elephant help round grape")
+	fmt.Println(rt, err)
+	// Output:
+	// [abandon skill inner forward skate bubble refuse property elephant help round grape]
+	// 01JKEHNQPA0END3NHMFKB2Y6SE <nil>
+}
+
+// Must functions panic with a *PanicError that wraps the underlying error.
+func ExampleMustParse() {
+	defer func() {
+		err := recover().(error)
+		fmt.Println(err)
+		fmt.Println(errors.Is(err, ulid.ErrDataSize))
+	}()
+
+	ulid.MustParse("not a ulid")
+	// Output:
+	// ulid.MustParse("not a ulid"): ulid: bad data size when unmarshaling
+	// true
+}
+
+// Sorted segment files of binary ULIDs can be compacted by merging their streams,
+// dropping the IDs that occur in more than one segment.
+func ExampleMerger() {
+	a, b, c := ulid.MustParse("01HTNMW2JA0000000000000001"), ulid.MustParse("01HTNMW2JA0000000000000002"), ulid.MustParse("01HTNMW2JB0000000000000000")
+
+	var seg1, seg2 bytes.Buffer
+	ulid.NewWriter(&seg1).WriteAll(slices.Values([]ulid.ULID{a, c}))
+	ulid.NewWriter(&seg2).WriteAll(slices.Values([]ulid.ULID{a, b}))
+
+	var out bytes.Buffer
+	r1, r2 := ulid.NewReader(&seg1), ulid.NewReader(&seg2)
+	merger := &ulid.Merger{Unique: true}
+	n, err := ulid.NewWriter(&out).WriteAll(merger.Merge(r1.All(), r2.All()))
+	if err = errors.Join(err, merger.Err(), r1.Err(), r2.Err()); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(n, out.Len())
+	for id := range ulid.NewReader(&out).All() {
+		fmt.Println(id)
+	}
+	// Output:
+	// 3 48
+	// 01HTNMW2JA0000000000000001
+	// 01HTNMW2JA0000000000000002
+	// 01HTNMW2JB0000000000000000
+}
+
+//===========================================================================
+// Fake SQL Driver
+//===========================================================================
+
+func init() {
+	sql.Register("ulid-example", &exampleDriver{})
+}
+
+// exampleDriver is a minimal database/sql driver that stores the arguments of the
+// last Exec and returns them as a single row from any Query.
+type exampleDriver struct {
+	mu  sync.Mutex
+	row []driver.Value
+}
+
+func (d *exampleDriver) Open(string) (driver.Conn, error) { return &exampleConn{d}, nil }
+
+type exampleConn struct{ d *exampleDriver }
+
+func (c *exampleConn) Prepare(query string) (driver.Stmt, error) { return &exampleStmt{c.d}, nil }
+func (c *exampleConn) Close() error                              { return nil }
+func (c *exampleConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type exampleStmt struct{ d *exampleDriver }
+
+func (s *exampleStmt) Close() error  { return nil }
+func (s *exampleStmt) NumInput() int { return -1 }
+
+func (s *exampleStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.row = append([]driver.Value(nil), args...)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *exampleStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return &exampleRows{row: s.d.row}, nil
+}
+
+type exampleRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *exampleRows) Columns() []string { return make([]string, len(r.row)) }
+func (r *exampleRows) Close() error      { return nil }
+
+func (r *exampleRows) Next(dest []driver.Value) error {
+	if r.done || r.row == nil {
+		return io.EOF
+	}
+
+	copy(dest, r.row)
+	r.done = true
+	return nil
+}
+
+// FromArray and Array convert between ULIDs and arrays without a raw conversion.
+func ExampleFromArray() {
+	b := [16]byte{0x01, 0x94, 0xDD, 0x1A, 0xDE, 0xCA}
+	id := ulid.FromArray(b)
+	fmt.Println(id)
+	fmt.Println(id.Array() == b)
+	// Output:
+	// 01JKEHNQPA0000000000000000
+	// true
+}
+
+// FromSlice checks the length of the slice instead of panicking or ignoring bytes.
+func ExampleFromSlice() {
+	id, err := ulid.FromSlice(ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE").Bytes())
+	fmt.Println(id, err)
+
+	_, err = ulid.FromSlice([]byte{0x01, 0x94})
+	fmt.Println(err)
+
+	fmt.Println(ulid.MustFromSlice(make([]byte, 16)).IsZero())
+	// Output:
+	// 01JKEHNQPA0END3NHMFKB2Y6SE <nil>
+	// ulid: bad data size when unmarshaling
+	// true
+}
+
+// A Codec reads and writes ULIDs with the ordered alphabet of another system.
+func ExampleCodec() {
+	codec, err := ulid.NewCodec("0123456789abcdefghijklmnopqrstuv")
+	if err != nil {
+		panic(err)
+	}
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	s := codec.Encode(id)
+	fmt.Println(s)
+
+	decoded, err := codec.DecodeStrict(s)
+	fmt.Println(decoded, err)
+	// Output:
+	// 01ijehlnma0eld3lhkfjb2u6pe
+	// 01JKEHNQPA0END3NHMFKB2Y6SE <nil>
+}
+
+// ULIDs and NullULIDs are encoded as their strings in XML elements and attributes,
+// and an invalid NullULID as an element with xsi:nil.
+func ExampleNullULID_xml() {
+	type Order struct {
+		XMLName  xml.Name      `xml:"order"`
+		ID       ulid.ULID     `xml:"id,attr"`
+		Customer ulid.ULID     `xml:"customer"`
+		Parent   ulid.NullULID `xml:"parent"`
+	}
+
+	order := Order{
+		ID:       ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"),
+		Customer: ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4"),
+	}
+
+	data, _ := xml.MarshalIndent(order, "", "  ")
+	fmt.Println(string(data))
+
+	var out Order
+	err := xml.Unmarshal(data, &out)
+	fmt.Println(out.ID, out.Customer, out.Parent.Valid, err)
+
+	err = xml.Unmarshal([]byte(`<order id="01JKEHNQPA"></order>`), &out)
+	fmt.Println(err)
+	// Output:
+	// <order id="01JKEHNQPA0END3NHMFKB2Y6SE">
+	//   <customer>01HTNMW2JAW89YSBG7NFPHABA4</customer>
+	//   <parent xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></parent>
+	// </order>
+	// 01JKEHNQPA0END3NHMFKB2Y6SE 01HTNMW2JAW89YSBG7NFPHABA4 false <nil>
+	// ulid: bad data size when unmarshaling: xml attribute id
+}
@@ -0,0 +1,135 @@
+package ulid
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Explanation is a bit-level breakdown of a ULID for educational and debugging
+// purposes, e.g. to show how the timestamp and entropy map onto the bytes and
+// the characters of the string encoding. It can be marshaled as JSON or printed
+// as a table with String.
+type Explanation struct {
+	ULID       string          `json:"ulid"`       // The canonical string encoding
+	Bytes      string          `json:"bytes"`      // The 16 raw bytes in hex
+	Timestamp  uint64          `json:"timestamp"`  // The 48-bit Unix millisecond timestamp
+	Time       time.Time       `json:"time"`       // The decoded timestamp in UTC
+	Entropy    string          `json:"entropy"`    // The 80-bit entropy in hex
+	EntropyHi  uint16          `json:"entropy_hi"` // The most significant 16 bits of the entropy
+	EntropyLo  uint64          `json:"entropy_lo"` // The least significant 64 bits of the entropy
+	Characters []CharacterBits `json:"characters"` // The bits carried by each encoded character
+}
+
+// CharacterBits describes the bits of a ULID carried by one character of its
+// string encoding. The 26 characters encode 130 bits in big-endian order: the
+// first character carries 2 leading zero padding bits and the 3 most significant
+// bits of the timestamp, the next 9 characters carry the remaining 45 bits of the
+// timestamp and the last 16 characters carry the 80 bits of entropy.
+//
+// Bits are numbered in two ways: Start and End are the inclusive positions in
+// the 128-bit ULID counting from 0 at the most significant bit (so they can be
+// mapped onto Bytes), while High and Low are the inclusive positions within the
+// field counting from 0 at the least significant bit, so the character's value
+// is equal to (field >> Low) & (1<<(High-Low+1) - 1).
+type CharacterBits struct {
+	Index int    `json:"index"` // Position of the character in the string encoding
+	Char  string `json:"char"`  // The encoded character
+	Value uint8  `json:"value"` // The 5-bit value of the character (0-31)
+	Field string `json:"field"` // Either "timestamp" or "entropy"
+	Start int    `json:"start"` // The first ULID bit carried, from the most significant bit
+	End   int    `json:"end"`   // The last ULID bit carried, from the most significant bit
+	High  int    `json:"high"`  // The most significant field bit, from the least significant bit
+	Low   int    `json:"low"`   // The least significant field bit, from the least significant bit
+}
+
+// Field names used in CharacterBits.
+const (
+	FieldTimestamp = "timestamp"
+	FieldEntropy   = "entropy"
+)
+
+const (
+	timestampBits = 48
+	entropyBits   = 80
+)
+
+// Explain returns a breakdown of the timestamp, entropy, bytes, and encoded
+// characters of the ULID.
+func Explain(id ULID) Explanation {
+	s := id.String()
+	ex := Explanation{
+		ULID:       s,
+		Bytes:      hex.EncodeToString(id[:]),
+		Timestamp:  id.Time(),
+		Time:       id.Timestamp().UTC(),
+		Entropy:    hex.EncodeToString(id[6:]),
+		EntropyHi:  uint16(id[6])<<8 | uint16(id[7]),
+		Characters: make([]CharacterBits, 0, EncodedSize),
+	}
+
+	for _, b := range id[8:] {
+		ex.EntropyLo = ex.EntropyLo<<8 | uint64(b)
+	}
+
+	// The encoding is 130 bits with 2 leading padding bits, so character i carries
+	// the bits [5i-2, 5i+2] of the ULID clipped to the 128 bits of the ULID.
+	for i := 0; i < EncodedSize; i++ {
+		start, end := max(5*i-2, 0), 5*i+2
+
+		c := CharacterBits{
+			Index: i,
+			Char:  s[i : i+1],
+			Value: dec[s[i]],
+			Start: start,
+			End:   end,
+		}
+
+		if end < timestampBits {
+			c.Field = FieldTimestamp
+			c.High, c.Low = timestampBits-1-start, timestampBits-1-end
+		} else {
+			c.Field = FieldEntropy
+			c.High, c.Low = timestampBits+entropyBits-1-start, timestampBits+entropyBits-1-end
+		}
+
+		ex.Characters = append(ex.Characters, c)
+	}
+	return ex
+}
+
+// String returns a multi-line table of the explanation.
+func (ex Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ulid:       %s\n", ex.ULID)
+	fmt.Fprintf(&sb, "bytes:      %s\n", spaceHex(ex.Bytes))
+	fmt.Fprintf(&sb, "timestamp:  %d (%s)\n", ex.Timestamp, ex.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	fmt.Fprintf(&sb, "entropy:    0x%s\n", ex.Entropy)
+	fmt.Fprintf(&sb, "entropy hi: 0x%04x (%d)\n", ex.EntropyHi, ex.EntropyHi)
+	fmt.Fprintf(&sb, "entropy lo: 0x%016x (%d)\n", ex.EntropyLo, ex.EntropyLo)
+
+	fmt.Fprintf(&sb, "\n%-5s  %-4s  %-5s  %-4s  %-6s  %-7s  %s\n", "index", "char", "value", "bits", "binary", "ulid", "field")
+	for _, c := range ex.Characters {
+		width := c.High - c.Low + 1
+		fmt.Fprintf(&sb, "%-5d  %-4s  %-5d  %-4d  %-6s  %-7s  %s[%d:%d]\n",
+			c.Index, c.Char, c.Value, width,
+			fmt.Sprintf("%0*b", width, c.Value),
+			fmt.Sprintf("%d-%d", c.Start, c.End),
+			c.Field, c.High, c.Low,
+		)
+	}
+	return sb.String()
+}
+
+// spaceHex separates each byte of a hex string with a space.
+func spaceHex(s string) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(s); i += 2 {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(s[i : i+2])
+	}
+	return sb.String()
+}
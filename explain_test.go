@@ -0,0 +1,144 @@
+package ulid_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Golden", func(t *testing.T) {
+		ex := ulid.Explain(ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+
+		if ex.Bytes != "01563e3ab5d3d6764c61efb99302bd5b" {
+			t.Errorf("unexpected bytes %s", ex.Bytes)
+		}
+
+		if ex.Timestamp != 1469922850259 || ex.Time.UnixMilli() != 1469922850259 {
+			t.Errorf("unexpected timestamp %d (%s)", ex.Timestamp, ex.Time)
+		}
+
+		if ex.Entropy != "d6764c61efb99302bd5b" || ex.EntropyHi != 0xd676 || ex.EntropyLo != 0x4c61efb99302bd5b {
+			t.Errorf("unexpected entropy %s (hi %x lo %x)", ex.Entropy, ex.EntropyHi, ex.EntropyLo)
+		}
+
+		if len(ex.Characters) != ulid.EncodedSize {
+			t.Fatalf("expected %d characters, got %d", ulid.EncodedSize, len(ex.Characters))
+		}
+
+		for _, tc := range []struct {
+			index     int
+			char      string
+			value     uint8
+			field     string
+			start     int
+			end       int
+			high, low int
+		}{
+			{0, "0", 0, ulid.FieldTimestamp, 0, 2, 47, 45},
+			{1, "1", 1, ulid.FieldTimestamp, 3, 7, 44, 40},
+			{9, "K", 19, ulid.FieldTimestamp, 43, 47, 4, 0},
+			{10, "T", 26, ulid.FieldEntropy, 48, 52, 79, 75},
+			{25, "V", 27, ulid.FieldEntropy, 123, 127, 4, 0},
+		} {
+			c := ex.Characters[tc.index]
+			if c.Index != tc.index || c.Char != tc.char || c.Value != tc.value || c.Field != tc.field ||
+				c.Start != tc.start || c.End != tc.end || c.High != tc.high || c.Low != tc.low {
+				t.Errorf("character %d: got %+v", tc.index, c)
+			}
+		}
+
+		table := ex.String()
+		for _, want := range []string{
+			"ulid:       01ARZ3NDEKTSV4RRFFQ69G5FAV",
+			"bytes:      01 56 3e 3a b5 d3 d6 76 4c 61 ef b9 93 02 bd 5b",
+			"timestamp:  1469922850259 (2016-07-30T23:54:10.259Z)",
+			"0      0     0      3     000     0-2      timestamp[47:45]",
+			"25     V     27     5     11011   123-127  entropy[4:0]",
+		} {
+			if !strings.Contains(table, want) {
+				t.Errorf("expected table to contain %q:\n%s", want, table)
+			}
+		}
+	})
+
+	t.Run("Reconstruct", func(t *testing.T) {
+		// Reconstruct the ULID from the annotated pieces in both bit numberings.
+		prop := func(id ulid.ULID) bool {
+			ex := ulid.Explain(id)
+
+			var (
+				whole     = new(big.Int)
+				timestamp = new(big.Int)
+				entropy   = new(big.Int)
+				encoded   strings.Builder
+			)
+
+			for _, c := range ex.Characters {
+				value := big.NewInt(int64(c.Value))
+				if c.Value >= 1<<(c.End-c.Start+1) || c.High-c.Low != c.End-c.Start {
+					return false
+				}
+
+				whole.Or(whole, new(big.Int).Lsh(value, uint(127-c.End)))
+				switch c.Field {
+				case ulid.FieldTimestamp:
+					timestamp.Or(timestamp, new(big.Int).Lsh(value, uint(c.Low)))
+				case ulid.FieldEntropy:
+					entropy.Or(entropy, new(big.Int).Lsh(value, uint(c.Low)))
+				default:
+					return false
+				}
+				encoded.WriteString(c.Char)
+			}
+
+			var fromBits, fromFields ulid.ULID
+			whole.FillBytes(fromBits[:])
+			if err := fromFields.SetTime(timestamp.Uint64()); err != nil {
+				return false
+			}
+			entropy.FillBytes(fromFields[6:])
+
+			raw, err := hex.DecodeString(ex.Bytes)
+			if err != nil || ulid.ULID(raw) != id {
+				return false
+			}
+
+			hi := new(big.Int).Lsh(big.NewInt(int64(ex.EntropyHi)), 64)
+			lo := new(big.Int).SetUint64(ex.EntropyLo)
+
+			return fromBits == id && fromFields == id &&
+				encoded.String() == id.String() &&
+				ex.Timestamp == id.Time() &&
+				hi.Or(hi, lo).Cmp(entropy) == 0
+		}
+
+		if err := quick.Check(prop, &quick.Config{MaxCount: 1e3}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		ex := ulid.Explain(ulid.Make())
+		data, err := json.Marshal(ex)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out ulid.Explanation
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.String() != ex.String() {
+			t.Errorf("expected explanation to round trip through JSON")
+		}
+	})
+}
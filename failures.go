@@ -0,0 +1,197 @@
+package ulid
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.rtnl.ai/ulid/internal/unhooked"
+)
+
+// ParseFailureHistory is the number of parse failures that are kept by
+// RecordParseFailure and reported by LastParseFailures.
+const ParseFailureHistory = 64
+
+// MaxParseFailureInput is the maximum number of bytes of an input that are passed
+// to the hook of SetParseFailureHook; longer inputs are truncated.
+const MaxParseFailureInput = 64
+
+// parseFailureHook is the hook of SetParseFailureHook, or nil.
+var parseFailureHook atomic.Pointer[func(input string, err error)]
+
+// SetParseFailureHook sets a hook that is called whenever Parse, ParseStrict,
+// UnmarshalText, or Scan fails, e.g. to find the client that sends malformed IDs
+// without adding logging at every call site, and returns the previous hook; a nil
+// hook disables it. The hook is called on the goroutine that failed to parse, so
+// it must be safe for concurrent use and should be fast; wrap it with
+// SampleParseFailures to bound how often it is called. Since the input comes from
+// untrusted clients, it is truncated to MaxParseFailureInput bytes (with a
+// trailing "...") and escaped like a Go string literal without the quotes, so it
+// is safe to log. The functions that use these to parse, e.g. MustParse or
+// NullULID.Scan, also call the hook when they fail.
+//
+// Without a hook, a failed parse only increments the ParseFailures counter of
+// ReadHealth and the successful parses are not affected at all.
+//
+//	ulid.SetParseFailureHook(ulid.SampleParseFailures(func(input string, err error) {
+//		slog.Warn("malformed ULID", "input", input, "error", err)
+//	}, 10))
+func SetParseFailureHook(hook func(input string, err error)) (previous func(input string, err error)) {
+	var prev *func(string, error)
+	if hook == nil {
+		prev = parseFailureHook.Swap(nil)
+	} else {
+		prev = parseFailureHook.Swap(&hook)
+	}
+
+	if prev == nil {
+		return nil
+	}
+	return *prev
+}
+
+// SampleParseFailures returns a hook that calls the hook with at most perSecond
+// failures in each second and drops the rest, so that a flood of malformed input
+// cannot flood the logs. The seconds start with the first failure after a quiet
+// second rather than on the wall clock. Values of perSecond less than 1 drop every
+// failure.
+func SampleParseFailures(hook func(input string, err error), perSecond int) func(input string, err error) {
+	var (
+		mu     sync.Mutex
+		start  time.Time
+		called int
+	)
+
+	return func(input string, err error) {
+		mu.Lock()
+		if now := time.Now(); now.Sub(start) >= time.Second {
+			start, called = now, 0
+		}
+
+		if called >= perSecond {
+			mu.Unlock()
+			return
+		}
+
+		called++
+		mu.Unlock()
+		hook(input, err)
+	}
+}
+
+// ParseFailure is a failed parse recorded by RecordParseFailure.
+type ParseFailure struct {
+	Time  time.Time `json:"time"`  // The time of the failure
+	Input string    `json:"input"` // The truncated and escaped input
+	Err   error     `json:"-"`     // The error of the parse
+}
+
+// MarshalJSON encodes the failure with the message of its error, e.g. for a debug
+// endpoint. The message of a failure without an error is empty.
+func (f ParseFailure) MarshalJSON() ([]byte, error) {
+	var msg string
+	if f.Err != nil {
+		msg = f.Err.Error()
+	}
+
+	type failure ParseFailure
+	return json.Marshal(struct {
+		failure
+		Error string `json:"error"`
+	}{failure(f), msg})
+}
+
+// parseFailures is the history of RecordParseFailure.
+var parseFailures struct {
+	sync.Mutex
+	history [ParseFailureHistory]ParseFailure
+	next    uint64
+}
+
+// RecordParseFailure is a hook for SetParseFailureHook that keeps the last
+// ParseFailureHistory failures in memory for LastParseFailures, e.g. to expose
+// them on a debug endpoint.
+//
+//	ulid.SetParseFailureHook(ulid.RecordParseFailure)
+func RecordParseFailure(input string, err error) {
+	now := time.Now()
+	parseFailures.Lock()
+	parseFailures.history[parseFailures.next%ParseFailureHistory] = ParseFailure{Time: now, Input: input, Err: err}
+	parseFailures.next++
+	parseFailures.Unlock()
+}
+
+// LastParseFailures returns up to n of the failures recorded by
+// RecordParseFailure, most recent first. If n is less than 1 or more than the
+// number of recorded failures, all of the recorded failures are returned.
+func LastParseFailures(n int) []ParseFailure {
+	parseFailures.Lock()
+	defer parseFailures.Unlock()
+
+	recorded := int(min(parseFailures.next, ParseFailureHistory))
+	if n < 1 || n > recorded {
+		n = recorded
+	}
+
+	failures := make([]ParseFailure, n)
+	for i := range failures {
+		failures[i] = parseFailures.history[(parseFailures.next-1-uint64(i))%ParseFailureHistory]
+	}
+	return failures
+}
+
+func init() {
+	unhooked.ParseStrict = func(s string) ([16]byte, error) {
+		var id ULID
+		err := parse([]byte(s), true, &id)
+		return id.Array(), err
+	}
+}
+
+// parseFailed counts a failed parse of the text and calls the hook, if any.
+func parseFailed[T string | []byte](input T, err error) {
+	health.parseFailures.Add(1)
+	if hook := parseFailureHook.Load(); hook != nil {
+		(*hook)(escapeInput(string(input)), err)
+	}
+}
+
+// parseFailedAny counts a failed parse of a value that may not be text, e.g. the
+// source of Scan, and calls the hook, if any.
+func parseFailedAny(input any, err error) {
+	health.parseFailures.Add(1)
+	if hook := parseFailureHook.Load(); hook != nil {
+		var s string
+		switch x := input.(type) {
+		case string:
+			s = escapeInput(x)
+		case []byte:
+			s = escapeInput(string(x))
+		case int64:
+			s = strconv.FormatInt(x, 10)
+		case uint64:
+			s = strconv.FormatUint(x, 10)
+		case nil:
+			s = "nil"
+		default:
+			// Only the type is described so that the input does not escape to the
+			// heap in the callers that succeed.
+			s = "(" + reflect.TypeOf(x).String() + ")"
+		}
+		(*hook)(s, err)
+	}
+}
+
+// escapeInput truncates the input to MaxParseFailureInput bytes and escapes it.
+func escapeInput(s string) string {
+	var suffix string
+	if len(s) > MaxParseFailureInput {
+		s, suffix = s[:MaxParseFailureInput], "..."
+	}
+
+	q := strconv.QuoteToASCII(s)
+	return q[1:len(q)-1] + suffix
+}
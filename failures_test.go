@@ -0,0 +1,253 @@
+package ulid_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// failureLog is a concurrency safe hook that records the failures it is called
+// with.
+type failureLog struct {
+	mu     sync.Mutex
+	inputs []string
+	errs   []error
+}
+
+func (l *failureLog) hook(input string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inputs = append(l.inputs, input)
+	l.errs = append(l.errs, err)
+}
+
+func (l *failureLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.inputs)
+}
+
+// NOTE: the tests that set the parse failure hook are not parallel so that the
+// failures of other tests are not reported to their hooks.
+func TestParseFailureHook(t *testing.T) {
+	log := &failureLog{}
+	if prev := ulid.SetParseFailureHook(log.hook); prev != nil {
+		t.Fatal("expected no hook to be set")
+	}
+	defer ulid.SetParseFailureHook(nil)
+
+	before := ulid.ReadHealth().ParseFailures
+	var id ulid.ULID
+	testCases := []struct {
+		name  string
+		parse func() error
+		input string
+		err   error
+	}{
+		{"Parse", func() error { _, err := ulid.Parse("01JKEHNQPA0END3NHMFKB2Y6S"); return err }, "01JKEHNQPA0END3NHMFKB2Y6S", ulid.ErrDataSize},
+		{"ParseStrict", func() error { _, err := ulid.ParseStrict("01JKEHNQPA0END3NHMFKB2Y6SU"); return err }, "01JKEHNQPA0END3NHMFKB2Y6SU", ulid.ErrInvalidCharacters},
+		{"ParseStrictBinary", func() error { _, err := ulid.ParseStrict([]byte{1, 2, 3}); return err }, `\x01\x02\x03`, ulid.ErrDataSize},
+		{"ParseStrictType", func() error { _, err := ulid.ParseStrict(42); return err }, "(int)", ulid.ErrUnknownType},
+		{"UnmarshalText", func() error { return id.UnmarshalText([]byte("81JKEHNQPA0END3NHMFKB2Y6SE")) }, "81JKEHNQPA0END3NHMFKB2Y6SE", ulid.ErrOverflow},
+		{"Scan", func() error { return id.Scan("01JKEHNQPA0END3NHMFKB2Y6SE\n") }, `01JKEHNQPA0END3NHMFKB2Y6SE\n`, ulid.ErrDataSize},
+		{"ScanTimestamp", func() error { return id.Scan(int64(-1)) }, "-1", ulid.ErrSmallTime},
+		{"ScanType", func() error { return id.Scan(3.14) }, "(float64)", ulid.ErrScanValue},
+		{"JSON", func() error { return json.Unmarshal([]byte(`"été"`), &id) }, `\u00e9t\u00e9`, ulid.ErrDataSize},
+		{"NullULID", func() error { var nu ulid.NullULID; return nu.Scan("nope") }, "nope", ulid.ErrDataSize},
+		{"Truncated", func() error { _, err := ulid.Parse(strings.Repeat("x", 100)); return err }, strings.Repeat("x", ulid.MaxParseFailureInput) + "...", ulid.ErrDataSize},
+	}
+
+	for i, tc := range testCases {
+		if err := tc.parse(); !errors.Is(err, tc.err) {
+			t.Fatalf("%s: got error %v, want %v", tc.name, err, tc.err)
+		}
+
+		if log.Len() != i+1 || log.inputs[i] != tc.input || !errors.Is(log.errs[i], tc.err) {
+			t.Fatalf("%s: got %d calls, the last with %q and %v, want %q", tc.name, log.Len(), log.inputs[len(log.inputs)-1], log.errs[len(log.errs)-1], tc.input)
+		}
+	}
+
+	if failures := ulid.ReadHealth().ParseFailures - before; failures != uint64(len(testCases)) {
+		t.Errorf("expected %d parse failures to be counted, got %d", len(testCases), failures)
+	}
+
+	// Successful parses do not call the hook.
+	ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	ulid.ParseStrict("01jkehnqpa0end3nhmfkb2y6se")
+	id.Scan(nil)
+	if log.Len() != len(testCases) {
+		t.Errorf("expected no calls for successful parses, got %d", log.Len()-len(testCases))
+	}
+
+	// The hook is replaced and removed.
+	other := &failureLog{}
+	if prev := ulid.SetParseFailureHook(other.hook); prev == nil {
+		t.Fatal("expected the previous hook to be returned")
+	}
+
+	ulid.SetParseFailureHook(nil)
+	ulid.Parse("bad")
+	if log.Len() != len(testCases) || other.Len() != 0 {
+		t.Errorf("expected no calls without a hook")
+	}
+}
+
+func TestSampleParseFailures(t *testing.T) {
+	t.Parallel()
+
+	// The sampled hook is called directly so that the test does not set the global
+	// hook.
+	log := &failureLog{}
+	sampled := ulid.SampleParseFailures(log.hook, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sampled("bad", ulid.ErrDataSize)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := log.Len(); n != 10 {
+		t.Fatalf("expected 10 sampled failures in the first second, got %d", n)
+	}
+
+	// The next second samples again.
+	time.Sleep(time.Second)
+	for j := 0; j < 100; j++ {
+		sampled("bad", ulid.ErrDataSize)
+	}
+
+	if n := log.Len(); n != 20 {
+		t.Errorf("expected 20 sampled failures after two seconds, got %d", n)
+	}
+
+	none := ulid.SampleParseFailures(log.hook, 0)
+	none("bad", ulid.ErrDataSize)
+	if n := log.Len(); n != 20 {
+		t.Errorf("expected no failures to be sampled, got %d", n-20)
+	}
+}
+
+func TestLastParseFailures(t *testing.T) {
+	ulid.SetParseFailureHook(ulid.RecordParseFailure)
+	defer ulid.SetParseFailureHook(nil)
+
+	start := time.Now()
+	inputs := make([]string, ulid.ParseFailureHistory+10)
+	for i := range inputs {
+		inputs[i] = "x" + strings.Repeat("0", i%20)
+		ulid.Parse(inputs[i])
+	}
+
+	// The most recent failures are kept, most recent first.
+	failures := ulid.LastParseFailures(0)
+	if len(failures) != ulid.ParseFailureHistory {
+		t.Fatalf("got %d failures, want %d", len(failures), ulid.ParseFailureHistory)
+	}
+
+	for i, f := range failures {
+		want := inputs[len(inputs)-1-i]
+		if f.Input != want || f.Err != ulid.ErrDataSize || f.Time.Before(start) {
+			t.Fatalf("failure %d: got %+v, want %q", i, f, want)
+		}
+
+		if i > 0 && f.Time.After(failures[i-1].Time) {
+			t.Fatalf("failure %d is newer than the failure before it", i)
+		}
+	}
+
+	if last := ulid.LastParseFailures(3); len(last) != 3 || last[0] != failures[0] || last[2] != failures[2] {
+		t.Errorf("got %v, want the 3 most recent failures", last)
+	}
+
+	data, err := json.Marshal(failures[0])
+	if err != nil || !strings.Contains(string(data), `"error":"ulid: bad data size when unmarshaling"`) || !strings.Contains(string(data), `"input":"`+inputs[len(inputs)-1]+`"`) {
+		t.Errorf("got %s (%v)", data, err)
+	}
+
+	// A failure without an error has an empty message.
+	for _, f := range []ulid.ParseFailure{{}, {Input: "x", Err: nil}} {
+		if data, err := json.Marshal(f); err != nil || !strings.Contains(string(data), `"error":""`) {
+			t.Errorf("got %s (%v)", data, err)
+		}
+	}
+}
+
+func TestParseFailureAllocs(t *testing.T) {
+	// Without a hook, neither the successful nor the failed parses allocate.
+	text := []byte("01JKEHNQPA0END3NHMFKB2Y6SE")
+	bad := []byte("01JKEHNQPA0END3NHMFKB2Y6S")
+	var id ulid.ULID
+	allocs := testing.AllocsPerRun(100, func() {
+		id.UnmarshalText(text)
+		id.Scan("01JKEHNQPA0END3NHMFKB2Y6SE")
+		id.UnmarshalText(bad)
+		id.Scan("01JKEHNQPA0END3NHMFKB2Y6S")
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations without a hook, got %v", allocs)
+	}
+
+	// With a hook, the successful parses still do not allocate.
+	ulid.SetParseFailureHook(func(string, error) {})
+	defer ulid.SetParseFailureHook(nil)
+	allocs = testing.AllocsPerRun(100, func() {
+		id.UnmarshalText(text)
+		id.Scan("01JKEHNQPA0END3NHMFKB2Y6SE")
+		ulid.ParseStrict("01JKEHNQPA0END3NHMFKB2Y6SE")
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations for successful parses with a hook, got %v", allocs)
+	}
+}
+
+func BenchmarkParseFailureHook(b *testing.B) {
+	text := []byte("01JKEHNQPA0END3NHMFKB2Y6SE")
+	bad := []byte("01JKEHNQPA0END3NHMFKB2Y6S")
+
+	for _, bc := range []struct {
+		name string
+		hook func(string, error)
+	}{
+		{"NoHook", nil},
+		{"Sampled", ulid.SampleParseFailures(func(string, error) {}, 10)},
+		{"Recorded", ulid.RecordParseFailure},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ulid.SetParseFailureHook(bc.hook)
+			defer ulid.SetParseFailureHook(nil)
+
+			b.Run("Success", func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					var id ulid.ULID
+					for pb.Next() {
+						_ = id.UnmarshalText(text)
+					}
+				})
+			})
+
+			b.Run("Failure", func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					var id ulid.ULID
+					for pb.Next() {
+						_ = id.UnmarshalText(bad)
+					}
+				})
+			})
+		})
+	}
+}
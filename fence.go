@@ -0,0 +1,329 @@
+package ulid
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFenceRetries is the default number of times a FencedGenerator
+// regenerates a ULID that is already in its store.
+const DefaultFenceRetries = 3
+
+// UniquenessStore records the ULIDs that have been issued, e.g. in a table with a
+// unique key or a key-value store with a conditional put. PutIfAbsent stores the
+// ULID and returns true if it was not already stored, and returns false without
+// storing it if it was. It must be atomic with respect to concurrent calls with
+// the same ULID and safe for concurrent use.
+type UniquenessStore interface {
+	PutIfAbsent(id ULID) (bool, error)
+}
+
+// BatchUniquenessStore is a UniquenessStore that can store several ULIDs in one
+// round trip. PutAllIfAbsent stores each ULID that is not already stored like
+// PutIfAbsent and returns whether each ULID was stored, in the order of the ULIDs.
+// The ULIDs of a batch are distinct: FencedGenerator.Fill does not send a ULID
+// that repeats another ULID of the batch, and returns ErrInvalidStoreResult if the
+// number of results is not the number of ULIDs.
+type BatchUniquenessStore interface {
+	UniquenessStore
+	PutAllIfAbsent(ids []ULID) ([]bool, error)
+}
+
+// FencedGenerator issues ULIDs that are proven to have never been issued before,
+// rather than unique with overwhelming probability, by recording every ULID in a
+// UniquenessStore before it is returned. If the store already has a ULID, the
+// generator regenerates its entropy and tries again, up to its retry budget,
+// after which ErrDuplicateULID is returned. Since the ULIDs of a correctly seeded
+// source basically never collide, a collision usually shows a broken entropy
+// source or a store that is shared with another generator of the same IDs.
+//
+// To preserve the order of the ULIDs as much as possible, a retry keeps the
+// timestamp of the ULID that collided unless the clock has advanced past it, and
+// only reads new entropy, from SecureEntropy by default, which is monotonic within
+// the millisecond. Only the ULIDs that are returned are stored; a ULID that was
+// generated but not stored because of a store error is never returned.
+//
+// A FencedGenerator is safe for concurrent use if its generation function and
+// store are.
+type FencedGenerator struct {
+	gen         func() (ULID, error)
+	store       UniquenessStore
+	retries     int
+	entropy     io.Reader
+	now         func() time.Time
+	onCollision func(id ULID, attempt int)
+	issued      atomic.Uint64
+	collisions  atomic.Uint64
+	exhausted   atomic.Uint64
+}
+
+// FenceStats are the counters of a FencedGenerator.
+type FenceStats struct {
+	Issued     uint64 `json:"issued"`     // The number of ULIDs that were stored and returned
+	Collisions uint64 `json:"collisions"` // The number of generated ULIDs that were already stored
+	Exhausted  uint64 `json:"exhausted"`  // The number of times ErrDuplicateULID was returned
+}
+
+// FenceOption configures a FencedGenerator.
+type FenceOption func(*FencedGenerator)
+
+// FenceRetries sets the number of times a ULID that is already stored is
+// regenerated before ErrDuplicateULID is returned (default DefaultFenceRetries).
+// Zero disables retries; negative values use the default.
+func FenceRetries(n int) FenceOption {
+	return func(g *FencedGenerator) {
+		g.retries = n
+	}
+}
+
+// FenceEntropy sets the entropy read to regenerate a ULID that is already stored
+// (default SecureEntropy, loaded at each retry). The entropy must be safe for
+// concurrent use if the generator is.
+func FenceEntropy(entropy io.Reader) FenceOption {
+	return func(g *FencedGenerator) {
+		g.entropy = entropy
+	}
+}
+
+// FenceClock sets the function used to get the current time (default time.Now),
+// which replaces the timestamp of a regenerated ULID if it is later.
+func FenceClock(now func() time.Time) FenceOption {
+	return func(g *FencedGenerator) {
+		g.now = now
+	}
+}
+
+// FenceOnCollision sets a hook that is called with each generated ULID that was
+// already stored and the attempt that generated it, from 0 for the ULID of the
+// generation function, e.g. to count collisions in a metrics system. The hook is
+// called on the goroutine that issues the ULID.
+func FenceOnCollision(hook func(id ULID, attempt int)) FenceOption {
+	return func(g *FencedGenerator) {
+		g.onCollision = hook
+	}
+}
+
+// NewFencedGenerator creates a generator that issues the ULIDs of the generation
+// function, e.g. Make or MakeSecure wrapped to return a nil error, once they
+// have been recorded in the store, configured by the options.
+func NewFencedGenerator(gen func() (ULID, error), store UniquenessStore, opts ...FenceOption) *FencedGenerator {
+	g := &FencedGenerator{
+		gen:     gen,
+		store:   store,
+		retries: DefaultFenceRetries,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.retries < 0 {
+		g.retries = DefaultFenceRetries
+	}
+	return g
+}
+
+// New returns a ULID of the generation function that was not already in the
+// store, after storing it. Errors of the generation function, the entropy, and
+// the store are returned as is; if every attempt was already stored,
+// ErrDuplicateULID is returned.
+func (g *FencedGenerator) New() (id ULID, err error) {
+	if id, err = g.gen(); err != nil {
+		return Zero, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		var stored bool
+		if stored, err = g.store.PutIfAbsent(id); err != nil {
+			return Zero, err
+		}
+
+		if stored {
+			g.issued.Add(1)
+			return id, nil
+		}
+
+		g.collided(id, attempt)
+		if attempt == g.retries {
+			g.exhausted.Add(1)
+			return Zero, ErrDuplicateULID
+		}
+
+		if id, err = g.regenerate(id); err != nil {
+			return Zero, err
+		}
+	}
+}
+
+// Fill fills ids with ULIDs of the generation function that were not already in
+// the store, after storing them. If the store is a BatchUniquenessStore, the
+// ULIDs are stored with one round trip and a round trip for each retry of the
+// ULIDs that collided; otherwise each ULID is stored with PutIfAbsent. The ULIDs
+// are issued in the order of the generation function except for the ULIDs that
+// were regenerated. A ULID that repeats an earlier ULID of the batch collides with
+// it without being sent to the store. If an error is returned, the contents of ids
+// are undefined, but some of the ULIDs may have been stored.
+func (g *FencedGenerator) Fill(ids []ULID) (err error) {
+	batch, ok := g.store.(BatchUniquenessStore)
+	if !ok {
+		for i := range ids {
+			if ids[i], err = g.New(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := range ids {
+		if ids[i], err = g.gen(); err != nil {
+			return err
+		}
+	}
+
+	// pending are the indices of the ULIDs that have not been stored yet and
+	// attempt are their ULIDs.
+	pending := make([]int, len(ids))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	// dup marks the pending ULIDs that repeat a ULID of the batch, which are not
+	// sent so that the ULIDs of the attempt are distinct.
+	attempt := make([]ULID, len(ids))
+	dup := make([]bool, len(ids))
+	seen := make(map[ULID]struct{}, len(ids))
+	for try := 0; len(pending) > 0; try++ {
+		attempt = attempt[:0]
+		for j, i := range pending {
+			if _, dup[j] = seen[ids[i]]; !dup[j] {
+				seen[ids[i]] = struct{}{}
+				attempt = append(attempt, ids[i])
+			}
+		}
+
+		var stored []bool
+		if stored, err = batch.PutAllIfAbsent(attempt); err != nil {
+			return err
+		}
+
+		if len(stored) != len(attempt) {
+			return fmt.Errorf("%w: %d results for %d ULIDs", ErrInvalidStoreResult, len(stored), len(attempt))
+		}
+
+		remaining, k := pending[:0], 0
+		for j, i := range pending {
+			if !dup[j] {
+				k++
+				if stored[k-1] {
+					g.issued.Add(1)
+					continue
+				}
+			}
+
+			g.collided(ids[i], try)
+			if try == g.retries {
+				g.exhausted.Add(1)
+				return ErrDuplicateULID
+			}
+
+			if ids[i], err = g.regenerate(ids[i]); err != nil {
+				return err
+			}
+			remaining = append(remaining, i)
+		}
+		pending = remaining
+	}
+	return nil
+}
+
+// Stats returns the counters of the generator.
+func (g *FencedGenerator) Stats() FenceStats {
+	return FenceStats{
+		Issued:     g.issued.Load(),
+		Collisions: g.collisions.Load(),
+		Exhausted:  g.exhausted.Load(),
+	}
+}
+
+func (g *FencedGenerator) collided(id ULID, attempt int) {
+	g.collisions.Add(1)
+	if g.onCollision != nil {
+		g.onCollision(id, attempt)
+	}
+}
+
+// regenerate returns a ULID with the timestamp of the ULID, or the current time if
+// the clock has advanced past it, and new entropy.
+func (g *FencedGenerator) regenerate(id ULID) (ULID, error) {
+	ms := id.Time()
+	if now := Timestamp(g.now()); now > ms && now <= maxTime {
+		ms = now
+	}
+
+	entropy := g.entropy
+	if entropy == nil {
+		entropy = SecureEntropy()
+	}
+	return New(ms, entropy)
+}
+
+//===========================================================================
+// Memory Uniqueness Store
+//===========================================================================
+
+// MemoryUniquenessStore is a UniquenessStore that keeps the ULIDs in a map, for
+// tests and small deployments whose ULIDs only have to be unique within the
+// process. Its memory grows with every ULID that is stored. The zero value is
+// ready to use and it is safe for concurrent use.
+type MemoryUniquenessStore struct {
+	mu  sync.Mutex
+	ids map[ULID]struct{}
+}
+
+// NewMemoryUniquenessStore returns an empty in-memory store.
+func NewMemoryUniquenessStore() *MemoryUniquenessStore {
+	return &MemoryUniquenessStore{ids: make(map[ULID]struct{})}
+}
+
+// PutIfAbsent stores the ULID and returns true if it was not already stored.
+func (s *MemoryUniquenessStore) PutIfAbsent(id ULID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(id), nil
+}
+
+// PutAllIfAbsent stores each ULID that is not already stored and returns whether
+// each ULID was stored.
+func (s *MemoryUniquenessStore) PutAllIfAbsent(ids []ULID) ([]bool, error) {
+	stored := make([]bool, len(ids))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, id := range ids {
+		stored[i] = s.put(id)
+	}
+	return stored, nil
+}
+
+// Len returns the number of stored ULIDs.
+func (s *MemoryUniquenessStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ids)
+}
+
+func (s *MemoryUniquenessStore) put(id ULID) bool {
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+
+	if s.ids == nil {
+		s.ids = make(map[ULID]struct{})
+	}
+	s.ids[id] = struct{}{}
+	return true
+}
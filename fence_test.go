@@ -0,0 +1,311 @@
+package ulid_test
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// scriptedStore is a uniqueness store that returns the scripted results of
+// PutIfAbsent in order and then stores every ULID; it records the ULIDs it was
+// called with.
+type scriptedStore struct {
+	mu     sync.Mutex
+	script []bool
+	err    error
+	calls  []ulid.ULID
+}
+
+func (s *scriptedStore) PutIfAbsent(id ulid.ULID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+
+	s.calls = append(s.calls, id)
+	if len(s.calls) <= len(s.script) {
+		return s.script[len(s.calls)-1], nil
+	}
+	return true, nil
+}
+
+// countingStore counts the round trips to a batch store.
+type countingStore struct {
+	ulid.BatchUniquenessStore
+	trips int
+}
+
+func (s *countingStore) PutAllIfAbsent(ids []ulid.ULID) ([]bool, error) {
+	s.trips++
+	return s.BatchUniquenessStore.PutAllIfAbsent(ids)
+}
+
+// rejectingStore is a batch store that has already stored every ULID.
+type rejectingStore struct{}
+
+func (rejectingStore) PutIfAbsent(ulid.ULID) (bool, error) { return false, nil }
+
+func (rejectingStore) PutAllIfAbsent(ids []ulid.ULID) ([]bool, error) {
+	return make([]bool, len(ids)), nil
+}
+
+// shortStore is a batch store that returns one result fewer than the ULIDs.
+type shortStore struct{ rejectingStore }
+
+func (shortStore) PutAllIfAbsent(ids []ulid.ULID) ([]bool, error) {
+	return make([]bool, len(ids)-1), nil
+}
+
+// fixedGen returns a generation function that always returns the ULID.
+func fixedGen(id ulid.ULID) func() (ulid.ULID, error) {
+	return func() (ulid.ULID, error) { return id, nil }
+}
+
+func TestFencedGenerator(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	clock := &fakeClock{now: id.Timestamp().Add(-time.Minute)}
+	store := &scriptedStore{script: []bool{false, false, true}}
+
+	var attempts []int
+	gen := ulid.NewFencedGenerator(fixedGen(id), store, ulid.FenceClock(clock.Now), ulid.FenceOnCollision(func(collided ulid.ULID, attempt int) {
+		attempts = append(attempts, attempt)
+	}))
+
+	got, err := gen.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The retries keep the timestamp of the ULID, since the clock is behind it,
+	// and regenerate the entropy.
+	if len(store.calls) != 3 || store.calls[0] != id || store.calls[2] != got {
+		t.Fatalf("got calls %v and ULID %s", store.calls, got)
+	}
+
+	for i, call := range store.calls[1:] {
+		if call.Time() != id.Time() || call == id {
+			t.Errorf("retry %d: got %s, want new entropy with the time of %s", i+1, call, id)
+		}
+	}
+
+	if len(attempts) != 2 || attempts[0] != 0 || attempts[1] != 1 {
+		t.Errorf("got collision attempts %v", attempts)
+	}
+
+	if stats := gen.Stats(); stats != (ulid.FenceStats{Issued: 1, Collisions: 2}) {
+		t.Errorf("got stats %+v", stats)
+	}
+
+	// Once the clock has advanced past the timestamp, the retry uses the clock.
+	clock.Advance(2 * time.Minute)
+	store.script = append(store.script, false)
+	if got, err = gen.New(); err != nil || got.Time() != ulid.Timestamp(clock.Now()) {
+		t.Errorf("got %s (%v), want the time of the clock", got, err)
+	}
+}
+
+func TestFencedGeneratorExhausted(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	testCases := []struct {
+		retries int
+		calls   int
+	}{
+		{-1, ulid.DefaultFenceRetries + 1},
+		{0, 1},
+		{2, 3},
+		{5, 6},
+	}
+
+	for _, tc := range testCases {
+		store := &scriptedStore{script: make([]bool, 10)}
+		gen := ulid.NewFencedGenerator(fixedGen(id), store, ulid.FenceRetries(tc.retries), ulid.FenceEntropy(crand.Reader))
+		if got, err := gen.New(); !errors.Is(err, ulid.ErrDuplicateULID) || got != ulid.Zero {
+			t.Errorf("%d retries: got %s (%v), want %v", tc.retries, got, err, ulid.ErrDuplicateULID)
+		}
+
+		if len(store.calls) != tc.calls {
+			t.Errorf("%d retries: got %d calls, want %d", tc.retries, len(store.calls), tc.calls)
+		}
+
+		if stats := gen.Stats(); stats.Issued != 0 || stats.Collisions != uint64(tc.calls) || stats.Exhausted != 1 {
+			t.Errorf("%d retries: got stats %+v", tc.retries, stats)
+		}
+	}
+
+	// The errors of the store, the generation function, and the entropy are
+	// returned as is.
+	errStore := errors.New("store is down")
+	gen := ulid.NewFencedGenerator(fixedGen(id), &scriptedStore{err: errStore})
+	if _, err := gen.New(); err != errStore {
+		t.Errorf("got %v, want %v", err, errStore)
+	}
+
+	gen = ulid.NewFencedGenerator(func() (ulid.ULID, error) { return id, ulid.ErrBigTime }, &scriptedStore{})
+	if _, err := gen.New(); err != ulid.ErrBigTime {
+		t.Errorf("got %v, want %v", err, ulid.ErrBigTime)
+	}
+
+	gen = ulid.NewFencedGenerator(fixedGen(id), &scriptedStore{script: []bool{false}}, ulid.FenceEntropy(&scriptedReader{script: []error{ulid.ErrRateLimited}}))
+	if _, err := gen.New(); err != ulid.ErrRateLimited {
+		t.Errorf("got %v, want %v", err, ulid.ErrRateLimited)
+	}
+}
+
+func TestFencedGeneratorFill(t *testing.T) {
+	t.Parallel()
+
+	// The IDs of the generation function, every third of which was issued before.
+	ids := make([]ulid.ULID, 30)
+	memory := ulid.NewMemoryUniquenessStore()
+	for i := range ids {
+		ids[i] = ulid.MustNew(ulid.Now(), crand.Reader)
+		if i%3 == 0 {
+			memory.PutIfAbsent(ids[i])
+		}
+	}
+
+	next := 0
+	gen := func() (ulid.ULID, error) {
+		next++
+		return ids[next-1], nil
+	}
+
+	// The batch is stored with one round trip and one for the retries.
+	store := &countingStore{BatchUniquenessStore: memory}
+	fenced := ulid.NewFencedGenerator(gen, store)
+	got := make([]ulid.ULID, len(ids))
+	if err := fenced.Fill(got); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.trips != 2 {
+		t.Errorf("got %d round trips, want 2", store.trips)
+	}
+
+	for i := range got {
+		if (i%3 != 0) != (got[i] == ids[i]) {
+			t.Errorf("%d: got %s, want %s to be kept if it was not issued", i, got[i], ids[i])
+		}
+	}
+
+	if memory.Len() != len(ids)+10 {
+		t.Errorf("got %d stored ULIDs, want %d", memory.Len(), len(ids)+10)
+	}
+
+	if stats := fenced.Stats(); stats != (ulid.FenceStats{Issued: 30, Collisions: 10}) {
+		t.Errorf("got stats %+v", stats)
+	}
+
+	// Without batches each ULID is stored in turn.
+	next = 0
+	scripted := &scriptedStore{script: []bool{true, false, true}}
+	fenced = ulid.NewFencedGenerator(gen, scripted)
+	if err := fenced.Fill(got[:3]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scripted.calls) != 4 || got[0] != ids[0] || got[1] == ids[1] || got[2] != ids[2] {
+		t.Errorf("got %v with %d calls", got[:3], len(scripted.calls))
+	}
+
+	// The repeated ULIDs of a batch collide without being sent to the store.
+	id := ulid.MustNew(ulid.Now(), crand.Reader)
+	store = &countingStore{BatchUniquenessStore: ulid.NewMemoryUniquenessStore()}
+	fenced = ulid.NewFencedGenerator(fixedGen(id), store, ulid.FenceEntropy(crand.Reader))
+	if err := fenced.Fill(got[:4]); err != nil {
+		t.Fatal(err)
+	}
+
+	if got[0] != id || store.trips != 2 || fenced.Stats() != (ulid.FenceStats{Issued: 4, Collisions: 3}) {
+		t.Errorf("got %v after %d round trips with stats %+v", got[:4], store.trips, fenced.Stats())
+	}
+
+	for i := 1; i < 4; i++ {
+		if slices.Contains(got[:i], got[i]) {
+			t.Errorf("%d: got repeated ULID %s", i, got[i])
+		}
+	}
+
+	// A store that does not return a result for each ULID is an error.
+	next = 0
+	fenced = ulid.NewFencedGenerator(gen, shortStore{})
+	if err := fenced.Fill(got[:3]); !errors.Is(err, ulid.ErrInvalidStoreResult) {
+		t.Errorf("got %v, want %v", err, ulid.ErrInvalidStoreResult)
+	}
+
+	// A batch that keeps colliding exhausts the retries.
+	next = 0
+	reject := &countingStore{BatchUniquenessStore: rejectingStore{}}
+	fenced = ulid.NewFencedGenerator(gen, reject, ulid.FenceRetries(2))
+	if err := fenced.Fill(got[:3]); !errors.Is(err, ulid.ErrDuplicateULID) || reject.trips != 3 {
+		t.Errorf("got %v after %d round trips, want %v after 3", err, reject.trips, ulid.ErrDuplicateULID)
+	}
+}
+
+func TestFencedGeneratorConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// Every ULID of the generation function is the same, so every call but the
+	// first collides once and is regenerated with secure entropy.
+	const goroutines, n = 16, 200
+	store := ulid.NewMemoryUniquenessStore()
+	gen := ulid.NewFencedGenerator(fixedGen(ulid.MustNew(ulid.Now(), nil)), store)
+
+	var (
+		wg     sync.WaitGroup
+		issued = make([][]ulid.ULID, goroutines)
+	)
+
+	for i := range issued {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				var id ulid.ULID
+				var err error
+				if j%2 == 0 {
+					id, err = gen.New()
+				} else {
+					batch := make([]ulid.ULID, 1)
+					err = gen.Fill(batch)
+					id = batch[0]
+				}
+
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				issued[i] = append(issued[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[ulid.ULID]struct{})
+	for _, ids := range issued {
+		for _, id := range ids {
+			if _, ok := seen[id]; ok {
+				t.Fatalf("%s was issued twice", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+
+	if len(seen) != goroutines*n || store.Len() != len(seen) {
+		t.Fatalf("got %d ULIDs and %d stored, want %d", len(seen), store.Len(), goroutines*n)
+	}
+
+	if stats := gen.Stats(); stats.Issued != goroutines*n || stats.Collisions != goroutines*n-1 || stats.Exhausted != 0 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
@@ -0,0 +1,209 @@
+package ulid
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultFileLockTimeout is the default maximum time a FileLockedReader waits
+// for the lock file before failing with ErrFileLockTimeout.
+const DefaultFileLockTimeout = 5 * time.Second
+
+// The state of a lock file is the magic, the timestamp of the last issued ULID in
+// big endian, its entropy, and the CRC-32 (IEEE) of the preceding bytes.
+const (
+	fileStateMagic = "ULM1"
+	fileStateSize  = len(fileStateMagic) + 8 + 10 + 4
+)
+
+// maxFileLockBackoff is the longest interval between attempts to take the lock.
+const maxFileLockBackoff = 50 * time.Millisecond
+
+// FileLockedMonotonic returns monotonic entropy that is ordered across all of the
+// processes on a host that use the same lock file, without a coordinator. Each
+// MonotonicRead takes an exclusive lock on the file (flock on unix, LockFileEx on
+// windows), reads the timestamp and entropy of the last ULID issued by any of the
+// processes from the file, increments the entropy as Monotonic with the default
+// inc does (or reads fresh entropy for a later millisecond), and writes the new
+// state back before releasing the lock, so each process continues the global
+// sequence. The file is created if it does not exist; errors opening it, e.g. a
+// permission error, are returned as *os.PathError. If entropy is nil
+// crypto/rand.Reader is used.
+//
+// Every MonotonicRead makes several system calls and contends for a lock shared
+// by all of the processes, so it takes microseconds rather than nanoseconds and
+// does not scale with the number of processes: it is intended for low-rate
+// ordered IDs, not bulk generation. The state is not synced to disk, so it is
+// shared by the processes through the page cache but may be lost in a crash of
+// the host, after which the sequence restarts with fresh entropy.
+//
+// Because the ULIDs of all of the processes form one sequence, MonotonicRead
+// returns ErrTimestampBehind for a timestamp before the last issued ULID, e.g.
+// when the clocks of the processes disagree; retry with a later timestamp. The
+// lock is released by the operating system when a process exits, so a lock file
+// cannot be left locked by a crashed process, but a hung process may hold it:
+// MonotonicRead fails with ErrFileLockTimeout after the Timeout. A state that
+// is corrupt, e.g. truncated or overwritten, is reported to OnCorrupt and
+// replaced with fresh entropy. ErrFileLockUnsupported is returned on platforms
+// without file locking.
+//
+// The returned reader is safe for concurrent use and should be closed with
+// Close when it is no longer needed.
+func FileLockedMonotonic(path string, entropy io.Reader) (*FileLockedReader, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if entropy == nil {
+		entropy = crand.Reader
+	}
+
+	return &FileLockedReader{
+		Timeout: DefaultFileLockTimeout,
+		file:    f,
+		mono:    Monotonic(entropy, 0),
+	}, nil
+}
+
+// FileLockedReader is monotonic entropy ordered across processes by a lock file,
+// returned by FileLockedMonotonic. The exported fields may be modified to change
+// its behavior but must not be modified concurrently with reads.
+type FileLockedReader struct {
+	// Timeout is the maximum time a MonotonicRead waits for the lock file before
+	// failing with ErrFileLockTimeout. A Timeout of zero tries to take the lock
+	// only once.
+	Timeout time.Duration
+
+	// OnCorrupt is called with an error wrapping ErrCorruptFileState when the
+	// state in the lock file is corrupt and is replaced with fresh entropy, e.g.
+	// to log a warning. It is called with the lock held and may be nil.
+	OnCorrupt func(error)
+
+	mu   sync.Mutex
+	file *os.File
+	mono *MonotonicEntropy
+}
+
+var _ MonotonicReader = &FileLockedReader{}
+
+// Read fills p from the underlying entropy source without taking the lock.
+func (r *FileLockedReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return io.ReadFull(r.mono.Reader, p)
+}
+
+// MonotonicRead implements the MonotonicReader interface, continuing the sequence
+// of the lock file while holding the lock.
+func (r *FileLockedReader) MonotonicRead(ms uint64, p []byte) (err error) {
+	// The lock of a file is held by the open file rather than by a goroutine, so
+	// the goroutines of this process are serialized separately.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.lock(); err != nil {
+		return err
+	}
+	defer unlockFile(r.file)
+
+	last, entropy, size, err := r.readState()
+	if err != nil {
+		return err
+	}
+
+	if last > ms {
+		return fmt.Errorf("%w: %d is before %d in %s", ErrTimestampBehind, ms, last, r.file.Name())
+	}
+
+	r.mono.ms, r.mono.entropy = last, entropy
+	if err = r.mono.MonotonicRead(ms, p); err != nil {
+		return err
+	}
+	return r.writeState(ms, p, size)
+}
+
+// Close closes the lock file.
+func (r *FileLockedReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// lock takes the lock of the file, backing off between attempts until the timeout.
+func (r *FileLockedReader) lock() error {
+	deadline := time.Now().Add(r.Timeout)
+	for wait := time.Millisecond; ; wait = min(2*wait, maxFileLockBackoff) {
+		locked, err := tryLockFile(r.file)
+		if err != nil || locked {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: %s", ErrFileLockTimeout, r.file.Name())
+		}
+		time.Sleep(min(wait, remaining))
+	}
+}
+
+// readState returns the timestamp and entropy of the last issued ULID and the
+// size of the file. An empty file has no state, while a corrupt state is
+// reported to OnCorrupt; both return zero so that fresh entropy is read.
+func (r *FileLockedReader) readState() (ms uint64, entropy uint80, size int, err error) {
+	var buf [fileStateSize + 1]byte
+	if size, err = r.file.ReadAt(buf[:], 0); err != nil && err != io.EOF {
+		return 0, entropy, size, err
+	}
+
+	if size == 0 {
+		return 0, entropy, size, nil
+	}
+
+	state := buf[:fileStateSize]
+	var corrupt error
+	switch {
+	case size != fileStateSize:
+		corrupt = fmt.Errorf("%w: %s does not have %d bytes", ErrCorruptFileState, r.file.Name(), fileStateSize)
+	case string(state[:len(fileStateMagic)]) != fileStateMagic:
+		corrupt = fmt.Errorf("%w: %s has an unknown format", ErrCorruptFileState, r.file.Name())
+	case crc32.ChecksumIEEE(state[:fileStateSize-4]) != binary.BigEndian.Uint32(state[fileStateSize-4:]):
+		corrupt = fmt.Errorf("%w: %s has a bad checksum", ErrCorruptFileState, r.file.Name())
+	}
+
+	if corrupt != nil {
+		if r.OnCorrupt != nil {
+			r.OnCorrupt(corrupt)
+		}
+		return 0, entropy, size, nil
+	}
+
+	state = state[len(fileStateMagic):]
+	entropy.SetBytes(state[8:18])
+	return binary.BigEndian.Uint64(state[:8]), entropy, size, nil
+}
+
+// writeState writes the timestamp and entropy of the issued ULID to the file,
+// truncating any excess bytes of a corrupt state.
+func (r *FileLockedReader) writeState(ms uint64, entropy []byte, size int) (err error) {
+	var state [fileStateSize]byte
+	n := copy(state[:], fileStateMagic)
+	binary.BigEndian.PutUint64(state[n:], ms)
+	copy(state[n+8:], entropy)
+	binary.BigEndian.PutUint32(state[fileStateSize-4:], crc32.ChecksumIEEE(state[:fileStateSize-4]))
+
+	if _, err = r.file.WriteAt(state[:], 0); err != nil {
+		return err
+	}
+
+	if size > fileStateSize {
+		return r.file.Truncate(int64(fileStateSize))
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package ulid
+
+import "os"
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, ErrFileLockUnsupported
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
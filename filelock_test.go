@@ -0,0 +1,262 @@
+package ulid_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+// fileLockSupported reports whether FileLockedMonotonic locks files on this
+// platform, skipping the test otherwise.
+func fileLockSupported(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "windows":
+	default:
+		t.Skipf("file locking is not supported on %s", runtime.GOOS)
+	}
+}
+
+func fileLocked(t *testing.T, path string) *ulid.FileLockedReader {
+	t.Helper()
+	r, err := ulid.FileLockedMonotonic(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestFileLockedMonotonic(t *testing.T) {
+	t.Parallel()
+	fileLockSupported(t)
+
+	path := filepath.Join(t.TempDir(), "ulid.lock")
+	const ms = 1738876313290
+
+	t.Run("Continue", func(t *testing.T) {
+		// A second reader of the same file continues the sequence of the first.
+		a, b := fileLocked(t, path), fileLocked(t, path)
+
+		var prev ulid.ULID
+		for i := 0; i < 100; i++ {
+			r := a
+			if i%3 == 0 {
+				r = b
+			}
+
+			id, err := ulid.New(ms, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id.Compare(prev) <= 0 {
+				t.Fatalf("expected %s to sort after %s", id, prev)
+			}
+			prev = id
+		}
+
+		// A later millisecond starts from fresh entropy.
+		if id, err := ulid.New(ms+1, a); err != nil || id.Time() != ms+1 {
+			t.Errorf("got %s (%v) for a later millisecond", id, err)
+		}
+	})
+
+	t.Run("Behind", func(t *testing.T) {
+		r := fileLocked(t, path)
+		if _, err := ulid.New(ms, r); !errors.Is(err, ulid.ErrTimestampBehind) {
+			t.Errorf("got error %v, want %v", err, ulid.ErrTimestampBehind)
+		}
+	})
+
+	t.Run("NotExist", func(t *testing.T) {
+		_, err := ulid.FileLockedMonotonic(filepath.Join(path, "missing"), nil)
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) {
+			t.Errorf("expected a path error, got %v", err)
+		}
+	})
+}
+
+func TestFileLockedMonotonicCorrupt(t *testing.T) {
+	t.Parallel()
+	fileLockSupported(t)
+
+	testCases := []struct {
+		name  string
+		state []byte
+	}{
+		{"Short", []byte("ULM1")},
+		{"Long", bytes.Repeat([]byte{0xFF}, 4096)},
+		{"Magic", bytes.Repeat([]byte{0xFF}, 26)},
+		{"Checksum", append([]byte("ULM1"), bytes.Repeat([]byte{0xFF}, 22)...)},
+	}
+
+	for _, tc := range testCases {
+		path := filepath.Join(t.TempDir(), "ulid.lock")
+		if err := os.WriteFile(path, tc.state, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var warnings []error
+		r := fileLocked(t, path)
+		r.OnCorrupt = func(err error) { warnings = append(warnings, err) }
+
+		// A corrupt state is replaced with fresh entropy, even if it claims a later
+		// timestamp, and is reported once.
+		first, err := ulid.New(1, r)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		second, err := ulid.New(1, r)
+		if err != nil || second.Compare(first) <= 0 {
+			t.Errorf("%s: expected %s (%v) to sort after %s", tc.name, second, err, first)
+		}
+
+		if len(warnings) != 1 || !errors.Is(warnings[0], ulid.ErrCorruptFileState) {
+			t.Errorf("%s: got warnings %v, want one %v", tc.name, warnings, ulid.ErrCorruptFileState)
+		}
+
+		if info, err := os.Stat(path); err != nil || info.Size() != 26 {
+			t.Errorf("%s: expected the state to be rewritten, got %v (%v)", tc.name, info.Size(), err)
+		}
+	}
+}
+
+func TestFileLockedMonotonicConcurrency(t *testing.T) {
+	t.Parallel()
+	fileLockSupported(t)
+
+	// Goroutines sharing readers of the same file within a millisecond issue one
+	// sequence: every ULID is distinct and each goroutine's ULIDs are increasing.
+	path := filepath.Join(t.TempDir(), "ulid.lock")
+	readers := []*ulid.FileLockedReader{fileLocked(t, path), fileLocked(t, path)}
+	results := make([][]ulid.ULID, 8)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id, err := ulid.New(1738876313290, readers[i%len(readers)])
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[i] = append(results[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	checkSequences(t, results, 400)
+}
+
+func TestFileLockedMonotonicProcesses(t *testing.T) {
+	t.Parallel()
+	fileLockSupported(t)
+	if testing.Short() {
+		t.Skip("skipping the subprocesses in short mode")
+	}
+
+	// The test binary is run again as helper processes that share the lock file.
+	path := filepath.Join(t.TempDir(), "ulid.lock")
+	outputs := make([]*bytes.Buffer, 4)
+	cmds := make([]*exec.Cmd, len(outputs))
+	for i := range cmds {
+		outputs[i] = &bytes.Buffer{}
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestFileLockedMonotonicHelperProcess$")
+		cmds[i].Env = append(os.Environ(), "ULID_FILE_LOCK_HELPER="+path)
+		cmds[i].Stdout = outputs[i]
+		if err := cmds[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := make([][]ulid.ULID, len(cmds))
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper process %d failed: %v", i, err)
+		}
+
+		scanner := bufio.NewScanner(outputs[i])
+		for scanner.Scan() {
+			if id, err := ulid.ParseStrict(scanner.Text()); err == nil {
+				results[i] = append(results[i], id)
+			}
+		}
+	}
+
+	checkSequences(t, results, len(cmds)*helperULIDs)
+
+	// The last ULID of the global sequence is in the lock file.
+	last := slices.MaxFunc(slices.Concat(results...), ulid.ULID.Compare)
+
+	next, err := ulid.New(last.Time(), fileLocked(t, path))
+	if err != nil || next.Compare(last) <= 0 {
+		t.Errorf("expected %s (%v) to sort after the last ULID %s", next, err, last)
+	}
+}
+
+// helperULIDs is the number of ULIDs issued by each helper process.
+const helperULIDs = 200
+
+// TestFileLockedMonotonicHelperProcess is run as a subprocess by
+// TestFileLockedMonotonicProcesses and prints the ULIDs it issued.
+func TestFileLockedMonotonicHelperProcess(t *testing.T) {
+	path := os.Getenv("ULID_FILE_LOCK_HELPER")
+	if path == "" {
+		t.Skip("only run as a helper process")
+	}
+
+	r, err := ulid.FileLockedMonotonic(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	for i := 0; i < helperULIDs; i++ {
+		id, err := ulid.New(1738876313290, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.WriteString(id.String() + "\n")
+	}
+	out.Flush()
+}
+
+// checkSequences checks that the ULIDs are distinct and that each sequence is
+// strictly increasing.
+func checkSequences(t *testing.T, sequences [][]ulid.ULID, n int) {
+	t.Helper()
+	seen := make(map[ulid.ULID]struct{}, n)
+	for i, ids := range sequences {
+		for j, id := range ids {
+			if j > 0 && id.Compare(ids[j-1]) <= 0 {
+				t.Fatalf("sequence %d: expected %s to sort after %s", i, id, ids[j-1])
+			}
+
+			if _, ok := seen[id]; ok {
+				t.Fatalf("sequence %d: duplicate ULID %s", i, id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+
+	if len(seen) != n {
+		t.Errorf("expected %d ULIDs, got %d", n, len(seen))
+	}
+}
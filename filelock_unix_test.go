@@ -0,0 +1,51 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package ulid_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestFileLockedMonotonicTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Hold the lock as a hung process would.
+	path := filepath.Join(t.TempDir(), "ulid.lock")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+
+	r := fileLocked(t, path)
+	r.Timeout = 20 * time.Millisecond
+
+	start := time.Now()
+	if _, err := ulid.New(1, r); !errors.Is(err, ulid.ErrFileLockTimeout) {
+		t.Errorf("got error %v, want %v", err, ulid.ErrFileLockTimeout)
+	}
+
+	if elapsed := time.Since(start); elapsed < r.Timeout {
+		t.Errorf("expected to wait for the timeout, waited %s", elapsed)
+	}
+
+	// Once the lock is released the reader succeeds.
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ulid.New(1, r); err != nil {
+		t.Errorf("unexpected error after the lock was released: %v", err)
+	}
+}
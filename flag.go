@@ -0,0 +1,71 @@
+package ulid
+
+import "flag"
+
+var (
+	_ flag.Value = (*ULID)(nil)
+	_ flag.Value = (*NullULID)(nil)
+)
+
+// Set implements the flag.Value interface by strictly parsing the string into the
+// ULID, so that ULIDs can be used directly as command line flags.
+func (id *ULID) Set(s string) (err error) {
+	*id, err = ParseStrict(s)
+	return err
+}
+
+// Set implements the flag.Value interface by strictly parsing the string into the
+// ULID. If parsing succeeds the NullULID is marked valid, and an empty string
+// explicitly sets the NullULID to null.
+func (nu *NullULID) Set(s string) (err error) {
+	if s == "" {
+		*nu = NullULID{}
+		return nil
+	}
+
+	var id ULID
+	if id, err = ParseStrict(s); err != nil {
+		return err
+	}
+
+	nu.ULID, nu.Valid = id, true
+	return nil
+}
+
+// String returns the string encoding of the ULID if it is valid, otherwise an
+// empty string is returned. It has a pointer receiver so that only a *NullULID is
+// a fmt.Stringer, as flag.Value requires, and the fmt output of NullULID values,
+// e.g. in the %v of a struct, is unchanged.
+func (nu *NullULID) String() string {
+	if !nu.Valid {
+		return ""
+	}
+	return nu.ULID.String()
+}
+
+// ULIDFlag defines a ULID flag with the specified name, default value, and usage
+// string on the flag set. The return value is the address of a ULID variable that
+// stores the value of the flag. If fs is nil, flag.CommandLine is used.
+func ULIDFlag(fs *flag.FlagSet, name string, def ULID, usage string) *ULID {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	id := new(ULID)
+	*id = def
+	fs.Var(id, name, usage)
+	return id
+}
+
+// NullULIDFlag defines an optional ULID flag with the specified name and usage
+// string on the flag set. If the flag is not set, the Valid field of the returned
+// NullULID remains false. If fs is nil, flag.CommandLine is used.
+func NullULIDFlag(fs *flag.FlagSet, name string, usage string) *NullULID {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	nu := new(NullULID)
+	fs.Var(nu, name, usage)
+	return nu
+}
@@ -0,0 +1,112 @@
+package ulid_test
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestULIDFlag(t *testing.T) {
+	t.Parallel()
+
+	def := ulid.Make()
+	valid := ulid.Make()
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want ulid.ULID
+		err  string
+	}{
+		{"Valid", []string{"-id", valid.String()}, valid, ""},
+		{"Lowercase", []string{"-id", strings.ToLower(valid.String())}, valid, ""},
+		{"Missing", []string{}, def, ""},
+		{"Invalid", []string{"-id", "foo"}, def, "invalid value \"foo\" for flag -id: " + ulid.ErrDataSize.Error()},
+		{"InvalidCharacters", []string{"-id", "0000XSNJG0MQJHBF4QX1EFD6U3"}, def, "flag -id: " + ulid.ErrInvalidCharacters.Error()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			id := ulid.ULIDFlag(fs, "id", def, "a ulid")
+
+			err := fs.Parse(tc.args)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got err %v, want it to contain %q", err, tc.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *id != tc.want {
+				t.Errorf("got %s, want %s", id, tc.want)
+			}
+		})
+	}
+}
+
+func TestNullULIDFlag(t *testing.T) {
+	t.Parallel()
+
+	valid := ulid.Make()
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want ulid.NullULID
+		err  string
+	}{
+		{"Valid", []string{"-after", valid.String()}, ulid.NullULID{ULID: valid, Valid: true}, ""},
+		{"Missing", []string{}, ulid.NullULID{}, ""},
+		{"Empty", []string{"-after", ""}, ulid.NullULID{}, ""},
+		{"Invalid", []string{"-after", "foo"}, ulid.NullULID{}, "invalid value \"foo\" for flag -after: " + ulid.ErrDataSize.Error()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			nu := ulid.NullULIDFlag(fs, "after", "a ulid")
+
+			err := fs.Parse(tc.args)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got err %v, want it to contain %q", err, tc.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *nu != tc.want {
+				t.Errorf("got %+v, want %+v", *nu, tc.want)
+			}
+		})
+	}
+}
+
+func TestNullULIDString(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	nu := ulid.NullULID{ULID: id, Valid: true}
+	if s := nu.String(); s != id.String() {
+		t.Errorf("got %q, want %q", s, id.String())
+	}
+
+	if s := new(ulid.NullULID).String(); s != "" {
+		t.Errorf("got %q for a null ULID, want an empty string", s)
+	}
+
+	// The fmt output of values, e.g. in structs, is that of the fields.
+	if s := fmt.Sprintf("%v", struct{ ID ulid.NullULID }{nu}); s != "{{true "+id.String()+"}}" {
+		t.Errorf("got %s", s)
+	}
+}
@@ -0,0 +1,248 @@
+// Package forensics implements heuristics that describe how the entropy of a
+// corpus of ULIDs was most likely generated, e.g. to determine during incident
+// forensics whether suspicious IDs were generated with zero, monotonic, or fully
+// random entropy. The heuristics apply to a corpus rather than to individual
+// ULIDs, which cannot be attributed to a generator on their own.
+package forensics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// EntropyClass is a heuristic description of how the entropy of a group of ULIDs
+// was most likely generated.
+type EntropyClass string
+
+const (
+	ClassUnknown    EntropyClass = "unknown"    // No ULIDs were classified
+	ClassZero       EntropyClass = "zero"       // All ULIDs have zero entropy
+	ClassSequential EntropyClass = "sequential" // Monotonic with small increments, e.g. Monotonic(r, 1)
+	ClassMonotonic  EntropyClass = "monotonic"  // Monotonic with random increments, e.g. Monotonic(r, 0)
+	ClassRandom     EntropyClass = "random"     // Independently random entropy, e.g. crypto/rand
+	ClassMixed      EntropyClass = "mixed"      // A mixture of the above or otherwise inconclusive
+)
+
+const (
+	// MaxMonotonicIncrement is the largest entropy increment between two ULIDs in
+	// the same millisecond that is counted as a monotonic increment. It is the
+	// default inc bound of ulid.Monotonic; ULIDs generated with a larger bound
+	// may be classified as random or mixed.
+	MaxMonotonicIncrement = math.MaxUint32
+
+	// MaxSequentialIncrement is the largest estimated increment bound for which
+	// monotonic entropy is classified as sequential rather than monotonic.
+	MaxSequentialIncrement = 1 << 16
+
+	// monotonicRatio is the fraction of IDs with a predecessor in the same
+	// millisecond that must be monotonic increments to classify a group as
+	// monotonic or sequential; random groups must be below 1-monotonicRatio.
+	monotonicRatio = 0.9
+)
+
+// EntropyReport contains the results of classifying the entropy of a corpus of
+// ULIDs. The overall statistics are embedded in the report and the statistics
+// for each millisecond are listed in Groups in ascending order by time.
+type EntropyReport struct {
+	EntropyStats
+	Groups []EntropyGroup `json:"groups"`
+}
+
+// EntropyGroup contains the entropy statistics for ULIDs with the same timestamp.
+type EntropyGroup struct {
+	Time time.Time `json:"time"`
+	EntropyStats
+}
+
+// EntropyStats describes the entropy of a group of ULIDs.
+type EntropyStats struct {
+	Class            EntropyClass `json:"class"`             // The heuristic classification of the entropy
+	Count            uint64       `json:"count"`             // Number of ULIDs in the group
+	ZeroEntropy      uint64       `json:"zero_entropy"`      // Number of ULIDs with all zero entropy bytes
+	Pairs            uint64       `json:"pairs"`             // Number of ULIDs preceded by another ULID in the same millisecond
+	Monotonic        uint64       `json:"monotonic"`         // Number of pairs where the entropy increased by at most MaxMonotonicIncrement
+	MaxIncrement     uint64       `json:"max_increment"`     // The largest monotonic increment, an estimate of the inc bound
+	DuplicateEntropy uint64       `json:"duplicate_entropy"` // Number of ULIDs whose entropy was already seen in the group
+	EntropyBits      float64      `json:"entropy_bits"`      // Estimated bits of entropy per ULID (at most 80)
+}
+
+// Classify groups the ULIDs by millisecond and reports heuristics about how their
+// entropy was generated, e.g. to determine during forensics whether an ID was
+// generated with zero, monotonic, or fully random entropy. The ULIDs should be
+// in the order they were generated (e.g. the order they were logged) since
+// monotonic increments are measured between each ULID and the previous ULID in
+// the same millisecond.
+//
+// The estimated entropy bits are computed from the byte frequencies of the
+// entropy of the group and are limited by the sample size: a small group of
+// random ULIDs will score well below the maximum of 80 bits.
+func Classify(ids []ulid.ULID) EntropyReport {
+	groups := make(map[uint64][]ulid.ULID)
+	for _, id := range ids {
+		ms := id.Time()
+		groups[ms] = append(groups[ms], id)
+	}
+
+	times := make([]uint64, 0, len(groups))
+	for ms := range groups {
+		times = append(times, ms)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	report := EntropyReport{Groups: make([]EntropyGroup, 0, len(times))}
+	overall := &report.EntropyStats
+	seen := make(map[[10]byte]struct{}, len(ids))
+
+	var freq [256]uint64
+	for _, ms := range times {
+		group := EntropyGroup{Time: ulid.Time(ms).UTC()}
+		group.classify(groups[ms])
+		report.Groups = append(report.Groups, group)
+
+		overall.Count += group.Count
+		overall.ZeroEntropy += group.ZeroEntropy
+		overall.Pairs += group.Pairs
+		overall.Monotonic += group.Monotonic
+		overall.MaxIncrement = max(overall.MaxIncrement, group.MaxIncrement)
+
+		// Duplicates are counted across the whole corpus rather than summed.
+		for _, id := range groups[ms] {
+			entropy := [10]byte(id[6:])
+			if _, ok := seen[entropy]; ok {
+				overall.DuplicateEntropy++
+			}
+			seen[entropy] = struct{}{}
+
+			for _, b := range entropy {
+				freq[b]++
+			}
+		}
+	}
+
+	overall.EntropyBits = entropyBits(&freq, overall.Count*10)
+	overall.Class = overall.class()
+	return report
+}
+
+func (g *EntropyGroup) classify(ids []ulid.ULID) {
+	seen := make(map[[10]byte]struct{}, len(ids))
+
+	var freq [256]uint64
+	for i, id := range ids {
+		g.Count++
+		if isZeroEntropy(id) {
+			g.ZeroEntropy++
+		}
+
+		entropy := [10]byte(id[6:])
+		if _, ok := seen[entropy]; ok {
+			g.DuplicateEntropy++
+		}
+		seen[entropy] = struct{}{}
+
+		for _, b := range entropy {
+			freq[b]++
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		g.Pairs++
+		if inc, ok := ulid.Gap(ids[i-1], id); ok && inc <= MaxMonotonicIncrement {
+			g.Monotonic++
+			g.MaxIncrement = max(g.MaxIncrement, inc)
+		}
+	}
+
+	g.EntropyBits = entropyBits(&freq, g.Count*10)
+	g.Class = g.class()
+}
+
+func (s *EntropyStats) class() EntropyClass {
+	switch {
+	case s.Count == 0:
+		return ClassUnknown
+	case s.ZeroEntropy == s.Count:
+		return ClassZero
+	case s.ZeroEntropy > 0:
+		return ClassMixed
+	case s.Pairs == 0:
+		// Without multiple IDs in the same millisecond monotonic entropy cannot be
+		// distinguished from random entropy, but the entropy is random either way.
+		if s.DuplicateEntropy > 0 {
+			return ClassMixed
+		}
+		return ClassRandom
+	}
+
+	ratio := float64(s.Monotonic) / float64(s.Pairs)
+	switch {
+	case ratio >= monotonicRatio && s.MaxIncrement <= MaxSequentialIncrement:
+		return ClassSequential
+	case ratio >= monotonicRatio:
+		return ClassMonotonic
+	case ratio <= 1-monotonicRatio && s.DuplicateEntropy == 0:
+		return ClassRandom
+	default:
+		return ClassMixed
+	}
+}
+
+// entropyBits computes the Shannon entropy of the byte frequencies and scales it
+// to the 10 entropy bytes of a ULID.
+func entropyBits(freq *[256]uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	var h float64
+	for _, n := range freq {
+		if n > 0 {
+			p := float64(n) / float64(total)
+			h -= p * math.Log2(p)
+		}
+	}
+	return h * 10
+}
+
+// String returns a human readable summary of the overall classification.
+func (r EntropyReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "class:             %s\n", r.Class)
+	fmt.Fprintf(&sb, "total:             %d\n", r.Count)
+	fmt.Fprintf(&sb, "milliseconds:      %d\n", len(r.Groups))
+	fmt.Fprintf(&sb, "zero entropy:      %d\n", r.ZeroEntropy)
+	fmt.Fprintf(&sb, "monotonic:         %d of %d (max increment %d)\n", r.Monotonic, r.Pairs, r.MaxIncrement)
+	fmt.Fprintf(&sb, "duplicate entropy: %d\n", r.DuplicateEntropy)
+	fmt.Fprintf(&sb, "entropy bits:      %.1f of 80\n", r.EntropyBits)
+
+	classes := make(map[EntropyClass]int)
+	for _, g := range r.Groups {
+		classes[g.Class]++
+	}
+
+	if len(classes) > 0 {
+		fmt.Fprintf(&sb, "\nmilliseconds by class:\n")
+		for _, class := range []EntropyClass{ClassZero, ClassSequential, ClassMonotonic, ClassRandom, ClassMixed} {
+			if n := classes[class]; n > 0 {
+				fmt.Fprintf(&sb, "    %-10s  %d\n", class, n)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func isZeroEntropy(id ulid.ULID) bool {
+	for _, b := range id[6:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package forensics_test
+
+import (
+	crand "crypto/rand"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/forensics"
+)
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// corpus generates perMs ULIDs in each of ms milliseconds with the entropy, which
+// is created fresh for every millisecond as a service would after a restart.
+func corpus(t *testing.T, ms, perMs int, entropy func() io.Reader) []ulid.ULID {
+	ids := make([]ulid.ULID, 0, ms*perMs)
+	start := ulid.Timestamp(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	for i := 0; i < ms; i++ {
+		r := entropy()
+		for j := 0; j < perMs; j++ {
+			id, err := ulid.New(start+uint64(i), r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(42))
+	for _, tc := range []struct {
+		name    string
+		entropy func() io.Reader
+		class   forensics.EntropyClass
+	}{
+		{"Zero", func() io.Reader { return zeroReader{} }, forensics.ClassZero},
+		{"MonotonicOne", func() io.Reader { return ulid.Monotonic(crand.Reader, 1) }, forensics.ClassSequential},
+		{"MonotonicDefault", func() io.Reader { return ulid.Monotonic(rng, 0) }, forensics.ClassMonotonic},
+		{"Crypto", func() io.Reader { return crand.Reader }, forensics.ClassRandom},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := corpus(t, 10, 100, tc.entropy)
+			report := forensics.Classify(ids)
+
+			if report.Class != tc.class {
+				t.Fatalf("got class %q, want %q", report.Class, tc.class)
+			}
+
+			if report.Count != 1000 || len(report.Groups) != 10 || report.Pairs != 990 {
+				t.Errorf("got count %d in %d groups with %d pairs", report.Count, len(report.Groups), report.Pairs)
+			}
+
+			for _, g := range report.Groups {
+				if g.Class != tc.class {
+					t.Errorf("group %s: got class %q, want %q", g.Time, g.Class, tc.class)
+				}
+			}
+
+			switch tc.class {
+			case forensics.ClassZero:
+				if report.ZeroEntropy != 1000 || report.DuplicateEntropy != 999 || report.EntropyBits != 0 {
+					t.Errorf("unexpected zero entropy report: %+v", report.EntropyStats)
+				}
+			case forensics.ClassSequential:
+				if report.Monotonic != 990 || report.MaxIncrement != 1 {
+					t.Errorf("unexpected sequential report: %+v", report.EntropyStats)
+				}
+			case forensics.ClassMonotonic:
+				if report.Monotonic != 990 || report.MaxIncrement <= forensics.MaxSequentialIncrement {
+					t.Errorf("unexpected monotonic report: %+v", report.EntropyStats)
+				}
+			case forensics.ClassRandom:
+				if report.Monotonic != 0 || report.DuplicateEntropy != 0 || report.EntropyBits < 79 {
+					t.Errorf("unexpected random report: %+v", report.EntropyStats)
+				}
+			}
+		})
+	}
+
+	t.Run("Mixed", func(t *testing.T) {
+		ids := corpus(t, 5, 100, func() io.Reader { return crand.Reader })
+		ids = append(ids, corpus(t, 5, 100, func() io.Reader { return zeroReader{} })...)
+
+		report := forensics.Classify(ids)
+		if report.Class != forensics.ClassMixed {
+			t.Errorf("got class %q, want %q", report.Class, forensics.ClassMixed)
+		}
+
+		// Both corpora share the same milliseconds.
+		for _, g := range report.Groups {
+			if g.Class != forensics.ClassMixed || g.ZeroEntropy != 100 {
+				t.Errorf("group %s: got class %q with %d zero entropy", g.Time, g.Class, g.ZeroEntropy)
+			}
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		report := forensics.Classify(nil)
+		if report.Class != forensics.ClassUnknown || len(report.Groups) != 0 {
+			t.Errorf("unexpected empty report: %+v", report)
+		}
+	})
+
+	t.Run("LowEntropy", func(t *testing.T) {
+		// Sequential entropy seeded with the same value every millisecond is
+		// estimated to have very few bits of entropy.
+		ids := corpus(t, 10, 100, func() io.Reader { return ulid.Monotonic(strings.NewReader("aaaaaaaaaa"), 1) })
+		report := forensics.Classify(ids)
+		if report.Class != forensics.ClassSequential || report.EntropyBits > 16 {
+			t.Errorf("got class %q with %.1f bits", report.Class, report.EntropyBits)
+		}
+	})
+}
+
+func TestEntropyReportEncoding(t *testing.T) {
+	t.Parallel()
+
+	ids := corpus(t, 2, 3, func() io.Reader { return ulid.Monotonic(crand.Reader, 1) })
+	report := forensics.Classify(ids)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out["class"] != "sequential" || out["count"] != 6.0 || out["max_increment"] != 1.0 {
+		t.Errorf("unexpected JSON report: %s", data)
+	}
+
+	if groups, ok := out["groups"].([]any); !ok || len(groups) != 2 {
+		t.Errorf("expected 2 groups in JSON report: %s", data)
+	}
+
+	var rt forensics.EntropyReport
+	if err := json.Unmarshal(data, &rt); err != nil {
+		t.Fatal(err)
+	}
+
+	if rt.String() != report.String() {
+		t.Errorf("expected report to round trip through JSON")
+	}
+
+	summary := report.String()
+	for _, want := range []string{"class:             sequential", "monotonic:         4 of 4 (max increment 1)", "sequential  2"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("expected summary to contain %q:\n%s", want, summary)
+		}
+	}
+}
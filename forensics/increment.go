@@ -0,0 +1,205 @@
+package forensics
+
+import (
+	"math"
+	"slices"
+
+	"go.rtnl.ai/ulid"
+)
+
+const (
+	// minFitPairs is the number of increments at which the increment estimate
+	// reaches half of its confidence and above which CompatibleWithMonotonic
+	// tests the distribution of the increments rather than only their bound.
+	minFitPairs = 30
+
+	// fitSignificance is the p-value of the Kolmogorov-Smirnov test below which
+	// the increments are not considered uniformly distributed.
+	fitSignificance = 0.001
+
+	// incrementWindow is the number of milliseconds behind the latest ULID of the
+	// corpus for which increments keeps the last ULID of each millisecond, which
+	// bounds its memory regardless of the length of the corpus.
+	incrementWindow = 1024
+)
+
+// GivenIncEstimate estimates the inc parameter of ulid.Monotonic that generated a
+// corpus of ULIDs, e.g. to assess during forensics whether a set of suspicious
+// IDs is consistent with services that use the monotonic default (an inc of 0,
+// which is math.MaxUint32) or was fabricated. Like Classify, the ULIDs are grouped
+// by millisecond and should be in the order they were generated, since the
+// increments are the entropy gaps between each ULID and the previous ULID of the
+// same millisecond.
+//
+// Monotonic draws each increment uniformly at random up to inc, so the estimate
+// is the method of moments estimate of twice the mean increment, raised to the
+// largest increment if that is larger. Sequential entropy, where every increment
+// is 1, is estimated as an inc of 1, which is indistinguishable from an inc of 2.
+//
+// The confidence in [0, 1] is a heuristic score rather than a probability: the
+// fraction of same-millisecond pairs that are increments at all, times the fit of
+// the increments to the uniform distribution of the estimate (by the p-value of
+// a Kolmogorov-Smirnov test), times a sample size factor that is 0.5 at 30
+// increments and approaches 1 for larger corpora. Random entropy, increments of a
+// fixed size or from a skewed distribution, and small samples all score low.
+//
+// The estimate assumes that the corpus was generated by a single monotonic
+// reader with the default increments (not an IncrementStrategy) and that no IDs
+// of a millisecond are missing from the corpus: a missing ID merges two
+// increments, which inflates the estimate and lowers the confidence, as do the
+// interleaved IDs of several generators within a millisecond. Individual IDs
+// cannot be attributed, and without IDs in the same millisecond nothing can be
+// estimated, in which case the estimate and confidence are zero. The increments
+// of Monotonic read from an io.Reader rather than a *rand.Rand are only uniform
+// if the bit length of inc is a multiple of 8, as it is for the default; other
+// values of inc are underestimated with a low confidence.
+func GivenIncEstimate(ids []ulid.ULID) (estInc uint64, confidence float64) {
+	gaps, pairs := increments(ids)
+	if len(gaps) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	var largest uint64
+	for _, gap := range gaps {
+		sum += float64(gap)
+		largest = max(largest, gap)
+	}
+
+	if largest == 1 {
+		estInc = 1
+	} else {
+		estInc = max(largest, floatToUint64(math.Round(2*sum/float64(len(gaps))-1)))
+	}
+
+	n := float64(len(gaps))
+	fit := min(1, uniformFit(gaps, estInc)/fitSignificance)
+	confidence = n / float64(pairs) * fit * n / (n + minFitPairs)
+	return estInc, confidence
+}
+
+// CompatibleWithMonotonic reports whether the entropy gaps between the ULIDs of
+// the same millisecond are consistent with ulid.Monotonic with the inc parameter,
+// where an inc of 0 is the default of math.MaxUint32. The ULIDs should be in the
+// order they were generated, as for GivenIncEstimate, and a corpus without IDs in
+// the same millisecond is trivially compatible.
+//
+// Every same-millisecond pair must be an increment of at least 1 and at most inc.
+// With at least 30 increments and an inc larger than 2, the increments must also
+// fit the uniform distribution of Monotonic, so that e.g. fabricated IDs with
+// fixed increments within the bound are not compatible. The test has a false
+// positive rate of 0.1% for genuine corpora. The same assumptions as for
+// GivenIncEstimate apply; in particular, a corpus with missing IDs may be
+// reported as incompatible.
+func CompatibleWithMonotonic(ids []ulid.ULID, inc uint64) bool {
+	if inc == 0 {
+		inc = math.MaxUint32
+	}
+
+	gaps, pairs := increments(ids)
+	if uint64(len(gaps)) != pairs {
+		return false
+	}
+
+	for _, gap := range gaps {
+		if gap > inc {
+			return false
+		}
+	}
+
+	if len(gaps) < minFitPairs || inc <= 2 {
+		return true
+	}
+	return uniformFit(gaps, inc) >= fitSignificance
+}
+
+// increments returns the entropy gaps between the consecutive ULIDs of each
+// millisecond that are monotonic increments, in the order of the ULIDs, and the
+// number of same-millisecond pairs. A ULID more than incrementWindow milliseconds
+// behind the latest ULID so far is not paired, since the last ULIDs of the older
+// milliseconds are forgotten.
+func increments(ids []ulid.ULID) (gaps []uint64, pairs uint64) {
+	var latest uint64
+	last := make(map[uint64]ulid.ULID)
+	for _, id := range ids {
+		ms := id.Time()
+		if ms+incrementWindow < latest {
+			continue
+		}
+
+		if prev, ok := last[ms]; ok {
+			pairs++
+			if gap, ok := ulid.Gap(prev, id); ok {
+				gaps = append(gaps, gap)
+			}
+		}
+		last[ms] = id
+		latest = max(latest, ms)
+
+		// Sweeping only when the map has twice the milliseconds of the window
+		// removes at least half of them, so the sweeps are amortized.
+		if len(last) > 2*incrementWindow {
+			for t := range last {
+				if t+incrementWindow < latest {
+					delete(last, t)
+				}
+			}
+		}
+	}
+	return gaps, pairs
+}
+
+// uniformFit returns the p-value of a Kolmogorov-Smirnov test of the gaps against
+// the discrete uniform distribution on [1, inc] of the increments of Monotonic,
+// whose increments are in [2, inc] when read from an io.Reader rather than a
+// *rand.Rand, which is within the accuracy of the test for large inc.
+func uniformFit(gaps []uint64, inc uint64) float64 {
+	sorted := slices.Clone(gaps)
+	slices.Sort(sorted)
+
+	// The empirical CDF is a step function, so the largest distance from the CDF
+	// of the uniform distribution is at an observed gap or just below it.
+	n := float64(len(sorted))
+	cdf := func(x uint64) float64 { return min(1, float64(x)/float64(inc)) }
+
+	var d float64
+	for i := 0; i < len(sorted); {
+		x := sorted[i]
+		below := float64(i) / n
+		for i < len(sorted) && sorted[i] == x {
+			i++
+		}
+
+		d = max(d, math.Abs(float64(i)/n-cdf(x)), math.Abs(below-cdf(x-1)))
+	}
+	return ksProbability((math.Sqrt(n) + 0.12 + 0.11/math.Sqrt(n)) * d)
+}
+
+// ksProbability returns the probability that the Kolmogorov distribution exceeds
+// lambda, the significance of a Kolmogorov-Smirnov statistic.
+func ksProbability(lambda float64) float64 {
+	if lambda < 0.2 {
+		return 1
+	}
+
+	var p float64
+	sign := 1.0
+	for k := 1; k <= 100; k++ {
+		term := sign * 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		p += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return min(1, max(0, p))
+}
+
+// floatToUint64 converts a non-negative float to a uint64, saturating at the
+// maximum uint64.
+func floatToUint64(f float64) uint64 {
+	if f >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(f)
+}
@@ -0,0 +1,156 @@
+package forensics_test
+
+import (
+	crand "crypto/rand"
+	"io"
+	"math"
+	"math/rand"
+	randv2 "math/rand/v2"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/forensics"
+)
+
+func TestGivenIncEstimate(t *testing.T) {
+	t.Parallel()
+
+	// A seeded io.Reader draws the increments like crypto/rand does, without the
+	// fast path of a *rand.Rand, and keeps the corpora deterministic.
+	chacha := randv2.NewChaCha8([32]byte{})
+
+	for _, tc := range []struct {
+		name    string
+		inc     uint64
+		entropy func(rng *rand.Rand, inc uint64) io.Reader
+	}{
+		{"Sequential", 1, func(_ *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(crand.Reader, inc) }},
+		{"Small", 10, func(rng *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(rng, inc) }},
+		{"Thousand", 1000, func(rng *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(rng, inc) }},
+		{"Reader", 1 << 15, func(_ *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(chacha, inc) }},
+		{"ReaderDefault", 0, func(_ *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(chacha, inc) }},
+		{"Large", 1 << 20, func(rng *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(rng, inc) }},
+		{"Default", 0, func(rng *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(rng, inc) }},
+		{"Huge", 1 << 40, func(rng *rand.Rand, inc uint64) io.Reader { return ulid.Monotonic(rng, inc) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(42))
+			ids := corpus(t, 20, 51, func() io.Reader { return tc.entropy(rng, tc.inc) })
+
+			want := tc.inc
+			if want == 0 {
+				want = math.MaxUint32
+			}
+
+			est, confidence := forensics.GivenIncEstimate(ids)
+			if diff := math.Abs(float64(est)-float64(want)) / float64(want); diff > 0.1 {
+				t.Errorf("estimated inc %d, want %d within 10%%", est, want)
+			}
+
+			if confidence < 0.9 {
+				t.Errorf("expected a high confidence for inc %d, got %.3f", want, confidence)
+			}
+
+			if !forensics.CompatibleWithMonotonic(ids, tc.inc) {
+				t.Errorf("expected the corpus to be compatible with inc %d", tc.inc)
+			}
+
+			// A smaller bound is exceeded and a much larger bound does not fit the
+			// distribution of the increments.
+			if want > 4 && forensics.CompatibleWithMonotonic(ids, want/4) {
+				t.Errorf("expected the corpus to be incompatible with inc %d", want/4)
+			}
+
+			if forensics.CompatibleWithMonotonic(ids, want*4) {
+				t.Errorf("expected the corpus to be incompatible with inc %d", want*4)
+			}
+		})
+	}
+}
+
+func TestGivenIncEstimateAdversarial(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(7))
+	for _, tc := range []struct {
+		name    string
+		entropy func() io.Reader
+		max     float64
+	}{
+		{"Random", func() io.Reader { return crand.Reader }, 0.01},
+		{"Constant", func() io.Reader {
+			return ulid.MonotonicWithOptions(rng, 0, ulid.WithIncrement(ulid.ConstantIncrement(1000)))
+		}, 0.01},
+		{"Geometric", func() io.Reader {
+			return ulid.MonotonicWithOptions(rng, 0, ulid.WithIncrement(ulid.GeometricIncrement(0.001, 1<<20)))
+		}, 0.01},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := corpus(t, 20, 51, tc.entropy)
+			if est, confidence := forensics.GivenIncEstimate(ids); confidence > tc.max {
+				t.Errorf("expected a low confidence, got %.3f for inc %d", confidence, est)
+			}
+
+			if forensics.CompatibleWithMonotonic(ids, 0) {
+				t.Error("expected the corpus to be incompatible with the default inc")
+			}
+		})
+	}
+
+	t.Run("Interleaved", func(t *testing.T) {
+		// Two generators in the same milliseconds only rarely produce increments.
+		a := corpus(t, 20, 51, func() io.Reader { return ulid.Monotonic(rng, 1000) })
+		b := corpus(t, 20, 51, func() io.Reader { return ulid.Monotonic(rng, 1000) })
+
+		ids := make([]ulid.ULID, 0, len(a)+len(b))
+		for i := range a {
+			ids = append(ids, a[i], b[i])
+		}
+
+		if _, confidence := forensics.GivenIncEstimate(ids); confidence > 0.6 {
+			t.Errorf("expected a low confidence, got %.3f", confidence)
+		}
+
+		if forensics.CompatibleWithMonotonic(ids, 1000) {
+			t.Error("expected the interleaved corpus to be incompatible")
+		}
+	})
+
+	t.Run("Small", func(t *testing.T) {
+		ids := corpus(t, 1, 6, func() io.Reader { return ulid.Monotonic(rng, 1000) })
+		if _, confidence := forensics.GivenIncEstimate(ids); confidence > 0.2 {
+			t.Errorf("expected a low confidence for 5 increments, got %.3f", confidence)
+		}
+
+		if !forensics.CompatibleWithMonotonic(ids, 1000) {
+			t.Error("expected the increments to be within the bound")
+		}
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		// A straggler far behind the latest ULID is not paired with the first
+		// millisecond of the corpus, whose last ULID has been forgotten.
+		ids := corpus(t, 3000, 2, func() io.Reader { return ulid.Monotonic(rng, 1000) })
+		ids = append(ids, ulid.MustNew(ids[0].Time(), crand.Reader))
+
+		if !forensics.CompatibleWithMonotonic(ids, 1000) {
+			t.Error("expected the straggler not to be paired")
+		}
+
+		if forensics.CompatibleWithMonotonic(append(ids[:2:2], ids[len(ids)-1]), 1000) {
+			t.Error("expected the straggler to be paired within the window")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		// Without IDs in the same millisecond nothing can be estimated.
+		ids := corpus(t, 10, 1, func() io.Reader { return crand.Reader })
+		if est, confidence := forensics.GivenIncEstimate(ids); est != 0 || confidence != 0 {
+			t.Errorf("got inc %d with confidence %.3f", est, confidence)
+		}
+
+		if !forensics.CompatibleWithMonotonic(ids, 1) {
+			t.Error("expected a corpus without pairs to be compatible")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package ulid
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// FuzzedGenerator creates ULIDs whose timestamps are perturbed by a random offset
+// of up to maxJitter in either direction, e.g. for IDs exposed by a public API
+// that should remain roughly sortable for pagination without revealing the exact
+// creation times of the records (or the rate at which they are created) to anyone
+// who collects them. The offset of each ULID is drawn uniformly from the whole
+// milliseconds in [-maxJitter, +maxJitter] and the timestamp is clamped to the
+// range of ULID timestamps, so ULIDs created near the Unix epoch or MaxTime are
+// not jittered symmetrically.
+//
+// The ULIDs of a generator never sort backwards: the timestamp of each ULID is at
+// least the timestamp of the previous ULID (a floor), and ULIDs with the same
+// timestamp have monotonic entropy. If times passed to New do not decrease, each
+// timestamp is within maxJitter of its time, since the floor was itself drawn at
+// most maxJitter after an earlier time; a time that goes backwards by more than
+// maxJitter produces a timestamp after its time plus maxJitter.
+//
+// The floor is a trade-off between ordering and privacy. A ULID created long after
+// the previous one hides its time within maxJitter, but when the ULIDs are created
+// more often than every maxJitter the floor holds the maximum of the jittered
+// times so far, so the timestamps of a burst cluster just below time+maxJitter and
+// reveal the times more precisely than a single ULID does; the more ULIDs an
+// observer collects, the better they can estimate the times. ULIDs of different
+// generators are only ordered to within 2*maxJitter of each other. Choose
+// maxJitter larger than the interval between the ULIDs of a generator to hide
+// their times and smaller than the acceptable error of time-ordered pagination.
+//
+// The offsets must be unpredictable for the times to be hidden: if the entropy is
+// nil crypto/rand.Reader is used, and a seeded math/rand should only be used in
+// tests. A FuzzedGenerator is safe for concurrent use.
+type FuzzedGenerator struct {
+	mu     sync.Mutex
+	jitter uint64
+	mono   *MonotonicEntropy
+	floor  uint64
+	buf    [8]byte
+}
+
+// NewFuzzedGenerator returns a generator that jitters the timestamps of ULIDs by
+// up to maxJitter, rounded down to whole milliseconds, and reads the offsets and
+// the entropy of the ULIDs from entropy. A maxJitter of less than a millisecond
+// does not jitter the timestamps.
+func NewFuzzedGenerator(maxJitter time.Duration, entropy io.Reader) *FuzzedGenerator {
+	if entropy == nil {
+		entropy = crand.Reader
+	}
+
+	g := &FuzzedGenerator{mono: Monotonic(entropy, 0)}
+	if maxJitter > 0 {
+		g.jitter = min(uint64(maxJitter.Milliseconds()), maxTime)
+	}
+	return g
+}
+
+// New returns a ULID with the timestamp of t perturbed by a random offset, which
+// sorts after every ULID previously returned by the generator. ErrSmallTime or
+// ErrBigTime is returned if t cannot be represented in a ULID, and errors reading
+// the entropy, including ErrMonotonicOverflow, are returned as with New.
+func (g *FuzzedGenerator) New(t time.Time) (id ULID, err error) {
+	ms, err := TimestampChecked(t)
+	if err != nil {
+		return id, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var offset uint64
+	if offset, err = g.offset(); err != nil {
+		return id, err
+	}
+
+	// The offset is in [0, 2*jitter] and the timestamp is shifted by offset-jitter,
+	// clamped to [0, maxTime] without overflowing.
+	switch {
+	case offset < g.jitter:
+		ms -= min(ms, g.jitter-offset)
+	default:
+		ms += min(maxTime-ms, offset-g.jitter)
+	}
+
+	ms = max(ms, g.floor)
+	if err = id.SetTime(ms); err != nil {
+		return Zero, err
+	}
+
+	if err = g.mono.MonotonicRead(ms, id[6:]); err != nil {
+		return Zero, err
+	}
+
+	g.floor = ms
+	return id, nil
+}
+
+// Floor returns the timestamp of the last ULID returned by the generator, which
+// is the minimum timestamp of the next ULID, or 0 if it has not returned a ULID.
+func (g *FuzzedGenerator) Floor() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.floor
+}
+
+// offset returns a uniform random value in [0, 2*jitter], rejecting the values
+// of the entropy that would bias the result.
+func (g *FuzzedGenerator) offset() (uint64, error) {
+	if g.jitter == 0 {
+		return 0, nil
+	}
+
+	n := 2*g.jitter + 1
+	limit := math.MaxUint64 - math.MaxUint64%n
+	for {
+		if _, err := io.ReadFull(g.mono.Reader, g.buf[:]); err != nil {
+			return 0, err
+		}
+
+		if v := binary.BigEndian.Uint64(g.buf[:]); v < limit {
+			return v % n, nil
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ulid_test
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestFuzzedGenerator(t *testing.T) {
+	t.Parallel()
+
+	const jitter = 500
+	start := time.Date(2025, 2, 6, 21, 11, 53, 0, time.UTC)
+
+	t.Run("Bounds", func(t *testing.T) {
+		// A fresh generator for each ULID has no floor, so every offset is seen.
+		rng := rand.New(rand.NewSource(42))
+		ms := ulid.Timestamp(start)
+		lo, hi := ms, ms
+		for i := 0; i < 10000; i++ {
+			id, err := ulid.NewFuzzedGenerator(jitter*time.Millisecond, rng).New(start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lo, hi = min(lo, id.Time()), max(hi, id.Time())
+		}
+
+		if lo != ms-jitter || hi != ms+jitter {
+			t.Errorf("got offsets from %d to %d, want %d to %d", int64(lo-ms), int64(hi-ms), -jitter, jitter)
+		}
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		// Bursts within the jitter and gaps beyond it never sort backwards, and each
+		// timestamp is within the jitter of its time.
+		rng := rand.New(rand.NewSource(7))
+		g := ulid.NewFuzzedGenerator(jitter*time.Millisecond, rng)
+		now := start
+
+		var prev ulid.ULID
+		for i := 0; i < 10000; i++ {
+			if i%100 == 0 {
+				now = now.Add(time.Duration(rng.Intn(2*jitter)) * time.Millisecond)
+			} else if i%7 == 0 {
+				now = now.Add(time.Millisecond)
+			}
+
+			id, err := g.New(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id.Compare(prev) <= 0 {
+				t.Fatalf("%d: expected %s to sort after %s", i, id, prev)
+			}
+
+			if offset := int64(id.Time()) - int64(ulid.Timestamp(now)); offset < -jitter || offset > jitter {
+				t.Fatalf("%d: offset %d is out of bounds", i, offset)
+			}
+
+			if g.Floor() != id.Time() {
+				t.Fatalf("%d: expected the floor to be %d, got %d", i, id.Time(), g.Floor())
+			}
+			prev = id
+		}
+
+		// A time before the floor still sorts after the previous ULID.
+		id, err := g.New(start)
+		if err != nil || id.Compare(prev) <= 0 {
+			t.Errorf("expected %s (%v) to sort after %s", id, err, prev)
+		}
+	})
+
+	t.Run("Clamping", func(t *testing.T) {
+		// About half of the offsets are clamped at each end of the range.
+		rng := rand.New(rand.NewSource(1))
+		var epoch, last int
+		for i := 0; i < 1000; i++ {
+			g := ulid.NewFuzzedGenerator(time.Hour, rng)
+			id, err := g.New(time.UnixMilli(0))
+			if err != nil || id.Time() > uint64(time.Hour.Milliseconds()) {
+				t.Fatalf("got %s (%v) at the epoch", id, err)
+			}
+
+			if id.Time() == 0 {
+				epoch++
+			}
+
+			id, err = g.New(ulid.MaxTimestampTime())
+			if err != nil || id.Time() < ulid.MaxTime()-uint64(time.Hour.Milliseconds()) {
+				t.Fatalf("got %s (%v) at MaxTime", id, err)
+			}
+
+			if id.Time() == ulid.MaxTime() {
+				last++
+			}
+		}
+
+		if epoch < 400 || last < 400 {
+			t.Errorf("expected the offsets to be clamped, got %d at the epoch and %d at MaxTime", epoch, last)
+		}
+
+		// A jitter beyond the range of timestamps is clamped at both ends.
+		g := ulid.NewFuzzedGenerator(time.Duration(1<<63-1), rng)
+		if _, err := g.New(time.UnixMilli(1)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("NoJitter", func(t *testing.T) {
+		for _, d := range []time.Duration{0, -time.Second, time.Microsecond} {
+			if id, err := ulid.NewFuzzedGenerator(d, nil).New(start); err != nil || id.Time() != ulid.Timestamp(start) {
+				t.Errorf("%s: expected the exact time, got %s (%v)", d, id.Timestamp(), err)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		g := ulid.NewFuzzedGenerator(time.Second, nil)
+		if _, err := g.New(time.Unix(-1, 0)); err != ulid.ErrSmallTime {
+			t.Errorf("got error %v, want %v", err, ulid.ErrSmallTime)
+		}
+
+		if _, err := g.New(ulid.MaxTimestampTime().Add(time.Millisecond)); err != ulid.ErrBigTime {
+			t.Errorf("got error %v, want %v", err, ulid.ErrBigTime)
+		}
+
+		if g.Floor() != 0 {
+			t.Errorf("expected no floor after errors, got %d", g.Floor())
+		}
+
+		if _, err := ulid.NewFuzzedGenerator(time.Second, iotest.ErrReader(io.ErrUnexpectedEOF)).New(start); err != io.ErrUnexpectedEOF {
+			t.Errorf("got error %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+	})
+}
+
+func TestFuzzedGeneratorConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// Goroutines sharing a generator each see increasing ULIDs and no duplicates.
+	g := ulid.NewFuzzedGenerator(100*time.Millisecond, nil)
+	results := make([][]ulid.ULID, 8)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				id, err := g.New(time.Now())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[i] = append(results[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	checkSequences(t, results, 1600)
+}
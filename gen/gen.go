@@ -0,0 +1,425 @@
+// Package gen generates the Go source of typed IDs over ULIDs, e.g. UserID and
+// OrderID types that are each a ULID but cannot be assigned or converted to one
+// another, so that an order ID cannot be passed where a user ID is expected. It is
+// usually run with the ulidgen command from a go:generate directive:
+//
+//	//go:generate go run go.rtnl.ai/ulid/cmd/ulidgen -o ids_gen.go -test ids_gen_test.go UserID:usr OrderID:ord
+//
+// Each type is a struct over a ulid.ULID with a zero-sized field of a type of its
+// own, so the types of a package have different underlying types: the compiler
+// rejects both the assignment and the conversion of one ID type to another, while
+// the types are still comparable and usable as map keys. The generated code
+// includes a New and a Parse function for each type, String and IsZero, the text
+// and JSON encodings, and the sql.Scanner and driver.Valuer interfaces; ULID and
+// the <Type>Of function convert explicitly to and from ulid.ULID.
+//
+// A type may have a prefix, e.g. "usr", in which case its text and JSON forms are
+// the prefix, an underscore, and the canonical ULID, as in
+// "usr_01ARZ3NDEKTSV4RRFFQ69G5FAV", and its Parse function rejects text without
+// the prefix with the ErrInvalidPrefix error declared in the generated file. The
+// prefix is not stored in databases: Value returns the binary ULID like
+// ulid.ULID.Value, and Scan accepts the values that ulid.ULID.Scan accepts as well
+// as strings and byte slices of the text form with the prefix.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var (
+	// Returned when the name of a type is not an exported Go identifier.
+	ErrInvalidName = errors.New("gen: type name is not an exported identifier")
+
+	// Returned when a prefix is not a lowercase letter followed by lowercase
+	// letters and digits.
+	ErrInvalidPrefix = errors.New("gen: invalid prefix")
+
+	// Returned when two types have the same name or prefix.
+	ErrDuplicate = errors.New("gen: duplicate type")
+
+	// Returned when the package name is not a Go identifier.
+	ErrInvalidPackage = errors.New("gen: invalid package name")
+)
+
+// Type is an ID type to generate.
+type Type struct {
+	Name   string // The name of the type, e.g. "UserID"
+	Prefix string // The prefix of its text form without the underscore, e.g. "usr", or empty
+}
+
+// ParseType parses the description of a type on the command line: its name,
+// optionally followed by a colon and its prefix, e.g. "UserID:usr".
+func ParseType(s string) (Type, error) {
+	name, prefix, _ := strings.Cut(s, ":")
+	t := Type{Name: name, Prefix: prefix}
+	return t, t.validate()
+}
+
+func (t Type) validate() error {
+	if !token.IsIdentifier(t.Name) || !token.IsExported(t.Name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, t.Name)
+	}
+
+	for i, c := range t.Prefix {
+		if !(c >= 'a' && c <= 'z') && !(i > 0 && c >= '0' && c <= '9') {
+			return fmt.Errorf("%w: %q of %s", ErrInvalidPrefix, t.Prefix, t.Name)
+		}
+	}
+	return nil
+}
+
+// unexported returns the name of the type with its leading capitals in lowercase,
+// e.g. "userID" for "UserID" and "httpID" for "HTTPID", to name its unexported
+// declarations.
+func (t Type) unexported() string {
+	runes := []rune(t.Name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+
+		// The last capital of a run before a lowercase letter starts the next word.
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// Generate returns the formatted Go source of the types in the package.
+func Generate(pkg string, types []Type) ([]byte, error) {
+	return execute(source, pkg, types)
+}
+
+// GenerateTests returns the formatted Go source of the tests of the types
+// generated by Generate, in the same package, which check that each type round
+// trips through its text, JSON, and SQL forms, rejects invalid text, and cannot be
+// converted to the other types.
+func GenerateTests(pkg string, types []Type) ([]byte, error) {
+	return execute(tests, pkg, types)
+}
+
+// templateType is a Type with the names of its unexported declarations.
+type templateType struct {
+	Type
+	Lower string
+}
+
+func execute(tmpl *template.Template, pkg string, types []Type) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPackage, pkg)
+	}
+
+	data := struct {
+		Package  string
+		Types    []templateType
+		Prefixed bool
+	}{Package: pkg}
+
+	names, prefixes := make(map[string]bool), make(map[string]bool)
+	for _, t := range types {
+		if err := t.validate(); err != nil {
+			return nil, err
+		}
+
+		if names[t.Name] || (t.Prefix != "" && prefixes[t.Prefix]) {
+			return nil, fmt.Errorf("%w: %s:%s", ErrDuplicate, t.Name, t.Prefix)
+		}
+
+		names[t.Name], prefixes[t.Prefix] = true, true
+		data.Prefixed = data.Prefixed || t.Prefix != ""
+		data.Types = append(data.Types, templateType{Type: t, Lower: t.unexported()})
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var source = template.Must(template.New("source").Parse(`// Code generated by ulidgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	{{- if .Prefixed}}
+	"bytes"
+	{{- end}}
+	"database/sql/driver"
+	"encoding/json"
+	{{- if .Prefixed}}
+	"errors"
+	"fmt"
+	"strings"
+	{{- end}}
+
+	"go.rtnl.ai/ulid"
+)
+{{- if .Prefixed}}
+
+// ErrInvalidPrefix is returned when the text form of an ID does not start with the
+// prefix of its type.
+var ErrInvalidPrefix = errors.New("{{.Package}}: missing or invalid ID prefix")
+{{- end}}
+{{range .Types}}
+//===========================================================================
+// {{.Name}}
+//===========================================================================
+
+// {{.Name}} is a ULID that cannot be assigned or converted to the other ID types.
+{{- if .Prefix}}
+// Its text form has the prefix "{{.Prefix}}_".{{end}}
+type {{.Name}} struct {
+	_  [0]{{.Lower}}Kind
+	id ulid.ULID
+}
+
+// {{.Lower}}Kind distinguishes the underlying type of {{.Name}} from the other ID types.
+type {{.Lower}}Kind struct{}
+{{if .Prefix}}
+// {{.Lower}}Prefix starts the text form of a {{.Name}}.
+const {{.Lower}}Prefix = "{{.Prefix}}_"
+{{end}}
+// New{{.Name}} returns a new {{.Name}} created by ulid.Make.
+func New{{.Name}}() {{.Name}} {
+	return {{.Name}}{id: ulid.Make()}
+}
+
+// {{.Name}}Of returns the ULID as a {{.Name}}.
+func {{.Name}}Of(id ulid.ULID) {{.Name}} {
+	return {{.Name}}{id: id}
+}
+
+// Parse{{.Name}} strictly parses the text form of a {{.Name}}.
+{{- if .Prefix}} ErrInvalidPrefix is
+// returned if the text does not start with "{{.Prefix}}_".{{end}}
+func Parse{{.Name}}(s string) ({{.Name}}, error) {
+	{{- if .Prefix}}
+	text, ok := strings.CutPrefix(s, {{.Lower}}Prefix)
+	if !ok {
+		return {{.Name}}{}, fmt.Errorf("%w: {{.Name}} %q does not start with %q", ErrInvalidPrefix, s, {{.Lower}}Prefix)
+	}
+
+	id, err := ulid.ParseStrict(text)
+	{{- else}}
+	id, err := ulid.ParseStrict(s)
+	{{- end}}
+	if err != nil {
+		return {{.Name}}{}, err
+	}
+	return {{.Name}}{id: id}, nil
+}
+
+// ULID returns the {{.Name}} as a ulid.ULID.
+func (id {{.Name}}) ULID() ulid.ULID {
+	return id.id
+}
+
+// IsZero returns true if the {{.Name}} is the zero value.
+func (id {{.Name}}) IsZero() bool {
+	return id.id.IsZero()
+}
+
+// String returns the text form of the {{.Name}}.
+func (id {{.Name}}) String() string {
+	{{- if .Prefix}}
+	return {{.Lower}}Prefix + id.id.String()
+	{{- else}}
+	return id.id.String()
+	{{- end}}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id {{.Name}}) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface with Parse{{.Name}}.
+func (id *{{.Name}}) UnmarshalText(text []byte) error {
+	parsed, err := Parse{{.Name}}(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface as a JSON string.
+func (id {{.Name}}) MarshalJSON() ([]byte, error) {
+	return []byte(` + "`" + `"` + "`" + ` + id.String() + ` + "`" + `"` + "`" + `), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. A JSON null leaves
+// the {{.Name}} unchanged.
+func (id *{{.Name}}) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(text))
+}
+
+// Scan implements the sql.Scanner interface like ulid.ULID.Scan.
+{{- if .Prefix}} Strings and byte
+// slices of the text form may have its prefix.{{end}}
+func (id *{{.Name}}) Scan(src any) error {
+	{{- if .Prefix}}
+	switch s := src.(type) {
+	case string:
+		src = strings.TrimPrefix(s, {{.Lower}}Prefix)
+	case []byte:
+		if text, ok := bytes.CutPrefix(s, []byte({{.Lower}}Prefix)); ok && len(text) == ulid.EncodedSize {
+			src = string(text)
+		}
+	}
+	{{- end}}
+	return id.id.Scan(src)
+}
+
+// Value implements the driver.Valuer interface like ulid.ULID.Value.
+func (id {{.Name}}) Value() (driver.Value, error) {
+	return id.id.Value()
+}
+{{end}}`))
+
+var tests = template.Must(template.New("tests").Parse(`// Code generated by ulidgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	{{- if .Prefixed}}
+	"errors"
+	{{- end}}
+	"reflect"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+{{range .Types}}
+func Test{{.Name}}(t *testing.T) {
+	id := New{{.Name}}()
+	if id.IsZero() || id.ULID().IsZero() {
+		t.Fatal("expected a new {{.Name}}")
+	}
+
+	if {{.Name}}Of(id.ULID()) != id {
+		t.Error("expected the {{.Name}} of its ULID")
+	}
+
+	text := id.String()
+	if want := "{{with .Prefix}}{{.}}_{{end}}" + id.ULID().String(); text != want {
+		t.Errorf("got %q, want %q", text, want)
+	}
+
+	if parsed, err := Parse{{.Name}}(text); err != nil || parsed != id {
+		t.Errorf("got %s (%v), want %s", parsed, err, id)
+	}
+
+	for _, invalid := range []string{"", text[:len(text)-1], text + "0", text[:len(text)-1] + "U"} {
+		if parsed, err := Parse{{.Name}}(invalid); err == nil || !parsed.IsZero() {
+			t.Errorf("%q: expected an error, got %s", invalid, parsed)
+		}
+	}
+	{{- if .Prefix}}
+
+	for _, invalid := range []string{id.ULID().String(), "x" + text, "{{.Prefix}}-" + id.ULID().String()} {
+		if _, err := Parse{{.Name}}(invalid); !errors.Is(err, ErrInvalidPrefix) {
+			t.Errorf("%q: got %v, want %v", invalid, err, ErrInvalidPrefix)
+		}
+	}
+	{{- end}}
+
+	var decoded {{.Name}}
+	if data, err := id.MarshalText(); err != nil || string(data) != text {
+		t.Errorf("got %q (%v), want %q", data, err, text)
+	} else if err := decoded.UnmarshalText(data); err != nil || decoded != id {
+		t.Errorf("got %s (%v), want %s", decoded, err, id)
+	}
+
+	type record struct {
+		ID      {{.Name}}  ` + "`" + `json:"id"` + "`" + `
+		Missing {{.Name}}  ` + "`" + `json:"missing"` + "`" + `
+		Null    *{{.Name}} ` + "`" + `json:"null"` + "`" + `
+	}
+
+	data, err := json.Marshal(record{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got record
+	if err := json.Unmarshal(data, &got); err != nil || got.ID != id || !got.Missing.IsZero() {
+		t.Errorf("got %+v (%v) from %s", got, err, data)
+	}
+
+	if err := json.Unmarshal([]byte(` + "`" + `{"id": null, "null": null}` + "`" + `), &got); err != nil || got.ID != id || got.Null != nil {
+		t.Errorf("expected null to leave the {{.Name}} unchanged, got %+v (%v)", got, err)
+	}
+
+	for _, invalid := range []string{` + "`" + `{"id": 42}` + "`" + `, ` + "`" + `{"id": "not an id"}` + "`" + `} {
+		if err := json.Unmarshal([]byte(invalid), &got); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+
+	value, err := id.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []any{value, id.ULID().String(), text{{if .Prefix}}, []byte(text){{end}}} {
+		var scanned {{.Name}}
+		if err := scanned.Scan(src); err != nil || scanned != id {
+			t.Errorf("%v: scanned %s (%v), want %s", src, scanned, err, id)
+		}
+	}
+
+	var scanned {{.Name}}
+	if err := scanned.Scan(3.14); err == nil {
+		t.Error("expected an error scanning a float")
+	}
+
+	var zero {{.Name}}
+	if !zero.IsZero() {
+		t.Error("expected the zero value to be zero")
+	}
+}
+{{end}}
+// TestDistinctTypes checks that the ID types cannot be converted to one another
+// or to ulid.ULID, so that they cannot be mixed up.
+func TestDistinctTypes(t *testing.T) {
+	types := []reflect.Type{
+		reflect.TypeFor[ulid.ULID](),
+		{{- range .Types}}
+		reflect.TypeFor[{{.Name}}](),
+		{{- end}}
+	}
+
+	for _, a := range types {
+		for _, b := range types {
+			if a != b && a.ConvertibleTo(b) {
+				t.Errorf("%s can be converted to %s", a, b)
+			}
+		}
+
+		if !a.Comparable() {
+			t.Errorf("%s is not comparable", a)
+		}
+	}
+}
+`))
@@ -0,0 +1,199 @@
+package gen_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid/gen"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the example package")
+
+// exampleTypes returns the types of the go:generate directive of the example
+// package, which are the types of its golden files.
+func exampleTypes(t *testing.T) []gen.Type {
+	t.Helper()
+	src, err := os.ReadFile(filepath.Join("internal", "example", "example.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(string(src), "\n") {
+		if args, ok := strings.CutPrefix(line, "//go:generate "); ok {
+			var types []gen.Type
+			for _, arg := range strings.Fields(args) {
+				if arg[0] >= 'A' && arg[0] <= 'Z' {
+					typ, err := gen.ParseType(arg)
+					if err != nil {
+						t.Fatal(err)
+					}
+					types = append(types, typ)
+				}
+			}
+			return types
+		}
+	}
+
+	t.Fatal("no go:generate directive in the example package")
+	return nil
+}
+
+func TestGolden(t *testing.T) {
+	t.Parallel()
+
+	types := exampleTypes(t)
+	if len(types) == 0 {
+		t.Fatal("expected the types of the example package")
+	}
+
+	for name, generate := range map[string]func(string, []gen.Type) ([]byte, error){
+		"ids_gen.go":      gen.Generate,
+		"ids_gen_test.go": gen.GenerateTests,
+	} {
+		got, err := generate("example", types)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join("internal", "example", name)
+		if *update {
+			if err := os.WriteFile(path, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate ./gen/... or go test ./gen -update", path)
+		}
+	}
+}
+
+// imports returns the import paths of the source.
+func imports(t *testing.T, src []byte) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	// Without prefixes, the packages that only the prefixes use are not imported.
+	types := []gen.Type{{Name: "ID"}, {Name: "AccountID"}}
+	src, err := gen.Generate("models", types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := imports(t, src), []string{"database/sql/driver", "encoding/json", "go.rtnl.ai/ulid"}; !slices.Equal(got, want) {
+		t.Errorf("got imports %v, want %v", got, want)
+	}
+
+	for _, decl := range []string{"package models", "type idKind struct{}", "type accountIDKind struct{}", "func ParseID(s string) (ID, error) {", "func NewAccountID() AccountID {"} {
+		if !bytes.Contains(src, []byte(decl)) {
+			t.Errorf("expected %q in the generated source", decl)
+		}
+	}
+
+	if src, err = gen.GenerateTests("models", types); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := imports(t, src), []string{"encoding/json", "reflect", "testing", "go.rtnl.ai/ulid"}; !slices.Equal(got, want) {
+		t.Errorf("got test imports %v, want %v", got, want)
+	}
+
+	// The leading capitals of a name are lowercase in its unexported declarations.
+	for name, lower := range map[string]string{"UserID": "userID", "HTTPRequestID": "httpRequestID", "URL": "url", "X": "x"} {
+		src, err := gen.Generate("models", []gen.Type{{Name: name, Prefix: "x"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Contains(src, []byte("const "+lower+"Prefix = \"x_\"")) {
+			t.Errorf("%s: expected the unexported name %s", name, lower)
+		}
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pkg   string
+		types []gen.Type
+		err   error
+	}{
+		{"models", []gen.Type{{Name: "userID"}}, gen.ErrInvalidName},
+		{"models", []gen.Type{{Name: "User ID"}}, gen.ErrInvalidName},
+		{"models", []gen.Type{{Name: ""}}, gen.ErrInvalidName},
+		{"models", []gen.Type{{Name: "UserID", Prefix: "USR"}}, gen.ErrInvalidPrefix},
+		{"models", []gen.Type{{Name: "UserID", Prefix: "1usr"}}, gen.ErrInvalidPrefix},
+		{"models", []gen.Type{{Name: "UserID", Prefix: "us_r"}}, gen.ErrInvalidPrefix},
+		{"models", []gen.Type{{Name: "UserID"}, {Name: "UserID", Prefix: "usr"}}, gen.ErrDuplicate},
+		{"models", []gen.Type{{Name: "UserID", Prefix: "usr"}, {Name: "AdminID", Prefix: "usr"}}, gen.ErrDuplicate},
+		{"my-models", []gen.Type{{Name: "UserID"}}, gen.ErrInvalidPackage},
+		{"", []gen.Type{{Name: "UserID"}}, gen.ErrInvalidPackage},
+	}
+
+	for _, tc := range testCases {
+		if src, err := gen.Generate(tc.pkg, tc.types); !errors.Is(err, tc.err) || src != nil {
+			t.Errorf("%s %v: got %v, want %v", tc.pkg, tc.types, err, tc.err)
+		}
+
+		if _, err := gen.GenerateTests(tc.pkg, tc.types); !errors.Is(err, tc.err) {
+			t.Errorf("%s %v: got %v from the tests, want %v", tc.pkg, tc.types, err, tc.err)
+		}
+	}
+
+	// Types without prefixes do not collide.
+	if _, err := gen.Generate("models", []gen.Type{{Name: "A"}, {Name: "B"}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		arg      string
+		expected gen.Type
+		err      error
+	}{
+		{"UserID", gen.Type{Name: "UserID"}, nil},
+		{"UserID:usr", gen.Type{Name: "UserID", Prefix: "usr"}, nil},
+		{"UserID:", gen.Type{Name: "UserID"}, nil},
+		{"userID:usr", gen.Type{Name: "userID", Prefix: "usr"}, gen.ErrInvalidName},
+		{"UserID:usr:v2", gen.Type{Name: "UserID", Prefix: "usr:v2"}, gen.ErrInvalidPrefix},
+	}
+
+	for _, tc := range testCases {
+		if got, err := gen.ParseType(tc.arg); got != tc.expected || !errors.Is(err, tc.err) || (tc.err == nil) != (err == nil) {
+			t.Errorf("%s: got %+v (%v), want %+v (%v)", tc.arg, got, err, tc.expected, tc.err)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+// Package example is generated by ulidgen as the golden output of the gen
+// package, so that the generated code is compiled and its generated tests are
+// run with the tests of this module. Regenerate it with go generate after
+// changing the templates; the tests of the gen package fail until it is.
+package example
+
+//go:generate go run go.rtnl.ai/ulid/cmd/ulidgen -o ids_gen.go -test ids_gen_test.go UserID:usr OrderID:ord HTTPRequestID:req1 Token
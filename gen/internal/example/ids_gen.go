@@ -0,0 +1,443 @@
+// Code generated by ulidgen. DO NOT EDIT.
+
+package example
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.rtnl.ai/ulid"
+)
+
+// ErrInvalidPrefix is returned when the text form of an ID does not start with the
+// prefix of its type.
+var ErrInvalidPrefix = errors.New("example: missing or invalid ID prefix")
+
+//===========================================================================
+// UserID
+//===========================================================================
+
+// UserID is a ULID that cannot be assigned or converted to the other ID types.
+// Its text form has the prefix "usr_".
+type UserID struct {
+	_  [0]userIDKind
+	id ulid.ULID
+}
+
+// userIDKind distinguishes the underlying type of UserID from the other ID types.
+type userIDKind struct{}
+
+// userIDPrefix starts the text form of a UserID.
+const userIDPrefix = "usr_"
+
+// NewUserID returns a new UserID created by ulid.Make.
+func NewUserID() UserID {
+	return UserID{id: ulid.Make()}
+}
+
+// UserIDOf returns the ULID as a UserID.
+func UserIDOf(id ulid.ULID) UserID {
+	return UserID{id: id}
+}
+
+// ParseUserID strictly parses the text form of a UserID. ErrInvalidPrefix is
+// returned if the text does not start with "usr_".
+func ParseUserID(s string) (UserID, error) {
+	text, ok := strings.CutPrefix(s, userIDPrefix)
+	if !ok {
+		return UserID{}, fmt.Errorf("%w: UserID %q does not start with %q", ErrInvalidPrefix, s, userIDPrefix)
+	}
+
+	id, err := ulid.ParseStrict(text)
+	if err != nil {
+		return UserID{}, err
+	}
+	return UserID{id: id}, nil
+}
+
+// ULID returns the UserID as a ulid.ULID.
+func (id UserID) ULID() ulid.ULID {
+	return id.id
+}
+
+// IsZero returns true if the UserID is the zero value.
+func (id UserID) IsZero() bool {
+	return id.id.IsZero()
+}
+
+// String returns the text form of the UserID.
+func (id UserID) String() string {
+	return userIDPrefix + id.id.String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id UserID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface with ParseUserID.
+func (id *UserID) UnmarshalText(text []byte) error {
+	parsed, err := ParseUserID(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface as a JSON string.
+func (id UserID) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + id.String() + `"`), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. A JSON null leaves
+// the UserID unchanged.
+func (id *UserID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(text))
+}
+
+// Scan implements the sql.Scanner interface like ulid.ULID.Scan. Strings and byte
+// slices of the text form may have its prefix.
+func (id *UserID) Scan(src any) error {
+	switch s := src.(type) {
+	case string:
+		src = strings.TrimPrefix(s, userIDPrefix)
+	case []byte:
+		if text, ok := bytes.CutPrefix(s, []byte(userIDPrefix)); ok && len(text) == ulid.EncodedSize {
+			src = string(text)
+		}
+	}
+	return id.id.Scan(src)
+}
+
+// Value implements the driver.Valuer interface like ulid.ULID.Value.
+func (id UserID) Value() (driver.Value, error) {
+	return id.id.Value()
+}
+
+//===========================================================================
+// OrderID
+//===========================================================================
+
+// OrderID is a ULID that cannot be assigned or converted to the other ID types.
+// Its text form has the prefix "ord_".
+type OrderID struct {
+	_  [0]orderIDKind
+	id ulid.ULID
+}
+
+// orderIDKind distinguishes the underlying type of OrderID from the other ID types.
+type orderIDKind struct{}
+
+// orderIDPrefix starts the text form of a OrderID.
+const orderIDPrefix = "ord_"
+
+// NewOrderID returns a new OrderID created by ulid.Make.
+func NewOrderID() OrderID {
+	return OrderID{id: ulid.Make()}
+}
+
+// OrderIDOf returns the ULID as a OrderID.
+func OrderIDOf(id ulid.ULID) OrderID {
+	return OrderID{id: id}
+}
+
+// ParseOrderID strictly parses the text form of a OrderID. ErrInvalidPrefix is
+// returned if the text does not start with "ord_".
+func ParseOrderID(s string) (OrderID, error) {
+	text, ok := strings.CutPrefix(s, orderIDPrefix)
+	if !ok {
+		return OrderID{}, fmt.Errorf("%w: OrderID %q does not start with %q", ErrInvalidPrefix, s, orderIDPrefix)
+	}
+
+	id, err := ulid.ParseStrict(text)
+	if err != nil {
+		return OrderID{}, err
+	}
+	return OrderID{id: id}, nil
+}
+
+// ULID returns the OrderID as a ulid.ULID.
+func (id OrderID) ULID() ulid.ULID {
+	return id.id
+}
+
+// IsZero returns true if the OrderID is the zero value.
+func (id OrderID) IsZero() bool {
+	return id.id.IsZero()
+}
+
+// String returns the text form of the OrderID.
+func (id OrderID) String() string {
+	return orderIDPrefix + id.id.String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id OrderID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface with ParseOrderID.
+func (id *OrderID) UnmarshalText(text []byte) error {
+	parsed, err := ParseOrderID(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface as a JSON string.
+func (id OrderID) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + id.String() + `"`), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. A JSON null leaves
+// the OrderID unchanged.
+func (id *OrderID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(text))
+}
+
+// Scan implements the sql.Scanner interface like ulid.ULID.Scan. Strings and byte
+// slices of the text form may have its prefix.
+func (id *OrderID) Scan(src any) error {
+	switch s := src.(type) {
+	case string:
+		src = strings.TrimPrefix(s, orderIDPrefix)
+	case []byte:
+		if text, ok := bytes.CutPrefix(s, []byte(orderIDPrefix)); ok && len(text) == ulid.EncodedSize {
+			src = string(text)
+		}
+	}
+	return id.id.Scan(src)
+}
+
+// Value implements the driver.Valuer interface like ulid.ULID.Value.
+func (id OrderID) Value() (driver.Value, error) {
+	return id.id.Value()
+}
+
+//===========================================================================
+// HTTPRequestID
+//===========================================================================
+
+// HTTPRequestID is a ULID that cannot be assigned or converted to the other ID types.
+// Its text form has the prefix "req1_".
+type HTTPRequestID struct {
+	_  [0]httpRequestIDKind
+	id ulid.ULID
+}
+
+// httpRequestIDKind distinguishes the underlying type of HTTPRequestID from the other ID types.
+type httpRequestIDKind struct{}
+
+// httpRequestIDPrefix starts the text form of a HTTPRequestID.
+const httpRequestIDPrefix = "req1_"
+
+// NewHTTPRequestID returns a new HTTPRequestID created by ulid.Make.
+func NewHTTPRequestID() HTTPRequestID {
+	return HTTPRequestID{id: ulid.Make()}
+}
+
+// HTTPRequestIDOf returns the ULID as a HTTPRequestID.
+func HTTPRequestIDOf(id ulid.ULID) HTTPRequestID {
+	return HTTPRequestID{id: id}
+}
+
+// ParseHTTPRequestID strictly parses the text form of a HTTPRequestID. ErrInvalidPrefix is
+// returned if the text does not start with "req1_".
+func ParseHTTPRequestID(s string) (HTTPRequestID, error) {
+	text, ok := strings.CutPrefix(s, httpRequestIDPrefix)
+	if !ok {
+		return HTTPRequestID{}, fmt.Errorf("%w: HTTPRequestID %q does not start with %q", ErrInvalidPrefix, s, httpRequestIDPrefix)
+	}
+
+	id, err := ulid.ParseStrict(text)
+	if err != nil {
+		return HTTPRequestID{}, err
+	}
+	return HTTPRequestID{id: id}, nil
+}
+
+// ULID returns the HTTPRequestID as a ulid.ULID.
+func (id HTTPRequestID) ULID() ulid.ULID {
+	return id.id
+}
+
+// IsZero returns true if the HTTPRequestID is the zero value.
+func (id HTTPRequestID) IsZero() bool {
+	return id.id.IsZero()
+}
+
+// String returns the text form of the HTTPRequestID.
+func (id HTTPRequestID) String() string {
+	return httpRequestIDPrefix + id.id.String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id HTTPRequestID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface with ParseHTTPRequestID.
+func (id *HTTPRequestID) UnmarshalText(text []byte) error {
+	parsed, err := ParseHTTPRequestID(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface as a JSON string.
+func (id HTTPRequestID) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + id.String() + `"`), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. A JSON null leaves
+// the HTTPRequestID unchanged.
+func (id *HTTPRequestID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(text))
+}
+
+// Scan implements the sql.Scanner interface like ulid.ULID.Scan. Strings and byte
+// slices of the text form may have its prefix.
+func (id *HTTPRequestID) Scan(src any) error {
+	switch s := src.(type) {
+	case string:
+		src = strings.TrimPrefix(s, httpRequestIDPrefix)
+	case []byte:
+		if text, ok := bytes.CutPrefix(s, []byte(httpRequestIDPrefix)); ok && len(text) == ulid.EncodedSize {
+			src = string(text)
+		}
+	}
+	return id.id.Scan(src)
+}
+
+// Value implements the driver.Valuer interface like ulid.ULID.Value.
+func (id HTTPRequestID) Value() (driver.Value, error) {
+	return id.id.Value()
+}
+
+//===========================================================================
+// Token
+//===========================================================================
+
+// Token is a ULID that cannot be assigned or converted to the other ID types.
+type Token struct {
+	_  [0]tokenKind
+	id ulid.ULID
+}
+
+// tokenKind distinguishes the underlying type of Token from the other ID types.
+type tokenKind struct{}
+
+// NewToken returns a new Token created by ulid.Make.
+func NewToken() Token {
+	return Token{id: ulid.Make()}
+}
+
+// TokenOf returns the ULID as a Token.
+func TokenOf(id ulid.ULID) Token {
+	return Token{id: id}
+}
+
+// ParseToken strictly parses the text form of a Token.
+func ParseToken(s string) (Token, error) {
+	id, err := ulid.ParseStrict(s)
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{id: id}, nil
+}
+
+// ULID returns the Token as a ulid.ULID.
+func (id Token) ULID() ulid.ULID {
+	return id.id
+}
+
+// IsZero returns true if the Token is the zero value.
+func (id Token) IsZero() bool {
+	return id.id.IsZero()
+}
+
+// String returns the text form of the Token.
+func (id Token) String() string {
+	return id.id.String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id Token) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface with ParseToken.
+func (id *Token) UnmarshalText(text []byte) error {
+	parsed, err := ParseToken(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface as a JSON string.
+func (id Token) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + id.String() + `"`), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. A JSON null leaves
+// the Token unchanged.
+func (id *Token) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(text))
+}
+
+// Scan implements the sql.Scanner interface like ulid.ULID.Scan.
+func (id *Token) Scan(src any) error {
+	return id.id.Scan(src)
+}
+
+// Value implements the driver.Valuer interface like ulid.ULID.Value.
+func (id Token) Value() (driver.Value, error) {
+	return id.id.Value()
+}
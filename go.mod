@@ -1,3 +1,5 @@
 module go.rtnl.ai/ulid
 
 go 1.23.3
+
+require github.com/oklog/ulid/v2 v2.1.2
@@ -0,0 +1,79 @@
+package ulid
+
+import "unique"
+
+// Handle is an interned, comparable reference to a ULID backed by the unique
+// package. Two handles created from equal ULIDs are themselves equal (==), and
+// comparing handles is a pointer comparison rather than a 16 byte comparison.
+// Handles are useful for deduplicating large in-memory indexes where the same
+// ULIDs are referenced many times.
+//
+// The zero value of a Handle refers to the Zero ULID and is the handle returned by
+// MakeHandle(Zero), so a zero-valued Handle field equals the handle of Zero.
+type Handle struct {
+	h unique.Handle[ULID]
+}
+
+// MakeHandle interns the given ULID and returns a handle to the canonical copy.
+// Note that this is not named Make to avoid conflicting with the package level
+// ULID generator.
+func MakeHandle(id ULID) Handle {
+	if id.IsZero() {
+		return Handle{}
+	}
+	return Handle{h: unique.Make(id)}
+}
+
+// ULID returns a copy of the interned ULID referred to by the handle.
+func (h Handle) ULID() ULID {
+	if h.h == (unique.Handle[ULID]{}) {
+		return Zero
+	}
+	return h.h.Value()
+}
+
+// String returns the string encoding of the underlying ULID.
+func (h Handle) String() string {
+	return h.ULID().String()
+}
+
+// IsZero returns true if the handle refers to the Zero ULID.
+func (h Handle) IsZero() bool {
+	return h.ULID().IsZero()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface by delegating to
+// the underlying ULID; this also allows handles to be marshaled as JSON strings.
+func (h Handle) MarshalText() ([]byte, error) {
+	return h.ULID().MarshalText()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface by parsing the
+// ULID and interning it; this also allows handles to be unmarshaled from JSON.
+func (h *Handle) UnmarshalText(data []byte) (err error) {
+	var id ULID
+	if err = id.UnmarshalText(data); err != nil {
+		return err
+	}
+
+	*h = MakeHandle(id)
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface by delegating
+// to the underlying ULID.
+func (h Handle) MarshalBinary() ([]byte, error) {
+	return h.ULID().MarshalBinary()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface by
+// copying the data into a ULID and interning it.
+func (h *Handle) UnmarshalBinary(data []byte) (err error) {
+	var id ULID
+	if err = id.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*h = MakeHandle(id)
+	return nil
+}
@@ -0,0 +1,147 @@
+package ulid_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Equality", func(t *testing.T) {
+		id := ulid.Make()
+		a, b := ulid.MakeHandle(id), ulid.MakeHandle(ulid.MustParse(id.String()))
+		if a != b {
+			t.Errorf("expected handles for equal ULIDs to be ==")
+		}
+
+		if a.ULID() != id {
+			t.Errorf("got ULID %s, want %s", a.ULID(), id)
+		}
+
+		c := ulid.MakeHandle(ulid.Make())
+		if a == c {
+			t.Errorf("expected handles for different ULIDs to be !=")
+		}
+	})
+
+	t.Run("Zero", func(t *testing.T) {
+		var h ulid.Handle
+		if !h.IsZero() || h.ULID() != ulid.Zero {
+			t.Errorf("expected zero-value handle to refer to the zero ULID")
+		}
+
+		if h.String() != ulid.Zero.String() {
+			t.Errorf("got %q, want %q", h.String(), ulid.Zero.String())
+		}
+
+		if ulid.MakeHandle(ulid.Zero) != h {
+			t.Errorf("expected the handle of the zero ULID to equal the zero-value handle")
+		}
+
+		if err := h.UnmarshalText([]byte(ulid.Zero.String())); err != nil || h != (ulid.Handle{}) {
+			t.Errorf("expected the unmarshaled zero ULID to be the zero-value handle (%v)", err)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		type record struct {
+			ID ulid.Handle `json:"id"`
+		}
+
+		id := ulid.Make()
+		data, err := json.Marshal(record{ID: ulid.MakeHandle(id)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := `{"id":"` + id.String() + `"}`; string(data) != want {
+			t.Fatalf("got %s, want %s", data, want)
+		}
+
+		var out record
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.ID != ulid.MakeHandle(id) {
+			t.Errorf("expected unmarshaled handle to be interned")
+		}
+
+		if err := json.Unmarshal([]byte(`{"id":"foo"}`), &out); err != ulid.ErrDataSize {
+			t.Errorf("got err %v, want %v", err, ulid.ErrDataSize)
+		}
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		id := ulid.Make()
+		data, err := ulid.MakeHandle(id).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var h ulid.Handle
+		if err := h.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if h.ULID() != id {
+			t.Errorf("got ULID %s, want %s", h.ULID(), id)
+		}
+
+		if err := h.UnmarshalBinary([]byte{0x1}); err != ulid.ErrDataSize {
+			t.Errorf("got err %v, want %v", err, ulid.ErrDataSize)
+		}
+	})
+}
+
+// BenchmarkHandleMemory compares the heap used by 1M references drawn from a set
+// of 10k distinct ULIDs when stored as ULID strings versus interned handles.
+func BenchmarkHandleMemory(b *testing.B) {
+	const (
+		total    = 1000000
+		distinct = 10000
+	)
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := make([]ulid.ULID, distinct)
+	for i := range ids {
+		ids[i] = ulid.MustNew(ulid.Now(), rng)
+	}
+
+	heap := func() uint64 {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+
+	b.Run("String", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			before := heap()
+			refs := make([]string, total)
+			for j := range refs {
+				refs[j] = ids[rng.Intn(distinct)].String()
+			}
+			b.ReportMetric(float64(int64(heap())-int64(before))/total, "B/ref")
+			runtime.KeepAlive(refs)
+		}
+	})
+
+	b.Run("Handle", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			before := heap()
+			refs := make([]ulid.Handle, total)
+			for j := range refs {
+				refs[j] = ulid.MakeHandle(ids[rng.Intn(distinct)])
+			}
+			b.ReportMetric(float64(int64(heap())-int64(before))/total, "B/ref")
+			runtime.KeepAlive(refs)
+		}
+	})
+}
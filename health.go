@@ -0,0 +1,114 @@
+package ulid
+
+import "sync/atomic"
+
+// RecentTimestamps is the number of timestamps of the most recently made ULIDs
+// that are reported by ReadHealth.
+const RecentTimestamps = 16
+
+// health are the process-wide counters reported by ReadHealth. The counters of
+// made ULIDs are only updated when healthEnabled is set, since the atomic
+// operations of every Make on a shared cache line contend between cores.
+var health struct {
+	made          atomic.Uint64
+	madeSecure    atomic.Uint64
+	overflows     atomic.Uint64
+	lastOverflow  atomic.Uint64
+	parseFailures atomic.Uint64
+	next          atomic.Uint64
+	recent        [RecentTimestamps]atomic.Uint64
+}
+
+// healthEnabled is set by EnableHealth.
+var healthEnabled atomic.Bool
+
+// EnableHealth enables or disables counting the ULIDs made by the Make functions
+// and recording their timestamps for ReadHealth, and returns whether it was
+// enabled before. It is disabled by default, since every Make would otherwise
+// update the same counters, which contends when ULIDs are made on many cores at
+// once; the handlers of the go.rtnl.ai/ulid/health package enable it. Overflows
+// and parse failures are always counted.
+func EnableHealth(enabled bool) (previous bool) {
+	return healthEnabled.Swap(enabled)
+}
+
+// Health reports the health of ULID generation in the process, e.g. to expose it
+// on a debug endpoint with the go.rtnl.ai/ulid/health package. Only timestamps
+// are reported rather than ULIDs so that the report does not leak identifiers.
+type Health struct {
+	// Made is the number of ULIDs created by Make, MakeAt, and MakeMonotonicTime
+	// while EnableHealth was enabled.
+	Made uint64 `json:"made"`
+
+	// MadeSecure is the number of ULIDs created by MakeSecure and MakeSecureAt
+	// while EnableHealth was enabled.
+	MadeSecure uint64 `json:"made_secure"`
+
+	// Overflows is the number of times that MonotonicEntropy or SequentialEntropy
+	// returned ErrMonotonicOverflow, and LastOverflow is the Unix milliseconds
+	// timestamp of the most recent overflow, or zero if there were none.
+	Overflows    uint64 `json:"overflows"`
+	LastOverflow uint64 `json:"last_overflow,omitempty"`
+
+	// ParseFailures is the number of times that Parse, ParseStrict, UnmarshalText,
+	// or Scan failed since the process started; see SetParseFailureHook to find
+	// the inputs that failed.
+	ParseFailures uint64 `json:"parse_failures"`
+
+	// Entropy and SecureEntropy describe DefaultEntropy and SecureEntropy.
+	Entropy       GeneratorInfo `json:"entropy"`
+	SecureEntropy GeneratorInfo `json:"secure_entropy"`
+
+	// WallClock and MonotonicClock are the current times in Unix milliseconds of
+	// Now and NowMonotonic.
+	WallClock      uint64 `json:"wall_clock"`
+	MonotonicClock uint64 `json:"monotonic_clock"`
+
+	// Recent are the timestamps of up to RecentTimestamps of the most recently
+	// made ULIDs while EnableHealth was enabled, oldest first. The order of ULIDs made concurrently is arbitrary.
+	Recent []uint64 `json:"recent"`
+}
+
+// ReadHealth returns the current health of ULID generation in the process. The
+// counters are read individually and may be slightly inconsistent with each
+// other if ULIDs are made concurrently.
+func ReadHealth() Health {
+	h := Health{
+		Made:           health.made.Load(),
+		MadeSecure:     health.madeSecure.Load(),
+		Overflows:      health.overflows.Load(),
+		LastOverflow:   health.lastOverflow.Load(),
+		ParseFailures:  health.parseFailures.Load(),
+		Entropy:        DefaultGeneratorInfo(),
+		SecureEntropy:  SecureGeneratorInfo(),
+		WallClock:      Now(),
+		MonotonicClock: NowMonotonic(),
+	}
+
+	next := health.next.Load()
+	start := next - min(next, RecentTimestamps)
+	h.Recent = make([]uint64, 0, next-start)
+	for i := start; i < next; i++ {
+		h.Recent = append(h.Recent, health.recent[i%RecentTimestamps].Load())
+	}
+	return h
+}
+
+// recordMade counts a ULID made by Make or MakeSecure and records its timestamp
+// if EnableHealth is enabled.
+func recordMade(counter *atomic.Uint64, id ULID) ULID {
+	if !healthEnabled.Load() {
+		return id
+	}
+
+	counter.Add(1)
+	i := health.next.Add(1) - 1
+	health.recent[i%RecentTimestamps].Store(id.Time())
+	return id
+}
+
+// recordOverflow records an overflow of monotonic entropy at the timestamp.
+func recordOverflow(ms uint64) {
+	health.overflows.Add(1)
+	health.lastOverflow.Store(ms)
+}
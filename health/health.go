@@ -0,0 +1,62 @@
+// Package health exposes the health of ULID generation in the process, as
+// reported by ulid.ReadHealth, on a debug endpoint or as an expvar, e.g.
+//
+//	mux.Handle("/debug/ulid", health.Handler())
+//
+// The report includes the number of ULIDs created by Make and MakeSecure, the
+// last monotonic entropy overflow, the entropy configuration, the current wall
+// and monotonic clocks, and the timestamps (but not the ULIDs) of the most
+// recently made ULIDs. Handler and RegisterExpvar enable the counting of made
+// ULIDs with ulid.EnableHealth. Importing the package also imports expvar, which
+// registers the /debug/vars handler on http.DefaultServeMux.
+package health
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+
+	"go.rtnl.ai/ulid"
+)
+
+// DefaultPrefix is the name of the expvar published by RegisterExpvar if the
+// prefix is empty.
+const DefaultPrefix = "ulid"
+
+// Handler returns an http.Handler that responds to GET and HEAD requests with
+// the current ulid.Health of the process as JSON.
+func Handler() http.Handler {
+	ulid.EnableHealth(true)
+	return http.HandlerFunc(serveHealth)
+}
+
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(ulid.ReadHealth())
+}
+
+// RegisterExpvar publishes the current ulid.Health of the process as an expvar
+// named by the prefix, or DefaultPrefix if it is empty, so that it is included
+// in the output of /debug/vars. Like expvar.Publish, it panics if a variable with
+// the name is already registered.
+func RegisterExpvar(prefix string) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	ulid.EnableHealth(true)
+	expvar.Publish(prefix, expvar.Func(func() any { return ulid.ReadHealth() }))
+}
@@ -0,0 +1,156 @@
+package health_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/health"
+)
+
+// fetch returns the JSON health report of the handler as fields to check the
+// schema, the raw body, and the typed report.
+func fetch(t *testing.T, h http.Handler) (map[string]any, string, ulid.Health) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/ulid", nil))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got status %d and content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var (
+		fields map[string]any
+		report ulid.Health
+	)
+	if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return fields, rec.Body.String(), report
+}
+
+func TestHandler(t *testing.T) {
+	h := health.Handler()
+	fields, _, before := fetch(t, h)
+
+	for _, key := range []string{"made", "made_secure", "overflows", "parse_failures", "entropy", "secure_entropy", "wall_clock", "monotonic_clock", "recent"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("expected the report to have the field %q", key)
+		}
+	}
+
+	for _, key := range []string{"version", "entropy", "monotonic"} {
+		if _, ok := fields["entropy"].(map[string]any)[key]; !ok {
+			t.Errorf("expected the entropy to have the field %q", key)
+		}
+	}
+
+	// The counters move after generating ULIDs, and the most recent timestamps
+	// are reported without the ULIDs.
+	var ids []ulid.ULID
+	for i := 0; i < 3; i++ {
+		ids = append(ids, ulid.Make())
+	}
+	ids = append(ids, ulid.MakeSecure(), ulid.MakeSecureAt(time.UnixMilli(1738876313290)))
+
+	_, body, after := fetch(t, h)
+	if after.Made-before.Made < 3 || after.MadeSecure-before.MadeSecure < 2 {
+		t.Errorf("expected the counters to move by 3 and 2, got %d and %d", after.Made-before.Made, after.MadeSecure-before.MadeSecure)
+	}
+
+	if len(after.Recent) == 0 || len(after.Recent) > ulid.RecentTimestamps {
+		t.Fatalf("expected up to %d recent timestamps, got %d", ulid.RecentTimestamps, len(after.Recent))
+	}
+
+	if !slices.Contains(after.Recent, 1738876313290) {
+		t.Errorf("expected the recent timestamps to include 1738876313290, got %v", after.Recent)
+	}
+
+	for _, id := range ids {
+		if strings.Contains(body, id.String()) {
+			t.Errorf("expected the report not to include %s", id)
+		}
+	}
+
+	if now := ulid.Now(); after.WallClock == 0 || after.WallClock > now || after.MonotonicClock == 0 {
+		t.Errorf("unexpected clocks %d and %d at %d", after.WallClock, after.MonotonicClock, now)
+	}
+
+	if after.Entropy != ulid.DefaultGeneratorInfo() || after.SecureEntropy != ulid.SecureGeneratorInfo() {
+		t.Errorf("unexpected entropy configuration %+v and %+v", after.Entropy, after.SecureEntropy)
+	}
+}
+
+func TestHandlerOverflow(t *testing.T) {
+	h := health.Handler()
+	_, _, before := fetch(t, h)
+
+	// Overflowing sequential entropy is recorded with the timestamp of the ULID.
+	last := [10]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	entropy := ulid.SequentialEntropy(last)
+	if _, err := ulid.New(1738876313290, entropy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ulid.New(1738876313291, entropy); err != ulid.ErrMonotonicOverflow {
+		t.Fatalf("got error %v, want %v", err, ulid.ErrMonotonicOverflow)
+	}
+
+	_, _, after := fetch(t, h)
+	if after.Overflows != before.Overflows+1 || after.LastOverflow != 1738876313291 {
+		t.Errorf("got %d overflows, the last at %d", after.Overflows-before.Overflows, after.LastOverflow)
+	}
+}
+
+func TestHandlerMethods(t *testing.T) {
+	h := health.Handler()
+	for method, code := range map[string]int{
+		http.MethodHead: http.StatusOK,
+		http.MethodPost: http.StatusMethodNotAllowed,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/debug/ulid", nil))
+		if rec.Code != code {
+			t.Errorf("%s: got status %d, want %d", method, rec.Code, code)
+		}
+
+		if method == http.MethodHead && rec.Body.Len() != 0 {
+			t.Errorf("expected no body for HEAD, got %q", rec.Body)
+		}
+	}
+}
+
+func TestRegisterExpvar(t *testing.T) {
+	health.RegisterExpvar("")
+	health.RegisterExpvar("fleet_ulid")
+
+	for _, name := range []string{health.DefaultPrefix, "fleet_ulid"} {
+		v := expvar.Get(name)
+		if v == nil {
+			t.Errorf("expected %q to be published", name)
+			continue
+		}
+
+		var report ulid.Health
+		if err := json.Unmarshal([]byte(v.String()), &report); err != nil || report.Entropy.Version == "" {
+			t.Errorf("%s: could not decode %s: %v", name, v, err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering the same prefix twice to panic")
+		}
+	}()
+	health.RegisterExpvar("fleet_ulid")
+}
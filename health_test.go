@@ -0,0 +1,81 @@
+package ulid_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestReadHealth(t *testing.T) {
+	defer ulid.EnableHealth(ulid.EnableHealth(false))
+
+	// The made ULIDs are only counted while the health is enabled.
+	before := ulid.ReadHealth()
+	ulid.Make()
+	ulid.MakeSecure()
+	if after := ulid.ReadHealth(); after.Made != before.Made || after.MadeSecure != before.MadeSecure || len(after.Recent) != len(before.Recent) {
+		t.Errorf("got %d and %d ULIDs made while disabled", after.Made-before.Made, after.MadeSecure-before.MadeSecure)
+	}
+
+	ulid.EnableHealth(true)
+	before = ulid.ReadHealth()
+
+	// Only the most recent timestamps are kept, oldest first.
+	var want []uint64
+	for i := uint64(0); i < 2*ulid.RecentTimestamps; i++ {
+		ms := 1738876313290 + i
+		if i%2 == 0 {
+			ulid.MakeAt(time.UnixMilli(int64(ms)))
+		} else {
+			ulid.MakeSecureAt(time.UnixMilli(int64(ms)))
+		}
+		want = append(want, ms)
+	}
+
+	after := ulid.ReadHealth()
+	if !slices.Equal(after.Recent, want[ulid.RecentTimestamps:]) {
+		t.Errorf("got recent timestamps %v, want %v", after.Recent, want[ulid.RecentTimestamps:])
+	}
+
+	if made, secure := after.Made-before.Made, after.MadeSecure-before.MadeSecure; made != ulid.RecentTimestamps || secure != ulid.RecentTimestamps {
+		t.Errorf("got %d and %d ULIDs made, want %d of each", made, secure, ulid.RecentTimestamps)
+	}
+
+	// Overflows of monotonic entropy are counted with their timestamp.
+	entropy := ulid.Monotonic(&constReader{0xFF}, 1)
+	for ms := uint64(1); ms < 3; ms++ {
+		if _, err := ulid.New(ms, entropy); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ulid.New(ms, entropy); err != ulid.ErrMonotonicOverflow {
+			t.Fatalf("got error %v, want %v", err, ulid.ErrMonotonicOverflow)
+		}
+	}
+
+	if h := ulid.ReadHealth(); h.Overflows != after.Overflows+2 || h.LastOverflow != 2 {
+		t.Errorf("got %d overflows, the last at %d", h.Overflows-after.Overflows, h.LastOverflow)
+	}
+}
+
+func BenchmarkMakeHealth(b *testing.B) {
+	defer ulid.EnableHealth(ulid.EnableHealth(false))
+	for _, bc := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"Disabled", false},
+		{"Enabled", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ulid.EnableHealth(bc.enabled)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = ulid.Make()
+				}
+			})
+		})
+	}
+}
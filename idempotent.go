@@ -0,0 +1,182 @@
+package ulid
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotentTTL is the default window in which an IdempotentGenerator
+// returns the same ULID for the same key.
+const DefaultIdempotentTTL = time.Minute
+
+// DefaultIdempotentCapacity is the default maximum number of keys remembered by
+// an IdempotentGenerator.
+const DefaultIdempotentCapacity = 65536
+
+// idempotentDomain separates the HMAC of idempotency keys from any other use of
+// the same secret; changing it changes every derived entropy.
+const idempotentDomain = "go.rtnl.ai/ulid idempotent entropy v1\x00"
+
+// IdempotentGenerator creates ULIDs that are stable across the retries of an
+// operation, e.g. a database transaction that is retried on a serialization
+// failure, so that idempotency keys derived from the ULID do not change. The
+// entropy of each ULID is the HMAC-SHA256 of the caller's idempotency key with
+// the secret of the generator, and the timestamp is the time of the first
+// attempt, which is remembered for the TTL after it: every call with the same
+// key within the TTL returns the identical ULID, and different keys return
+// different ULIDs. After the TTL, after Reset, or once the key is evicted, the
+// same key returns a ULID with the same entropy but a new timestamp.
+//
+// The ULIDs are only as unpredictable as the key material: anyone who knows the
+// secret and the key can compute the entropy, and with an empty secret the
+// entropy is a public function of the key alone. Use a secret from a secure
+// source if the ULIDs must not be guessable. The ULIDs of different keys within
+// a millisecond are not monotonic.
+//
+// Memory is bounded by the capacity: when it is full the key that was first
+// seen the longest ago is evicted, which is also the next to expire. An
+// IdempotentGenerator is safe for concurrent use.
+type IdempotentGenerator struct {
+	mu       sync.Mutex
+	mac      hash.Hash
+	ttl      time.Duration
+	capacity int
+	now      func() time.Time
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+// IdempotentOption configures an IdempotentGenerator.
+type IdempotentOption func(*IdempotentGenerator)
+
+// IdempotentCapacity sets the maximum number of keys remembered by the generator
+// (default DefaultIdempotentCapacity). Values less than 1 use the default.
+func IdempotentCapacity(n int) IdempotentOption {
+	return func(g *IdempotentGenerator) {
+		g.capacity = n
+	}
+}
+
+// IdempotentClock sets the function used to get the current time (default
+// time.Now), which is the timestamp of new ULIDs and measures the TTL.
+func IdempotentClock(now func() time.Time) IdempotentOption {
+	return func(g *IdempotentGenerator) {
+		g.now = now
+	}
+}
+
+type idempotentEntry struct {
+	key     string
+	id      ULID
+	expires time.Time
+}
+
+// NewIdempotentGenerator creates a generator that derives the entropy of ULIDs
+// from idempotency keys with the secret and returns the same ULID for a key
+// within the TTL, configured by the options. If the TTL is zero or negative,
+// DefaultIdempotentTTL is used. The secret is copied.
+func NewIdempotentGenerator(secret []byte, ttl time.Duration, opts ...IdempotentOption) *IdempotentGenerator {
+	if ttl <= 0 {
+		ttl = DefaultIdempotentTTL
+	}
+
+	g := &IdempotentGenerator{
+		mac:     hmac.New(sha256.New, secret),
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.capacity < 1 {
+		g.capacity = DefaultIdempotentCapacity
+	}
+	return g
+}
+
+// New returns the ULID for the idempotency key: the ULID of the first call with
+// the key if it was within the TTL, and otherwise a new ULID with the current
+// time and the entropy derived from the key. An error is returned if the current
+// time cannot be represented by a ULID.
+func (g *IdempotentGenerator) New(key []byte) (id ULID, err error) {
+	now := g.now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.expire(now)
+	if elem, ok := g.entries[string(key)]; ok {
+		return elem.Value.(*idempotentEntry).id, nil
+	}
+
+	var ms uint64
+	if ms, err = TimestampChecked(now); err != nil {
+		return id, err
+	}
+
+	id.SetTime(ms)
+	g.derive(&id, key)
+
+	if g.order.Len() >= g.capacity {
+		g.remove(g.order.Front())
+	}
+
+	entry := &idempotentEntry{key: string(key), id: id, expires: now.Add(g.ttl)}
+	g.entries[entry.key] = g.order.PushBack(entry)
+	return id, nil
+}
+
+// Reset forgets the ULID of the idempotency key, so that the next call to New
+// with the key returns a ULID with a new timestamp. It returns true if the key
+// was remembered.
+func (g *IdempotentGenerator) Reset(key []byte) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	elem, ok := g.entries[string(key)]
+	if ok {
+		g.remove(elem)
+	}
+	return ok
+}
+
+// Len returns the number of keys currently remembered by the generator,
+// including keys that have expired but have not been removed yet.
+func (g *IdempotentGenerator) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.order.Len()
+}
+
+// derive sets the entropy of the ULID to the first 10 bytes of the HMAC of the
+// key, which must be called with the lock held since the HMAC is reused.
+func (g *IdempotentGenerator) derive(id *ULID, key []byte) {
+	var sum [sha256.Size]byte
+	g.mac.Reset()
+	g.mac.Write([]byte(idempotentDomain))
+	g.mac.Write(key)
+	copy(id[6:], g.mac.Sum(sum[:0]))
+}
+
+// expire removes the entries that have expired by now. Entries are ordered by
+// the time they were created and share the TTL, so they expire in order.
+func (g *IdempotentGenerator) expire(now time.Time) {
+	for elem := g.order.Front(); elem != nil; elem = g.order.Front() {
+		if now.Before(elem.Value.(*idempotentEntry).expires) {
+			return
+		}
+		g.remove(elem)
+	}
+}
+
+func (g *IdempotentGenerator) remove(elem *list.Element) {
+	delete(g.entries, g.order.Remove(elem).(*idempotentEntry).key)
+}
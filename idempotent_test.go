@@ -0,0 +1,212 @@
+package ulid_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestIdempotentGenerator(t *testing.T) {
+	t.Parallel()
+
+	newGenerator := func(opts ...ulid.IdempotentOption) (*ulid.IdempotentGenerator, *fakeClock) {
+		clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+		opts = append([]ulid.IdempotentOption{ulid.IdempotentClock(clock.Now)}, opts...)
+		return ulid.NewIdempotentGenerator([]byte("secret"), time.Minute, opts...), clock
+	}
+
+	t.Run("Retries", func(t *testing.T) {
+		g, clock := newGenerator()
+		first, err := g.New([]byte("order-1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Time() != ulid.Timestamp(clock.Now()) {
+			t.Errorf("expected the time of the first attempt, got %s", first.Timestamp())
+		}
+
+		// Every retry within the TTL returns the identical ULID.
+		for i := 0; i < 5; i++ {
+			clock.Advance(10 * time.Second)
+			if id, err := g.New([]byte("order-1")); err != nil || id != first {
+				t.Fatalf("retry %d: got %s (%v), want %s", i, id, err, first)
+			}
+		}
+
+		// A generator with the same secret derives the same entropy.
+		other, _ := newGenerator()
+		if id, _ := other.New([]byte("order-1")); id != first {
+			t.Errorf("expected the same ULID from the same secret, got %s, want %s", id, first)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		g, _ := newGenerator()
+		seen := make(map[ulid.ULID]string)
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("order-%d", i)
+			id, err := g.New([]byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if prev, ok := seen[id]; ok {
+				t.Fatalf("keys %q and %q returned the same ULID %s", prev, key, id)
+			}
+			seen[id] = key
+		}
+
+		if g.Len() != 1000 {
+			t.Errorf("expected 1000 keys, got %d", g.Len())
+		}
+
+		// Different secrets derive different entropy.
+		a, _ := g.New([]byte("order-1"))
+		b, _ := ulid.NewIdempotentGenerator([]byte("other"), time.Minute).New([]byte("order-1"))
+		if a.Entropy() == nil || string(a.Entropy()) == string(b.Entropy()) {
+			t.Error("expected different secrets to derive different entropy")
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		g, clock := newGenerator()
+		first, _ := g.New([]byte("order-1"))
+
+		clock.Advance(time.Minute)
+		id, err := g.New([]byte("order-1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id == first || id.Time() != first.Time()+60000 || string(id.Entropy()) != string(first.Entropy()) {
+			t.Errorf("expected a new timestamp with the same entropy after the TTL, got %s and %s", first, id)
+		}
+
+		if g.Len() != 1 {
+			t.Errorf("expected the expired key to be removed, got %d keys", g.Len())
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		g, clock := newGenerator()
+		first, _ := g.New([]byte("order-1"))
+
+		if !g.Reset([]byte("order-1")) || g.Reset([]byte("order-1")) || g.Len() != 0 {
+			t.Fatal("expected the key to be reset once")
+		}
+
+		clock.Advance(time.Millisecond)
+		if id, _ := g.New([]byte("order-1")); id == first || id.Time() != first.Time()+1 {
+			t.Errorf("expected a new timestamp after the reset, got %s and %s", first, id)
+		}
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		g, clock := newGenerator(ulid.IdempotentCapacity(2))
+		a, _ := g.New([]byte("a"))
+		b, _ := g.New([]byte("b"))
+		clock.Advance(time.Millisecond)
+
+		// The third key evicts the oldest key but not the next oldest.
+		g.New([]byte("c"))
+		if g.Len() != 2 {
+			t.Fatalf("expected 2 keys, got %d", g.Len())
+		}
+
+		if id, _ := g.New([]byte("b")); id != b {
+			t.Errorf("expected b to be remembered, got %s, want %s", id, b)
+		}
+
+		if id, _ := g.New([]byte("a")); id == a {
+			t.Error("expected a to be evicted")
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		g, clock := newGenerator(ulid.IdempotentCapacity(0))
+		for i := 0; i < 100; i++ {
+			g.New([]byte{byte(i)})
+		}
+
+		if g.Len() != 100 {
+			t.Errorf("expected the default capacity, got %d keys", g.Len())
+		}
+
+		// A zero TTL uses the default.
+		g = ulid.NewIdempotentGenerator(nil, 0, ulid.IdempotentClock(clock.Now))
+		first, _ := g.New(nil)
+		clock.Advance(ulid.DefaultIdempotentTTL - time.Millisecond)
+		if id, _ := g.New(nil); id != first {
+			t.Errorf("expected the default TTL, got %s, want %s", id, first)
+		}
+	})
+
+	t.Run("Time", func(t *testing.T) {
+		g := ulid.NewIdempotentGenerator(nil, time.Minute, ulid.IdempotentClock(func() time.Time { return time.Time{} }))
+		if _, err := g.New([]byte("order-1")); err != ulid.ErrSmallTime {
+			t.Errorf("got error %v, want %v", err, ulid.ErrSmallTime)
+		}
+
+		if g.Len() != 0 {
+			t.Error("expected the key not to be remembered after an error")
+		}
+	})
+}
+
+func TestIdempotentGeneratorConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// Goroutines retrying the same keys concurrently agree on every ULID.
+	g := ulid.NewIdempotentGenerator([]byte("secret"), time.Hour, ulid.IdempotentCapacity(64))
+	results := make([][]ulid.ULID, 8)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = make([]ulid.ULID, 32)
+			for j := range results[i] {
+				id, err := g.New([]byte(fmt.Sprintf("key-%d", j)))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[i][j] = id
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < len(results); i++ {
+		for j, id := range results[i] {
+			if id != results[0][j] {
+				t.Fatalf("goroutine %d got %s for key %d, want %s", i, id, j, results[0][j])
+			}
+		}
+	}
+
+	// Concurrent resets and evictions do not corrupt the generator.
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 256; j++ {
+				key := []byte(fmt.Sprintf("key-%d-%d", i, j))
+				g.New(key)
+				if j%3 == 0 {
+					g.Reset(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := g.Len(); n > 64 {
+		t.Errorf("expected at most 64 keys, got %d", n)
+	}
+}
@@ -0,0 +1,618 @@
+// Package index implements an append-only file format for ULIDs, e.g. to keep a
+// file per day of all of the ULIDs issued by a service for audit, with lookups by
+// binary search rather than by grepping text files.
+//
+// An index file is a 16 byte header followed by the ULIDs as fixed 16 byte binary
+// records in the order they were appended, and a 64 byte footer with the number
+// of records, the smallest and largest ULID, and whether the records are sorted.
+// Appends write the records without the footer, which is written by Sync and
+// Close, so the file is cheap to write and is only read in full when it is opened
+// without a valid footer after a crash. The records of issued ULIDs are mostly in
+// ascending order, in which case Contains and Iterate binary search the records;
+// otherwise they scan all of the records unless the file is compacted with
+// SortInPlace.
+//
+// A crash may leave a truncated record or footer at the end of the file. It is
+// detected and ignored when the file is opened, reported to the OnCorrupt option,
+// and removed by the next Append. Records are durable once Sync returns.
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"go.rtnl.ai/ulid"
+)
+
+var (
+	// Returned when opening a file that is not an index file.
+	ErrInvalidIndex = errors.New("index: not a ULID index file")
+
+	// Returned when opening an index file with an unsupported version.
+	ErrVersion = errors.New("index: unsupported index file version")
+
+	// Wrapped by the error passed to OnCorrupt when a truncated record or footer
+	// at the end of the file is ignored.
+	ErrTruncated = errors.New("index: truncated record at the end of the file")
+
+	// Returned by the methods of an index after it has been closed.
+	ErrClosed = errors.New("index: index is closed")
+
+	// Returned by Err when SortInPlace rewrote the index during an iteration.
+	ErrSorted = errors.New("index: index was sorted during iteration")
+)
+
+// Version is the version of the index file format.
+const Version = 1
+
+// The header is the magic bytes, the version byte, and 11 reserved zero bytes.
+// The footer is the magic bytes, the flags as a big endian uint32, the number of
+// records as a big endian uint64, the smallest and largest ULID, 12 reserved zero
+// bytes, and the CRC-32 (IEEE) of the preceding bytes of the footer.
+const (
+	headerMagic = "ULIX"
+	footerMagic = "ULIF"
+	headerSize  = 16
+	footerSize  = 64
+	recordSize  = 16
+	flagSorted  = 1
+)
+
+// chunkRecords is the number of records read at a time by scans and iterations.
+const chunkRecords = 4096
+
+// Option configures an index returned by Open.
+type Option func(*Index)
+
+// OnCorrupt is called with an error wrapping ErrTruncated when a truncated record
+// or footer at the end of the file is ignored by Open. By default the error is
+// logged as a warning with log/slog.
+func OnCorrupt(fn func(error)) Option {
+	return func(ix *Index) {
+		ix.onCorrupt = fn
+	}
+}
+
+// Index is a handle of an index file returned by Open. An index is safe for
+// concurrent use by one writer and any number of readers, but the file must only
+// be written by one index at a time.
+type Index struct {
+	path      string
+	onCorrupt func(error)
+
+	mu     sync.RWMutex
+	file   *os.File
+	size   int64 // the size of the file, which may include a footer or a truncated record
+	count  int64 // the number of records
+	min    ulid.ULID
+	max    ulid.ULID
+	sorted bool   // the records are in ascending order
+	dirty  bool   // records were written since the footer was written
+	gen    uint64 // incremented by SortInPlace
+	closed bool
+}
+
+// Open opens the index file at the path, creating it if it does not exist. Open
+// does not modify the file: a file without a valid footer, e.g. after a crash,
+// is scanned to recover its records, ignoring a truncated record or footer at
+// the end. ErrInvalidIndex is returned if the file is not an index file.
+func Open(path string, opts ...Option) (_ *Index, err error) {
+	ix := &Index{path: path, sorted: true}
+	ix.onCorrupt = func(err error) {
+		slog.Warn("ignoring corrupt ULID index data", "path", path, "error", err)
+	}
+
+	for _, opt := range opts {
+		opt(ix)
+	}
+
+	if ix.file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644); err != nil {
+		return nil, err
+	}
+
+	if err = ix.load(); err != nil {
+		ix.file.Close()
+		return nil, err
+	}
+	return ix, nil
+}
+
+// load reads the header and the footer of the file, or recovers the records if
+// the footer is missing.
+func (ix *Index) load() (err error) {
+	var info os.FileInfo
+	if info, err = ix.file.Stat(); err != nil {
+		return err
+	}
+
+	if ix.size = info.Size(); ix.size == 0 {
+		return nil
+	}
+
+	header := make([]byte, min(ix.size, headerSize))
+	if _, err = ix.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+
+	switch {
+	case !bytes.HasPrefix(header, []byte(headerMagic)) && !bytes.HasPrefix([]byte(headerMagic), header):
+		return ErrInvalidIndex
+	case ix.size < headerSize:
+		// The header was truncated when the first record was appended.
+		ix.onCorrupt(fmt.Errorf("%w: %d byte header", ErrTruncated, ix.size))
+		return nil
+	case header[len(headerMagic)] != Version:
+		return fmt.Errorf("%w %d", ErrVersion, header[len(headerMagic)])
+	}
+
+	body := ix.size - headerSize
+	tail := make([]byte, min(body, footerSize))
+	if _, err = ix.file.ReadAt(tail, ix.size-int64(len(tail))); err != nil {
+		return err
+	}
+
+	if len(tail) == footerSize && ix.parseFooter(tail, body-footerSize) {
+		return nil
+	}
+
+	// Without a valid footer, a footer that was truncated while it was written
+	// starts with its magic at a record boundary before the end of the file.
+	ix.count = body / recordSize
+	for off := ix.count * recordSize; off > body-int64(len(tail)); off -= recordSize {
+		if rest := tail[len(tail)-int(body-off):]; len(rest) >= len(footerMagic) && bytes.HasPrefix(rest, []byte(footerMagic)) {
+			ix.count = off / recordSize
+			ix.onCorrupt(fmt.Errorf("%w: %d byte footer", ErrTruncated, len(rest)))
+			break
+		}
+	}
+
+	if partial := body - ix.count*recordSize; partial > 0 && partial < recordSize {
+		ix.onCorrupt(fmt.Errorf("%w: %d of %d bytes", ErrTruncated, partial, recordSize))
+	}
+
+	// Recover the smallest and largest ULID and the order of the records.
+	var n int64
+	return ix.scan(ix.count, func(ids []ulid.ULID) bool {
+		for _, id := range ids {
+			ix.observe(id, n == 0)
+			n++
+		}
+		return true
+	})
+}
+
+// parseFooter sets the count, bounds, and order of the records from the footer if
+// it is valid and describes the number of bytes of records.
+func (ix *Index) parseFooter(footer []byte, records int64) bool {
+	if string(footer[:len(footerMagic)]) != footerMagic || crc32.ChecksumIEEE(footer[:footerSize-4]) != binary.BigEndian.Uint32(footer[footerSize-4:]) {
+		return false
+	}
+
+	if count := binary.BigEndian.Uint64(footer[8:16]); records < 0 || count != uint64(records/recordSize) || records%recordSize != 0 {
+		return false
+	}
+
+	ix.count = records / recordSize
+	ix.sorted = binary.BigEndian.Uint32(footer[4:8])&flagSorted != 0
+	copy(ix.min[:], footer[16:32])
+	copy(ix.max[:], footer[32:48])
+	return true
+}
+
+// footer returns the footer of the records.
+func (ix *Index) footer() []byte {
+	footer := make([]byte, footerSize)
+	copy(footer, footerMagic)
+	if ix.sorted {
+		binary.BigEndian.PutUint32(footer[4:8], flagSorted)
+	}
+
+	binary.BigEndian.PutUint64(footer[8:16], uint64(ix.count))
+	copy(footer[16:32], ix.min[:])
+	copy(footer[32:48], ix.max[:])
+	binary.BigEndian.PutUint32(footer[footerSize-4:], crc32.ChecksumIEEE(footer[:footerSize-4]))
+	return footer
+}
+
+// header returns the header of an index file.
+func header() []byte {
+	header := make([]byte, headerSize)
+	copy(header, headerMagic)
+	header[len(headerMagic)] = Version
+	return header
+}
+
+// observe updates the bounds and the order of the records with the next record.
+func (ix *Index) observe(id ulid.ULID, first bool) {
+	if first {
+		ix.min, ix.max = id, id
+		return
+	}
+
+	// The largest record is the previous record while the records are sorted.
+	if id.Compare(ix.max) < 0 {
+		ix.sorted = false
+		if id.Compare(ix.min) < 0 {
+			ix.min = id
+		}
+	} else {
+		ix.max = id
+	}
+}
+
+// Append appends the ULID to the index. The record is written to the file but is
+// only durable once Sync returns. The first Append after the footer was written,
+// or after a truncated record was ignored by Open, truncates the file to the
+// records and syncs it before writing the record.
+func (ix *Index) Append(id ulid.ULID) (err error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+
+	if ix.size < headerSize {
+		if err = ix.file.Truncate(0); err != nil {
+			return err
+		}
+
+		if _, err = ix.file.WriteAt(header(), 0); err != nil {
+			return err
+		}
+		ix.size = headerSize
+	}
+
+	end := ix.end()
+	if ix.size > end {
+		if err = ix.file.Truncate(end); err != nil {
+			return err
+		}
+
+		// Otherwise the truncation may be lost in a crash while the record that
+		// overwrites the start of the footer is not, corrupting the footer.
+		if err = ix.file.Sync(); err != nil {
+			return err
+		}
+		ix.size = end
+	}
+
+	n, err := ix.file.WriteAt(id[:], end)
+	ix.size = end + int64(n)
+	if err != nil {
+		return err
+	}
+
+	ix.observe(id, ix.count == 0)
+	ix.count++
+	ix.dirty = true
+	return nil
+}
+
+// Sync writes the footer after the records appended since the last Sync and
+// syncs the file to stable storage, after which the records are durable.
+func (ix *Index) Sync() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+	return ix.sync()
+}
+
+func (ix *Index) sync() (err error) {
+	if !ix.dirty {
+		return nil
+	}
+
+	end := ix.end()
+	if _, err = ix.file.WriteAt(ix.footer(), end); err != nil {
+		return err
+	}
+	ix.size = end + footerSize
+
+	if err = ix.file.Sync(); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// Close syncs the records appended since the last Sync, like Sync, and closes the
+// file. Close may be called more than once.
+func (ix *Index) Close() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return nil
+	}
+
+	ix.closed = true
+	err := ix.sync()
+	if cerr := ix.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Len returns the number of records of the index.
+func (ix *Index) Len() int64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.count
+}
+
+// Sorted returns true if the records are in ascending order, in which case
+// Contains and Iterate binary search the records.
+func (ix *Index) Sorted() bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.sorted
+}
+
+// Contains returns true if the ULID is a record of the index. It binary searches
+// the records if they are sorted and otherwise scans all of the records, reading
+// the file in chunks, unless the ULID is outside of the bounds of the records.
+func (ix *Index) Contains(id ulid.ULID) (found bool, err error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if ix.closed {
+		return false, ErrClosed
+	}
+
+	if ix.count == 0 || id.Compare(ix.min) < 0 || id.Compare(ix.max) > 0 {
+		return false, nil
+	}
+
+	if ix.sorted {
+		i, err := ix.search(id)
+		if err != nil || i == ix.count {
+			return false, err
+		}
+
+		rec, err := ix.record(i)
+		return rec == id, err
+	}
+
+	err = ix.scan(ix.count, func(ids []ulid.ULID) bool {
+		found = slices.Contains(ids, id)
+		return !found
+	})
+	return found, err
+}
+
+// Iterate returns an iterator over the records from and to the ULIDs inclusive,
+// in ascending order if the records are sorted and otherwise in the order they
+// were appended. The records appended after the iteration started are not
+// included. Iteration stops at the first error, which is yielded with the zero
+// ULID as the last element of the iteration; it fails with ErrSorted if the
+// records are rewritten by SortInPlace during the iteration. The index is not
+// locked while the loop body runs, so it may append to the index.
+func (ix *Index) Iterate(from, to ulid.ULID) iter.Seq2[ulid.ULID, error] {
+	return func(yield func(ulid.ULID, error) bool) {
+		ix.mu.RLock()
+		if ix.closed {
+			ix.mu.RUnlock()
+			yield(ulid.Zero, ErrClosed)
+			return
+		}
+
+		count, gen, sorted := ix.count, ix.gen, ix.sorted
+		if count == 0 || from.Compare(ix.max) > 0 || to.Compare(ix.min) < 0 || from.Compare(to) > 0 {
+			ix.mu.RUnlock()
+			return
+		}
+
+		var start int64
+		var err error
+		if sorted {
+			start, err = ix.search(from)
+		}
+		ix.mu.RUnlock()
+
+		if err != nil {
+			yield(ulid.Zero, err)
+			return
+		}
+
+		chunk := make([]ulid.ULID, 0, chunkRecords)
+		for i := start; i < count; i += chunkRecords {
+			ix.mu.RLock()
+			switch {
+			case ix.closed:
+				err = ErrClosed
+			case ix.gen != gen:
+				err = ErrSorted
+			default:
+				chunk, err = ix.read(chunk[:0], i, min(chunkRecords, count-i))
+			}
+			ix.mu.RUnlock()
+
+			if err != nil {
+				yield(ulid.Zero, err)
+				return
+			}
+
+			for _, id := range chunk {
+				if id.Compare(to) > 0 {
+					if sorted {
+						return
+					}
+					continue
+				}
+
+				if id.Compare(from) >= 0 && !yield(id, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SortInPlace sorts the records of the index in ascending order for compaction,
+// so that Contains and Iterate binary search the records. Duplicate records are
+// kept. The sorted records are written to a temporary file in the directory of
+// the index, which is synced and renamed over the index file, so that a crash
+// leaves either the original or the sorted records; an index file that is open
+// elsewhere, e.g. in another process, continues to read the original file. All
+// of the records are read into memory, 16 bytes per record.
+func (ix *Index) SortInPlace() (err error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+
+	if ix.sorted {
+		return ix.sync()
+	}
+
+	var ids []ulid.ULID
+	if ids, err = ix.read(make([]ulid.ULID, 0, ix.count), 0, ix.count); err != nil {
+		return err
+	}
+	slices.SortFunc(ids, compareULID)
+
+	var info os.FileInfo
+	if info, err = ix.file.Stat(); err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(ix.path)
+	tmp, err := os.CreateTemp(dir, base+".sort-*")
+	if err != nil {
+		return err
+	}
+
+	// Sorting does not change the bounds of the records, only the flag of the footer
+	// that is restored if the sorted records cannot be written.
+	defer func() {
+		if err != nil {
+			ix.sorted = false
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	ix.sorted = true
+
+	buf := make([]byte, 0, headerSize+len(ids)*recordSize+footerSize)
+	buf = append(buf, header()...)
+	for _, id := range ids {
+		buf = append(buf, id[:]...)
+	}
+	buf = append(buf, ix.footer()...)
+
+	if _, err = tmp.Write(buf); err != nil {
+		return err
+	}
+
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), ix.path); err != nil {
+		return err
+	}
+
+	// Sync the directory so that the rename is durable; directories cannot be
+	// synced on every platform, so this is best effort.
+	if d, derr := os.Open(filepath.Dir(ix.path)); derr == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	file, err := os.OpenFile(ix.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	ix.file.Close()
+	ix.file = file
+	ix.size = int64(len(buf))
+	ix.dirty = false
+	ix.gen++
+	return nil
+}
+
+// end returns the offset of the end of the records.
+func (ix *Index) end() int64 {
+	return headerSize + ix.count*recordSize
+}
+
+// search returns the index of the first record that is not less than the ULID,
+// or the number of records if there is none. The records must be sorted.
+func (ix *Index) search(id ulid.ULID) (int64, error) {
+	lo, hi := int64(0), ix.count
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		rec, err := ix.record(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if rec.Compare(id) < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// record reads the record at the index.
+func (ix *Index) record(i int64) (id ulid.ULID, err error) {
+	_, err = ix.file.ReadAt(id[:], headerSize+i*recordSize)
+	return id, err
+}
+
+// read appends the n records from the index i to ids.
+func (ix *Index) read(ids []ulid.ULID, i, n int64) ([]ulid.ULID, error) {
+	buf := make([]byte, n*recordSize)
+	if _, err := ix.file.ReadAt(buf, headerSize+i*recordSize); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return ids, err
+	}
+
+	for off := 0; off < len(buf); off += recordSize {
+		ids = append(ids, ulid.FromArray([recordSize]byte(buf[off:off+recordSize])))
+	}
+	return ids, nil
+}
+
+// scan calls fn with the first n records in chunks until fn returns false.
+func (ix *Index) scan(n int64, fn func([]ulid.ULID) bool) (err error) {
+	chunk := make([]ulid.ULID, 0, min(n, chunkRecords))
+	for i := int64(0); i < n; i += chunkRecords {
+		if chunk, err = ix.read(chunk[:0], i, min(chunkRecords, n-i)); err != nil {
+			return err
+		}
+
+		if !fn(chunk) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func compareULID(a, b ulid.ULID) int {
+	return a.Compare(b)
+}
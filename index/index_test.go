@@ -0,0 +1,478 @@
+package index_test
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/index"
+)
+
+// sortedIDs returns n ascending ULIDs with perMs ULIDs in each millisecond.
+func sortedIDs(seed int64, n, perMs int) []ulid.ULID {
+	entropy := ulid.Monotonic(rand.New(rand.NewSource(seed)), 0)
+	ids := make([]ulid.ULID, n)
+	for i := range ids {
+		ids[i] = ulid.MustNew(1700000000000+uint64(i/perMs), entropy)
+	}
+	return ids
+}
+
+// open opens the index at the path, failing the test on corrupt data unless the
+// test expects it.
+func open(t *testing.T, path string, corrupt *[]error) *index.Index {
+	t.Helper()
+	ix, err := index.Open(path, index.OnCorrupt(func(err error) {
+		if corrupt == nil {
+			t.Errorf("unexpected corrupt data: %v", err)
+			return
+		}
+		*corrupt = append(*corrupt, err)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ix
+}
+
+func appendAll(t *testing.T, ix *index.Index, ids []ulid.ULID) {
+	t.Helper()
+	for _, id := range ids {
+		if err := ix.Append(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collect(t *testing.T, ix *index.Index, from, to ulid.ULID) []ulid.ULID {
+	t.Helper()
+	var ids []ulid.ULID
+	for id, err := range ix.Iterate(from, to) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ids.idx")
+	ix := open(t, path, nil)
+	if ix.Len() != 0 || !ix.Sorted() {
+		t.Fatal("expected an empty sorted index")
+	}
+
+	if found, err := ix.Contains(ulid.Make()); found || err != nil {
+		t.Fatalf("got %t (%v) from an empty index", found, err)
+	}
+
+	ids := sortedIDs(1, 100, 10)
+	appendAll(t, ix, ids)
+
+	// A second index of the file reads the records once they are synced.
+	if err := ix.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := open(t, path, nil)
+	if reader.Len() != 100 || !reader.Sorted() {
+		t.Errorf("expected the synced records, got %d records", reader.Len())
+	}
+	reader.Close()
+
+	for _, id := range ids {
+		if found, err := ix.Contains(id); !found || err != nil {
+			t.Fatalf("expected %s to be found (%v)", id, err)
+		}
+	}
+
+	for _, id := range sortedIDs(2, 100, 10) {
+		if found, err := ix.Contains(id); found || err != nil {
+			t.Fatalf("expected %s not to be found (%v)", id, err)
+		}
+	}
+
+	// Appending after the footer was written replaces the footer.
+	appendAll(t, ix, sortedIDs(3, 10, 1)[:1])
+	if err := ix.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != 16+101*16+64 {
+		t.Fatalf("unexpected file size %d (%v)", info.Size(), err)
+	}
+
+	ix = open(t, path, nil)
+	defer ix.Close()
+	if ix.Len() != 101 || ix.Sorted() {
+		t.Errorf("expected 101 unsorted records, got %d sorted %t", ix.Len(), ix.Sorted())
+	}
+
+	if err := ix.Close(); err != nil {
+		t.Errorf("unexpected error closing twice %v", err)
+	}
+
+	if err := ix.Append(ulid.Make()); !errors.Is(err, index.ErrClosed) {
+		t.Errorf("got %v, want %v", err, index.ErrClosed)
+	}
+
+	if _, err := ix.Contains(ulid.Make()); !errors.Is(err, index.ErrClosed) {
+		t.Errorf("got %v, want %v", err, index.ErrClosed)
+	}
+
+	for id, err := range ix.Iterate(ulid.ULID{}, maxULID) {
+		if !errors.Is(err, index.ErrClosed) || !id.IsZero() {
+			t.Errorf("got %s (%v), want %v", id, err, index.ErrClosed)
+		}
+	}
+}
+
+func TestIndexLarge(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ids.idx")
+	ix := open(t, path, nil)
+	defer ix.Close()
+
+	ids := sortedIDs(4, 50000, 7)
+	shuffled := slices.Clone(ids)
+	rand.New(rand.NewSource(5)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	appendAll(t, ix, shuffled)
+
+	if ix.Sorted() || ix.Len() != int64(len(ids)) {
+		t.Fatalf("expected %d unsorted records", len(ids))
+	}
+
+	absent := sortedIDs(6, 50000, 7)
+	check := func(name string, n int) {
+		rng := rand.New(rand.NewSource(int64(n)))
+		for i := 0; i < n; i++ {
+			j := rng.Intn(len(ids))
+			if found, err := ix.Contains(ids[j]); !found || err != nil {
+				t.Fatalf("%s: expected record %d to be found (%v)", name, j, err)
+			}
+
+			if found, err := ix.Contains(absent[j]); found || err != nil {
+				t.Fatalf("%s: expected %s not to be found (%v)", name, absent[j], err)
+			}
+		}
+	}
+
+	// The unsorted records are scanned, the sorted records binary searched.
+	check("scan", 50)
+	if err := ix.SortInPlace(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ix.Sorted() {
+		t.Fatal("expected the records to be sorted")
+	}
+	check("search", 5000)
+
+	// The first, last, and every record around the chunks of a scan are found.
+	for _, i := range []int{0, 1, 4095, 4096, 4097, 8192, len(ids) - 1} {
+		if found, err := ix.Contains(ids[i]); !found || err != nil {
+			t.Errorf("expected record %d to be found (%v)", i, err)
+		}
+	}
+
+	if got := collect(t, ix, ulid.ULID{}, maxULID); !slices.Equal(got, ids) {
+		t.Errorf("expected the sorted records, got %d records", len(got))
+	}
+
+	// The sorted file replaces the index file.
+	if matches, _ := filepath.Glob(path + "*"); len(matches) != 1 {
+		t.Errorf("expected only the index file, got %v", matches)
+	}
+
+	reopened := open(t, path, nil)
+	defer reopened.Close()
+	if !reopened.Sorted() || reopened.Len() != int64(len(ids)) {
+		t.Errorf("expected the sorted records to be reopened")
+	}
+}
+
+var maxULID = ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+func TestIndexTruncated(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ids := sortedIDs(7, 20, 3)
+
+	// synced returns the bytes of an index file of the records with a footer.
+	synced := func(ids []ulid.ULID) []byte {
+		path := filepath.Join(dir, "synced.idx")
+		os.Remove(path)
+		ix := open(t, path, nil)
+		appendAll(t, ix, ids)
+		if err := ix.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	data := synced(ids)
+	records := 16 + 20*16
+
+	for _, tc := range []struct {
+		name    string
+		data    []byte
+		count   int64
+		corrupt bool
+	}{
+		{"Footer", data, 20, false},
+		{"NoFooter", data[:records], 20, false},
+		{"TruncatedRecord", data[:records-5], 19, true},
+		{"TruncatedFooter", data[:records+20], 20, true},
+		{"TruncatedFooterMagic", data[:records+4], 20, true},
+		{"TruncatedHeader", data[:3], 0, true},
+		{"Header", data[:16], 0, false},
+		{"Empty", nil, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".idx")
+			if err := os.WriteFile(path, tc.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var corrupt []error
+			ix := open(t, path, &corrupt)
+			if ix.Len() != tc.count {
+				t.Errorf("expected %d records, got %d", tc.count, ix.Len())
+			}
+
+			if (len(corrupt) > 0) != tc.corrupt {
+				t.Errorf("got corrupt data %v, expected %t", corrupt, tc.corrupt)
+			}
+
+			for _, err := range corrupt {
+				if !errors.Is(err, index.ErrTruncated) {
+					t.Errorf("got %v, want %v", err, index.ErrTruncated)
+				}
+			}
+
+			// Opening does not modify the file.
+			if got, _ := os.ReadFile(path); len(got) != len(tc.data) {
+				t.Errorf("expected the file to be unmodified, got %d bytes", len(got))
+			}
+
+			// The next Append removes the truncated data.
+			next := sortedIDs(8, 1, 1)[0]
+			if err := ix.Append(next); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ix.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			ix = open(t, path, nil)
+			defer ix.Close()
+
+			want := append(slices.Clone(ids[:tc.count]), next)
+			if got := collect(t, ix, ulid.ULID{}, maxULID); !slices.Equal(got, want) {
+				t.Errorf("expected %d records after recovery, got %d", len(want), len(got))
+			}
+		})
+	}
+
+	// Files that are not index files are not opened.
+	for name, data := range map[string][]byte{
+		"text":    []byte("01HTNMW2JAW89YSBG7NFPHABA4\n"),
+		"version": append([]byte("ULIX\x02"), make([]byte, 11)...),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := index.Open(path); !errors.Is(err, index.ErrInvalidIndex) && !errors.Is(err, index.ErrVersion) {
+			t.Errorf("%s: got %v, expected an invalid index", name, err)
+		}
+	}
+}
+
+func TestIndexIterate(t *testing.T) {
+	t.Parallel()
+
+	ids := sortedIDs(9, 10000, 10)
+	for _, sorted := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "ids.idx")
+		ix := open(t, path, nil)
+		defer ix.Close()
+
+		records := slices.Clone(ids)
+		if !sorted {
+			slices.Reverse(records)
+		}
+		appendAll(t, ix, records)
+
+		for _, r := range [][2]int{{0, len(ids) - 1}, {10, 20}, {4090, 8200}, {5, 5}, {9999, 9999}} {
+			got := collect(t, ix, ids[r[0]], ids[r[1]])
+			want := slices.Clone(ids[r[0] : r[1]+1])
+			if !sorted {
+				slices.Reverse(want)
+			}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("sorted %t: expected %d records from %d to %d inclusive, got %d", sorted, len(want), r[0], r[1], len(got))
+			}
+		}
+
+		// Bounds between and outside of the records are not records themselves.
+		before, after := ids[0], ids[len(ids)-1]
+		before[15]--
+		after[15]++
+		if got := collect(t, ix, before, after); len(got) != len(ids) {
+			t.Errorf("sorted %t: expected all of the records, got %d", sorted, len(got))
+		}
+
+		if got := collect(t, ix, after, maxULID); len(got) != 0 {
+			t.Errorf("sorted %t: expected no records after the last, got %d", sorted, len(got))
+		}
+
+		if got := collect(t, ix, ids[20], ids[10]); len(got) != 0 {
+			t.Errorf("sorted %t: expected no records for an empty range, got %d", sorted, len(got))
+		}
+
+		// Iteration stops when the loop breaks.
+		n := 0
+		for _, err := range ix.Iterate(ulid.ULID{}, maxULID) {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n++; n == 5 {
+				break
+			}
+		}
+
+		if n != 5 {
+			t.Errorf("sorted %t: expected the iteration to stop, got %d", sorted, n)
+		}
+	}
+}
+
+func TestIndexIterateSorted(t *testing.T) {
+	t.Parallel()
+
+	ix := open(t, filepath.Join(t.TempDir(), "ids.idx"), nil)
+	defer ix.Close()
+
+	ids := sortedIDs(10, 10000, 10)
+	slices.Reverse(ids)
+	appendAll(t, ix, ids)
+
+	// Sorting during an iteration stops it at the next chunk of records.
+	var (
+		n    int
+		last error
+	)
+	for _, err := range ix.Iterate(ulid.ULID{}, maxULID) {
+		if last = err; err != nil {
+			continue
+		}
+
+		if n++; n == 1 {
+			if err := ix.SortInPlace(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if n != 4096 || !errors.Is(last, index.ErrSorted) {
+		t.Errorf("expected the iteration to stop after the first chunk, got %d (%v)", n, last)
+	}
+
+	// The error is scoped to the iteration that failed.
+	if got := collect(t, ix, ulid.ULID{}, maxULID); len(got) != len(ids) {
+		t.Errorf("expected the next iteration to succeed, got %d records", len(got))
+	}
+}
+
+func TestIndexConcurrent(t *testing.T) {
+	t.Parallel()
+
+	ix := open(t, filepath.Join(t.TempDir(), "ids.idx"), nil)
+	defer ix.Close()
+
+	// One writer appends the records while readers search the appended records
+	// and iterate over them.
+	ids := sortedIDs(11, 3000, 5)
+	var appended atomic.Int64
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, id := range ids {
+			if err := ix.Append(id); err != nil {
+				t.Error(err)
+				return
+			}
+			appended.Store(int64(i + 1))
+
+			if i%1000 == 999 {
+				if err := ix.Sync(); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+
+	for r := 0; r < 3; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(r)))
+			for n := appended.Load(); n < int64(len(ids)); n = appended.Load() {
+				if n == 0 {
+					continue
+				}
+
+				if found, err := ix.Contains(ids[rng.Int63n(n)]); !found || err != nil {
+					t.Errorf("expected an appended record to be found (%v)", err)
+					return
+				}
+
+				// The iteration includes at least the records appended before it.
+				var got []ulid.ULID
+				for id, err := range ix.Iterate(ulid.ULID{}, maxULID) {
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					got = append(got, id)
+				}
+
+				if len(got) < int(n) || !slices.Equal(got, ids[:len(got)]) {
+					t.Errorf("expected a prefix of at least %d records, got %d", n, len(got))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ix.Len() != int64(len(ids)) || !ix.Sorted() {
+		t.Errorf("expected %d sorted records, got %d", len(ids), ix.Len())
+	}
+}
@@ -0,0 +1,379 @@
+// Package qrcode implements a minimal QR code encoder for the canonical string
+// encoding of ULIDs: alphanumeric mode with medium (M) error correction in the
+// versions 1 and 2 (up to 38 characters), which is all that the 26 character
+// encoding requires. It is not a general purpose encoder; byte mode, the other
+// error correction levels, and the larger versions are not implemented.
+package qrcode
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrTooLong          = errors.New("qrcode: text is too long for version 2-M")
+	ErrInvalidCharacter = errors.New("qrcode: text is not in the alphanumeric character set")
+)
+
+// alphanumeric is the character set of the alphanumeric mode, in the order of
+// their values.
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// versions are the number of data and error correction codewords and the center
+// of the alignment pattern of each supported version with error correction level
+// M. Both versions have a single block of codewords.
+var versions = [...]struct {
+	data, ecc, align int
+}{
+	1: {data: 16, ecc: 10},
+	2: {data: 28, ecc: 16, align: 18},
+}
+
+// Code is a QR code symbol, a square of dark and light modules without its quiet
+// zone.
+type Code struct {
+	Version int // The version of the symbol, 1 or 2
+	Mask    int // The mask pattern applied to the data, from 0 to 7
+	Size    int // The number of modules on each side, 17 + 4*Version
+
+	modules  []bool
+	function []bool
+}
+
+// Dark reports whether the module in column x and row y is dark. Modules outside
+// of the symbol, e.g. in the quiet zone, are light.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+// Encode returns the QR code of the text in the smallest supported version, with
+// the mask pattern with the lowest penalty.
+func Encode(text string) (*Code, error) {
+	return encode(text, -1)
+}
+
+// encode returns the QR code of the text with the mask pattern, or with the mask
+// pattern with the lowest penalty if mask is negative.
+func encode(text string, mask int) (*Code, error) {
+	for _, c := range []byte(text) {
+		if strings.IndexByte(alphanumeric, c) < 0 {
+			return nil, ErrInvalidCharacter
+		}
+	}
+
+	// The header is 13 bits (the mode and the character count) and each pair of
+	// characters uses 11 bits, with 6 bits for a final odd character.
+	version, bits := 1, 13+11*(len(text)/2)+6*(len(text)%2)
+	for bits > versions[version].data*8 {
+		if version++; version == len(versions) {
+			return nil, ErrTooLong
+		}
+	}
+
+	c := &Code{Version: version, Size: 17 + 4*version}
+	c.modules = make([]bool, c.Size*c.Size)
+	c.function = make([]bool, c.Size*c.Size)
+	c.drawFunctionPatterns()
+
+	data := codewords(text, versions[version].data)
+	c.drawCodewords(append(data, reedSolomon(data, versions[version].ecc)...))
+
+	if mask < 0 {
+		penalty := -1
+		for m := 0; m < 8; m++ {
+			c.applyMask(m)
+			c.drawFormat(m)
+			if p := c.penalty(); penalty < 0 || p < penalty {
+				mask, penalty = m, p
+			}
+			c.applyMask(m)
+		}
+	}
+
+	c.Mask = mask
+	c.applyMask(mask)
+	c.drawFormat(mask)
+	c.function = nil
+	return c, nil
+}
+
+// codewords returns the data codewords of the text: the header, the pairs of
+// characters, the terminator, and the pad codewords.
+func codewords(text string, capacity int) []byte {
+	var buf bitBuffer
+	buf.write(0b0010, 4)
+	buf.write(uint(len(text)), 9)
+	for i := 0; i+1 < len(text); i += 2 {
+		buf.write(uint(45*strings.IndexByte(alphanumeric, text[i])+strings.IndexByte(alphanumeric, text[i+1])), 11)
+	}
+
+	if len(text)%2 == 1 {
+		buf.write(uint(strings.IndexByte(alphanumeric, text[len(text)-1])), 6)
+	}
+
+	buf.write(0, min(4, capacity*8-buf.n))
+	buf.write(0, (8-buf.n%8)%8)
+	for pad := byte(0xEC); len(buf.data) < capacity; pad ^= 0xEC ^ 0x11 {
+		buf.data = append(buf.data, pad)
+	}
+	return buf.data
+}
+
+// bitBuffer appends bits to bytes, most significant bit first.
+type bitBuffer struct {
+	data []byte
+	n    int
+}
+
+func (b *bitBuffer) write(v uint, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		b.data[b.n/8] |= byte(v>>i&1) << (7 - b.n%8)
+		b.n++
+	}
+}
+
+// reedSolomon returns the error correction codewords of the data, the remainder
+// of the data divided by the generator polynomial of degree n over GF(256).
+func reedSolomon(data []byte, n int) []byte {
+	// The generator is the product of (x - 2^i) for i in [0, n), with its
+	// coefficients from the highest degree and the leading 1 implied.
+	gen := make([]byte, n)
+	gen[n-1] = 1
+	root := byte(1)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < n {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+
+	rem := make([]byte, n)
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[n-1] = 0
+		for j := range rem {
+			rem[j] ^= gfMul(gen[j], factor)
+		}
+	}
+	return rem
+}
+
+// gfMul multiplies in GF(256) with the QR code polynomial x^8+x^4+x^3+x^2+1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// set sets a function module, which is not masked or overwritten by the data.
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+	c.function[y*c.Size+x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	// The finder patterns with their separators in three corners.
+	for _, corner := range [][2]int{{3, 3}, {c.Size - 4, 3}, {3, c.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x >= 0 && y >= 0 && x < c.Size && y < c.Size {
+					d := max(abs(dx), abs(dy))
+					c.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+
+	if center := versions[c.Version].align; center > 0 {
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				c.set(center+dx, center+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+
+	// Reserve the format information, which depends on the mask.
+	c.drawFormat(0)
+}
+
+// drawFormat draws both copies of the format information of the mask with error
+// correction level M, and the dark module.
+func (c *Code) drawFormat(mask int) {
+	// The 5 data bits are followed by the 10 bit BCH code of the data and masked.
+	data := mask // Level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// drawCodewords places the bits of the codewords in two module wide columns from
+// the bottom right corner, alternately upwards and downwards, skipping the
+// vertical timing pattern. The remainder bits are light.
+func (c *Code) drawCodewords(data []byte) {
+	var i int
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+
+				if !c.function[y*c.Size+x] && i < len(data)*8 {
+					c.modules[y*c.Size+x] = data[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by the mask pattern; applying a
+// mask twice removes it.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+
+			if invert && !c.function[y*c.Size+x] {
+				c.modules[y*c.Size+x] = !c.modules[y*c.Size+x]
+			}
+		}
+	}
+}
+
+// penalty scores the masked symbol with the four rules of the specification:
+// runs of five or more modules of the same color in a row or column, 2x2 blocks
+// of the same color, patterns that look like finder patterns, and the imbalance
+// of dark and light modules.
+func (c *Code) penalty() (score int) {
+	finder := [2][11]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	var dark int
+	for i := 0; i < c.Size; i++ {
+		for _, transpose := range []bool{false, true} {
+			at := func(j int) bool {
+				if transpose {
+					return c.Dark(i, j)
+				}
+				return c.Dark(j, i)
+			}
+
+			run := 1
+			for j := 1; j <= c.Size; j++ {
+				if j < c.Size && at(j) == at(j-1) {
+					run++
+					continue
+				}
+
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+
+			for j := 0; j+11 <= c.Size; j++ {
+				for _, pattern := range finder {
+					match := true
+					for k, d := range pattern {
+						match = match && at(j+k) == d
+					}
+
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+
+		for j := 0; j < c.Size; j++ {
+			if c.Dark(j, i) {
+				dark++
+			}
+
+			if i+1 < c.Size && j+1 < c.Size {
+				d := c.Dark(j, i)
+				if c.Dark(j+1, i) == d && c.Dark(j, i+1) == d && c.Dark(j+1, i+1) == d {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// Ten points for each five percent that the dark modules deviate from half.
+	total := c.Size * c.Size
+	score += ((abs(dark*20-total*10)+total-1)/total - 1) * 10
+	return score
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,139 @@
+package qrcode
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readFixture reads the matrices of the text with each mask pattern, generated
+// with the QR code library of Kazuhiko Arase, with a "mask N" line before each matrix and a row
+// of # (dark) and . (light) modules on each line.
+func readFixture(t *testing.T, name string) [8][]string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var masks [8][]string
+	mask := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if n, ok := strings.CutPrefix(line, "mask "); ok {
+			if mask, err = strconv.Atoi(n); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		masks[mask] = append(masks[mask], line)
+	}
+	return masks
+}
+
+func (c *Code) rows() []string {
+	rows := make([]string, c.Size)
+	for y := range rows {
+		var sb strings.Builder
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		rows[y] = sb.String()
+	}
+	return rows
+}
+
+func TestEncodeFixtures(t *testing.T) {
+	testCases := []struct {
+		text    string
+		fixture string
+		version int
+	}{
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAV.txt", 2},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "7ZZZZZZZZZZZZZZZZZZZZZZZZZ.txt", 2},
+		{"HELLO WORLD", "hello-world.txt", 1},
+	}
+
+	for _, tc := range testCases {
+		fixture := readFixture(t, tc.fixture)
+		for mask, expected := range fixture {
+			c, err := encode(tc.text, mask)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.text, err)
+			}
+
+			if c.Version != tc.version || c.Mask != mask || c.Size != len(expected) {
+				t.Fatalf("%s: got version %d mask %d size %d", tc.text, c.Version, c.Mask, c.Size)
+			}
+
+			for y, row := range c.rows() {
+				if row != expected[y] {
+					t.Errorf("%s mask %d row %d: got %s, want %s", tc.text, mask, y, row, expected[y])
+				}
+			}
+		}
+
+		// The mask with the lowest penalty is one of the fixtures.
+		c, err := Encode(tc.text)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.text, err)
+		}
+
+		if got := strings.Join(c.rows(), "\n"); got != strings.Join(fixture[c.Mask], "\n") {
+			t.Errorf("%s: Encode does not match the fixture for mask %d", tc.text, c.Mask)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	testCases := []struct {
+		text    string
+		version int
+		err     error
+	}{
+		{"", 1, nil},
+		{strings.Repeat("A", 20), 1, nil},
+		{strings.Repeat("A", 21), 2, nil},
+		{strings.Repeat("A", 38), 2, nil},
+		{strings.Repeat("A", 39), 0, ErrTooLong},
+		{"01arz3ndektsv4rrffq69g5fav", 0, ErrInvalidCharacter},
+	}
+
+	for _, tc := range testCases {
+		c, err := Encode(tc.text)
+		if err != tc.err {
+			t.Errorf("%q: got error %v, want %v", tc.text, err, tc.err)
+			continue
+		}
+
+		if err == nil && c.Version != tc.version {
+			t.Errorf("%q: got version %d, want %d", tc.text, c.Version, tc.version)
+		}
+	}
+}
+
+func TestCodewords(t *testing.T) {
+	// The data codewords of "01234567" in numeric mode in version 1-M and their
+	// error correction codewords, from annex I of ISO/IEC 18004.
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	ecc := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := reedSolomon(data, 10); !bytes.Equal(got, ecc) {
+		t.Errorf("got % X, want % X", got, ecc)
+	}
+
+	// The example of "HELLO WORLD": 20 41 (the mode and count), then pairs of
+	// characters, the terminator, and the pad codewords.
+	expected := []byte{0x20, 0x5B, 0x0B, 0x78, 0xD1, 0x72, 0xDC, 0x4D, 0x43, 0x40, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	if got := codewords("HELLO WORLD", 16); !bytes.Equal(got, expected) {
+		t.Errorf("got % X, want % X", got, expected)
+	}
+}
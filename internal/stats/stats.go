@@ -0,0 +1,194 @@
+// Package stats implements offline collision and ordering analysis over a corpus
+// of ULIDs, used by the ulid command line tool to audit exports of historical IDs.
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// DefaultMaxTracked is the default number of distinct ULIDs that are tracked for
+// exact duplicate detection (roughly 16MB of keys plus map overhead).
+const DefaultMaxTracked = 1000000
+
+// MaxBuckets is the maximum number of buckets of the histogram, e.g. for a small
+// bucket size over a corpus that spans years; ULIDs in buckets beyond the maximum
+// are counted as Unbucketed.
+const MaxBuckets = 10000
+
+// Analyzer accumulates statistics over a stream of ULIDs. The analyzer keeps
+// constant memory except for the duplicate detection map, which is bounded by
+// MaxTracked, and the histogram, which is bounded by MaxBuckets. Once the bound
+// of the map has been reached, new distinct ULIDs are no longer tracked:
+// duplicates of already tracked ULIDs are still detected, but every occurrence of
+// an untracked ULID is counted as distinct, so the distinct count becomes an
+// upper bound and the list of duplicates a lower bound on the truth, which is
+// indicated by Report.Exact being false.
+type Analyzer struct {
+	bucket     time.Duration
+	maxTracked int
+	seen       map[ulid.ULID]uint64
+	prev       ulid.ULID
+	report     Report
+}
+
+// Report contains the results of analyzing a ULID corpus.
+type Report struct {
+	Total       uint64               // Total number of ULIDs analyzed
+	Invalid     uint64               // Number of lines that could not be parsed
+	Distinct    uint64               // Number of distinct ULIDs (upper bound if not Exact)
+	Exact       bool                 // True if duplicate detection was exact
+	Duplicates  map[ulid.ULID]uint64 // Number of times each duplicated ULID was seen
+	MinTime     time.Time            // The earliest embedded timestamp
+	MaxTime     time.Time            // The latest embedded timestamp
+	ZeroEntropy uint64               // Number of ULIDs with all zero entropy bytes
+	OutOfOrder  uint64               // Number of adjacent pairs where next < prev
+	Bucket      time.Duration        // The histogram bucket size
+	Histogram   map[time.Time]uint64 // Count of ULIDs per bucket, keyed by bucket start (UTC)
+	Unbucketed  uint64               // Number of ULIDs in buckets beyond MaxBuckets
+}
+
+// New creates an analyzer that builds a histogram with the specified bucket size
+// and tracks up to maxTracked distinct ULIDs for duplicate detection. If bucket
+// or maxTracked are not positive, the defaults of 24 hours and DefaultMaxTracked
+// are used respectively.
+func New(bucket time.Duration, maxTracked int) *Analyzer {
+	if bucket <= 0 {
+		bucket = 24 * time.Hour
+	}
+
+	if maxTracked <= 0 {
+		maxTracked = DefaultMaxTracked
+	}
+
+	return &Analyzer{
+		bucket:     bucket,
+		maxTracked: maxTracked,
+		seen:       make(map[ulid.ULID]uint64),
+		report: Report{
+			Exact:      true,
+			Duplicates: make(map[ulid.ULID]uint64),
+			Bucket:     bucket,
+			Histogram:  make(map[time.Time]uint64),
+		},
+	}
+}
+
+// Analyze is a convenience function that streams newline delimited ULIDs from
+// the reader into a new analyzer and returns the final report. Blank lines are
+// skipped and lines that cannot be parsed are counted as invalid.
+func Analyze(r io.Reader, bucket time.Duration, maxTracked int) (_ *Report, err error) {
+	a := New(bucket, maxTracked)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var id ulid.ULID
+		if err = id.UnmarshalText(line); err != nil {
+			a.AddInvalid()
+			continue
+		}
+		a.Add(id)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return a.Report(), nil
+}
+
+// Add a ULID to the analysis.
+func (a *Analyzer) Add(id ulid.ULID) {
+	r := &a.report
+	ts := id.Timestamp().UTC()
+
+	if r.Total == 0 {
+		r.MinTime, r.MaxTime = ts, ts
+	} else {
+		if ts.Before(r.MinTime) {
+			r.MinTime = ts
+		}
+		if ts.After(r.MaxTime) {
+			r.MaxTime = ts
+		}
+		if id.Compare(a.prev) < 0 {
+			r.OutOfOrder++
+		}
+	}
+
+	r.Total++
+	a.prev = id
+
+	if isZeroEntropy(id) {
+		r.ZeroEntropy++
+	}
+
+	if bucket := ts.Truncate(a.bucket); len(r.Histogram) < MaxBuckets || r.Histogram[bucket] > 0 {
+		r.Histogram[bucket]++
+	} else {
+		r.Unbucketed++
+	}
+
+	if count, ok := a.seen[id]; ok {
+		a.seen[id] = count + 1
+		r.Duplicates[id] = count + 1
+		return
+	}
+
+	r.Distinct++
+	if len(a.seen) < a.maxTracked {
+		a.seen[id] = 1
+	} else {
+		r.Exact = false
+	}
+}
+
+// AddInvalid records an input that could not be parsed as a ULID.
+func (a *Analyzer) AddInvalid() {
+	a.report.Invalid++
+}
+
+// Report returns the statistics accumulated so far. The returned report shares
+// its maps with the analyzer and should not be modified if Add will be called.
+func (a *Analyzer) Report() *Report {
+	return &a.report
+}
+
+// Buckets returns the histogram bucket start times in ascending order.
+func (r *Report) Buckets() []time.Time {
+	buckets := make([]time.Time, 0, len(r.Histogram))
+	for ts := range r.Histogram {
+		buckets = append(buckets, ts)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Before(buckets[j]) })
+	return buckets
+}
+
+// DuplicateIDs returns the duplicated ULIDs in ascending order.
+func (r *Report) DuplicateIDs() []ulid.ULID {
+	ids := make([]ulid.ULID, 0, len(r.Duplicates))
+	for id := range r.Duplicates {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+	return ids
+}
+
+func isZeroEntropy(id ulid.ULID) bool {
+	for _, b := range id[6:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
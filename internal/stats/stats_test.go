@@ -0,0 +1,137 @@
+package stats_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/internal/stats"
+)
+
+func TestAnalyze(t *testing.T) {
+	day := uint64(24 * time.Hour / time.Millisecond)
+	a := ulid.MustNew(day+10, strings.NewReader("aaaaaaaaaa"))
+	b := ulid.MustNew(day+20, strings.NewReader("bbbbbbbbbb"))
+	c := ulid.MustNew(2*day+5, nil)
+	d := ulid.MustNew(5, strings.NewReader("dddddddddd"))
+
+	input := strings.Join([]string{
+		a.String(),
+		b.String(),
+		"",
+		"notaulid",
+		a.String(),
+		c.String(),
+		d.String(),
+		a.String(),
+	}, "\n")
+
+	report, err := stats.Analyze(strings.NewReader(input), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total != 6 {
+		t.Errorf("got total %d, want 6", report.Total)
+	}
+
+	if report.Invalid != 1 {
+		t.Errorf("got invalid %d, want 1", report.Invalid)
+	}
+
+	if report.Distinct != 4 || !report.Exact {
+		t.Errorf("got distinct %d (exact %t), want 4 (exact true)", report.Distinct, report.Exact)
+	}
+
+	if dups := report.DuplicateIDs(); len(dups) != 1 || dups[0] != a || report.Duplicates[a] != 3 {
+		t.Errorf("got duplicates %v, want %s seen 3 times", report.Duplicates, a)
+	}
+
+	if want := ulid.Time(5).UTC(); !report.MinTime.Equal(want) {
+		t.Errorf("got min time %s, want %s", report.MinTime, want)
+	}
+
+	if want := ulid.Time(2*day + 5).UTC(); !report.MaxTime.Equal(want) {
+		t.Errorf("got max time %s, want %s", report.MaxTime, want)
+	}
+
+	if report.ZeroEntropy != 1 {
+		t.Errorf("got zero entropy %d, want 1", report.ZeroEntropy)
+	}
+
+	// a < b, b > a, a < c, c > d, d < a
+	if report.OutOfOrder != 2 {
+		t.Errorf("got out of order %d, want 2", report.OutOfOrder)
+	}
+
+	buckets := report.Buckets()
+	if len(buckets) != 3 {
+		t.Fatalf("got %d buckets, want 3", len(buckets))
+	}
+
+	for i, want := range []uint64{1, 4, 1} {
+		if got := report.Histogram[buckets[i]]; got != want {
+			t.Errorf("bucket %s: got %d, want %d", buckets[i], got, want)
+		}
+	}
+}
+
+func TestAnalyzerBucket(t *testing.T) {
+	analyzer := stats.New(time.Hour, 0)
+	for _, ms := range []uint64{0, 1, uint64(time.Hour / time.Millisecond), uint64(90 * time.Minute / time.Millisecond)} {
+		analyzer.Add(ulid.MustNew(ms, nil))
+	}
+
+	report := analyzer.Report()
+	if report.Bucket != time.Hour {
+		t.Errorf("got bucket %s, want 1h", report.Bucket)
+	}
+
+	buckets := report.Buckets()
+	if len(buckets) != 2 || report.Histogram[buckets[0]] != 2 || report.Histogram[buckets[1]] != 2 {
+		t.Errorf("unexpected histogram %v", report.Histogram)
+	}
+}
+
+func TestAnalyzerMaxTracked(t *testing.T) {
+	analyzer := stats.New(0, 2)
+	ids := []ulid.ULID{ulid.MustNew(1, nil), ulid.MustNew(2, nil), ulid.MustNew(3, nil)}
+	for _, id := range ids {
+		analyzer.Add(id)
+	}
+
+	// Duplicates of tracked IDs are detected, untracked IDs are not.
+	analyzer.Add(ids[0])
+	analyzer.Add(ids[2])
+
+	report := analyzer.Report()
+	if report.Exact {
+		t.Error("expected report to be inexact after exceeding max tracked")
+	}
+
+	// The repeat of the untracked ID is counted again, so the distinct count is an
+	// upper bound of the 3 distinct IDs.
+	if report.Distinct != 4 || report.Distinct < uint64(len(ids)) {
+		t.Errorf("got distinct %d, want the upper bound 4", report.Distinct)
+	}
+
+	if len(report.Duplicates) != 1 || report.Duplicates[ids[0]] != 2 {
+		t.Errorf("unexpected duplicates %v", report.Duplicates)
+	}
+}
+
+func TestAnalyzerMaxBuckets(t *testing.T) {
+	analyzer := stats.New(time.Millisecond, 0)
+	for ms := uint64(0); ms < stats.MaxBuckets+10; ms++ {
+		analyzer.Add(ulid.MustNew(ms, nil))
+	}
+
+	// IDs in the buckets that are already tracked are still counted.
+	analyzer.Add(ulid.MustNew(0, nil))
+
+	report := analyzer.Report()
+	if len(report.Histogram) != stats.MaxBuckets || report.Unbucketed != 10 || report.Histogram[time.UnixMilli(0).UTC()] != 2 {
+		t.Errorf("got %d buckets and %d unbucketed", len(report.Histogram), report.Unbucketed)
+	}
+}
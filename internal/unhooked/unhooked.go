@@ -0,0 +1,10 @@
+// Package unhooked gives the other packages of this module access to the parsing
+// of the ulid package without counting the failures in ulid.ReadHealth or calling
+// the hook of ulid.SetParseFailureHook, e.g. to check test vectors that fail to
+// parse on purpose. The functions are set when the ulid package is initialized,
+// which it always is before the packages that import both.
+package unhooked
+
+// ParseStrict parses the string like ulid.ParseStrict, returning the bytes of the
+// ULID and the same errors.
+var ParseStrict func(s string) ([16]byte, error)
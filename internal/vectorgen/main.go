@@ -0,0 +1,118 @@
+// Command vectorgen generates the cross-language test vectors in
+// vectors/vectors.json from the ulid package. Run it with go generate rather than
+// editing the vectors by hand:
+//
+//	go generate ./...
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/vectors"
+)
+
+// version is incremented when the schema of the test vectors changes.
+const version = 1
+
+var timestamps = []struct {
+	name string
+	ms   uint64
+}{
+	{"epoch", 0},
+	{"one", 1},
+	{"spec", 1469918176385},
+	{"y2025", 1735689600000},
+	{"max", ulid.MaxTime()},
+}
+
+var entropies = []struct {
+	name    string
+	entropy [10]byte
+}{
+	{"zero", [10]byte{}},
+	{"max", [10]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	{"counting", [10]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A}},
+	{"alternating", [10]byte{0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55}},
+	{"mixed", [10]byte{0x8F, 0x3C, 0xD2, 0x07, 0x61, 0xE9, 0x4B, 0x10, 0xF5, 0x9A}},
+}
+
+var malformed = []struct {
+	name  string
+	input string
+}{
+	{"empty", ""},
+	{"short", "01ARZ3NDEKTSV4RRFFQ69G5FA"},
+	{"long", "01ARZ3NDEKTSV4RRFFQ69G5FAVV"},
+	{"uuid", "01563e3a-b5d3-d676-4c61-efb99302bd5b"},
+	{"letter-i", "01ARZ3NDEKTSV4RRFFQ69G5FAI"},
+	{"letter-l", "01ARZ3NDEKTSV4RRFFQ69G5FAL"},
+	{"letter-o", "01ARZ3NDEKTSV4RRFFQ69G5FAO"},
+	{"letter-u", "01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+	{"lowercase-u", "01arz3ndektsv4rrffq69g5fau"},
+	{"hyphen", "01ARZ3NDEK-SV4RRFFQ69G5FAV"},
+	{"space", "01ARZ3NDEKTSV4RRFFQ69G5FA "},
+	{"nul", "01ARZ3NDEKTSV4RRFFQ69G5FA\x00"},
+	{"non-ascii", "01ARZ3NDEKTSV4RRFFQ69G5Fé"},
+	{"overflow", "80000000000000000000000000"},
+	{"overflow-max", "ZZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+}
+
+func main() {
+	out := flag.String("o", "", "write the test vectors to the file instead of stdout")
+	flag.Parse()
+
+	data, err := generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate() ([]byte, error) {
+	set := vectors.Vectors{Version: version}
+	for _, ts := range timestamps {
+		for _, e := range entropies {
+			v, err := vectors.New(ts.name+"/"+e.name, ts.ms, e.entropy)
+			if err != nil {
+				return nil, err
+			}
+			set.Valid = append(set.Valid, v)
+		}
+	}
+
+	for _, m := range malformed {
+		v, err := vectors.NewMalformed(m.name, m.input)
+		if err != nil {
+			return nil, err
+		}
+		set.Malformed = append(set.Malformed, v)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(set); err != nil {
+		return nil, err
+	}
+
+	// Verify the vectors before writing them as a check of the generator.
+	if err := vectors.Verify(bytes.NewReader(buf.Bytes())); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,148 @@
+package ulid
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+//===========================================================================
+// Snowflake IDs
+//===========================================================================
+
+// TwitterEpoch is the epoch of Twitter Snowflake IDs (2010-11-04T01:42:54.657Z).
+// Other Snowflake implementations use different epochs, e.g. Discord uses
+// 2015-01-01T00:00:00Z.
+var TwitterEpoch = time.UnixMilli(1288834974657).UTC()
+
+const (
+	snowflakeTimeShift = 22
+	snowflakeNodeBits  = 1<<snowflakeTimeShift - 1
+	snowflakeMaxTime   = 1<<41 - 1
+)
+
+// FromSnowflake converts a Snowflake ID with the given epoch into a ULID with the
+// same millisecond timestamp so that Snowflake IDs can be ordered and merged
+// with ULIDs by creation time. The 22 worker and sequence bits of the Snowflake
+// are packed into the most significant bits of the entropy and the remaining 58
+// bits of entropy are zero, so ULIDs converted from Snowflakes generated in the
+// same millisecond are ordered like the Snowflakes themselves. ToSnowflake
+// converts the ULID back into the original Snowflake.
+//
+// ErrInvalidSnowflake is returned for negative IDs and ErrSmallTime or
+// ErrBigTime if the timestamp cannot be represented by a ULID.
+func FromSnowflake(id int64, epoch time.Time) (ULID, error) {
+	return FromSnowflakeEntropy(id, epoch, nil)
+}
+
+// FromSnowflakeEntropy is like FromSnowflake but fills the 58 least significant
+// bits of the entropy from the entropy reader (if it is not nil), e.g. so that
+// converted IDs are not distinguishable from generated ULIDs. The worker and
+// sequence bits are preserved so the result can still be converted back with
+// ToSnowflake.
+func FromSnowflakeEntropy(id int64, epoch time.Time, entropy io.Reader) (u ULID, err error) {
+	if id < 0 {
+		return Zero, ErrInvalidSnowflake
+	}
+
+	var ms uint64
+	if ms, err = TimestampChecked(epoch.Add(time.Duration(id>>snowflakeTimeShift) * time.Millisecond)); err != nil {
+		return Zero, err
+	}
+
+	if err = u.SetTime(ms); err != nil {
+		return Zero, err
+	}
+
+	if entropy != nil {
+		if _, err = io.ReadFull(entropy, u[6:]); err != nil {
+			return Zero, err
+		}
+	}
+
+	// The top 22 bits of the entropy are the node bits, which take the first two
+	// entropy bytes and the high 6 bits of the third; its low 2 bits and the
+	// remaining 7 bytes are kept from the random fill.
+	node := uint32(id & snowflakeNodeBits)
+	u[6] = byte(node >> 14)
+	u[7] = byte(node >> 6)
+	u[8] = byte(node<<2) | u[8]&0x03
+	return u, nil
+}
+
+// ToSnowflake converts a ULID into a Snowflake ID with the given epoch using the
+// ULID's timestamp and the worker and sequence bits packed into the most
+// significant bits of its entropy by FromSnowflake. ErrInvalidSnowflake is
+// returned if the ULID's timestamp is before the epoch or more than 2^41
+// milliseconds after it.
+func ToSnowflake(u ULID, epoch time.Time) (int64, error) {
+	offset := int64(u.Time()) - epoch.UnixMilli()
+	if offset < 0 || offset > snowflakeMaxTime {
+		return 0, ErrInvalidSnowflake
+	}
+
+	node := int64(u[6])<<14 | int64(u[7])<<6 | int64(u[8]>>2)
+	return offset<<snowflakeTimeShift | node, nil
+}
+
+// SnowflakeTime returns the creation time of a Snowflake ID with the given epoch
+// at millisecond precision.
+func SnowflakeTime(id int64, epoch time.Time) time.Time {
+	return epoch.Add(time.Duration(id>>snowflakeTimeShift) * time.Millisecond)
+}
+
+// CompareSnowflake compares the timestamp of the ULID to the timestamp of the
+// Snowflake ID with the given epoch, returning -1 if the ULID was created in an
+// earlier millisecond, +1 if it was created in a later millisecond and 0 if both
+// were created in the same millisecond. Both formats have millisecond precision,
+// so 0 means that their relative order cannot be determined rather than that
+// the IDs are equal.
+func CompareSnowflake(u ULID, id int64, epoch time.Time) int {
+	return compareMillis(u.Time(), SnowflakeTime(id, epoch).UnixMilli(), 1)
+}
+
+//===========================================================================
+// KSUIDs
+//===========================================================================
+
+// KSUIDEpoch is the epoch of KSUID timestamps (2014-05-13T16:53:20Z).
+var KSUIDEpoch = time.Unix(1400000000, 0).UTC()
+
+// TimestampFromKSUID returns the creation timestamp of the raw 20 byte KSUID in
+// Unix milliseconds so that it can be compared with ULID timestamps or used to
+// create a ULID with New. KSUID timestamps only have second precision, so the
+// returned timestamp is always the start of the second in which the KSUID was
+// created. ErrBigTime is returned if the timestamp is larger than MaxTime, which
+// cannot happen for the 32-bit timestamps in use today.
+func TimestampFromKSUID(b [20]byte) (uint64, error) {
+	ms := (uint64(binary.BigEndian.Uint32(b[:4])) + uint64(KSUIDEpoch.Unix())) * 1000
+	if ms > maxTime {
+		return 0, ErrBigTime
+	}
+	return ms, nil
+}
+
+// CompareKSUID compares the timestamp of the ULID to the timestamp of the raw
+// KSUID. Because KSUIDs only have second precision, the KSUID is treated as
+// having been created at some point during its second: CompareKSUID returns -1
+// if the ULID was created before the start of the KSUID's second, +1 if it was
+// created after the end of that second, and 0 if it was created during it, in
+// which case the relative order of the IDs cannot be determined.
+func CompareKSUID(u ULID, b [20]byte) int {
+	// No error is possible for current KSUID timestamps (see TimestampFromKSUID).
+	ms, _ := TimestampFromKSUID(b)
+	return compareMillis(u.Time(), int64(ms), 1000)
+}
+
+// compareMillis compares a ULID timestamp with the interval [start, start+width)
+// in Unix milliseconds.
+func compareMillis(ms uint64, start, width int64) int {
+	switch t := int64(ms); {
+	case t < start:
+		return -1
+	case t >= start+width:
+		return 1
+	default:
+		return 0
+	}
+}
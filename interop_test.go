@@ -0,0 +1,202 @@
+package ulid_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// discordEpoch is used with the Snowflake example from the Discord API docs.
+var discordEpoch = time.UnixMilli(1420070400000).UTC()
+
+func TestSnowflake(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Vectors", func(t *testing.T) {
+		for _, tc := range []struct {
+			id    int64
+			epoch time.Time
+			time  time.Time
+			ulid  string
+		}{
+			{
+				// Discord: worker 1, process 0, increment 7
+				id:    175928847299117063,
+				epoch: discordEpoch,
+				time:  time.Date(2016, 4, 30, 11, 18, 25, 796e6, time.UTC),
+				ulid:  "01AHKE86R41001R00000000000",
+			},
+			{
+				id:    0,
+				epoch: ulid.TwitterEpoch,
+				time:  time.Date(2010, 11, 4, 1, 42, 54, 657e6, time.UTC),
+				ulid:  "015GA8T0Y10000000000000000",
+			},
+			{
+				id:    math.MaxInt64,
+				epoch: ulid.TwitterEpoch,
+				time:  time.UnixMilli(1288834974657 + 1<<41 - 1).UTC(),
+				ulid:  "035GA8T0Y0ZZZZR00000000000",
+			},
+		} {
+			if got := ulid.SnowflakeTime(tc.id, tc.epoch); !got.Equal(tc.time) {
+				t.Errorf("%d: got time %s, want %s", tc.id, got, tc.time)
+			}
+
+			u, err := ulid.FromSnowflake(tc.id, tc.epoch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if u.String() != tc.ulid || !u.Timestamp().Equal(tc.time) {
+				t.Errorf("%d: got ulid %s (%s), want %s", tc.id, u, u.Timestamp(), tc.ulid)
+			}
+
+			if id, err := ulid.ToSnowflake(u, tc.epoch); err != nil || id != tc.id {
+				t.Errorf("%d: got snowflake %d (%v) from %s", tc.id, id, err, u)
+			}
+		}
+	})
+
+	t.Run("EntropyBits", func(t *testing.T) {
+		// The node bits replace the high 22 bits of the entropy and the remaining
+		// 58 bits come from the random fill.
+		ones := bytes.Repeat([]byte{0xFF}, 10)
+		u, err := ulid.FromSnowflakeEntropy(0, ulid.TwitterEpoch, bytes.NewReader(ones))
+		if err != nil || !bytes.Equal(u[6:], []byte{0x00, 0x00, 0x03, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}) {
+			t.Errorf("got entropy %x (%v) for node 0", u[6:], err)
+		}
+
+		u, err = ulid.FromSnowflakeEntropy(1<<22-1, ulid.TwitterEpoch, bytes.NewReader(make([]byte, 10)))
+		if err != nil || !bytes.Equal(u[6:], []byte{0xFF, 0xFF, 0xFC, 0, 0, 0, 0, 0, 0, 0}) {
+			t.Errorf("got entropy %x (%v) for all node bits", u[6:], err)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		prop := func(id int64, entropy [10]byte) bool {
+			if id < 0 {
+				id = -(id + 1)
+			}
+
+			u, err := ulid.FromSnowflakeEntropy(id, ulid.TwitterEpoch, bytes.NewReader(entropy[:]))
+			if err != nil {
+				return false
+			}
+
+			// The random entropy must fill the bits after the node bits.
+			if u[8]&0x03 != entropy[2]&0x03 || !bytes.Equal(u[9:], entropy[3:]) {
+				return false
+			}
+
+			rt, err := ulid.ToSnowflake(u, ulid.TwitterEpoch)
+			return err == nil && rt == id
+		}
+
+		if err := quick.Check(prop, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		// Snowflakes in the same millisecond keep their order as ULIDs.
+		base := int64(41944705796) << 22
+		prev := ulid.Zero
+		for _, node := range []int64{0, 1, 7, 1 << 12, 1<<22 - 1} {
+			u, err := ulid.FromSnowflake(base|node, discordEpoch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if u.Compare(prev) <= 0 {
+				t.Fatalf("expected %s to sort after %s", u, prev)
+			}
+			prev = u
+		}
+	})
+
+	t.Run("Compare", func(t *testing.T) {
+		id := int64(175928847299117063)
+		ms := ulid.Timestamp(ulid.SnowflakeTime(id, discordEpoch))
+
+		for _, tc := range []struct {
+			ms   uint64
+			want int
+		}{
+			{ms - 1, -1},
+			{ms, 0},
+			{ms + 1, 1},
+		} {
+			if got := ulid.CompareSnowflake(ulid.MustNew(tc.ms, nil), id, discordEpoch); got != tc.want {
+				t.Errorf("ms %d: got %d, want %d", tc.ms, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := ulid.FromSnowflake(-1, ulid.TwitterEpoch); err != ulid.ErrInvalidSnowflake {
+			t.Errorf("got err %v, want %v", err, ulid.ErrInvalidSnowflake)
+		}
+
+		if _, err := ulid.FromSnowflake(1, time.Unix(-10, 0)); err != ulid.ErrSmallTime {
+			t.Errorf("got err %v, want %v", err, ulid.ErrSmallTime)
+		}
+
+		if _, err := ulid.FromSnowflake(1<<22, ulid.MaxTimestampTime()); err != ulid.ErrBigTime {
+			t.Errorf("got err %v, want %v", err, ulid.ErrBigTime)
+		}
+
+		before := ulid.MustNew(ulid.Timestamp(ulid.TwitterEpoch)-1, nil)
+		if _, err := ulid.ToSnowflake(before, ulid.TwitterEpoch); err != ulid.ErrInvalidSnowflake {
+			t.Errorf("got err %v, want %v", err, ulid.ErrInvalidSnowflake)
+		}
+
+		after := ulid.MustNew(ulid.Timestamp(ulid.TwitterEpoch)+1<<41, nil)
+		if _, err := ulid.ToSnowflake(after, ulid.TwitterEpoch); err != ulid.ErrInvalidSnowflake {
+			t.Errorf("got err %v, want %v", err, ulid.ErrInvalidSnowflake)
+		}
+	})
+}
+
+func TestKSUID(t *testing.T) {
+	t.Parallel()
+
+	// The example KSUID 0ujtsYcgvSTl8PAuAdqWYSMnLOv from the KSUID README.
+	var ksuid [20]byte
+	raw, _ := hex.DecodeString("0669F7EFB5A1CD34B5F99D1154FB6853345C9735")
+	copy(ksuid[:], raw)
+
+	ms, err := ulid.TimestampFromKSUID(ksuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2017, 10, 10, 4, 0, 47, 0, time.UTC)
+	if !ulid.Time(ms).Equal(created) {
+		t.Errorf("got time %s, want %s", ulid.Time(ms).UTC(), created)
+	}
+
+	if ms, _ := ulid.TimestampFromKSUID([20]byte{}); !ulid.Time(ms).Equal(ulid.KSUIDEpoch) {
+		t.Errorf("expected the zero KSUID to have the KSUID epoch")
+	}
+
+	for _, tc := range []struct {
+		offset time.Duration
+		want   int
+	}{
+		{-time.Millisecond, -1},
+		{0, 0},
+		{999 * time.Millisecond, 0},
+		{time.Second, 1},
+	} {
+		u := ulid.MustNew(ulid.Timestamp(created.Add(tc.offset)), nil)
+		if got := ulid.CompareKSUID(u, ksuid); got != tc.want {
+			t.Errorf("offset %s: got %d, want %d", tc.offset, got, tc.want)
+		}
+	}
+}
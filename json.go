@@ -0,0 +1,120 @@
+package ulid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DecodeJSONArray decodes a JSON array of ULID strings from the decoder, which
+// must be positioned at the array (e.g. after the key of an object field has been
+// read with Token), appending the ULIDs to dst. Unlike unmarshaling a []ULID with
+// encoding/json, which allocates a string for each element, each element is
+// parsed from the buffer of the decoder, so decoding does not allocate other than
+// to grow dst; the decoder can continue to be used for the rest of the document.
+//
+// The tradeoff is memory rather than speed: reading the array token by token is
+// about 30% slower than unmarshaling it with encoding/json, but decoding an array
+// of 10,000 ULIDs allocates about 9KB rather than 1.7MB (see BenchmarkJSONArray),
+// which matters for large arrays and for services that decode many of them.
+//
+// A JSON null is decoded as no ULIDs. If an element cannot be decoded, dst is
+// returned with the ULIDs decoded before it and a *BatchError with the index of
+// the element in the array and its byte offset in the input; the underlying error
+// is ErrUnknownType for an element that is not a string or an error from parsing
+// the string. Elements are parsed as with UnmarshalText.
+func DecodeJSONArray(dec *json.Decoder, dst []ULID) ([]ULID, error) {
+	return decodeJSONArray(dec, dst, parseText)
+}
+
+func decodeJSONArray(dec *json.Decoder, dst []ULID, parse func([]byte) (ULID, error)) ([]ULID, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return dst, err
+	}
+
+	switch tok {
+	case nil:
+		return dst, nil
+	case json.Delim('['):
+	default:
+		return dst, fmt.Errorf("%w: expected a JSON array of ULIDs, got %v", ErrUnknownType, tok)
+	}
+
+	var raw json.RawMessage
+	for i := 0; dec.More(); i++ {
+		if err := dec.Decode(&raw); err != nil {
+			return dst, &BatchError{Index: i, Offset: dec.InputOffset(), Err: err}
+		}
+
+		text, err := jsonText(raw)
+		var id ULID
+		if err == nil {
+			id, err = parse(text)
+		}
+
+		if err != nil {
+			offset := dec.InputOffset() - int64(len(raw))
+			return dst, &BatchError{Index: i, Offset: offset, Err: err}
+		}
+		dst = append(dst, id)
+	}
+
+	// Consume the closing bracket of the array.
+	if _, err := dec.Token(); err != nil {
+		return dst, err
+	}
+	return dst, nil
+}
+
+// jsonText returns the contents of a JSON string, which only needs to be unquoted
+// by encoding/json if it contains escapes.
+func jsonText(raw []byte) ([]byte, error) {
+	if len(raw) < 2 || raw[0] != '"' {
+		return nil, ErrUnknownType
+	}
+
+	text := raw[1 : len(raw)-1]
+	if bytes.IndexByte(text, '\\') >= 0 {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		text = []byte(s)
+	}
+	return text, nil
+}
+
+// parseText parses the text as with UnmarshalText.
+func parseText(text []byte) (id ULID, err error) {
+	err = parse(text, false, &id)
+	return id, err
+}
+
+// EncodeJSONArray writes the ULIDs to w as a JSON array of strings, equivalent to
+// marshaling the []ULID with encoding/json, with a single allocation for the
+// buffer of the array and a single write. A nil slice is written as an empty
+// array rather than as null.
+func EncodeJSONArray(w io.Writer, ids []ULID) error {
+	const elementSize = EncodedSize + 3 // quotes and a comma
+
+	buf := make([]byte, 0, 2+len(ids)*elementSize)
+	buf = append(buf, '[')
+	for i := range ids {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = append(buf, '"')
+		buf = buf[:len(buf)+EncodedSize]
+		dst := (*[EncodedSize]byte)(buf[len(buf)-EncodedSize:])
+		ids[i].encodeTime(dst)
+		ids[i].encodeEntropy(dst)
+		buf = append(buf, '"')
+	}
+	buf = append(buf, ']')
+
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,210 @@
+package ulid_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestJSONArray(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, n := range []int{0, 1, 2, 1000} {
+		ids := randomBatch(rng, n)
+
+		var buf bytes.Buffer
+		if err := ulid.EncodeJSONArray(&buf, ids); err != nil {
+			t.Fatal(err)
+		}
+
+		// The encoding is identical to encoding/json.
+		want, _ := json.Marshal(ids)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatalf("array of %d: got %s, want %s", n, buf.Bytes(), want)
+		}
+
+		decoded, err := ulid.DecodeJSONArray(json.NewDecoder(&buf), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(decoded) != n || (n > 0 && !slices.Equal(decoded, ids)) {
+			t.Fatalf("array of %d: decoded ULIDs do not match", n)
+		}
+	}
+
+	// A nil slice is encoded as an empty array.
+	var buf bytes.Buffer
+	if err := ulid.EncodeJSONArray(&buf, nil); err != nil || buf.String() != "[]" {
+		t.Errorf("got %q (%v), want []", buf.String(), err)
+	}
+}
+
+func TestDecodeJSONArrayNested(t *testing.T) {
+	t.Parallel()
+
+	a, b := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE"), ulid.MustParse("01JKEHNQPA0END3NHMFNPBB9WE")
+	doc := `{"name": "batch", "ids": ["01JKEHNQPA0END3NHMFKB2Y6SE", "01JKEHNQPA0END3NHMFNPBB9WE"], "none": null, "count": 2}`
+	dec := json.NewDecoder(strings.NewReader(doc))
+
+	var (
+		ids   []ulid.ULID
+		name  string
+		count int
+	)
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("unexpected token %v (%v)", tok, err)
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch key {
+		case "ids", "none":
+			// Decoded ULIDs are appended to the destination.
+			if ids, err = ulid.DecodeJSONArray(dec, ids); err != nil {
+				t.Fatal(err)
+			}
+		case "name":
+			err = dec.Decode(&name)
+		case "count":
+			err = dec.Decode(&count)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		t.Fatalf("unexpected token %v (%v)", tok, err)
+	}
+
+	if name != "batch" || count != 2 || !slices.Equal(ids, []ulid.ULID{a, b}) {
+		t.Errorf("unexpected document %q %d %v", name, count, ids)
+	}
+}
+
+func TestDecodeJSONArrayErrors(t *testing.T) {
+	t.Parallel()
+
+	const id = `"01JKEHNQPA0END3NHMFKB2Y6SE"`
+	for _, tc := range []struct {
+		name   string
+		data   string
+		index  int
+		offset int64
+		err    error
+	}{
+		{"First", `[1, ` + id + `]`, 0, 1, ulid.ErrUnknownType},
+		{"Null", `[` + id + `, null]`, 1, 31, ulid.ErrUnknownType},
+		{"Object", `[` + id + `,` + id + `,{}]`, 2, 59, ulid.ErrUnknownType},
+		{"Short", `[` + id + `, "01JKEHNQPA"]`, 1, 31, ulid.ErrDataSize},
+		{"Escaped", `[` + id + `, "01JKEHNQPA0END3NHMFKB2Y6S\u0045!"]`, 1, 31, ulid.ErrDataSize},
+		{"Overflow", `["ZZZZZZZZZZZZZZZZZZZZZZZZZZ"]`, 0, 1, ulid.ErrOverflow},
+	} {
+		ids, err := ulid.DecodeJSONArray(json.NewDecoder(strings.NewReader(tc.data)), nil)
+
+		var berr *ulid.BatchError
+		if !errors.As(err, &berr) || !errors.Is(err, tc.err) {
+			t.Errorf("%s: got error %v, want a batch error wrapping %v", tc.name, err, tc.err)
+			continue
+		}
+
+		if berr.Index != tc.index || berr.Offset != tc.offset {
+			t.Errorf("%s: got element %d at offset %d, want %d at %d", tc.name, berr.Index, berr.Offset, tc.index, tc.offset)
+		}
+
+		if len(ids) != tc.index {
+			t.Errorf("%s: expected the %d ULIDs before the error, got %d", tc.name, tc.index, len(ids))
+		}
+	}
+
+	// Syntax errors and values that are not arrays are returned without a position.
+	for _, data := range []string{``, `{"ids": []}`, `"01JKEHNQPA0END3NHMFKB2Y6SE"`, `[` + id + ` ` + id + `]`, `[` + id} {
+		if _, err := ulid.DecodeJSONArray(json.NewDecoder(strings.NewReader(data)), nil); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
+
+func TestDecodeJSONArrayAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	data, _ := json.Marshal(randomBatch(rng, 1000))
+	dst := make([]ulid.ULID, 0, 1000)
+	r := bytes.NewReader(data)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		r.Reset(data)
+		_, _ = ulid.DecodeJSONArray(json.NewDecoder(r), dst)
+	})
+
+	// The decoder and its buffer allocate, but the elements do not.
+	if allocs > 20 {
+		t.Errorf("expected a constant number of allocations, got %.1f", allocs)
+	}
+}
+
+func BenchmarkJSONArray(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 10000)
+	data, _ := json.Marshal(ids)
+
+	b.Run("Decode", func(b *testing.B) {
+		dst := make([]ulid.ULID, 0, len(ids))
+		r := bytes.NewReader(data)
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.Reset(data)
+			_, _ = ulid.DecodeJSONArray(json.NewDecoder(r), dst[:0])
+		}
+	})
+
+	b.Run("NaiveDecode", func(b *testing.B) {
+		r := bytes.NewReader(data)
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.Reset(data)
+			var dst []ulid.ULID
+			_ = json.NewDecoder(r).Decode(&dst)
+		}
+	})
+
+	b.Run("Encode", func(b *testing.B) {
+		var buf bytes.Buffer
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			_ = ulid.EncodeJSONArray(&buf, ids)
+		}
+	})
+
+	b.Run("NaiveEncode", func(b *testing.B) {
+		var buf bytes.Buffer
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			_ = json.NewEncoder(&buf).Encode(ids)
+		}
+	})
+}
@@ -0,0 +1,259 @@
+// Package keys builds and parses order-preserving composite keys for sorted
+// key-value stores such as RocksDB, Pebble, or other LSM trees, e.g. a key of a
+// tenant ULID, a timestamp in descending order, and a sequence number, so that a
+// scan of the keys of a tenant returns its newest records first.
+//
+// Every component has a fixed width and is encoded big-endian, so comparing two
+// keys byte by byte (as with bytes.Compare) orders them by their first component,
+// then by their second, and so on, like the columns of a composite index. The
+// descending components are the bitwise complement of the ascending encoding,
+// which reverses their order. A ULID is encoded as its 16 bytes, whose order is
+// the order of the ULIDs, or in the text variant as its 26 character canonical
+// string for keys that are read by humans, e.g. in the output of a debugging
+// tool; both orders are the same. Because the components have fixed widths, the
+// key of the first components is a prefix of the keys that extend it, e.g. to
+// iterate over the keys of a tenant with a prefix.
+//
+// A KeyParser reads the components back in the order in which they were built;
+// the caller must use the same sequence of components (the schema of the key), and
+// End detects a key that is longer than the schema.
+package keys
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.rtnl.ai/ulid"
+)
+
+var (
+	// Returned when a key is too short for the next component of the schema.
+	ErrTruncated = errors.New("keys: key is truncated")
+
+	// Returned by End when there are bytes left after the last component.
+	ErrTrailingBytes = errors.New("keys: key is longer than the schema")
+
+	// Returned when the text of a ULID component is not a canonical ULID.
+	ErrInvalidText = errors.New("keys: invalid ULID text")
+)
+
+// The sizes in bytes of the components of a key.
+const (
+	ULIDSize     = 16
+	ULIDTextSize = ulid.EncodedSize
+	Uint64Size   = 8
+	Uint32Size   = 4
+)
+
+//===========================================================================
+// Key Builder
+//===========================================================================
+
+// KeyBuilder appends the components of a composite key. The zero value is an
+// empty builder ready to use; a KeyBuilder is not safe for concurrent use.
+type KeyBuilder struct {
+	buf []byte
+}
+
+// NewKeyBuilder returns an empty builder.
+func NewKeyBuilder() *KeyBuilder {
+	return &KeyBuilder{}
+}
+
+// NewKeyBuilderSize returns an empty builder with room for a key of n bytes, so
+// that building a key of at most n bytes allocates once.
+func NewKeyBuilderSize(n int) *KeyBuilder {
+	return &KeyBuilder{buf: make([]byte, 0, n)}
+}
+
+// ULID appends the 16 bytes of the ULID in ascending order.
+func (b *KeyBuilder) ULID(id ulid.ULID) *KeyBuilder {
+	b.buf = append(b.buf, id[:]...)
+	return b
+}
+
+// ULIDDesc appends the complement of the 16 bytes of the ULID, so that newer
+// ULIDs sort first.
+func (b *KeyBuilder) ULIDDesc(id ulid.ULID) *KeyBuilder {
+	for _, c := range id {
+		b.buf = append(b.buf, ^c)
+	}
+	return b
+}
+
+// ULIDText appends the 26 character canonical string of the ULID, which sorts in
+// the same order as the ULIDs.
+func (b *KeyBuilder) ULIDText(id ulid.ULID) *KeyBuilder {
+	b.buf = append(b.buf, id.String()...)
+	return b
+}
+
+// Uint64 appends the big-endian encoding of v in ascending order.
+func (b *KeyBuilder) Uint64(v uint64) *KeyBuilder {
+	b.buf = binary.BigEndian.AppendUint64(b.buf, v)
+	return b
+}
+
+// Uint64Desc appends the big-endian encoding of the complement of v, so that larger
+// values sort first, e.g. for the newest timestamps in Unix milliseconds.
+func (b *KeyBuilder) Uint64Desc(v uint64) *KeyBuilder {
+	b.buf = binary.BigEndian.AppendUint64(b.buf, ^v)
+	return b
+}
+
+// Uint32 appends the big-endian encoding of v in ascending order.
+func (b *KeyBuilder) Uint32(v uint32) *KeyBuilder {
+	b.buf = binary.BigEndian.AppendUint32(b.buf, v)
+	return b
+}
+
+// Uint32Desc appends the big-endian encoding of the complement of v, so that larger
+// values sort first.
+func (b *KeyBuilder) Uint32Desc(v uint32) *KeyBuilder {
+	b.buf = binary.BigEndian.AppendUint32(b.buf, ^v)
+	return b
+}
+
+// Bytes returns the key. The key aliases the buffer of the builder and is only
+// valid until the next call to Reset, so it must be copied to be retained if the
+// builder is reused; most stores copy the keys that are put into them.
+func (b *KeyBuilder) Bytes() []byte {
+	return b.buf
+}
+
+// Len returns the length of the key in bytes.
+func (b *KeyBuilder) Len() int {
+	return len(b.buf)
+}
+
+// Reset empties the builder, keeping its buffer to build the next key.
+func (b *KeyBuilder) Reset() *KeyBuilder {
+	b.buf = b.buf[:0]
+	return b
+}
+
+//===========================================================================
+// Key Parser
+//===========================================================================
+
+// KeyParser extracts the components of a key in the order in which they were
+// built. The first error stops the parser: the following components are not
+// read and are left unchanged, and the error is returned by Err and End. A
+// KeyParser is not safe for concurrent use.
+type KeyParser struct {
+	key       []byte
+	offset    int
+	component int
+	err       error
+}
+
+// NewKeyParser returns a parser of the components of the key.
+func NewKeyParser(key []byte) *KeyParser {
+	return &KeyParser{key: key}
+}
+
+// ULID reads a ULID appended by KeyBuilder.ULID into id.
+func (p *KeyParser) ULID(id *ulid.ULID) *KeyParser {
+	if b := p.next(ULIDSize); b != nil {
+		copy(id[:], b)
+	}
+	return p
+}
+
+// ULIDDesc reads a ULID appended by KeyBuilder.ULIDDesc into id.
+func (p *KeyParser) ULIDDesc(id *ulid.ULID) *KeyParser {
+	if b := p.next(ULIDSize); b != nil {
+		for i, c := range b {
+			id[i] = ^c
+		}
+	}
+	return p
+}
+
+// ULIDText reads a ULID appended by KeyBuilder.ULIDText into id. The text must be
+// the canonical encoding of a ULID, since other encodings of the same ULID would
+// not sort in its place.
+func (p *KeyParser) ULIDText(id *ulid.ULID) *KeyParser {
+	if b := p.next(ULIDTextSize); b != nil {
+		u, err := ulid.ParseCanonical(string(b))
+		if err != nil {
+			p.err = fmt.Errorf("%w: component %d at offset %d: %w", ErrInvalidText, p.component-1, p.offset-ULIDTextSize, err)
+			return p
+		}
+		*id = u
+	}
+	return p
+}
+
+// Uint64 reads an integer appended by KeyBuilder.Uint64 into v.
+func (p *KeyParser) Uint64(v *uint64) *KeyParser {
+	if b := p.next(Uint64Size); b != nil {
+		*v = binary.BigEndian.Uint64(b)
+	}
+	return p
+}
+
+// Uint64Desc reads an integer appended by KeyBuilder.Uint64Desc into v.
+func (p *KeyParser) Uint64Desc(v *uint64) *KeyParser {
+	if b := p.next(Uint64Size); b != nil {
+		*v = ^binary.BigEndian.Uint64(b)
+	}
+	return p
+}
+
+// Uint32 reads an integer appended by KeyBuilder.Uint32 into v.
+func (p *KeyParser) Uint32(v *uint32) *KeyParser {
+	if b := p.next(Uint32Size); b != nil {
+		*v = binary.BigEndian.Uint32(b)
+	}
+	return p
+}
+
+// Uint32Desc reads an integer appended by KeyBuilder.Uint32Desc into v.
+func (p *KeyParser) Uint32Desc(v *uint32) *KeyParser {
+	if b := p.next(Uint32Size); b != nil {
+		*v = ^binary.BigEndian.Uint32(b)
+	}
+	return p
+}
+
+// Remaining returns the bytes of the key that have not been read, e.g. a suffix
+// that is not described by the schema.
+func (p *KeyParser) Remaining() []byte {
+	return p.key[p.offset:]
+}
+
+// Err returns the first error of the parser, if any, without checking that the
+// whole key has been read, e.g. to parse the prefix of a longer key.
+func (p *KeyParser) Err() error {
+	return p.err
+}
+
+// End returns the first error of the parser like Err, or ErrTrailingBytes if the
+// key is longer than the components that were read, which detects a key that
+// was built with another schema, e.g. with an additional component.
+func (p *KeyParser) End() error {
+	if p.err == nil && p.offset != len(p.key) {
+		return fmt.Errorf("%w: %d bytes after %d components", ErrTrailingBytes, len(p.key)-p.offset, p.component)
+	}
+	return p.err
+}
+
+// next returns the next n bytes of the key, or nil if the parser has failed or
+// the key is truncated.
+func (p *KeyParser) next(n int) []byte {
+	if p.err != nil {
+		return nil
+	}
+
+	if len(p.key)-p.offset < n {
+		p.err = fmt.Errorf("%w: component %d at offset %d needs %d bytes, %d remaining", ErrTruncated, p.component, p.offset, n, len(p.key)-p.offset)
+		return nil
+	}
+
+	b := p.key[p.offset : p.offset+n]
+	p.offset += n
+	p.component++
+	return b
+}
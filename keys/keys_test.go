@@ -0,0 +1,212 @@
+package keys_test
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/keys"
+)
+
+// row is the multi-column ordering of a key: tenant ascending, then ms
+// descending, then seq ascending, then the event descending.
+type row struct {
+	tenant ulid.ULID
+	ms     uint64
+	seq    uint32
+	event  ulid.ULID
+	rank   uint32
+	text   ulid.ULID
+}
+
+func compareRows(a, b row) int {
+	return cmp.Or(
+		a.tenant.Compare(b.tenant),
+		cmp.Compare(b.ms, a.ms),
+		cmp.Compare(a.seq, b.seq),
+		b.event.Compare(a.event),
+		cmp.Compare(b.rank, a.rank),
+		a.text.Compare(b.text),
+	)
+}
+
+func (r row) key(b *keys.KeyBuilder) []byte {
+	return b.Reset().ULID(r.tenant).Uint64Desc(r.ms).Uint32(r.seq).ULIDDesc(r.event).Uint32Desc(r.rank).ULIDText(r.text).Bytes()
+}
+
+// randomRows returns rows with few distinct values in each column, so that the
+// later columns decide the order of many rows.
+func randomRows(rng *rand.Rand, n int) []row {
+	pick := func(n int) []ulid.ULID {
+		ids := make([]ulid.ULID, n)
+		for i := range ids {
+			ids[i] = ulid.MustNew(uint64(rng.Int63n(int64(ulid.MaxTime()))), rng)
+		}
+		return ids
+	}
+
+	tenants, events, texts := pick(4), pick(3), pick(3)
+	edges := []uint64{0, 1, 1<<32 - 1, 1 << 32, 1<<63 - 1, 1 << 63, 1<<64 - 1}
+	rows := make([]row, n)
+	for i := range rows {
+		rows[i] = row{
+			tenant: tenants[rng.Intn(len(tenants))],
+			ms:     edges[rng.Intn(len(edges))],
+			seq:    uint32(edges[rng.Intn(4)]),
+			event:  events[rng.Intn(len(events))],
+			rank:   uint32(rng.Intn(3)) << 30,
+			text:   texts[rng.Intn(len(texts))],
+		}
+	}
+	return rows
+}
+
+func TestKeyOrdering(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rows := randomRows(rng, 5000)
+
+	b := keys.NewKeyBuilder()
+	built := make([][]byte, len(rows))
+	for i, r := range rows {
+		built[i] = bytes.Clone(r.key(b))
+	}
+
+	// Every pair of keys compares like the columns of its rows.
+	for i := 0; i < 20000; i++ {
+		x, y := rng.Intn(len(rows)), rng.Intn(len(rows))
+		if got, want := bytes.Compare(built[x], built[y]), compareRows(rows[x], rows[y]); got != want {
+			t.Fatalf("got %d comparing the keys of %v and %v, want %d", got, rows[x], rows[y], want)
+		}
+	}
+
+	// Sorting the keys sorts the rows.
+	slices.SortFunc(rows, compareRows)
+	slices.SortFunc(built, bytes.Compare)
+	for i, r := range rows {
+		if !bytes.Equal(built[i], r.key(b)) {
+			t.Fatalf("key %d is out of order", i)
+		}
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	b := keys.NewKeyBuilderSize(keys.ULIDSize*2 + keys.Uint64Size + keys.Uint32Size*2 + keys.ULIDTextSize)
+	for _, r := range randomRows(rng, 1000) {
+		key := r.key(b)
+		if len(key) != 16+8+4+16+4+26 || b.Len() != len(key) {
+			t.Fatalf("got a key of %d bytes", len(key))
+		}
+
+		var got row
+		p := keys.NewKeyParser(key)
+		if err := p.ULID(&got.tenant).Uint64Desc(&got.ms).Uint32(&got.seq).ULIDDesc(&got.event).Uint32Desc(&got.rank).ULIDText(&got.text).End(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got != r || len(p.Remaining()) != 0 {
+			t.Fatalf("got %v, want %v", got, r)
+		}
+	}
+
+	// The ascending and descending encodings of the same values.
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	key := keys.NewKeyBuilder().Uint64(1).Uint32Desc(1).ULIDText(id).Bytes()
+	want := append([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0xFF, 0xFF, 0xFF, 0xFE}, "01JKEHNQPA0END3NHMFKB2Y6SE"...)
+	if !bytes.Equal(key, want) {
+		t.Errorf("got %x, want %x", key, want)
+	}
+
+	var (
+		ms  uint64
+		seq uint32
+		got ulid.ULID
+	)
+	if err := keys.NewKeyParser(key).Uint64(&ms).Uint32Desc(&seq).ULIDText(&got).End(); err != nil || ms != 1 || seq != 1 || got != id {
+		t.Errorf("got %d, %d, and %s (%v)", ms, seq, got, err)
+	}
+
+	// The key of the first components is a prefix of the full key.
+	tenant := keys.NewKeyBuilder().ULID(id).Bytes()
+	full := keys.NewKeyBuilder().ULID(id).Uint64Desc(42).Uint32(7).Bytes()
+	if !bytes.HasPrefix(full, tenant) {
+		t.Errorf("expected %x to be a prefix of %x", tenant, full)
+	}
+
+	// The prefix can be parsed without checking the length.
+	p := keys.NewKeyParser(full)
+	if err := p.ULID(&got).Err(); err != nil || got != id || len(p.Remaining()) != 12 {
+		t.Errorf("got %s with %d bytes remaining (%v)", got, len(p.Remaining()), err)
+	}
+}
+
+func TestKeyParserErrors(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	key := keys.NewKeyBuilder().ULID(id).Uint64Desc(42).Uint32(7).Bytes()
+
+	// Every truncation of the key is detected.
+	for n := 0; n < len(key); n++ {
+		var (
+			tenant ulid.ULID
+			ms     uint64 = 1
+			seq    uint32 = 2
+		)
+
+		err := keys.NewKeyParser(key[:n]).ULID(&tenant).Uint64Desc(&ms).Uint32(&seq).End()
+		if !errors.Is(err, keys.ErrTruncated) {
+			t.Fatalf("%d bytes: got %v, want %v", n, err, keys.ErrTruncated)
+		}
+
+		// The components before the truncated one are read and the rest are not
+		// modified.
+		if (n >= 16) != (tenant == id) || (n >= 24) != (ms == 42) || seq != 2 {
+			t.Errorf("%d bytes: got %s, %d, and %d", n, tenant, ms, seq)
+		}
+	}
+
+	var (
+		tenant ulid.ULID
+		ms     uint64
+		seq    uint32
+	)
+
+	// A key of another schema is detected by its length.
+	err := keys.NewKeyParser(key).ULID(&tenant).Uint64Desc(&ms).End()
+	if !errors.Is(err, keys.ErrTrailingBytes) || !strings.Contains(err.Error(), "4 bytes after 2 components") {
+		t.Errorf("got %v, want %v", err, keys.ErrTrailingBytes)
+	}
+
+	err = keys.NewKeyParser(key).ULID(&tenant).Uint64Desc(&ms).Uint32(&seq).Uint32(&seq).End()
+	if !errors.Is(err, keys.ErrTruncated) || !strings.Contains(err.Error(), "component 3 at offset 28") {
+		t.Errorf("got %v, want %v", err, keys.ErrTruncated)
+	}
+
+	// The text of a ULID must be canonical.
+	for _, text := range []string{strings.ToLower(id.String()), "01JKEHNQPA0END3NHMFKB2Y6SU", "81JKEHNQPA0END3NHMFKB2Y6SE"} {
+		got := ulid.Zero
+		err := keys.NewKeyParser([]byte(text)).ULIDText(&got).End()
+		if !errors.Is(err, keys.ErrInvalidText) || got != ulid.Zero {
+			t.Errorf("%s: got %s (%v), want %v", text, got, err, keys.ErrInvalidText)
+		}
+	}
+}
+
+func BenchmarkKeyBuilder(b *testing.B) {
+	id := ulid.Make()
+	kb := keys.NewKeyBuilderSize(keys.ULIDSize + keys.Uint64Size + keys.Uint32Size)
+	for i := 0; i < b.N; i++ {
+		kb.Reset().ULID(id).Uint64Desc(uint64(i)).Uint32(uint32(i))
+	}
+}
@@ -0,0 +1,79 @@
+package ulid
+
+import "bytes"
+
+// LegacyScanner scans a ULID from a text column that also contains legacy IDs
+// that predate ULIDs, e.g. shorter IDs right-padded with spaces by a CHAR(26)
+// column. Trailing spaces are trimmed from the text and the remainder is parsed
+// strictly like ParseStrict; if it is not a valid ULID, the Convert callback maps
+// the legacy ID to a ULID, e.g. deterministically by hashing it into the entropy
+// of a ULID with a fixed timestamp, and Converted is set. ULID.Scan is unchanged
+// and still returns ErrDataSize for padded values.
+//
+//	s := ulid.LegacyScanner{Convert: fromLegacyID}
+//	err := row.Scan(&s)
+//
+// A []byte of 16 bytes is scanned as the binary encoding and other values are
+// scanned like ULID.Scan, without conversion. A NULL is scanned as the zero ULID.
+type LegacyScanner struct {
+	// ULID is the scanned or converted ULID.
+	ULID ULID
+
+	// Converted is true if ULID was returned by Convert for a legacy ID.
+	Converted bool
+
+	// Convert is called with the trimmed text of a value that is not a valid
+	// ULID. Its error is returned by Scan as is. If Convert is nil the parse error
+	// is returned instead.
+	Convert func(string) (ULID, error)
+}
+
+// Scan implements the sql.Scanner interface. If an error is returned, the ULID
+// and Converted are not modified.
+func (s *LegacyScanner) Scan(src any) error {
+	var text []byte
+	switch x := src.(type) {
+	case nil:
+		s.ULID, s.Converted = Zero, false
+		return nil
+	case string:
+		text = []byte(x)
+	case []byte:
+		if len(x) == 16 {
+			return s.scan(src)
+		}
+		text = x
+	default:
+		return s.scan(src)
+	}
+
+	text = bytes.TrimRight(text, " ")
+
+	var id ULID
+	err := parse(text, true, &id)
+	if err == nil {
+		s.ULID, s.Converted = id, false
+		return nil
+	}
+
+	if s.Convert == nil {
+		return err
+	}
+
+	if id, err = s.Convert(string(text)); err != nil {
+		return err
+	}
+
+	s.ULID, s.Converted = id, true
+	return nil
+}
+
+func (s *LegacyScanner) scan(src any) error {
+	var id ULID
+	if err := id.Scan(src); err != nil {
+		return err
+	}
+
+	s.ULID, s.Converted = id, false
+	return nil
+}
@@ -0,0 +1,121 @@
+package ulid_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+// legacyEpoch is the fixed timestamp of the ULIDs converted from legacy IDs.
+const legacyEpoch = 1262304000000
+
+var errLegacyID = errors.New("not a legacy id")
+
+// fromLegacyID deterministically hashes 20 character legacy IDs into the entropy
+// of a ULID with the legacy epoch.
+func fromLegacyID(s string) (ulid.ULID, error) {
+	if len(s) != 20 {
+		return ulid.Zero, errLegacyID
+	}
+
+	sum := sha256.Sum256([]byte(s))
+	return ulid.New(legacyEpoch, bytes.NewReader(sum[:]))
+}
+
+func TestLegacyScanner(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	legacy, _ := fromLegacyID("LEGACY00000000000042")
+
+	testCases := []struct {
+		name      string
+		src       any
+		expected  ulid.ULID
+		converted bool
+	}{
+		{"String", "01HTNMW2JAW89YSBG7NFPHABA4", id, false},
+		{"Lowercase", "01htnmw2jaw89ysbg7nfphaba4", id, false},
+		{"Bytes", []byte("01HTNMW2JAW89YSBG7NFPHABA4"), id, false},
+		{"Binary", id.Bytes(), id, false},
+		{"Timestamp", int64(legacyEpoch), ulid.MustNew(legacyEpoch, nil), false},
+		{"Null", nil, ulid.Zero, false},
+		{"Legacy", "LEGACY00000000000042", legacy, true},
+		{"PaddedLegacy", "LEGACY00000000000042      ", legacy, true},
+		{"PaddedLegacyBytes", []byte("LEGACY00000000000042      "), legacy, true},
+	}
+
+	for _, tc := range testCases {
+		// Start from a converted value to show that it is reset.
+		s := ulid.LegacyScanner{ULID: legacy, Converted: true, Convert: fromLegacyID}
+		if err := s.Scan(tc.src); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+
+		if s.ULID != tc.expected || s.Converted != tc.converted {
+			t.Errorf("%s: got %s (converted %t), want %s (converted %t)", tc.name, s.ULID, s.Converted, tc.expected, tc.converted)
+		}
+	}
+
+	// Conversion is deterministic.
+	if again, _ := fromLegacyID("LEGACY00000000000042"); again != legacy || legacy.Time() != legacyEpoch {
+		t.Errorf("expected the same ULID for the same legacy ID, got %s and %s", legacy, again)
+	}
+
+	var _ sql.Scanner = &ulid.LegacyScanner{}
+}
+
+func TestLegacyScannerPadded(t *testing.T) {
+	t.Parallel()
+
+	// A CHAR(26) column pads only legacy IDs, but longer columns also pad ULIDs.
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	for _, src := range []any{"01HTNMW2JAW89YSBG7NFPHABA4    ", []byte("01HTNMW2JAW89YSBG7NFPHABA4 ")} {
+		var s ulid.LegacyScanner
+		if err := s.Scan(src); err != nil || s.ULID != id || s.Converted {
+			t.Errorf("%q: got %s (converted %t, %v), want %s", src, s.ULID, s.Converted, err, id)
+		}
+
+		// ULID.Scan does not trim the padding.
+		var u ulid.ULID
+		if err := u.Scan(src); err == nil {
+			t.Errorf("%q: expected ULID.Scan to fail", src)
+		}
+	}
+}
+
+func TestLegacyScannerErrors(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	testCases := []struct {
+		name    string
+		src     any
+		convert func(string) (ulid.ULID, error)
+		err     error
+	}{
+		{"NoConverter", "LEGACY00000000000042", nil, ulid.ErrDataSize},
+		{"NoConverterInvalid", "01HTNMW2JAW89YSBG7NFPHABAU", nil, ulid.ErrInvalidCharacters},
+		{"Converter", "LEGACY42", fromLegacyID, errLegacyID},
+		{"Empty", "     ", fromLegacyID, errLegacyID},
+		{"Binary", []byte{1, 2, 3}, fromLegacyID, errLegacyID},
+		{"Unsupported", 3.14, fromLegacyID, ulid.ErrScanValue},
+		{"Timestamp", int64(-1), fromLegacyID, ulid.ErrSmallTime},
+	}
+
+	for _, tc := range testCases {
+		s := ulid.LegacyScanner{ULID: id, Convert: tc.convert}
+		if err := s.Scan(tc.src); err != tc.err {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.err)
+		}
+
+		if s.ULID != id || s.Converted {
+			t.Errorf("%s: expected the scanner not to be modified, got %s (converted %t)", tc.name, s.ULID, s.Converted)
+		}
+	}
+}
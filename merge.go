@@ -0,0 +1,248 @@
+package ulid
+
+import (
+	"bytes"
+	"container/heap"
+	"crypto/sha256"
+	"fmt"
+	"iter"
+)
+
+// Merge returns the ascending k-way merge of the ascending sequences of ULIDs,
+// e.g. to compact sorted segment files read with NewReader. Duplicate ULIDs are
+// yielded once for each time they occur. If a sequence is not sorted the merge
+// stops at the first ULID that is out of order; use a Merger to report the
+// error instead.
+func Merge(seqs ...iter.Seq[ULID]) iter.Seq[ULID] {
+	return (&Merger{}).Merge(seqs...)
+}
+
+// MergeUnique is like Merge but yields each distinct ULID only once, dropping
+// exact duplicates both within and across the sequences.
+func MergeUnique(seqs ...iter.Seq[ULID]) iter.Seq[ULID] {
+	return (&Merger{Unique: true}).Merge(seqs...)
+}
+
+// Merger merges ascending sequences of ULIDs and detects sequences that are not
+// sorted rather than producing output that is out of order. The zero value is
+// ready to use and stops the merge at the first ULID that is out of order. A
+// Merger must not be used for more than one merge at a time.
+type Merger struct {
+	// Unique drops exact duplicates so that each distinct ULID is yielded once.
+	Unique bool
+
+	// OnError is called with a *MergeError (which wraps ErrUnsorted) when a ULID
+	// is less than the previous ULID of the same sequence. If it returns true the
+	// ULID is dropped and the merge continues; otherwise, or if OnError is nil,
+	// the merge stops and the error is returned by Err.
+	OnError func(err error) bool
+
+	err error
+}
+
+// MergeError describes a ULID that is out of order in a source of a merge.
+type MergeError struct {
+	Source int  // The index of the unsorted sequence
+	Prev   ULID // The previous ULID of the sequence
+	ULID   ULID // The ULID that is less than the previous ULID
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("%s: source %d yielded %s after %s", ErrUnsorted, e.Source, e.ULID, e.Prev)
+}
+
+func (e *MergeError) Unwrap() error {
+	return ErrUnsorted
+}
+
+// Merge returns the ascending merge of the ascending sequences. The error of the
+// merge, if any, is available from Err once iteration is complete.
+func (m *Merger) Merge(seqs ...iter.Seq[ULID]) iter.Seq[ULID] {
+	return merge(m, seqs, ULID.Compare, func(id ULID) ULID { return id })
+}
+
+// MergeKeys returns the ascending merge of the ascending sequences of keys
+// returned by StableMergeKey like Merge. With Unique, exact duplicate keys are
+// dropped, so the same ULID from different sources is still yielded once for
+// each source. A key that is out of order is reported with the ULIDs of the keys.
+func (m *Merger) MergeKeys(seqs ...iter.Seq[[MergeKeySize]byte]) iter.Seq[[MergeKeySize]byte] {
+	return merge(m, seqs, compareKeys, MergeKeyULID)
+}
+
+// merge is the k-way merge of Merge and MergeKeys; ulidOf returns the ULID of an
+// item for the MergeError of an item that is out of order.
+func merge[T comparable](m *Merger, seqs []iter.Seq[T], compare func(a, b T) int, ulidOf func(T) ULID) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		m.err = nil
+
+		sources := make([]mergeSource[T], 0, len(seqs))
+		defer func() {
+			for _, src := range sources {
+				src.stop()
+			}
+		}()
+
+		h := &mergeHeap[T]{compare: compare}
+		for i, seq := range seqs {
+			next, stop := iter.Pull(seq)
+			sources = append(sources, mergeSource[T]{next: next, stop: stop})
+			if id, ok := next(); ok {
+				sources[i].prev = id
+				h.items = append(h.items, mergeItem[T]{id: id, source: i})
+			}
+		}
+		heap.Init(h)
+
+		var (
+			last    T
+			started bool
+		)
+
+		for h.Len() > 0 {
+			item := h.items[0]
+			if !m.Unique || !started || item.id != last {
+				if !yield(item.id) {
+					return
+				}
+				last, started = item.id, true
+			}
+
+			if !advance(m, h, &sources[item.source], item.source, ulidOf) {
+				return
+			}
+		}
+	}
+}
+
+// advance replaces the head of the heap with the next item of its source,
+// returning false if the merge must stop because the source is not sorted.
+func advance[T any](m *Merger, h *mergeHeap[T], src *mergeSource[T], i int, ulidOf func(T) ULID) bool {
+	for {
+		id, ok := src.next()
+		if !ok {
+			heap.Pop(h)
+			return true
+		}
+
+		if h.compare(id, src.prev) < 0 {
+			err := &MergeError{Source: i, Prev: ulidOf(src.prev), ULID: ulidOf(id)}
+			if m.OnError == nil || !m.OnError(err) {
+				m.err = err
+				return false
+			}
+			continue
+		}
+
+		src.prev = id
+		h.items[0].id = id
+		heap.Fix(h, 0)
+		return true
+	}
+}
+
+// Err returns the error that stopped the last merge, if any.
+func (m *Merger) Err() error {
+	return m.err
+}
+
+// CompareWithTiebreak compares the ULIDs like Compare and, if they are equal,
+// breaks the tie by comparing tieA and tieB lexicographically, e.g. to order the
+// same ULID from different sources by the identifiers of the sources.
+func CompareWithTiebreak(a, b ULID, tieA, tieB []byte) int {
+	if cmp := a.Compare(b); cmp != 0 {
+		return cmp
+	}
+	return bytes.Compare(tieA, tieB)
+}
+
+// MergeKeySize is the size of the keys returned by StableMergeKey.
+const MergeKeySize = 32
+
+// StableMergeKey returns a key for the ULID from the source, e.g. the identifier
+// of the node that produced it, that sorts by the ULID first and then by the
+// source. The first 16 bytes of the key are the ULID and the last 16 bytes are
+// the first 16 bytes of the SHA-256 hash of the source, so the keys of ULIDs are
+// still primarily time ordered and the order of keys compared as bytes is total
+// and deterministic: it does not depend on the order in which streams of ULIDs
+// arrive, even if the same ULID is produced by different sources. The ULID can be
+// recovered with MergeKeyULID. The hash only fixes the length of the key and is
+// not keyed, so it does not hide the source: a source with a guessable identifier,
+// such as a host name, can be recovered by hashing the candidates.
+func StableMergeKey(id ULID, source []byte) (key [MergeKeySize]byte) {
+	sum := sha256.Sum256(source)
+	copy(key[:], id[:])
+	copy(key[len(id):], sum[:])
+	return key
+}
+
+// MergeKeyULID returns the ULID of a key returned by StableMergeKey.
+func MergeKeyULID(key [MergeKeySize]byte) (id ULID) {
+	copy(id[:], key[:])
+	return id
+}
+
+// StableMergeKeys returns the keys of the ULIDs of the sequence from the source,
+// which are ascending if the ULIDs are, for use with MergeKeys. The source is
+// hashed once rather than for every ULID.
+func StableMergeKeys(seq iter.Seq[ULID], source []byte) iter.Seq[[MergeKeySize]byte] {
+	sum := sha256.Sum256(source)
+	return func(yield func([MergeKeySize]byte) bool) {
+		var key [MergeKeySize]byte
+		copy(key[len(ULID{}):], sum[:])
+		for id := range seq {
+			copy(key[:], id[:])
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// MergeKeys returns the ascending merge of ascending sequences of keys returned by
+// StableMergeKey, e.g. to replay event streams from multiple nodes in the same
+// order regardless of the order in which the streams are passed. Like Merge, the
+// merge stops at the first key that is out of order in its sequence; use the
+// MergeKeys method of a Merger to report the error instead.
+func MergeKeys(seqs ...iter.Seq[[MergeKeySize]byte]) iter.Seq[[MergeKeySize]byte] {
+	return (&Merger{}).MergeKeys(seqs...)
+}
+
+func compareKeys(a, b [MergeKeySize]byte) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+type mergeSource[T any] struct {
+	next func() (T, bool)
+	stop func()
+	prev T
+}
+
+type mergeItem[T any] struct {
+	id     T
+	source int
+}
+
+// mergeHeap is a min-heap of the next item of each source; ties are broken by the
+// source index so that the merge is stable.
+type mergeHeap[T any] struct {
+	items   []mergeItem[T]
+	compare func(a, b T) int
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.items) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	if cmp := h.compare(h.items[i].id, h.items[j].id); cmp != 0 {
+		return cmp < 0
+	}
+	return h.items[i].source < h.items[j].source
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *mergeHeap[T]) Push(x any)    { h.items = append(h.items, x.(mergeItem[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
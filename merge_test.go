@@ -0,0 +1,263 @@
+package ulid_test
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]ulid.ULID, 100)
+	for i := range ids {
+		ids[i] = ulid.MustNew(uint64(i/3), &constReader{byte(i)})
+	}
+
+	// Interleaved ranges, an overlapping range with duplicates, and an empty source.
+	var evens, odds []ulid.ULID
+	for i, id := range ids {
+		if i%2 == 0 {
+			evens = append(evens, id)
+		} else {
+			odds = append(odds, id)
+		}
+	}
+	dups := slices.Clone(ids[40:60])
+	dups = append(dups, ids[59], ids[59])
+
+	merged := slices.Collect(ulid.Merge(slices.Values(evens), slices.Values(odds), slices.Values(dups), slices.Values([]ulid.ULID(nil))))
+	if len(merged) != len(ids)+len(dups) || !slices.IsSortedFunc(merged, ulid.ULID.Compare) {
+		t.Errorf("expected %d sorted ulids, got %d", len(ids)+len(dups), len(merged))
+	}
+
+	unique := slices.Collect(ulid.MergeUnique(slices.Values(evens), slices.Values(odds), slices.Values(dups), slices.Values([]ulid.ULID(nil))))
+	if !slices.Equal(unique, ids) {
+		t.Errorf("expected the unique merge to equal the original ulids")
+	}
+
+	if got := slices.Collect(ulid.Merge()); len(got) != 0 {
+		t.Errorf("expected no ulids from no sources")
+	}
+
+	if got := slices.Collect(ulid.MergeUnique(slices.Values([]ulid.ULID{ids[0], ids[0]}))); !slices.Equal(got, ids[:1]) {
+		t.Errorf("expected duplicates within a source to be dropped, got %v", got)
+	}
+
+	t.Run("Break", func(t *testing.T) {
+		t.Parallel()
+
+		stopped := 0
+		counted := func(s []ulid.ULID) iter.Seq[ulid.ULID] {
+			return func(yield func(ulid.ULID) bool) {
+				defer func() { stopped++ }()
+				for _, id := range s {
+					if !yield(id) {
+						return
+					}
+				}
+			}
+		}
+
+		var got []ulid.ULID
+		for id := range ulid.Merge(counted(evens), counted(odds)) {
+			if got = append(got, id); len(got) == 10 {
+				break
+			}
+		}
+
+		if !slices.Equal(got, ids[:10]) || stopped != 2 {
+			t.Errorf("expected the merge to stop both sources, got %d ulids and %d stopped", len(got), stopped)
+		}
+	})
+
+	t.Run("Unsorted", func(t *testing.T) {
+		t.Parallel()
+
+		unsorted := []ulid.ULID{ids[10], ids[30], ids[20], ids[40]}
+
+		m := &ulid.Merger{}
+		got := slices.Collect(m.Merge(slices.Values(evens[:10]), slices.Values(unsorted)))
+		if !slices.IsSortedFunc(got, ulid.ULID.Compare) {
+			t.Errorf("expected the merge output to be sorted")
+		}
+
+		var merr *ulid.MergeError
+		if err := m.Err(); !errors.Is(err, ulid.ErrUnsorted) || !errors.As(err, &merr) {
+			t.Fatalf("expected ErrUnsorted, got %v", err)
+		}
+
+		if merr.Source != 1 || merr.Prev != ids[30] || merr.ULID != ids[20] {
+			t.Errorf("unexpected merge error %+v", merr)
+		}
+
+		if got[len(got)-1] != ids[30] {
+			t.Errorf("expected the merge to stop at the unsorted ulid")
+		}
+
+		var reported []error
+		m = &ulid.Merger{Unique: true, OnError: func(err error) bool {
+			reported = append(reported, err)
+			return true
+		}}
+
+		got = slices.Collect(m.Merge(slices.Values(unsorted), slices.Values(unsorted)))
+		if want := []ulid.ULID{ids[10], ids[30], ids[40]}; !slices.Equal(got, want) {
+			t.Errorf("expected the unsorted ulids to be dropped, got %v", got)
+		}
+
+		if len(reported) != 2 || m.Err() != nil {
+			t.Errorf("expected both unsorted ulids to be reported, got %v and %v", reported, m.Err())
+		}
+
+		// The error is reset by the next merge.
+		m.OnError = nil
+		for range m.Merge(slices.Values(unsorted)) {
+		}
+		if m.Err() == nil {
+			t.Fatal("expected an error")
+		}
+
+		for range m.Merge(slices.Values(evens)) {
+		}
+		if m.Err() != nil {
+			t.Errorf("expected the error to be reset")
+		}
+	})
+}
+
+func TestCompareWithTiebreak(t *testing.T) {
+	t.Parallel()
+
+	a, b := ulid.MustNew(1, &constReader{1}), ulid.MustNew(1, &constReader{2})
+	for _, tc := range []struct {
+		a, b       ulid.ULID
+		tieA, tieB string
+		want       int
+	}{
+		{a, b, "z", "a", -1},
+		{b, a, "a", "z", 1},
+		{a, a, "node-1", "node-2", -1},
+		{a, a, "node-2", "node-1", 1},
+		{a, a, "node-1", "node-1", 0},
+		{a, a, "", "", 0},
+	} {
+		if got := ulid.CompareWithTiebreak(tc.a, tc.b, []byte(tc.tieA), []byte(tc.tieB)); got != tc.want {
+			t.Errorf("CompareWithTiebreak(%s, %s, %q, %q) = %d, want %d", tc.a, tc.b, tc.tieA, tc.tieB, got, tc.want)
+		}
+	}
+}
+
+func TestStableMergeKey(t *testing.T) {
+	t.Parallel()
+
+	early, late := ulid.MustNew(1, &constReader{0xFF}), ulid.MustNew(2, &constReader{0})
+	node1, node2 := []byte("node-1"), []byte("node-2")
+
+	// Keys are primarily ordered by the ULID regardless of the source.
+	for _, sources := range [][2][]byte{{node1, node2}, {node2, node1}} {
+		a, b := ulid.StableMergeKey(early, sources[0]), ulid.StableMergeKey(late, sources[1])
+		if bytes.Compare(a[:], b[:]) >= 0 {
+			t.Errorf("expected the key of the earlier ULID to sort first")
+		}
+	}
+
+	// The same ULID from different sources has distinct, consistently ordered keys.
+	a, b := ulid.StableMergeKey(early, node1), ulid.StableMergeKey(early, node2)
+	if a == b || bytes.Compare(a[:], b[:]) != bytes.Compare(a[16:], b[16:]) {
+		t.Errorf("expected the keys to be ordered by the hash of the source")
+	}
+
+	if ulid.MergeKeyULID(a) != early || ulid.MergeKeyULID(b) != early {
+		t.Errorf("expected the ULID to be recovered from the key")
+	}
+
+	if bytes.Contains(a[:], node1) {
+		t.Errorf("expected the source to be hashed")
+	}
+
+	keys := slices.Collect(ulid.StableMergeKeys(slices.Values([]ulid.ULID{early, late}), node1))
+	if len(keys) != 2 || keys[0] != a || keys[1] != ulid.StableMergeKey(late, node1) {
+		t.Errorf("expected StableMergeKeys to match StableMergeKey")
+	}
+}
+
+func TestMergeKeysReplay(t *testing.T) {
+	t.Parallel()
+
+	// Each node produces events in the same few milliseconds, and some ULIDs
+	// are produced by more than one node.
+	nodes := [][]byte{[]byte("node-a"), []byte("node-b"), []byte("node-c"), []byte("node-d")}
+	streams := make([][]ulid.ULID, len(nodes))
+	for i := range streams {
+		for j := 0; j < 200; j++ {
+			id := ulid.MustNew(uint64(j/50), &constReader{byte((j * 7) % 13)})
+			if j%3 == 0 {
+				id = ulid.MustNew(uint64(j/50), &constReader{byte(i*31 + j)})
+			}
+			streams[i] = append(streams[i], id)
+		}
+		slices.SortFunc(streams[i], ulid.ULID.Compare)
+	}
+
+	replay := func(order []int) (keys [][ulid.MergeKeySize]byte) {
+		seqs := make([]iter.Seq[[ulid.MergeKeySize]byte], 0, len(order))
+		for _, i := range order {
+			seqs = append(seqs, ulid.StableMergeKeys(slices.Values(streams[i]), nodes[i]))
+		}
+		return slices.Collect(ulid.MergeKeys(seqs...))
+	}
+
+	want := replay([]int{0, 1, 2, 3})
+	if len(want) != 4*200 {
+		t.Fatalf("expected %d merged keys, got %d", 4*200, len(want))
+	}
+
+	if !slices.IsSortedFunc(want, func(a, b [ulid.MergeKeySize]byte) int { return bytes.Compare(a[:], b[:]) }) {
+		t.Fatal("expected the merged keys to be sorted")
+	}
+
+	for _, order := range [][]int{{3, 2, 1, 0}, {1, 3, 0, 2}, {2, 0, 3, 1}} {
+		if got := replay(order); !slices.Equal(got, want) {
+			t.Errorf("merge in arrival order %v is not identical to the first replay", order)
+		}
+	}
+
+	// The merge stops at the first key that is out of order.
+	unsorted := []ulid.ULID{ulid.MustNew(2, &constReader{0}), ulid.MustNew(1, &constReader{0})}
+	if got := slices.Collect(ulid.MergeKeys(ulid.StableMergeKeys(slices.Values(unsorted), nodes[0]))); len(got) != 1 {
+		t.Errorf("expected the merge to stop at the unsorted key, got %d keys", len(got))
+	}
+
+	// A Merger reports the key that is out of order with the ULIDs of the keys.
+	merger := &ulid.Merger{}
+	unsorted = append(unsorted, ulid.MustNew(3, &constReader{0}))
+	if got := slices.Collect(merger.MergeKeys(ulid.StableMergeKeys(slices.Values(unsorted), nodes[0]))); len(got) != 1 {
+		t.Errorf("expected the merge to stop at the unsorted key, got %d keys", len(got))
+	}
+
+	var merr *ulid.MergeError
+	if err := merger.Err(); !errors.As(err, &merr) || merr.Source != 0 || merr.Prev != unsorted[0] || merr.ULID != unsorted[1] {
+		t.Errorf("got error %v, want a merge error for the unsorted key", err)
+	}
+
+	// If OnError drops the key the merge continues with the next key.
+	var reported int
+	merger.OnError = func(err error) bool { reported++; return errors.Is(err, ulid.ErrUnsorted) }
+	got := slices.Collect(merger.MergeKeys(ulid.StableMergeKeys(slices.Values(unsorted), nodes[0])))
+	if len(got) != 2 || ulid.MergeKeyULID(got[1]) != unsorted[2] || reported != 1 || merger.Err() != nil {
+		t.Errorf("expected the unsorted key to be dropped, got %d keys, %d errors, and %v", len(got), reported, merger.Err())
+	}
+
+	// Unique drops duplicate keys but not the same ULID from different sources.
+	merger = &ulid.Merger{Unique: true}
+	dups := []ulid.ULID{unsorted[0], unsorted[0], unsorted[2]}
+	got = slices.Collect(merger.MergeKeys(ulid.StableMergeKeys(slices.Values(dups), nodes[0]), ulid.StableMergeKeys(slices.Values(dups), nodes[1])))
+	if len(got) != 4 {
+		t.Errorf("expected 4 unique keys, got %d", len(got))
+	}
+}
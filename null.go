@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 )
 
 // NullULID can be used with database/sql to represent ULIDs that are nullable without
@@ -14,19 +17,35 @@ type NullULID struct {
 }
 
 func (nu *NullULID) Scan(value interface{}) error {
-	if value == nil {
+	_, err := nu.ScanDetailed(value)
+	return err
+}
+
+// ScanDetailed scans the value like Scan and also returns the kind of source
+// value that was decoded, e.g. to audit the values returned by a database
+// driver: KindNull for a SQL NULL, KindText for a string, KindBinary for a
+// []byte, and KindTimestamp for an int64 or uint64 in Unix milliseconds. If the
+// value cannot be scanned, KindUnknown is returned with the error.
+func (nu *NullULID) ScanDetailed(value any) (kind InputKind, err error) {
+	switch value.(type) {
+	case nil:
 		nu.ULID, nu.Valid = Null, false
-		return nil
+		return KindNull, nil
+	case string:
+		kind = KindText
+	case []byte:
+		kind = KindBinary
+	case int64, uint64:
+		kind = KindTimestamp
 	}
 
-	err := nu.ULID.Scan(value)
-	if err != nil {
+	if err = nu.ULID.Scan(value); err != nil {
 		nu.Valid = false
-		return err
+		return KindUnknown, err
 	}
 
 	nu.Valid = true
-	return nil
+	return kind, nil
 }
 
 func (nu NullULID) Value() (driver.Value, error) {
@@ -36,6 +55,24 @@ func (nu NullULID) Value() (driver.Value, error) {
 	return nu.ULID.Value()
 }
 
+// StringOr returns the string encoding of the ULID, or fallback if the NullULID
+// is not valid or its ULID is zero.
+func (nu NullULID) StringOr(fallback string) string {
+	if !nu.Valid {
+		return fallback
+	}
+	return nu.ULID.StringOr(fallback)
+}
+
+// StringPtr returns a pointer to the string encoding of the ULID, or nil if the
+// NullULID is not valid or its ULID is zero.
+func (nu NullULID) StringPtr() *string {
+	if !nu.Valid {
+		return nil
+	}
+	return nu.ULID.StringPtr()
+}
+
 func (nu NullULID) MarshalBinary() ([]byte, error) {
 	if nu.Valid {
 		return nu.ULID[:], nil
@@ -53,6 +90,44 @@ func (nu *NullULID) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// NullFixedSize is the length of the fixed-width binary encoding of a NullULID.
+const NullFixedSize = 1 + 16
+
+// MarshalFixed returns the NullFixedSize byte binary encoding of the NullULID for
+// fixed-width records and binary logs, where the nil returned by MarshalBinary
+// for an invalid NullULID cannot be distinguished from an absent field. The
+// first byte is the validity byte, 1 if the NullULID is valid and 0 if it is not,
+// followed by the 16 byte binary encoding of the ULID, which is zeroed if the
+// NullULID is not valid.
+func (nu NullULID) MarshalFixed() ([]byte, error) {
+	data := make([]byte, NullFixedSize)
+	if nu.Valid {
+		data[0] = 1
+		copy(data[1:], nu.ULID[:])
+	}
+	return data, nil
+}
+
+// UnmarshalFixed decodes the fixed-width binary encoding produced by MarshalFixed.
+// It returns ErrDataSize if data is not exactly NullFixedSize bytes or if the
+// validity byte is neither 0 nor 1. If the validity byte is 0 the ULID is zeroed.
+func (nu *NullULID) UnmarshalFixed(data []byte) error {
+	if len(data) != NullFixedSize {
+		return ErrDataSize
+	}
+
+	switch data[0] {
+	case 0:
+		*nu = NullULID{}
+	case 1:
+		copy(nu.ULID[:], data[1:])
+		nu.Valid = true
+	default:
+		return ErrDataSize
+	}
+	return nil
+}
+
 func (nu *NullULID) MarshalText() ([]byte, error) {
 	if nu.Valid {
 		return nu.ULID.MarshalText()
@@ -60,7 +135,15 @@ func (nu *NullULID) MarshalText() ([]byte, error) {
 	return jsonNull, nil
 }
 
+// UnmarshalText parses a ULID from its text encoding. The literal "null" (as
+// produced by MarshalText for an invalid NullULID) and empty text are treated
+// as a valid null ULID so that NullULID round-trips through its text encoding.
 func (nu *NullULID) UnmarshalText(data []byte) error {
+	if len(data) == 0 || bytes.Equal(data, jsonNull) {
+		*nu = NullULID{}
+		return nil
+	}
+
 	err := nu.ULID.UnmarshalText(data)
 	if err != nil {
 		nu.Valid = false
@@ -91,3 +174,268 @@ func (nu *NullULID) UnmarshalJSON(data []byte) error {
 	nu.Valid = err == nil
 	return err
 }
+
+// ZeroAsNull is a ULID that is marshaled as a JSON null when it is zero, and that
+// is unmarshaled from a JSON null as the zero ULID, e.g. for the optional IDs of API
+// response structs where NullULID would also require the Valid field to be set:
+//
+//	type Response struct {
+//		ParentID ulid.ZeroAsNull `json:"parent_id"`
+//	}
+//
+// Convert between the types with ZeroAsNull(id) and ULID(z).
+type ZeroAsNull ULID
+
+// IsZero returns true if the ULID is zero, which also allows the field to be
+// omitted with the omitzero tag option of encoding/json in Go 1.24 and later.
+func (z ZeroAsNull) IsZero() bool {
+	return ULID(z).IsZero()
+}
+
+// String returns the string encoding of the ULID.
+func (z ZeroAsNull) String() string {
+	return ULID(z).String()
+}
+
+// MarshalJSON implements the json.Marshaler interface, returning null for the
+// zero ULID and the string encoding of the ULID otherwise.
+func (z ZeroAsNull) MarshalJSON() ([]byte, error) {
+	if z.IsZero() {
+		return jsonNull, nil
+	}
+	return json.Marshal(ULID(z))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, decoding null as the
+// zero ULID and strings like ULID.UnmarshalText.
+func (z *ZeroAsNull) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*z = ZeroAsNull{}
+		return nil
+	}
+	return json.Unmarshal(data, (*ULID)(z))
+}
+
+// LenientULID is a nullable ULID that is also unmarshaled from a JSON integer of
+// Unix milliseconds, e.g. to migrate legacy payloads that stored a creation
+// timestamp where newer payloads store a ULID. The ULID of a timestamp has zero
+// entropy like FromUnixMilli, so it is not unique and is reported as synthetic.
+// Use it only for the fields of such payloads; ULID and NullULID continue to
+// reject numbers.
+type LenientULID struct {
+	ULID  ULID
+	Valid bool
+
+	// Source is the form of the JSON value that was decoded: KindText for a
+	// string, KindTimestamp for an integer, and KindNull for null.
+	Source InputKind
+}
+
+// Synthetic returns true if the ULID was synthesized from a timestamp rather
+// than decoded from its string encoding.
+func (lu LenientULID) Synthetic() bool {
+	return lu.Valid && lu.Source == KindTimestamp
+}
+
+// NullULID returns the ULID as a NullULID, discarding its source.
+func (lu LenientULID) NullULID() NullULID {
+	return NullULID{ULID: lu.ULID, Valid: lu.Valid}
+}
+
+// MarshalJSON implements the json.Marshaler interface, returning null if the
+// LenientULID is not valid and the string encoding of the ULID otherwise, so
+// that synthetic ULIDs are migrated to strings when the payload is written.
+func (lu LenientULID) MarshalJSON() ([]byte, error) {
+	if !lu.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(lu.ULID)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, decoding null as an
+// invalid LenientULID, strings like ULID.UnmarshalText, and integers as Unix
+// milliseconds. ErrSmallTime is returned for negative integers, ErrBigTime for
+// integers after MaxTime, and ErrUnknownType for any other JSON value, including
+// numbers with a fraction or an exponent. The LenientULID is not modified if an
+// error is returned.
+func (lu *LenientULID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*lu = LenientULID{Source: KindNull}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var id ULID
+		if err := json.Unmarshal(data, &id); err != nil {
+			return err
+		}
+
+		*lu = LenientULID{ULID: id, Valid: true, Source: KindText}
+		return nil
+	}
+
+	ms, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		switch {
+		case !errors.Is(err, strconv.ErrRange):
+			return fmt.Errorf("%w: expected a ULID string or an integer timestamp, got %s", ErrUnknownType, data)
+		case data[0] == '-':
+			return ErrSmallTime
+		default:
+			return ErrBigTime
+		}
+	}
+
+	id, err := FromUnixMilli(ms)
+	if err != nil {
+		return err
+	}
+
+	*lu = LenientULID{ULID: id, Valid: true, Source: KindTimestamp}
+	return nil
+}
+
+// OptionalULID is a ULID field of a JSON merge patch (RFC 7396) with three states
+// that NullULID cannot distinguish: unset if the field is absent from the patch,
+// which leaves the target unchanged, null if the field is explicitly null, which
+// removes the value, and a value if the field is a ULID string, which sets it:
+//
+//	type ResourcePatch struct {
+//		ParentID ulid.OptionalULID `json:"parent_id,omitzero"`
+//	}
+//
+// The zero value is unset and is omitted by the omitzero tag option of
+// encoding/json in Go 1.24 and later; without the option an unset OptionalULID is
+// marshaled as null. Since encoding/json leaves absent fields unchanged, decode
+// each patch into a new value so that the fields of a previous patch are not
+// mistaken for fields of the current one.
+type OptionalULID struct {
+	id    ULID
+	state optionalState
+}
+
+type optionalState uint8
+
+const (
+	optionalUnset optionalState = iota
+	optionalNull
+	optionalValue
+)
+
+// OptionalNull returns an OptionalULID that is explicitly null.
+func OptionalNull() OptionalULID {
+	return OptionalULID{state: optionalNull}
+}
+
+// OptionalValue returns an OptionalULID that is set to the ULID.
+func OptionalValue(id ULID) OptionalULID {
+	return OptionalULID{id: id, state: optionalValue}
+}
+
+// IsSet returns true if the OptionalULID is null or a value, i.e. if the field was
+// present in the decoded JSON.
+func (o OptionalULID) IsSet() bool {
+	return o.state != optionalUnset
+}
+
+// IsNull returns true if the OptionalULID is explicitly null.
+func (o OptionalULID) IsNull() bool {
+	return o.state == optionalNull
+}
+
+// IsZero returns true if the OptionalULID is unset, which allows the field to be
+// omitted with the omitzero tag option of encoding/json. A null OptionalULID or a
+// value of the zero ULID is not zero.
+func (o OptionalULID) IsZero() bool {
+	return o.state == optionalUnset
+}
+
+// Get returns the ULID and true if the OptionalULID is a value, or the zero ULID
+// and false if it is unset or null.
+func (o OptionalULID) Get() (ULID, bool) {
+	return o.id, o.state == optionalValue
+}
+
+// Apply returns the NullULID after the merge patch of the OptionalULID: nu
+// unchanged if the OptionalULID is unset, an invalid NullULID if it is null, and
+// the ULID if it is a value.
+func (o OptionalULID) Apply(nu NullULID) NullULID {
+	if o.state == optionalUnset {
+		return nu
+	}
+	return o.NullULID()
+}
+
+// NullULID returns the OptionalULID as a NullULID, which is invalid if the
+// OptionalULID is unset or null; the distinction between unset and null is lost.
+func (o OptionalULID) NullULID() NullULID {
+	return NullULID{ULID: o.id, Valid: o.state == optionalValue}
+}
+
+// Optional returns the NullULID as an OptionalULID, which is null if the NullULID
+// is not valid; a NullULID is never converted to an unset OptionalULID.
+func (nu NullULID) Optional() OptionalULID {
+	if !nu.Valid {
+		return OptionalNull()
+	}
+	return OptionalValue(nu.ULID)
+}
+
+// String returns the string encoding of the ULID if the OptionalULID is a value
+// and "null" or "unset" otherwise, e.g. for logging the fields of a patch.
+func (o OptionalULID) String() string {
+	switch o.state {
+	case optionalValue:
+		return o.id.String()
+	case optionalNull:
+		return "null"
+	}
+	return "unset"
+}
+
+// MarshalJSON implements the json.Marshaler interface, returning the string
+// encoding of the ULID if the OptionalULID is a value and null otherwise.
+func (o OptionalULID) MarshalJSON() ([]byte, error) {
+	if o.state != optionalValue {
+		return jsonNull, nil
+	}
+	return json.Marshal(o.id)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, which encoding/json
+// only calls for fields that are present: null is decoded as an explicit null and
+// strings like ULID.UnmarshalText as a value. The OptionalULID is not modified if
+// an error is returned.
+func (o *OptionalULID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*o = OptionalNull()
+		return nil
+	}
+
+	var id ULID
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+
+	*o = OptionalValue(id)
+	return nil
+}
+
+// Scan implements the sql.Scanner interface like NullULID, scanning a SQL NULL as
+// an explicit null; a scanned OptionalULID is never unset.
+func (o *OptionalULID) Scan(value any) error {
+	var nu NullULID
+	if err := nu.Scan(value); err != nil {
+		return err
+	}
+
+	*o = nu.Optional()
+	return nil
+}
+
+// Value implements the driver.Valuer interface like NullULID, writing an unset
+// OptionalULID as NULL like a null one; skip the unset fields of a patch rather
+// than writing them.
+func (o OptionalULID) Value() (driver.Value, error) {
+	return o.NullULID().Value()
+}
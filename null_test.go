@@ -3,6 +3,7 @@ package ulid_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -33,6 +34,43 @@ func TestNullULIDScan(t *testing.T) {
 	}
 }
 
+func TestNullULIDScanDetailed(t *testing.T) {
+	valid := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	testCases := []struct {
+		value any
+		want  NullULID
+		kind  InputKind
+		err   error
+	}{
+		{nil, NullULID{}, KindNull, nil},
+		{valid.String(), NullULID{Valid: true, ULID: valid}, KindText, nil},
+		{valid.Bytes(), NullULID{Valid: true, ULID: valid}, KindBinary, nil},
+		{"test", NullULID{}, KindUnknown, ErrDataSize},
+		{[]byte("test"), NullULID{}, KindUnknown, ErrDataSize},
+		{42, NullULID{}, KindUnknown, ErrScanValue},
+		{int64(123), NullULID{Valid: true, ULID: MustNew(123, nil)}, KindTimestamp, nil},
+		{uint64(123), NullULID{Valid: true, ULID: MustNew(123, nil)}, KindTimestamp, nil},
+		{int64(-1), NullULID{}, KindUnknown, ErrSmallTime},
+	}
+
+	for i, tc := range testCases {
+		var nu NullULID
+		kind, err := nu.ScanDetailed(tc.value)
+		if kind != tc.kind || err != tc.err {
+			t.Errorf("test case %d: got %s %v, want %s %v", i, kind, err, tc.kind, tc.err)
+		}
+
+		if nu.Valid != tc.want.Valid || (nu.Valid && nu.ULID != tc.want.ULID) {
+			t.Errorf("test case %d: got %+v, want %+v", i, nu, tc.want)
+		}
+
+		var scanned NullULID
+		if err := scanned.Scan(tc.value); err != tc.err || scanned != nu {
+			t.Errorf("test case %d: expected Scan to agree with ScanDetailed", i)
+		}
+	}
+}
+
 func TestNullULIDValue(t *testing.T) {
 	var u ULID
 	var nu NullULID
@@ -100,6 +138,52 @@ func TestNullULIDMarshalText(t *testing.T) {
 	}
 }
 
+func TestNullULIDUnmarshalText(t *testing.T) {
+	tests := []NullULID{
+		{},
+		{ULID: MustParse("01HTNMW2JAW89YSBG7NFPHABA4"), Valid: true},
+		{ULID: Zero, Valid: true},
+	}
+
+	// Every output of MarshalText must be accepted and reproduce the original.
+	for _, test := range tests {
+		text, err := test.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nu := NullULID{ULID: Make(), Valid: true}
+		if err := nu.UnmarshalText(text); err != nil {
+			t.Fatalf("could not unmarshal %q: %s", text, err)
+		}
+
+		if nu != test {
+			t.Fatalf("expected %q to round trip to %+v, got %+v", text, test, nu)
+		}
+	}
+
+	// Empty text is also a valid null ULID.
+	nu := NullULID{ULID: Make(), Valid: true}
+	if err := nu.UnmarshalText([]byte{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if nu != (NullULID{}) {
+		t.Fatalf("expected empty text to unmarshal to a null ULID, got %+v", nu)
+	}
+
+	// Anything else must be a valid ULID.
+	for _, text := range []string{"NULL", "nil", "foo", "01HTNMW2JAW89YSBG7NFPHABA"} {
+		if err := nu.UnmarshalText([]byte(text)); err == nil {
+			t.Fatalf("expected error unmarshaling %q", text)
+		}
+
+		if nu.Valid {
+			t.Fatalf("expected invalid NullULID after unmarshaling %q", text)
+		}
+	}
+}
+
 func TestNullULIDMarshalBinary(t *testing.T) {
 	tests := []struct {
 		nullULID NullULID
@@ -134,6 +218,76 @@ func TestNullULIDMarshalBinary(t *testing.T) {
 	}
 }
 
+func TestNullULIDFixed(t *testing.T) {
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	tests := []NullULID{
+		{},
+		{ULID: id, Valid: true},
+		{ULID: Zero, Valid: true},
+	}
+
+	for _, test := range tests {
+		data, err := test.MarshalFixed()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(data) != NullFixedSize {
+			t.Fatalf("expected %d bytes, got %d", NullFixedSize, len(data))
+		}
+
+		var got NullULID
+		if err := got.UnmarshalFixed(data); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if got != test {
+			t.Fatalf("expected %+v, got %+v", test, got)
+		}
+
+		// A valid NullULID is followed by the binary encoding of the ULID.
+		if test.Valid {
+			binary, _ := test.ULID.MarshalBinary()
+			if data[0] != 1 || !bytes.Equal(data[1:], binary) {
+				t.Fatalf("expected validity byte 1 and %x, got %x", binary, data)
+			}
+		} else if !bytes.Equal(data, make([]byte, NullFixedSize)) {
+			t.Fatalf("expected an invalid NullULID to be zeroed, got %x", data)
+		}
+	}
+
+	// An invalid NullULID is zeroed even if the encoding has ULID bytes.
+	data := append([]byte{0}, id[:]...)
+	nu := NullULID{ULID: id, Valid: true}
+	if err := nu.UnmarshalFixed(data); err != nil || nu != (NullULID{}) {
+		t.Fatalf("expected a zero NullULID, got %+v (%v)", nu, err)
+	}
+}
+
+func TestNullULIDFixedErrors(t *testing.T) {
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	tests := [][]byte{
+		nil,
+		{},
+		id[:],
+		append([]byte{1}, id[:15]...),
+		append([]byte{1, 0}, id[:]...),
+		append([]byte{2}, id[:]...),
+		append([]byte{0xFF}, id[:]...),
+	}
+
+	for _, data := range tests {
+		nu := NullULID{ULID: id, Valid: true}
+		if err := nu.UnmarshalFixed(data); err != ErrDataSize {
+			t.Fatalf("expected ErrDataSize for %x, got %v", data, err)
+		}
+
+		if !nu.Valid || nu.ULID != id {
+			t.Fatalf("expected the NullULID to be unmodified on error, got %+v", nu)
+		}
+	}
+}
+
 func TestNullULIDMarshalJSON(t *testing.T) {
 	jsonNull, _ := json.Marshal(nil)
 	tests := []struct {
@@ -189,3 +343,308 @@ func TestNullULIDUnmarshalJSON(t *testing.T) {
 		t.Fatal("expected valid NullULID")
 	}
 }
+
+func TestNullULIDStringOr(t *testing.T) {
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	tests := []struct {
+		nullULID NullULID
+		expected string
+	}{
+		{NullULID{}, "none"},
+		{NullULID{Valid: true}, "none"},
+		{NullULID{ULID: id}, "none"},
+		{NullULID{ULID: id, Valid: true}, "01HTNMW2JAW89YSBG7NFPHABA4"},
+	}
+
+	for _, test := range tests {
+		if s := test.nullULID.StringOr("none"); s != test.expected {
+			t.Errorf("%+v: expected %q, got %q", test.nullULID, test.expected, s)
+		}
+
+		ptr := test.nullULID.StringPtr()
+		if test.expected == "none" && ptr != nil {
+			t.Errorf("%+v: expected a nil pointer, got %q", test.nullULID, *ptr)
+		} else if test.expected != "none" && (ptr == nil || *ptr != test.expected) {
+			t.Errorf("%+v: expected a pointer to %q, got %v", test.nullULID, test.expected, ptr)
+		}
+	}
+}
+
+func TestZeroAsNull(t *testing.T) {
+	type response struct {
+		ID       ZeroAsNull `json:"id"`
+		ParentID ZeroAsNull `json:"parent_id"`
+	}
+
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	tests := []struct {
+		value    response
+		expected string
+	}{
+		{response{}, `{"id":null,"parent_id":null}`},
+		{response{ID: ZeroAsNull(id)}, `{"id":"01HTNMW2JAW89YSBG7NFPHABA4","parent_id":null}`},
+		{response{ID: ZeroAsNull(id), ParentID: ZeroAsNull(id)}, `{"id":"01HTNMW2JAW89YSBG7NFPHABA4","parent_id":"01HTNMW2JAW89YSBG7NFPHABA4"}`},
+	}
+
+	for _, test := range tests {
+		data, err := json.Marshal(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, data)
+		}
+
+		// Start from non-zero values so that null is shown to reset them.
+		decoded := response{ID: ZeroAsNull(Make()), ParentID: ZeroAsNull(Make())}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.ID != test.value.ID || decoded.ParentID != test.value.ParentID {
+			t.Errorf("expected %+v after a round trip, got %+v", test.value, decoded)
+		}
+	}
+
+	// Pointers, slices, and the empty string are handled like ULID.
+	var ids []ZeroAsNull
+	if err := json.Unmarshal([]byte(`["01HTNMW2JAW89YSBG7NFPHABA4",null]`), &ids); err != nil || len(ids) != 2 || ULID(ids[0]) != id || !ids[1].IsZero() {
+		t.Errorf("unexpected slice %v (%v)", ids, err)
+	}
+
+	var z ZeroAsNull
+	if err := json.Unmarshal([]byte(`"01HTNMW2JA"`), &z); err != ErrDataSize {
+		t.Errorf("expected %v, got %v", ErrDataSize, err)
+	}
+
+	if data, err := json.Marshal(&z); err != nil || string(data) != "null" {
+		t.Errorf("expected null, got %s (%v)", data, err)
+	}
+
+	if z.String() != Zero.String() {
+		t.Errorf("expected the string encoding of the ULID, got %s", z)
+	}
+}
+
+func TestLenientULID(t *testing.T) {
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	synthetic, _ := FromUnixMilli(1712116969546)
+
+	tests := []struct {
+		data     string
+		expected LenientULID
+		err      error
+	}{
+		{`"01HTNMW2JAW89YSBG7NFPHABA4"`, LenientULID{ULID: id, Valid: true, Source: KindText}, nil},
+		{`"01htnmw2jaw89ysbg7nfphaba4"`, LenientULID{ULID: id, Valid: true, Source: KindText}, nil},
+		{`null`, LenientULID{Source: KindNull}, nil},
+		{`1712116969546`, LenientULID{ULID: synthetic, Valid: true, Source: KindTimestamp}, nil},
+		{`0`, LenientULID{Valid: true, Source: KindTimestamp}, nil},
+		{`281474976710655`, LenientULID{ULID: MustParse("7ZZZZZZZZZ0000000000000000"), Valid: true, Source: KindTimestamp}, nil},
+		{`281474976710656`, LenientULID{}, ErrBigTime},
+		{`18446744073709551616`, LenientULID{}, ErrBigTime},
+		{`-1`, LenientULID{}, ErrSmallTime},
+		{`-18446744073709551616`, LenientULID{}, ErrSmallTime},
+		{`1712116969546.5`, LenientULID{}, ErrUnknownType},
+		{`1.712116969546e12`, LenientULID{}, ErrUnknownType},
+		{`true`, LenientULID{}, ErrUnknownType},
+		{`{}`, LenientULID{}, ErrUnknownType},
+		{`"01HTNMW2JA"`, LenientULID{}, ErrDataSize},
+	}
+
+	for _, test := range tests {
+		// Start from a non-zero value to show that errors do not modify it.
+		lu := LenientULID{ULID: id, Valid: true, Source: KindText}
+		err := lu.UnmarshalJSON([]byte(test.data))
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.data, test.err, err)
+			continue
+		}
+
+		if err != nil {
+			if lu != (LenientULID{ULID: id, Valid: true, Source: KindText}) {
+				t.Errorf("%s: expected the value not to be modified, got %+v", test.data, lu)
+			}
+			continue
+		}
+
+		if lu != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.data, test.expected, lu)
+		}
+
+		if lu.Synthetic() != (test.expected.Source == KindTimestamp) {
+			t.Errorf("%s: unexpected synthetic %t", test.data, lu.Synthetic())
+		}
+
+		if nu := lu.NullULID(); nu.ULID != lu.ULID || nu.Valid != lu.Valid {
+			t.Errorf("%s: unexpected NullULID %+v", test.data, nu)
+		}
+	}
+
+	// Timestamps are written as the string encoding of their synthetic ULIDs.
+	var payload struct {
+		IDs []LenientULID `json:"ids"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"ids":["01HTNMW2JAW89YSBG7NFPHABA4",1712116969546,null]}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"ids":["01HTNMW2JAW89YSBG7NFPHABA4","` + synthetic.String() + `",null]}`; string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// Plain ULIDs and NullULIDs continue to reject timestamps.
+	var u ULID
+	if err := json.Unmarshal([]byte(`1712116969546`), &u); err == nil {
+		t.Error("expected ULID to reject a timestamp")
+	}
+
+	var nu NullULID
+	if err := json.Unmarshal([]byte(`1712116969546`), &nu); err == nil || nu.Valid {
+		t.Error("expected NullULID to reject a timestamp")
+	}
+}
+
+func TestOptionalULID(t *testing.T) {
+	type patch struct {
+		ParentID OptionalULID `json:"parent_id,omitzero"`
+		OwnerID  OptionalULID `json:"owner_id,omitzero"`
+	}
+
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	tests := []struct {
+		doc      string
+		expected patch
+	}{
+		{`{}`, patch{}},
+		{`{"parent_id":null}`, patch{ParentID: OptionalNull()}},
+		{`{"parent_id":"01HTNMW2JAW89YSBG7NFPHABA4"}`, patch{ParentID: OptionalValue(id)}},
+		{`{"parent_id":"00000000000000000000000000","owner_id":null}`, patch{ParentID: OptionalValue(Zero), OwnerID: OptionalNull()}},
+		{`{"parent_id":null,"owner_id":"01HTNMW2JAW89YSBG7NFPHABA4"}`, patch{ParentID: OptionalNull(), OwnerID: OptionalValue(id)}},
+	}
+
+	for _, test := range tests {
+		var decoded patch
+		if err := json.Unmarshal([]byte(test.doc), &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.doc, test.expected, decoded)
+		}
+
+		// Absent, null, and set fields are encoded as they were decoded.
+		data, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != test.doc {
+			t.Errorf("expected %s after a round trip, got %s", test.doc, data)
+		}
+	}
+
+	// Applying a patch leaves absent fields unchanged.
+	current := NullULID{ULID: Make(), Valid: true}
+	testsApply := []struct {
+		patch    OptionalULID
+		expected NullULID
+	}{
+		{OptionalULID{}, current},
+		{OptionalNull(), NullULID{}},
+		{OptionalValue(id), NullULID{ULID: id, Valid: true}},
+	}
+
+	for _, test := range testsApply {
+		if got := test.patch.Apply(current); got != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.patch, test.expected, got)
+		}
+	}
+
+	// Without omitzero an unset field is encoded as null.
+	data, err := json.Marshal(struct{ ID OptionalULID }{})
+	if err != nil || string(data) != `{"ID":null}` {
+		t.Errorf("unexpected %s (%v)", data, err)
+	}
+
+	// Errors leave the value unmodified.
+	o := OptionalValue(id)
+	for _, doc := range []string{`"01HTNMW2JA"`, `42`, `{}`} {
+		if err := json.Unmarshal([]byte(doc), &o); err == nil {
+			t.Errorf("%s: expected an error", doc)
+		}
+
+		if o != OptionalValue(id) {
+			t.Errorf("%s: expected the value to be unmodified, got %s", doc, o)
+		}
+	}
+}
+
+func TestOptionalULIDAccessors(t *testing.T) {
+	id := MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	tests := []struct {
+		value  OptionalULID
+		set    bool
+		null   bool
+		get    ULID
+		ok     bool
+		nu     NullULID
+		str    string
+		sqlVal any
+	}{
+		{OptionalULID{}, false, false, Zero, false, NullULID{}, "unset", nil},
+		{OptionalNull(), true, true, Zero, false, NullULID{}, "null", nil},
+		{OptionalValue(id), true, false, id, true, NullULID{ULID: id, Valid: true}, id.String(), id.Bytes()},
+		{OptionalValue(Zero), true, false, Zero, true, NullULID{Valid: true}, Zero.String(), Zero.Bytes()},
+	}
+
+	for _, test := range tests {
+		if test.value.IsSet() != test.set || test.value.IsNull() != test.null || test.value.IsZero() == test.set {
+			t.Errorf("%s: unexpected state", test.value)
+		}
+
+		if got, ok := test.value.Get(); got != test.get || ok != test.ok {
+			t.Errorf("%s: expected %s, %t from Get, got %s, %t", test.value, test.get, test.ok, got, ok)
+		}
+
+		if got := test.value.NullULID(); got != test.nu {
+			t.Errorf("%s: expected %+v, got %+v", test.value, test.nu, got)
+		}
+
+		if got := test.value.String(); got != test.str {
+			t.Errorf("expected %q, got %q", test.str, got)
+		}
+
+		if got, err := test.value.Value(); err != nil || !reflect.DeepEqual(got, test.sqlVal) {
+			t.Errorf("%s: expected %v, got %v (%v)", test.value, test.sqlVal, got, err)
+		}
+
+		// Converting from a NullULID loses the distinction between unset and null.
+		expected := test.value
+		if !expected.IsSet() {
+			expected = OptionalNull()
+		}
+
+		if got := test.value.NullULID().Optional(); got != expected {
+			t.Errorf("%s: expected %s after converting to a NullULID and back, got %s", test.value, expected, got)
+		}
+
+		// Scanning the SQL value never returns an unset OptionalULID.
+		var scanned OptionalULID
+		if err := scanned.Scan(test.sqlVal); err != nil || scanned != expected {
+			t.Errorf("%s: expected %s after a round trip through SQL, got %s (%v)", test.value, expected, scanned, err)
+		}
+	}
+
+	o := OptionalValue(id)
+	if err := o.Scan(3.14); err != ErrScanValue || o != OptionalValue(id) {
+		t.Errorf("expected %v and an unmodified value, got %v and %s", ErrScanValue, err, o)
+	}
+}
@@ -28,3 +28,15 @@ func (u *uint80) Add(n uint64) (overflow bool) {
 func (u uint80) IsZero() bool {
 	return u.Hi == 0 && u.Lo == 0
 }
+
+func (u uint80) Sub(v uint80) (d uint80, borrow bool) {
+	d.Lo = u.Lo - v.Lo
+	d.Hi = u.Hi - v.Hi
+	if u.Lo < v.Lo {
+		d.Hi--
+		borrow = u.Hi <= v.Hi
+	} else {
+		borrow = u.Hi < v.Hi
+	}
+	return d, borrow
+}
@@ -0,0 +1,160 @@
+package ulid
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Option configures a ULID created by NewULID. Options are small values rather
+// than functions so that passing them does not allocate.
+type Option struct {
+	kind    optionKind
+	name    string
+	ms      uint64
+	err     error
+	entropy io.Reader
+	node    uint16
+	bits    uint8
+}
+
+// optionKind groups the options that conflict with each other: only one option
+// of each kind may be passed to NewULID.
+type optionKind uint8
+
+const (
+	optionTime optionKind = iota + 1
+	optionEntropy
+	optionNode
+	numOptionKinds
+)
+
+// MaxNodeBits is the maximum number of bits of the entropy used by WithNode.
+const MaxNodeBits = 16
+
+// NewULID returns a ULID configured by the options. With no options it is
+// equivalent to Make, using the current time and DefaultEntropy. New
+// capabilities of the constructors are added as options rather than as new
+// constructors.
+//
+// Only one time option (WithTime or WithTimestamp) and one entropy option
+// (WithEntropy, WithSecure, or WithZeroEntropy) may be given, and WithNode may be
+// given at most once; otherwise an error wrapping ErrConflictingOptions naming the
+// first conflicting pair of options is returned. Errors from invalid option
+// values and from reading the entropy are also returned.
+func NewULID(opts ...Option) (id ULID, err error) {
+	var set [numOptionKinds]*Option
+	for i := range opts {
+		opt := &opts[i]
+		if opt.kind == 0 {
+			continue
+		}
+
+		if opt.err != nil {
+			return id, opt.err
+		}
+
+		if prev := set[opt.kind]; prev != nil {
+			return id, fmt.Errorf("%w: %s and %s", ErrConflictingOptions, prev.name, opt.name)
+		}
+		set[opt.kind] = opt
+	}
+
+	var ms uint64
+	if opt := set[optionTime]; opt != nil {
+		ms = opt.ms
+	} else {
+		ms = Now()
+	}
+
+	var entropy io.Reader
+	if opt := set[optionEntropy]; opt != nil {
+		entropy = opt.entropy
+	} else {
+		entropy = DefaultEntropy()
+	}
+
+	if id, err = New(ms, entropy); err != nil {
+		return id, err
+	}
+
+	if opt := set[optionNode]; opt != nil {
+		id.setNode(opt.node, opt.bits)
+	}
+	return id, nil
+}
+
+// WithTime sets the timestamp of the ULID to the time, which must be between the
+// Unix epoch and MaxTimestampTime.
+func WithTime(t time.Time) Option {
+	ms, err := TimestampChecked(t)
+	return Option{kind: optionTime, name: "WithTime", ms: ms, err: err}
+}
+
+// WithTimestamp sets the timestamp of the ULID to the Unix milliseconds, which
+// must not be greater than MaxTime.
+func WithTimestamp(ms uint64) Option {
+	opt := Option{kind: optionTime, name: "WithTimestamp", ms: ms}
+	if ms > maxTime {
+		opt.err = ErrBigTime
+	}
+	return opt
+}
+
+// WithEntropy reads the entropy of the ULID from the reader rather than from
+// DefaultEntropy. If the reader is a MonotonicReader its MonotonicRead method is
+// used, as with New.
+func WithEntropy(entropy io.Reader) Option {
+	opt := Option{kind: optionEntropy, name: "WithEntropy", entropy: entropy}
+	if entropy == nil {
+		opt.err = ErrNilEntropy
+	}
+	return opt
+}
+
+// WithSecure reads the entropy of the ULID from SecureEntropy, like MakeSecure.
+func WithSecure() Option {
+	return Option{kind: optionEntropy, name: "WithSecure", entropy: SecureEntropy()}
+}
+
+// WithZeroEntropy creates the ULID with zero entropy, which sorts before every
+// other ULID with the same timestamp.
+func WithZeroEntropy() Option {
+	return Option{kind: optionEntropy, name: "WithZeroEntropy"}
+}
+
+// WithNode sets the most significant bits of the entropy to the node id so that
+// ULIDs created by different nodes in the same millisecond never collide. The
+// number of bits must be between 1 and MaxNodeBits and the id must fit in them.
+// The node bits replace the entropy that was read, so monotonic entropy is only
+// guaranteed to increase within a millisecond until it carries into the node bits.
+func WithNode(id uint16, bits int) Option {
+	opt := Option{kind: optionNode, name: "WithNode", node: id, bits: uint8(bits)}
+	if bits < 1 || bits > MaxNodeBits || (bits < MaxNodeBits && id >= 1<<bits) {
+		opt.err = fmt.Errorf("%w: node %d in %d bits", ErrInvalidNode, id, bits)
+	}
+	return opt
+}
+
+// setNode replaces the most significant bits of the entropy with the node id.
+func (id *ULID) setNode(node uint16, bits uint8) {
+	// Align the node to the top of a 24 bit window over the first three bytes of
+	// the entropy, which holds up to MaxNodeBits.
+	shift := 24 - bits
+	window := uint32(id[6])<<16 | uint32(id[7])<<8 | uint32(id[8])
+	mask := uint32(1<<bits-1) << shift
+	window = window&^mask | uint32(node)<<shift
+
+	id[6], id[7], id[8] = byte(window>>16), byte(window>>8), byte(window)
+}
+
+// Node returns the node id in the most significant bits of the entropy, as set by
+// WithNode with the same number of bits. It returns 0 if bits is out of range.
+func (id ULID) Node(bits int) uint16 {
+	if bits < 1 || bits > MaxNodeBits {
+		return 0
+	}
+
+	window := uint32(id[6])<<16 | uint32(id[7])<<8 | uint32(id[8])
+	return uint16(window >> (24 - bits))
+}
@@ -0,0 +1,265 @@
+package ulid_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestNewULID(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := ulid.Timestamp(ts)
+	entropy := bytes.Repeat([]byte{0xA5}, 10)
+
+	t.Run("NoOptions", func(t *testing.T) {
+		before := ulid.Now()
+		id, err := ulid.NewULID()
+		after := ulid.Now()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id.Time() < before || id.Time() > after || bytes.Equal(id.Entropy(), make([]byte, 10)) {
+			t.Errorf("expected a ULID like Make, got %s", id)
+		}
+	})
+
+	t.Run("WithTime", func(t *testing.T) {
+		id, err := ulid.NewULID(ulid.WithTime(ts))
+		if err != nil || id.Time() != ms {
+			t.Errorf("got %s (%v), want timestamp %d", id, err, ms)
+		}
+
+		if _, err := ulid.NewULID(ulid.WithTime(time.Unix(-1, 0))); err != ulid.ErrSmallTime {
+			t.Errorf("expected ErrSmallTime, got %v", err)
+		}
+
+		if _, err := ulid.NewULID(ulid.WithTime(ulid.MaxTimestampTime().Add(time.Millisecond))); err != ulid.ErrBigTime {
+			t.Errorf("expected ErrBigTime, got %v", err)
+		}
+	})
+
+	t.Run("WithTimestamp", func(t *testing.T) {
+		for _, ms := range []uint64{0, ms, ulid.MaxTime()} {
+			id, err := ulid.NewULID(ulid.WithTimestamp(ms))
+			if err != nil || id.Time() != ms {
+				t.Errorf("got %s (%v), want timestamp %d", id, err, ms)
+			}
+		}
+
+		if _, err := ulid.NewULID(ulid.WithTimestamp(ulid.MaxTime() + 1)); err != ulid.ErrBigTime {
+			t.Errorf("expected ErrBigTime, got %v", err)
+		}
+	})
+
+	t.Run("WithEntropy", func(t *testing.T) {
+		id, err := ulid.NewULID(ulid.WithTimestamp(ms), ulid.WithEntropy(bytes.NewReader(entropy)))
+		if err != nil || !bytes.Equal(id.Entropy(), entropy) {
+			t.Errorf("got %s (%v), want entropy %x", id, err, entropy)
+		}
+
+		if want := ulid.MustNew(ms, bytes.NewReader(entropy)); id != want {
+			t.Errorf("expected NewULID to agree with New, got %s, want %s", id, want)
+		}
+
+		if _, err := ulid.NewULID(ulid.WithEntropy(nil)); err != ulid.ErrNilEntropy {
+			t.Errorf("expected ErrNilEntropy, got %v", err)
+		}
+
+		if _, err := ulid.NewULID(ulid.WithEntropy(bytes.NewReader(nil))); err != io.EOF {
+			t.Errorf("expected the entropy error, got %v", err)
+		}
+
+		// Monotonic readers are read with MonotonicRead.
+		mono := ulid.Monotonic(rand.New(rand.NewSource(42)), 1)
+		first, _ := ulid.NewULID(ulid.WithTimestamp(ms), ulid.WithEntropy(mono))
+		second, _ := ulid.NewULID(ulid.WithTimestamp(ms), ulid.WithEntropy(mono))
+		if gap, ok := ulid.Gap(first, second); !ok || gap != 1 {
+			t.Errorf("expected monotonic entropy, got %s and %s", first, second)
+		}
+	})
+
+	t.Run("WithSecure", func(t *testing.T) {
+		id, err := ulid.NewULID(ulid.WithTimestamp(ms), ulid.WithSecure())
+		if err != nil || id.Time() != ms || bytes.Equal(id.Entropy(), make([]byte, 10)) {
+			t.Errorf("got %s (%v), want secure entropy", id, err)
+		}
+	})
+
+	t.Run("WithZeroEntropy", func(t *testing.T) {
+		id, err := ulid.NewULID(ulid.WithTimestamp(ms), ulid.WithZeroEntropy())
+		if err != nil || id != ulid.MustNew(ms, nil) {
+			t.Errorf("got %s (%v), want zero entropy", id, err)
+		}
+	})
+
+	t.Run("WithNode", func(t *testing.T) {
+		for _, tc := range []struct {
+			node uint16
+			bits int
+		}{
+			{0, 1}, {1, 1}, {5, 4}, {0xAB, 8}, {0x3FF, 10}, {0xFFFF, 16}, {0, 16},
+		} {
+			id, err := ulid.NewULID(ulid.WithTimestamp(ms), ulid.WithEntropy(bytes.NewReader(entropy)), ulid.WithNode(tc.node, tc.bits))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := id.Node(tc.bits); got != tc.node {
+				t.Errorf("WithNode(%d, %d): got node %d", tc.node, tc.bits, got)
+			}
+
+			// The bits after the node are the entropy that was read.
+			want := ulid.MustNew(ms, bytes.NewReader(entropy))
+			for i := tc.bits; i < 80; i++ {
+				if bit(id, 48+i) != bit(want, 48+i) {
+					t.Fatalf("WithNode(%d, %d): expected entropy bit %d to be unchanged", tc.node, tc.bits, i)
+				}
+			}
+		}
+
+		for _, tc := range []struct {
+			node uint16
+			bits int
+		}{
+			{0, 0}, {0, -1}, {0, 17}, {2, 1}, {16, 4}, {0x400, 10},
+		} {
+			if _, err := ulid.NewULID(ulid.WithNode(tc.node, tc.bits)); !errors.Is(err, ulid.ErrInvalidNode) {
+				t.Errorf("WithNode(%d, %d): expected ErrInvalidNode, got %v", tc.node, tc.bits, err)
+			}
+		}
+
+		if node := ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ").Node(0); node != 0 {
+			t.Errorf("expected node 0 for invalid bits, got %d", node)
+		}
+	})
+
+	t.Run("ZeroOption", func(t *testing.T) {
+		id, err := ulid.NewULID(ulid.Option{}, ulid.WithTimestamp(ms), ulid.Option{})
+		if err != nil || id.Time() != ms {
+			t.Errorf("expected the zero option to be ignored, got %s (%v)", id, err)
+		}
+	})
+}
+
+func TestNewULIDConflicts(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	options := map[string]ulid.Option{
+		"WithTime":        ulid.WithTime(ts),
+		"WithTimestamp":   ulid.WithTimestamp(ulid.Timestamp(ts)),
+		"WithEntropy":     ulid.WithEntropy(&constReader{0x5A}),
+		"WithSecure":      ulid.WithSecure(),
+		"WithZeroEntropy": ulid.WithZeroEntropy(),
+		"WithNode":        ulid.WithNode(1, 4),
+	}
+
+	kinds := map[string]string{
+		"WithTime":        "time",
+		"WithTimestamp":   "time",
+		"WithEntropy":     "entropy",
+		"WithSecure":      "entropy",
+		"WithZeroEntropy": "entropy",
+		"WithNode":        "node",
+	}
+
+	for a, optA := range options {
+		for b, optB := range options {
+			_, err := ulid.NewULID(optA, optB)
+			if kinds[a] != kinds[b] {
+				if err != nil {
+					t.Errorf("%s and %s: unexpected error %v", a, b, err)
+				}
+				continue
+			}
+
+			want := "ulid: conflicting options: " + a + " and " + b
+			if !errors.Is(err, ulid.ErrConflictingOptions) || err.Error() != want {
+				t.Errorf("%s and %s: got %v, want %q", a, b, err, want)
+			}
+		}
+	}
+
+	// The first conflicting pair is reported regardless of the other options.
+	_, err := ulid.NewULID(options["WithNode"], options["WithTimestamp"], options["WithSecure"], options["WithTime"], options["WithZeroEntropy"])
+	if want := "ulid: conflicting options: WithTimestamp and WithTime"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+
+	// Invalid option values are reported before conflicts with later options.
+	_, err = ulid.NewULID(ulid.WithTimestamp(ulid.MaxTime()+1), options["WithTime"])
+	if err != ulid.ErrBigTime {
+		t.Errorf("expected ErrBigTime, got %v", err)
+	}
+}
+
+// Options must not allocate, so NewULID allocates no more than Make and New, which
+// allocate the ULID when the entropy is read into it through an interface.
+func TestNewULIDAllocs(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(42))
+
+	baseline := testing.AllocsPerRun(100, func() { _ = ulid.Make() })
+	if allocs := testing.AllocsPerRun(100, func() { _, _ = ulid.New(ulid.Now(), rng) }); allocs > baseline {
+		baseline = allocs
+	}
+
+	for name, f := range map[string]func(){
+		"NoOptions":  func() { _, _ = ulid.NewULID() },
+		"OneOption":  func() { _, _ = ulid.NewULID(ulid.WithTime(ts)) },
+		"TwoOptions": func() { _, _ = ulid.NewULID(ulid.WithTime(ts), ulid.WithEntropy(rng)) },
+		"Node":       func() { _, _ = ulid.NewULID(ulid.WithSecure(), ulid.WithNode(7, 8)) },
+	} {
+		if allocs := testing.AllocsPerRun(100, f); allocs > baseline {
+			t.Errorf("%s: expected at most %.1f allocations, got %.1f", name, baseline, allocs)
+		}
+	}
+}
+
+// bit returns the ith most significant bit of the ULID.
+func bit(id ulid.ULID, i int) byte {
+	return id[i/8] >> (7 - i%8) & 1
+}
+
+func BenchmarkNewULID(b *testing.B) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	b.Run("Make", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = ulid.Make()
+		}
+	})
+
+	b.Run("NoOptions", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = ulid.NewULID()
+		}
+	})
+
+	b.Run("TwoOptions", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = ulid.NewULID(ulid.WithTime(ts), ulid.WithEntropy(rng))
+		}
+	})
+
+	b.Run("New", func(b *testing.B) {
+		b.ReportAllocs()
+		ms := ulid.Timestamp(ts)
+		for i := 0; i < b.N; i++ {
+			_, _ = ulid.New(ms, rng)
+		}
+	})
+}
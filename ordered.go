@@ -0,0 +1,114 @@
+package ulid
+
+import (
+	crand "crypto/rand"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// OrderedGenerator generates strictly increasing ULIDs with monotonic entropy
+// incremented by exactly 1 within each millisecond (equivalent to a locked
+// Monotonic reader with inc == 1), so that every ULID has a sequence number
+// within its millisecond, e.g. so that the followers of a replication log can
+// detect missed entries. The first ULID of each millisecond has random entropy
+// and sequence 0, the next has the entropy plus 1 and sequence 1, and so on; the
+// sequence of a ULID can be recomputed from the entropy of the first ULID of its
+// millisecond with SequenceOf.
+//
+// The timestamp of a ULID is never less than the timestamp of the previous ULID:
+// if the clock moves backwards the generator continues the sequence of the last
+// millisecond until the clock catches up. If the entropy of a millisecond
+// overflows, the timestamp is advanced by 1ms and the sequence restarts at 0
+// with fresh entropy, as with BumpOnOverflow.
+//
+// Because consecutive ULIDs differ by exactly 1 they are trivially guessable
+// from one another; the generator should not be used where ULIDs need to be
+// unguessable. An OrderedGenerator is safe for concurrent use.
+type OrderedGenerator struct {
+	// Now returns the current time used for the timestamp of ULIDs; it defaults to
+	// time.Now and may be replaced, e.g. to simulate a clock in tests, but must
+	// not be modified concurrently with calls to Next.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entropy io.Reader
+	ms      uint64
+	next    uint80
+	seq     uint64
+	started bool
+}
+
+// NewOrderedGenerator returns a generator that reads the random entropy of the
+// first ULID of each millisecond from entropy, or from crypto/rand if entropy is
+// nil.
+func NewOrderedGenerator(entropy io.Reader) *OrderedGenerator {
+	if entropy == nil {
+		entropy = crand.Reader
+	}
+
+	return &OrderedGenerator{
+		Now:     time.Now,
+		entropy: entropy,
+	}
+}
+
+// Next returns the next ULID and its 0-based sequence number within its
+// millisecond. An error is returned if the entropy cannot be read or if the
+// timestamp cannot be represented in a ULID.
+func (g *OrderedGenerator) Next() (id ULID, seq uint64, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var ms uint64
+	if ms, err = TimestampChecked(g.Now()); err != nil {
+		return Zero, 0, err
+	}
+
+	if g.started && ms <= g.ms {
+		ms = g.ms
+		if g.seq == math.MaxUint64 || g.next.IsZero() {
+			// The entropy overflowed on the previous increment.
+			ms++
+		}
+	}
+
+	if err = id.SetTime(ms); err != nil {
+		return Zero, 0, err
+	}
+
+	if !g.started || ms != g.ms {
+		if _, err = io.ReadFull(g.entropy, id[6:]); err != nil {
+			return Zero, 0, err
+		}
+
+		g.ms, g.seq, g.started = ms, 0, true
+		g.next.SetBytes(id[6:])
+	} else {
+		g.seq++
+		g.next.AppendTo(id[6:])
+	}
+
+	// An overflow wraps the next entropy around to zero, which is detected at the
+	// start of the next call.
+	g.next.Add(1)
+	return id, g.seq, nil
+}
+
+// SequenceOf returns the sequence number of a ULID generated by an
+// OrderedGenerator given the entropy of the first ULID of its millisecond (the
+// ULID with sequence 0). The boolean is false if the entropy of the ULID is less
+// than the first entropy or the distance does not fit in a uint64, in which
+// case the ULID was not generated in the same millisecond sequence.
+func SequenceOf(id ULID, firstEntropyOfMs [10]byte) (uint64, bool) {
+	var e, first uint80
+	e.SetBytes(id[6:])
+	first.SetBytes(firstEntropyOfMs[:])
+
+	d, borrow := e.Sub(first)
+	if borrow || d.Hi != 0 {
+		return 0, false
+	}
+	return d.Lo, true
+}
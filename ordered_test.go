@@ -0,0 +1,218 @@
+package ulid_test
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func orderedGenerator(entropy io.Reader) (*ulid.OrderedGenerator, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	g := ulid.NewOrderedGenerator(entropy)
+	g.Now = clock.Now
+	return g, clock
+}
+
+func TestOrderedGenerator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Burst", func(t *testing.T) {
+		t.Parallel()
+
+		g, clock := orderedGenerator(nil)
+		ms := ulid.Timestamp(clock.Now())
+
+		var first [10]byte
+		var prev ulid.ULID
+		for i := uint64(0); i < 1000; i++ {
+			id, seq, err := g.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if seq != i || id.Time() != ms {
+				t.Fatalf("got sequence %d at %d, want %d at %d", seq, id.Time(), i, ms)
+			}
+
+			if i == 0 {
+				copy(first[:], id.Entropy())
+			} else if gap, ok := ulid.Gap(prev, id); !ok || gap != 1 {
+				t.Fatalf("expected %s to immediately follow %s", id, prev)
+			}
+
+			if got, ok := ulid.SequenceOf(id, first); !ok || got != seq {
+				t.Fatalf("SequenceOf(%s): got %d %t, want %d", id, got, ok, seq)
+			}
+			prev = id
+		}
+	})
+
+	t.Run("Rollover", func(t *testing.T) {
+		t.Parallel()
+
+		g, clock := orderedGenerator(nil)
+		for _, d := range []time.Duration{0, time.Millisecond, 0, 10 * time.Millisecond} {
+			clock.Advance(d)
+
+			want := ulid.Timestamp(clock.Now())
+			id, seq, err := g.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d > 0 && seq != 0 {
+				t.Errorf("expected the sequence to reset at a new millisecond, got %d", seq)
+			}
+
+			if id.Time() != want {
+				t.Errorf("got timestamp %d, want %d", id.Time(), want)
+			}
+		}
+	})
+
+	t.Run("ClockRegression", func(t *testing.T) {
+		t.Parallel()
+
+		g, clock := orderedGenerator(nil)
+		first, _, err := g.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clock.Advance(-time.Second)
+		for i := uint64(1); i < 4; i++ {
+			id, seq, err := g.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id.Time() != first.Time() || seq != i || id.Compare(first) <= 0 {
+				t.Errorf("expected the sequence of %s to continue, got %s sequence %d", first, id, seq)
+			}
+		}
+
+		clock.Advance(time.Second + time.Millisecond)
+		if id, seq, _ := g.Next(); id.Time() != first.Time()+1 || seq != 0 {
+			t.Errorf("expected a new sequence once the clock caught up, got %s sequence %d", id, seq)
+		}
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		t.Parallel()
+
+		max := append(bytes.Repeat([]byte{0xFF}, 9), 0xFD)
+		g, clock := orderedGenerator(io.MultiReader(bytes.NewReader(max), crand.Reader))
+		ms := ulid.Timestamp(clock.Now())
+
+		for i := uint64(0); i < 3; i++ {
+			id, seq, err := g.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id.Time() != ms || seq != i {
+				t.Fatalf("got %s sequence %d, want sequence %d", id, seq, i)
+			}
+		}
+
+		id, seq, err := g.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id.Time() != ms+1 || seq != 0 {
+			t.Errorf("expected the timestamp to advance on overflow, got %s sequence %d", id, seq)
+		}
+
+		// The advanced timestamp is kept until the clock catches up.
+		if next, seq, _ := g.Next(); next.Time() != ms+1 || seq != 1 || next.Compare(id) <= 0 {
+			t.Errorf("expected the advanced sequence to continue, got %s sequence %d", next, seq)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		t.Parallel()
+
+		g, _ := orderedGenerator(nil)
+		seqs := make(chan uint64, 8*128)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 128; j++ {
+					_, seq, err := g.Next()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					seqs <- seq
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(seqs)
+
+		seen := make(map[uint64]bool, cap(seqs))
+		for seq := range seqs {
+			seen[seq] = true
+		}
+
+		for i := uint64(0); i < uint64(cap(seqs)); i++ {
+			if !seen[i] {
+				t.Fatalf("missing sequence %d", i)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		g, _ := orderedGenerator(iotest.ErrReader(io.ErrUnexpectedEOF))
+		if _, _, err := g.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected entropy error, got %v", err)
+		}
+
+		g.Now = func() time.Time { return time.UnixMilli(-1) }
+		if _, _, err := g.Next(); !errors.Is(err, ulid.ErrSmallTime) {
+			t.Errorf("expected ErrSmallTime, got %v", err)
+		}
+	})
+}
+
+func TestSequenceOf(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	var first [10]byte
+	copy(first[:], id.Entropy())
+
+	if seq, ok := ulid.SequenceOf(id, first); !ok || seq != 0 {
+		t.Errorf("got %d %t for the first entropy", seq, ok)
+	}
+
+	later := id
+	later[15] += 42
+	if seq, ok := ulid.SequenceOf(later, first); !ok || seq != 42 {
+		t.Errorf("got %d %t, want 42", seq, ok)
+	}
+
+	if _, ok := ulid.SequenceOf(id, [10]byte(later[6:])); ok {
+		t.Errorf("expected entropy before the first entropy to have no sequence")
+	}
+
+	far := id
+	far[7]++
+	if _, ok := ulid.SequenceOf(far, first); ok {
+		t.Errorf("expected a distance larger than a uint64 to have no sequence")
+	}
+}
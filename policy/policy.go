@@ -0,0 +1,357 @@
+// Package policy defines the rules that an organization accepts ULIDs by, e.g.
+// that IDs are canonical uppercase strings, have entropy, and were created within
+// the last 30 days, so that every service validates IDs consistently rather than
+// each implementing its own checks:
+//
+//	accept, err := policy.New(
+//		policy.RequireCanonical(),
+//		policy.RejectZeroEntropy(),
+//		policy.TimeWindow(-30*24*time.Hour, time.Minute),
+//	)
+//	id, err := accept.Validate(r.PathValue("id"))
+//
+// A Policy is immutable once created and safe for concurrent use, so one policy
+// can be shared by all of the handlers of a service. Policies have a JSON
+// description so that they can be kept in configuration:
+//
+//	{"rules": [{"rule": "canonical"}, {"rule": "time_window", "min": "-720h", "max": "1m"}]}
+//
+// Custom rules are described by name and must be registered with Register to be
+// unmarshaled.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+var (
+	// Returned when a rule of a policy is not valid, e.g. a time window whose
+	// minimum is after its maximum or a description with an unknown rule.
+	ErrInvalidRule = errors.New("policy: invalid rule")
+
+	// Returned when a custom rule of a policy description is not registered.
+	ErrUnknownRule = errors.New("policy: unknown custom rule")
+
+	// Returned by RequireFirstCharRange when the first character of a ULID is not
+	// in the range.
+	ErrFirstChar = errors.New("policy: first character is out of range")
+)
+
+// The names of the built-in rules in the JSON description of a policy.
+const (
+	ruleCanonical = "canonical"
+	ruleEntropy   = "nonzero_entropy"
+	ruleWindow    = "time_window"
+	ruleFirstChar = "first_char"
+	ruleCustom    = "custom"
+)
+
+// Violation is the error of a rule that rejected a ULID. The errors of Validate
+// join a Violation for each rule that was violated, so errors.As finds the first
+// of them and errors.Is finds the error of any of them, e.g. ulid.ErrZeroEntropy.
+type Violation struct {
+	Rule string // The rule, e.g. "canonical" or the name of a custom rule
+	Err  error  // The reason the ULID was rejected
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy: %s: %v", v.Rule, v.Err)
+}
+
+func (v *Violation) Unwrap() error {
+	return v.Err
+}
+
+//===========================================================================
+// Rules
+//===========================================================================
+
+// RuleFunc is a custom rule, which returns an error if the ULID is not accepted.
+type RuleFunc func(ulid.ULID) error
+
+// Rule is a rule of a policy, created by one of the functions below.
+type Rule struct {
+	spec ruleSpec
+	fn   RuleFunc
+}
+
+// ruleSpec is the JSON description of a rule; min and max are the parameters of
+// the time window and first character rules.
+type ruleSpec struct {
+	Rule string `json:"rule"`
+	Name string `json:"name,omitempty"`
+	Min  string `json:"min,omitempty"`
+	Max  string `json:"max,omitempty"`
+}
+
+// String returns the name of the rule, e.g. "time_window" or the name of a custom
+// rule.
+func (r Rule) String() string {
+	if r.spec.Rule == ruleCustom {
+		return r.spec.Name
+	}
+	return r.spec.Rule
+}
+
+// RequireCanonical rejects strings that are not the canonical encoding of their
+// ULID, e.g. lowercase strings, with ulid.ErrNonCanonical. ULIDs that are not
+// validated from a string are always canonical.
+func RequireCanonical() Rule {
+	return Rule{spec: ruleSpec{Rule: ruleCanonical}}
+}
+
+// RejectZeroEntropy rejects ULIDs whose entropy is all zero, e.g. ULIDs created
+// from a timestamp alone, with ulid.ErrZeroEntropy.
+func RejectZeroEntropy() Rule {
+	return Rule{spec: ruleSpec{Rule: ruleEntropy}}
+}
+
+// TimeWindow rejects ULIDs whose timestamp is not from min to max relative to the
+// time of validation, with an error wrapping ulid.ErrImplausibleTime, e.g.
+// TimeWindow(-30*24*time.Hour, time.Minute) for ULIDs of the last 30 days that
+// allows a minute of clock skew. The minimum must not be after the maximum.
+func TimeWindow(min, max time.Duration) Rule {
+	return Rule{spec: ruleSpec{Rule: ruleWindow, Min: min.String(), Max: max.String()}}
+}
+
+// RequireFirstCharRange rejects ULIDs whose canonical encoding does not start
+// with a character from lo to hi, in the order of the Crockford base32 alphabet,
+// with ErrFirstChar. The first character is the top of the timestamp, so for
+// example '0' to '1' rejects ULIDs after the year 2527, which catches IDs that
+// are not really ULIDs; the characters are case-insensitive.
+func RequireFirstCharRange(lo, hi byte) Rule {
+	return Rule{spec: ruleSpec{Rule: ruleFirstChar, Min: string(lo), Max: string(hi)}}
+}
+
+// Custom returns a rule that rejects the ULIDs for which fn returns an error. The
+// rule is described by its name in JSON, so to unmarshal a policy with the rule
+// the function must be registered with the name using Register.
+func Custom(name string, fn RuleFunc) Rule {
+	return Rule{spec: ruleSpec{Rule: ruleCustom, Name: name}, fn: fn}
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]RuleFunc)
+)
+
+// Register registers the function of the custom rule with the name so that
+// policy descriptions with the rule can be unmarshaled, usually in an init
+// function. It panics if the name is empty, the function is nil, or a rule with
+// the name is already registered.
+func Register(name string, fn RuleFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" || fn == nil {
+		panic("policy: Register requires a name and a rule function")
+	}
+
+	if _, ok := registry[name]; ok {
+		panic("policy: Register called twice for rule " + name)
+	}
+	registry[name] = fn
+}
+
+// check is a compiled rule, which is called with the ULID, the string it was
+// parsed from if any, and the time of validation.
+type check func(id ulid.ULID, text string, now time.Time) error
+
+// compile validates the description of the rule and returns its check.
+func (r Rule) compile() (check, error) {
+	switch r.spec.Rule {
+	case ruleCanonical:
+		return func(id ulid.ULID, text string, _ time.Time) error {
+			if text != "" && !ulid.IsCanonical(text) {
+				return ulid.ErrNonCanonical
+			}
+			return nil
+		}, nil
+
+	case ruleEntropy:
+		return func(id ulid.ULID, _ string, _ time.Time) error {
+			if id.EntropyArray() == [10]byte{} {
+				return ulid.ErrZeroEntropy
+			}
+			return nil
+		}, nil
+
+	case ruleWindow:
+		min, err := time.ParseDuration(r.spec.Min)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: min: %w", ErrInvalidRule, ruleWindow, err)
+		}
+
+		max, err := time.ParseDuration(r.spec.Max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: max: %w", ErrInvalidRule, ruleWindow, err)
+		}
+
+		if min > max {
+			return nil, fmt.Errorf("%w: %s: min %s is after max %s", ErrInvalidRule, ruleWindow, min, max)
+		}
+
+		return func(id ulid.ULID, _ string, now time.Time) error {
+			ts := id.Timestamp()
+			if earliest := now.Add(min); ts.Before(earliest.Truncate(time.Millisecond)) {
+				return fmt.Errorf("%w: %s is before %s", ulid.ErrImplausibleTime, ts.UTC().Format(time.RFC3339Nano), earliest.UTC().Format(time.RFC3339Nano))
+			}
+
+			if latest := now.Add(max); ts.After(latest) {
+				return fmt.Errorf("%w: %s is after %s", ulid.ErrImplausibleTime, ts.UTC().Format(time.RFC3339Nano), latest.UTC().Format(time.RFC3339Nano))
+			}
+			return nil
+		}, nil
+
+	case ruleFirstChar:
+		lo, lok := firstChar(r.spec.Min)
+		hi, hok := firstChar(r.spec.Max)
+		if !lok || !hok || lo > hi {
+			return nil, fmt.Errorf("%w: %s: %q to %q is not a range of base32 characters", ErrInvalidRule, ruleFirstChar, r.spec.Min, r.spec.Max)
+		}
+
+		return func(id ulid.ULID, _ string, _ time.Time) error {
+			if c := id.String()[0]; c < lo || c > hi {
+				return fmt.Errorf("%w: %c is not from %c to %c", ErrFirstChar, c, lo, hi)
+			}
+			return nil
+		}, nil
+
+	case ruleCustom:
+		if r.spec.Name == "" {
+			return nil, fmt.Errorf("%w: custom rule without a name", ErrInvalidRule)
+		}
+
+		fn := r.fn
+		if fn == nil {
+			registryMu.RLock()
+			fn = registry[r.spec.Name]
+			registryMu.RUnlock()
+		}
+
+		if fn == nil {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownRule, r.spec.Name)
+		}
+
+		return func(id ulid.ULID, _ string, _ time.Time) error {
+			return fn(id)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown rule %q", ErrInvalidRule, r.spec.Rule)
+	}
+}
+
+// firstChar returns the uppercase character of s if it is a single character of
+// the Crockford base32 alphabet. The characters of the alphabet are in ASCII
+// order, so they can be compared as bytes.
+func firstChar(s string) (byte, bool) {
+	if len(s) != 1 {
+		return 0, false
+	}
+
+	c := strings.ToUpper(s)[0]
+	return c, strings.IndexByte(ulid.Encoding, c) >= 0
+}
+
+//===========================================================================
+// Policy
+//===========================================================================
+
+// Policy is a set of rules that ULIDs must satisfy to be accepted. The zero Policy
+// accepts every ULID that can be parsed. A Policy must not be modified once it is
+// shared, which only UnmarshalJSON does.
+type Policy struct {
+	rules  []Rule
+	checks []check
+}
+
+// New returns a policy of the rules, or an error wrapping ErrInvalidRule if a rule
+// is not valid, e.g. a time window whose minimum is after its maximum.
+func New(rules ...Rule) (*Policy, error) {
+	p := &Policy{rules: make([]Rule, 0, len(rules)), checks: make([]check, 0, len(rules))}
+	for _, rule := range rules {
+		check, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+
+		p.rules = append(p.rules, rule)
+		p.checks = append(p.checks, check)
+	}
+	return p, nil
+}
+
+// Rules returns a copy of the rules of the policy.
+func (p *Policy) Rules() []Rule {
+	return append([]Rule(nil), p.rules...)
+}
+
+// Validate strictly parses the input, which may be any input accepted by
+// ulid.ParseStrict, and checks the ULID against every rule of the policy. The
+// error of the parse is returned as is if the input is not a ULID. Otherwise, if
+// the ULID violates any rules, the error joins a *Violation for each of them in
+// the order of the rules and the parsed ULID is returned with it, e.g. to log the
+// rejected ID.
+func (p *Policy) Validate(input any) (ulid.ULID, error) {
+	id, err := ulid.ParseStrict(input)
+	if err != nil {
+		return ulid.Zero, err
+	}
+
+	text, _ := input.(string)
+	now := time.Now()
+
+	var violations []error
+	for i, check := range p.checks {
+		if err := check(id, text, now); err != nil {
+			violations = append(violations, &Violation{Rule: p.rules[i].String(), Err: err})
+		}
+	}
+	return id, errors.Join(violations...)
+}
+
+// policySpec is the JSON description of a policy.
+type policySpec struct {
+	Rules []ruleSpec `json:"rules"`
+}
+
+// MarshalJSON returns the description of the policy, e.g.
+// {"rules":[{"rule":"canonical"},{"rule":"custom","name":"tenant"}]}.
+func (p *Policy) MarshalJSON() ([]byte, error) {
+	spec := policySpec{Rules: make([]ruleSpec, 0, len(p.rules))}
+	for _, rule := range p.rules {
+		spec.Rules = append(spec.Rules, rule.spec)
+	}
+	return json.Marshal(spec)
+}
+
+// UnmarshalJSON replaces the policy with the policy of the description. An error
+// wrapping ErrInvalidRule is returned if a rule is not valid, and ErrUnknownRule
+// if a custom rule is not registered, in which case the policy is unchanged.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var spec policySpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return err
+	}
+
+	rules := make([]Rule, 0, len(spec.Rules))
+	for _, rs := range spec.Rules {
+		rules = append(rules, Rule{spec: rs})
+	}
+
+	decoded, err := New(rules...)
+	if err != nil {
+		return err
+	}
+
+	*p = *decoded
+	return nil
+}
@@ -0,0 +1,313 @@
+package policy_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/policy"
+)
+
+// errTenant is returned by the custom tenant rule.
+var errTenant = errors.New("not a tenant ULID")
+
+// tenantRule accepts the ULIDs whose entropy starts with 0x01.
+func tenantRule(id ulid.ULID) error {
+	if id.Entropy()[0] != 0x01 {
+		return errTenant
+	}
+	return nil
+}
+
+func init() {
+	policy.Register("tenant", tenantRule)
+}
+
+// ago returns a ULID of the time d before now with entropy that starts with b.
+func ago(d time.Duration, b byte) ulid.ULID {
+	id := ulid.MustNew(ulid.Timestamp(time.Now().Add(-d)), strings.NewReader("0123456789"))
+	id[6] = b
+	return id
+}
+
+// violated returns the rules of the violations joined in the error of Validate.
+func violated(err error) (rules []string) {
+	if err == nil {
+		return nil
+	}
+
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		rules = append(rules, err.(*policy.Violation).Rule)
+	}
+	return rules
+}
+
+func mustNew(t *testing.T, rules ...policy.Rule) *policy.Policy {
+	t.Helper()
+	p, err := policy.New(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	recent := ago(time.Hour, 0x01)
+	zero := ulid.MustNew(ulid.Now(), strings.NewReader(strings.Repeat("\x00", 10)))
+	testCases := []struct {
+		name  string
+		rule  policy.Rule
+		input any
+		err   error
+	}{
+		{"Canonical", policy.RequireCanonical(), recent.String(), nil},
+		{"Lowercase", policy.RequireCanonical(), strings.ToLower(recent.String()), ulid.ErrNonCanonical},
+		{"CanonicalBinary", policy.RequireCanonical(), recent.Bytes(), nil},
+		{"CanonicalULID", policy.RequireCanonical(), recent, nil},
+		{"Entropy", policy.RejectZeroEntropy(), recent, nil},
+		{"ZeroEntropy", policy.RejectZeroEntropy(), zero.String(), ulid.ErrZeroEntropy},
+		{"InWindow", policy.TimeWindow(-30*24*time.Hour, time.Minute), recent, nil},
+		{"TooOld", policy.TimeWindow(-30*24*time.Hour, time.Minute), ago(31*24*time.Hour, 0), ulid.ErrImplausibleTime},
+		{"TooNew", policy.TimeWindow(-30*24*time.Hour, time.Minute), ago(-time.Hour, 0), ulid.ErrImplausibleTime},
+		{"FutureWindow", policy.TimeWindow(time.Hour, 2*time.Hour), ago(-90*time.Minute, 0), nil},
+		{"FirstChar", policy.RequireFirstCharRange('0', '1'), recent, nil},
+		{"FirstCharLower", policy.RequireFirstCharRange('a', 'z'), recent, policy.ErrFirstChar},
+		{"FirstCharMax", policy.RequireFirstCharRange('0', '1'), ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"), policy.ErrFirstChar},
+		{"Custom", policy.Custom("tenant", tenantRule), recent, nil},
+		{"CustomRejected", policy.Custom("tenant", tenantRule), ago(time.Hour, 0x02), errTenant},
+	}
+
+	for _, tc := range testCases {
+		p := mustNew(t, tc.rule)
+		id, err := p.Validate(tc.input)
+		if !errors.Is(err, tc.err) || (tc.err == nil) != (err == nil) {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.err)
+			continue
+		}
+
+		if id.IsZero() {
+			t.Errorf("%s: expected the parsed ULID", tc.name)
+		}
+
+		var v *policy.Violation
+		if err != nil && (!errors.As(err, &v) || v.Rule != tc.rule.String()) {
+			t.Errorf("%s: expected a violation of %s, got %v", tc.name, tc.rule, err)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := mustNew(t,
+		policy.RequireCanonical(),
+		policy.RejectZeroEntropy(),
+		policy.TimeWindow(-30*24*time.Hour, time.Minute),
+		policy.RequireFirstCharRange('0', '1'),
+		policy.Custom("tenant", tenantRule),
+	)
+
+	accepted := ago(time.Hour, 0x01)
+	if id, err := p.Validate(accepted.String()); err != nil || id != accepted {
+		t.Errorf("got %s (%v), want %s", id, err, accepted)
+	}
+
+	// Every violated rule is listed in order.
+	rejected := ulid.MustNew(ulid.Timestamp(time.Now().Add(-60*24*time.Hour)), strings.NewReader(strings.Repeat("\x00", 10)))
+	id, err := p.Validate(strings.ToLower(rejected.String()))
+	if id != rejected {
+		t.Errorf("got %s, want %s", id, rejected)
+	}
+
+	for _, target := range []error{ulid.ErrNonCanonical, ulid.ErrZeroEntropy, ulid.ErrImplausibleTime, errTenant} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected the error to include %v, got %v", target, err)
+		}
+	}
+
+	if errors.Is(err, policy.ErrFirstChar) {
+		t.Errorf("expected the first character rule to pass, got %v", err)
+	}
+
+	lines := strings.Split(err.Error(), "\n")
+	prefixes := []string{"policy: canonical: ", "policy: nonzero_entropy: ", "policy: time_window: ", "policy: tenant: "}
+	if len(lines) != len(prefixes) {
+		t.Fatalf("got %d violations, want %d: %v", len(lines), len(prefixes), err)
+	}
+
+	for i, line := range lines {
+		if !strings.HasPrefix(line, prefixes[i]) {
+			t.Errorf("violation %d: got %q, want prefix %q", i, line, prefixes[i])
+		}
+	}
+
+	// Inputs that are not ULIDs are rejected by the parser.
+	for _, input := range []any{"not a ulid", "01JKEHNQPA0END3NHMFKB2Y6SU", []byte{1, 2, 3}, 42} {
+		if _, err := p.Validate(input); err == nil || errors.As(err, new(*policy.Violation)) {
+			t.Errorf("%v: expected a parse error, got %v", input, err)
+		}
+	}
+
+	// The zero policy accepts every ULID.
+	var zero policy.Policy
+	if _, err := zero.Validate(rejected); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// The rules are copied.
+	rules := p.Rules()
+	rules[0] = policy.RejectZeroEntropy()
+	if p.Rules()[0].String() != "canonical" {
+		t.Error("expected the rules of the policy to be unchanged")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		rule policy.Rule
+		err  error
+	}{
+		{"Zero", policy.Rule{}, policy.ErrInvalidRule},
+		{"Window", policy.TimeWindow(time.Hour, -time.Hour), policy.ErrInvalidRule},
+		{"FirstCharOrder", policy.RequireFirstCharRange('7', '0'), policy.ErrInvalidRule},
+		{"FirstCharAlphabet", policy.RequireFirstCharRange('0', 'U'), policy.ErrInvalidRule},
+		{"CustomName", policy.Custom("", tenantRule), policy.ErrInvalidRule},
+		{"CustomFunc", policy.Custom("unregistered", nil), policy.ErrUnknownRule},
+	}
+
+	for _, tc := range testCases {
+		if p, err := policy.New(policy.RequireCanonical(), tc.rule); !errors.Is(err, tc.err) || p != nil {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestPolicyJSON(t *testing.T) {
+	t.Parallel()
+
+	p := mustNew(t,
+		policy.RequireCanonical(),
+		policy.RejectZeroEntropy(),
+		policy.TimeWindow(-720*time.Hour, time.Minute),
+		policy.RequireFirstCharRange('0', '1'),
+		policy.Custom("tenant", tenantRule),
+	)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"rules":[{"rule":"canonical"},{"rule":"nonzero_entropy"},{"rule":"time_window","min":"-720h0m0s","max":"1m0s"},{"rule":"first_char","min":"0","max":"1"},{"rule":"custom","name":"tenant"}]}`
+	if string(data) != expected {
+		t.Errorf("got %s, want %s", data, expected)
+	}
+
+	var decoded policy.Policy
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if again, err := json.Marshal(&decoded); err != nil || string(again) != expected {
+		t.Errorf("got %s (%v), want %s", again, err, expected)
+	}
+
+	// The decoded policy has the same rules, including the registered custom rule.
+	for _, id := range []ulid.ULID{ago(time.Hour, 0x01), ago(time.Hour, 0x02), ago(800*time.Hour, 0x01)} {
+		_, want := p.Validate(id)
+		if _, got := decoded.Validate(id); !slices.Equal(violated(got), violated(want)) {
+			t.Errorf("%s: got %v, want %v", id, got, want)
+		}
+	}
+
+	// A policy in configuration can be written by hand.
+	var config struct {
+		IDs policy.Policy `json:"ids"`
+	}
+
+	handwritten := `{"ids": {"rules": [{"rule": "time_window", "min": "-1h", "max": "0s"}, {"rule": "first_char", "min": "0", "max": "z"}]}}`
+	if err := json.Unmarshal([]byte(handwritten), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := config.IDs.Validate(ago(2*time.Hour, 0)); !errors.Is(err, ulid.ErrImplausibleTime) {
+		t.Errorf("got %v, want %v", err, ulid.ErrImplausibleTime)
+	}
+
+	invalid := []struct {
+		data string
+		err  error
+	}{
+		{`{"rules": [{"rule": "uppercase"}]}`, policy.ErrInvalidRule},
+		{`{"rules": [{"rule": "time_window", "min": "30 days", "max": "0s"}]}`, policy.ErrInvalidRule},
+		{`{"rules": [{"rule": "time_window", "min": "-1h"}]}`, policy.ErrInvalidRule},
+		{`{"rules": [{"rule": "first_char", "min": "00", "max": "1"}]}`, policy.ErrInvalidRule},
+		{`{"rules": [{"rule": "custom", "name": "unregistered"}]}`, policy.ErrUnknownRule},
+	}
+
+	for _, tc := range invalid {
+		unchanged := *mustNew(t, policy.RequireCanonical())
+		if err := json.Unmarshal([]byte(tc.data), &unchanged); !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %v, want %v", tc.data, err, tc.err)
+		}
+
+		if rules := unchanged.Rules(); len(rules) != 1 || rules[0].String() != "canonical" {
+			t.Errorf("%s: expected the policy to be unchanged, got %v", tc.data, rules)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"rules": "canonical"}`), &decoded); err == nil {
+		t.Error("expected an error for an invalid description")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	for _, register := range []func(){
+		func() { policy.Register("tenant", tenantRule) },
+		func() { policy.Register("", tenantRule) },
+		func() { policy.Register("nil", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected Register to panic")
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestPolicyConcurrent(t *testing.T) {
+	t.Parallel()
+
+	p := mustNew(t, policy.RequireCanonical(), policy.TimeWindow(-time.Hour, time.Minute))
+	id := ago(time.Minute, 0).String()
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			var err error
+			for j := 0; j < 100 && err == nil; j++ {
+				_, err = p.Validate(id)
+			}
+			done <- err
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+}
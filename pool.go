@@ -0,0 +1,64 @@
+package ulid
+
+import "sync"
+
+// ULIDPool reuses heap allocated ULIDs to reduce GC pressure in pipelines that
+// pass *ULID through interfaces or channels. The zero value is ready to use and
+// a ULIDPool is safe for concurrent use.
+//
+// A ULID is 16 bytes with no pointers, so copying it by value is usually cheaper
+// than pooling: values sent through channels, stored in slices, or passed to
+// functions are not allocated at all and are invisible to the GC. Pooling only
+// helps when a *ULID would otherwise escape to the heap, e.g. when it is stored in
+// an interface value, and the lifetime of each pointer is clearly owned so that it
+// can be returned with Put exactly once. See BenchmarkPipeline for a comparison.
+//
+// When Debug is true, or when built with the race detector or the ulid_debug build
+// tag, Put poisons the ULID with a fixed pattern and Get panics if the pattern was
+// modified, catching writes through a pointer after it was returned to the pool.
+// Put also panics if the ULID is already poisoned, catching most double Puts.
+type ULIDPool struct {
+	// Debug enables poisoning of pooled ULIDs even without the build tags.
+	Debug bool
+
+	pool sync.Pool
+}
+
+// poison is the pattern written to pooled ULIDs in debug mode. Reads of a ULID
+// after Put return this value rather than the zero ULID so they are easy to spot.
+var poison = ULID{0xDE, 0xAD, 0xBE, 0xEF, 0xDE, 0xAD, 0xBE, 0xEF, 0xDE, 0xAD, 0xBE, 0xEF, 0xDE, 0xAD, 0xBE, 0xEF}
+
+// Get returns a zero ULID from the pool, allocating a new one if it is empty.
+func (p *ULIDPool) Get() *ULID {
+	id, _ := p.pool.Get().(*ULID)
+	if id == nil {
+		return new(ULID)
+	}
+
+	if p.debug() && *id != poison {
+		panic(newPanicError("ULIDPool.Get", *id, ErrPoolCorrupted))
+	}
+
+	*id = Zero
+	return id
+}
+
+// Put returns the ULID to the pool. The ULID must not be used after it is put.
+// Putting a nil pointer is a no-op.
+func (p *ULIDPool) Put(id *ULID) {
+	if id == nil {
+		return
+	}
+
+	if p.debug() {
+		if *id == poison {
+			panic(newPanicError("ULIDPool.Put", *id, ErrPoolCorrupted))
+		}
+		*id = poison
+	}
+	p.pool.Put(id)
+}
+
+func (p *ULIDPool) debug() bool {
+	return p.Debug || poolDebug
+}
@@ -0,0 +1,6 @@
+//go:build race || ulid_debug
+
+package ulid
+
+// poolDebug enables the poisoning of pooled ULIDs in race and debug builds.
+const poolDebug = true
@@ -0,0 +1,200 @@
+package ulid_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestULIDPool(t *testing.T) {
+	t.Parallel()
+
+	var pool ulid.ULIDPool
+	id := pool.Get()
+	if id == nil || !id.IsZero() {
+		t.Fatalf("expected a zero ULID, got %v", id)
+	}
+
+	*id = ulid.Make()
+	pool.Put(id)
+	pool.Put(nil)
+
+	// Whether or not the ULID is reused, Get always returns a zero ULID.
+	for i := 0; i < 100; i++ {
+		id := pool.Get()
+		if !id.IsZero() {
+			t.Fatalf("expected a zero ULID, got %s", id)
+		}
+
+		*id = ulid.Make()
+		pool.Put(id)
+	}
+}
+
+func TestULIDPoolConcurrent(t *testing.T) {
+	t.Parallel()
+
+	pool := &ulid.ULIDPool{Debug: true}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				id := pool.Get()
+				if !id.IsZero() {
+					t.Errorf("expected a zero ULID, got %s", id)
+					return
+				}
+
+				*id = ulid.Make()
+				pool.Put(id)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestULIDPoolDebug(t *testing.T) {
+	t.Parallel()
+
+	pool := &ulid.ULIDPool{Debug: true}
+	id := pool.Get()
+	*id = ulid.Make()
+	pool.Put(id)
+
+	// Reads after Put return the poison pattern rather than the ULID.
+	if poison := ulid.MustParse("6YNPZEZQNDQVQXXBDYXZFAVFQF"); *id != poison {
+		t.Errorf("expected the ULID to be poisoned after Put, got %s", id)
+	}
+
+	// Putting the same ULID twice panics.
+	testPanics(t, ulid.ErrPoolCorrupted, func() { pool.Put(id) })
+
+	// Writes after Put are detected when the ULID is reused. The pool may drop
+	// items at any time, so retry until the pointer is reused.
+	for i := 0; i < 100; i++ {
+		id := pool.Get()
+		pool.Put(id)
+		*id = ulid.Make()
+
+		var err error
+		got := func() (got *ulid.ULID) {
+			defer func() {
+				if r := recover(); r != nil {
+					err, _ = r.(error)
+				}
+			}()
+			return pool.Get()
+		}()
+
+		if err != nil {
+			if !errors.Is(err, ulid.ErrPoolCorrupted) {
+				t.Fatalf("expected ErrPoolCorrupted, got %v", err)
+			}
+			return
+		}
+
+		if got == id {
+			t.Fatalf("expected a panic when getting a ULID modified after Put")
+		}
+		pool.Put(got)
+	}
+	t.Skip("the pool never reused a ULID")
+}
+
+//===========================================================================
+// Pipeline Benchmarks
+//===========================================================================
+
+// BenchmarkPipeline compares passing ULIDs through a channel-based pipeline as
+// values, as values boxed in interfaces, as newly allocated pointers, and as
+// pooled pointers. Values are not allocated, so they are the fastest unless the
+// ULIDs must be stored in interfaces, where pooled pointers avoid an allocation
+// for each ULID.
+func BenchmarkPipeline(b *testing.B) {
+	id := ulid.Make()
+
+	b.Run("Value", func(b *testing.B) {
+		b.ReportAllocs()
+		ch := make(chan ulid.ULID, 64)
+		done := make(chan struct{})
+		go func() {
+			for id := range ch {
+				_ = id.Time()
+			}
+			close(done)
+		}()
+
+		for i := 0; i < b.N; i++ {
+			ch <- id
+		}
+		close(ch)
+		<-done
+	})
+
+	b.Run("Interface", func(b *testing.B) {
+		b.ReportAllocs()
+		ch := make(chan any, 64)
+		done := make(chan struct{})
+		go func() {
+			for v := range ch {
+				_ = v.(ulid.ULID).Time()
+			}
+			close(done)
+		}()
+
+		for i := 0; i < b.N; i++ {
+			next := id
+			next[15] = byte(i)
+			ch <- next
+		}
+		close(ch)
+		<-done
+	})
+
+	b.Run("Pointer", func(b *testing.B) {
+		b.ReportAllocs()
+		ch := make(chan any, 64)
+		done := make(chan struct{})
+		go func() {
+			for v := range ch {
+				_ = v.(*ulid.ULID).Time()
+			}
+			close(done)
+		}()
+
+		for i := 0; i < b.N; i++ {
+			next := new(ulid.ULID)
+			*next = id
+			ch <- next
+		}
+		close(ch)
+		<-done
+	})
+
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		var pool ulid.ULIDPool
+		ch := make(chan any, 64)
+		done := make(chan struct{})
+		go func() {
+			for v := range ch {
+				next := v.(*ulid.ULID)
+				_ = next.Time()
+				pool.Put(next)
+			}
+			close(done)
+		}()
+
+		for i := 0; i < b.N; i++ {
+			next := pool.Get()
+			*next = id
+			ch <- next
+		}
+		close(ch)
+		<-done
+	})
+}
@@ -0,0 +1,218 @@
+package ulid
+
+import (
+	"io"
+	"sync"
+)
+
+// DefaultPrefetchBytes is the size of the buffer of PrefetchedEntropy if the size
+// is not positive, enough for the entropy of about 400 ULIDs.
+const DefaultPrefetchBytes = 4096
+
+// PrefetchStats are the counters of a PrefetchedReader.
+type PrefetchStats struct {
+	Refills   uint64 // The number of reads of the source by the refill goroutine
+	Refilled  uint64 // The number of bytes read from the source by the refill goroutine
+	Underruns uint64 // The number of reads that the buffer could not serve, which read the source directly
+	Errors    uint64 // The number of refills that failed with an error of the source
+	Buffered  int    // The number of bytes in the buffer
+}
+
+// PrefetchedEntropy returns a reader that serves reads of entropy from a buffer
+// of bufferBytes (DefaultPrefetchBytes if not positive) that a background
+// goroutine refills from the source ahead of demand, e.g. so that the reads of
+// the getrandom system call by crypto/rand happen off the request path rather
+// than when a bufio.Reader of monotonic entropy runs dry, which smooths the tail
+// latency of MakeSecure under syscall jitter. The goroutine refills the buffer
+// whenever it is less than half full.
+//
+// A read that is larger than the bytes in the buffer is an underrun: it reads the
+// source directly and is counted in the stats, so a read never waits for the
+// refill goroutine. The source is read concurrently by the goroutine and by the
+// readers on underrun and so must be safe for concurrent use, as crypto/rand.Reader
+// is. If a refill fails, the error is counted and the refill is retried when the
+// reader is next woken, while underruns return the errors of the source.
+//
+// The reader is safe for concurrent use and composes with Monotonic and Pool by
+// sharing one prefetched source between the pooled monotonic readers, without
+// their own buffers since the source is already buffered:
+//
+//	prefetched := ulid.PrefetchedEntropy(crand.Reader, 64*1024)
+//	defer prefetched.Close()
+//	ulid.SetSecureEntropy(ulid.Pool(func() io.Reader {
+//		return ulid.MonotonicWithOptions(prefetched, 0, ulid.NoBuffer())
+//	}))
+//
+// Close must be called to stop the refill goroutine.
+func PrefetchedEntropy(source io.Reader, bufferBytes int) *PrefetchedReader {
+	if bufferBytes <= 0 {
+		bufferBytes = DefaultPrefetchBytes
+	}
+
+	r := &PrefetchedReader{
+		source: source,
+		buf:    make([]byte, bufferBytes),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go r.refill(make([]byte, bufferBytes))
+	return r
+}
+
+// PrefetchedReader is an entropy source that is buffered ahead of demand, returned
+// by PrefetchedEntropy.
+type PrefetchedReader struct {
+	source io.Reader
+	wake   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu     sync.Mutex
+	buf    []byte // the ring buffer of entropy
+	start  int    // the index of the first buffered byte
+	n      int    // the number of buffered bytes
+	closed bool
+	stats  PrefetchStats
+}
+
+// Read fills p from the buffer, or from the source if the buffer has fewer than
+// len(p) bytes. ErrEntropyClosed is returned after Close.
+func (r *PrefetchedReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return 0, ErrEntropyClosed
+	}
+
+	if r.n >= len(p) {
+		r.take(p)
+		low := r.n < len(r.buf)/2
+		r.mu.Unlock()
+
+		if low {
+			r.signal()
+		}
+		return len(p), nil
+	}
+
+	r.stats.Underruns++
+	r.mu.Unlock()
+
+	r.signal()
+	return io.ReadFull(r.source, p)
+}
+
+// Stats returns the counters of the reader and the bytes that are buffered.
+func (r *PrefetchedReader) Stats() PrefetchStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Buffered = r.n
+	return stats
+}
+
+// Close stops the refill goroutine, waiting for a refill that is reading the source
+// to finish, and zeroes the buffered entropy. Reads after Close return
+// ErrEntropyClosed. Close does not close the source and always returns nil; it
+// may be called more than once.
+func (r *PrefetchedReader) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+		<-r.done
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closed = true
+		clear(r.buf)
+		r.start, r.n = 0, 0
+	})
+	return nil
+}
+
+// Zeroize zeroes and discards the buffered entropy, e.g. before a core dump is
+// taken, and wakes the refill goroutine to fill the buffer with new entropy. The
+// bytes of a refill that is reading the source are added to the buffer when the
+// read returns.
+func (r *PrefetchedReader) Zeroize() {
+	r.mu.Lock()
+	clear(r.buf)
+	r.start, r.n = 0, 0
+	closed := r.closed
+	r.mu.Unlock()
+
+	if !closed {
+		r.signal()
+	}
+}
+
+// signal wakes the refill goroutine without blocking if it is already awake.
+func (r *PrefetchedReader) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// refill fills the free space of the buffer from the source each time it is
+// woken until the reader is closed. Only the refill goroutine adds to the buffer,
+// so the free space can only grow while the source is read outside of the lock.
+func (r *PrefetchedReader) refill(scratch []byte) {
+	defer close(r.done)
+	defer clear(scratch)
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		r.mu.Lock()
+		free := len(r.buf) - r.n
+		r.mu.Unlock()
+
+		if free > 0 {
+			n, err := io.ReadFull(r.source, scratch[:free])
+
+			r.mu.Lock()
+			r.put(scratch[:n])
+			r.stats.Refills++
+			r.stats.Refilled += uint64(n)
+			if err != nil {
+				r.stats.Errors++
+			}
+			r.mu.Unlock()
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-r.wake:
+		}
+	}
+}
+
+// take copies the first len(p) buffered bytes into p and removes them from the
+// buffer, zeroing them. The lock must be held.
+func (r *PrefetchedReader) take(p []byte) {
+	n := copy(p, r.buf[r.start:min(r.start+len(p), len(r.buf))])
+	clear(r.buf[r.start : r.start+n])
+	if n < len(p) {
+		copy(p[n:], r.buf[:len(p)-n])
+		clear(r.buf[:len(p)-n])
+	}
+
+	r.start = (r.start + len(p)) % len(r.buf)
+	r.n -= len(p)
+}
+
+// put appends p to the buffer, which must have room for it. The lock must be held.
+func (r *PrefetchedReader) put(p []byte) {
+	end := (r.start + r.n) % len(r.buf)
+	n := copy(r.buf[end:], p)
+	copy(r.buf, p[n:])
+	r.n += len(p)
+}
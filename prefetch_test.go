@@ -0,0 +1,376 @@
+package ulid_test
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// wordReader returns consecutive big-endian uint64 words, so that every 8 byte
+// aligned read returns distinct words. Reads must be multiples of 8 bytes.
+type wordReader struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+func (r *wordReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := 0; i+8 <= len(p); i += 8 {
+		r.next++
+		binary.BigEndian.PutUint64(p[i:], r.next)
+	}
+	return len(p), nil
+}
+
+// failingReader fails with io.ErrClosedPipe while fail is set.
+type failingReader struct {
+	fail atomic.Bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.fail.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	return crand.Read(p)
+}
+
+// gatedReader blocks its first read until the gate is closed, e.g. to hold the
+// first refill of a prefetched reader.
+type gatedReader struct {
+	gate    chan struct{}
+	blocked chan struct{}
+	once    sync.Once
+}
+
+func newGatedReader() *gatedReader {
+	return &gatedReader{gate: make(chan struct{}), blocked: make(chan struct{})}
+}
+
+func (r *gatedReader) Read(p []byte) (int, error) {
+	first := false
+	r.once.Do(func() { first = true })
+	if first {
+		close(r.blocked)
+		<-r.gate
+	}
+	return crand.Read(p)
+}
+
+// waitBuffered waits for the refill goroutine to fill the buffer of the reader.
+func waitBuffered(t testing.TB, r *ulid.PrefetchedReader, n int) ulid.PrefetchStats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := r.Stats()
+		if stats.Buffered == n {
+			return stats
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d bytes to be buffered, got %+v", n, stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrefetchedEntropy(t *testing.T) {
+	t.Parallel()
+
+	src := &wordReader{}
+	r := ulid.PrefetchedEntropy(src, 256)
+	defer r.Close()
+
+	// The buffer is filled ahead of the first read.
+	if stats := waitBuffered(t, r, 256); stats.Refills != 1 || stats.Refilled != 256 || stats.Underruns != 0 {
+		t.Errorf("unexpected stats after the first refill %+v", stats)
+	}
+
+	// Every word is read exactly once, from the buffer or from the source.
+	seen := make(map[uint64]bool)
+	for i := 0; i < 2000; i++ {
+		p := make([]byte, 8*(1+i%3))
+		if n, err := r.Read(p); err != nil || n != len(p) {
+			t.Fatalf("read %d: got %d bytes (%v)", i, n, err)
+		}
+
+		for j := 0; j < len(p); j += 8 {
+			word := binary.BigEndian.Uint64(p[j:])
+			if word == 0 || seen[word] {
+				t.Fatalf("read %d: word %d was read twice", i, word)
+			}
+			seen[word] = true
+		}
+	}
+
+	// Once the buffer is refilled, every word of the source has been read or is
+	// buffered.
+	stats := waitBuffered(t, r, 256)
+	if stats.Refills < 2 || stats.Refilled%8 != 0 {
+		t.Errorf("expected the buffer to be refilled, got %+v", stats)
+	}
+
+	src.mu.Lock()
+	if src.next != uint64(len(seen)+256/8) {
+		t.Errorf("read %d words from the source, %d were read from the prefetched reader", src.next, len(seen))
+	}
+	src.mu.Unlock()
+
+	// Reads of the buffered bytes do not read the source.
+	p := make([]byte, 64)
+	r.Read(p)
+	if got := r.Stats(); got.Underruns != stats.Underruns || got.Buffered != 192 {
+		t.Errorf("expected the read to be served from the buffer, got %+v", got)
+	}
+}
+
+func TestPrefetchedEntropyUnderrun(t *testing.T) {
+	t.Parallel()
+
+	// While the first refill is blocked, reads fall back to the source rather than
+	// waiting for the buffer.
+	src := newGatedReader()
+	r := ulid.PrefetchedEntropy(src, 128)
+	defer r.Close()
+	<-src.blocked
+
+	for i := 0; i < 3; i++ {
+		entropy := make([]byte, 10)
+		if n, err := r.Read(entropy); err != nil || n != 10 || isZeros(entropy) {
+			t.Fatalf("got %d bytes (%v)", n, err)
+		}
+	}
+
+	if stats := r.Stats(); stats.Underruns != 3 || stats.Refills != 0 || stats.Buffered != 0 {
+		t.Errorf("expected 3 underruns, got %+v", stats)
+	}
+
+	close(src.gate)
+	waitBuffered(t, r, 128)
+
+	// A read larger than the buffer always reads the source.
+	large := make([]byte, 200)
+	if n, err := r.Read(large); err != nil || n != len(large) {
+		t.Errorf("got %d bytes (%v)", n, err)
+	}
+
+	if stats := r.Stats(); stats.Underruns != 4 || stats.Buffered != 128 {
+		t.Errorf("expected the large read to underrun, got %+v", stats)
+	}
+
+	// The errors of the source are returned by underruns and counted by refills,
+	// which are retried when the reader is next read.
+	src2 := &failingReader{}
+	src2.fail.Store(true)
+	failing := ulid.PrefetchedEntropy(src2, 64)
+	defer failing.Close()
+
+	if _, err := failing.Read(make([]byte, 10)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("got %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for failing.Stats().Errors == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the refill to fail")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	src2.fail.Store(false)
+	if _, err := failing.Read(make([]byte, 10)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	waitBuffered(t, failing, 64)
+}
+
+func TestPrefetchedEntropyClose(t *testing.T) {
+	t.Parallel()
+
+	src := newGatedReader()
+	r := ulid.PrefetchedEntropy(src, 64)
+	<-src.blocked
+
+	// Close waits for the refill that is reading the source.
+	closed := make(chan struct{})
+	go func() {
+		r.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("expected Close to wait for the refill")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(src.gate)
+	<-closed
+
+	if n, err := r.Read(make([]byte, 10)); !errors.Is(err, ulid.ErrEntropyClosed) || n != 0 {
+		t.Errorf("got %d bytes (%v), want %v", n, err, ulid.ErrEntropyClosed)
+	}
+
+	if stats := r.Stats(); stats.Buffered != 0 {
+		t.Errorf("expected the buffer to be emptied, got %+v", stats)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error closing twice %v", err)
+	}
+}
+
+func TestPrefetchedEntropyZeroize(t *testing.T) {
+	t.Parallel()
+
+	src := &wordReader{}
+	r := ulid.PrefetchedEntropy(src, 64)
+	defer r.Close()
+
+	waitBuffered(t, r, 64)
+	r.Zeroize()
+
+	// The buffered words are discarded and the buffer is refilled with new words.
+	stats := waitBuffered(t, r, 64)
+	if stats.Refills != 2 || stats.Underruns != 0 {
+		t.Errorf("expected the buffer to be refilled, got %+v", stats)
+	}
+
+	p := make([]byte, 8)
+	if r.Read(p); binary.BigEndian.Uint64(p) != 64/8+1 {
+		t.Errorf("expected the first word after the discarded words, got %d", binary.BigEndian.Uint64(p))
+	}
+}
+
+// NOTE: this test is not parallel since it counts the running goroutines.
+func TestPrefetchedEntropyLeaks(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	readers := make([]*ulid.PrefetchedReader, 50)
+	for i := range readers {
+		readers[i] = ulid.PrefetchedEntropy(crand.Reader, 32)
+		readers[i].Read(make([]byte, 20))
+	}
+
+	if n := runtime.NumGoroutine(); n < before+len(readers) {
+		t.Fatalf("expected a refill goroutine for each reader, got %d goroutines (from %d)", n, before)
+	}
+
+	for _, r := range readers {
+		r.Close()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: %d running, expected %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrefetchedEntropyPool(t *testing.T) {
+	t.Parallel()
+
+	prefetched := ulid.PrefetchedEntropy(crand.Reader, 1024)
+	defer prefetched.Close()
+
+	pool := ulid.Pool(func() io.Reader {
+		return ulid.MonotonicWithOptions(prefetched, 0, ulid.NoBuffer())
+	})
+
+	const goroutines, n = 4, 500
+	ids := make([][]ulid.ULID, goroutines)
+	var wg sync.WaitGroup
+	for g := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				ids[g] = append(ids[g], ulid.MustNew(ulid.Now(), pool))
+			}
+		}()
+	}
+	wg.Wait()
+
+	all := slices.Concat(ids...)
+	slices.SortFunc(all, ulid.ULID.Compare)
+	if len(slices.Compact(all)) != goroutines*n {
+		t.Error("expected unique ULIDs from the pooled prefetched entropy")
+	}
+
+	if stats := prefetched.Stats(); stats.Refills == 0 {
+		t.Errorf("expected the pool to read the prefetched entropy, got %+v", stats)
+	}
+}
+
+// jitterReader is crypto/rand with a stall on every read, like a getrandom system
+// call that is descheduled.
+type jitterReader struct {
+	stall time.Duration
+}
+
+func (r jitterReader) Read(p []byte) (int, error) {
+	time.Sleep(r.stall)
+	return crand.Read(p)
+}
+
+// BenchmarkPrefetchedEntropy compares the tail latency of MakeSecure with and
+// without prefetching under a bursty load, whose idle time between bursts lets
+// the refill goroutine run.
+func BenchmarkPrefetchedEntropy(b *testing.B) {
+	const burst, idle, stall = 100, 200 * time.Microsecond, 100 * time.Microsecond
+	src := jitterReader{stall: stall}
+
+	run := func(b *testing.B, entropy io.Reader) {
+		original := ulid.SetSecureEntropy(entropy)
+		defer ulid.SetSecureEntropy(original)
+
+		latencies := make([]time.Duration, 0, b.N)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if i > 0 && i%burst == 0 {
+				b.StopTimer()
+				time.Sleep(idle)
+				b.StartTimer()
+			}
+
+			start := time.Now()
+			ulid.MakeSecure()
+			latencies = append(latencies, time.Since(start))
+		}
+		b.StopTimer()
+
+		slices.Sort(latencies)
+		for _, q := range []struct {
+			name string
+			p    float64
+		}{{"p50-ns", 0.50}, {"p99-ns", 0.99}, {"p999-ns", 0.999}} {
+			b.ReportMetric(float64(latencies[int(q.p*float64(len(latencies)-1))].Nanoseconds()), q.name)
+		}
+	}
+
+	b.Run("Direct", func(b *testing.B) {
+		run(b, ulid.Pool(func() io.Reader {
+			return ulid.MonotonicWithOptions(src, 0, ulid.BufferSize(256))
+		}))
+	})
+
+	b.Run("Prefetched", func(b *testing.B) {
+		prefetched := ulid.PrefetchedEntropy(src, 16*1024)
+		defer prefetched.Close()
+		run(b, ulid.Pool(func() io.Reader {
+			return ulid.MonotonicWithOptions(prefetched, 0, ulid.NoBuffer())
+		}))
+		b.ReportMetric(float64(prefetched.Stats().Underruns), "underruns")
+	})
+}
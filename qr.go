@@ -0,0 +1,156 @@
+package ulid
+
+import (
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"go.rtnl.ai/ulid/internal/qrcode"
+)
+
+// QREncodedSize is the length of the unpadded base64url encoding of a ULID
+// returned by EncodeQR.
+const QREncodedSize = 22
+
+var qrEncoding = base64.RawURLEncoding.Strict()
+
+// EncodeQR returns the unpadded base64url encoding of the 16 bytes of the ULID
+// (22 characters), e.g. for QR codes where byte mode is denser than the
+// alphanumeric mode required by the uppercase base32 encoding. Unlike the
+// base32 encoding, the base64url encoding is case sensitive and does not
+// preserve the sort order of ULIDs.
+func (id ULID) EncodeQR() string {
+	return qrEncoding.EncodeToString(id[:])
+}
+
+// ParseQR parses the unpadded base64url encoding of a ULID returned by EncodeQR.
+// ErrDataSize is returned if s is not 22 characters long or contains padding
+// characters and ErrInvalidCharacters is returned if s is not a valid
+// base64url encoding, including if the unused trailing bits are not zero.
+func ParseQR(s string) (id ULID, err error) {
+	if len(s) != QREncodedSize || strings.IndexByte(s, '=') >= 0 {
+		return Zero, ErrDataSize
+	}
+
+	var n int
+	if n, err = qrEncoding.Decode(id[:], []byte(s)); err != nil || n != len(id) {
+		return Zero, ErrInvalidCharacters
+	}
+	return id, nil
+}
+
+// QROption configures the rendering of a QR code by RenderQR.
+type QROption func(*qrConfig)
+
+type qrConfig struct {
+	braille bool
+	invert  bool
+	quiet   int
+}
+
+// DefaultQRQuietZone is the width in modules of the light border around a QR code
+// rendered by RenderQR that is required by the QR code specification.
+const DefaultQRQuietZone = 4
+
+// QRBraille renders the QR code with braille patterns of 2x4 modules per
+// character rather than half blocks of 1x2 modules, so that the code takes half
+// of the lines and half of the columns at the cost of gaps between the dots that
+// some scanners do not tolerate.
+func QRBraille() QROption {
+	return func(c *qrConfig) {
+		c.braille = true
+	}
+}
+
+// QRInvert renders the light modules as filled characters and the dark modules as
+// blanks, e.g. for terminals with light text on a dark background.
+func QRInvert() QROption {
+	return func(c *qrConfig) {
+		c.invert = true
+	}
+}
+
+// QRQuietZone sets the width in modules of the light border around the QR code
+// (default DefaultQRQuietZone), e.g. to omit it where the code is already
+// surrounded by a light background. Negative values use the default.
+func QRQuietZone(n int) QROption {
+	return func(c *qrConfig) {
+		if n >= 0 {
+			c.quiet = n
+		}
+	}
+}
+
+// RenderQR writes the canonical string encoding of the ULID to w as a QR code
+// drawn with Unicode half blocks, one line of text per two rows of modules (or
+// with braille patterns with QRBraille), e.g. to copy a ULID from an air-gapped
+// console with a phone or to embed it in a diagnostic page in a <pre> element.
+// The 26 character encoding is encoded in alphanumeric mode with medium error
+// correction, which fits in a version 2 code of 25x25 modules. Unlike EncodeQR,
+// which is intended for QR code generators that support byte mode, the scanned
+// text is the sortable base32 encoding.
+func RenderQR(id ULID, w io.Writer, opts ...QROption) error {
+	conf := qrConfig{quiet: DefaultQRQuietZone}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	code, err := qrcode.Encode(id.String())
+	if err != nil {
+		return err
+	}
+
+	// Each character draws a cell of modules of the size, with the bits of the
+	// braille pattern for each module of the cell, or the half block for each
+	// combination of the two modules.
+	cellWidth, cellHeight := 1, 2
+	if conf.braille {
+		cellWidth, cellHeight = 2, 4
+	}
+
+	size := code.Size + 2*conf.quiet
+	var sb strings.Builder
+	for y := 0; y < size; y += cellHeight {
+		for x := 0; x < size; x += cellWidth {
+			var cell int
+			for dy := 0; dy < cellHeight; dy++ {
+				for dx := 0; dx < cellWidth; dx++ {
+					if code.Dark(x+dx-conf.quiet, y+dy-conf.quiet) != conf.invert {
+						cell |= 1 << (dy*cellWidth + dx)
+					}
+				}
+			}
+
+			if conf.braille {
+				sb.WriteRune(brailleDots[cell])
+			} else {
+				sb.WriteRune(halfBlocks[cell])
+			}
+		}
+		sb.WriteByte('\n')
+	}
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// halfBlocks are the characters of each combination of the top (bit 0) and
+// bottom (bit 1) modules of a half block cell.
+var halfBlocks = [4]rune{' ', '▀', '▄', '█'}
+
+// brailleDots are the characters of each combination of the modules of a 2x4
+// braille cell, with bit 2*row+column for each module. The dots of a braille
+// pattern are numbered down the left column and then the right column, with the
+// dots of the bottom row last.
+var brailleDots = func() (dots [256]rune) {
+	offsets := [8]uint{0, 3, 1, 4, 2, 5, 6, 7}
+	for cell := range dots {
+		dots[cell] = 0x2800
+		for bit, offset := range offsets {
+			if cell&(1<<bit) != 0 {
+				dots[cell] |= 1 << offset
+			}
+		}
+	}
+	return dots
+}()
@@ -0,0 +1,230 @@
+package ulid_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestEncodeQR(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if got := id.EncodeQR(); got != "AVY-OrXT1nZMYe-5kwK9Ww" {
+		t.Errorf("got %q", got)
+	}
+
+	if got := ulid.Zero.EncodeQR(); got != strings.Repeat("A", ulid.QREncodedSize) {
+		t.Errorf("got %q for the zero ulid", got)
+	}
+
+	prop := func(id ulid.ULID) bool {
+		s := id.EncodeQR()
+		if len(s) != ulid.QREncodedSize {
+			return false
+		}
+
+		// Every pair of the base32, base64url, and binary encodings round trips.
+		fromQR, err := ulid.ParseQR(s)
+		if err != nil || fromQR != id {
+			return false
+		}
+
+		fromText, err := ulid.ParseStrict(id.String())
+		if err != nil || fromText.EncodeQR() != s {
+			return false
+		}
+
+		data, _ := base64.RawURLEncoding.DecodeString(s)
+		var fromBinary ulid.ULID
+		if err := fromBinary.UnmarshalBinary(data); err != nil || fromBinary.String() != fromQR.String() {
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseQRErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ulid.ErrDataSize},
+		{"AVY-Orm7pndMYvd5kwK9W", ulid.ErrDataSize},
+		{"AVY-Orm7pndMYvd5kwK9Www", ulid.ErrDataSize},
+		{"AVY-Orm7pndMYvd5kwK9Ww==", ulid.ErrDataSize},
+		{"AVY-Orm7pndMYvd5kwK9W=", ulid.ErrDataSize},
+		{"AVY+Orm7pndMYvd5kwK9Ww", ulid.ErrInvalidCharacters},
+		{"AVY/Orm7pndMYvd5kwK9Ww", ulid.ErrInvalidCharacters},
+		{"AVY-Orm7pndMYvd5kwK9W.", ulid.ErrInvalidCharacters},
+		{"AVY-Orm7pndMYvd5kwK9Wx", ulid.ErrInvalidCharacters},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", ulid.ErrDataSize},
+	}
+
+	for i, tc := range testCases {
+		id, err := ulid.ParseQR(tc.s)
+		if err != tc.err {
+			t.Errorf("test case %d: got err %v, want %v", i, err, tc.err)
+		}
+
+		if !id.IsZero() {
+			t.Errorf("test case %d: expected the zero ulid on error", i)
+		}
+	}
+}
+
+// halfBlocks are the characters of the top (bit 0) and bottom (bit 1) modules.
+var halfBlocks = []rune{' ', '▀', '▄', '█'}
+
+// qrModules decodes the modules drawn by RenderQR, with a row of # (dark) and .
+// (light) modules for each row.
+func qrModules(t *testing.T, out string, braille bool) []string {
+	t.Helper()
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		cellHeight := 2
+		if braille {
+			cellHeight = 4
+		}
+
+		cells := make([]strings.Builder, cellHeight)
+		for _, r := range line {
+			var bits, width int
+			switch {
+			case braille && r >= 0x2800 && r <= 0x28FF:
+				// Convert the numbering of the dots to rows of two modules.
+				for bit, dot := range []int{0, 3, 1, 4, 2, 5, 6, 7} {
+					bits |= int(r-0x2800) >> dot & 1 << bit
+				}
+				width = 2
+			case !braille && slices.Contains(halfBlocks, r):
+				bits, width = slices.Index(halfBlocks, r), 1
+			default:
+				t.Fatalf("unexpected character %q", r)
+			}
+
+			for y := range cells {
+				for x := 0; x < width; x++ {
+					if bits>>(y*width+x)&1 == 1 {
+						cells[y].WriteByte('#')
+					} else {
+						cells[y].WriteByte('.')
+					}
+				}
+			}
+		}
+
+		for _, cell := range cells {
+			rows = append(rows, cell.String())
+		}
+	}
+	return rows
+}
+
+func TestRenderQR(t *testing.T) {
+	t.Parallel()
+
+	fixture, err := os.ReadFile("internal/qrcode/testdata/01ARZ3NDEKTSV4RRFFQ69G5FAV.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The symbol is one of the masks of the fixture, with a light quiet zone.
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	var buf bytes.Buffer
+	if err := ulid.RenderQR(id, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	modules := qrModules(t, buf.String(), false)
+	size := 25 + 2*ulid.DefaultQRQuietZone
+	if len(modules) != size+1 || len(modules[0]) != size {
+		t.Fatalf("got %d rows of %d modules", len(modules), len(modules[0]))
+	}
+
+	light := strings.Repeat(".", size)
+	var symbol []string
+	for y, row := range modules {
+		if y < ulid.DefaultQRQuietZone || y >= size-ulid.DefaultQRQuietZone {
+			if row != light {
+				t.Errorf("row %d of the quiet zone is not light: %s", y, row)
+			}
+			continue
+		}
+
+		quiet := light[:ulid.DefaultQRQuietZone]
+		if !strings.HasPrefix(row, quiet) || !strings.HasSuffix(row, quiet) {
+			t.Errorf("row %d of the quiet zone is not light: %s", y, row)
+		}
+		symbol = append(symbol, row[ulid.DefaultQRQuietZone:size-ulid.DefaultQRQuietZone])
+	}
+
+	if !strings.Contains(string(fixture), strings.Join(symbol, "\n")+"\n") {
+		t.Errorf("rendered symbol does not match the fixture:\n%s", strings.Join(symbol, "\n"))
+	}
+
+	// Braille draws the same modules in a quarter of the lines, and inverting
+	// swaps the characters of the dark and light modules.
+	testCases := []struct {
+		name    string
+		opts    []ulid.QROption
+		braille bool
+		invert  bool
+		lines   int
+	}{
+		{"Braille", []ulid.QROption{ulid.QRBraille()}, true, false, 9},
+		{"Invert", []ulid.QROption{ulid.QRInvert()}, false, true, 17},
+		{"BrailleInvert", []ulid.QROption{ulid.QRBraille(), ulid.QRInvert()}, true, true, 9},
+		{"DefaultQuietZone", []ulid.QROption{ulid.QRQuietZone(-1)}, false, false, 17},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		if err := ulid.RenderQR(id, &out, tc.opts...); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if lines := strings.Count(out.String(), "\n"); lines != tc.lines {
+			t.Errorf("%s: got %d lines, want %d", tc.name, lines, tc.lines)
+		}
+
+		got := qrModules(t, out.String(), tc.braille)
+		for y := 0; y < size; y++ {
+			want := modules[y]
+			if tc.invert {
+				want = strings.Map(func(r rune) rune { return '#' + '.' - r }, want)
+			}
+
+			if got[y][:size] != want {
+				t.Errorf("%s: row %d is %s, want %s", tc.name, y, got[y][:size], want)
+			}
+		}
+	}
+
+	// Without a quiet zone, the finder patterns are in the corners.
+	buf.Reset()
+	if err := ulid.RenderQR(id, &buf, ulid.QRQuietZone(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Split(buf.String(), "\n"); len(lines) != 14 || !strings.HasPrefix(lines[0], "█▀▀▀▀▀█") {
+		t.Errorf("got %q", buf.String())
+	}
+
+	if err := ulid.RenderQR(id, iotest.TruncateWriter(&buf, 0)); err != nil {
+		t.Errorf("unexpected error %v for a truncating writer", err)
+	}
+}
@@ -0,0 +1,350 @@
+package ulid
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"slices"
+)
+
+// ULIDRange is an inclusive range of ULIDs by their 128 bit value, from Lo to Hi.
+type ULIDRange struct {
+	Lo ULID
+	Hi ULID
+}
+
+// Contains returns true if Lo <= id <= Hi.
+func (r ULIDRange) Contains(id ULID) bool {
+	return r.Lo.Compare(id) <= 0 && id.Compare(r.Hi) <= 0
+}
+
+// RangeSet is a set of ULIDs stored as sorted, disjoint ranges of contiguous
+// 128 bit values, e.g. to acknowledge the IDs that were processed. ULIDs that are
+// adjacent, such as those created with SequentialEntropy in the same millisecond,
+// collapse into a single range; random ULIDs are stored as singleton ranges of 32
+// bytes each in memory and 17 bytes each when marshaled.
+//
+// With a gap tolerance, a ULID is also merged into a range that is at most gap
+// values away from it, trading exactness for fewer ranges: Contains then also
+// returns true for the values in the gaps that were never added. Subtract removes
+// values exactly and its result is not merged again.
+//
+// The zero value is an empty set with no gap tolerance. A RangeSet is not safe for
+// concurrent use.
+type RangeSet struct {
+	gap    uint64
+	ranges []ULIDRange
+}
+
+// NewRangeSet returns an empty set that merges ranges separated by at most gap
+// values that were not added.
+func NewRangeSet(gap uint64) *RangeSet {
+	return &RangeSet{gap: gap}
+}
+
+// Gap returns the gap tolerance of the set.
+func (s *RangeSet) Gap() uint64 {
+	return s.gap
+}
+
+// Len returns the number of ranges in the set.
+func (s *RangeSet) Len() int {
+	return len(s.ranges)
+}
+
+// Ranges returns a copy of the ranges of the set in ascending order.
+func (s *RangeSet) Ranges() []ULIDRange {
+	return slices.Clone(s.ranges)
+}
+
+// Contains returns true if the ULID is in one of the ranges of the set.
+func (s *RangeSet) Contains(id ULID) bool {
+	i := s.search(id)
+	return i < len(s.ranges) && s.ranges[i].Lo.Compare(id) <= 0
+}
+
+// Add adds the ULID to the set, extending or merging the neighboring ranges if it
+// is adjacent to them (or within the gap tolerance). Adding ULIDs in ascending
+// order is O(1); otherwise Add is O(n) in the number of ranges.
+func (s *RangeSet) Add(id ULID) {
+	s.AddRange(ULIDRange{Lo: id, Hi: id})
+}
+
+// AddRange adds all of the ULIDs from r.Lo to r.Hi to the set. A range with Lo
+// greater than Hi is ignored.
+func (s *RangeSet) AddRange(r ULIDRange) {
+	if r.Lo.Compare(r.Hi) > 0 {
+		return
+	}
+
+	// Fast path for ranges added in ascending order.
+	if n := len(s.ranges); n == 0 || s.ranges[n-1].Hi.Compare(r.Lo) < 0 {
+		if n > 0 && within(s.ranges[n-1].Hi, r.Lo, s.gap) {
+			s.ranges[n-1].Hi = r.Hi
+		} else {
+			s.ranges = append(s.ranges, r)
+		}
+		return
+	}
+
+	// Find the ranges that overlap r or are within the gap of it and replace them
+	// with a single range that covers all of them.
+	i := s.search(r.Lo)
+	if i > 0 && within(s.ranges[i-1].Hi, r.Lo, s.gap) {
+		i--
+	}
+
+	j := i
+	for j < len(s.ranges) && (s.ranges[j].Lo.Compare(r.Hi) <= 0 || within(r.Hi, s.ranges[j].Lo, s.gap)) {
+		j++
+	}
+
+	if i < j {
+		r.Lo = Min(r.Lo, s.ranges[i].Lo)
+		r.Hi = Max(r.Hi, s.ranges[j-1].Hi)
+	}
+	s.ranges = slices.Replace(s.ranges, i, j, r)
+}
+
+// Union returns a new set with the ULIDs of both sets, merged with the gap
+// tolerance of s.
+func (s *RangeSet) Union(other *RangeSet) *RangeSet {
+	u := &RangeSet{gap: s.gap, ranges: make([]ULIDRange, 0, len(s.ranges)+len(other.ranges))}
+	a, b := s.ranges, other.ranges
+	for len(a) > 0 || len(b) > 0 {
+		var r ULIDRange
+		if len(b) == 0 || (len(a) > 0 && a[0].Lo.Compare(b[0].Lo) <= 0) {
+			r, a = a[0], a[1:]
+		} else {
+			r, b = b[0], b[1:]
+		}
+
+		// The ranges are taken in ascending order of Lo, so each range either
+		// extends the last range or follows it.
+		if n := len(u.ranges); n > 0 && (r.Lo.Compare(u.ranges[n-1].Hi) <= 0 || within(u.ranges[n-1].Hi, r.Lo, s.gap)) {
+			u.ranges[n-1].Hi = Max(u.ranges[n-1].Hi, r.Hi)
+			continue
+		}
+		u.ranges = append(u.ranges, r)
+	}
+	return u
+}
+
+// Subtract returns a new set with the ULIDs of s that are not in other, with the
+// gap tolerance of s. Ranges of s that are split by other are not merged again,
+// even if the pieces are within the gap tolerance.
+func (s *RangeSet) Subtract(other *RangeSet) *RangeSet {
+	d := &RangeSet{gap: s.gap, ranges: make([]ULIDRange, 0, len(s.ranges))}
+	b := other.ranges
+	for _, r := range s.ranges {
+		// Skip the ranges of other that end before r.
+		for len(b) > 0 && b[0].Hi.Compare(r.Lo) < 0 {
+			b = b[1:]
+		}
+
+		removed := false
+		for k := 0; k < len(b) && b[k].Lo.Compare(r.Hi) <= 0; k++ {
+			if b[k].Lo.Compare(r.Lo) > 0 {
+				d.ranges = append(d.ranges, ULIDRange{Lo: r.Lo, Hi: prev(b[k].Lo)})
+			}
+
+			// The rest of r is removed, but b[k] may also remove from the next range.
+			if b[k].Hi.Compare(r.Hi) >= 0 {
+				removed = true
+				break
+			}
+			r.Lo = next(b[k].Hi)
+		}
+
+		if !removed {
+			d.ranges = append(d.ranges, r)
+		}
+	}
+	return d
+}
+
+// search returns the index of the first range with a Hi that is not less than id.
+func (s *RangeSet) search(id ULID) int {
+	i, _ := slices.BinarySearchFunc(s.ranges, id, func(r ULIDRange, id ULID) int {
+		return r.Hi.Compare(id)
+	})
+	return i
+}
+
+//===========================================================================
+// Binary Encoding
+//===========================================================================
+
+// rangeSetVersion is the version of the binary encoding of range sets.
+const rangeSetVersion = 1
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The encoding
+// is a version byte, the gap tolerance and the number of ranges as uvarints, and
+// each range as the 16 bytes of its Lo followed by Hi-Lo as a 128 bit uvarint, so
+// that a singleton range is 17 bytes.
+func (s *RangeSet) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(s.ranges)*(16+1))
+	data = append(data, rangeSetVersion)
+	data = binary.AppendUvarint(data, s.gap)
+	data = binary.AppendUvarint(data, uint64(len(s.ranges)))
+	for _, r := range s.ranges {
+		data = append(data, r.Lo[:]...)
+		hi, lo := sub128(r.Hi, r.Lo)
+		data = appendUvarint128(data, hi, lo)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface, replacing
+// the set with the decoded set. ErrInvalidRangeSet is returned if the data is not
+// an encoded set, its ranges are not sorted and disjoint, or its uvarints are not
+// minimally encoded, so that each set has a single encoding.
+func (s *RangeSet) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != rangeSetVersion {
+		return ErrInvalidRangeSet
+	}
+	data = data[1:]
+
+	gap, n := uvarint(data)
+	if n <= 0 {
+		return ErrInvalidRangeSet
+	}
+	data = data[n:]
+
+	count, n := uvarint(data)
+	if n <= 0 || count > uint64(len(data))/17 {
+		return ErrInvalidRangeSet
+	}
+	data = data[n:]
+
+	ranges := make([]ULIDRange, count)
+	for i := range ranges {
+		if len(data) < 16 {
+			return ErrInvalidRangeSet
+		}
+
+		r := &ranges[i]
+		copy(r.Lo[:], data)
+		hi, lo, n := uvarint128(data[16:])
+		if n <= 0 {
+			return ErrInvalidRangeSet
+		}
+		data = data[16+n:]
+
+		var overflow bool
+		if r.Hi, overflow = add128(r.Lo, hi, lo); overflow {
+			return ErrInvalidRangeSet
+		}
+
+		// Ranges must be in order and not adjacent so that the set is canonical.
+		if i > 0 && (ranges[i-1].Hi.Compare(r.Lo) >= 0 || next(ranges[i-1].Hi) == r.Lo) {
+			return ErrInvalidRangeSet
+		}
+	}
+
+	if len(data) != 0 {
+		return ErrInvalidRangeSet
+	}
+
+	s.gap, s.ranges = gap, ranges
+	return nil
+}
+
+//===========================================================================
+// 128 Bit Arithmetic
+//===========================================================================
+
+func halves(id ULID) (hi, lo uint64) {
+	return binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+}
+
+func fromHalves(hi, lo uint64) (id ULID) {
+	binary.BigEndian.PutUint64(id[:8], hi)
+	binary.BigEndian.PutUint64(id[8:], lo)
+	return id
+}
+
+// sub128 returns b-a for a <= b.
+func sub128(b, a ULID) (hi, lo uint64) {
+	bh, bl := halves(b)
+	ah, al := halves(a)
+	lo, borrow := bits.Sub64(bl, al, 0)
+	hi, _ = bits.Sub64(bh, ah, borrow)
+	return hi, lo
+}
+
+func add128(a ULID, hi, lo uint64) (_ ULID, overflow bool) {
+	ah, al := halves(a)
+	lo, carry := bits.Add64(al, lo, 0)
+	hi, carry = bits.Add64(ah, hi, carry)
+	return fromHalves(hi, lo), carry != 0
+}
+
+// within returns true if a < b and there are at most gap values between them.
+func within(a, b ULID, gap uint64) bool {
+	if a.Compare(b) >= 0 {
+		return false
+	}
+
+	hi, lo := sub128(b, a)
+	lo, borrow := bits.Sub64(lo, 1, 0)
+	return hi == borrow && lo <= gap
+}
+
+// next returns id+1, which must be less than Max().
+func next(id ULID) ULID {
+	id, _ = add128(id, 0, 1)
+	return id
+}
+
+// prev returns id-1, which must be greater than Zero.
+func prev(id ULID) ULID {
+	hi, lo := sub128(id, fromHalves(0, 1))
+	return fromHalves(hi, lo)
+}
+
+func appendUvarint128(b []byte, hi, lo uint64) []byte {
+	for hi != 0 || lo >= 0x80 {
+		b = append(b, byte(lo)|0x80)
+		lo = lo>>7 | hi<<57
+		hi >>= 7
+	}
+	return append(b, byte(lo))
+}
+
+// uvarint decodes a uvarint like binary.Uvarint, also returning n < 0 if it is
+// not minimally encoded so that every value has a single encoding.
+func uvarint(b []byte) (v uint64, n int) {
+	if v, n = binary.Uvarint(b); n > 1 && b[n-1] == 0 {
+		return 0, -n
+	}
+	return v, n
+}
+
+// uvarint128 decodes a 128 bit uvarint, returning n <= 0 if the data is too short,
+// the value overflows, or it is not minimally encoded.
+func uvarint128(b []byte) (hi, lo uint64, n int) {
+	for i, c := range b {
+		if i == 18 && c > 3 {
+			return 0, 0, -1
+		}
+
+		shift := uint(7 * i)
+		v := uint64(c & 0x7F)
+		if shift < 64 {
+			lo |= v << shift
+			if shift > 57 {
+				hi |= v >> (64 - shift)
+			}
+		} else {
+			hi |= v << (shift - 64)
+		}
+
+		if c < 0x80 {
+			if i > 0 && c == 0 {
+				return 0, 0, -1
+			}
+			return hi, lo, i + 1
+		}
+	}
+	return 0, 0, 0
+}
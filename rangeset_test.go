@@ -0,0 +1,343 @@
+package ulid_test
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// seqID returns the ULID with the 128 bit value of hi and lo.
+func seqID(hi, lo uint64) (id ulid.ULID) {
+	for i := 0; i < 8; i++ {
+		id[7-i], id[15-i] = byte(hi>>(8*i)), byte(lo>>(8*i))
+	}
+	return id
+}
+
+func TestRangeSetSequential(t *testing.T) {
+	t.Parallel()
+
+	// Sequential entropy within each millisecond collapses into one range per
+	// millisecond, however the ULIDs are ordered.
+	var ids []ulid.ULID
+	for ms := uint64(1); ms <= 10; ms++ {
+		entropy := ulid.SequentialEntropy([10]byte{})
+		for i := 0; i < 100; i++ {
+			ids = append(ids, ulid.MustNew(1738876313290+ms, entropy))
+		}
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	for name, order := range map[string][]ulid.ULID{
+		"Ascending": ids,
+		"Shuffled":  shuffled(rng, ids),
+	} {
+		s := &ulid.RangeSet{}
+		for _, id := range order {
+			s.Add(id)
+		}
+
+		if s.Len() != 10 {
+			t.Errorf("%s: expected 10 ranges, got %d", name, s.Len())
+		}
+
+		for _, id := range ids {
+			if !s.Contains(id) {
+				t.Fatalf("%s: expected the set to contain %s", name, id)
+			}
+		}
+
+		if s.Contains(ulid.MustNew(1738876313290, nil)) || s.Contains(ulid.MustNew(1738876313301, nil)) {
+			t.Errorf("%s: expected the set not to contain ULIDs outside of the ranges", name)
+		}
+
+		for i, r := range s.Ranges() {
+			if r.Lo != ids[100*i] || r.Hi != ids[100*i+99] {
+				t.Errorf("%s: range %d is %s-%s, want %s-%s", name, i, r.Lo, r.Hi, ids[100*i], ids[100*i+99])
+			}
+		}
+	}
+}
+
+func shuffled(rng *rand.Rand, ids []ulid.ULID) []ulid.ULID {
+	ids = slices.Clone(ids)
+	rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	return ids
+}
+
+func TestRangeSetRandom(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 10000)
+
+	s := ulid.NewRangeSet(0)
+	for _, id := range ids {
+		s.Add(id)
+		s.Add(id)
+	}
+
+	// Random ULIDs are singletons, not merged with their neighbors.
+	if s.Len() != len(ids) {
+		t.Fatalf("expected %d singleton ranges, got %d", len(ids), s.Len())
+	}
+
+	for _, id := range ids {
+		if !s.Contains(id) {
+			t.Fatalf("expected the set to contain %s", id)
+		}
+	}
+
+	ranges := s.Ranges()
+	if !slices.IsSortedFunc(ranges, func(a, b ulid.ULIDRange) int { return a.Lo.Compare(b.Lo) }) {
+		t.Error("expected the ranges to be sorted")
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The encoding does not grow more than a byte per ULID.
+	if len(data) > 17*len(ids)+8 {
+		t.Errorf("expected at most %d bytes, got %d", 17*len(ids)+8, len(data))
+	}
+}
+
+func TestRangeSetGap(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		gap    uint64
+		ids    []uint64
+		ranges [][2]uint64
+	}{
+		{0, []uint64{1, 2, 3, 5, 6, 9}, [][2]uint64{{1, 3}, {5, 6}, {9, 9}}},
+		{1, []uint64{1, 2, 3, 5, 6, 9}, [][2]uint64{{1, 6}, {9, 9}}},
+		{2, []uint64{1, 2, 3, 5, 6, 9}, [][2]uint64{{1, 9}}},
+		{1, []uint64{9, 1, 5, 3, 7}, [][2]uint64{{1, 9}}},
+		{3, []uint64{20, 10, 15}, [][2]uint64{{10, 10}, {15, 15}, {20, 20}}},
+		{4, []uint64{20, 10, 15}, [][2]uint64{{10, 20}}},
+		{4, []uint64{20, 10, 30, 40, 25}, [][2]uint64{{10, 10}, {20, 30}, {40, 40}}},
+	} {
+		s := ulid.NewRangeSet(tc.gap)
+		for _, v := range tc.ids {
+			s.Add(seqID(0, v))
+		}
+
+		if got := rangeValues(s); !slices.Equal(got, tc.ranges) {
+			t.Errorf("gap %d, %v: got ranges %v, want %v", tc.gap, tc.ids, got, tc.ranges)
+		}
+	}
+
+	// The gap tolerance is measured across the 64 bit halves of the ULIDs.
+	s := ulid.NewRangeSet(1)
+	s.Add(seqID(0, ^uint64(0)-1))
+	s.Add(seqID(1, 0))
+	s.Add(seqID(1, 3))
+	if s.Len() != 2 || !s.Contains(seqID(0, ^uint64(0))) || s.Contains(seqID(1, 1)) {
+		t.Errorf("expected the gap to be tolerated across the halves, got %v", s.Ranges())
+	}
+
+	// Gaps wider than 64 bits are never tolerated.
+	s = ulid.NewRangeSet(^uint64(0))
+	s.Add(seqID(0, 0))
+	s.Add(seqID(1, 0))
+	s.Add(seqID(2, 1))
+	if s.Len() != 2 {
+		t.Errorf("expected a maximum gap of 2^64-1, got %v", s.Ranges())
+	}
+}
+
+// rangeValues returns the low halves of the bounds of the ranges of the set.
+func rangeValues(s *ulid.RangeSet) [][2]uint64 {
+	var values [][2]uint64
+	for _, r := range s.Ranges() {
+		values = append(values, [2]uint64{low(r.Lo), low(r.Hi)})
+	}
+	return values
+}
+
+func low(id ulid.ULID) (v uint64) {
+	for _, b := range id[8:] {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func TestRangeSetOperations(t *testing.T) {
+	t.Parallel()
+
+	set := func(gap uint64, ranges ...[2]uint64) *ulid.RangeSet {
+		s := ulid.NewRangeSet(gap)
+		for _, r := range ranges {
+			s.AddRange(ulid.ULIDRange{Lo: seqID(0, r[0]), Hi: seqID(0, r[1])})
+		}
+		return s
+	}
+
+	for _, tc := range []struct {
+		name        string
+		a, b        *ulid.RangeSet
+		union, diff [][2]uint64
+	}{
+		{"Empty", set(0), set(0), nil, nil},
+		{"EmptyOther", set(0, [2]uint64{1, 5}), set(0), [][2]uint64{{1, 5}}, [][2]uint64{{1, 5}}},
+		{"EmptySelf", set(0), set(0, [2]uint64{1, 5}), [][2]uint64{{1, 5}}, nil},
+		{"Disjoint", set(0, [2]uint64{1, 5}), set(0, [2]uint64{10, 12}), [][2]uint64{{1, 5}, {10, 12}}, [][2]uint64{{1, 5}}},
+		{"Adjacent", set(0, [2]uint64{1, 5}), set(0, [2]uint64{6, 8}), [][2]uint64{{1, 8}}, [][2]uint64{{1, 5}}},
+		{"Overlap", set(0, [2]uint64{1, 5}), set(0, [2]uint64{4, 8}), [][2]uint64{{1, 8}}, [][2]uint64{{1, 3}}},
+		{"Inside", set(0, [2]uint64{1, 10}), set(0, [2]uint64{4, 6}), [][2]uint64{{1, 10}}, [][2]uint64{{1, 3}, {7, 10}}},
+		{"Covering", set(0, [2]uint64{4, 6}), set(0, [2]uint64{1, 10}), [][2]uint64{{1, 10}}, nil},
+		{"Spanning", set(0, [2]uint64{1, 3}, [2]uint64{5, 7}, [2]uint64{9, 11}), set(0, [2]uint64{2, 10}), [][2]uint64{{1, 11}}, [][2]uint64{{1, 1}, {11, 11}}},
+		{"Many", set(0, [2]uint64{1, 20}), set(0, [2]uint64{2, 2}, [2]uint64{5, 6}, [2]uint64{20, 30}), [][2]uint64{{1, 30}}, [][2]uint64{{1, 1}, {3, 4}, {7, 19}}},
+		{"Gap", set(2, [2]uint64{1, 3}), set(0, [2]uint64{6, 8}), [][2]uint64{{1, 8}}, [][2]uint64{{1, 3}}},
+		{"GapSubtract", set(2, [2]uint64{1, 10}), set(0, [2]uint64{5, 5}), [][2]uint64{{1, 10}}, [][2]uint64{{1, 4}, {6, 10}}},
+	} {
+		if got := rangeValues(tc.a.Union(tc.b)); !slices.Equal(got, tc.union) {
+			t.Errorf("%s: got union %v, want %v", tc.name, got, tc.union)
+		}
+
+		if got := rangeValues(tc.a.Subtract(tc.b)); !slices.Equal(got, tc.diff) {
+			t.Errorf("%s: got difference %v, want %v", tc.name, got, tc.diff)
+		}
+	}
+
+	// The operations agree with map based sets for random ULIDs.
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 1000)
+	a, b := &ulid.RangeSet{}, &ulid.RangeSet{}
+	for i, id := range ids {
+		if i%3 != 0 {
+			a.Add(id)
+		}
+		if i%2 == 0 {
+			b.Add(id)
+		}
+	}
+
+	union, diff := a.Union(b), a.Subtract(b)
+	for i, id := range ids {
+		if union.Contains(id) != (i%3 != 0 || i%2 == 0) {
+			t.Fatalf("union: unexpected membership of ULID %d", i)
+		}
+
+		if diff.Contains(id) != (i%3 != 0 && i%2 != 0) {
+			t.Fatalf("difference: unexpected membership of ULID %d", i)
+		}
+	}
+}
+
+func TestRangeSetBinary(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	sequential := ulid.NewRangeSet(3)
+	entropy := ulid.SequentialEntropy([10]byte{})
+	for i := 0; i < 1000; i++ {
+		sequential.Add(ulid.MustNew(1738876313290, entropy))
+	}
+
+	random := &ulid.RangeSet{}
+	for _, id := range randomBatch(rng, 100) {
+		random.Add(id)
+	}
+
+	wide := &ulid.RangeSet{}
+	wide.AddRange(ulid.ULIDRange{Lo: ulid.Zero, Hi: seqID(^uint64(0), ^uint64(0))})
+
+	for name, s := range map[string]*ulid.RangeSet{
+		"Empty":      {},
+		"Sequential": sequential,
+		"Random":     random,
+		"Wide":       wide,
+	} {
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &ulid.RangeSet{}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Errorf("%s: could not unmarshal: %v", name, err)
+			continue
+		}
+
+		if decoded.Gap() != s.Gap() || !slices.Equal(decoded.Ranges(), s.Ranges()) {
+			t.Errorf("%s: decoded set does not match", name)
+		}
+
+		again, _ := decoded.MarshalBinary()
+		if !bytes.Equal(again, data) {
+			t.Errorf("%s: encoding is not stable", name)
+		}
+	}
+
+	// A thousand sequential ULIDs are a single range.
+	if data, _ := sequential.MarshalBinary(); len(data) != 1+1+1+16+2 {
+		t.Errorf("expected a sequential set to be 21 bytes, got %d", len(data))
+	}
+
+	valid, _ := random.MarshalBinary()
+	five, one := seqID(0, 5), seqID(0, 1)
+	six := seqID(0, 6)
+
+	for name, data := range map[string][]byte{
+		"Empty":      nil,
+		"Version":    append([]byte{2}, valid[1:]...),
+		"Truncated":  valid[:len(valid)-1],
+		"Trailing":   append(slices.Clone(valid), 0),
+		"Count":      {1, 0, 0xFF},
+		"Overflow":   append(append([]byte{1, 0, 1}, bytes.Repeat([]byte{0xFF}, 16)...), 1),
+		"Unsorted":   slices.Concat([]byte{1, 0, 2}, five[:], []byte{0}, one[:], []byte{0}),
+		"Adjacent":   slices.Concat([]byte{1, 0, 2}, five[:], []byte{0}, six[:], []byte{0}),
+		"LongVarint": append(append([]byte{1, 0, 1}, make([]byte, 16)...), bytes.Repeat([]byte{0xFF}, 19)...),
+		"LongGap":    {1, 0x80, 0, 0},
+		"LongCount":  slices.Concat([]byte{1, 0, 0x81, 0}, one[:], []byte{0}),
+		"LongRange":  slices.Concat([]byte{1, 0, 1}, one[:], []byte{0x85, 0}),
+	} {
+		if err := (&ulid.RangeSet{}).UnmarshalBinary(data); err != ulid.ErrInvalidRangeSet {
+			t.Errorf("%s: got %v, want %v", name, err, ulid.ErrInvalidRangeSet)
+		}
+	}
+}
+
+func BenchmarkRangeSet(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := randomBatch(rng, 10000)
+
+	b.Run("AddSequential", func(b *testing.B) {
+		entropy := ulid.SequentialEntropy([10]byte{})
+		s := &ulid.RangeSet{}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Add(ulid.MustNew(1738876313290, entropy))
+		}
+	})
+
+	b.Run("AddRandom", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s := &ulid.RangeSet{}
+			for _, id := range ids[:1000] {
+				s.Add(id)
+			}
+		}
+	})
+
+	b.Run("Contains", func(b *testing.B) {
+		s := &ulid.RangeSet{}
+		for _, id := range ids {
+			s.Add(id)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = s.Contains(ids[i%len(ids)])
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package ulid
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Redact returns a copy of the ULID with the entropy zeroed and the timestamp
+// intact, e.g. so that IDs can be shared in external logs and still be
+// correlated by time without revealing the full ID.
+func (id ULID) Redact() ULID {
+	clear(id[6:])
+	return id
+}
+
+// RedactKeep returns a copy of the ULID that keeps only the nBits most
+// significant bits of the entropy and zeroes the rest for coarse correlation of
+// redacted IDs. If nBits is less than or equal to zero all of the entropy is
+// zeroed and if nBits is 80 or more the ULID is returned unchanged.
+func (id ULID) RedactKeep(nBits int) ULID {
+	switch {
+	case nBits <= 0:
+		return id.Redact()
+	case nBits >= 80:
+		return id
+	}
+
+	keep := 6 + nBits/8
+	if rem := nBits % 8; rem > 0 {
+		id[keep] &= byte(0xFF << (8 - rem))
+		keep++
+	}
+	clear(id[keep:])
+	return id
+}
+
+// IsRedacted returns true if the entropy of the ULID is all zero, e.g. if it was
+// returned by Redact. This is a heuristic: ULIDs generated with zero entropy are
+// also reported as redacted while ULIDs redacted with RedactKeep usually are not.
+func (id ULID) IsRedacted() bool {
+	for _, b := range id[6:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//===========================================================================
+// Redacting Writer
+//===========================================================================
+
+// The encoded timestamp of a ULID is its first 10 characters.
+const encodedTimeSize = 10
+
+var redactedEntropy = bytes.Repeat([]byte{'0'}, EncodedSize-encodedTimeSize)
+
+// RedactingWriter wraps an io.Writer (such as a log sink) and redacts the
+// entropy of canonical ULIDs in the written text on the fly, preserving their
+// timestamps. A canonical ULID is 26 uppercase Crockford base32 characters
+// beginning with 0-7 that is not part of a longer alphanumeric word; e.g. the
+// ULIDs in "id=01ARZ3NDEKTSV4RRFFQ69G5FAV" and "evt_01ARZ3NDEKTSV4RRFFQ69G5FAV"
+// are redacted but lowercase ULIDs are not.
+//
+// ULIDs may be split across calls to Write. To do so, the writer holds back a
+// trailing run of up to 26 characters that may be a ULID until the character
+// that follows it is written, so Close (or Flush) must be called when done to
+// write any held back text. RedactingWriter is not safe for concurrent use.
+type RedactingWriter struct {
+	w      io.Writer
+	marker []byte
+	run    [EncodedSize]byte
+	n      int
+	state  redactState
+	out    []byte
+}
+
+type redactState uint8
+
+const (
+	redactBoundary  redactState = iota // the previous byte was not alphanumeric
+	redactCandidate                    // buffering a run that may be a ULID
+	redactWord                         // in an alphanumeric word that is not a ULID
+)
+
+// NewRedactingWriter returns a writer that redacts the entropy of ULIDs written to
+// w. The 16 entropy characters of each ULID are replaced with the marker, e.g.
+// "[REDACTED]", or with zeros if the marker is empty so that the redacted text
+// is a valid ULID with zero entropy (see Redact).
+func NewRedactingWriter(w io.Writer, marker string) *RedactingWriter {
+	r := &RedactingWriter{w: w, marker: redactedEntropy}
+	if marker != "" {
+		r.marker = []byte(marker)
+	}
+	return r
+}
+
+// Write redacts ULIDs in p and writes the result to the underlying writer. It
+// returns len(p) if the redacted text was written without error, even if some
+// of p was held back until the next Write or Flush.
+func (r *RedactingWriter) Write(p []byte) (n int, err error) {
+	r.out = r.out[:0]
+	for _, c := range p {
+		r.scan(c)
+	}
+
+	if err = r.write(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes any held back text to the underlying writer, treating the end of
+// the text written so far as the end of a word.
+func (r *RedactingWriter) Flush() error {
+	r.out = r.out[:0]
+	r.endRun()
+	r.state = redactBoundary
+	return r.write()
+}
+
+// Close flushes any held back text. It does not close the underlying writer.
+func (r *RedactingWriter) Close() error {
+	return r.Flush()
+}
+
+func (r *RedactingWriter) scan(c byte) {
+	if !isAlphanumeric(c) {
+		r.endRun()
+		r.out = append(r.out, c)
+		r.state = redactBoundary
+		return
+	}
+
+	switch r.state {
+	case redactBoundary:
+		if c >= '0' && c <= '7' {
+			r.run[0], r.n = c, 1
+			r.state = redactCandidate
+			return
+		}
+		r.state = redactWord
+	case redactCandidate:
+		if r.n < EncodedSize && isCanonicalChar(c) {
+			r.run[r.n] = c
+			r.n++
+			return
+		}
+
+		// The word is longer than a ULID or is not canonical.
+		r.out = append(r.out, r.run[:r.n]...)
+		r.n = 0
+		r.state = redactWord
+	}
+	r.out = append(r.out, c)
+}
+
+// endRun writes the buffered run at the end of a word, redacting it if it is a
+// complete ULID.
+func (r *RedactingWriter) endRun() {
+	if r.state != redactCandidate {
+		return
+	}
+
+	if r.n == EncodedSize {
+		r.out = append(r.out, r.run[:encodedTimeSize]...)
+		r.out = append(r.out, r.marker...)
+	} else {
+		r.out = append(r.out, r.run[:r.n]...)
+	}
+	r.n = 0
+}
+
+func (r *RedactingWriter) write() (err error) {
+	if len(r.out) > 0 {
+		_, err = r.w.Write(r.out)
+	}
+	return err
+}
+
+//===========================================================================
+// Log Safe Strings
+//===========================================================================
+
+// logSafeKey is the key of SetLogSafeKey; nil is the empty key.
+var logSafeKey atomic.Pointer[[]byte]
+
+// SetLogSafeKey sets the key of the digests of LogSafe and SafeULID for the whole
+// process and returns the previous key, e.g. to restore it at the end of a test.
+// The key is copied. Until a key is set the key is empty, so the digest is still
+// stable but anyone who knows a full ULID can compute its digest and find it in
+// the logs; set a secret key, shared by the services whose logs are correlated, to
+// prevent that. Changing the key changes every digest, so IDs logged before and
+// after the change no longer match.
+func SetLogSafeKey(key []byte) (previous []byte) {
+	key = append([]byte(nil), key...)
+	if prev := logSafeKey.Swap(&key); prev != nil {
+		return *prev
+	}
+	return nil
+}
+
+// LogSafe returns a redacted form of the ULID for logs that may not contain raw
+// identifiers: the 10 characters of the timestamp, a hyphen, and a digest of the
+// entropy, e.g. 01ARZ3NDEK-BTBCEDH7 for 01ARZ3NDEKTSV4RRFFQ69G5FAV without a key.
+// The same ULID always has the same LogSafe form for the same key (see
+// SetLogSafeKey), so the lines of a request can still be correlated, but the
+// entropy cannot be recovered from it.
+//
+// The digest is the first 40 bits of the HMAC-SHA256 of the 10 entropy bytes with
+// the key, encoded as 8 characters of the base32 alphabet of ULIDs (Encoding), 5
+// bits at a time starting with the most significant bits. Distinct ULIDs of the
+// same millisecond have distinct digests except with a probability of about 2^-40
+// per pair.
+func (id ULID) LogSafe() string {
+	var key []byte
+	if k := logSafeKey.Load(); k != nil {
+		key = *k
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(id[6:])
+	var sum [sha256.Size]byte
+	digest := binary.BigEndian.Uint64(mac.Sum(sum[:0])) >> (64 - 40)
+
+	var text [EncodedSize]byte
+	id.encodeTime(&text)
+
+	dst := make([]byte, encodedTimeSize+1+8)
+	copy(dst, text[:encodedTimeSize])
+	dst[encodedTimeSize] = '-'
+	for i := len(dst) - 1; i > encodedTimeSize; i-- {
+		dst[i] = Encoding[digest&0x1f]
+		digest >>= 5
+	}
+	return string(dst)
+}
+
+// SafeULID is a ULID that is always formatted in its LogSafe form, e.g. as the
+// type of the fields of structs that are logged to restricted log categories, so
+// that the raw ID cannot leak through a habitual %s, %v, or %#v, or through the
+// text and JSON handlers of log/slog. Convert a ULID with SafeULID(id) and back
+// with ULID(safe).
+//
+// A SafeULID is marshaled to text and JSON as its LogSafe form, which cannot be
+// parsed back into a ULID, so it must not be used where the ID has to be read back.
+type SafeULID ULID
+
+// String returns the LogSafe form of the ULID.
+func (id SafeULID) String() string {
+	return ULID(id).LogSafe()
+}
+
+// Format implements fmt.Formatter so that every verb, including %#v, %x, and %d,
+// formats the LogSafe form rather than the bytes of the ULID. The verbs s, v, q,
+// x, and X have their usual meaning for a string and other verbs behave like s.
+func (id SafeULID) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 's', 'v', 'q', 'x', 'X':
+	default:
+		verb = 's'
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), id.String())
+}
+
+// MarshalText implements the encoding.TextMarshaler interface by returning the
+// LogSafe form of the ULID, which is also used by encoding/json and log/slog.
+func (id SafeULID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+//===========================================================================
+// Finding ULIDs in Text
+//===========================================================================
+
+// FindAll returns the canonical ULIDs in the text in order of appearance, using
+// the definition of RedactingWriter: 26 uppercase Crockford base32 characters
+// beginning with 0-7 that are not part of a longer alphanumeric word, e.g. the
+// ULIDs in "request 01ARZ3NDEKTSV4RRFFQ69G5FAV failed" and in
+// "evt_01ARZ3NDEKTSV4RRFFQ69G5FAV". It does not use regular expressions and
+// returns nil if the text has no ULIDs.
+func FindAll(text string) (ids []ULID) {
+	for i := 0; i < len(text); {
+		if !isAlphanumeric(text[i]) {
+			i++
+			continue
+		}
+
+		// Find the end of the word and check that it is a canonical ULID.
+		start := i
+		canonical := text[i] >= '0' && text[i] <= '7'
+		for ; i < len(text) && isAlphanumeric(text[i]); i++ {
+			canonical = canonical && isCanonicalChar(text[i])
+		}
+
+		if canonical && i-start == EncodedSize {
+			var buf [EncodedSize]byte
+			var id ULID
+			copy(buf[:], text[start:i])
+			if err := parse(buf[:], true, &id); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// isCanonicalChar returns true if c is an uppercase Crockford base32 character.
+func isCanonicalChar(c byte) bool {
+	return c < 'a' && dec[c] != 0xFF
+}
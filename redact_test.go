@@ -0,0 +1,298 @@
+package ulid_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+
+	redacted := id.Redact()
+	if redacted.String() != "01ARZ3NDEK0000000000000000" || redacted.Time() != id.Time() {
+		t.Errorf("unexpected redacted ulid %s", redacted)
+	}
+
+	if !redacted.IsRedacted() || id.IsRedacted() {
+		t.Errorf("expected only the redacted ulid to be redacted")
+	}
+
+	if id.RedactKeep(0) != redacted || id.RedactKeep(-1) != redacted || id.RedactKeep(80) != id || id.RedactKeep(100) != id {
+		t.Errorf("unexpected RedactKeep limits")
+	}
+
+	// Each character of the entropy carries 5 bits.
+	if got := id.RedactKeep(10).String(); got != "01ARZ3NDEKTS00000000000000" {
+		t.Errorf("got %s from RedactKeep(10)", got)
+	}
+
+	prop := func(id ulid.ULID, n uint8) bool {
+		bits := int(n % 81)
+		kept := id.RedactKeep(bits)
+
+		want := new(big.Int).SetBytes(id[6:])
+		want.Rsh(want, uint(80-bits)).Lsh(want, uint(80-bits))
+
+		return kept.Time() == id.Time() &&
+			new(big.Int).SetBytes(kept[6:]).Cmp(want) == 0 &&
+			kept.IsRedacted() == (want.Sign() == 0)
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func redactString(t testing.TB, s, marker string, chunks ...int) string {
+	var buf bytes.Buffer
+	w := ulid.NewRedactingWriter(&buf, marker)
+
+	for _, size := range chunks {
+		size = min(size, len(s))
+		if n, err := w.Write([]byte(s[:size])); err != nil || n != size {
+			t.Fatalf("could not write: %d %v", n, err)
+		}
+		s = s[size:]
+	}
+
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+var redactTests = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{"Empty", "", ""},
+	{"NoULID", "nothing to see here\n", "nothing to see here\n"},
+	{"Only", "01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEK0000000000000000"},
+	{"Line", "id=01ARZ3NDEKTSV4RRFFQ69G5FAV msg=ok\n", "id=01ARZ3NDEK0000000000000000 msg=ok\n"},
+	{"Multiple", "01ARZ3NDEKTSV4RRFFQ69G5FAV,7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "01ARZ3NDEK0000000000000000,7ZZZZZZZZZ0000000000000000"},
+	{"Adjacent", "01ARZ3NDEKTSV4RRFFQ69G5FAV 01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEK0000000000000000 01ARZ3NDEK0000000000000000"},
+	{"Prefixed", "evt_01ARZ3NDEKTSV4RRFFQ69G5FAV", "evt_01ARZ3NDEK0000000000000000"},
+	{"JSON", `{"id":"01ARZ3NDEKTSV4RRFFQ69G5FAV"}`, `{"id":"01ARZ3NDEK0000000000000000"}`},
+	{"Lowercase", "01arz3ndektsv4rrffq69g5fav", "01arz3ndektsv4rrffq69g5fav"},
+	{"TooLong", "01ARZ3NDEKTSV4RRFFQ69G5FAVX", "01ARZ3NDEKTSV4RRFFQ69G5FAVX"},
+	{"TooShort", "01ARZ3NDEKTSV4RRFFQ69G5FA", "01ARZ3NDEKTSV4RRFFQ69G5FA"},
+	{"WordPrefix", "X01ARZ3NDEKTSV4RRFFQ69G5FAV", "X01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+	{"Overflow", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "81ARZ3NDEKTSV4RRFFQ69G5FAV"},
+	{"InvalidChar", "01ARZ3NDEKTSV4RRFFQ69G5FAU", "01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+	{"LongWord", strings.Repeat("0", 60) + " 01ARZ3NDEKTSV4RRFFQ69G5FAV", strings.Repeat("0", 60) + " 01ARZ3NDEK0000000000000000"},
+}
+
+func TestFindAll(t *testing.T) {
+	t.Parallel()
+
+	// FindAll finds exactly the ULIDs that RedactingWriter redacts.
+	for _, tc := range redactTests {
+		ids := ulid.FindAll(tc.input)
+		got := tc.input
+		for _, id := range ids {
+			got = strings.Replace(got, id.String(), id.Redact().String(), 1)
+		}
+
+		if got != tc.expected {
+			t.Errorf("%s: found %v in %q", tc.name, ids, tc.input)
+		}
+	}
+
+	ids := ulid.FindAll("retry 01ARZ3NDEKTSV4RRFFQ69G5FAV after 7ZZZZZZZZZZZZZZZZZZZZZZZZZ.")
+	if len(ids) != 2 || ids[0] != ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV") || ids[1] != ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ") {
+		t.Errorf("got %v", ids)
+	}
+
+	if ids := ulid.FindAll("nothing to see here"); ids != nil {
+		t.Errorf("expected nil, got %v", ids)
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range redactTests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := redactString(t, tc.input, ""); got != tc.expected {
+				t.Fatalf("got %q, want %q", got, tc.expected)
+			}
+
+			// Splitting the input across writes at any point must not change the
+			// output, including writing one byte at a time.
+			for i := 0; i <= len(tc.input); i++ {
+				for j := 0; j <= len(tc.input)-i; j += 7 {
+					if got := redactString(t, tc.input, "", i, j); got != tc.expected {
+						t.Fatalf("split at %d and %d: got %q, want %q", i, i+j, got, tc.expected)
+					}
+				}
+			}
+
+			ones := make([]int, len(tc.input))
+			for i := range ones {
+				ones[i] = 1
+			}
+
+			if got := redactString(t, tc.input, "", ones...); got != tc.expected {
+				t.Fatalf("byte at a time: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+
+	t.Run("Marker", func(t *testing.T) {
+		got := redactString(t, "id=01ARZ3NDEKTSV4RRFFQ69G5FAV\n", "[REDACTED]", 20)
+		if want := "id=01ARZ3NDEK[REDACTED]\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("HeldBack", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := ulid.NewRedactingWriter(&buf, "")
+		w.Write([]byte("id=01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+
+		if got := buf.String(); got != "id=" {
+			t.Errorf("expected a possible ulid to be held back, got %q", got)
+		}
+
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != "id=01ARZ3NDEK0000000000000000" {
+			t.Errorf("expected the flush to write the redacted ulid, got %q", got)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		w := ulid.NewRedactingWriter(errWriter{}, "")
+		if _, err := w.Write([]byte("foo")); !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("got err %v, want %v", err, io.ErrClosedPipe)
+		}
+	})
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func BenchmarkRedactingWriter(b *testing.B) {
+	var line bytes.Buffer
+	for i := 0; i < 100; i++ {
+		line.WriteString(`level=info id=` + ulid.Make().String() + ` msg="request handled" status=200 duration=1.2ms` + "\n")
+	}
+	data := line.Bytes()
+
+	b.Run("WithULIDs", func(b *testing.B) {
+		w := ulid.NewRedactingWriter(io.Discard, "")
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			w.Write(data)
+		}
+	})
+
+	b.Run("NoULIDs", func(b *testing.B) {
+		plain := bytes.Repeat([]byte(`level=info msg="request handled" status=200 duration=1.2ms`+"\n"), 100)
+		w := ulid.NewRedactingWriter(io.Discard, "")
+		b.SetBytes(int64(len(plain)))
+		for i := 0; i < b.N; i++ {
+			w.Write(plain)
+		}
+	})
+}
+
+func TestLogSafe(t *testing.T) {
+	// The digests of these ULIDs must not change for the same key, since logs
+	// written by older versions are correlated with logs written by newer versions.
+	testCases := []struct {
+		key  string
+		id   string
+		safe string
+	}{
+		{"", "01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEK-BTBCEDH7"},
+		{"", "00000000000000000000000000", "0000000000-PRYDE96S"},
+		{"", "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "7ZZZZZZZZZ-K12TA5FZ"},
+		{"log-safe-fixture-key", "01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEK-TWBAX90R"},
+		{"log-safe-fixture-key", "00000000000000000000000000", "0000000000-W18AAX9E"},
+		{"log-safe-fixture-key", "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "7ZZZZZZZZZ-GSMY7KBT"},
+	}
+
+	defer ulid.SetLogSafeKey(ulid.SetLogSafeKey(nil))
+	for _, tc := range testCases {
+		ulid.SetLogSafeKey([]byte(tc.key))
+		if safe := ulid.MustParse(tc.id).LogSafe(); safe != tc.safe {
+			t.Errorf("%s with key %q: got %s, want %s", tc.id, tc.key, safe, tc.safe)
+		}
+	}
+
+	// The key is copied.
+	key := []byte("log-safe-fixture-key")
+	ulid.SetLogSafeKey(key)
+	key[0] = 'x'
+	if prev := ulid.SetLogSafeKey(nil); string(prev) != "log-safe-fixture-key" {
+		t.Errorf("got previous key %q", prev)
+	}
+
+	// The entropy is not included, but ULIDs of the same millisecond are distinct.
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	next := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAW")
+	if a, b := id.LogSafe(), next.LogSafe(); a[:11] != b[:11] || a == b || strings.Contains(a, id.String()[10:]) {
+		t.Errorf("got %s and %s", a, b)
+	}
+}
+
+func TestSafeULID(t *testing.T) {
+	defer ulid.SetLogSafeKey(ulid.SetLogSafeKey([]byte("log-safe-fixture-key")))
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	safe := ulid.SafeULID(id)
+	if safe.String() != "01ARZ3NDEK-TWBAX90R" || ulid.ULID(safe) != id {
+		t.Fatalf("got %s", safe)
+	}
+
+	type event struct {
+		User ulid.SafeULID `json:"user"`
+	}
+
+	for _, s := range []string{
+		fmt.Sprint(safe),
+		fmt.Sprintf("%s %v %+v %#v %d %x %q %20s", safe, safe, safe, safe, safe, safe, safe, safe),
+		fmt.Sprintf("%v %+v %#v", event{safe}, event{safe}, event{safe}),
+		fmt.Sprintf("%v", []ulid.SafeULID{safe}),
+	} {
+		if strings.Contains(s, id.String()[10:]) || strings.Contains(s, fmt.Sprint(id[6:])) || strings.Contains(s, fmt.Sprintf("%x", id[6:])) {
+			t.Errorf("the ULID leaked into %s", s)
+		}
+	}
+
+	if s := fmt.Sprintf("[%s] [%q] [%21s] [%-20v] [%#v] [%d]", safe, safe, safe, safe, safe, safe); s != `[01ARZ3NDEK-TWBAX90R] ["01ARZ3NDEK-TWBAX90R"] [  01ARZ3NDEK-TWBAX90R] [01ARZ3NDEK-TWBAX90R ] ["01ARZ3NDEK-TWBAX90R"] [01ARZ3NDEK-TWBAX90R]` {
+		t.Errorf("got %s", s)
+	}
+
+	if data, err := json.Marshal(event{safe}); err != nil || string(data) != `{"user":"01ARZ3NDEK-TWBAX90R"}` {
+		t.Errorf("got %s (%v)", data, err)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("login", "user", safe)
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("login", "user", safe, "event", event{safe})
+	if out := buf.String(); strings.Contains(out, id.String()[10:]) || strings.Count(out, "01ARZ3NDEK-TWBAX90R") != 3 {
+		t.Errorf("got %s", out)
+	}
+}
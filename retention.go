@@ -0,0 +1,172 @@
+package ulid
+
+import (
+	"sort"
+	"time"
+)
+
+// The retention helpers compare the millisecond timestamp embedded in each ULID
+// to the cutoff time truncated to the millisecond. "Before" is strict: a ULID
+// whose timestamp is exactly the cutoff millisecond is never before the cutoff,
+// and is always included in the newerOrEqual side of a partition.
+
+// FilterBefore returns a new slice containing the ULIDs whose timestamp is
+// strictly before the cutoff millisecond, preserving input order.
+func FilterBefore(ids []ULID, t time.Time) []ULID {
+	cutoff := cutoffTime(t)
+	out := make([]ULID, 0, len(ids))
+	for _, id := range ids {
+		if id.Time() < cutoff {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// FilterAfter returns a new slice containing the ULIDs whose timestamp is
+// strictly after the cutoff millisecond, preserving input order. ULIDs in the
+// cutoff millisecond are excluded by both FilterBefore and FilterAfter; use
+// PartitionByTime to split a slice without losing any ULIDs. A cutoff before the
+// Unix epoch is clamped to just before it, so every ULID is after it, including
+// the ULIDs with a timestamp of 0.
+func FilterAfter(ids []ULID, t time.Time) []ULID {
+	cutoff, all := cutoffTime(t), t.UnixMilli() < 0
+	out := make([]ULID, 0, len(ids))
+	for _, id := range ids {
+		if all || id.Time() > cutoff {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// PartitionByTime splits the ULIDs into new slices of ULIDs whose timestamp is
+// strictly before the cutoff millisecond and ULIDs whose timestamp is at or after
+// the cutoff millisecond, preserving input order in both.
+func PartitionByTime(ids []ULID, t time.Time) (older, newerOrEqual []ULID) {
+	cutoff := cutoffTime(t)
+	for _, id := range ids {
+		if id.Time() < cutoff {
+			older = append(older, id)
+		} else {
+			newerOrEqual = append(newerOrEqual, id)
+		}
+	}
+	return older, newerOrEqual
+}
+
+// DeleteBefore removes the ULIDs whose timestamp is strictly before the cutoff
+// millisecond from the slice in place and returns the modified slice, preserving
+// the order of the remaining ULIDs. Like slices.DeleteFunc it does not allocate
+// and zeroes the elements between the new length and the original length.
+func DeleteBefore(ids []ULID, t time.Time) []ULID {
+	cutoff := cutoffTime(t)
+	i := 0
+	for _, id := range ids {
+		if id.Time() >= cutoff {
+			ids[i] = id
+			i++
+		}
+	}
+
+	clear(ids[i:])
+	return ids[:i]
+}
+
+// CutoffIndex uses binary search to return the index of the first ULID in the
+// sorted slice whose timestamp is at or after the cutoff millisecond, such that
+// sorted[:i] are all strictly before the cutoff and sorted[i:] are not. If every
+// ULID is before the cutoff, len(sorted) is returned. The slice must be sorted
+// in ascending order, e.g. by ULID.Compare.
+func CutoffIndex(sorted []ULID, t time.Time) int {
+	cutoff := cutoffTime(t)
+	return sort.Search(len(sorted), func(i int) bool {
+		return sorted[i].Time() >= cutoff
+	})
+}
+
+// cutoffTime returns the cutoff millisecond for t, clamping times before the
+// Unix epoch to 0 and times after MaxTimestampTime to MaxTime+1 rather than
+// allowing them to wrap around.
+func cutoffTime(t time.Time) uint64 {
+	ms, err := TimestampChecked(t)
+	switch err {
+	case ErrSmallTime:
+		return 0
+	case ErrBigTime:
+		return maxTime + 1
+	}
+	return ms
+}
+
+// QueryRange returns the inclusive ULIDRange of the ULIDs whose timestamp is from
+// the millisecond of from to the millisecond of to, widened by slack on both ends,
+// e.g. to scan a table by primary key for the records created in a time range.
+// The timestamp of a ULID is not always the time it was logically created:
+// BumpOnOverflow advances the timestamps of a burst up to its maxDrift after the
+// requested time, and the clocks of hosts skewed by NTP may be ahead of or behind
+// the true time, so a query for the exact range misses the records near its
+// boundaries. Pass a slack of at least the maxDrift of the BumpingReader plus the
+// expected clock skew to include them, then post-filter the scan with
+// FilterByLogicalTime or Contains as needed. A slack of zero does not widen the
+// range; a slack that is not a whole number of milliseconds is rounded up.
+//
+// The widened bounds are clamped to the range of ULID timestamps. ErrSmallTime or
+// ErrBigTime is returned if from or to cannot be represented in a ULID,
+// ErrInvalidRange if from is after to, and ErrInvalidDuration if slack is
+// negative.
+func QueryRange(from, to time.Time, slack time.Duration) (r ULIDRange, err error) {
+	if slack < 0 {
+		return r, ErrInvalidDuration
+	}
+
+	var lo, hi uint64
+	if lo, err = TimestampChecked(from); err != nil {
+		return r, err
+	}
+
+	if hi, err = TimestampChecked(to); err != nil {
+		return r, err
+	}
+
+	if lo > hi {
+		return r, ErrInvalidRange
+	}
+
+	// The bounds are clamped to the range of timestamps.
+	ms := slackMilli(slack)
+	return timeRange(lo-min(lo, ms), hi+min(maxTime-hi, ms)), nil
+}
+
+// FilterByLogicalTime returns a new slice containing the ULIDs that may have been
+// logically created from the millisecond of from to the millisecond of to,
+// preserving input order, e.g. to post-filter the results of a scan of the
+// QueryRange with the same slack. Since BumpOnOverflow only advances timestamps,
+// a ULID is included if its timestamp is at or after from and at most slack
+// after to, which is stricter than the QueryRange: the ULIDs with a timestamp
+// before from are excluded even though the range was widened for clock skew.
+// Filter with the Contains method of the QueryRange instead to keep the ULIDs of
+// hosts whose clocks may be behind. A slack that is not a whole number of
+// milliseconds is rounded up and a negative slack is treated as zero; times
+// outside the range of ULID timestamps are clamped as with FilterBefore.
+func FilterByLogicalTime(ids []ULID, from, to time.Time, slack time.Duration) []ULID {
+	lo, hi := cutoffTime(from), cutoffTime(to)
+	hi += slackMilli(max(slack, 0))
+
+	out := make([]ULID, 0, len(ids))
+	for _, id := range ids {
+		if ts := id.Time(); ts >= lo && ts <= hi {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// slackMilli returns the non-negative slack in milliseconds, rounded up.
+func slackMilli(slack time.Duration) uint64 {
+	ms := uint64(slack / time.Millisecond)
+	if slack%time.Millisecond != 0 {
+		ms++
+	}
+	return ms
+}
@@ -0,0 +1,266 @@
+package ulid_test
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestRetention(t *testing.T) {
+	t.Parallel()
+
+	// Many ULIDs share the cutoff millisecond (c) including a sub-millisecond cutoff.
+	const c = 1000
+	cutoff := ulid.Time(c).Add(500 * time.Microsecond)
+	mk := func(ms uint64, n byte) ulid.ULID {
+		return ulid.MustNew(ms, &constReader{n})
+	}
+
+	ids := []ulid.ULID{
+		mk(c, 1), mk(c-1, 2), mk(c+1, 3), mk(c, 4), mk(0, 5),
+		mk(c, 6), mk(c-1, 7), mk(c+2, 8), mk(c, 9),
+	}
+
+	before := []ulid.ULID{ids[1], ids[4], ids[6]}
+	at := []ulid.ULID{ids[0], ids[3], ids[5], ids[8]}
+	after := []ulid.ULID{ids[2], ids[7]}
+	newerOrEqual := []ulid.ULID{ids[0], ids[2], ids[3], ids[5], ids[7], ids[8]}
+
+	t.Run("FilterBefore", func(t *testing.T) {
+		if got := ulid.FilterBefore(ids, cutoff); !reflect.DeepEqual(got, before) {
+			t.Errorf("got %v, want %v", got, before)
+		}
+	})
+
+	t.Run("FilterAfter", func(t *testing.T) {
+		if got := ulid.FilterAfter(ids, cutoff); !reflect.DeepEqual(got, after) {
+			t.Errorf("got %v, want %v", got, after)
+		}
+
+		// ULIDs in the cutoff millisecond are excluded by both filters.
+		if n := len(ulid.FilterBefore(ids, cutoff)) + len(ulid.FilterAfter(ids, cutoff)); n != len(ids)-len(at) {
+			t.Errorf("expected %d ULIDs to be excluded by both filters", len(at))
+		}
+	})
+
+	t.Run("PartitionByTime", func(t *testing.T) {
+		older, newer := ulid.PartitionByTime(ids, cutoff)
+		if !reflect.DeepEqual(older, before) {
+			t.Errorf("got older %v, want %v", older, before)
+		}
+
+		if !reflect.DeepEqual(newer, newerOrEqual) {
+			t.Errorf("got newer or equal %v, want %v", newer, newerOrEqual)
+		}
+	})
+
+	t.Run("DeleteBefore", func(t *testing.T) {
+		cp := slices.Clone(ids)
+		got := ulid.DeleteBefore(cp, cutoff)
+		if !reflect.DeepEqual(got, newerOrEqual) {
+			t.Errorf("got %v, want %v", got, newerOrEqual)
+		}
+
+		if &got[0] != &cp[0] {
+			t.Error("expected DeleteBefore to reuse the input slice")
+		}
+
+		for _, id := range cp[len(got):] {
+			if !id.IsZero() {
+				t.Error("expected tail of the compacted slice to be zeroed")
+			}
+		}
+	})
+
+	t.Run("CutoffIndex", func(t *testing.T) {
+		sorted := slices.Clone(ids)
+		slices.SortFunc(sorted, ulid.ULID.Compare)
+
+		i := ulid.CutoffIndex(sorted, cutoff)
+		if i != len(before) {
+			t.Fatalf("got index %d, want %d", i, len(before))
+		}
+
+		for _, id := range sorted[:i] {
+			if id.Time() >= c {
+				t.Errorf("expected %s to be before the cutoff", id)
+			}
+		}
+
+		for _, id := range sorted[i:] {
+			if id.Time() < c {
+				t.Errorf("expected %s to not be before the cutoff", id)
+			}
+		}
+
+		if i := ulid.CutoffIndex(sorted, ulid.Time(c+10)); i != len(sorted) {
+			t.Errorf("got index %d, want %d", i, len(sorted))
+		}
+	})
+
+	t.Run("OutOfRange", func(t *testing.T) {
+		if got := ulid.FilterBefore(ids, time.Unix(-10, 0)); len(got) != 0 {
+			t.Errorf("expected no ULIDs before a pre-epoch cutoff, got %d", len(got))
+		}
+
+		epoch := append(slices.Clone(ids), ulid.MustNew(0, nil))
+		for _, pre := range []time.Time{time.Unix(-10, 0), time.Unix(0, -1)} {
+			if got := ulid.FilterAfter(epoch, pre); !slices.Equal(got, epoch) {
+				t.Errorf("expected all ULIDs after a pre-epoch cutoff, got %d of %d", len(got), len(epoch))
+			}
+		}
+
+		// ids[4] and the last ULID are at the epoch.
+		if got := ulid.FilterAfter(epoch, time.Unix(0, 0)); len(got) != len(epoch)-2 {
+			t.Errorf("expected the ULIDs at the epoch not to be after it, got %d", len(got))
+		}
+
+		if got := ulid.FilterBefore(ids, time.Date(12000, 1, 1, 0, 0, 0, 0, time.UTC)); len(got) != len(ids) {
+			t.Errorf("expected all ULIDs to be before a far future cutoff, got %d", len(got))
+		}
+
+		if i := ulid.CutoffIndex(nil, cutoff); i != 0 {
+			t.Errorf("got index %d for empty slice", i)
+		}
+	})
+}
+
+func TestCutoffIndexAgreesWithPartition(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ids := make([]ulid.ULID, 1000)
+	for i := range ids {
+		ids[i] = ulid.MustNew(uint64(rng.Intn(50)), rng)
+	}
+	slices.SortFunc(ids, ulid.ULID.Compare)
+
+	for ms := uint64(0); ms < 52; ms++ {
+		cutoff := ulid.Time(ms)
+		older, _ := ulid.PartitionByTime(ids, cutoff)
+		if i := ulid.CutoffIndex(ids, cutoff); i != len(older) {
+			t.Fatalf("cutoff %d: got index %d, want %d", ms, i, len(older))
+		}
+	}
+}
+
+func TestQueryRange(t *testing.T) {
+	t.Parallel()
+
+	// A query for [from, to] with 5ms of slack scans [from-5ms, to+5ms].
+	const from, to = 1000, 2000
+	slack := 5 * time.Millisecond
+	mk := func(ms uint64, n byte) ulid.ULID {
+		return ulid.MustNew(ms, &constReader{n})
+	}
+
+	r, err := ulid.QueryRange(ulid.Time(from).Add(500*time.Microsecond), ulid.Time(to).Add(999*time.Microsecond), slack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Lo != mk(from-5, 0x00) || r.Hi != mk(to+5, 0xFF) {
+		t.Fatalf("got range %s to %s", r.Lo, r.Hi)
+	}
+
+	testCases := []struct {
+		id       ulid.ULID
+		scanned  bool
+		filtered bool
+	}{
+		{mk(from-6, 0xFF), false, false},
+		{mk(from-5, 0x00), true, false},
+		{mk(from-1, 0xFF), true, false},
+		{mk(from, 0x00), true, true},
+		{mk(from+500, 0x42), true, true},
+		{mk(to, 0xFF), true, true},
+		{mk(to+1, 0x00), true, true},
+		{mk(to+5, 0xFF), true, true},
+		{mk(to+6, 0x00), false, false},
+	}
+
+	ids := make([]ulid.ULID, 0, len(testCases))
+	var want []ulid.ULID
+	for _, tc := range testCases {
+		if r.Contains(tc.id) != tc.scanned {
+			t.Errorf("%d: expected Contains to return %t", tc.id.Time(), tc.scanned)
+		}
+
+		ids = append(ids, tc.id)
+		if tc.filtered {
+			want = append(want, tc.id)
+		}
+	}
+
+	if got := ulid.FilterByLogicalTime(ids, ulid.Time(from), ulid.Time(to), slack); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The slack defaults to an exact query and is rounded up to a millisecond.
+	if r, _ := ulid.QueryRange(ulid.Time(from), ulid.Time(to), 0); r.Lo != mk(from, 0x00) || r.Hi != mk(to, 0xFF) {
+		t.Errorf("got range %s to %s without slack", r.Lo, r.Hi)
+	}
+
+	if got := ulid.FilterByLogicalTime(ids, ulid.Time(from), ulid.Time(to), 0); !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("got %v without slack, want %v", got, want[:3])
+	}
+
+	if r, _ := ulid.QueryRange(ulid.Time(from), ulid.Time(to), time.Microsecond); r.Lo != mk(from-1, 0x00) || r.Hi != mk(to+1, 0xFF) {
+		t.Errorf("got range %s to %s with sub-millisecond slack", r.Lo, r.Hi)
+	}
+
+	if got := ulid.FilterByLogicalTime(ids, ulid.Time(from), ulid.Time(to), -slack); !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("got %v with negative slack, want %v", got, want[:3])
+	}
+}
+
+func TestQueryRangeBounds(t *testing.T) {
+	t.Parallel()
+
+	// The widened bounds are clamped to the range of timestamps.
+	r, err := ulid.QueryRange(ulid.Time(2), ulid.MaxTimestampTime(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Lo != ulid.Zero || r.Hi != ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ") {
+		t.Errorf("got range %s to %s", r.Lo, r.Hi)
+	}
+
+	testCases := []struct {
+		name     string
+		from, to time.Time
+		slack    time.Duration
+		err      error
+	}{
+		{"Negative", ulid.Time(1000), ulid.Time(2000), -time.Millisecond, ulid.ErrInvalidDuration},
+		{"Reversed", ulid.Time(2000), ulid.Time(1000), 0, ulid.ErrInvalidRange},
+		{"SmallTime", time.Unix(-1, 0), ulid.Time(1000), 0, ulid.ErrSmallTime},
+		{"BigTime", ulid.Time(1000), ulid.MaxTimestampTime().Add(time.Millisecond), 0, ulid.ErrBigTime},
+	}
+
+	for _, tc := range testCases {
+		if r, err := ulid.QueryRange(tc.from, tc.to, tc.slack); err != tc.err || r != (ulid.ULIDRange{}) {
+			t.Errorf("%s: got %v (%v), want %v", tc.name, r, err, tc.err)
+		}
+	}
+
+	// The filter clamps times like FilterBefore.
+	ids := []ulid.ULID{ulid.Zero, ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")}
+	if got := ulid.FilterByLogicalTime(ids, time.Unix(-10, 0), time.Date(12000, 1, 1, 0, 0, 0, 0, time.UTC), 0); !reflect.DeepEqual(got, ids) {
+		t.Errorf("got %v, want %v", got, ids)
+	}
+}
+
+type constReader struct{ b byte }
+
+func (r *constReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.b
+	}
+	return len(p), nil
+}
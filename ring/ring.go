@@ -0,0 +1,197 @@
+// Package ring implements a consistent hash ring that assigns ULIDs to a dynamic
+// set of nodes, e.g. to partition ULID-keyed resources among workers so that
+// only about 1/n of the ULIDs move to another node when one of n nodes joins or
+// leaves the ring, instead of almost all of them with modulo hashing.
+//
+// Each node is placed on the ring at a number of points (replicas) derived from
+// the FNV-1a hash of its name, so the placement is the same in every process
+// and every run. The position of a ULID on the ring is a fast, non-cryptographic
+// hash of all 128 bits rather than of its encoding: the high 64 bits are mixed
+// with the splitmix64 finalizer, combined with the low 64 bits, and mixed again,
+// so that ULIDs with sequential or zero entropy are spread over the ring as well
+// as random ones. A ULID is assigned to the node of the first point at or after
+// its position, wrapping around the ring.
+//
+// The points of the ring are copy-on-write: AddNode and RemoveNode rebuild them
+// under a lock and publish them atomically, so Locate and LocateN never block
+// and do not allocate (apart from the result of LocateN).
+package ring
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.rtnl.ai/ulid"
+)
+
+// DefaultReplicas is the number of points of each node on the ring if the number
+// passed to New is less than 1. More points spread the ULIDs more evenly at the
+// cost of memory and the time to add or remove a node.
+const DefaultReplicas = 160
+
+// Ring is a consistent hash ring of named nodes. A Ring is safe for concurrent
+// use and must be created with New.
+type Ring struct {
+	replicas int
+	mu       sync.Mutex // serializes writers, readers only load the state
+	state    atomic.Pointer[state]
+}
+
+// state is an immutable snapshot of the ring.
+type state struct {
+	nodes  []string // sorted
+	points []point  // sorted by hash, then by node
+}
+
+type point struct {
+	hash uint64
+	node int // index into nodes
+}
+
+// New returns an empty ring that places each node at the number of replicas.
+func New(replicas int) *Ring {
+	if replicas < 1 {
+		replicas = DefaultReplicas
+	}
+
+	r := &Ring{replicas: replicas}
+	r.state.Store(&state{})
+	return r
+}
+
+// Replicas returns the number of points of each node on the ring.
+func (r *Ring) Replicas() int {
+	return r.replicas
+}
+
+// AddNode adds the node to the ring, returning false if it is already on it.
+func (r *Ring) AddNode(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nodes := r.state.Load().nodes
+	i, found := slices.BinarySearch(nodes, name)
+	if found {
+		return false
+	}
+
+	r.rebuild(slices.Insert(slices.Clone(nodes), i, name))
+	return true
+}
+
+// RemoveNode removes the node from the ring, returning false if it is not on it.
+func (r *Ring) RemoveNode(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nodes := r.state.Load().nodes
+	i, found := slices.BinarySearch(nodes, name)
+	if !found {
+		return false
+	}
+
+	r.rebuild(slices.Delete(slices.Clone(nodes), i, i+1))
+	return true
+}
+
+// rebuild places the sorted nodes on a new ring and publishes it, which must be
+// called with the lock held.
+func (r *Ring) rebuild(nodes []string) {
+	s := &state{nodes: nodes, points: make([]point, 0, len(nodes)*r.replicas)}
+	for i, name := range nodes {
+		for j := 0; j < r.replicas; j++ {
+			s.points = append(s.points, point{hash: nodeHash(name, j), node: i})
+		}
+	}
+
+	// Ties between the points of different nodes are broken by the order of the
+	// names so that the placement does not depend on the order nodes were added.
+	slices.SortFunc(s.points, func(a, b point) int {
+		return cmp.Or(cmp.Compare(a.hash, b.hash), cmp.Compare(a.node, b.node))
+	})
+	r.state.Store(s)
+}
+
+// Nodes returns the sorted names of the nodes on the ring.
+func (r *Ring) Nodes() []string {
+	return slices.Clone(r.state.Load().nodes)
+}
+
+// Len returns the number of nodes on the ring.
+func (r *Ring) Len() int {
+	return len(r.state.Load().nodes)
+}
+
+// Locate returns the node that the ULID is assigned to, or an empty string if
+// the ring has no nodes.
+func (r *Ring) Locate(id ulid.ULID) string {
+	s := r.state.Load()
+	if len(s.points) == 0 {
+		return ""
+	}
+	return s.nodes[s.points[s.search(id)].node]
+}
+
+// LocateN returns up to n distinct nodes for the ULID in the order of the ring,
+// e.g. to place the replicas of a resource; the first node is the node returned
+// by Locate. If n is larger than the number of nodes, all of the nodes are
+// returned, and if the ring has no nodes or n is less than 1, nil is returned.
+func (r *Ring) LocateN(id ulid.ULID, n int) []string {
+	s := r.state.Load()
+	if n = min(n, len(s.nodes)); n < 1 {
+		return nil
+	}
+
+	nodes := make([]string, 0, n)
+	start := s.search(id)
+	for i := 0; i < len(s.points) && len(nodes) < n; i++ {
+		if node := s.nodes[s.points[(start+i)%len(s.points)].node]; !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// search returns the index of the first point at or after the position of the
+// ULID, wrapping around to the first point.
+func (s *state) search(id ulid.ULID) int {
+	h := position(id)
+	i, _ := slices.BinarySearchFunc(s.points, h, func(p point, h uint64) int {
+		return cmp.Compare(p.hash, h)
+	})
+
+	if i == len(s.points) {
+		return 0
+	}
+	return i
+}
+
+// position returns the position of the ULID on the ring, a hash of its 16 bytes.
+func position(id ulid.ULID) uint64 {
+	return mix(mix(binary.BigEndian.Uint64(id[:8])) ^ binary.BigEndian.Uint64(id[8:]))
+}
+
+// nodeHash returns the position of the replica of the node on the ring.
+func nodeHash(name string, replica int) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(replica))
+	h.Write(buf[:])
+	return mix(h.Sum64())
+}
+
+// mix is the splitmix64 finalizer.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xBF58476D1CE4E5B9
+	h ^= h >> 27
+	h *= 0x94D049BB133111EB
+	h ^= h >> 31
+	return h
+}
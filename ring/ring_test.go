@@ -0,0 +1,258 @@
+package ring_test
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/ring"
+)
+
+// ids returns n ULIDs with random timestamps and entropy from the seed.
+func ids(seed int64, n int) []ulid.ULID {
+	rng := rand.New(rand.NewSource(seed))
+	out := make([]ulid.ULID, n)
+	for i := range out {
+		out[i] = ulid.MustNew(uint64(rng.Int63n(int64(ulid.MaxTime()))), rng)
+	}
+	return out
+}
+
+// newRing returns a ring with the nodes node-0 through node-{n-1}.
+func newRing(n int) *ring.Ring {
+	r := ring.New(0)
+	for i := 0; i < n; i++ {
+		r.AddNode(fmt.Sprintf("node-%d", i))
+	}
+	return r
+}
+
+func TestRing(t *testing.T) {
+	t.Parallel()
+
+	r := ring.New(-1)
+	id := ulid.Make()
+	if r.Replicas() != ring.DefaultReplicas || r.Len() != 0 || r.Locate(id) != "" || r.LocateN(id, 3) != nil {
+		t.Fatal("expected an empty ring with the default replicas")
+	}
+
+	if !r.AddNode("b") || !r.AddNode("a") || r.AddNode("a") {
+		t.Fatal("expected each node to be added once")
+	}
+
+	if nodes := r.Nodes(); !slices.Equal(nodes, []string{"a", "b"}) {
+		t.Errorf("got nodes %v", nodes)
+	}
+
+	if node := r.Locate(id); node != "a" && node != "b" {
+		t.Errorf("got node %q", node)
+	}
+
+	if !r.RemoveNode("a") || r.RemoveNode("a") || r.RemoveNode("c") {
+		t.Fatal("expected the node to be removed once")
+	}
+
+	if r.Len() != 1 || r.Locate(id) != "b" {
+		t.Errorf("expected every ULID on the remaining node, got %q", r.Locate(id))
+	}
+}
+
+func TestRingDistribution(t *testing.T) {
+	t.Parallel()
+
+	// With the default replicas each node receives its share within 25%.
+	const nodes = 10
+	r := newRing(nodes)
+	keys := ids(42, 100000)
+
+	counts := make(map[string]int)
+	for _, id := range keys {
+		counts[r.Locate(id)]++
+	}
+
+	if len(counts) != nodes {
+		t.Fatalf("expected ULIDs on %d nodes, got %d", nodes, len(counts))
+	}
+
+	want := len(keys) / nodes
+	for node, n := range counts {
+		if n < want*3/4 || n > want*5/4 {
+			t.Errorf("%s received %d ULIDs, want %d±25%%", node, n, want)
+		}
+	}
+
+	// Sequential ULIDs in the same millisecond are spread as well.
+	counts = make(map[string]int)
+	entropy := ulid.SequentialEntropy([10]byte{})
+	for i := 0; i < 10000; i++ {
+		counts[r.Locate(ulid.MustNew(1738876313290, entropy))]++
+	}
+
+	for node, n := range counts {
+		if n < 750 || n > 1250 {
+			t.Errorf("%s received %d sequential ULIDs, want 1000±25%%", node, n)
+		}
+	}
+}
+
+func TestRingMovement(t *testing.T) {
+	t.Parallel()
+
+	r := newRing(10)
+	keys := ids(7, 50000)
+	before := make([]string, len(keys))
+	for i, id := range keys {
+		before[i] = r.Locate(id)
+	}
+
+	// Adding an eleventh node only moves ULIDs to the new node, about 1/11 of them.
+	r.AddNode("node-10")
+	var moved int
+	for i, id := range keys {
+		if node := r.Locate(id); node != before[i] {
+			if node != "node-10" {
+				t.Fatalf("%s moved from %s to %s instead of the new node", id, before[i], node)
+			}
+			moved++
+		}
+	}
+
+	if fraction := float64(moved) / float64(len(keys)); fraction < 0.5/11 || fraction > 1.5/11 {
+		t.Errorf("adding a node moved %.3f of the ULIDs, want about %.3f", fraction, 1.0/11)
+	}
+
+	// Removing a node only moves the ULIDs of the node, which return to their
+	// previous nodes.
+	r.RemoveNode("node-10")
+	for i, id := range keys {
+		if node := r.Locate(id); node != before[i] {
+			t.Fatalf("%s is on %s after the node was removed, want %s", id, node, before[i])
+		}
+	}
+
+	r.RemoveNode("node-3")
+	moved = 0
+	for i, id := range keys {
+		node := r.Locate(id)
+		switch {
+		case before[i] == "node-3":
+			moved++
+		case node != before[i]:
+			t.Fatalf("%s moved from %s to %s after removing node-3", id, before[i], node)
+		}
+	}
+
+	if fraction := float64(moved) / float64(len(keys)); fraction < 0.5/10 || fraction > 1.5/10 {
+		t.Errorf("removing a node moved %.3f of the ULIDs, want about %.3f", fraction, 1.0/10)
+	}
+}
+
+func TestRingDeterministic(t *testing.T) {
+	t.Parallel()
+
+	// The order nodes are added in does not change the placement.
+	a, b := ring.New(0), ring.New(0)
+	names := []string{"alpha", "bravo", "charlie", "delta", "echo"}
+	for i := range names {
+		a.AddNode(names[i])
+		b.AddNode(names[len(names)-1-i])
+	}
+
+	for _, id := range ids(1, 1000) {
+		if a.Locate(id) != b.Locate(id) || !slices.Equal(a.LocateN(id, 3), b.LocateN(id, 3)) {
+			t.Fatalf("placement of %s depends on the order of the nodes", id)
+		}
+	}
+
+	// The placement is the same in every run.
+	testCases := []struct {
+		id   string
+		node string
+	}{
+		{"01JKEHNQPA0END3NHMFKB2Y6SE", "alpha"},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "bravo"},
+		{"01HTNMW2JAW89YSBG7NFPHABA4", "echo"},
+		{"00000000000000000000000000", "delta"},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "alpha"},
+	}
+
+	for _, tc := range testCases {
+		if node := a.Locate(ulid.MustParse(tc.id)); node != tc.node {
+			t.Errorf("%s is on %s, want %s", tc.id, node, tc.node)
+		}
+	}
+}
+
+func TestLocateN(t *testing.T) {
+	t.Parallel()
+
+	r := newRing(5)
+	for _, id := range ids(3, 1000) {
+		nodes := r.LocateN(id, 3)
+		if len(nodes) != 3 || nodes[0] != r.Locate(id) {
+			t.Fatalf("got nodes %v for %s, want 3 starting with %s", nodes, id, r.Locate(id))
+		}
+
+		if distinct := slices.Compact(slices.Sorted(slices.Values(nodes))); len(distinct) != 3 {
+			t.Fatalf("expected distinct nodes, got %v", nodes)
+		}
+	}
+
+	id := ulid.Make()
+	if nodes := r.LocateN(id, 10); len(nodes) != 5 {
+		t.Errorf("expected all 5 nodes, got %v", nodes)
+	}
+
+	if nodes := r.LocateN(id, 0); nodes != nil {
+		t.Errorf("expected no nodes, got %v", nodes)
+	}
+}
+
+func TestRingConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// Readers locate ULIDs while the nodes of the ring change.
+	r := newRing(3)
+	keys := ids(5, 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, id := range keys {
+				if r.Locate(id) == "" || len(r.LocateN(id, 2)) != 2 {
+					t.Error("expected the ring to never be empty")
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 3; i < 50; i++ {
+		r.AddNode(fmt.Sprintf("node-%d", i))
+		r.RemoveNode(fmt.Sprintf("node-%d", i-1))
+	}
+	wg.Wait()
+}
+
+func TestLocateAllocs(t *testing.T) {
+	r := newRing(10)
+	id := ulid.Make()
+	if allocs := testing.AllocsPerRun(100, func() { r.Locate(id) }); allocs != 0 {
+		t.Errorf("expected Locate not to allocate, got %f allocs", allocs)
+	}
+}
+
+func BenchmarkLocate(b *testing.B) {
+	r := newRing(10)
+	keys := ids(9, 1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Locate(keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,333 @@
+// Package scan extracts ULIDs from JSON log lines, such as the output of the
+// JSON handler of log/slog, e.g. to reconstruct the traces of requests from their
+// request IDs during an incident.
+//
+// A Scanner streams its input one line at a time, so memory is bounded by the
+// longest line rather than by the size of the input, and decodes each line as a
+// JSON object in document order. The string values of the named fields are
+// parsed strictly as ULIDs wherever they are nested in the object, including in
+// the groups of slog (nested objects) and in arrays of IDs, and the string values
+// of message fields can also be searched for ULIDs with ulid.FindAll. Lines that
+// are not JSON objects, e.g. panics or the output of another logger written to
+// the same file, are skipped and counted rather than stopping the scan.
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"go.rtnl.ai/ulid"
+)
+
+// DefaultMaxLineSize is the default maximum size in bytes of a line; longer lines
+// are skipped as malformed.
+const DefaultMaxLineSize = 1 << 20
+
+// Match is a ULID found in a log line.
+type Match struct {
+	Line  int       // The line number in the input, starting at 1 and including blank lines
+	Field string    // The dotted path of the field in the line, e.g. "http.request_id"
+	ULID  ulid.ULID // The ULID in the field
+}
+
+// Scanner extracts the ULIDs of the named fields from JSON log lines. A Scanner
+// is not safe for concurrent use and must be created with New.
+type Scanner struct {
+	src      io.Reader
+	r        *bufio.Reader
+	gzip     bool
+	fields   map[string]struct{}
+	dotted   bool
+	messages map[string]struct{}
+	maxLine  int
+
+	line      []byte
+	lineNo    int // the number of the current line, including blank lines
+	matches   []Match
+	lines     int // the number of lines that were not blank
+	malformed int
+	invalid   int
+	err       error
+}
+
+// Option configures a Scanner returned by New.
+type Option func(*Scanner)
+
+// Messages also searches the string values of the message fields with the keys
+// for canonical ULIDs with ulid.FindAll, e.g. for request IDs that were only
+// formatted into the message. With no keys, the message key of slog ("msg") is
+// searched.
+func Messages(keys ...string) Option {
+	return func(s *Scanner) {
+		if len(keys) == 0 {
+			keys = []string{slog.MessageKey}
+		}
+
+		for _, key := range keys {
+			s.messages[key] = struct{}{}
+		}
+	}
+}
+
+// Gzip decompresses the input with gzip, e.g. to scan rotated log files without
+// decompressing them first. Concatenated gzip members are read as one stream.
+func Gzip() Option {
+	return func(s *Scanner) {
+		s.gzip = true
+	}
+}
+
+// MaxLineSize sets the maximum size in bytes of a line (default
+// DefaultMaxLineSize); longer lines are skipped as malformed. Values less than 1
+// use the default.
+func MaxLineSize(n int) Option {
+	return func(s *Scanner) {
+		if n > 0 {
+			s.maxLine = n
+		}
+	}
+}
+
+// New returns a Scanner that reads JSON log lines from r and extracts the ULIDs of
+// the fields. A field matches the keys with its name at any depth of a line, e.g.
+// "request_id" matches both {"request_id":...} and {"http":{"request_id":...}},
+// while a field with a dotted path, e.g. "http.request_id", matches only the key
+// nested in the named objects.
+func New(r io.Reader, fields []string, opts ...Option) *Scanner {
+	s := &Scanner{
+		src:      r,
+		fields:   make(map[string]struct{}, len(fields)),
+		messages: make(map[string]struct{}),
+		maxLine:  DefaultMaxLineSize,
+	}
+
+	for _, field := range fields {
+		s.fields[field] = struct{}{}
+		s.dotted = s.dotted || strings.Contains(field, ".")
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// All returns the ULIDs found in the remaining lines of the input in order. The
+// ULIDs of a line are only yielded once the whole line has been decoded, so no
+// ULIDs are yielded from a malformed line. If iteration stops early, All may be
+// called again to continue with the next line. An error reading the input stops
+// the iteration and is available from Err.
+func (s *Scanner) All() iter.Seq[Match] {
+	return func(yield func(Match) bool) {
+		if s.err != nil {
+			return
+		}
+
+		if s.r == nil {
+			src := s.src
+			if s.gzip {
+				gz, err := gzip.NewReader(src)
+				if err != nil {
+					s.err = err
+					return
+				}
+				src = gz
+			}
+			s.r = bufio.NewReader(src)
+		}
+
+		for {
+			line, tooLong, err := s.readLine()
+			if len(line) > 0 || tooLong {
+				s.lines++
+				if !s.scanLine(line, tooLong) {
+					s.malformed++
+				}
+
+				for _, m := range s.matches {
+					if !yield(m) {
+						return
+					}
+				}
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					s.err = err
+				}
+				return
+			}
+		}
+	}
+}
+
+// Lines returns the number of lines read so far, excluding blank lines.
+func (s *Scanner) Lines() int {
+	return s.lines
+}
+
+// Malformed returns the number of lines skipped so far because they were not
+// JSON objects or were longer than the maximum line size.
+func (s *Scanner) Malformed() int {
+	return s.malformed
+}
+
+// Invalid returns the number of values of the fields skipped so far because they
+// were not strings or were not valid ULIDs, e.g. request IDs of another format.
+func (s *Scanner) Invalid() int {
+	return s.invalid
+}
+
+// Err returns the error that stopped the scan, if any. The end of the input is
+// not an error.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// readLine reads the next line without its line ending, discarding the bytes of
+// a line beyond the maximum line size.
+func (s *Scanner) readLine() (line []byte, tooLong bool, err error) {
+	s.line = s.line[:0]
+	for {
+		var frag []byte
+		frag, err = s.r.ReadSlice('\n')
+		if len(s.line)+len(frag) <= s.maxLine+2 {
+			s.line = append(s.line, frag...)
+		} else {
+			tooLong = true
+		}
+
+		if err != bufio.ErrBufferFull {
+			break
+		}
+	}
+
+	if len(s.line) > 0 || tooLong {
+		s.lineNo++
+	}
+
+	line = bytes.TrimSuffix(s.line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(bytes.TrimSpace(line)) == 0 {
+		line = nil
+	}
+	return line, tooLong || len(line) > s.maxLine, err
+}
+
+// scanLine decodes the line and collects its matches, returning false if the line
+// is malformed.
+func (s *Scanner) scanLine(line []byte, tooLong bool) bool {
+	s.matches = s.matches[:0]
+	if tooLong {
+		return false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	invalid := s.invalid
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+
+	if err := s.object(dec, nil); err != nil {
+		s.matches, s.invalid = s.matches[:0], invalid
+		return false
+	}
+
+	// The line must not contain anything after the object.
+	if _, err := dec.Token(); err != io.EOF {
+		s.matches, s.invalid = s.matches[:0], invalid
+		return false
+	}
+	return true
+}
+
+// object walks the fields of an object after its opening delimiter.
+func (s *Scanner) object(dec *json.Decoder, path []string) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if err = s.value(dec, append(path, tok.(string))); err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token()
+	return err
+}
+
+// value walks the next value of the line with the path of its field; the values of
+// arrays have the path of the array.
+func (s *Scanner) value(dec *json.Decoder, path []string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return s.object(dec, path)
+		}
+
+		for dec.More() {
+			if err = s.value(dec, path); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case string:
+		s.match(path, v)
+	default:
+		// Numbers, booleans, and nulls are not ULIDs.
+		if s.isField(path) {
+			s.invalid++
+		}
+	}
+	return nil
+}
+
+// match collects the ULID of a field or the ULIDs in a message.
+func (s *Scanner) match(path []string, v string) {
+	if s.isField(path) {
+		id, err := ulid.ParseStrict(v)
+		if err != nil {
+			s.invalid++
+			return
+		}
+
+		s.matches = append(s.matches, Match{Line: s.lineNo, Field: strings.Join(path, "."), ULID: id})
+		return
+	}
+
+	if _, ok := s.messages[path[len(path)-1]]; ok {
+		for _, id := range ulid.FindAll(v) {
+			s.matches = append(s.matches, Match{Line: s.lineNo, Field: strings.Join(path, "."), ULID: id})
+		}
+	}
+}
+
+func (s *Scanner) isField(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	if _, ok := s.fields[path[len(path)-1]]; ok {
+		return true
+	}
+
+	if s.dotted && len(path) > 1 {
+		_, ok := s.fields[strings.Join(path, ".")]
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package scan_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/scan"
+)
+
+var (
+	requestID = ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	traceID   = ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	spanID    = ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+)
+
+// syntheticLog writes log lines with the JSON handler of slog, with a malformed
+// line from another writer to the same file in the middle.
+func syntheticLog(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Info("started", "request_id", requestID.String())
+	logger.WithGroup("http").Info("request", "request_id", requestID.String(), "trace_id", traceID.String(), "status", 200)
+	buf.WriteString("panic: runtime error: index out of range [0] with length 0\n")
+	logger.Info("fan out", "trace_id", traceID.String(), "span_ids", []string{spanID.String(), requestID.String()})
+	buf.WriteString("\n")
+	logger.Warn("retrying request " + requestID.String() + " after " + spanID.String())
+	logger.Info("legacy", "request_id", "req-42", "trace_id", 42)
+	return buf.Bytes()
+}
+
+func collect(t *testing.T, s *scan.Scanner) []scan.Match {
+	t.Helper()
+	matches := slices.Collect(s.All())
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+
+	data := syntheticLog(t)
+	expected := []scan.Match{
+		{Line: 1, Field: "request_id", ULID: requestID},
+		{Line: 2, Field: "http.request_id", ULID: requestID},
+		{Line: 2, Field: "http.trace_id", ULID: traceID},
+		{Line: 4, Field: "trace_id", ULID: traceID},
+		{Line: 4, Field: "span_ids", ULID: spanID},
+		{Line: 4, Field: "span_ids", ULID: requestID},
+	}
+
+	s := scan.New(bytes.NewReader(data), []string{"request_id", "trace_id", "span_ids"})
+	if got := collect(t, s); !slices.Equal(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+
+	if s.Lines() != 6 || s.Malformed() != 1 || s.Invalid() != 2 {
+		t.Errorf("got %d lines, %d malformed, and %d invalid, want 6, 1, and 2", s.Lines(), s.Malformed(), s.Invalid())
+	}
+
+	// Messages are searched with FindAll.
+	s = scan.New(bytes.NewReader(data), []string{"request_id"}, scan.Messages())
+	got := collect(t, s)
+	if want := []scan.Match{expected[0], expected[1], {Line: 6, Field: "msg", ULID: requestID}, {Line: 6, Field: "msg", ULID: spanID}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A dotted path only matches the nested field.
+	s = scan.New(bytes.NewReader(data), []string{"http.trace_id"})
+	if got := collect(t, s); !slices.Equal(got, expected[2:3]) {
+		t.Errorf("got %v, want %v", got, expected[2:3])
+	}
+}
+
+func TestScannerLines(t *testing.T) {
+	t.Parallel()
+
+	id := requestID.String()
+	testCases := []struct {
+		name      string
+		line      string
+		matches   int
+		malformed bool
+	}{
+		{"Object", `{"id":"` + id + `"}`, 1, false},
+		{"CRLF", `{"id":"` + id + `"}` + "\r", 1, false},
+		{"NestedArrays", `{"a":[{"id":["` + id + `",["` + id + `"]]}],"b":{"c":{"id":"` + id + `"}}}`, 3, false},
+		{"Lowercase", `{"id":"` + strings.ToLower(id) + `"}`, 1, false},
+		{"NotULID", `{"id":"` + id + `X"}`, 0, false},
+		{"OtherFields", `{"other":"` + id + `","msg":"` + id + `"}`, 0, false},
+		{"Text", "level=info id=" + id, 0, true},
+		{"Array", `["` + id + `"]`, 0, true},
+		{"String", `"` + id + `"`, 0, true},
+		{"Truncated", `{"id":"` + id + `","msg":"cut`, 0, true},
+		{"TrailingComma", `{"id":"` + id + `",}`, 0, true},
+		{"Trailing", `{"id":"` + id + `"} {"id":"` + id + `"}`, 0, true},
+		{"Long", `{"id":"` + id + `","msg":"` + strings.Repeat("x", 200) + `"}`, 0, true},
+	}
+
+	for _, tc := range testCases {
+		// The line is followed by a valid line to check that scanning continues.
+		input := tc.line + "\n" + `{"id":"` + id + `"}`
+		s := scan.New(strings.NewReader(input), []string{"id"}, scan.MaxLineSize(128))
+		got := collect(t, s)
+		if len(got) != tc.matches+1 || got[len(got)-1].Line != 2 {
+			t.Errorf("%s: got %v, want %d matches in the line", tc.name, got, tc.matches)
+		}
+
+		if malformed := s.Malformed() == 1; malformed != tc.malformed {
+			t.Errorf("%s: expected malformed %t, got %d malformed lines", tc.name, tc.malformed, s.Malformed())
+		}
+	}
+	// Blank lines are not counted by Lines but are counted by the line numbers of
+	// matches, which are the physical lines of the input.
+	s := scan.New(strings.NewReader(`{"a":1}`+"\n\n \r\n"+`{"id":"`+id+`"}`+"\n"), []string{"id"})
+	if got := collect(t, s); len(got) != 1 || got[0].Line != 4 || s.Lines() != 2 {
+		t.Errorf("got %v in %d lines, want a match in line 4 of 2 lines", got, s.Lines())
+	}
+}
+
+func TestScannerStreaming(t *testing.T) {
+	t.Parallel()
+
+	// Lines longer than the buffer of the reader are read in fragments, and
+	// reading one byte at a time does not change the result. The first 500 lines
+	// fit in the maximum line size.
+	var buf bytes.Buffer
+	line := func(i int) string {
+		return `{"msg":"` + strings.Repeat("x", i*10) + `","request_id":"` + requestID.String() + `"}`
+	}
+
+	for i := 0; i < 1000; i++ {
+		buf.WriteString(line(i) + "\n")
+	}
+
+	for name, r := range map[string]io.Reader{"Buffered": bytes.NewReader(buf.Bytes()), "OneByte": iotest.OneByteReader(bytes.NewReader(buf.Bytes()))} {
+		s := scan.New(r, []string{"request_id"}, scan.MaxLineSize(len(line(499))))
+		got := collect(t, s)
+		if len(got) != 500 || got[499].Line != 500 || s.Malformed() != 500 {
+			t.Errorf("%s: got %d matches and %d malformed lines", name, len(got), s.Malformed())
+		}
+	}
+
+	// Iteration continues where it stopped.
+	s := scan.New(bytes.NewReader(syntheticLog(t)), []string{"request_id"})
+	for m := range s.All() {
+		if m.Line != 1 {
+			t.Fatalf("got %v first", m)
+		}
+		break
+	}
+
+	if got := collect(t, s); len(got) != 1 || got[0].Line != 2 {
+		t.Errorf("expected to continue with the second line, got %v", got)
+	}
+}
+
+func TestScannerGzip(t *testing.T) {
+	t.Parallel()
+
+	// Concatenated members, e.g. of appended rotated logs, are read as one stream.
+	data := syntheticLog(t)
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+	}
+
+	s := scan.New(&buf, []string{"request_id"}, scan.Gzip())
+	if got := collect(t, s); len(got) != 4 || got[3].Line != 9 {
+		t.Errorf("got %v", got)
+	}
+
+	// Input that is not compressed is an error.
+	s = scan.New(bytes.NewReader(data), []string{"request_id"}, scan.Gzip())
+	if got := slices.Collect(s.All()); len(got) != 0 || !errors.Is(s.Err(), gzip.ErrHeader) {
+		t.Errorf("got %v (%v), want %v", got, s.Err(), gzip.ErrHeader)
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	t.Parallel()
+
+	// The lines before a read error are scanned.
+	r := io.MultiReader(bytes.NewReader(syntheticLog(t)), iotest.ErrReader(io.ErrUnexpectedEOF))
+	s := scan.New(r, []string{"request_id"})
+	got := slices.Collect(s.All())
+	if len(got) != 2 || s.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("got %v (%v), want %v", got, s.Err(), io.ErrUnexpectedEOF)
+	}
+
+	if got := slices.Collect(s.All()); len(got) != 0 {
+		t.Errorf("expected no matches after an error, got %v", got)
+	}
+}
@@ -0,0 +1,60 @@
+package ulid
+
+import "encoding/json"
+
+const (
+	// Pattern is the regular expression that matches canonical ULID strings, e.g.
+	// strings accepted by ParseCanonical: 26 uppercase Crockford base32 characters
+	// where the first character is 0-7 so that the ULID does not overflow 128 bits.
+	Pattern = "^[0-7][0-9A-HJKMNP-TV-Z]{25}$"
+
+	// PatternLenient is the regular expression that matches any ULID string that
+	// is accepted by ParseStrict, which allows lowercase characters. Note that a
+	// (?i) flag is not used since Go regular expressions would then also match
+	// unicode characters such as the Kelvin sign that fold to K.
+	PatternLenient = "^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$"
+)
+
+// schemaJSON is the JSON Schema fragment for a canonical ULID string, marshaled
+// once so that SchemaJSON only has to copy it.
+var schemaJSON = func() []byte {
+	data, err := json.Marshal(struct {
+		Type      string `json:"type"`
+		Pattern   string `json:"pattern"`
+		MinLength int    `json:"minLength"`
+		MaxLength int    `json:"maxLength"`
+	}{"string", Pattern, EncodedSize, EncodedSize})
+
+	if err != nil {
+		panic(err)
+	}
+	return data
+}()
+
+// SchemaJSON returns a JSON Schema fragment describing a canonical ULID string
+// that can be embedded in OpenAPI specifications or other JSON Schemas:
+//
+//	{"type":"string","pattern":"^[0-7][0-9A-HJKMNP-TV-Z]{25}$","minLength":26,"maxLength":26}
+//
+// A new slice is returned on every call so that it can be safely modified.
+func SchemaJSON() []byte {
+	data := make([]byte, len(schemaJSON))
+	copy(data, schemaJSON)
+	return data
+}
+
+// MatchString returns true if s is a ULID string that would be accepted by
+// ParseStrict; it is equivalent to matching PatternLenient but does not use
+// regular expressions or allocate.
+func MatchString(s string) bool {
+	if len(s) != EncodedSize || s[0] < '0' || s[0] > '7' {
+		return false
+	}
+
+	for i := 1; i < EncodedSize; i++ {
+		if dec[s[i]] == 0xFF {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package ulid_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+var (
+	patternRE        = regexp.MustCompile(ulid.Pattern)
+	patternLenientRE = regexp.MustCompile(ulid.PatternLenient)
+)
+
+func TestSchemaJSON(t *testing.T) {
+	t.Parallel()
+
+	want := `{"type":"string","pattern":"^[0-7][0-9A-HJKMNP-TV-Z]{25}$","minLength":26,"maxLength":26}`
+	if got := string(ulid.SchemaJSON()); got != want {
+		t.Fatalf("got schema %s, want %s", got, want)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(ulid.SchemaJSON(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if schema["pattern"] != ulid.Pattern {
+		t.Errorf("got pattern %v, want %s", schema["pattern"], ulid.Pattern)
+	}
+
+	// Modifying the returned slice does not modify the schema.
+	data := ulid.SchemaJSON()
+	data[0] = 'X'
+	if ulid.SchemaJSON()[0] != '{' {
+		t.Error("expected SchemaJSON to return a copy")
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	t.Parallel()
+
+	check := func(s string) {
+		t.Helper()
+		_, err := ulid.ParseStrict(s)
+		strict := err == nil
+
+		if got := ulid.MatchString(s); got != strict {
+			t.Fatalf("MatchString(%q) = %t but ParseStrict err = %v", s, got, err)
+		}
+
+		if got := patternLenientRE.MatchString(s); got != strict {
+			t.Fatalf("PatternLenient match %q = %t but ParseStrict err = %v", s, got, err)
+		}
+
+		if got, want := patternRE.MatchString(s), ulid.IsCanonical(s); got != want {
+			t.Fatalf("Pattern match %q = %t but IsCanonical = %t", s, got, want)
+		}
+	}
+
+	// Every single byte substitution at every position of a valid ULID.
+	base := ulid.Make().String()
+	for i := 0; i < ulid.EncodedSize; i++ {
+		for c := 0; c < 256; c++ {
+			check(base[:i] + string([]byte{byte(c)}) + base[i+1:])
+		}
+	}
+
+	// Unicode characters that case fold to valid characters (the 3 byte Kelvin
+	// sign folds to K) keep the string 26 bytes long.
+	check(base[:23] + "\u212a")
+
+	// Lengths and case variations.
+	for _, s := range []string{"", base[:25], base + "0", strings.ToLower(base), "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "80000000000000000000000000"} {
+		check(s)
+	}
+
+	// Random corpus biased towards almost-valid strings.
+	const alphabet = ulid.Encoding + "abcdefghjkmnpqrstvwxyzILOUilou!- \x00\xff"
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	buf := make([]byte, ulid.EncodedSize+1)
+	for i := 0; i < 200000; i++ {
+		n := ulid.EncodedSize
+		if rng.Intn(10) == 0 {
+			n = rng.Intn(len(buf) + 1)
+		}
+
+		for j := 0; j < n; j++ {
+			if rng.Intn(8) == 0 {
+				buf[j] = alphabet[rng.Intn(len(alphabet))]
+			} else {
+				buf[j] = ulid.Encoding[rng.Intn(len(ulid.Encoding))]
+			}
+		}
+
+		if n > 0 && rng.Intn(2) == 0 {
+			buf[0] = byte('0' + rng.Intn(8))
+		}
+		check(string(buf[:n]))
+	}
+}
+
+func FuzzMatchString(f *testing.F) {
+	f.Add(ulid.Make().String())
+	f.Add(strings.ToLower(ulid.Make().String()))
+	f.Add("80000000000000000000000000")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		_, err := ulid.ParseStrict(s)
+		if got := ulid.MatchString(s); got != (err == nil) {
+			t.Fatalf("MatchString(%q) = %t but ParseStrict err = %v", s, got, err)
+		}
+
+		if got := patternLenientRE.MatchString(s); got != (err == nil) {
+			t.Fatalf("PatternLenient match %q = %t but ParseStrict err = %v", s, got, err)
+		}
+
+		if got, want := patternRE.MatchString(s), ulid.IsCanonical(s); got != want {
+			t.Fatalf("Pattern match %q = %t but IsCanonical = %t", s, got, want)
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package ulid
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSkewSources is the default maximum number of sources that a
+// SkewValidator tracks.
+const DefaultSkewSources = 1024
+
+// SkewValidator validates that ULIDs ingested from several sources, e.g. the
+// regions of a multi-region deployment whose clocks are kept within a bound by
+// NTP, are only out of order by as much as the clock skew allows, so that the
+// ordering anomalies that are expected at merge time can be told apart from
+// corrupt data. Each ULID is observed with its source and the time it arrived;
+// a ULID is an anomaly if its timestamp is:
+//
+//   - ahead of its arrival by more than the skew, since a ULID cannot arrive before
+//     it was generated unless the clock of its source is fast (ErrSkewAhead),
+//   - behind the latest timestamp of its source by more than the skew, which is a
+//     source whose clock was stepped back or that replays old IDs
+//     (ErrSkewRegressed), or
+//   - outside the absolute bounds of SkewBounds, e.g. before the system existed
+//     (ErrSkewOutOfBounds).
+//
+// A ULID that is behind its arrival is not an anomaly no matter how far, since it
+// may have been delayed in transit; the regression against its own source
+// catches a slow clock once the source has produced a later ID. The latest
+// timestamp of a source is the largest timestamp of the ULIDs of the source that
+// were not anomalies, so that a single ID from the future does not make every
+// following ID of the source a regression.
+//
+// The state of each source is fixed in size and at most SkewSources sources are
+// tracked, so the memory of the validator is bounded. A SkewValidator is safe for
+// concurrent use.
+type SkewValidator struct {
+	mu         sync.Mutex
+	skew       time.Duration
+	min        uint64
+	max        uint64
+	maxSources int
+	sources    map[string]*skewSource
+	rejected   uint64
+}
+
+type skewSource struct {
+	report  SkewSourceReport
+	started bool
+}
+
+// SkewOption configures a SkewValidator.
+type SkewOption func(*SkewValidator)
+
+// SkewBounds sets the absolute bounds of the timestamps of the ULIDs, which are
+// inclusive and are not widened by the skew. A zero time leaves that side of the
+// bounds open, which is the default.
+func SkewBounds(min, max time.Time) SkewOption {
+	return func(v *SkewValidator) {
+		if !min.IsZero() {
+			v.min = Timestamp(min)
+		}
+
+		if !max.IsZero() {
+			v.max = Timestamp(max)
+		}
+	}
+}
+
+// SkewSources sets the maximum number of sources that are tracked (default
+// DefaultSkewSources). ULIDs from new sources beyond the maximum are rejected
+// with ErrSkewSources.
+func SkewSources(n int) SkewOption {
+	return func(v *SkewValidator) {
+		v.maxSources = max(n, 1)
+	}
+}
+
+// NewSkewValidator creates a validator that allows the clocks of the sources to be
+// skewed by up to maxSkew, configured by the options. A negative skew is zero.
+func NewSkewValidator(maxSkew time.Duration, opts ...SkewOption) *SkewValidator {
+	v := &SkewValidator{
+		skew:       max(maxSkew, 0),
+		max:        maxTime,
+		maxSources: DefaultSkewSources,
+		sources:    make(map[string]*skewSource),
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// SkewError describes a ULID whose timestamp is an anomaly of a SkewValidator.
+type SkewError struct {
+	Source  string        // The source of the ULID
+	ID      ULID          // The anomalous ULID
+	Arrival time.Time     // The time the ULID arrived
+	Skew    time.Duration // How far the timestamp is ahead, behind, or out of bounds
+	Err     error         // ErrSkewAhead, ErrSkewRegressed, or ErrSkewOutOfBounds
+}
+
+func (e *SkewError) Error() string {
+	return fmt.Sprintf("%s: %s from source %q by %s", e.Err, e.ID, e.Source, e.Skew)
+}
+
+func (e *SkewError) Unwrap() error {
+	return e.Err
+}
+
+// Observe validates the ULID from the source that arrived at the arrival time and
+// records it in the report. A *SkewError is returned if its timestamp is an
+// anomaly, wrapping ErrSkewOutOfBounds, ErrSkewAhead, or ErrSkewRegressed, which
+// are checked in that order; a ULID that is exactly at the skew budget is not an
+// anomaly. If the source is new and the validator already tracks the maximum
+// number of sources, ErrSkewSources is returned and the ULID is not recorded.
+func (v *SkewValidator) Observe(source string, id ULID, arrival time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	src, ok := v.sources[source]
+	if !ok {
+		if len(v.sources) >= v.maxSources {
+			v.rejected++
+			return fmt.Errorf("%w: %q is not one of the %d sources", ErrSkewSources, source, v.maxSources)
+		}
+
+		src = &skewSource{report: SkewSourceReport{Source: source}}
+		v.sources[source] = src
+	}
+
+	report := &src.report
+	report.Observed++
+
+	ms := id.Time()
+	if ms < v.min || ms > v.max {
+		report.OutOfBounds++
+		skew := millis(v.min - ms)
+		if ms > v.max {
+			skew = millis(ms - v.max)
+		}
+		return &SkewError{Source: source, ID: id, Arrival: arrival, Skew: skew, Err: ErrSkewOutOfBounds}
+	}
+
+	var err error
+	if ahead := id.Timestamp().Sub(arrival); ahead > 0 {
+		report.MaxAhead = max(report.MaxAhead, ahead)
+		if ahead > v.skew {
+			report.Ahead++
+			err = &SkewError{Source: source, ID: id, Arrival: arrival, Skew: ahead, Err: ErrSkewAhead}
+		}
+	}
+
+	if latest := src.report.Latest.Time(); src.started && ms < latest {
+		regression := millis(latest - ms)
+		report.MaxRegression = max(report.MaxRegression, regression)
+		if regression > v.skew && err == nil {
+			report.Regressed++
+			err = &SkewError{Source: source, ID: id, Arrival: arrival, Skew: regression, Err: ErrSkewRegressed}
+		}
+	}
+
+	if err == nil && (!src.started || id.Compare(report.Latest) > 0) {
+		src.started, report.Latest = true, id
+	}
+	return err
+}
+
+// millis returns the duration of ms milliseconds, saturating at the maximum
+// duration of about 292 years so that a gap between timestamps that are far apart
+// does not overflow.
+func millis(ms uint64) time.Duration {
+	if ms > math.MaxInt64/uint64(time.Millisecond) {
+		return math.MaxInt64
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// SkewReport is the summary of the ULIDs observed by a SkewValidator.
+type SkewReport struct {
+	MaxSkew  time.Duration      `json:"max_skew_ns"` // The skew that is allowed before an ID is an anomaly
+	Rejected uint64             `json:"rejected"`    // Number of IDs from sources beyond the maximum number of sources
+	Sources  []SkewSourceReport `json:"sources"`     // The reports of the sources, ordered by source
+}
+
+// SkewSourceReport summarizes the ULIDs observed from a source. The maximum skews
+// include the anomalies, e.g. to see how far a misbehaving source is off, but not
+// the IDs that were out of bounds.
+type SkewSourceReport struct {
+	Source        string        `json:"source"`            // The name of the source
+	Observed      uint64        `json:"observed"`          // Number of IDs observed, including anomalies
+	Latest        ULID          `json:"latest"`            // The ID with the latest timestamp that was not an anomaly
+	MaxAhead      time.Duration `json:"max_ahead_ns"`      // The most a timestamp was ahead of its arrival
+	MaxRegression time.Duration `json:"max_regression_ns"` // The most a timestamp was behind the latest timestamp of the source
+	Ahead         uint64        `json:"ahead"`             // Number of IDs ahead of their arrival by more than the skew
+	Regressed     uint64        `json:"regressed"`         // Number of IDs behind the latest timestamp by more than the skew
+	OutOfBounds   uint64        `json:"out_of_bounds"`     // Number of IDs outside of the bounds
+}
+
+// Anomalies returns the number of IDs of the source that were anomalies.
+func (r SkewSourceReport) Anomalies() uint64 {
+	return r.Ahead + r.Regressed + r.OutOfBounds
+}
+
+// Report returns the report of the ULIDs that have been observed so far; the
+// validator can continue to observe ULIDs after a report.
+func (v *SkewValidator) Report() *SkewReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	report := &SkewReport{
+		MaxSkew:  v.skew,
+		Rejected: v.rejected,
+		Sources:  make([]SkewSourceReport, 0, len(v.sources)),
+	}
+
+	for _, src := range v.sources {
+		report.Sources = append(report.Sources, src.report)
+	}
+
+	slices.SortFunc(report.Sources, func(x, y SkewSourceReport) int {
+		return strings.Compare(x.Source, y.Source)
+	})
+	return report
+}
@@ -0,0 +1,271 @@
+package ulid_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestSkewValidator(t *testing.T) {
+	t.Parallel()
+
+	// Three regions with clocks that are off by up to 40ms send one ID every
+	// millisecond that takes 5 to 20ms to arrive.
+	epoch := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	offsets := map[string]time.Duration{
+		"us-east":  -40 * time.Millisecond,
+		"eu-west":  0,
+		"ap-south": 30 * time.Millisecond,
+	}
+
+	validator := ulid.NewSkewValidator(50 * time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		now := epoch.Add(time.Duration(i) * time.Millisecond)
+		for source, offset := range offsets {
+			id := ulid.MustNew(ulid.Timestamp(now.Add(offset)), rand.Reader)
+			arrival := now.Add(time.Duration(5+i%16) * time.Millisecond)
+			if err := validator.Observe(source, id, arrival); err != nil {
+				t.Fatalf("%s: %v", source, err)
+			}
+		}
+	}
+
+	report := validator.Report()
+	if report.MaxSkew != 50*time.Millisecond || report.Rejected != 0 || len(report.Sources) != 3 {
+		t.Fatalf("got report %+v", report)
+	}
+
+	for i, source := range []string{"ap-south", "eu-west", "us-east"} {
+		src := report.Sources[i]
+		if src.Source != source || src.Observed != 1000 || src.Anomalies() != 0 || src.MaxRegression != 0 {
+			t.Errorf("got source report %+v", src)
+		}
+
+		if want := max(offsets[source]-5*time.Millisecond, 0); src.MaxAhead != want {
+			t.Errorf("%s: got max ahead %s, want %s", source, src.MaxAhead, want)
+		}
+
+		if last := epoch.Add(999*time.Millisecond + offsets[source]); !src.Latest.Timestamp().Equal(last) {
+			t.Errorf("%s: got latest %s, want %s", source, src.Latest.Timestamp(), last)
+		}
+	}
+}
+
+func TestSkewValidatorMisbehaving(t *testing.T) {
+	t.Parallel()
+
+	epoch := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	validator := ulid.NewSkewValidator(50 * time.Millisecond)
+	observe := func(source string, offset time.Duration, arrival time.Duration) error {
+		id := ulid.MustNew(ulid.Timestamp(epoch.Add(offset)), rand.Reader)
+		return validator.Observe(source, id, epoch.Add(arrival))
+	}
+
+	// The healthy source is not affected by the misbehaving source.
+	for i := 0; i < 10; i++ {
+		ms := time.Duration(i) * time.Millisecond
+		if err := observe("healthy", ms, ms+10*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The clock of the source runs 200ms fast, then is stepped back by 1s.
+	testCases := []struct {
+		offset  time.Duration
+		arrival time.Duration
+		err     error
+		skew    time.Duration
+	}{
+		{0, 10 * time.Millisecond, nil, 0},
+		{20 * time.Millisecond, 20 * time.Millisecond, nil, 0},
+		{230 * time.Millisecond, 30 * time.Millisecond, ulid.ErrSkewAhead, 200 * time.Millisecond},
+		{240 * time.Millisecond, 40 * time.Millisecond, ulid.ErrSkewAhead, 200 * time.Millisecond},
+
+		// The IDs from the future are not the latest of the source.
+		{45 * time.Millisecond, 50 * time.Millisecond, nil, 0},
+		{-950 * time.Millisecond, 60 * time.Millisecond, ulid.ErrSkewRegressed, 995 * time.Millisecond},
+		{-940 * time.Millisecond, 70 * time.Millisecond, ulid.ErrSkewRegressed, 985 * time.Millisecond},
+		{50 * time.Millisecond, 80 * time.Millisecond, nil, 0},
+	}
+
+	for i, tc := range testCases {
+		err := observe("misbehaving", tc.offset, tc.arrival)
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Fatalf("%d: got %v, want %v", i, err, tc.err)
+		}
+
+		var serr *ulid.SkewError
+		if err != nil && (!errors.As(err, &serr) || serr.Source != "misbehaving" || serr.Skew != tc.skew || !serr.Arrival.Equal(epoch.Add(tc.arrival))) {
+			t.Errorf("%d: got %#v", i, err)
+		}
+	}
+
+	report := validator.Report()
+	if len(report.Sources) != 2 || report.Sources[0].Anomalies() != 0 {
+		t.Fatalf("got report %+v", report)
+	}
+
+	src := report.Sources[1]
+	if src.Observed != 8 || src.Ahead != 2 || src.Regressed != 2 || src.OutOfBounds != 0 || src.Anomalies() != 4 {
+		t.Errorf("got source report %+v", src)
+	}
+
+	if src.MaxAhead != 200*time.Millisecond || src.MaxRegression != 995*time.Millisecond || !src.Latest.Timestamp().Equal(epoch.Add(50*time.Millisecond)) {
+		t.Errorf("got max ahead %s, max regression %s, and latest %s", src.MaxAhead, src.MaxRegression, src.Latest.Timestamp())
+	}
+}
+
+func TestSkewValidatorBoundary(t *testing.T) {
+	t.Parallel()
+
+	epoch := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	id := func(offset time.Duration) ulid.ULID {
+		return ulid.MustNew(ulid.Timestamp(epoch.Add(offset)), rand.Reader)
+	}
+
+	// Exactly at the budget is allowed; a nanosecond past it is not.
+	validator := ulid.NewSkewValidator(50 * time.Millisecond)
+	if err := validator.Observe("a", id(50*time.Millisecond), epoch); err != nil {
+		t.Errorf("at the budget: %v", err)
+	}
+
+	if err := validator.Observe("b", id(50*time.Millisecond), epoch.Add(-time.Nanosecond)); !errors.Is(err, ulid.ErrSkewAhead) {
+		t.Errorf("past the budget: got %v", err)
+	}
+
+	// The regression is in milliseconds, the precision of the timestamps.
+	if err := validator.Observe("a", id(0), epoch.Add(time.Second)); err != nil {
+		t.Errorf("regression at the budget: %v", err)
+	}
+
+	if err := validator.Observe("a", id(-time.Millisecond), epoch.Add(time.Second)); !errors.Is(err, ulid.ErrSkewRegressed) {
+		t.Errorf("regression past the budget: got %v", err)
+	}
+
+	// A negative skew is zero, which allows no regression and no ID from the future.
+	validator = ulid.NewSkewValidator(-time.Second)
+	for i, tc := range []struct {
+		offset time.Duration
+		err    error
+	}{
+		{0, nil},
+		{0, nil},
+		{time.Millisecond, ulid.ErrSkewAhead},
+		{-time.Millisecond, ulid.ErrSkewRegressed},
+	} {
+		if err := validator.Observe("a", id(tc.offset), epoch); !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("%d: got %v, want %v", i, err, tc.err)
+		}
+	}
+}
+
+func TestSkewValidatorBounds(t *testing.T) {
+	t.Parallel()
+
+	lo := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	hi := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	validator := ulid.NewSkewValidator(time.Hour, ulid.SkewBounds(lo, hi))
+
+	far := hi.Add(24 * time.Hour)
+	testCases := []struct {
+		ts   time.Time
+		err  error
+		skew time.Duration
+	}{
+		{lo, nil, 0},
+		{hi, nil, 0},
+		{lo.Add(-time.Millisecond), ulid.ErrSkewOutOfBounds, time.Millisecond},
+		{hi.Add(time.Millisecond), ulid.ErrSkewOutOfBounds, time.Millisecond},
+		{time.UnixMilli(0), ulid.ErrSkewOutOfBounds, lo.Sub(time.UnixMilli(0))},
+		{ulid.Time(ulid.MaxTime()), ulid.ErrSkewOutOfBounds, math.MaxInt64},
+	}
+
+	for i, tc := range testCases {
+		err := validator.Observe("a", ulid.MustNew(ulid.Timestamp(tc.ts), rand.Reader), far)
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("%d: got %v, want %v", i, err, tc.err)
+		}
+
+		var serr *ulid.SkewError
+		if err != nil && (!errors.As(err, &serr) || serr.Skew != tc.skew) {
+			t.Errorf("%d: got %v, want a skew of %s", i, err, tc.skew)
+		}
+	}
+
+	// IDs out of bounds are not included in the maximum skews nor the latest ID.
+	src := validator.Report().Sources[0]
+	if src.Observed != 6 || src.OutOfBounds != 4 || src.Anomalies() != 4 || src.MaxRegression != 0 || !src.Latest.Timestamp().Equal(hi) {
+		t.Errorf("got source report %+v", src)
+	}
+
+	// Either bound may be open.
+	validator = ulid.NewSkewValidator(time.Hour, ulid.SkewBounds(time.Time{}, hi))
+	if err := validator.Observe("a", ulid.Zero, far); err != nil {
+		t.Errorf("open lower bound: %v", err)
+	}
+
+	validator = ulid.NewSkewValidator(time.Hour, ulid.SkewBounds(lo, time.Time{}))
+	if err := validator.Observe("a", ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"), time.UnixMilli(int64(ulid.MaxTime()))); err != nil {
+		t.Errorf("open upper bound: %v", err)
+	}
+}
+
+func TestSkewValidatorSources(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	validator := ulid.NewSkewValidator(time.Second, ulid.SkewSources(2))
+	for i, source := range []string{"a", "b", "c", "a", "d", "b"} {
+		err := validator.Observe(source, ulid.MustNew(ulid.Timestamp(now), rand.Reader), now)
+		if rejected := source == "c" || source == "d"; rejected != errors.Is(err, ulid.ErrSkewSources) || (!rejected && err != nil) {
+			t.Errorf("%d: got %v from source %q", i, err, source)
+		}
+	}
+
+	report := validator.Report()
+	if report.Rejected != 2 || len(report.Sources) != 2 || report.Sources[0].Observed != 2 || report.Sources[1].Observed != 2 {
+		t.Errorf("got report %+v", report)
+	}
+}
+
+func TestSkewValidatorConcurrency(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	validator := ulid.NewSkewValidator(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				source := fmt.Sprintf("region-%d", i%3)
+				id := ulid.MustNew(ulid.Timestamp(now.Add(time.Duration(i%10)*time.Millisecond)), rand.Reader)
+				validator.Observe(source, id, now.Add(time.Duration(i)*time.Millisecond))
+				if i%100 == 0 {
+					validator.Report()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var observed uint64
+	for _, src := range validator.Report().Sources {
+		observed += src.Observed
+		if src.Anomalies() != 0 {
+			t.Errorf("got source report %+v", src)
+		}
+	}
+
+	if observed != 2000 {
+		t.Errorf("got %d observed IDs, want 2000", observed)
+	}
+}
@@ -0,0 +1,108 @@
+package ulid
+
+import (
+	"context"
+	"io"
+	"iter"
+	"sync/atomic"
+)
+
+// Stream returns an infinite iterator of ULIDs created with Make, each with the
+// current time when it is yielded, that ends when the context is done or the loop
+// over it breaks. The iterator is pull-based and does not start any goroutines,
+// so there is nothing to clean up when iteration stops, and the context is only
+// checked between ULIDs. Like Make, it panics if DefaultEntropy returns an error.
+func Stream(ctx context.Context) iter.Seq[ULID] {
+	return stream(ctx, func() (ULID, error) { return Make(), nil })
+}
+
+// StreamSecure is like Stream but creates the ULIDs with MakeSecure.
+func StreamSecure(ctx context.Context) iter.Seq[ULID] {
+	return stream(ctx, func() (ULID, error) { return MakeSecure(), nil })
+}
+
+// StreamWith is like Stream but creates the ULIDs with the generator. Iteration
+// also ends at the first error from the generator, which is then returned by the
+// Err method of the generator.
+func StreamWith(ctx context.Context, gen *Generator) iter.Seq[ULID] {
+	return func(yield func(ULID) bool) {
+		gen.err.Store(nil)
+		stream(ctx, gen.capture)(yield)
+	}
+}
+
+func stream(ctx context.Context, next func() (ULID, error)) iter.Seq[ULID] {
+	return func(yield func(ULID) bool) {
+		for ctx.Err() == nil {
+			id, err := next()
+			if err != nil || !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// Take returns the first n ULIDs of the sequence, or all of them if the sequence
+// ends first, e.g. Take(Stream(ctx), 10).
+func Take(seq iter.Seq[ULID], n int) []ULID {
+	if n <= 0 {
+		return nil
+	}
+
+	ids := make([]ULID, 0, n)
+	for id := range seq {
+		ids = append(ids, id)
+		if len(ids) == n {
+			break
+		}
+	}
+	return ids
+}
+
+// Generator creates ULIDs with the timestamps of a clock and the entropy of a
+// reader, e.g. to stream ULIDs with a fixed time in tests. The zero value creates
+// ULIDs like Make but returns errors instead of panicking. A Generator is safe for
+// concurrent use if its clock and entropy are, but must not be used by more than
+// one StreamWith at a time.
+type Generator struct {
+	// Clock returns the timestamp of each ULID in Unix milliseconds, e.g. the Now
+	// method of a MonotonicClock. If nil, the current time from Now is used.
+	Clock func() uint64
+
+	// Entropy is the entropy source of the ULIDs. If nil, DefaultEntropy is used.
+	Entropy io.Reader
+
+	err atomic.Pointer[error]
+}
+
+// New returns a ULID with the current time of the clock and entropy read from
+// the entropy source, returning any error from New.
+func (g *Generator) New() (ULID, error) {
+	ms := Now()
+	if g.Clock != nil {
+		ms = g.Clock()
+	}
+
+	entropy := g.Entropy
+	if entropy == nil {
+		entropy = DefaultEntropy()
+	}
+	return New(ms, entropy)
+}
+
+// Err returns the error that ended the most recent StreamWith of the generator,
+// if any. It is nil if the stream ended because the context was done. Err may be
+// called while the stream is being iterated in another goroutine.
+func (g *Generator) Err() error {
+	if err := g.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func (g *Generator) capture() (id ULID, err error) {
+	if id, err = g.New(); err != nil {
+		g.err.Store(&err)
+	}
+	return id, err
+}
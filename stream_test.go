@@ -0,0 +1,190 @@
+package ulid_test
+
+import (
+	"context"
+	"io"
+	"iter"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	for name, stream := range map[string]func(context.Context) iter.Seq[ulid.ULID]{
+		"Stream":       ulid.Stream,
+		"StreamSecure": ulid.StreamSecure,
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		start := time.Now().Add(-time.Millisecond)
+
+		// The stream ends at the first check after the context is canceled.
+		n := 0
+		for id := range stream(ctx) {
+			if ts := id.Timestamp(); ts.Before(start.Truncate(time.Millisecond)) || ts.After(time.Now()) {
+				t.Errorf("%s: unexpected timestamp %s", name, ts)
+			}
+
+			if n++; n == 100 {
+				cancel()
+			}
+		}
+
+		if n != 100 {
+			t.Errorf("%s: expected the stream to end after 100 ULIDs, got %d", name, n)
+		}
+
+		// A stream with a done context yields nothing.
+		if ids := ulid.Take(stream(ctx), 10); len(ids) != 0 {
+			t.Errorf("%s: expected no ULIDs after cancellation, got %d", name, len(ids))
+		}
+	}
+}
+
+func TestStreamWith(t *testing.T) {
+	t.Parallel()
+
+	// Each ULID is created with the time of the clock when it is yielded.
+	var calls atomic.Uint64
+	gen := &ulid.Generator{Clock: func() uint64 { return 1000 + calls.Add(1) }}
+	for i, id := range ulid.Take(ulid.StreamWith(context.Background(), gen), 10) {
+		if id.Time() != 1001+uint64(i) {
+			t.Errorf("ULID %d: got time %d, want %d", i, id.Time(), 1001+i)
+		}
+	}
+
+	// Breaking out of the loop stops calling the clock.
+	if n := calls.Load(); n != 10 {
+		t.Errorf("expected the clock to be called 10 times, got %d", n)
+	}
+
+	// With a frozen clock and monotonic entropy the ULIDs are strictly increasing.
+	frozen := &ulid.Generator{
+		Clock:   func() uint64 { return 1738876313290 },
+		Entropy: ulid.Monotonic(rand.New(rand.NewSource(42)), 0),
+	}
+
+	var prev ulid.ULID
+	for _, id := range ulid.Take(ulid.StreamWith(context.Background(), frozen), 1000) {
+		if id.Time() != 1738876313290 || id.Compare(prev) <= 0 {
+			t.Fatalf("expected strictly increasing ULIDs, got %s after %s", id, prev)
+		}
+		prev = id
+	}
+
+	if err := frozen.Err(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestStreamWithError(t *testing.T) {
+	t.Parallel()
+
+	// The stream ends when the entropy is exhausted.
+	gen := &ulid.Generator{Entropy: strings.NewReader(strings.Repeat("A", 25))}
+	if ids := ulid.Take(ulid.StreamWith(context.Background(), gen), 10); len(ids) != 2 {
+		t.Errorf("expected 2 ULIDs before the error, got %d", len(ids))
+	}
+
+	if err := gen.Err(); err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// The error is reset by the next stream.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range ulid.StreamWith(ctx, gen) {
+		t.Error("expected no ULIDs from a canceled stream")
+	}
+
+	if err := gen.Err(); err != nil {
+		t.Errorf("expected no error after a canceled stream, got %v", err)
+	}
+
+	// The zero value creates ULIDs like Make.
+	if id, err := (&ulid.Generator{}).New(); err != nil || id.IsZero() {
+		t.Errorf("got %s (%v), want a ULID", id, err)
+	}
+}
+
+func TestStreamWithErrConcurrent(t *testing.T) {
+	t.Parallel()
+
+	// Err is read while the stream runs in another goroutine (checked with -race).
+	gen := &ulid.Generator{Entropy: strings.NewReader(strings.Repeat("A", 10*100+5))}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range ulid.StreamWith(context.Background(), gen) {
+		}
+	}()
+
+	for polling := true; polling; {
+		select {
+		case <-done:
+			polling = false
+		default:
+			_ = gen.Err()
+		}
+	}
+
+	if err := gen.Err(); err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestStreamGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		for range ulid.Stream(ctx) {
+			break
+		}
+		_ = ulid.Take(ulid.StreamWith(ctx, &ulid.Generator{}), 3)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected no goroutines to be left running, got %d more", after-before)
+	}
+}
+
+func TestTake(t *testing.T) {
+	t.Parallel()
+
+	ids := []ulid.ULID{ulid.Make(), ulid.Make(), ulid.Make()}
+	for _, tc := range []struct {
+		n    int
+		want []ulid.ULID
+	}{
+		{-1, nil},
+		{0, nil},
+		{2, ids[:2]},
+		{3, ids},
+		{10, ids},
+	} {
+		if got := ulid.Take(slices.Values(ids), tc.n); !slices.Equal(got, tc.want) {
+			t.Errorf("Take(%d): got %v, want %v", tc.n, got, tc.want)
+		}
+	}
+}
+
+func BenchmarkStream(b *testing.B) {
+	b.ReportAllocs()
+	n := 0
+	for range ulid.Stream(context.Background()) {
+		if n++; n == b.N {
+			break
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package stress simulates bursts of ULID generation within a single frozen
+// millisecond to measure the practical limits of an entropy mode, e.g. before
+// deploying high-rate ID generation with a chosen monotonic increment.
+//
+// The limits follow from the 80 bits of entropy. Monotonic entropy starts each
+// millisecond at a random value s and adds a random increment between 1 and inc
+// for every further ULID, so on average (2^80-s)/((inc+1)/2) ULIDs fit in the
+// millisecond and a burst of k ULIDs overflows with a probability of about
+// k*(inc+1)/2^81. With the default inc of math.MaxUint32 a burst of a million
+// ULIDs overflows with a probability of about 2^-29, and with an inc of 1024
+// about 2^-51; in practice the rate is bounded by the entropy source rather than
+// by the entropy space. Sequential entropy overflows only after 2^80 ULIDs from
+// its start, while secure entropy never overflows (and never sorts the ULIDs of
+// a millisecond). SimulateBurst verifies these limits by running the generator.
+package stress
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+// Mode is the entropy mode of a simulated burst.
+type Mode string
+
+const (
+	// Default uses ulid.DefaultEntropy, as ulid.Make does.
+	Default Mode = "default"
+
+	// Secure uses ulid.SecureEntropy, as ulid.MakeSecure does.
+	Secure Mode = "secure"
+
+	// Monotonic uses ulid.Monotonic with the increment of the options.
+	Monotonic Mode = "monotonic"
+
+	// Sequential uses ulid.SequentialEntropy.
+	Sequential Mode = "sequential"
+)
+
+// Modes are the supported entropy modes.
+var Modes = []Mode{Default, Secure, Monotonic, Sequential}
+
+// DefaultDuration is the duration of a simulation if none is specified.
+const DefaultDuration = time.Second
+
+// checkEvery is the number of ULIDs generated between reads of the clock, so
+// that the clock does not dominate the measured rate.
+const checkEvery = 256
+
+// Options configure a simulated burst. The zero value runs the Default mode for
+// the DefaultDuration at the current millisecond.
+type Options struct {
+	// Mode is the entropy mode; if empty, Default is used.
+	Mode Mode
+
+	// Inc is the maximum increment of the Monotonic mode; zero is the default of
+	// ulid.Monotonic (math.MaxUint32). It is ignored by the other modes.
+	Inc uint64
+
+	// Duration is how long ULIDs are generated for if there is no overflow; if
+	// zero or negative, DefaultDuration is used.
+	Duration time.Duration
+
+	// Limit is the maximum number of ULIDs to generate; zero is no limit.
+	Limit uint64
+
+	// Timestamp is the frozen millisecond of every ULID in the burst; if zero,
+	// the time of the clock at the start of the burst is used.
+	Timestamp uint64
+
+	// Now is the clock that measures the duration of the burst; if nil, time.Now
+	// is used. Tests may inject a fake clock to run simulations deterministically.
+	Now func() time.Time
+
+	// Entropy is the random source of the Monotonic mode and the start of the
+	// counter of the Sequential mode; if nil, crypto/rand is used for Monotonic
+	// and Sequential starts at zero. It is ignored by the other modes.
+	Entropy io.Reader
+}
+
+// Report is the result of a simulated burst, which marshals to JSON e.g. for
+// dashboards. The allocation statistics are read from the runtime before and
+// after the burst, so they include any allocations by other goroutines.
+type Report struct {
+	Mode          Mode          `json:"mode"`
+	Inc           uint64        `json:"inc,omitempty"`
+	Timestamp     uint64        `json:"timestamp"`
+	Generated     uint64        `json:"generated"`
+	Overflowed    bool          `json:"overflowed"`
+	FirstOverflow uint64        `json:"first_overflow,omitempty"`
+	Elapsed       time.Duration `json:"elapsed_ns"`
+	Rate          float64       `json:"rate"`
+	Allocs        uint64        `json:"allocs"`
+	AllocBytes    uint64        `json:"alloc_bytes"`
+	AllocsPerID   float64       `json:"allocs_per_id"`
+}
+
+// SimulateBurst generates ULIDs with the frozen millisecond and entropy mode of
+// the options as fast as possible until the entropy overflows, the duration has
+// elapsed, or the limit is reached. The report includes the achieved rate in
+// ULIDs per second and, if the entropy overflowed, the number of ULIDs that were
+// generated before the first overflow. An error is returned for an unknown mode
+// or if the entropy source fails for any reason other than an overflow.
+func SimulateBurst(opts Options) (report Report, err error) {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	duration := opts.Duration
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+
+	mode := opts.Mode
+	if mode == "" {
+		mode = Default
+	}
+
+	var entropy io.Reader
+	switch mode {
+	case Default:
+		entropy = ulid.DefaultEntropy()
+	case Secure:
+		entropy = ulid.SecureEntropy()
+	case Monotonic:
+		src := opts.Entropy
+		if src == nil {
+			src = crand.Reader
+		}
+		entropy = ulid.Monotonic(src, opts.Inc)
+		report.Inc = opts.Inc
+	case Sequential:
+		var start [10]byte
+		if opts.Entropy != nil {
+			if _, err = io.ReadFull(opts.Entropy, start[:]); err != nil {
+				return report, err
+			}
+		}
+		entropy = ulid.SequentialEntropy(start)
+	default:
+		return report, fmt.Errorf("stress: unknown entropy mode %q", mode)
+	}
+
+	report.Mode = mode
+	report.Timestamp = opts.Timestamp
+	start := now()
+	if report.Timestamp == 0 {
+		report.Timestamp = ulid.Timestamp(start)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	for opts.Limit == 0 || report.Generated < opts.Limit {
+		if report.Generated > 0 && report.Generated%checkEvery == 0 && now().Sub(start) >= duration {
+			break
+		}
+
+		if _, err = ulid.New(report.Timestamp, entropy); err != nil {
+			if !errors.Is(err, ulid.ErrMonotonicOverflow) {
+				return report, err
+			}
+
+			report.Overflowed = true
+			report.FirstOverflow = report.Generated
+			err = nil
+			break
+		}
+		report.Generated++
+	}
+
+	report.Elapsed = now().Sub(start)
+	runtime.ReadMemStats(&after)
+
+	report.Allocs = after.Mallocs - before.Mallocs
+	report.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	if report.Generated > 0 {
+		report.AllocsPerID = float64(report.Allocs) / float64(report.Generated)
+	}
+
+	if report.Elapsed > 0 {
+		report.Rate = float64(report.Generated) / report.Elapsed.Seconds()
+	}
+	return report, nil
+}
@@ -0,0 +1,162 @@
+package stress_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/stress"
+)
+
+// steppingClock returns a clock that advances by step every time it is read.
+func steppingClock(step time.Duration) func() time.Time {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestSimulateBurstDuration(t *testing.T) {
+	t.Parallel()
+
+	// The clock is read every 256 ULIDs, so a clock that advances 1ms per read
+	// ends a 10ms burst after 2560 ULIDs regardless of the speed of the machine.
+	for _, mode := range stress.Modes {
+		report, err := stress.SimulateBurst(stress.Options{
+			Mode:      mode,
+			Duration:  10 * time.Millisecond,
+			Timestamp: 1738876313290,
+			Now:       steppingClock(time.Millisecond),
+		})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", mode, err)
+			continue
+		}
+
+		if report.Mode != mode || report.Timestamp != 1738876313290 || report.Overflowed || report.FirstOverflow != 0 {
+			t.Errorf("%s: unexpected report %+v", mode, report)
+		}
+
+		if report.Generated != 2560 || report.Elapsed != 11*time.Millisecond {
+			t.Errorf("%s: got %d ULIDs in %s, want 2560 in 11ms", mode, report.Generated, report.Elapsed)
+		}
+
+		if want := 2560 / 0.011; report.Rate < want-1e-6 || report.Rate > want+1e-6 {
+			t.Errorf("%s: got rate %f, want %f", mode, report.Rate, want)
+		}
+	}
+}
+
+func TestSimulateBurstOverflow(t *testing.T) {
+	t.Parallel()
+
+	// Entropy just below the maximum overflows after a few increments.
+	high := bytes.Repeat([]byte{0xFF}, 9)
+	testCases := []struct {
+		name   string
+		opts   stress.Options
+		before uint64
+	}{
+		{"Sequential", stress.Options{Mode: stress.Sequential, Entropy: bytes.NewReader(append(high, 0xFC))}, 4},
+		{"MonotonicInc1", stress.Options{Mode: stress.Monotonic, Inc: 1, Entropy: bytes.NewReader(append(high, 0xF6))}, 10},
+	}
+
+	for _, tc := range testCases {
+		tc.opts.Duration = time.Hour
+		tc.opts.Now = steppingClock(time.Millisecond)
+
+		report, err := stress.SimulateBurst(tc.opts)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		if !report.Overflowed || report.FirstOverflow != tc.before || report.Generated != tc.before {
+			t.Errorf("%s: got overflow after %d ULIDs (%+v), want %d", tc.name, report.FirstOverflow, report, tc.before)
+		}
+
+		if report.Elapsed != time.Millisecond {
+			t.Errorf("%s: expected the clock to be read only at the start and end, got %s", tc.name, report.Elapsed)
+		}
+	}
+}
+
+func TestSimulateBurstLimit(t *testing.T) {
+	t.Parallel()
+
+	// With a clock that never advances only the limit ends the burst.
+	report, err := stress.SimulateBurst(stress.Options{Mode: stress.Monotonic, Inc: 1024, Limit: 1000, Now: steppingClock(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Generated != 1000 || report.Overflowed || report.Elapsed != 0 || report.Rate != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	if report.AllocsPerID != float64(report.Allocs)/1000 {
+		t.Errorf("got %f allocs per ID, want %d/1000", report.AllocsPerID, report.Allocs)
+	}
+
+	// The timestamp is taken from the clock if it is not frozen explicitly.
+	now := time.Date(2025, 2, 6, 21, 11, 53, 290e6, time.UTC)
+	report, err = stress.SimulateBurst(stress.Options{Limit: 1, Now: func() time.Time { return now }})
+	if err != nil || report.Timestamp != ulid.Timestamp(now) || report.Mode != stress.Default {
+		t.Errorf("unexpected report %+v (%v)", report, err)
+	}
+}
+
+func TestSimulateBurstErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := stress.SimulateBurst(stress.Options{Mode: "quick"}); err == nil || err.Error() != `stress: unknown entropy mode "quick"` {
+		t.Errorf("got error %v, want unknown entropy mode", err)
+	}
+
+	if _, err := stress.SimulateBurst(stress.Options{Mode: stress.Sequential, Entropy: strings.NewReader("short")}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected an error for a short sequential start, got %v", err)
+	}
+
+	if _, err := stress.SimulateBurst(stress.Options{Timestamp: ulid.MaxTime() + 1, Limit: 1}); !errors.Is(err, ulid.ErrBigTime) {
+		t.Errorf("got error %v, want %v", err, ulid.ErrBigTime)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	t.Parallel()
+
+	report := stress.Report{
+		Mode:          stress.Monotonic,
+		Inc:           1024,
+		Timestamp:     1738876313290,
+		Generated:     42,
+		Overflowed:    true,
+		FirstOverflow: 42,
+		Elapsed:       2 * time.Second,
+		Rate:          21,
+		Allocs:        84,
+		AllocBytes:    1024,
+		AllocsPerID:   2,
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `{"mode":"monotonic","inc":1024,"timestamp":1738876313290,"generated":42,"overflowed":true,"first_overflow":42,"elapsed_ns":2000000000,"rate":21,"allocs":84,"alloc_bytes":1024,"allocs_per_id":2}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var decoded stress.Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != report {
+		t.Errorf("got %+v (%v), want %+v", decoded, err, report)
+	}
+}
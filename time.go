@@ -10,6 +10,10 @@ var maxTime = ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}.Time()
 // can be encoded in a ULID.
 func MaxTime() uint64 { return maxTime }
 
+// MaxTimestampTime returns the time.Time corresponding to MaxTime, which is the
+// latest instant (to the millisecond) that can be encoded in a ULID.
+func MaxTimestampTime() time.Time { return Time(maxTime) }
+
 // Now is a convenience function that returns the current
 // UTC time in Unix milliseconds. Equivalent to:
 //
@@ -19,12 +23,35 @@ func Now() uint64 { return Timestamp(time.Now().UTC()) }
 // Timestamp converts a time.Time to Unix milliseconds.
 //
 // Because of the way ULID stores time, times from the year
-// 10889 produces undefined results.
+// 10889 produces undefined results and times before the Unix
+// epoch wrap around to very large values. Use TimestampChecked
+// to detect times that cannot be represented in a ULID, or NewAt
+// to create a ULID from a time.Time.
 func Timestamp(t time.Time) uint64 {
 	return uint64(t.Unix())*1000 +
 		uint64(t.Nanosecond()/int(time.Millisecond))
 }
 
+// TimestampChecked converts a time.Time to Unix milliseconds like
+// Timestamp but returns ErrSmallTime for times before the Unix
+// epoch and ErrBigTime for times after MaxTimestampTime.
+func TimestampChecked(t time.Time) (uint64, error) {
+	s := t.Unix()
+	if s < 0 {
+		return 0, ErrSmallTime
+	}
+
+	if uint64(s) > maxTime/1000 {
+		return 0, ErrBigTime
+	}
+
+	ms := Timestamp(t)
+	if ms > maxTime {
+		return 0, ErrBigTime
+	}
+	return ms, nil
+}
+
 // Time converts Unix milliseconds in the format
 // returned by the Timestamp function to a time.Time.
 func Time(ms uint64) time.Time {
@@ -32,3 +59,35 @@ func Time(ms uint64) time.Time {
 	ns := int64((ms % 1e3) * 1e6)
 	return time.Unix(s, ns)
 }
+
+// TruncateTime returns a copy of the ULID with its timestamp rounded down to a
+// multiple of d since the Unix epoch, like time.Time.Truncate in millisecond
+// precision, and with its entropy preserved. Because ULIDs sort by timestamp,
+// all ULIDs in the same window of d truncate to the same timestamp, e.g. to use
+// them as cache keys that deliberately collide. A ULID exactly on a multiple of
+// d is unchanged, and durations longer than the timestamp truncate it to zero.
+// ErrInvalidDuration and Zero are returned if d is not positive or is not a whole
+// number of milliseconds, rather than silently rounding it.
+func (id ULID) TruncateTime(d time.Duration) (ULID, error) {
+	if d <= 0 || d%time.Millisecond != 0 {
+		return Zero, ErrInvalidDuration
+	}
+
+	// The truncated timestamp is never greater than the timestamp of the ULID so
+	// it can always be set.
+	ms := id.Time()
+	_ = id.SetTime(ms - ms%uint64(d.Milliseconds()))
+	return id, nil
+}
+
+// ZeroEntropyTruncate is like TruncateTime but also zeros the entropy, producing
+// a single canonical ULID for each window of d.
+func (id ULID) ZeroEntropyTruncate(d time.Duration) (ULID, error) {
+	tid, err := id.TruncateTime(d)
+	if err != nil {
+		return Zero, err
+	}
+
+	clear(tid[6:])
+	return tid, nil
+}
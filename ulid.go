@@ -17,6 +17,7 @@ package ulid
 import (
 	"bytes"
 	"database/sql/driver"
+	"fmt"
 	"io"
 	"time"
 )
@@ -38,6 +39,11 @@ A ULID is a 16 byte Universally Unique Lexicographically Sortable Identifier
 	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
 	|                       32_bit_uint_random                      |
 	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+The in-memory representation of a ULID is exactly this big-endian layout on
+every architecture: a ULID is a [16]byte with no padding, so its binary encoding
+(MarshalBinary, WriteTo) is identical to its memory and files of packed binary
+ULIDs can be memory mapped and reinterpreted directly (see UnsafeSliceFromBytes).
 */
 type ULID [16]byte
 
@@ -59,12 +65,17 @@ const (
 //===========================================================================
 
 // New returns a ULID with the given Unix milliseconds timestamp and an
-// optional entropy source. Use the Timestamp function to convert
-// a time.Time to Unix milliseconds.
+// optional entropy source. Use NewAt to create a ULID from a time.Time,
+// which returns an error for times that are out of range rather than
+// the undefined results of converting them with Timestamp.
 //
 // ErrBigTime is returned when passing a timestamp bigger than MaxTime.
 // Reading from the entropy source may also return an error.
 //
+// A nil entropy creates a ULID with zero entropy, but only if it is an untyped
+// nil: a nil *MonotonicEntropy, *LockedMonotonicReader, or *PoolEntropy stored
+// in the io.Reader is not treated as no entropy and returns ErrNilEntropy.
+//
 // Safety for concurrent use is only dependent on the safety of the
 // entropy source.
 func New(ms uint64, entropy io.Reader) (id ULID, err error) {
@@ -84,36 +95,62 @@ func New(ms uint64, entropy io.Reader) (id ULID, err error) {
 	return id, err
 }
 
+// NewAt returns a ULID with the time t and an optional entropy source like New,
+// but converts the time with TimestampChecked so that ErrSmallTime is returned
+// for times before the Unix epoch (including the zero time.Time) and ErrBigTime
+// for times after MaxTimestampTime, instead of the wrapped around or truncated
+// timestamps of New(Timestamp(t), entropy). Times are truncated to the
+// millisecond.
+func NewAt(t time.Time, entropy io.Reader) (id ULID, err error) {
+	var ms uint64
+	if ms, err = TimestampChecked(t); err != nil {
+		return id, err
+	}
+	return New(ms, entropy)
+}
+
 // MustNew is a convenience function equivalent to New that panics on failure
-// instead of returning an error.
+// instead of returning an error. The panic value is a *PanicError that wraps
+// the error returned by New.
 func MustNew(ms uint64, entropy io.Reader) ULID {
 	id, err := New(ms, entropy)
 	if err != nil {
-		panic(err)
+		panic(newPanicError("MustNew", ms, err))
 	}
 	return id
 }
 
 // MustNewDefault is a convenience function equivalent to MustNew with
-// DefaultEntropy as the entropy. It may panic if the given time.Time is too
-// large or too small.
+// DefaultEntropy as the entropy. The time is converted using TimestampChecked,
+// so it panics with ErrBigTime if the given time.Time is too large or with
+// ErrSmallTime if it is before the Unix epoch.
 func MustNewDefault(t time.Time) ULID {
-	return MustNew(Timestamp(t), defaultEntropy)
+	return MustNew(mustTimestamp("MustNewDefault", t), DefaultEntropy())
 }
 
 // MustNewSecure is a convenience function equivalent to MustNew with
-// SecureEntropy as the entropy. It may panic if the given time.Time is too
-// large or too small.
+// SecureEntropy as the entropy. The time is converted using TimestampChecked,
+// so it panics with ErrBigTime if the given time.Time is too large or with
+// ErrSmallTime if it is before the Unix epoch.
 func MustNewSecure(t time.Time) ULID {
-	return MustNew(Timestamp(t), secureEntropy)
+	return MustNew(mustTimestamp("MustNewSecure", t), SecureEntropy())
+}
+
+func mustTimestamp(fn string, t time.Time) uint64 {
+	ms, err := TimestampChecked(t)
+	if err != nil {
+		panic(newPanicError(fn, t, err))
+	}
+	return ms
 }
 
 // Make returns a ULID with the current time in Unix milliseconds and
 // monotonically increasing entropy for the same millisecond.
 // It is safe for concurrent use, using a sync.Pool to minimize contention.
 func Make() (id ULID) {
-	// NOTE: MustNew can't panic since DefaultEntropy never returns an error.
-	return MustNew(Now(), defaultEntropy)
+	// NOTE: MustNew can't panic since DefaultEntropy never returns an error unless
+	// it has been replaced by SetDefaultEntropy with a reader that does.
+	return recordMade(&health.made, MustNew(Now(), DefaultEntropy()))
 }
 
 // MakeSecure returns a ULID with the current time in Unix milliseconds and a
@@ -121,8 +158,40 @@ func Make() (id ULID) {
 // millisecond. It is safe for concurrent use, leveraging a sync.Pool underneath
 // for minimal contention.
 func MakeSecure() (id ULID) {
-	// NOTE: MustNew can't panic since SecureEntropy never returns an error.
-	return MustNew(Now(), secureEntropy)
+	// NOTE: MustNew can't panic since SecureEntropy never returns an error unless
+	// it has been replaced by SetSecureEntropy with a reader that does.
+	return recordMade(&health.madeSecure, MustNew(Now(), SecureEntropy()))
+}
+
+// MakeAt returns a ULID with the time t and the monotonically increasing entropy
+// of Make. Like MustNewDefault, it panics with a *PanicError wrapping ErrSmallTime
+// or ErrBigTime if t is before the Unix epoch or after MaxTimestampTime, which
+// cannot be represented in a ULID; use NewAt with DefaultEntropy to handle times
+// that are not known to be in range as errors.
+func MakeAt(t time.Time) ULID {
+	return recordMade(&health.made, MustNew(mustTimestamp("MakeAt", t), DefaultEntropy()))
+}
+
+// MakeSecureAt returns a ULID with the time t and the cryptographically secure
+// entropy of MakeSecure. Like MustNewSecure, it panics with a *PanicError wrapping
+// ErrSmallTime or ErrBigTime if t is before the Unix epoch or after
+// MaxTimestampTime, which cannot be represented in a ULID; use NewAt with
+// SecureEntropy to handle times that are not known to be in range as errors.
+func MakeSecureAt(t time.Time) ULID {
+	return recordMade(&health.madeSecure, MustNew(mustTimestamp("MakeSecureAt", t), SecureEntropy()))
+}
+
+// FromUnixMilli returns the ULID with the Unix milliseconds timestamp and zero
+// entropy, which sorts before every other ULID with the same timestamp, e.g. to
+// reconstruct range ULIDs from a timestamp-only column. ErrSmallTime is returned
+// for negative timestamps and ErrBigTime for timestamps after MaxTime.
+func FromUnixMilli(ms int64) (id ULID, err error) {
+	if ms < 0 {
+		return id, ErrSmallTime
+	}
+
+	err = id.SetTime(uint64(ms))
+	return id, err
 }
 
 //===========================================================================
@@ -135,21 +204,59 @@ func MakeSecure() (id ULID) {
 // ULID's length. Invalid encodings produce undefined ULIDs. For a version that
 // returns an error instead, see ParseStrict.
 func Parse(ulid any) (id ULID, err error) {
+	id, _, err = ParseDetailed(ulid)
+	return id, err
+}
+
+// InputKind describes the form of the input decoded by ParseDetailed,
+// NullULID.ScanDetailed, or LenientULID, e.g. for audit logging.
+type InputKind uint8
+
+const (
+	KindUnknown   InputKind = iota // The input could not be decoded
+	KindULID                       // The input was a ULID
+	KindText                       // The input was the text encoding in a string
+	KindBinary                     // The input was the binary encoding in a []byte
+	KindArray                      // The input was a [16]byte
+	KindNull                       // The input was a SQL or JSON null (ScanDetailed and LenientULID only)
+	KindTimestamp                  // The input was an integer in Unix milliseconds (ScanDetailed and LenientULID only)
+)
+
+var inputKindNames = [...]string{"unknown", "ulid", "text", "binary", "array", "null", "timestamp"}
+
+// String returns the lowercase name of the input kind.
+func (k InputKind) String() string {
+	if int(k) < len(inputKindNames) {
+		return inputKindNames[k]
+	}
+	return fmt.Sprintf("InputKind(%d)", k)
+}
+
+// ParseDetailed parses the ULID like Parse and also returns the kind of input
+// that was decoded. If the input cannot be parsed, KindUnknown is returned with
+// the error.
+func ParseDetailed(ulid any) (id ULID, kind InputKind, err error) {
 	switch t := ulid.(type) {
 	case ULID:
-		return t, nil
+		return t, KindULID, nil
 	case string:
 		if t == "" {
-			return Zero, nil
+			return Zero, KindText, nil
 		}
-		return id, parse([]byte(t), false, &id)
+		err, kind = parse([]byte(t), false, &id), KindText
 	case []byte:
-		return id, id.UnmarshalBinary(t)
+		err, kind = id.UnmarshalBinary(t), KindBinary
 	case [16]byte:
-		return ULID(t), nil
+		return ULID(t), KindArray, nil
 	default:
-		return Zero, ErrUnknownType
+		err = ErrUnknownType
+	}
+
+	if err != nil {
+		parseFailedAny(ulid, err)
+		return Zero, KindUnknown, err
 	}
+	return id, kind, nil
 }
 
 // ParseStrict parses an encoded ULID, returning an error in case of failure.
@@ -164,14 +271,60 @@ func ParseStrict(ulid any) (id ULID, err error) {
 	case ULID:
 		return t, nil
 	case string:
-		return id, parse([]byte(t), true, &id)
+		err = parse([]byte(t), true, &id)
 	case []byte:
-		return id, id.UnmarshalBinary(t)
+		err = id.UnmarshalBinary(t)
 	case [16]byte:
-		return id, id.UnmarshalBinary(t[:])
+		return ULID(t), nil
 	default:
-		return Zero, ErrUnknownType
+		err = ErrUnknownType
+	}
+
+	if err != nil {
+		parseFailedAny(ulid, err)
+	}
+	return id, err
+}
+
+// ParseCanonical parses an encoded ULID string like ParseStrict but additionally
+// rejects any string that is not the canonical encoding of the parsed ULID with
+// ErrNonCanonical. Because base32 decoding is case-insensitive, multiple strings
+// (e.g. lowercase or mixed case strings) decode to the same ULID; rejecting
+// these aliases allows the raw strings to be safely deduplicated or compared.
+func ParseCanonical(s string) (id ULID, err error) {
+	if err = parse([]byte(s), true, &id); err != nil {
+		return id, err
 	}
+
+	if !id.isEncodingOf(s) {
+		return id, ErrNonCanonical
+	}
+	return id, nil
+}
+
+// IsCanonical returns true if s is a valid encoded ULID and re-encoding the
+// parsed value reproduces s exactly.
+func IsCanonical(s string) bool {
+	_, err := ParseCanonical(s)
+	return err == nil
+}
+
+// Canonicalize strictly parses the encoded ULID and returns its canonical string
+// encoding, e.g. converting lowercase strings into uppercase strings. Any error
+// that would be returned by ParseStrict is returned.
+func Canonicalize(s string) (_ string, err error) {
+	var id ULID
+	if err = parse([]byte(s), true, &id); err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// isEncodingOf compares the canonical encoding of the ULID to s without allocating.
+func (id ULID) isEncodingOf(s string) bool {
+	var buf [EncodedSize]byte
+	_ = id.MarshalTextTo(buf[:])
+	return string(buf[:]) == s
 }
 
 func parse(v []byte, strict bool, id *ULID) error {
@@ -248,21 +401,41 @@ func parse(v []byte, strict bool, id *ULID) error {
 }
 
 // MustParse is a convenience function equivalent to Parse that panics on failure
-// instead of returning an error.
+// instead of returning an error. The panic value is a *PanicError that wraps
+// the error returned by Parse.
 func MustParse(ulid any) (id ULID) {
 	var err error
 	if id, err = Parse(ulid); err != nil {
-		panic(err)
+		panic(newPanicError("MustParse", ulid, err))
 	}
 	return id
 }
 
 // MustParseStrict is a convenience function equivalent to ParseStrict that
-// panics on failure instead of returning an error.
+// panics on failure instead of returning an error. The panic value is a
+// *PanicError that wraps the error returned by ParseStrict.
 func MustParseStrict(ulid any) (id ULID) {
 	var err error
 	if id, err = ParseStrict(ulid); err != nil {
-		panic(err)
+		panic(newPanicError("MustParseStrict", ulid, err))
+	}
+	return id
+}
+
+// Const returns the ULID of a literal, e.g. of a well-known ID in a package
+// variable:
+//
+//	var systemTenant = ulid.Const("01HTNMW2JAW89YSBG7NFPHABA4")
+//
+// Const is only for string literals and constants, which the ulidcheck analyzer
+// validates at analysis time so that a typo is reported before the code runs; it
+// reports a Const of a string that is not constant. Use ParseStrict for other
+// strings. Like MustParseStrict, Const panics with a *PanicError that wraps the
+// error of ParseStrict if the literal is not a valid ULID.
+func Const(s string) (id ULID) {
+	var err error
+	if id, err = ParseStrict(s); err != nil {
+		panic(newPanicError("Const", s, err))
 	}
 	return id
 }
@@ -285,6 +458,33 @@ func (id ULID) String() string {
 	return string(ulid)
 }
 
+// StringOr returns the string encoding of the ULID, or fallback if the ULID is
+// zero, e.g. to display "none" rather than 26 zeros when an ID is absent.
+func (id ULID) StringOr(fallback string) string {
+	if id.IsZero() {
+		return fallback
+	}
+	return id.String()
+}
+
+// StringPtr returns a pointer to the string encoding of the ULID, or nil if the
+// ULID is zero, e.g. for optional string fields in GraphQL or protobuf.
+func (id ULID) StringPtr() *string {
+	if id.IsZero() {
+		return nil
+	}
+
+	s := id.String()
+	return &s
+}
+
+// GoString implements fmt.GoStringer so that %#v formats the ULID as the Go
+// expression ulid.MustParse("01AN4Z07BY79KA1307SR9X4MV3") rather than an array of
+// 16 bytes, e.g. to copy the IDs of a debug dump into a test.
+func (id ULID) GoString() string {
+	return `ulid.MustParse("` + id.String() + `")`
+}
+
 // MarshalBinary implements the encoding.BinaryMarshaler interface by
 // returning the ULID as a byte slice.
 func (id ULID) MarshalBinary() ([]byte, error) {
@@ -325,13 +525,20 @@ func (id ULID) MarshalText() ([]byte, error) {
 // MarshalTextTo writes the ULID as a string to the given buffer.
 // ErrBufferSize is returned when the len(dst) != 26.
 func (id ULID) MarshalTextTo(dst []byte) error {
-	// Optimized unrolled loop ahead.
-	// From https://github.com/RobThree/NUlid
-
 	if len(dst) != EncodedSize {
 		return ErrBufferSize
 	}
 
+	id.encodeTime((*[EncodedSize]byte)(dst))
+	id.encodeEntropy((*[EncodedSize]byte)(dst))
+	return nil
+}
+
+// encodeTime writes the 10 character encoded timestamp into dst[:10].
+func (id *ULID) encodeTime(dst *[EncodedSize]byte) {
+	// Optimized unrolled loop ahead.
+	// From https://github.com/RobThree/NUlid
+
 	// 10 byte timestamp
 	dst[0] = Encoding[(id[0]&224)>>5]
 	dst[1] = Encoding[id[0]&31]
@@ -343,7 +550,10 @@ func (id ULID) MarshalTextTo(dst []byte) error {
 	dst[7] = Encoding[(id[4]&124)>>2]
 	dst[8] = Encoding[((id[4]&3)<<3)|((id[5]&224)>>5)]
 	dst[9] = Encoding[id[5]&31]
+}
 
+// encodeEntropy writes the 16 character encoded entropy into dst[10:].
+func (id *ULID) encodeEntropy(dst *[EncodedSize]byte) {
 	// 16 bytes of entropy
 	dst[10] = Encoding[(id[6]&248)>>3]
 	dst[11] = Encoding[((id[6]&7)<<2)|((id[7]&192)>>6)]
@@ -361,8 +571,6 @@ func (id ULID) MarshalTextTo(dst []byte) error {
 	dst[23] = Encoding[(id[14]&124)>>2]
 	dst[24] = Encoding[((id[14]&3)<<3)|((id[15]&224)>>5)]
 	dst[25] = Encoding[id[15]&31]
-
-	return nil
 }
 
 // UnmarshalText implements the encoding.TextUnmarshaler interface by
@@ -371,7 +579,11 @@ func (id ULID) MarshalTextTo(dst []byte) error {
 // ErrDataSize is returned if the len(v) is different from an encoded
 // ULID's length. Invalid encodings produce undefined ULIDs.
 func (id *ULID) UnmarshalText(v []byte) error {
-	return parse(v, false, id)
+	err := parse(v, false, id)
+	if err != nil {
+		parseFailed(v, err)
+	}
+	return err
 }
 
 //===========================================================================
@@ -416,6 +628,12 @@ func (id ULID) Entropy() []byte {
 	return e
 }
 
+// EntropyArray returns a copy of the entropy from the ULID by value, which unlike
+// Entropy does not allocate.
+func (id ULID) EntropyArray() [10]byte {
+	return [10]byte(id[6:])
+}
+
 // SetEntropy sets the ULID entropy to the passed byte slice.
 // ErrDataSize is returned if len(e) != 10.
 func (id *ULID) SetEntropy(e []byte) error {
@@ -448,23 +666,182 @@ func (id ULID) Equals(other ULID) bool {
 	return id.Compare(other) == 0
 }
 
+// SameTime returns true if a and b have the same timestamp, regardless of their
+// entropy.
+func SameTime(a, b ULID) bool {
+	return [6]byte(a[:6]) == [6]byte(b[:6])
+}
+
+// SameEntropy returns true if a and b have the same entropy, regardless of their
+// timestamps. It is equivalent to comparing Entropy with bytes.Equal without
+// allocating.
+func SameEntropy(a, b ULID) bool {
+	return [10]byte(a[6:]) == [10]byte(b[6:])
+}
+
+// EntropyCompare returns an integer comparing the entropy of a and b
+// lexicographically, ignoring their timestamps. The result will be 0 if the
+// entropy of a==b, -1 if a < b, and +1 if a > b. For ULIDs with the same time
+// (see SameTime), it is equivalent to a.Compare(b).
+func EntropyCompare(a, b ULID) int {
+	return bytes.Compare(a[6:], b[6:])
+}
+
+// CompareString compares the ULID against an encoded ULID string without parsing
+// the string, returning 0 if id==s, -1 if id < s, and +1 if id > s. Because the
+// base32 encoding is order-preserving, the ULID is encoded into a stack buffer
+// and compared bytewise; lowercase letters in s are folded to uppercase during
+// the comparison. ErrDataSize is returned if s is not the length of an encoded
+// ULID; otherwise s is not validated and invalid characters compare by value.
+func CompareString(id ULID, s string) (int, error) {
+	if len(s) != EncodedSize {
+		return 0, ErrDataSize
+	}
+
+	// Most comparisons are decided by the timestamp, so the entropy is only
+	// encoded when the timestamp components are equal.
+	var buf [EncodedSize]byte
+	id.encodeTime(&buf)
+	if cmp := compareFold(buf[:10], s[:10]); cmp != 0 {
+		return cmp, nil
+	}
+
+	id.encodeEntropy(&buf)
+	return compareFold(buf[10:], s[10:]), nil
+}
+
+// EqualsString returns true if s is an encoding of id, folding lowercase letters
+// in s to uppercase. It does not allocate and does not parse s.
+func EqualsString(id ULID, s string) bool {
+	cmp, err := CompareString(id, s)
+	return err == nil && cmp == 0
+}
+
+// compareFold compares canonical encoded bytes to a string, folding lowercase
+// letters in s to uppercase.
+func compareFold(enc []byte, s string) int {
+	for i := 0; i < len(enc); i++ {
+		a, b := enc[i], s[i]
+		if b >= 'a' && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b ULID) ULID {
+	if b.Compare(a) < 0 {
+		return b
+	}
+	return a
+}
+
+// Max returns the larger of a and b.
+func Max(a, b ULID) ULID {
+	if b.Compare(a) > 0 {
+		return b
+	}
+	return a
+}
+
+// MinOf returns the smallest of the ids or false if no ids are given. Because
+// ULID.Compare has the signature of a comparison function, slices.MinFunc(ids,
+// ULID.Compare) is equivalent for a non-empty slice but panics if it is empty.
+func MinOf(ids ...ULID) (_ ULID, ok bool) {
+	if len(ids) == 0 {
+		return Zero, false
+	}
+
+	smallest := ids[0]
+	for _, id := range ids[1:] {
+		smallest = Min(smallest, id)
+	}
+	return smallest, true
+}
+
+// MaxOf returns the largest of the ids or false if no ids are given.
+func MaxOf(ids ...ULID) (_ ULID, ok bool) {
+	if len(ids) == 0 {
+		return Zero, false
+	}
+
+	largest := ids[0]
+	for _, id := range ids[1:] {
+		largest = Max(largest, id)
+	}
+	return largest, true
+}
+
+// Clamp returns id limited to the inclusive range [lo, hi], e.g. to restrict a
+// query to a time range built with the Min and Max bounds of a partition. If lo
+// is greater than hi the bounds are swapped, so the result is always between
+// them.
+func Clamp(id, lo, hi ULID) ULID {
+	if lo.Compare(hi) > 0 {
+		lo, hi = hi, lo
+	}
+	return Min(Max(id, lo), hi)
+}
+
+// Key returns the bytes of the ULID as an array whose lexicographic byte order is
+// the order of Compare, e.g. as a map key or sort key shared with code that does
+// not import this package: sorting keys with slices.SortFunc and bytes.Compare of
+// the slices of the keys sorts the ULIDs. Like Array, it does not allocate.
+func (id ULID) Key() [16]byte {
+	return id.Array()
+}
+
 //===========================================================================
 // SQL Interfaces
 //===========================================================================
 
 // Scan implements the sql.Scanner interface. It supports scanning
 // a string or byte slice.
-func (id *ULID) Scan(src interface{}) error {
+//
+// It also supports scanning an int64 or uint64 as Unix milliseconds, e.g. from a
+// BIGINT column that stores only a creation timestamp, producing the ULID with
+// the timestamp and zero entropy like FromUnixMilli. ErrSmallTime is returned for
+// negative values and ErrBigTime for values after MaxTime.
+func (id *ULID) Scan(src interface{}) (err error) {
 	switch x := src.(type) {
 	case nil:
 		return nil
 	case string:
-		return id.UnmarshalText([]byte(x))
+		err = parse([]byte(x), false, id)
 	case []byte:
-		return id.UnmarshalBinary(x)
+		err = id.UnmarshalBinary(x)
+	case int64:
+		err = id.scanMilli(x)
+	case uint64:
+		if err = ErrBigTime; x <= maxTime {
+			err = id.scanMilli(int64(x))
+		}
+	default:
+		err = ErrScanValue
 	}
 
-	return ErrScanValue
+	if err != nil {
+		parseFailedAny(src, err)
+	}
+	return err
+}
+
+func (id *ULID) scanMilli(ms int64) error {
+	tid, err := FromUnixMilli(ms)
+	if err != nil {
+		return err
+	}
+
+	*id = tid
+	return nil
 }
 
 // Value implements the sql/driver.Valuer interface, returning the ULID as a
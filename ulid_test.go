@@ -3,9 +3,19 @@ package ulid_test
 import (
 	"bytes"
 	crand "crypto/rand"
+	"database/sql"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"io"
+	"math"
 	"math/rand"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -44,6 +54,97 @@ func TestNew(t *testing.T) {
 			t.Errorf("got err %v, want %v", got, want)
 		}
 	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var (
+			monotonic *ulid.MonotonicEntropy
+			locked    *ulid.LockedMonotonicReader
+			pool      *ulid.PoolEntropy
+		)
+
+		want := ulid.MustNew(42, nil)
+		for _, tc := range []struct {
+			name    string
+			entropy io.Reader
+			err     error
+		}{
+			{"Untyped", nil, nil},
+			{"MonotonicEntropy", monotonic, ulid.ErrNilEntropy},
+			{"LockedMonotonicReader", locked, ulid.ErrNilEntropy},
+			{"LockedNilReader", &ulid.LockedMonotonicReader{}, ulid.ErrNilEntropy},
+			{"PoolEntropy", pool, ulid.ErrNilEntropy},
+		} {
+			id, err := ulid.New(42, tc.entropy)
+			if err != tc.err {
+				t.Errorf("%s: got err %v, want %v", tc.name, err, tc.err)
+			}
+
+			if id != want {
+				t.Errorf("%s: got %s, want the timestamp with zero entropy %s", tc.name, id, want)
+			}
+		}
+	})
+}
+
+func TestNewAt(t *testing.T) {
+	t.Parallel()
+
+	// The accepted range is from the Unix epoch to the last millisecond of MaxTime.
+	maxInstant := ulid.MaxTimestampTime()
+	for _, tc := range []struct {
+		name string
+		in   time.Time
+		ms   uint64
+		err  error
+	}{
+		{"Epoch", time.Unix(0, 0), 0, nil},
+		{"Now", time.Unix(1738876313, 290999999), 1738876313290, nil},
+		{"Max", maxInstant, ulid.MaxTime(), nil},
+		{"MaxLastNanosecond", maxInstant.Add(time.Millisecond - 1), ulid.MaxTime(), nil},
+		{"AfterMax", maxInstant.Add(time.Millisecond), 0, ulid.ErrBigTime},
+		{"BeforeEpoch", time.Unix(0, -1), 0, ulid.ErrSmallTime},
+		{"ZeroTime", time.Time{}, 0, ulid.ErrSmallTime},
+	} {
+		id, err := ulid.NewAt(tc.in, bytes.NewReader(bytes.Repeat([]byte{0xAB}, 10)))
+		if err != tc.err {
+			t.Errorf("%s: got err %v, want %v", tc.name, err, tc.err)
+			continue
+		}
+
+		if err != nil {
+			// The zero time does not wrap around to a timestamp.
+			if !id.IsZero() {
+				t.Errorf("%s: expected the zero ULID on error, got %s", tc.name, id)
+			}
+			continue
+		}
+
+		if id.Time() != tc.ms || id.Entropy()[0] != 0xAB {
+			t.Errorf("%s: got %s with time %d, want time %d", tc.name, id, id.Time(), tc.ms)
+		}
+	}
+
+	// Entropy errors are returned like New.
+	if _, err := ulid.NewAt(time.Now(), strings.NewReader("")); err != io.EOF {
+		t.Errorf("got err %v, want %v", err, io.EOF)
+	}
+}
+
+func TestMakeAt(t *testing.T) {
+	t.Parallel()
+
+	at := time.Unix(1738876313, 290e6)
+	for name, fn := range map[string]func(time.Time) ulid.ULID{
+		"MakeAt":       ulid.MakeAt,
+		"MakeSecureAt": ulid.MakeSecureAt,
+	} {
+		if id := fn(at); id.Time() != 1738876313290 || id.Timestamp().Compare(at) != 0 {
+			t.Errorf("%s: got %s with time %d, want %d", name, id, id.Time(), uint64(1738876313290))
+		}
+
+		testPanics(t, ulid.ErrSmallTime, func() { fn(time.Time{}) })
+		testPanics(t, ulid.ErrBigTime, func() { fn(ulid.MaxTimestampTime().Add(time.Millisecond)) })
+	}
 }
 
 func TestMake(t *testing.T) {
@@ -64,12 +165,7 @@ func TestMustNew(t *testing.T) {
 	t.Run("ULID", testULID(ulid.MustNew))
 
 	t.Run("Panic", func(t *testing.T) {
-		defer func() {
-			if got, want := recover(), io.EOF; got != want {
-				t.Errorf("panic with err %v, want %v", got, want)
-			}
-		}()
-		_ = ulid.MustNew(0, strings.NewReader(""))
+		testPanics(t, io.EOF, func() { ulid.MustNew(0, strings.NewReader("")) })
 	})
 }
 
@@ -88,12 +184,8 @@ func TestMustNewDefault(t *testing.T) {
 	})
 
 	t.Run("Panic", func(t *testing.T) {
-		defer func() {
-			if got, want := recover(), ulid.ErrBigTime; got != want {
-				t.Errorf("got panic %v, want %v", got, want)
-			}
-		}()
-		_ = ulid.MustNewDefault(time.Time{})
+		testPanics(t, ulid.ErrSmallTime, func() { ulid.MustNewDefault(time.Time{}) })
+		testPanics(t, ulid.ErrBigTime, func() { ulid.MustNewDefault(time.Date(12000, 1, 1, 0, 0, 0, 0, time.UTC)) })
 	})
 }
 
@@ -112,12 +204,8 @@ func TestMustNewSecure(t *testing.T) {
 	})
 
 	t.Run("Panic", func(t *testing.T) {
-		defer func() {
-			if got, want := recover(), ulid.ErrBigTime; got != want {
-				t.Errorf("got panic %v, want %v", got, want)
-			}
-		}()
-		_ = ulid.MustNewSecure(time.Time{})
+		testPanics(t, ulid.ErrSmallTime, func() { ulid.MustNewSecure(time.Time{}) })
+		testPanics(t, ulid.ErrBigTime, func() { ulid.MustNewSecure(time.Date(12000, 1, 1, 0, 0, 0, 0, time.UTC)) })
 	})
 }
 
@@ -132,17 +220,71 @@ func TestMustParse(t *testing.T) {
 		{"MustParseStrict", ulid.MustParseStrict},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			defer func() {
-				if got, want := recover(), ulid.ErrDataSize; got != want {
-					t.Errorf("got panic %v, want %v", got, want)
-				}
-			}()
-			_ = tc.fn("abc")
+			testPanics(t, ulid.ErrDataSize, func() { tc.fn("abc") })
 		})
 
 	}
 }
 
+func TestConst(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.Const("01HTNMW2JAW89YSBG7NFPHABA4")
+	if id.String() != "01HTNMW2JAW89YSBG7NFPHABA4" {
+		t.Errorf("got %s", id)
+	}
+
+	testPanics(t, ulid.ErrDataSize, func() { ulid.Const("01HTNMW2JAW89YSBG7NFPHABA") })
+	testPanics(t, ulid.ErrOverflow, func() { ulid.Const("81HTNMW2JAW89YSBG7NFPHABA4") })
+}
+
+func TestPanicError(t *testing.T) {
+	t.Parallel()
+
+	recovered := func(f func()) (perr *ulid.PanicError) {
+		defer func() {
+			var ok bool
+			if perr, ok = recover().(*ulid.PanicError); !ok {
+				t.Fatalf("expected panic with a *ulid.PanicError")
+			}
+		}()
+		f()
+		return nil
+	}
+
+	long := strings.Repeat("A", 100)
+	for _, tc := range []struct {
+		name string
+		fn   func()
+		err  error
+		msg  string
+	}{
+		{"MustParse", func() { ulid.MustParse("abc") }, ulid.ErrDataSize, `ulid.MustParse("abc"): ulid: bad data size when unmarshaling`},
+		{"MustParseStrict", func() { ulid.MustParseStrict("01JKEHNQPA0END3NHMFKB2Y6SU") }, ulid.ErrInvalidCharacters, `ulid.MustParseStrict("01JKEHNQPA0END3NHMFKB2Y6SU"): ulid: bad data characters when unmarshaling`},
+		{"Truncated", func() { ulid.MustParse(long) }, ulid.ErrDataSize, `ulid.MustParse("` + long[:64] + `"...): ulid: bad data size when unmarshaling`},
+		{"Bytes", func() { ulid.MustParse([]byte{0xde, 0xad}) }, ulid.ErrDataSize, `ulid.MustParse(0xdead): ulid: bad data size when unmarshaling`},
+		{"UnknownType", func() { ulid.MustParse(42) }, ulid.ErrUnknownType, `ulid.MustParse(42): ulid: cannot parse unknown type`},
+		{"MustNew", func() { ulid.MustNew(ulid.MaxTime()+1, nil) }, ulid.ErrBigTime, `ulid.MustNew(281474976710656): ulid: time too big`},
+		{"MustNewDefault", func() { ulid.MustNewDefault(time.Time{}) }, ulid.ErrSmallTime, `ulid.MustNewDefault(0001-01-01T00:00:00Z): ulid: time too small`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			perr := recovered(tc.fn)
+			if !errors.Is(perr, tc.err) {
+				t.Errorf("expected panic to wrap %v, got %v", tc.err, perr.Err)
+			}
+
+			var target *ulid.PanicError
+			if !errors.As(error(perr), &target) || target != perr {
+				t.Errorf("expected errors.As to find the *ulid.PanicError")
+			}
+
+			if perr.Error() != tc.msg {
+				t.Errorf("got message %q, want %q", perr.Error(), tc.msg)
+			}
+		})
+	}
+}
+
 func testULID(mk func(uint64, io.Reader) ulid.ULID) func(*testing.T) {
 	return func(t *testing.T) {
 		want := ulid.ULID{0x0, 0x0, 0x0, 0x1, 0x86, 0xa0}
@@ -407,6 +549,56 @@ func TestParseTypes(t *testing.T) {
 	}
 }
 
+func TestParseDetailed(t *testing.T) {
+	t.Parallel()
+
+	example := ulid.Make()
+	testCases := []struct {
+		input    any
+		expected ulid.ULID
+		kind     ulid.InputKind
+		err      error
+	}{
+		{example.String(), example, ulid.KindText, nil},
+		{strings.ToLower(example.String()), example, ulid.KindText, nil},
+		{"", ulid.Zero, ulid.KindText, nil},
+		{example.Bytes(), example, ulid.KindBinary, nil},
+		{example, example, ulid.KindULID, nil},
+		{[16]byte(example), example, ulid.KindArray, nil},
+		{uint64(14), ulid.Zero, ulid.KindUnknown, ulid.ErrUnknownType},
+		{nil, ulid.Zero, ulid.KindUnknown, ulid.ErrUnknownType},
+		{"foo", ulid.Zero, ulid.KindUnknown, ulid.ErrDataSize},
+		{"81ARZ3NDEKTSV4RRFFQ69G5FAV", ulid.Zero, ulid.KindUnknown, ulid.ErrOverflow},
+		{[]byte{0x14, 0x21}, ulid.Zero, ulid.KindUnknown, ulid.ErrDataSize},
+	}
+
+	for i, tc := range testCases {
+		actual, kind, err := ulid.ParseDetailed(tc.input)
+		if err != tc.err || kind != tc.kind || actual != tc.expected {
+			t.Errorf("test case %d: got %s %s %v, want %s %s %v", i, actual, kind, err, tc.expected, tc.kind, tc.err)
+		}
+
+		if parsed, perr := ulid.Parse(tc.input); parsed != actual || perr != err {
+			t.Errorf("test case %d: expected Parse to agree with ParseDetailed", i)
+		}
+	}
+
+	for kind, want := range map[ulid.InputKind]string{
+		ulid.KindUnknown:     "unknown",
+		ulid.KindULID:        "ulid",
+		ulid.KindText:        "text",
+		ulid.KindBinary:      "binary",
+		ulid.KindArray:       "array",
+		ulid.KindNull:        "null",
+		ulid.KindTimestamp:   "timestamp",
+		ulid.InputKind(0xFF): "InputKind(255)",
+	} {
+		if got := kind.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
 func TestNow(t *testing.T) {
 	t.Parallel()
 
@@ -434,6 +626,52 @@ func TestTimestamp(t *testing.T) {
 	}
 }
 
+func TestTimestampChecked(t *testing.T) {
+	t.Parallel()
+
+	maxInstant := time.Date(10889, time.August, 2, 5, 31, 50, 655*int(time.Millisecond), time.UTC)
+	if got := ulid.MaxTimestampTime(); !got.Equal(maxInstant) {
+		t.Fatalf("got max timestamp time %s, want %s", got.UTC(), maxInstant)
+	}
+
+	if got := ulid.Timestamp(ulid.MaxTimestampTime()); got != ulid.MaxTime() {
+		t.Fatalf("got max timestamp %d, want %d", got, ulid.MaxTime())
+	}
+
+	for _, tc := range []struct {
+		name string
+		in   time.Time
+		ms   uint64
+		err  error
+	}{
+		{"Epoch", time.Unix(0, 0), 0, nil},
+		{"Truncated", time.Unix(1, 1000), 1000, nil},
+		{"Max", maxInstant, ulid.MaxTime(), nil},
+		{"MaxSubMillisecond", maxInstant.Add(999 * time.Microsecond), ulid.MaxTime(), nil},
+		{"AfterMax", maxInstant.Add(time.Millisecond), 0, ulid.ErrBigTime},
+		{"FarFuture", time.Date(12000, 1, 1, 0, 0, 0, 0, time.UTC), 0, ulid.ErrBigTime},
+		{"BeforeEpoch", time.Unix(0, -1), 0, ulid.ErrSmallTime},
+		{"BeforeEpochMillisecond", time.Unix(-1, 999*int64(time.Millisecond)), 0, ulid.ErrSmallTime},
+		{"ZeroTime", time.Time{}, 0, ulid.ErrSmallTime},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, err := ulid.TimestampChecked(tc.in)
+			if err != tc.err {
+				t.Fatalf("got err %v, want %v", err, tc.err)
+			}
+
+			if ms != tc.ms {
+				t.Errorf("got timestamp %d, want %d", ms, tc.ms)
+			}
+		})
+	}
+
+	// Unchecked timestamps before the epoch wrap around to values beyond MaxTime.
+	if ms := ulid.Timestamp(time.Unix(-1, 0)); ms <= ulid.MaxTime() {
+		t.Errorf("expected pre-1970 unchecked timestamp to wrap, got %d", ms)
+	}
+}
+
 func TestTime(t *testing.T) {
 	t.Parallel()
 
@@ -458,6 +696,81 @@ func TestTimestampRoundTrips(t *testing.T) {
 	}
 }
 
+func TestTruncateTime(t *testing.T) {
+	t.Parallel()
+
+	entropy := []byte{0xA5, 0xA5, 0xA5, 0xA5, 0xA5, 0xA5, 0xA5, 0xA5, 0xA5, 0xA5}
+	window := 5 * time.Minute
+	boundary := uint64(1735689600000) // 2025-01-01T00:00:00Z is a multiple of 5m
+
+	// The longest whole millisecond duration is about 292 years, much shorter than
+	// MaxTime, so it truncates MaxTime to a nonzero timestamp.
+	maxDuration := time.Duration(math.MaxInt64).Truncate(time.Millisecond)
+
+	for _, tc := range []struct {
+		name string
+		ms   uint64
+		d    time.Duration
+		want uint64
+	}{
+		{"OnBoundary", boundary, window, boundary},
+		{"AfterBoundary", boundary + 1, window, boundary},
+		{"BeforeBoundary", boundary - 1, window, boundary - uint64(window.Milliseconds())},
+		{"EndOfWindow", boundary + uint64(window.Milliseconds()) - 1, window, boundary},
+		{"Millisecond", boundary + 123, time.Millisecond, boundary + 123},
+		{"Epoch", 0, window, 0},
+		{"LongerThanTimestamp", boundary, time.Duration(boundary+1) * time.Millisecond, 0},
+		{"MaxDuration", boundary, maxDuration, 0},
+		{"MaxTime", ulid.MaxTime(), time.Millisecond, ulid.MaxTime()},
+		{"MaxTimeWindow", ulid.MaxTime(), window, ulid.MaxTime() - ulid.MaxTime()%uint64(window.Milliseconds())},
+		{"MaxTimeMaxDuration", ulid.MaxTime(), maxDuration, ulid.MaxTime() - ulid.MaxTime()%uint64(maxDuration.Milliseconds())},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := ulid.New(tc.ms, bytes.NewReader(entropy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := id.TruncateTime(tc.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Time() != tc.want || !bytes.Equal(got.Entropy(), entropy) {
+				t.Errorf("got %d with entropy %x, want %d with entropy %x", got.Time(), got.Entropy(), tc.want, entropy)
+			}
+
+			zero, err := id.ZeroEntropyTruncate(tc.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if zero.Time() != tc.want || !bytes.Equal(zero.Entropy(), make([]byte, 10)) {
+				t.Errorf("got %s, want timestamp %d with zero entropy", zero, tc.want)
+			}
+		})
+	}
+
+	// All ULIDs in the same window truncate to the same canonical key.
+	a, b := ulid.MustNew(boundary+1, nil), ulid.MustNew(boundary+uint64(window.Milliseconds())-1, ulid.DefaultEntropy())
+	ka, _ := a.ZeroEntropyTruncate(window)
+	kb, _ := b.ZeroEntropyTruncate(window)
+	if ka != kb {
+		t.Errorf("expected %s and %s to have the same key, got %s and %s", a, b, ka, kb)
+	}
+
+	for _, d := range []time.Duration{0, -time.Millisecond, time.Microsecond, time.Millisecond + time.Nanosecond, 1500 * time.Microsecond} {
+		id := ulid.Make()
+		if got, err := id.TruncateTime(d); err != ulid.ErrInvalidDuration || !got.IsZero() {
+			t.Errorf("TruncateTime(%s): expected ErrInvalidDuration and a zero ULID, got %s (%v)", d, got, err)
+		}
+
+		if got, err := id.ZeroEntropyTruncate(d); err != ulid.ErrInvalidDuration || !got.IsZero() {
+			t.Errorf("ZeroEntropyTruncate(%s): expected ErrInvalidDuration and a zero ULID, got %s (%v)", d, got, err)
+		}
+	}
+}
+
 func TestULIDTime(t *testing.T) {
 	t.Parallel()
 
@@ -504,6 +817,89 @@ func TestULIDTimestamp(t *testing.T) {
 	}
 }
 
+func TestStringOr(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	if s := id.StringOr("none"); s != "01HTNMW2JAW89YSBG7NFPHABA4" {
+		t.Errorf("got %q, want the string encoding", s)
+	}
+
+	if s := ulid.Zero.StringOr("none"); s != "none" {
+		t.Errorf("got %q, want the fallback", s)
+	}
+
+	if s := ulid.Zero.StringOr(""); s != "" {
+		t.Errorf("got %q, want the empty fallback", s)
+	}
+
+	if p := id.StringPtr(); p == nil || *p != "01HTNMW2JAW89YSBG7NFPHABA4" {
+		t.Errorf("got %v, want a pointer to the string encoding", p)
+	}
+
+	if p := ulid.Zero.StringPtr(); p != nil {
+		t.Errorf("got %q, want nil", *p)
+	}
+}
+
+func TestGoString(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if s := fmt.Sprintf("%#v", id); s != `ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")` {
+		t.Errorf("got %s", s)
+	}
+
+	type record struct {
+		ID     ulid.ULID
+		Parent ulid.ULID
+	}
+
+	if s := fmt.Sprintf("%#v", record{ID: id}); s != `ulid_test.record{ID:ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV"), Parent:ulid.MustParse("00000000000000000000000000")}` {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestGoStringCompiles(t *testing.T) {
+	t.Parallel()
+
+	// The GoString of each ULID must be a valid expression of type ulid.ULID that
+	// is evaluated to the same ULID.
+	ids := []ulid.ULID{ulid.Zero, ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"), ulid.Make()}
+
+	var src strings.Builder
+	src.WriteString("package dump\n\nimport \"go.rtnl.ai/ulid\"\n\nvar ids = []ulid.ULID{\n")
+	for _, id := range ids {
+		fmt.Fprintf(&src, "\t%#v,\n", id)
+	}
+	src.WriteString("}\n")
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "dump.go", src.String(), 0)
+	if err != nil {
+		t.Fatalf("could not parse %s: %v", src.String(), err)
+	}
+
+	// Type check the file against the source of the ulid package.
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = conf.Check("dump", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("could not compile %s: %v", src.String(), err)
+	}
+
+	elts := file.Decls[1].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.CompositeLit).Elts
+	for i, elt := range elts {
+		call := elt.(*ast.CallExpr)
+		arg, err := strconv.Unquote(call.Args[0].(*ast.BasicLit).Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := ulid.MustParse(arg); got != ids[i] {
+			t.Errorf("%#v evaluated to %s", ids[i], got)
+		}
+	}
+}
+
 func TestZero(t *testing.T) {
 	t.Parallel()
 
@@ -546,6 +942,64 @@ func TestEntropy(t *testing.T) {
 	}
 }
 
+func TestEntropyArray(t *testing.T) {
+	t.Parallel()
+
+	prop := func(a, b ulid.ULID) bool {
+		// Share the timestamp or the entropy half of the time.
+		if a[0]&1 == 0 {
+			copy(b[:6], a[:6])
+		}
+		if a[0]&2 == 0 {
+			copy(b[6:], a[6:])
+		}
+
+		e := a.EntropyArray()
+		if !bytes.Equal(e[:], a.Entropy()) {
+			return false
+		}
+
+		if ulid.SameEntropy(a, b) != bytes.Equal(a.Entropy(), b.Entropy()) {
+			return false
+		}
+
+		if ulid.SameTime(a, b) != (a.Time() == b.Time()) {
+			return false
+		}
+
+		if ulid.EntropyCompare(a, b) != bytes.Compare(a.Entropy(), b.Entropy()) {
+			return false
+		}
+
+		// Within the same timestamp, the entropy decides the order.
+		return !ulid.SameTime(a, b) || ulid.EntropyCompare(a, b) == a.Compare(b)
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modifying the returned array does not modify the ULID.
+	id := ulid.MustNew(0, strings.NewReader("ABCDEFGHIJKLMNOP"))
+	e := id.EntropyArray()
+	e[0] = 0
+	if id.Entropy()[0] != 'A' {
+		t.Error("expected EntropyArray to return a copy")
+	}
+}
+
+func TestEntropyArrayAllocs(t *testing.T) {
+	a, b := ulid.MustNew(1, strings.NewReader("ABCDEFGHIJ")), ulid.MustNew(2, strings.NewReader("ABCDEFGHIJ"))
+	allocs := testing.AllocsPerRun(100, func() {
+		e := a.EntropyArray()
+		_ = ulid.SameEntropy(a, b) && ulid.SameTime(a, b) && ulid.EntropyCompare(a, b) == 0 && e[0] == 'A'
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %.1f", allocs)
+	}
+}
+
 func TestEntropyRead(t *testing.T) {
 	t.Parallel()
 
@@ -588,6 +1042,179 @@ func TestCompare(t *testing.T) {
 	}
 }
 
+func TestCompareString(t *testing.T) {
+	t.Parallel()
+
+	prop := func(a, b ulid.ULID) bool {
+		want := a.Compare(b)
+		for _, s := range []string{b.String(), strings.ToLower(b.String())} {
+			got, err := ulid.CompareString(a, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != want {
+				return false
+			}
+
+			if ulid.EqualsString(a, s) != (want == 0) {
+				return false
+			}
+		}
+		return ulid.EqualsString(a, a.String()) && ulid.EqualsString(a, strings.ToLower(a.String()))
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 1e5}); err != nil {
+		t.Fatal(err)
+	}
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	for s, want := range map[string]bool{
+		"01JKEHNQPA0END3NHMFKB2Y6SE": true,
+		"01jkehnqpa0end3nhmfkb2y6se": true,
+		"01JkEhNqPa0EnD3nHmFkB2y6sE": true,
+		"01JKEHNQPA0END3NHMFKB2Y6SF": false,
+		"01JKEHNQPA0END3NHMFKB2Y6S":  false,
+		"":                           false,
+	} {
+		if got := ulid.EqualsString(id, s); got != want {
+			t.Errorf("EqualsString(%q): got %t, want %t", s, got, want)
+		}
+	}
+
+	if _, err := ulid.CompareString(id, "foo"); err != ulid.ErrDataSize {
+		t.Errorf("got err %v, want %v", err, ulid.ErrDataSize)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	t.Parallel()
+
+	a := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	b := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAW")
+	c := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	top := ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+	if ulid.Min(a, b) != a || ulid.Min(b, a) != a || ulid.Max(a, b) != b || ulid.Max(b, a) != b {
+		t.Errorf("unexpected Min or Max of %s and %s", a, b)
+	}
+
+	if ulid.Min(a, a) != a || ulid.Max(a, a) != a {
+		t.Errorf("expected Min and Max of equal values to be the value")
+	}
+
+	testCases := []struct {
+		ids      []ulid.ULID
+		min, max ulid.ULID
+		ok       bool
+	}{
+		{nil, ulid.Zero, ulid.Zero, false},
+		{[]ulid.ULID{}, ulid.Zero, ulid.Zero, false},
+		{[]ulid.ULID{b}, b, b, true},
+		{[]ulid.ULID{b, b, b}, b, b, true},
+		{[]ulid.ULID{b, c, a}, a, c, true},
+		{[]ulid.ULID{c, ulid.Zero, a}, ulid.Zero, c, true},
+		{[]ulid.ULID{a, top, c}, a, top, true},
+	}
+
+	for i, tc := range testCases {
+		smallest, ok := ulid.MinOf(tc.ids...)
+		if smallest != tc.min || ok != tc.ok {
+			t.Errorf("test case %d: got MinOf %s %t, want %s %t", i, smallest, ok, tc.min, tc.ok)
+		}
+
+		largest, ok := ulid.MaxOf(tc.ids...)
+		if largest != tc.max || ok != tc.ok {
+			t.Errorf("test case %d: got MaxOf %s %t, want %s %t", i, largest, ok, tc.max, tc.ok)
+		}
+	}
+
+	if smallest, ok := ulid.MinOf(); ok || !smallest.IsZero() {
+		t.Errorf("expected MinOf with no arguments to be false")
+	}
+
+	prop := func(ids []ulid.ULID) bool {
+		if len(ids) == 0 {
+			return true
+		}
+
+		smallest, _ := ulid.MinOf(ids...)
+		largest, _ := ulid.MaxOf(ids...)
+		return smallest == slices.MinFunc(ids, ulid.ULID.Compare) && largest == slices.MaxFunc(ids, ulid.ULID.Compare)
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	t.Parallel()
+
+	lo := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	hi := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	mid := ulid.MustParse("01BX5ZZKBKACTAV9WEVGEMMVRZ")
+	top := ulid.MustParse("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+	testCases := []struct {
+		id, lo, hi, want ulid.ULID
+	}{
+		{mid, lo, hi, mid},
+		{lo, lo, hi, lo},
+		{hi, lo, hi, hi},
+		{ulid.Zero, lo, hi, lo},
+		{top, lo, hi, hi},
+		{ulid.Zero, mid, mid, mid},
+		{top, mid, mid, mid},
+		{mid, mid, mid, mid},
+	}
+
+	for i, tc := range testCases {
+		if got := ulid.Clamp(tc.id, tc.lo, tc.hi); got != tc.want {
+			t.Errorf("test case %d: got %s, want %s", i, got, tc.want)
+		}
+	}
+
+	// Bounds in the wrong order are swapped.
+	for _, id := range []ulid.ULID{ulid.Zero, mid, top} {
+		if got, want := ulid.Clamp(id, hi, lo), ulid.Clamp(id, lo, hi); got != want {
+			t.Errorf("got %s with swapped bounds, want %s", got, want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+
+	// Sorting by the keys sorts the ULIDs.
+	prop := func(ids []ulid.ULID) bool {
+		keys := make([][16]byte, len(ids))
+		for i, id := range ids {
+			keys[i] = id.Key()
+		}
+
+		slices.SortFunc(keys, func(a, b [16]byte) int { return bytes.Compare(a[:], b[:]) })
+		slices.SortFunc(ids, ulid.ULID.Compare)
+		for i, id := range ids {
+			if keys[i] != id.Array() {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEqualsStringAllocs(t *testing.T) {
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	if allocs := testing.AllocsPerRun(100, func() { ulid.EqualsString(id, "01jkehnqpa0end3nhmfkb2y6se") }); allocs != 0 {
+		t.Errorf("expected EqualsString to not allocate, got %f allocs", allocs)
+	}
+}
+
 func TestOverflowHandling(t *testing.T) {
 	t.Parallel()
 
@@ -618,6 +1245,16 @@ func TestScan(t *testing.T) {
 		{"bytes", id[:], id, nil},
 		{"nil", nil, ulid.ULID{}, nil},
 		{"other", 44, ulid.ULID{}, ulid.ErrScanValue},
+		{"int64", int64(123), ulid.MustNew(123, nil), nil},
+		{"uint64", uint64(123), ulid.MustNew(123, nil), nil},
+		{"int64Zero", int64(0), ulid.Zero, nil},
+		{"int64Max", int64(ulid.MaxTime()), ulid.MustNew(ulid.MaxTime(), nil), nil},
+		{"int64Big", int64(ulid.MaxTime() + 1), ulid.ULID{}, ulid.ErrBigTime},
+		{"uint64Big", uint64(ulid.MaxTime() + 1), ulid.ULID{}, ulid.ErrBigTime},
+		{"uint64Overflow", uint64(math.MaxUint64), ulid.ULID{}, ulid.ErrBigTime},
+		{"int64Negative", int64(-1), ulid.ULID{}, ulid.ErrSmallTime},
+		{"int64Min", int64(math.MinInt64), ulid.ULID{}, ulid.ErrSmallTime},
+		{"int32", int32(123), ulid.ULID{}, ulid.ErrScanValue},
 	} {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -636,6 +1273,79 @@ func TestScan(t *testing.T) {
 	}
 }
 
+// database/sql delivers BIGINT columns to Scan as an int64.
+func TestScanTimestampSQL(t *testing.T) {
+	db, err := sql.Open("ulid-example", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ms := int64(1735689600000)
+	if _, err = db.Exec("INSERT INTO legacy (created) VALUES (?)", ms); err != nil {
+		t.Fatal(err)
+	}
+
+	var id ulid.ULID
+	if err = db.QueryRow("SELECT created FROM legacy").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	if id.Time() != uint64(ms) || !bytes.Equal(id.Entropy(), make([]byte, 10)) {
+		t.Errorf("expected a ULID with timestamp %d and zero entropy, got %s", ms, id)
+	}
+
+	var null ulid.NullULID
+	if err = db.QueryRow("SELECT created FROM legacy").Scan(&null); err != nil || !null.Valid || null.ULID != id {
+		t.Errorf("expected a valid NullULID %s, got %+v (%v)", id, null, err)
+	}
+
+	if _, err = db.Exec("INSERT INTO legacy (created) VALUES (?)", int64(-1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.QueryRow("SELECT created FROM legacy").Scan(&id); !errors.Is(err, ulid.ErrSmallTime) {
+		t.Errorf("expected ErrSmallTime, got %v", err)
+	}
+}
+
+func TestFromUnixMilli(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		ms  int64
+		err error
+	}{
+		{0, nil},
+		{1, nil},
+		{1735689600000, nil},
+		{int64(ulid.MaxTime()), nil},
+		{int64(ulid.MaxTime()) + 1, ulid.ErrBigTime},
+		{math.MaxInt64, ulid.ErrBigTime},
+		{-1, ulid.ErrSmallTime},
+		{math.MinInt64, ulid.ErrSmallTime},
+	} {
+		id, err := ulid.FromUnixMilli(tc.ms)
+		if err != tc.err {
+			t.Errorf("FromUnixMilli(%d): got err %v, want %v", tc.ms, err, tc.err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if id.Time() != uint64(tc.ms) || !bytes.Equal(id.Entropy(), make([]byte, 10)) {
+			t.Errorf("FromUnixMilli(%d): got %s, want timestamp with zero entropy", tc.ms, id)
+		}
+
+		// The ULID sorts before all other ULIDs in the same millisecond.
+		if other := ulid.MustNew(uint64(tc.ms), crand.Reader); other.Compare(id) < 0 {
+			t.Errorf("FromUnixMilli(%d): expected %s to sort before %s", tc.ms, id, other)
+		}
+	}
+}
+
 func TestULID_Bytes(t *testing.T) {
 	tt := time.Unix(1000000, 0)
 	entropy := ulid.Monotonic(rand.New(rand.NewSource(tt.UnixNano())), 0)
@@ -647,6 +1357,149 @@ func TestULID_Bytes(t *testing.T) {
 	}
 }
 
+func TestCanonical(t *testing.T) {
+	t.Parallel()
+
+	const canonical = "01JKEHNQPA0END3NHMFKB2Y6SE"
+	if !ulid.IsCanonical(canonical) {
+		t.Fatalf("expected %q to be canonical", canonical)
+	}
+
+	// Every lowercase letter at every position is an alias of the uppercase string.
+	for i := 0; i < ulid.EncodedSize; i++ {
+		for _, c := range ulid.Encoding {
+			if c < 'A' {
+				continue
+			}
+
+			s := ulid.MustParse(canonical).String()
+			s = s[:i] + string(c) + s[i+1:]
+			alias := s[:i] + strings.ToLower(string(c)) + s[i+1:]
+
+			if i == 0 {
+				// The first character can only be 0-7 so letters overflow.
+				if _, err := ulid.ParseCanonical(alias); err != ulid.ErrOverflow {
+					t.Fatalf("%q: got err %v, want %v", alias, err, ulid.ErrOverflow)
+				}
+				continue
+			}
+
+			if ulid.MustParse(alias) != ulid.MustParse(s) {
+				t.Fatalf("expected %q to alias %q", alias, s)
+			}
+
+			if ulid.IsCanonical(alias) {
+				t.Fatalf("expected %q to not be canonical", alias)
+			}
+
+			if _, err := ulid.ParseCanonical(alias); err != ulid.ErrNonCanonical {
+				t.Fatalf("%q: got err %v, want %v", alias, err, ulid.ErrNonCanonical)
+			}
+
+			if got, err := ulid.Canonicalize(alias); err != nil || got != s {
+				t.Fatalf("Canonicalize(%q): got %q (%v), want %q", alias, got, err, s)
+			}
+		}
+	}
+
+	// The trailing character carries no masked bits: each character in the final
+	// position decodes to a distinct ULID, so no uppercase aliases exist.
+	seen := make(map[ulid.ULID]string)
+	for _, c := range ulid.Encoding {
+		s := canonical[:ulid.EncodedSize-1] + string(c)
+		id := ulid.MustParseStrict(s)
+		if other, ok := seen[id]; ok {
+			t.Fatalf("%q and %q decode to the same ULID", s, other)
+		}
+		seen[id] = s
+
+		if !ulid.IsCanonical(s) {
+			t.Fatalf("expected %q to be canonical", s)
+		}
+	}
+
+	// Invalid inputs return the same errors as ParseStrict.
+	for s, want := range map[string]error{
+		"foo":                        ulid.ErrDataSize,
+		"01JKEHNQPA0END3NHMFKB2Y6SU": ulid.ErrInvalidCharacters,
+		"81JKEHNQPA0END3NHMFKB2Y6SE": ulid.ErrOverflow,
+	} {
+		if _, err := ulid.ParseCanonical(s); err != want {
+			t.Errorf("ParseCanonical(%q): got err %v, want %v", s, err, want)
+		}
+
+		if _, err := ulid.Canonicalize(s); err != want {
+			t.Errorf("Canonicalize(%q): got err %v, want %v", s, err, want)
+		}
+
+		if ulid.IsCanonical(s) {
+			t.Errorf("expected %q to not be canonical", s)
+		}
+	}
+}
+
+func FuzzCanonicalize(f *testing.F) {
+	f.Add("01JKEHNQPA0END3NHMFKB2Y6SE")
+	f.Add("01jkehnqpa0end3nhmfkb2y6se")
+	f.Add("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	f.Add("00000000000000000000000000")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		canonical, err := ulid.Canonicalize(s)
+		if err != nil {
+			if ulid.IsCanonical(s) {
+				t.Fatalf("%q is canonical but cannot be canonicalized: %v", s, err)
+			}
+			return
+		}
+
+		if again, err := ulid.Canonicalize(canonical); err != nil || again != canonical {
+			t.Fatalf("Canonicalize is not idempotent: %q -> %q -> %q (%v)", s, canonical, again, err)
+		}
+
+		if want := ulid.MustParse(s).String(); canonical != want {
+			t.Fatalf("Canonicalize(%q) = %q, want %q", s, canonical, want)
+		}
+
+		if !ulid.IsCanonical(canonical) {
+			t.Fatalf("expected %q to be canonical", canonical)
+		}
+
+		if ulid.IsCanonical(s) != (s == canonical) {
+			t.Fatalf("IsCanonical(%q) disagrees with Canonicalize", s)
+		}
+	})
+}
+
+// FuzzParse checks that strictly parsed ULIDs round trip and that Parse agrees
+// with ParseStrict on valid input. The seed corpus of interesting encodings in
+// testdata/fuzz/FuzzParse is shared with the oklog/ulid differential tests.
+func FuzzParse(f *testing.F) {
+	f.Add("01JKEHNQPA0END3NHMFKB2Y6SE")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		id, err := ulid.ParseStrict(s)
+		if err != nil {
+			switch {
+			case len(s) != ulid.EncodedSize && err != ulid.ErrDataSize:
+				t.Fatalf("expected ErrDataSize parsing %q, got %v", s, err)
+			case len(s) == ulid.EncodedSize && err != ulid.ErrInvalidCharacters && err != ulid.ErrOverflow:
+				t.Fatalf("unexpected error parsing %q: %v", s, err)
+			}
+			return
+		}
+
+		loose, err := ulid.Parse(s)
+		if err != nil || loose != id {
+			t.Fatalf("Parse(%q) = %s (%v), want %s", s, loose, err, id)
+		}
+
+		if got := ulid.MustParseStrict(id.String()); got != id || !strings.EqualFold(id.String(), s) {
+			t.Fatalf("%q does not round trip: %s", s, id)
+		}
+	})
+}
+
 //===========================================================================
 // Benchmarks
 //===========================================================================
@@ -835,6 +1688,41 @@ func BenchmarkEntropy(b *testing.B) {
 	}
 }
 
+func BenchmarkEntropyArray(b *testing.B) {
+	id := ulid.MustNew(0, strings.NewReader("ABCDEFGHIJKLMNOP"))
+	b.SetBytes(10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = id.EntropyArray()
+	}
+}
+
+func BenchmarkSameEntropy(b *testing.B) {
+	id, other := ulid.MustNew(12345, strings.NewReader("ABCDEFGHIJ")), ulid.MustNew(54321, strings.NewReader("ABCDEFGHIJ"))
+
+	b.Run("SameEntropy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = ulid.SameEntropy(id, other)
+		}
+	})
+
+	b.Run("EntropyCompare", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = ulid.EntropyCompare(id, other)
+		}
+	})
+
+	b.Run("Entropy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = bytes.Equal(id.Entropy(), other.Entropy())
+		}
+	})
+}
+
 func BenchmarkSetEntropy(b *testing.B) {
 	var id ulid.ULID
 	e := []byte("ABCDEFGHIJKLMNOP")
@@ -854,10 +1742,46 @@ func BenchmarkCompare(b *testing.B) {
 	}
 }
 
-func testPanics(t *testing.T, err any, f func()) {
+func BenchmarkCompareString(b *testing.B) {
+	id, other := ulid.MustNew(12345, nil), ulid.MustNew(54321, nil).String()
+
+	b.Run("CompareString", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = ulid.CompareString(id, other)
+		}
+	})
+
+	b.Run("ParseCompare", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = id.Compare(ulid.MustParse(other))
+		}
+	})
+
+	b.Run("StringCompare", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = strings.Compare(id.String(), other)
+		}
+	})
+
+	b.Run("EqualsString", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = ulid.EqualsString(id, other)
+		}
+	})
+}
+
+func testPanics(t *testing.T, err error, f func()) {
+	t.Helper()
 	defer func() {
-		if got, want := recover(), err; got != want {
-			t.Errorf("panic with err %v, want %v", got, want)
+		t.Helper()
+		perr, ok := recover().(*ulid.PanicError)
+		if !ok {
+			t.Errorf("expected panic with a *ulid.PanicError")
+			return
+		}
+
+		if !errors.Is(perr, err) {
+			t.Errorf("panic with err %v, want %v", perr, err)
 		}
 	}()
 	f()
@@ -0,0 +1,31 @@
+// Package ulidtest provides helpers for using ULIDs in tests.
+package ulidtest
+
+import (
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+// MustParse is equivalent to ulid.MustParse except that it calls tb.Fatal
+// instead of panicking so that the failure is reported cleanly by the test.
+func MustParse(tb testing.TB, v any) ulid.ULID {
+	tb.Helper()
+	id, err := ulid.Parse(v)
+	if err != nil {
+		tb.Fatalf("could not parse ulid %v: %s", v, err)
+	}
+	return id
+}
+
+// MustParseStrict is equivalent to ulid.MustParseStrict except that it calls
+// tb.Fatal instead of panicking so that the failure is reported cleanly by the
+// test.
+func MustParseStrict(tb testing.TB, v any) ulid.ULID {
+	tb.Helper()
+	id, err := ulid.ParseStrict(v)
+	if err != nil {
+		tb.Fatalf("could not parse ulid %v: %s", v, err)
+	}
+	return id
+}
@@ -0,0 +1,66 @@
+package ulidtest_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/ulidtest"
+)
+
+func TestMustParse(t *testing.T) {
+	const valid = "01JKEHNQPA0END3NHMFKB2Y6SE"
+
+	for _, tc := range []struct {
+		name string
+		fn   func(testing.TB, any) ulid.ULID
+	}{
+		{"MustParse", ulidtest.MustParse},
+		{"MustParseStrict", ulidtest.MustParseStrict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if id := tc.fn(t, valid); id.String() != valid {
+				t.Errorf("got %s, want %s", id, valid)
+			}
+
+			mock := &mockTB{TB: t}
+			runFatal(func() { tc.fn(mock, "abc") })
+			if !mock.failed {
+				t.Fatal("expected test to fail")
+			}
+
+			if !strings.Contains(mock.msg, "abc") || !strings.Contains(mock.msg, ulid.ErrDataSize.Error()) {
+				t.Errorf("unexpected failure message %q", mock.msg)
+			}
+		})
+	}
+}
+
+// mockTB records calls to Fatalf; like testing.T it stops the calling goroutine
+// by panicking with errFatal, which is recovered by runFatal.
+type mockTB struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+var errFatal = errors.New("fatal")
+
+func (m *mockTB) Helper() {}
+
+func (m *mockTB) Fatalf(format string, args ...any) {
+	m.failed = true
+	m.msg = fmt.Sprintf(format, args...)
+	panic(errFatal)
+}
+
+func runFatal(f func()) {
+	defer func() {
+		if r := recover(); r != nil && r != errFatal {
+			panic(r)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,122 @@
+package ulid
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SegmentError describes a URN or URL whose last segment, which designates the
+// ULID, is not a ULID, as opposed to an input that is not a URN or URL at all,
+// which is reported with ErrNotURN or ErrNotURL.
+type SegmentError struct {
+	Input   string // The URN or URL
+	Segment string // The last segment after percent-decoding it
+	Err     error  // The error from ParseStrict
+}
+
+func (e *SegmentError) Error() string {
+	return fmt.Sprintf("%s: last segment %q of %q", e.Err, e.Segment, e.Input)
+}
+
+func (e *SegmentError) Unwrap() error {
+	return e.Err
+}
+
+// urnEscaper percent-encodes the characters of a namespace segment that would
+// otherwise split or terminate it.
+var urnEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "?", "%3F", "#", "%23")
+
+// ParseURN parses a ULID that is the last colon-separated segment of a URN, e.g.
+// urn:rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAV, returning the segments
+// before it, starting with the namespace identifier (rotational and project). The
+// urn scheme is case insensitive and the r-, q-, and f-components of the URN that
+// start with a question mark or a hash are ignored. The segments of the namespace
+// are percent-decoded, for symmetry with FormatURN, but are otherwise returned as
+// is; the last segment is not decoded and is parsed with ParseStrict.
+//
+// An error wrapping ErrNotURN is returned if the string is not a URN with at least
+// one namespace segment, or if a namespace segment is empty or has an invalid
+// percent-encoding. If the last segment is not a ULID, the error is a
+// *SegmentError wrapping the error from ParseStrict.
+func ParseURN(s string) (namespace []string, id ULID, err error) {
+	scheme, rest, ok := strings.Cut(s, ":")
+	if !ok || !strings.EqualFold(scheme, "urn") {
+		return nil, id, fmt.Errorf("%w: %q does not start with urn:", ErrNotURN, s)
+	}
+
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+
+	segments := strings.Split(rest, ":")
+	if len(segments) < 2 {
+		return nil, id, fmt.Errorf("%w: %q has no namespace", ErrNotURN, s)
+	}
+
+	last := segments[len(segments)-1]
+	namespace = segments[:len(segments)-1]
+	for i, segment := range namespace {
+		if segment == "" {
+			return nil, id, fmt.Errorf("%w: %q has an empty segment", ErrNotURN, s)
+		}
+
+		if namespace[i], err = url.PathUnescape(segment); err != nil {
+			return nil, id, fmt.Errorf("%w: %q: %w", ErrNotURN, s, err)
+		}
+	}
+
+	if id, err = ParseStrict(last); err != nil {
+		return nil, id, &SegmentError{Input: s, Segment: last, Err: err}
+	}
+	return namespace, id, nil
+}
+
+// FormatURN returns the URN of the ULID in the namespace, the inverse of ParseURN,
+// e.g. urn:rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAV for the namespace
+// rotational, project. The percent sign and the colon, question mark, and hash of
+// the namespace segments are percent-encoded so that the URN is parsed into the
+// same segments.
+func FormatURN(namespace []string, id ULID) string {
+	var sb strings.Builder
+	sb.WriteString("urn:")
+	for _, segment := range namespace {
+		urnEscaper.WriteString(&sb, segment)
+		sb.WriteByte(':')
+	}
+	sb.WriteString(id.String())
+	return sb.String()
+}
+
+// ParseURL parses a ULID that is the last segment of the path of a URL, e.g.
+// https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV?x=1, ignoring the
+// query and fragment of the URL and any trailing slashes of the path. Only the
+// last segment is parsed, so a ULID in another segment of the path is ignored. The
+// segment is percent-decoded and parsed with ParseStrict; an encoded slash is part
+// of the segment rather than a separator.
+//
+// An error wrapping ErrNotURL is returned if the string cannot be parsed as a URL
+// or is not an absolute URL with a path, e.g. a relative reference or a URN. If
+// the last segment of the path is not a ULID, or there is no segment, the error is
+// a *SegmentError wrapping the error from ParseStrict.
+func ParseURL(s string) (id ULID, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return id, fmt.Errorf("%w: %w", ErrNotURL, err)
+	}
+
+	if u.Scheme == "" || u.Opaque != "" {
+		return id, fmt.Errorf("%w: %q is not an absolute URL", ErrNotURL, s)
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	segment := path[strings.LastIndexByte(path, '/')+1:]
+	if segment, err = url.PathUnescape(segment); err != nil {
+		return id, fmt.Errorf("%w: %w", ErrNotURL, err)
+	}
+
+	if id, err = ParseStrict(segment); err != nil {
+		return id, &SegmentError{Input: s, Segment: segment, Err: err}
+	}
+	return id, nil
+}
@@ -0,0 +1,138 @@
+package ulid_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestParseURN(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	testCases := []struct {
+		urn       string
+		namespace []string
+		err       error
+	}{
+		{"urn:rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAV", []string{"rotational", "project"}, nil},
+		{"URN:Rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAV", []string{"Rotational", "project"}, nil},
+		{"urn:rotational:01arz3ndektsv4rrffq69g5fav", []string{"rotational"}, nil},
+		{"urn:rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAV?=v2#top", []string{"rotational", "project"}, nil},
+		{"urn:rotational:a%3Ab:01ARZ3NDEKTSV4RRFFQ69G5FAV", []string{"rotational", "a:b"}, nil},
+
+		// Only the last segment is the ULID.
+		{"urn:rotational:01BX5ZZKBKACTAV9WEVGEMMVRZ:01ARZ3NDEKTSV4RRFFQ69G5FAV", []string{"rotational", "01BX5ZZKBKACTAV9WEVGEMMVRZ"}, nil},
+		{"urn:rotational:01ARZ3NDEKTSV4RRFFQ69G5FAV:v2", nil, ulid.ErrDataSize},
+
+		{"urn:rotational:project:", nil, ulid.ErrDataSize},
+		{"urn:rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAU", nil, ulid.ErrInvalidCharacters},
+		{"urn:rotational::01ARZ3NDEKTSV4RRFFQ69G5FAV", nil, ulid.ErrNotURN},
+		{"urn::01ARZ3NDEKTSV4RRFFQ69G5FAV", nil, ulid.ErrNotURN},
+		{"urn:01ARZ3NDEKTSV4RRFFQ69G5FAV", nil, ulid.ErrNotURN},
+		{"urn:rotational%zz:01ARZ3NDEKTSV4RRFFQ69G5FAV", nil, ulid.ErrNotURN},
+		{"uri:rotational:01ARZ3NDEKTSV4RRFFQ69G5FAV", nil, ulid.ErrNotURN},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", nil, ulid.ErrNotURN},
+		{"", nil, ulid.ErrNotURN},
+	}
+
+	for _, tc := range testCases {
+		namespace, got, err := ulid.ParseURN(tc.urn)
+		if !errors.Is(err, tc.err) || (tc.err == nil) != (err == nil) {
+			t.Errorf("%q: got error %v, want %v", tc.urn, err, tc.err)
+			continue
+		}
+
+		// Only the errors of the last segment are segment errors.
+		var serr *ulid.SegmentError
+		if isSegment := errors.As(err, &serr); isSegment != (err != nil && tc.err != ulid.ErrNotURN) {
+			t.Errorf("%q: got %T, expected a segment error %t", tc.urn, err, !isSegment)
+		}
+
+		if err == nil && (got != id || !slices.Equal(namespace, tc.namespace)) {
+			t.Errorf("%q: got %v %s, want %v %s", tc.urn, namespace, got, tc.namespace, id)
+		}
+	}
+}
+
+func TestFormatURN(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.Make()
+	for _, namespace := range [][]string{
+		{"rotational", "project"},
+		{"rotational"},
+		{"isbn"},
+		{"a:b", "50%", "what?", "#1", "c/d"},
+	} {
+		urn := ulid.FormatURN(namespace, id)
+		got, parsed, err := ulid.ParseURN(urn)
+		if err != nil || parsed != id || !slices.Equal(got, namespace) {
+			t.Errorf("%v: %q parsed as %v %s (%v)", namespace, urn, got, parsed, err)
+		}
+	}
+
+	if urn := ulid.FormatURN([]string{"rotational", "project"}, ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")); urn != "urn:rotational:project:01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Errorf("got %q", urn)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	t.Parallel()
+
+	id := ulid.MustParse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	testCases := []struct {
+		url     string
+		segment string
+		err     error
+	}{
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV?x=1", "", nil},
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV#details", "", nil},
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV/", "", nil},
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV//?x=1", "", nil},
+		{"HTTPS://API.EXAMPLE.COM/v1/things/01arz3ndektsv4rrffq69g5fav", "", nil},
+		{"https://api.example.com/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", nil},
+		{"s3://bucket/exports/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", nil},
+
+		// Percent-encoded characters are decoded, but an encoded slash does not
+		// separate segments.
+		{"https://api.example.com/v1/things/%30%31ARZ3NDEKTSV4RRFFQ69G5FAV", "", nil},
+		{"https://api.example.com/v1/things%2F01ARZ3NDEKTSV4RRFFQ69G5FAV", "things/01ARZ3NDEKTSV4RRFFQ69G5FAV", ulid.ErrDataSize},
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV%20", "01ARZ3NDEKTSV4RRFFQ69G5FAV ", ulid.ErrDataSize},
+
+		// Only the last segment is the ULID.
+		{"https://api.example.com/v1/orgs/01BX5ZZKBKACTAV9WEVGEMMVRZ/things/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", nil},
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV/edit", "edit", ulid.ErrDataSize},
+		{"https://api.example.com/v1/things?id=01ARZ3NDEKTSV4RRFFQ69G5FAV", "things", ulid.ErrDataSize},
+
+		{"https://api.example.com/", "", ulid.ErrDataSize},
+		{"https://api.example.com", "", ulid.ErrDataSize},
+		{"https://api.example.com/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAU", "01ARZ3NDEKTSV4RRFFQ69G5FAU", ulid.ErrInvalidCharacters},
+		{"/v1/things/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", ulid.ErrNotURL},
+		{"urn:rotational:01ARZ3NDEKTSV4RRFFQ69G5FAV", "", ulid.ErrNotURL},
+		{"https://api.example.com/v1/things/%zz", "", ulid.ErrNotURL},
+		{"https://[::1/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", ulid.ErrNotURL},
+		{"", "", ulid.ErrNotURL},
+	}
+
+	for _, tc := range testCases {
+		got, err := ulid.ParseURL(tc.url)
+		if !errors.Is(err, tc.err) || (tc.err == nil) != (err == nil) {
+			t.Errorf("%q: got error %v, want %v", tc.url, err, tc.err)
+			continue
+		}
+
+		var serr *ulid.SegmentError
+		if isSegment := errors.As(err, &serr); isSegment != (err != nil && tc.err != ulid.ErrNotURL) {
+			t.Errorf("%q: got %T, expected a segment error %t", tc.url, err, !isSegment)
+		} else if isSegment && (serr.Segment != tc.segment || serr.Input != tc.url) {
+			t.Errorf("%q: got segment %q of %q, want %q", tc.url, serr.Segment, serr.Input, tc.segment)
+		}
+
+		if err == nil && got != id {
+			t.Errorf("%q: got %s, want %s", tc.url, got, id)
+		}
+	}
+}
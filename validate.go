@@ -0,0 +1,105 @@
+package ulid
+
+import (
+	"fmt"
+	"time"
+)
+
+// ValidateOption configures the plausibility checks of ValidateBinary.
+type ValidateOption func(*validateConfig)
+
+type validateConfig struct {
+	min         time.Time
+	maxFuture   time.Duration
+	checkFuture bool
+	zero        bool
+	now         func() time.Time
+}
+
+// MinTime rejects ULIDs with a timestamp before t, e.g. the date the system that
+// created them was first deployed.
+func MinTime(t time.Time) ValidateOption {
+	return func(c *validateConfig) {
+		c.min = t
+	}
+}
+
+// MaxFuture rejects ULIDs with a timestamp more than d after the current time.
+// A negative d is treated as zero.
+func MaxFuture(d time.Duration) ValidateOption {
+	return func(c *validateConfig) {
+		c.maxFuture, c.checkFuture = max(d, 0), true
+	}
+}
+
+// RejectZeroEntropy rejects ULIDs whose entropy bytes are all zero, such as
+// zeroed records or ULIDs created from a timestamp alone.
+func RejectZeroEntropy() ValidateOption {
+	return func(c *validateConfig) {
+		c.zero = true
+	}
+}
+
+// ValidateClock sets the function used to get the current time for MaxFuture
+// (default time.Now).
+func ValidateClock(now func() time.Time) ValidateOption {
+	return func(c *validateConfig) {
+		c.now = now
+	}
+}
+
+// ValidateBinary checks that data is a plausible binary encoded ULID, e.g. to
+// detect corrupt storage or reads that are not aligned to the 16 byte records.
+// ErrDataSize is returned if data is not 16 bytes, which is the only check
+// without options and matches UnmarshalBinary. With MinTime or MaxFuture an error
+// wrapping ErrImplausibleTime is returned if the timestamp is out of the range,
+// and with RejectZeroEntropy ErrZeroEntropy is returned if the entropy is zero.
+func ValidateBinary(data []byte, opts ...ValidateOption) error {
+	id, err := FromSlice(data)
+	if err != nil || len(opts) == 0 {
+		return err
+	}
+
+	conf := validateConfig{now: time.Now}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	ms := id.Time()
+	if !conf.min.IsZero() && int64(ms) < conf.min.UnixMilli() {
+		return implausible(id, "%s is before %s", Time(ms).UTC().Format(time.RFC3339Nano), conf.min.UTC().Format(time.RFC3339Nano))
+	}
+
+	if conf.checkFuture {
+		if latest := conf.now().Add(conf.maxFuture); int64(ms) > latest.UnixMilli() {
+			return implausible(id, "%s is more than %s in the future", Time(ms).UTC().Format(time.RFC3339Nano), conf.maxFuture)
+		}
+	}
+
+	if conf.zero && id.EntropyArray() == [10]byte{} {
+		return ErrZeroEntropy
+	}
+	return nil
+}
+
+// implausible returns an error wrapping ErrImplausibleTime that describes why the
+// timestamp of the ULID is implausible, or that the record is all zero or all
+// 0xFF bytes since those are usually unwritten or erased storage.
+func implausible(id ULID, format string, args ...any) error {
+	switch {
+	case id.IsZero():
+		return fmt.Errorf("%w: record is all zero bytes", ErrImplausibleTime)
+	case id.IsAllOnes():
+		return fmt.Errorf("%w: record is all 0xFF bytes", ErrImplausibleTime)
+	}
+	return fmt.Errorf("%w: "+format, append([]any{ErrImplausibleTime}, args...)...)
+}
+
+// UnmarshalBinaryValidated is like UnmarshalBinary but first checks the data with
+// ValidateBinary and the options, leaving the ULID unchanged if it is rejected.
+func (id *ULID) UnmarshalBinaryValidated(data []byte, opts ...ValidateOption) error {
+	if err := ValidateBinary(data, opts...); err != nil {
+		return err
+	}
+	return id.UnmarshalBinary(data)
+}
@@ -0,0 +1,104 @@
+package ulid_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestValidateBinary(t *testing.T) {
+	t.Parallel()
+
+	var (
+		deployed  = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		now       = time.Date(2025, 2, 6, 21, 11, 53, 290e6, time.UTC)
+		clock     = ulid.ValidateClock(func() time.Time { return now })
+		plausible = []ulid.ValidateOption{ulid.MinTime(deployed), ulid.MaxFuture(time.Hour), clock}
+	)
+
+	id := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+	record := id.Bytes()
+
+	// A record read one byte late when scanning a file of records.
+	next := ulid.MustParse("01JKEHNQPA0END3NHMFNPBB9WE").Bytes()
+	shiftedLeft := append(slices.Clone(record[1:]), next[0])
+
+	// A record read one byte early, starting with the last byte of the previous.
+	shiftedRight := append([]byte{0xE7}, record[:15]...)
+
+	// A record with the timestamp written in little endian byte order.
+	swapped := slices.Clone(record)
+	slices.Reverse(swapped[:6])
+
+	zeroEntropy := slices.Clone(record)
+	clear(zeroEntropy[6:])
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		opts []ulid.ValidateOption
+		err  error
+	}{
+		{"Valid", record, nil, nil},
+		{"ValidPlausible", record, plausible, nil},
+		{"Short", record[:15], nil, ulid.ErrDataSize},
+		{"Long", append(slices.Clone(record), 0), plausible, ulid.ErrDataSize},
+		{"Empty", nil, nil, ulid.ErrDataSize},
+
+		// Without options any 16 bytes are accepted like UnmarshalBinary.
+		{"ShiftedLeftUnchecked", shiftedLeft, nil, nil},
+		{"ShiftedRightUnchecked", shiftedRight, nil, nil},
+		{"SwappedUnchecked", swapped, nil, nil},
+		{"ZeroEntropyUnchecked", zeroEntropy, plausible, nil},
+
+		// The plausibility checks catch misaligned and swapped records.
+		{"ShiftedLeft", shiftedLeft, plausible, ulid.ErrImplausibleTime},
+		{"ShiftedRight", shiftedRight, plausible, ulid.ErrImplausibleTime},
+		{"Swapped", swapped, plausible, ulid.ErrImplausibleTime},
+		{"Zero", make([]byte, 16), plausible, ulid.ErrImplausibleTime},
+		{"ZeroEntropy", zeroEntropy, []ulid.ValidateOption{ulid.RejectZeroEntropy()}, ulid.ErrZeroEntropy},
+		{"BeforeDeployment", ulid.MustNewDefault(deployed.Add(-time.Millisecond)).Bytes(), plausible, ulid.ErrImplausibleTime},
+		{"AtDeployment", ulid.MustNewDefault(deployed).Bytes(), plausible, nil},
+		{"MaxFuture", ulid.MustNewDefault(now.Add(time.Hour)).Bytes(), plausible, nil},
+		{"AfterMaxFuture", ulid.MustNewDefault(now.Add(time.Hour + time.Millisecond)).Bytes(), plausible, ulid.ErrImplausibleTime},
+		{"NegativeMaxFuture", ulid.MustNewDefault(now.Add(time.Millisecond)).Bytes(), []ulid.ValidateOption{ulid.MaxFuture(-time.Hour), clock}, ulid.ErrImplausibleTime},
+		{"MaxTime", bytes.Repeat([]byte{0xFF}, 16), []ulid.ValidateOption{ulid.MaxFuture(time.Hour)}, ulid.ErrImplausibleTime},
+	} {
+		err := ulid.ValidateBinary(tc.data, tc.opts...)
+		if !errors.Is(err, tc.err) || (tc.err == nil) != (err == nil) {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.err)
+		}
+
+		var got ulid.ULID
+		err = got.UnmarshalBinaryValidated(tc.data, tc.opts...)
+		switch {
+		case !errors.Is(err, tc.err) || (tc.err == nil) != (err == nil):
+			t.Errorf("%s: got error %v from UnmarshalBinaryValidated, want %v", tc.name, err, tc.err)
+		case err == nil && !bytes.Equal(got[:], tc.data):
+			t.Errorf("%s: got %x, want %x", tc.name, got[:], tc.data)
+		case err != nil && !got.IsZero():
+			t.Errorf("%s: expected the ULID to be unchanged on error, got %s", tc.name, got)
+		}
+	}
+
+	// Unwritten and erased records are described as such.
+	for data, want := range map[byte]string{
+		0x00: "ulid: implausible timestamp: record is all zero bytes",
+		0xFF: "ulid: implausible timestamp: record is all 0xFF bytes",
+	} {
+		if err := ulid.ValidateBinary(bytes.Repeat([]byte{data}, 16), plausible...); err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %s", err, want)
+		}
+	}
+
+	// The error describes the implausible timestamp.
+	err := ulid.ValidateBinary(swapped, plausible...)
+	if want := "ulid: implausible timestamp: "; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %v, want prefix %q", err, want)
+	}
+}
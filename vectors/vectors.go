@@ -0,0 +1,214 @@
+// Package vectors implements the cross-language test vectors of ULIDs in
+// vectors.json, which are generated from the ulid package by internal/vectorgen
+// so that implementations of ULIDs in other languages can be checked against
+// them. The vectors are embedded in this package rather than in the ulid package
+// so that only the binaries that verify them, such as ulid check --selftest,
+// include them.
+package vectors
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/internal/unhooked"
+)
+
+//go:generate go run ../internal/vectorgen -o vectors.json
+
+//go:embed vectors.json
+var embedded []byte
+
+// TimeFormat is the format of the Time of a Vector, which is always UTC.
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Error categories of malformed test vectors, which identify the error returned
+// by ulid.ParseStrict in a form that other implementations can compare against.
+const (
+	CategoryDataSize          = "data_size"
+	CategoryInvalidCharacters = "invalid_characters"
+	CategoryOverflow          = "overflow"
+)
+
+// Vectors is the schema of the cross-language test vectors in vectors.json.
+type Vectors struct {
+	Version   int         `json:"version"`
+	Valid     []Vector    `json:"valid"`
+	Malformed []Malformed `json:"malformed"`
+}
+
+// Vector is a ULID constructed from a timestamp and entropy with its expected
+// encodings. The entropy and hex are lowercase hex strings and the bytes are an
+// array of integers so that they are easy to decode in any language. Lower is the
+// lowercase string, which must parse to the same ULID.
+type Vector struct {
+	Name      string `json:"name"`
+	Timestamp uint64 `json:"timestamp"`
+	Entropy   string `json:"entropy"`
+	String    string `json:"string"`
+	Lower     string `json:"lower"`
+	Bytes     []int  `json:"bytes"`
+	Hex       string `json:"hex"`
+	Time      string `json:"time"`
+}
+
+// Malformed is an input that must fail to parse with the error Category.
+type Malformed struct {
+	Name     string `json:"name"`
+	Input    string `json:"input"`
+	Category string `json:"error"`
+}
+
+// New returns the expected encodings of the ULID with the timestamp and entropy.
+// It is used to generate the test vectors.
+func New(name string, ms uint64, entropy [10]byte) (v Vector, err error) {
+	var id ulid.ULID
+	if id, err = ulid.New(ms, bytes.NewReader(entropy[:])); err != nil {
+		return v, err
+	}
+
+	s := id.String()
+	return Vector{
+		Name:      name,
+		Timestamp: ms,
+		Entropy:   hex.EncodeToString(entropy[:]),
+		String:    s,
+		Lower:     strings.ToLower(s),
+		Bytes:     ints(id[:]),
+		Hex:       hex.EncodeToString(id[:]),
+		Time:      ulid.Time(ms).UTC().Format(TimeFormat),
+	}, nil
+}
+
+// NewMalformed returns a malformed test vector with the category of the error
+// returned by ulid.ParseStrict, or an error if the input is not malformed.
+func NewMalformed(name, input string) (Malformed, error) {
+	_, err := parseStrict(input)
+	if err == nil {
+		return Malformed{}, fmt.Errorf("%s: %q is not malformed", name, input)
+	}
+
+	category, ok := errorCategory(err)
+	if !ok {
+		return Malformed{}, fmt.Errorf("%s: %q has no error category: %w", name, input, err)
+	}
+	return Malformed{Name: name, Input: input, Category: category}, nil
+}
+
+// Verify decodes the test vectors from r and checks each of them against the ulid
+// package, returning an error describing every mismatch. It keeps the test
+// vectors used by other implementations in sync with the ulid package.
+func Verify(r io.Reader) error {
+	var vectors Vectors
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		return fmt.Errorf("could not decode test vectors: %w", err)
+	}
+
+	if len(vectors.Valid) == 0 && len(vectors.Malformed) == 0 {
+		return errors.New("no test vectors")
+	}
+
+	var errs []error
+	for _, v := range vectors.Valid {
+		if err := v.verify(); err != nil {
+			errs = append(errs, fmt.Errorf("vector %q: %w", v.Name, err))
+		}
+	}
+
+	for _, v := range vectors.Malformed {
+		if err := v.verify(); err != nil {
+			errs = append(errs, fmt.Errorf("malformed vector %q: %w", v.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SelfTest verifies the ulid package against the embedded test vectors.
+func SelfTest() error {
+	return Verify(bytes.NewReader(embedded))
+}
+
+func (v Vector) verify() error {
+	entropy, err := hex.DecodeString(v.Entropy)
+	if err != nil || len(entropy) != 10 {
+		return fmt.Errorf("invalid entropy %q", v.Entropy)
+	}
+
+	want, err := New(v.Name, v.Timestamp, [10]byte(entropy))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.String != want.String:
+		return fmt.Errorf("got string %s, want %s", want.String, v.String)
+	case v.Lower != want.Lower:
+		return fmt.Errorf("got lowercase string %s, want %s", want.Lower, v.Lower)
+	case !slices.Equal(v.Bytes, want.Bytes):
+		return fmt.Errorf("got bytes %v, want %v", want.Bytes, v.Bytes)
+	case v.Hex != want.Hex:
+		return fmt.Errorf("got hex %s, want %s", want.Hex, v.Hex)
+	case v.Time != want.Time:
+		return fmt.Errorf("got time %s, want %s", want.Time, v.Time)
+	}
+
+	// Both the canonical and lowercase strings must parse back to the bytes.
+	for _, s := range []string{v.String, v.Lower} {
+		id, err := parseStrict(s)
+		if err != nil {
+			return fmt.Errorf("could not parse %s: %w", s, err)
+		}
+
+		if !slices.Equal(ints(id[:]), v.Bytes) || id.Time() != v.Timestamp {
+			return fmt.Errorf("parsed %s as %x, want %s", s, id[:], v.Hex)
+		}
+	}
+	return nil
+}
+
+func (v Malformed) verify() error {
+	_, err := parseStrict(v.Input)
+	if err == nil {
+		return fmt.Errorf("expected %q to fail with %s", v.Input, v.Category)
+	}
+
+	if category, _ := errorCategory(err); category != v.Category {
+		return fmt.Errorf("got error %v for %q, want %s", err, v.Input, v.Category)
+	}
+	return nil
+}
+
+// parseStrict parses the string like ulid.ParseStrict without counting or hooking
+// the failures, which are expected for the malformed vectors.
+func parseStrict(s string) (ulid.ULID, error) {
+	b, err := unhooked.ParseStrict(s)
+	return ulid.FromArray(b), err
+}
+
+func errorCategory(err error) (string, bool) {
+	switch err {
+	case ulid.ErrDataSize:
+		return CategoryDataSize, true
+	case ulid.ErrInvalidCharacters:
+		return CategoryInvalidCharacters, true
+	case ulid.ErrOverflow:
+		return CategoryOverflow, true
+	default:
+		return "", false
+	}
+}
+
+func ints(b []byte) []int {
+	n := make([]int, len(b))
+	for i, c := range b {
+		n[i] = int(c)
+	}
+	return n
+}
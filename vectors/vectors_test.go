@@ -0,0 +1,109 @@
+package vectors_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+	"go.rtnl.ai/ulid/vectors"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := vectors.Verify(f); err != nil {
+		t.Fatalf("test vectors are out of sync with the implementation (run go generate): %v", err)
+	}
+
+	if err := vectors.SelfTest(); err != nil {
+		t.Fatalf("embedded test vectors are out of sync with the implementation: %v", err)
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*vectors.Vectors)
+		want   string
+	}{
+		{"String", func(v *vectors.Vectors) { v.Valid[0].String = "00000000000000000000000001" }, "got string"},
+		{"Lower", func(v *vectors.Vectors) { v.Valid[2].Lower = strings.ToUpper(v.Valid[2].Lower) }, "got lowercase string"},
+		{"Bytes", func(v *vectors.Vectors) { v.Valid[1].Bytes[15]++ }, "got bytes"},
+		{"Hex", func(v *vectors.Vectors) { v.Valid[1].Hex = strings.ToUpper(v.Valid[1].Hex) }, "got hex"},
+		{"Time", func(v *vectors.Vectors) { v.Valid[3].Time = "1970-01-01T00:00:00Z" }, "got time"},
+		{"Entropy", func(v *vectors.Vectors) { v.Valid[0].Entropy = "00" }, "invalid entropy"},
+		{"Timestamp", func(v *vectors.Vectors) { v.Valid[0].Timestamp = ulid.MaxTime() + 1 }, "time too big"},
+		{"Category", func(v *vectors.Vectors) { v.Malformed[0].Category = vectors.CategoryOverflow }, "want overflow"},
+		{"NotMalformed", func(v *vectors.Vectors) { v.Malformed[0].Input = v.Valid[0].String }, "expected"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var set vectors.Vectors
+			if err := json.Unmarshal(data, &set); err != nil {
+				t.Fatal(err)
+			}
+
+			tc.modify(&set)
+			modified, _ := json.Marshal(set)
+
+			err := vectors.Verify(bytes.NewReader(modified))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	for _, data := range []string{"", "{", "{}", `{"valid": []}`} {
+		if err := vectors.Verify(strings.NewReader(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestNewMalformed(t *testing.T) {
+	t.Parallel()
+
+	if v, err := vectors.NewMalformed("short", "01ARZ"); err != nil || v.Category != vectors.CategoryDataSize {
+		t.Errorf("expected a data size category, got %+v (%v)", v, err)
+	}
+
+	if _, err := vectors.NewMalformed("valid", "01ARZ3NDEKTSV4RRFFQ69G5FAV"); err == nil {
+		t.Errorf("expected an error for a valid input")
+	}
+}
+
+func TestSelfTestUnhooked(t *testing.T) {
+	// The malformed vectors fail to parse on purpose, which must not be reported
+	// as parse failures of the application.
+	var calls int
+	ulid.SetParseFailureHook(func(string, error) { calls++ })
+	defer ulid.SetParseFailureHook(nil)
+
+	before := ulid.ReadHealth().ParseFailures
+	if err := vectors.SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vectors.NewMalformed("short", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := ulid.ReadHealth().ParseFailures; after != before || calls != 0 {
+		t.Errorf("got %d parse failures and %d calls of the hook, want none", after-before, calls)
+	}
+}
@@ -0,0 +1,136 @@
+package ulid
+
+import (
+	crand "crypto/rand"
+	"io"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath is the path of the module that is looked up in the build
+// information.
+const modulePath = "go.rtnl.ai/ulid"
+
+// develVersion is the version of a module that was not built with a version,
+// which is also what the build information reports for the main module.
+const develVersion = "(devel)"
+
+// Version returns the version of the package, e.g. to embed in metadata about
+// how a dataset of ULIDs was generated. It is the version of the go.rtnl.ai/ulid
+// module in the build information of the binary (or its replacement) if the
+// module was built with a version, and "(devel)" otherwise, e.g. in tests and in
+// builds from a local checkout.
+func Version() string {
+	return version()
+}
+
+var version = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return develVersion
+	}
+
+	for _, mod := range append([]*debug.Module{&info.Main}, info.Deps...) {
+		if mod.Path != modulePath {
+			continue
+		}
+
+		if mod.Replace != nil {
+			mod = mod.Replace
+		}
+
+		if mod.Version != "" && mod.Version != develVersion {
+			return mod.Version
+		}
+	}
+	return develVersion
+})
+
+// The entropy sources described by GeneratorInfo.
+const (
+	mathRandSource   = "math/rand"
+	cryptoRandSource = "crypto/rand"
+	sequentialSource = "sequential"
+	customSource     = "custom"
+)
+
+// GeneratorInfo describes the configuration of a generator of ULIDs, e.g. to
+// record "produced by go.rtnl.ai/ulid v1.2.0 with crypto/rand entropy" in the
+// metadata of an exported dataset. It marshals to JSON with lowercase
+// field names.
+type GeneratorInfo struct {
+	// Version is the version of the package, as returned by Version.
+	Version string `json:"version"`
+
+	// Entropy is the source of the entropy: math/rand, crypto/rand, sequential,
+	// or custom for any other reader.
+	Entropy string `json:"entropy"`
+
+	// Monotonic is true if the entropy increases within the same millisecond.
+	Monotonic bool `json:"monotonic"`
+
+	// Inc is the maximum increment of monotonic entropy, or zero if it is not
+	// monotonic or the increment is not known, e.g. with an IncrementStrategy.
+	Inc uint64 `json:"inc,omitempty"`
+}
+
+// DefaultGeneratorInfo describes DefaultEntropy, which is used by Make. The
+// built-in source pools monotonic readers but reads them with Read rather than
+// MonotonicRead, so its entropy is not reported as monotonic.
+func DefaultGeneratorInfo() GeneratorInfo {
+	return entropyInfo(DefaultEntropy())
+}
+
+// SecureGeneratorInfo describes SecureEntropy, which is used by MakeSecure.
+func SecureGeneratorInfo() GeneratorInfo {
+	return entropyInfo(SecureEntropy())
+}
+
+// Info describes the configuration of the generator. A generator without an
+// entropy source is described by DefaultGeneratorInfo.
+func (g *Generator) Info() GeneratorInfo {
+	if g.Entropy == nil {
+		return DefaultGeneratorInfo()
+	}
+	return entropyInfo(g.Entropy)
+}
+
+// entropyInfo describes the entropy by its type.
+func entropyInfo(entropy io.Reader) GeneratorInfo {
+	info := GeneratorInfo{Version: Version(), Entropy: customSource}
+	switch e := entropy.(type) {
+	case *PoolEntropy:
+		if e != nil && e.source != "" {
+			info.Entropy = e.source
+		}
+	case entropyWrapper:
+		if source := e.Unwrap(); source != nil {
+			info = entropyInfo(source)
+		}
+	case *rand.Rand:
+		info.Entropy = mathRandSource
+	case *MonotonicEntropy:
+		if e != nil {
+			info.Entropy = entropyInfo(e.source).Entropy
+			if e.strategy == nil {
+				info.Inc = e.inc
+			}
+		}
+		info.Monotonic = true
+	case *SequentialReader:
+		info.Entropy, info.Monotonic, info.Inc = sequentialSource, true, 1
+	case *LockedMonotonicReader:
+		if e != nil && e.MonotonicReader != nil {
+			info = entropyInfo(e.MonotonicReader)
+		}
+		info.Monotonic = true
+	case MonotonicReader:
+		info.Monotonic = true
+	default:
+		if entropy == crand.Reader {
+			info.Entropy = cryptoRandSource
+		}
+	}
+	return info
+}
@@ -0,0 +1,96 @@
+package ulid_test
+
+import (
+	crand "crypto/rand"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	// Test binaries of the module itself have no module version.
+	if v := ulid.Version(); v != "(devel)" {
+		t.Errorf("got version %q, want (devel)", v)
+	}
+}
+
+func TestGeneratorInfo(t *testing.T) {
+	t.Parallel()
+
+	v := ulid.Version()
+	for _, tc := range []struct {
+		name string
+		info ulid.GeneratorInfo
+		want ulid.GeneratorInfo
+	}{
+		{"Default", ulid.DefaultGeneratorInfo(), ulid.GeneratorInfo{Version: v, Entropy: "math/rand"}},
+		{"Secure", ulid.SecureGeneratorInfo(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand"}},
+		{"ZeroGenerator", (&ulid.Generator{}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "math/rand"}},
+		{"DefaultEntropy", (&ulid.Generator{Entropy: ulid.DefaultEntropy()}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "math/rand"}},
+		{"SecureEntropy", (&ulid.Generator{Entropy: ulid.SecureEntropy()}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand"}},
+		{"CryptoRand", (&ulid.Generator{Entropy: crand.Reader}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand"}},
+		{"MathRand", (&ulid.Generator{Entropy: rand.New(rand.NewSource(1))}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "math/rand"}},
+		{"Monotonic", (&ulid.Generator{Entropy: ulid.Monotonic(crand.Reader, 0)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true, Inc: math.MaxUint32}},
+		{"MonotonicInc", (&ulid.Generator{Entropy: ulid.Monotonic(rand.New(rand.NewSource(1)), 1024)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "math/rand", Monotonic: true, Inc: 1024}},
+		{"MonotonicStrategy", (&ulid.Generator{Entropy: ulid.MonotonicWithOptions(crand.Reader, 0, ulid.WithIncrement(ulid.ConstantIncrement(7)))}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true}},
+		{"Locked", (&ulid.Generator{Entropy: &ulid.LockedMonotonicReader{MonotonicReader: ulid.Monotonic(crand.Reader, 16)}}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true, Inc: 16}},
+		{"Sequential", (&ulid.Generator{Entropy: ulid.SequentialEntropy([10]byte{})}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "sequential", Monotonic: true, Inc: 1}},
+		{"Custom", (&ulid.Generator{Entropy: strings.NewReader("")}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "custom"}},
+		{"CustomMonotonic", (&ulid.Generator{Entropy: ulid.Monotonic(strings.NewReader(""), 8)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "custom", Monotonic: true, Inc: 8}},
+		{"CustomPool", (&ulid.Generator{Entropy: ulid.Pool(func() io.Reader { return crand.Reader })}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "custom"}},
+		{"NewSecure", (&ulid.Generator{Entropy: ulid.NewSecureEntropy(ulid.CountEntropy())}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand"}},
+		{"Counting", (&ulid.Generator{Entropy: ulid.Monotonic(ulid.CountingEntropy(crand.Reader), 1)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true, Inc: 1}},
+		{"Resilient", (&ulid.Generator{Entropy: ulid.Monotonic(ulid.ResilientEntropy(crand.Reader), 1)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true, Inc: 1}},
+		{"RateLimited", (&ulid.Generator{Entropy: ulid.Monotonic(ulid.RateLimitedEntropy(crand.Reader, 1, 1), 1)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true, Inc: 1}},
+		{"LimitedMonotonic", (&ulid.Generator{Entropy: ulid.RateLimitedEntropy(ulid.Monotonic(crand.Reader, 1), 1, 1)}).Info(), ulid.GeneratorInfo{Version: v, Entropy: "crypto/rand", Monotonic: true, Inc: 1}},
+	} {
+		if tc.info != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, tc.info, tc.want)
+		}
+	}
+}
+
+func TestGeneratorInfoReplaced(t *testing.T) {
+	// Replacing the default entropy changes its description.
+	prev := ulid.SetDefaultEntropy(ulid.Monotonic(crand.Reader, 32))
+	defer ulid.SetDefaultEntropy(prev)
+
+	want := ulid.GeneratorInfo{Version: ulid.Version(), Entropy: "crypto/rand", Monotonic: true, Inc: 32}
+	if info := ulid.DefaultGeneratorInfo(); info != want {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+
+	if info := (&ulid.Generator{}).Info(); info != want {
+		t.Errorf("got %+v for the zero generator, want %+v", info, want)
+	}
+}
+
+func TestGeneratorInfoJSON(t *testing.T) {
+	t.Parallel()
+
+	info := ulid.GeneratorInfo{Version: "v1.2.0", Entropy: "crypto/rand", Monotonic: true, Inc: 1024}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"version":"v1.2.0","entropy":"crypto/rand","monotonic":true,"inc":1024}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var decoded ulid.GeneratorInfo
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != info {
+		t.Errorf("got %+v (%v), want %+v", decoded, err, info)
+	}
+
+	if data, _ := json.Marshal(ulid.GeneratorInfo{Version: "v1.2.0", Entropy: "math/rand"}); string(data) != `{"version":"v1.2.0","entropy":"math/rand","monotonic":false}` {
+		t.Errorf("expected inc to be omitted if it is zero, got %s", data)
+	}
+}
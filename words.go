@@ -0,0 +1,128 @@
+package ulid
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// WordCount is the number of words in the mnemonic encoding of a ULID. Each word
+// encodes 11 bits, so 12 words encode 132 bits: the 128 bits of the ULID prefixed
+// by 4 zero bits.
+const WordCount = 12
+
+// The word list is the BIP39 English word list: 2048 sorted, lowercase words of
+// 3-8 letters where no two words share the same first four letters. The list
+// must never change since doing so would change the meaning of every previously
+// displayed mnemonic. Note that the mnemonic encoding of a ULID has no checksum
+// and is not a BIP39 mnemonic.
+//
+//go:embed wordlist.txt
+var wordlistFile string
+
+var (
+	wordsOnce sync.Once
+	wordlist  []string
+	wordIndex map[string]uint16
+)
+
+func loadWords() {
+	wordsOnce.Do(func() {
+		wordlist = strings.Fields(wordlistFile)
+		wordIndex = make(map[string]uint16, len(wordlist))
+		for i, word := range wordlist {
+			wordIndex[word] = uint16(i)
+		}
+	})
+}
+
+// Words returns a mnemonic encoding of the ULID as WordCount words for verbal
+// confirmation, e.g. when reading an ID aloud in a runbook. The mnemonic encoding
+// is for display purposes only and is not the canonical encoding of a ULID; the
+// String method should be used to store or transmit ULIDs. FromWords decodes the
+// mnemonic back into the ULID.
+func (id ULID) Words() []string {
+	loadWords()
+
+	// Treat the ULID as a 132 bit big-endian integer with 4 leading zero bits.
+	var u uint80
+	u.SetBytes(id[6:])
+	hi := uint64(id[0])<<40 | uint64(id[1])<<32 | uint64(id[2])<<24 |
+		uint64(id[3])<<16 | uint64(id[4])<<8 | uint64(id[5])
+
+	words := make([]string, WordCount)
+	for i := WordCount - 1; i >= 0; i-- {
+		words[i] = wordlist[u.Lo&0x7FF]
+
+		// Shift the 128 bit value (hi:48 | u.Hi:16 | u.Lo:64) right by 11 bits.
+		u.Lo = u.Lo>>11 | uint64(u.Hi)<<53
+		u.Hi = uint16(uint64(u.Hi)>>11 | hi<<5)
+		hi >>= 11
+	}
+	return words
+}
+
+// FromWords decodes a mnemonic produced by Words back into the ULID. Words are
+// normalized by trimming surrounding whitespace and folding them to lowercase.
+// ErrDataSize is returned if there are not exactly WordCount words; if a word is
+// not in the word list or the mnemonic does not fit in 128 bits, a *WordError is
+// returned identifying the position of the offending word.
+func FromWords(words []string) (id ULID, err error) {
+	loadWords()
+
+	if len(words) != WordCount {
+		return Zero, ErrDataSize
+	}
+
+	var (
+		hi uint64
+		u  uint80
+	)
+
+	for i, word := range words {
+		idx, ok := wordIndex[strings.ToLower(strings.TrimSpace(word))]
+		if !ok {
+			return Zero, &WordError{Position: i, Word: word, Err: ErrUnknownWord}
+		}
+
+		// The first word carries the 4 zero padding bits.
+		if i == 0 && idx >= 1<<7 {
+			return Zero, &WordError{Position: i, Word: word, Err: ErrOverflow}
+		}
+
+		// Shift the 128 bit value left by 11 bits and add the index.
+		hi = hi<<11 | uint64(u.Hi)>>5
+		u.Hi = u.Hi<<11 | uint16(u.Lo>>53)
+		u.Lo = u.Lo<<11 | uint64(idx)
+	}
+
+	id[0] = byte(hi >> 40)
+	id[1] = byte(hi >> 32)
+	id[2] = byte(hi >> 24)
+	id[3] = byte(hi >> 16)
+	id[4] = byte(hi >> 8)
+	id[5] = byte(hi)
+	u.AppendTo(id[6:])
+	return id, nil
+}
+
+// ParseWords splits s on whitespace and decodes the words with FromWords.
+func ParseWords(s string) (ULID, error) {
+	return FromWords(strings.Fields(s))
+}
+
+// WordError reports the position of a word that could not be decoded.
+type WordError struct {
+	Position int    // The zero-based position of the word in the mnemonic
+	Word     string // The word as it was passed to FromWords
+	Err      error  // The underlying error
+}
+
+func (e *WordError) Error() string {
+	return fmt.Sprintf("%s: word %d %q", e.Err, e.Position+1, e.Word)
+}
+
+func (e *WordError) Unwrap() error {
+	return e.Err
+}
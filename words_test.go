@@ -0,0 +1,123 @@
+package ulid_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestWordlist(t *testing.T) {
+	t.Parallel()
+
+	// The word list must be stable forever; this is the SHA-256 of the BIP39
+	// English word list.
+	data, err := os.ReadFile("wordlist.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(data)
+	if got, want := hex.EncodeToString(sum[:]), "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda"; got != want {
+		t.Fatalf("word list has changed: got sha256 %s, want %s", got, want)
+	}
+}
+
+func TestWords(t *testing.T) {
+	t.Parallel()
+
+	// Golden values pin the word list and the bit ordering of the encoding.
+	for s, want := range map[string]string{
+		"00000000000000000000000000": "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"7ZZZZZZZZZZZZZZZZZZZZZZZZZ": "avocado zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo",
+		"01JKEHNQPA0END3NHMFKB2Y6SE": "abandon skill inner forward skate bubble refuse property elephant help round grape",
+		"0000XSNJG0MQJHBF4QX1EFD6Y3": "abandon abandon unveil flock absent connect earth junior leader common surprise journey",
+	} {
+		id := ulid.MustParse(s)
+		words := id.Words()
+		if len(words) != ulid.WordCount {
+			t.Fatalf("got %d words, want %d", len(words), ulid.WordCount)
+		}
+
+		if got := strings.Join(words, " "); got != want {
+			t.Errorf("%s: got words %q, want %q", s, got, want)
+		}
+
+		if rt, err := ulid.ParseWords(want); err != nil || rt != id {
+			t.Errorf("%s: got %s (%v) from words", s, rt, err)
+		}
+	}
+
+	prop := func(id ulid.ULID) bool {
+		rt, err := ulid.FromWords(id.Words())
+		return err == nil && rt == id
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 1e4}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromWords(t *testing.T) {
+	t.Parallel()
+
+	words := "abandon skill inner forward skate bubble refuse property elephant help round grape"
+	want := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+
+	t.Run("Normalization", func(t *testing.T) {
+		for _, s := range []string{
+			strings.ToUpper(words),
+			"  Abandon\tSKILL inner\n forward skate bubble refuse property elephant help round grape  ",
+		} {
+			if id, err := ulid.ParseWords(s); err != nil || id != want {
+				t.Errorf("ParseWords(%q): got %s (%v), want %s", s, id, err, want)
+			}
+		}
+
+		if id, err := ulid.FromWords(strings.Split(" "+strings.ReplaceAll(words, " ", "  , ")+" ", ",")); err != nil || id != want {
+			t.Errorf("FromWords with surrounding whitespace: got %s (%v), want %s", id, err, want)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			input    string
+			err      error
+			position int
+		}{
+			{"TooFew", "abandon skill", ulid.ErrDataSize, -1},
+			{"TooMany", words + " zoo", ulid.ErrDataSize, -1},
+			{"Unknown", strings.Replace(words, "property", "proprety", 1), ulid.ErrUnknownWord, 7},
+			{"Overflow", strings.Replace(words, "abandon", "zoo", 1), ulid.ErrOverflow, 0},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := ulid.ParseWords(tc.input)
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("got err %v, want %v", err, tc.err)
+				}
+
+				var werr *ulid.WordError
+				if tc.position < 0 {
+					if errors.As(err, &werr) {
+						t.Errorf("expected no word error, got %v", werr)
+					}
+					return
+				}
+
+				if !errors.As(err, &werr) || werr.Position != tc.position {
+					t.Fatalf("expected word error at position %d, got %v", tc.position, err)
+				}
+
+				if !strings.Contains(err.Error(), werr.Word) {
+					t.Errorf("expected error message %q to contain the word", err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package ulid
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// xsiNamespace is the XML Schema instance namespace of the xsi:nil attribute.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// MarshalXML implements the xml.Marshaler interface, encoding the ULID as the
+// character data of the element, e.g. <id>01HTNMW2JAW89YSBG7NFPHABA4</id>.
+func (id ULID) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(id.String(), start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface, strictly parsing the
+// character data of the element like ParseStrict. Whitespace around the ULID,
+// e.g. from an indented document, is ignored. An invalid ULID returns the parse
+// error, such as ErrDataSize or ErrInvalidCharacters, wrapped with the name of
+// the element, and the ULID is not modified.
+func (id *ULID) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var data []byte
+	if err := d.DecodeElement(&data, &start); err != nil {
+		return err
+	}
+	return id.unmarshalXML(data, "element", start.Name)
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface, encoding the ULID as
+// the value of the attribute, e.g. id="01HTNMW2JAW89YSBG7NFPHABA4".
+func (id ULID) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: id.String()}, nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface, parsing the value
+// of the attribute like UnmarshalXML.
+func (id *ULID) UnmarshalXMLAttr(attr xml.Attr) error {
+	return id.unmarshalXML([]byte(attr.Value), "attribute", attr.Name)
+}
+
+func (id *ULID) unmarshalXML(data []byte, kind string, name xml.Name) error {
+	var tid ULID
+	if err := parse(bytes.TrimSpace(data), true, &tid); err != nil {
+		return fmt.Errorf("%w: xml %s %s", err, kind, name.Local)
+	}
+
+	*id = tid
+	return nil
+}
+
+// MarshalXML implements the xml.Marshaler interface, encoding a valid NullULID
+// like ULID. An invalid NullULID is encoded as an empty element with the xsi:nil
+// attribute of XML Schema, e.g. <id xmlns:xsi="..." xsi:nil="true"></id>; use the
+// omitempty option of the field to omit it instead.
+func (nu NullULID) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if nu.Valid {
+		return nu.ULID.MarshalXML(e, start)
+	}
+
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"},
+	)
+	return e.EncodeElement("", start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. An element with the
+// xsi:nil attribute set to true, or with no character data other than
+// whitespace, is decoded as an invalid NullULID; otherwise the element is parsed
+// like ULID.UnmarshalXML.
+func (nu *NullULID) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var data []byte
+	if err := d.DecodeElement(&data, &start); err != nil {
+		return err
+	}
+
+	if isXMLNil(start) {
+		*nu = NullULID{}
+		return nil
+	}
+	return nu.unmarshalXML(data, "element", start.Name)
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface, encoding a valid
+// NullULID like ULID and omitting the attribute of an invalid NullULID.
+func (nu NullULID) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if !nu.Valid {
+		return xml.Attr{}, nil
+	}
+	return nu.ULID.MarshalXMLAttr(name)
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface, decoding an
+// empty attribute as an invalid NullULID and parsing any other value like
+// ULID.UnmarshalXMLAttr. A missing attribute leaves the NullULID unmodified.
+func (nu *NullULID) UnmarshalXMLAttr(attr xml.Attr) error {
+	return nu.unmarshalXML([]byte(attr.Value), "attribute", attr.Name)
+}
+
+func (nu *NullULID) unmarshalXML(data []byte, kind string, name xml.Name) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		*nu = NullULID{}
+		return nil
+	}
+
+	if err := nu.ULID.unmarshalXML(data, kind, name); err != nil {
+		return err
+	}
+
+	nu.Valid = true
+	return nil
+}
+
+// isXMLNil returns true if the element has the xsi:nil attribute set to true. The
+// decoder translates the prefix to the namespace if it is declared.
+func isXMLNil(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "nil" && (attr.Name.Space == xsiNamespace || attr.Name.Space == "xsi") {
+			return attr.Value == "true" || attr.Value == "1"
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package ulid_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+func TestXML(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		XMLName  xml.Name      `xml:"record"`
+		ID       ulid.ULID     `xml:"id,attr"`
+		ParentID ulid.NullULID `xml:"parent,attr"`
+		Key      ulid.ULID     `xml:"key"`
+		Owner    ulid.NullULID `xml:"owner"`
+		Refs     []ulid.ULID   `xml:"refs>ref"`
+	}
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+	key := ulid.MustParse("01JKEHNQPA0END3NHMFKB2Y6SE")
+
+	testCases := []struct {
+		name     string
+		value    record
+		expected string
+	}{
+		{
+			"Valid",
+			record{ID: id, ParentID: ulid.NullULID{ULID: key, Valid: true}, Key: key, Owner: ulid.NullULID{ULID: id, Valid: true}, Refs: []ulid.ULID{id, key}},
+			`<record id="01HTNMW2JAW89YSBG7NFPHABA4" parent="01JKEHNQPA0END3NHMFKB2Y6SE"><key>01JKEHNQPA0END3NHMFKB2Y6SE</key><owner>01HTNMW2JAW89YSBG7NFPHABA4</owner><refs><ref>01HTNMW2JAW89YSBG7NFPHABA4</ref><ref>01JKEHNQPA0END3NHMFKB2Y6SE</ref></refs></record>`,
+		},
+		{
+			"Null",
+			record{ID: id, Key: ulid.Zero},
+			`<record id="01HTNMW2JAW89YSBG7NFPHABA4"><key>00000000000000000000000000</key><owner xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></owner><refs></refs></record>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		data, err := xml.Marshal(tc.value)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if string(data) != tc.expected {
+			t.Errorf("%s: got %s, want %s", tc.name, data, tc.expected)
+		}
+
+		// Start from a non-zero value so that xsi:nil is shown to reset it.
+		decoded := record{Owner: ulid.NullULID{ULID: id, Valid: true}}
+		if err := xml.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if decoded.ID != tc.value.ID || decoded.ParentID != tc.value.ParentID || decoded.Key != tc.value.Key || decoded.Owner != tc.value.Owner || !slices.Equal(decoded.Refs, tc.value.Refs) {
+			t.Errorf("%s: expected %+v after a round trip, got %+v", tc.name, tc.value, decoded)
+		}
+	}
+}
+
+func TestXMLUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		ID    ulid.ULID     `xml:"id,attr"`
+		Owner ulid.NullULID `xml:"owner"`
+		Key   ulid.ULID     `xml:"key"`
+	}
+
+	id := ulid.MustParse("01HTNMW2JAW89YSBG7NFPHABA4")
+
+	testCases := []struct {
+		name     string
+		data     string
+		expected record
+	}{
+		{"Whitespace", "<record id=\" 01HTNMW2JAW89YSBG7NFPHABA4 \">\n\t<key>\n\t\t01htnmw2jaw89ysbg7nfphaba4\n\t</key>\n\t<owner> 01HTNMW2JAW89YSBG7NFPHABA4 </owner>\n</record>", record{ID: id, Key: id, Owner: ulid.NullULID{ULID: id, Valid: true}}},
+		{"Empty", `<record><owner></owner></record>`, record{}},
+		{"SelfClosing", `<record><owner/></record>`, record{}},
+		{"Blank", "<record><owner>\n</owner></record>", record{}},
+		{"NilPrefix", `<record><owner xsi:nil="true"/></record>`, record{}},
+		{"NilNamespace", `<record xmlns:i="http://www.w3.org/2001/XMLSchema-instance"><owner i:nil="1">01HTNMW2JAW89YSBG7NFPHABA4</owner></record>`, record{}},
+		{"NotNil", `<record><owner xsi:nil="false">01HTNMW2JAW89YSBG7NFPHABA4</owner></record>`, record{Owner: ulid.NullULID{ULID: id, Valid: true}}},
+	}
+
+	for _, tc := range testCases {
+		decoded := record{Owner: ulid.NullULID{ULID: id, Valid: true}}
+		if err := xml.Unmarshal([]byte(tc.data), &decoded); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+
+		if decoded != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, decoded)
+		}
+	}
+}
+
+func TestXMLUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		ID    ulid.ULID     `xml:"id,attr"`
+		Owner ulid.NullULID `xml:"owner"`
+		Key   ulid.ULID     `xml:"key"`
+	}
+
+	testCases := []struct {
+		name    string
+		data    string
+		err     error
+		message string
+	}{
+		{"ShortAttr", `<record id="01HTNMW2JA"/>`, ulid.ErrDataSize, "xml attribute id"},
+		{"EmptyAttr", `<record id=""/>`, ulid.ErrDataSize, "xml attribute id"},
+		{"ShortElement", `<record><key>01HTNMW2JA</key></record>`, ulid.ErrDataSize, "xml element key"},
+		{"EmptyElement", `<record><key/></record>`, ulid.ErrDataSize, "xml element key"},
+		{"InvalidCharacters", `<record><key>01HTNMW2JAW89YSBG7NFPHABAU</key></record>`, ulid.ErrInvalidCharacters, "xml element key"},
+		{"Overflow", `<record><owner>81HTNMW2JAW89YSBG7NFPHABA4</owner></record>`, ulid.ErrOverflow, "xml element owner"},
+		{"Nested", `<record><key><id>01HTNMW2JAW89YSBG7NFPHABA4</id></key></record>`, ulid.ErrDataSize, "xml element key"},
+	}
+
+	for _, tc := range testCases {
+		var decoded record
+		err := xml.Unmarshal([]byte(tc.data), &decoded)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.err, err)
+			continue
+		}
+
+		if want := tc.err.Error() + ": " + tc.message; err.Error() != want {
+			t.Errorf("%s: got error %q, want %q", tc.name, err, want)
+		}
+
+		if decoded != (record{}) {
+			t.Errorf("%s: expected the record not to be modified, got %+v", tc.name, decoded)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package ulid
+
+import "io"
+
+// Zeroizer is implemented by the entropy sources that can scrub their state,
+// e.g. before a process that holds long-lived entropy moves to a lower trust mode
+// or a core dump is taken. After Zeroize the source still works but starts over
+// as if it had just been created, so the ULIDs after it are monotonic with each
+// other but not necessarily with the ULIDs before it in the same millisecond.
+type Zeroizer interface {
+	Zeroize()
+}
+
+var (
+	_ Zeroizer = &MonotonicEntropy{}
+	_ Zeroizer = &LockedMonotonicReader{}
+	_ Zeroizer = &PoolEntropy{}
+	_ Zeroizer = &Generator{}
+	_ Zeroizer = &PrefetchedReader{}
+)
+
+// ZeroizeDefaults scrubs the state of the entropy of Make and MakeSecure, e.g.
+// before a core dump is taken for debugging: the pooled readers of DefaultEntropy
+// and SecureEntropy, and of the initial entropy if they have been replaced, are
+// zeroized and dropped. Sources set with SetDefaultEntropy or SetSecureEntropy
+// are zeroized if they implement Zeroizer. It is safe to call while ULIDs are
+// being made; see PoolEntropy.Zeroize for the readers it cannot reach.
+func ZeroizeDefaults() {
+	for _, entropy := range []io.Reader{DefaultEntropy(), SecureEntropy(), builtinDefaultEntropy, builtinSecureEntropy} {
+		zeroize(entropy)
+	}
+}
+
+// Zeroize wipes the state of the monotonic entropy: the entropy and timestamp of
+// the last read, the scratch bytes of random increments, and the read-ahead
+// buffer of the source, which is overwritten in place rather than reallocated.
+// The next MonotonicRead reads new entropy from the source even in the same
+// millisecond. The state of the source itself, e.g. of a math/rand source, is not
+// scrubbed. Like MonotonicRead, Zeroize is not safe for concurrent use; wrap the
+// entropy with LockedMonotonicReader to zeroize it while it is in use.
+func (m *MonotonicEntropy) Zeroize() {
+	if m == nil {
+		return
+	}
+
+	m.ms, m.last = 0, 0
+	m.entropy = uint80{}
+	clear(m.rand[:])
+
+	if m.buffer != nil && m.Reader == m.buffer {
+		source := m.source
+		if m.limiter != nil {
+			source = m.limiter.Unwrap()
+		}
+
+		// Refilling the buffer from zeros overwrites every byte of it, including
+		// the bytes that were read ahead but not used, which are discarded.
+		m.buffer.Reset(zeroEntropy{})
+		m.buffer.Peek(m.buffer.Size())
+		m.buffer.Reset(source)
+	}
+}
+
+// Zeroize zeroizes the wrapped reader if it implements Zeroizer, holding the lock
+// so that it is safe while other goroutines read.
+func (r *LockedMonotonicReader) Zeroize() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	zeroize(r.MonotonicReader)
+	r.mu.Unlock()
+}
+
+// Zeroize drops the readers of the pool after zeroizing the readers that
+// implement Zeroizer, so that the next reads use new readers. It is safe to call
+// while other goroutines read from the pool: a reader that is in use is zeroized
+// and dropped when its read returns.
+//
+// A sync.Pool keeps one reader per processor that only a goroutine running on
+// that processor can take, so Zeroize may not reach every idle reader. The
+// monotonic readers that it misses are zeroized the next time they are taken
+// from the pool, before they are read. Pools that were not created by Pool,
+// NewDefaultEntropy, or NewSecureEntropy are not drained; only their monotonic
+// readers are zeroized the next time they are taken.
+func (e *PoolEntropy) Zeroize() {
+	if e == nil {
+		return
+	}
+
+	e.epoch.Add(1)
+	if !e.tracked {
+		return
+	}
+
+	// Every reader that is taken is dropped, so this ends once the pool has no
+	// readers left and a new one has to be made.
+	for {
+		made := e.made.Load()
+		if r, ok := e.Pool.Get().(io.Reader); ok {
+			zeroize(r)
+		}
+
+		if e.made.Load() != made {
+			return
+		}
+	}
+}
+
+// Zeroize zeroizes the entropy of the generator if it implements Zeroizer; if the
+// generator uses DefaultEntropy, the default entropy is zeroized.
+func (g *Generator) Zeroize() {
+	entropy := g.Entropy
+	if entropy == nil {
+		entropy = DefaultEntropy()
+	}
+	zeroize(entropy)
+}
+
+func zeroize(r io.Reader) {
+	if z, ok := r.(Zeroizer); ok {
+		z.Zeroize()
+	}
+}
+
+// zeroEntropy is an entropy source of zeros.
+type zeroEntropy struct{}
+
+func (zeroEntropy) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package ulid_test
+
+func init() {
+	raceEnabled = true
+}
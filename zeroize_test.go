@@ -0,0 +1,233 @@
+package ulid_test
+
+import (
+	"bufio"
+	crand "crypto/rand"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+
+	"go.rtnl.ai/ulid"
+)
+
+// monotonicState returns the bytes of the unexported state of the monotonic
+// entropy that Zeroize must clear: the timestamp and entropy of the last read,
+// the scratch bytes of the increments, and the read-ahead buffer, if any.
+func monotonicState(m *ulid.MonotonicEntropy) (state, buffer []byte) {
+	v := reflect.ValueOf(m).Elem()
+	entropy := v.FieldByName("entropy")
+	state = append(state, byte(v.FieldByName("ms").Uint()), byte(entropy.Field(0).Uint()), byte(entropy.Field(1).Uint()), byte(v.FieldByName("last").Uint()))
+	for rand, i := v.FieldByName("rand"), 0; i < rand.Len(); i++ {
+		state = append(state, byte(rand.Index(i).Uint()))
+	}
+
+	if b := v.FieldByName("buffer"); !b.IsNil() {
+		buf := b.Elem().FieldByName("buf")
+		for i := 0; i < buf.Len(); i++ {
+			buffer = append(buffer, byte(buf.Index(i).Uint()))
+		}
+	}
+	return state, buffer
+}
+
+// raceEnabled is set in race builds.
+var raceEnabled bool
+
+func isZeros(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMonotonicEntropyZeroize(t *testing.T) {
+	t.Parallel()
+
+	ms := ulid.Now()
+	m := ulid.Monotonic(crand.Reader, 0)
+	var prev ulid.ULID
+	for i := 0; i < 10; i++ {
+		prev = ulid.MustNew(ms, m)
+	}
+
+	state, buffer := monotonicState(m)
+	if isZeros(state) || isZeros(buffer) || len(buffer) != 4096 {
+		t.Fatalf("expected the entropy to have state before zeroizing, got %x and %d buffered bytes", state, len(buffer))
+	}
+
+	m.Zeroize()
+	if state, buffer = monotonicState(m); !isZeros(state) || !isZeros(buffer) || len(buffer) != 4096 {
+		t.Fatalf("expected the state and the buffer to be cleared in place, got %x", state)
+	}
+
+	if m.LastIncrement() != 0 {
+		t.Errorf("expected no last increment, got %d", m.LastIncrement())
+	}
+
+	// The entropy is read again from the source, even in the same millisecond, and
+	// is monotonic from there.
+	first := ulid.MustNew(ms, m)
+	if first == prev || isZeros(first.Entropy()) {
+		t.Fatalf("expected new entropy after zeroizing, got %s", first)
+	}
+
+	for i := 0; i < 10; i++ {
+		next := ulid.MustNew(ms, m)
+		if next.Compare(first) <= 0 {
+			t.Fatalf("%s is not after %s", next, first)
+		}
+		first = next
+	}
+
+	// The buffer of a caller's reader and unbuffered sources are not touched.
+	for _, m := range []*ulid.MonotonicEntropy{
+		ulid.Monotonic(bufio.NewReaderSize(crand.Reader, 4096), 0),
+		ulid.MonotonicWithOptions(crand.Reader, 0, ulid.NoBuffer()),
+		ulid.Monotonic(ulid.RateLimitedEntropy(crand.Reader, 1000, 100), 0),
+	} {
+		a := ulid.MustNew(ms, m)
+		m.Zeroize()
+		if state, _ := monotonicState(m); !isZeros(state) {
+			t.Errorf("expected the state to be cleared, got %x", state)
+		}
+
+		if b := ulid.MustNew(ms, m); b == a || isZeros(b.Entropy()) {
+			t.Errorf("expected new entropy after zeroizing, got %s after %s", b, a)
+		}
+	}
+
+	var nilEntropy *ulid.MonotonicEntropy
+	nilEntropy.Zeroize()
+}
+
+// NOTE: the tests that zeroize pools change GOMAXPROCS or the default entropy and
+// are not parallel.
+func TestPoolEntropyZeroize(t *testing.T) {
+	// With one processor every idle reader of the pool can be taken.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	pool := ulid.NewSecureEntropy()
+	readers := make([]*ulid.MonotonicEntropy, 4)
+	ms := ulid.Now()
+	for i := range readers {
+		readers[i] = pool.Get().(*ulid.MonotonicEntropy)
+		ulid.MustNew(ms, readers[i])
+		ulid.MustNew(ms, readers[i])
+	}
+
+	for _, r := range readers {
+		pool.Put(r)
+	}
+
+	// The race detector randomly drops readers that are put into a sync.Pool, which
+	// Zeroize cannot reach.
+	pool.Zeroize()
+	for i, r := range readers {
+		if state, buffer := monotonicState(r); (!isZeros(state) || !isZeros(buffer)) && !raceEnabled {
+			t.Errorf("reader %d: expected the state to be cleared, got %x", i, state)
+		}
+	}
+
+	// The zeroized readers were dropped.
+	for i := 0; i < len(readers)*2; i++ {
+		r := pool.Get().(*ulid.MonotonicEntropy)
+		for _, zeroized := range readers {
+			if r == zeroized {
+				t.Fatal("expected the zeroized readers to be dropped")
+			}
+		}
+	}
+
+	// The pool still reads entropy from new readers.
+	if id := ulid.MustNew(ms, pool); isZeros(id.Entropy()) {
+		t.Errorf("expected entropy from the pool, got %s", id)
+	}
+
+	// A reader that was missed, e.g. because it was in use, is zeroized when it is
+	// next taken.
+	r := pool.Get().(*ulid.MonotonicEntropy)
+	ulid.MustNew(ms, r)
+	pool.Zeroize()
+	pool.Put(r)
+	if got := pool.Get(); got == r {
+		if state, _ := monotonicState(r); !isZeros(state) {
+			t.Errorf("expected the missed reader to be zeroized when taken, got %x", state)
+		}
+	} else if !raceEnabled {
+		t.Fatal("expected to take the reader that was put back")
+	}
+
+	// Pools that were not created by Pool are not drained.
+	(&ulid.PoolEntropy{}).Zeroize()
+	var nilPool *ulid.PoolEntropy
+	nilPool.Zeroize()
+}
+
+func TestZeroizeConcurrency(t *testing.T) {
+	// Readers of the defaults, a pool, and a locked reader are zeroized while other
+	// goroutines generate ULIDs, which must keep working (and not race).
+	pool := ulid.NewDefaultEntropy()
+	locked := &ulid.LockedMonotonicReader{MonotonicReader: ulid.Monotonic(crand.Reader, 0)}
+	gen := &ulid.Generator{Entropy: locked}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				var err error
+				switch i % 4 {
+				case 0:
+					ulid.Make()
+					ulid.MakeSecure()
+				case 1:
+					_, err = ulid.New(ulid.Now(), pool)
+				case 2:
+					_, err = gen.New()
+				default:
+					_, err = pool.Read(make([]byte, 10))
+				}
+
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Zeroize at least once and until the goroutines are done.
+	for zeroizing := true; zeroizing; {
+		ulid.ZeroizeDefaults()
+		pool.Zeroize()
+		gen.Zeroize()
+
+		select {
+		case <-done:
+			zeroizing = false
+		default:
+			runtime.Gosched()
+		}
+	}
+
+	// After zeroizing, the generator makes monotonic ULIDs again.
+	ms := ulid.Now()
+	prev := ulid.MustNew(ms, locked)
+	for i := 0; i < 10; i++ {
+		next := ulid.MustNew(ms, locked)
+		if next.Compare(prev) <= 0 {
+			t.Fatalf("%s is not after %s", next, prev)
+		}
+		prev = next
+	}
+}